@@ -0,0 +1,103 @@
+// Command admin manages admin accounts from the command line. Its one
+// subcommand, create-superadmin, creates the first super admin, who can then
+// invite everyone else through the admin API:
+//
+//	go run ./cmd/admin create-superadmin -email ops@example.com -first-name Abebe -last-name Kebede
+//
+// The account gets a temporary password, printed once, which must be
+// replaced at first sign-in.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/database"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "create-superadmin" {
+		fmt.Fprintln(os.Stderr, "usage: admin create-superadmin -email EMAIL -first-name NAME -last-name NAME [-force]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("create-superadmin", flag.ExitOnError)
+	email := flags.String("email", "", "the admin's email, used to sign in")
+	firstName := flags.String("first-name", "", "the admin's first name")
+	lastName := flags.String("last-name", "", "the admin's last name")
+	force := flags.Bool("force", false, "create the admin even if a super admin already exists")
+	flags.Parse(os.Args[2:])
+	if *email == "" || *firstName == "" || *lastName == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	cfg := config.Load()
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	if err := utils.ConfigurePasswords(utils.PasswordPolicy{
+		Scheme:            cfg.PasswordScheme,
+		Argon2Memory:      uint32(cfg.Argon2Memory),
+		Argon2Iterations:  uint32(cfg.Argon2Iterations),
+		Argon2Parallelism: uint8(cfg.Argon2Parallelism),
+		BcryptCost:        int(cfg.BcryptCost),
+	}); err != nil {
+		log.Fatal(err)
+	}
+
+	// Once there is a super admin, admins are added through the API, where
+	// it is audited
+	var superAdmins int64
+	if err := db.Model(&models.Admin{}).Where("role = ? AND is_active = ?", "super_admin", true).Count(&superAdmins).Error; err != nil {
+		log.Fatal(err)
+	}
+	if superAdmins > 0 && !*force {
+		log.Fatal("A super admin already exists; invite admins through the admin API, or pass -force")
+	}
+
+	var existing int64
+	db.Unscoped().Model(&models.Admin{}).Where("email = ?", *email).Count(&existing)
+	if existing > 0 {
+		log.Fatalf("An admin with email %s already exists", *email)
+	}
+
+	password, err := utils.GenerateTemporaryPassword()
+	if err != nil {
+		log.Fatal(err)
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	admin := models.Admin{
+		Email:              *email,
+		PasswordHash:       hash,
+		FirstName:          *firstName,
+		LastName:           *lastName,
+		Role:               "super_admin",
+		IsActive:           true,
+		MustChangePassword: true,
+	}
+	if err := db.Create(&admin).Error; err != nil {
+		log.Fatal("Failed to create admin:", err)
+	}
+
+	fmt.Printf("Created super admin %d (%s)\n", admin.ID, admin.Email)
+	fmt.Printf("Temporary password: %s\n", password)
+	fmt.Println("Sign in with POST /api/v1/admin/auth/login and set a new password with PUT /api/v1/admin/auth/password.")
+}
@@ -0,0 +1,37 @@
+// Command loadgen generates registration, swiping, and messaging traffic
+// against a running server and prints latency percentiles per operation.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ethiopia-dating-app/internal/loadtest"
+)
+
+func main() {
+	var cfg loadtest.Config
+	flag.StringVar(&cfg.BaseURL, "url", "http://localhost:8080", "server base URL")
+	flag.IntVar(&cfg.Users, "users", 50, "virtual users to register")
+	flag.Float64Var(&cfg.RegisterRate, "register-rate", 10, "registrations per second during ramp-up")
+	flag.Float64Var(&cfg.SwipeRate, "swipe-rate", 20, "swipes per second")
+	flag.Float64Var(&cfg.MessageRate, "message-rate", 5, "messages per second")
+	flag.DurationVar(&cfg.Duration, "duration", time.Minute, "how long to generate load after ramp-up")
+	flag.DurationVar(&cfg.Timeout, "timeout", 10*time.Second, "per-request timeout")
+	flag.Parse()
+
+	// Ctrl-C stops the run early but still prints the report
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stats, err := loadtest.Run(ctx, cfg)
+	stats.Report(os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,44 @@
+// Command partition-messages moves the messages table to one hash-partitioned
+// on conversation_id, copying existing rows in batches. Run it once, ideally
+// at a quiet time: the table is locked only for the final catch-up.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/database"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	partitions := flag.Int("partitions", 16, "number of hash partitions")
+	batchSize := flag.Int("batch", 5000, "messages copied per batch")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	cfg := config.Load()
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+
+	partitioned, err := database.MessagesPartitioned(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if partitioned {
+		log.Println("Messages are already partitioned")
+		return
+	}
+
+	if err := database.PartitionMessages(db, *partitions, *batchSize); err != nil {
+		log.Fatal("Partitioning failed:", err)
+	}
+}
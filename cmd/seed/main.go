@@ -0,0 +1,57 @@
+// Command seed fills a local database with fake users, likes, matches, and
+// conversations for demos and load tests. It refuses to run in release mode.
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/database"
+	"ethiopia-dating-app/internal/seed"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	users := flag.Int("users", 100, "number of users to create")
+	likes := flag.Int("likes", 10, "likes each user sends")
+	matchChance := flag.Float64("match-chance", 0.3, "chance that a like is returned")
+	randomSeed := flag.Int64("seed", 0, "random seed (default: current time)")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	cfg := config.Load()
+	if cfg.GinMode == "release" {
+		log.Fatal("Refusing to seed fake data with GIN_MODE=release")
+	}
+
+	if *randomSeed == 0 {
+		*randomSeed = time.Now().UnixNano()
+	}
+
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+
+	if err := database.SeedInterests(db); err != nil {
+		log.Fatal(err)
+	}
+
+	err = seed.Run(db, seed.Options{
+		Users:        *users,
+		LikesPerUser: *likes,
+		Seed:         *randomSeed,
+		MatchChance:  *matchChance,
+	})
+	if err != nil {
+		log.Fatal("Seeding failed:", err)
+	}
+	log.Printf("Done (seed %d)", *randomSeed)
+}
@@ -0,0 +1,77 @@
+// Command storage-cleanup checks stored media against the database and
+// deletes objects in the bucket that no row points at. It only lists them
+// unless run with -delete, and refuses to delete while some stored URLs
+// can't be matched to the bucket, since a changed storage endpoint would
+// otherwise make every file look orphaned.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/database"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	remove := flag.Bool("delete", false, "delete the orphaned objects instead of listing them")
+	force := flag.Bool("force", false, "delete even when some stored URLs point outside the bucket")
+	grace := flag.Duration("grace", 0, "leave objects younger than this alone (default STORAGE_ORPHAN_GRACE)")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	cfg := config.Load()
+	if *grace == 0 {
+		*grace = cfg.StorageOrphanGrace
+	}
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	storage, err := services.NewStorageService(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
+	ctx := context.Background()
+	audit, err := services.AuditStorage(ctx, db, storage, *grace)
+	if err != nil {
+		log.Fatal("Storage check failed:", err)
+	}
+	if err := db.Create(&audit.Report).Error; err != nil {
+		log.Printf("Failed to save the storage report: %v", err)
+	}
+
+	report := audit.Report
+	log.Printf("%d objects (%d bytes), %d referenced files: %d missing, %d unresolvable, %d placeholders; %d orphaned objects (%d bytes)",
+		report.ObjectCount, report.ObjectBytes, report.ReferenceCount, report.MissingCount, report.UnresolvableCount,
+		report.PlaceholderCount, report.OrphanedCount, report.OrphanedBytes)
+
+	if !*remove {
+		for _, object := range audit.Orphaned {
+			log.Printf("orphaned: %s (%d bytes, %s)", object.Key, object.Size, object.LastModified.Format("2006-01-02"))
+		}
+		return
+	}
+	if report.UnresolvableCount > 0 && !*force {
+		log.Fatalf("%d stored URLs don't point into the bucket; check the storage settings or rerun with -force", report.UnresolvableCount)
+	}
+
+	deleted := 0
+	for _, object := range audit.Orphaned {
+		if err := storage.DeleteObject(ctx, object.Key); err != nil {
+			log.Printf("Failed to delete %s: %v", object.Key, err)
+			continue
+		}
+		deleted++
+	}
+	log.Printf("Deleted %d of %d orphaned objects", deleted, len(audit.Orphaned))
+}
@@ -0,0 +1,9 @@
+// Command worker runs the background jobs without the HTTP server, so job
+// load can be scaled separately from API instances
+package main
+
+import "ethiopia-dating-app/internal/app"
+
+func main() {
+	app.Main(app.ModeWorker)
+}
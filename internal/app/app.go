@@ -0,0 +1,189 @@
+// Package app builds the application's dependency graph once at startup and
+// runs the parts a process is responsible for: the HTTP API, the background
+// jobs, or both.
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/database"
+	"ethiopia-dating-app/internal/jobs"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+	"ethiopia-dating-app/internal/websocket"
+
+	"gorm.io/gorm"
+)
+
+// Mode selects which parts of the application a process runs
+type Mode string
+
+const (
+	ModeAll    Mode = "all"    // API server and background jobs
+	ModeAPI    Mode = "api"    // API server only
+	ModeWorker Mode = "worker" // background jobs only
+)
+
+// App holds the shared dependencies. Everything a handler, job, or service
+// needs is built here so new services are wired in one place.
+type App struct {
+	Config           *config.Config
+	DB               *gorm.DB
+	Redis            *redis.Client
+	Hub              *websocket.Hub
+	SMS              services.SMSSender
+	IdentityVerifier services.IdentityVerifier
+	GeoIP            services.GeoLocator
+	Storage          *services.StorageService
+	Links            *services.DeepLinker
+	Activity         *services.ActivityRecorder
+	Telegram         services.TelegramBot
+	PhotoLabeler     services.PhotoLabeler
+	Translator       services.Translator
+	Captcha          services.CaptchaVerifier
+	Notifier         *services.Dispatcher
+	Mailer           services.Mailer
+}
+
+// New connects to the database and Redis and builds the services
+func New(cfg *config.Config) (*App, error) {
+	// The default secret is public, so anyone could mint tokens with it
+	if cfg.GinMode == "release" && cfg.JWTAlgorithm == "HS256" && cfg.JWTSecret == utils.DefaultJWTSecret {
+		return nil, fmt.Errorf("refusing to start in release mode with the default JWT_SECRET")
+	}
+	if err := utils.ConfigureJWT(utils.JWTSettings{
+		Algorithm:       cfg.JWTAlgorithm,
+		Secret:          cfg.JWTSecret,
+		PreviousSecrets: cfg.JWTPreviousSecrets,
+		PrivateKeyFile:  cfg.JWTPrivateKeyFile,
+		KeyID:           cfg.JWTKeyID,
+		JWKSFile:        cfg.JWTJWKSFile,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+
+	if err := utils.ConfigurePasswords(utils.PasswordPolicy{
+		Scheme:            cfg.PasswordScheme,
+		Argon2Memory:      uint32(cfg.Argon2Memory),
+		Argon2Iterations:  uint32(cfg.Argon2Iterations),
+		Argon2Parallelism: uint8(cfg.Argon2Parallelism),
+		BcryptCost:        int(cfg.BcryptCost),
+	}); err != nil {
+		return nil, err
+	}
+
+	geoIP, err := services.NewGeoLocator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := services.NewStorageService(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	redisClient, err := redis.Initialize(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	sms := services.NewSMSSender(cfg)
+	telegram := services.NewTelegramBot(cfg)
+	mailer := services.NewMailer(cfg)
+
+	return &App{
+		Config:           cfg,
+		DB:               db,
+		Redis:            redisClient,
+		Hub:              websocket.NewHub(redisClient),
+		SMS:              sms,
+		IdentityVerifier: services.NewIdentityVerifier(cfg),
+		GeoIP:            geoIP,
+		Storage:          storage,
+		Links:            services.NewDeepLinker(cfg),
+		Activity:         services.NewActivityRecorder(db),
+		Telegram:         telegram,
+		PhotoLabeler:     services.NewPhotoLabeler(cfg),
+		Translator:       services.CacheTranslations(redisClient, services.NewTranslator(cfg), cfg.TranslationCacheTTL),
+		Captcha:          services.NewCaptchaVerifier(cfg),
+		Notifier: services.NewDispatcher(db, cfg.NotificationChannels,
+			services.NewPushNotifier(db, services.NewPushSender(cfg)),
+			services.NewTelegramNotifier(db, telegram),
+			services.NewSMSNotifier(db, sms),
+			services.NewEmailNotifier(db, mailer),
+		),
+		Mailer: mailer,
+	}, nil
+}
+
+// Jobs lists the background jobs run in worker mode
+func (a *App) Jobs() []jobs.Job {
+	list := []jobs.Job{
+		jobs.DisappearingMessages(a.DB),
+		jobs.DateCheckIns(a.DB, a.SMS, a.Config.CheckInGracePeriod),
+		jobs.MessageStats(a.DB),
+		jobs.Suspensions(a.DB),
+		jobs.PausedAccounts(a.DB),
+		jobs.MatchMilestones(a.DB),
+		jobs.AnalyticsViews(a.DB),
+		jobs.StalePresence(a.Redis),
+		jobs.WeeklyDigests(a.DB, a.Redis, a.Notifier),
+		jobs.FirstMessageWarnings(a.DB, websocket.NewRelay(a.Redis)),
+		jobs.MatchFeedbackPrompts(a.DB, a.Notifier),
+		jobs.PublicStats(a.DB, a.Redis),
+		jobs.ReportSLAs(a.DB, a.Redis, a.Mailer, a.Config.AdminPanelURL),
+		jobs.PreferenceRevalidation(a.DB, a.Redis, a.Config.HideIncompatibleMatches),
+		jobs.FeatureWindowAnnouncements(a.DB, a.Notifier, a.Config.TimeZone),
+		jobs.StorageConsistency(a.DB, a.Storage, a.Config.StorageOrphanGrace),
+		jobs.TrustScores(a.DB, a.Config),
+		jobs.SuggestiveBios(a.DB),
+		jobs.PhotoLabelBackfill(a.DB, a.PhotoLabeler, a.Config.CleanModePhotoThreshold),
+		jobs.ExpiredDislikes(a.DB, a.Config.DislikeExpiry),
+	}
+	if a.Config.MessageRetention > 0 {
+		list = append(list, jobs.MessageRetention(a.DB, a.Storage, a.Config.MessageRetention))
+	}
+	return list
+}
+
+// Run starts the parts of the application selected by mode and blocks until
+// ctx is cancelled or the server fails
+func (a *App) Run(ctx context.Context, mode Mode) error {
+	switch mode {
+	case ModeAll, ModeAPI, ModeWorker:
+	default:
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+
+	if mode != ModeAPI {
+		jobs.Start(ctx, a.Redis, a.Jobs()...)
+	}
+	if mode == ModeWorker {
+		<-ctx.Done()
+		return nil
+	}
+
+	go a.Hub.Run()
+	go a.Hub.EnforceModeration(ctx, a.Redis)
+	go a.Hub.RelayEvents(ctx, a.Redis)
+	a.Activity.Start()
+	return NewServer(a).Run(ctx)
+}
+
+// Close saves pending activity and releases the database and Redis
+// connections
+func (a *App) Close() {
+	a.Activity.Stop()
+	if sqlDB, err := a.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	a.Redis.Close()
+}
@@ -0,0 +1,361 @@
+package app
+
+import (
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/handlers"
+	"ethiopia-dating-app/internal/middleware"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newRouter registers every route on a new engine
+func newRouter(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, h *Handlers, hub *websocket.Hub, activity *services.ActivityRecorder) *gin.Engine {
+	router := gin.Default()
+	if cfg.MultipartMemory > 0 {
+		router.MaxMultipartMemory = cfg.MultipartMemory
+	}
+
+	// CORS middleware
+	router.Use(middleware.CORS())
+
+	// Report bursts of server errors to the admin operations feed
+	router.Use(middleware.ErrorRate(redisClient, cfg.OpsErrorSpikeThreshold))
+
+	// Bound every request, and the queries it makes, by REQUEST_TIMEOUT.
+	// Upload routes get STORAGE_TIMEOUT on top for each upload they wait on
+	// in turn, so slow links aren't cut off partway through storing files.
+	uploadTimeout := func(uploads int) time.Duration {
+		return cfg.RequestTimeout + time.Duration(uploads)*cfg.StorageTimeout
+	}
+	router.Use(middleware.Timeout(cfg.RequestTimeout, map[string]time.Duration{
+		"/api/v1/users/profile/photo":                  uploadTimeout(1),
+		"/api/v1/users/profile/photos":                 uploadTimeout(handlers.PhotoUploadRounds),
+		"/api/v1/users/verification/identity/document": uploadTimeout(1),
+		"/api/v1/users/tickets":                        uploadTimeout(handlers.MaxTicketAttachments),
+	}))
+
+	// Upload routes may carry their files plus some form overhead; everything
+	// else is held to MAX_BODY_SIZE
+	const multipartOverhead = 1024 * 1024
+	router.Use(middleware.BodyLimit(cfg.MaxBodySize, map[string]int64{
+		"/api/v1/users/profile/photo":                  cfg.MaxFileSize + multipartOverhead,
+		"/api/v1/users/profile/photos":                 handlers.MaxPhotosPerUpload*cfg.MaxFileSize + multipartOverhead,
+		"/api/v1/users/verification/identity/document": cfg.MaxFileSize + multipartOverhead,
+		"/api/v1/users/tickets":                        handlers.MaxTicketAttachments*cfg.MaxFileSize + multipartOverhead,
+	}))
+
+	// Health check
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	// Public keys for services that verify access tokens themselves
+	router.GET("/.well-known/jwks.json", h.App.GetJWKS)
+
+	// Everything but admin routes, app config, health and the JWKS answers
+	// 503 while maintenance mode is on
+	maintenance := middleware.Maintenance(redisClient, cfg)
+
+	// Shareable profile and deep links
+	router.GET("/u/:handle", maintenance, h.User.GetPublicProfile)
+	router.GET("/l/:kind/:id", maintenance, h.Link.ResolveLink)
+
+	// Users waiting for their city to launch can't discover or match
+	notWaitlisted := middleware.NotWaitlisted(db)
+
+	// Nor can users who haven't finished onboarding discover anyone
+	onboarded := middleware.Onboarded(db)
+
+	// API v1 routes
+	v1 := router.Group("/api/v1")
+	{
+		// App config is registered before the version gate so outdated
+		// clients can still learn that they need to upgrade
+		v1.GET("/app-config", h.App.GetAppConfig)
+		v1.GET("/stats/public", maintenance, h.App.GetPublicStats)
+		v1.Use(middleware.MinClientVersion(cfg.MinClientVersion))
+		v1.Use(maintenance)
+
+		// Authentication routes
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/register", middleware.Activity(activity, "register"), h.Auth.Register)
+			auth.POST("/login", middleware.Activity(activity, "login"), h.Auth.Login)
+			auth.POST("/verify-otp", h.Auth.VerifyOTP)
+			auth.POST("/resend-otp", h.Auth.ResendOTP)
+			auth.POST("/refresh", h.Auth.RefreshToken)
+			auth.POST("/logout", middleware.AuthRequired(redisClient), middleware.Activity(activity, "logout"), h.Auth.Logout)
+			auth.GET("/appeal", middleware.AppealAuthRequired(), h.Auth.GetAppeal)
+			auth.POST("/appeal", middleware.AppealAuthRequired(), h.Auth.SubmitAppeal)
+		}
+
+		// User routes
+		users := v1.Group("/users")
+		users.Use(middleware.AuthRequired(redisClient), middleware.Presence(redisClient))
+		{
+			users.GET("/profile", h.User.GetProfile)
+			users.GET("/activity", h.User.GetActivity)
+			users.POST("/heartbeat", h.User.Heartbeat)
+			users.GET("/username/available", h.User.CheckUsername)
+			users.PUT("/username", h.User.SetUsername)
+			users.GET("/stats", h.User.GetStats)
+			users.GET("/digest/latest", h.User.GetLatestDigest)
+			users.GET("/events", h.User.GetEvents)
+			users.PUT("/profile", middleware.Activity(activity, "profile_update"), h.User.UpdateProfile)
+			users.GET("/settings", h.User.GetSettings)
+			users.PUT("/settings", h.User.UpdateSettings)
+			users.PUT("/settings/pause", h.User.PauseAccount)
+			users.GET("/settings/notifications", h.User.GetNotificationPreferences)
+			users.PUT("/settings/notifications", h.User.UpdateNotificationPreferences)
+			users.POST("/push-tokens", h.User.RegisterPushToken)
+			users.DELETE("/push-tokens", h.User.DeletePushToken)
+			users.GET("/profile/bios", h.User.GetBios)
+			users.PUT("/profile/bios/:language", middleware.Activity(activity, "profile_update"), h.User.PutBio)
+			users.DELETE("/profile/bios/:language", h.User.DeleteBio)
+			users.GET("/bio/:user_id", h.User.GetBio)
+			users.PUT("/profile/prompts", middleware.Activity(activity, "profile_update"), h.User.UpdatePrompts)
+			users.POST("/profile/photo", middleware.Activity(activity, "photo_upload"), h.User.UploadPhoto)
+			users.POST("/profile/photos", middleware.Activity(activity, "photo_upload"), h.User.UploadPhotos)
+			users.DELETE("/profile/photo/:id", middleware.Activity(activity, "photo_delete"), h.User.DeletePhoto)
+			users.PUT("/profile/photo/:id/caption", h.User.UpdatePhotoCaption)
+			users.PUT("/profile/photo/:id/privacy", h.User.UpdatePhotoPrivacy)
+			users.GET("/discover", notWaitlisted, onboarded, h.User.DiscoverUsers)
+			users.POST("/discover/deck", notWaitlisted, onboarded, h.User.CreateDeck)
+			users.GET("/discover/deck/next", notWaitlisted, onboarded, h.User.GetNextDeckCards)
+			users.GET("/discover/active-nearby", notWaitlisted, onboarded, h.User.GetActiveNearby)
+			users.GET("/favorites", h.User.GetFavorites)
+			users.POST("/favorites/:user_id", h.User.AddToFavorites)
+			users.DELETE("/favorites/:user_id", h.User.RemoveFromFavorites)
+			users.POST("/block/:user_id", h.User.BlockUser)
+			users.DELETE("/block/:user_id", h.User.UnblockUser)
+			users.POST("/block/phone", h.User.BlockByPhone)
+			users.GET("/blocked", h.User.GetBlockedUsers)
+			users.POST("/blocked/unblock", h.User.BulkUnblock)
+			users.POST("/report", middleware.Activity(activity, "report_filed"), h.User.ReportUser)
+			users.GET("/verification", h.Auth.GetVerificationStatus)
+			users.POST("/verification/email", h.Auth.SendEmailVerification)
+			users.POST("/verification/email/confirm", h.Auth.ConfirmEmailVerification)
+			users.POST("/verification/phone", h.Auth.SendPhoneVerification)
+			users.POST("/verification/phone/confirm", h.Auth.ConfirmPhoneVerification)
+			users.GET("/verification/identity", h.Verification.GetIdentityVerification)
+			users.POST("/verification/identity/document", middleware.Activity(activity, "identity_submitted"), h.Verification.SubmitIdentityDocument)
+			users.POST("/verification/identity/fayda", middleware.Activity(activity, "identity_submitted"), h.Verification.SubmitFaydaVerification)
+			users.GET("/badges", h.Verification.GetBadges)
+			users.POST("/badges/student", h.Verification.RequestStudentBadge)
+			users.POST("/badges/student/confirm", h.Verification.ConfirmStudentBadge)
+			users.POST("/badges/professional", h.Verification.RequestProfessionalBadge)
+			users.PUT("/badges/:kind", h.Verification.UpdateBadge)
+			users.DELETE("/badges/:kind", h.Verification.DeleteBadge)
+			users.GET("/free-features", h.User.GetFreeFeatures)
+			users.POST("/contacts/sync", h.User.SyncContacts)
+			users.DELETE("/contacts", h.User.DeleteContacts)
+			users.POST("/telegram/link", h.Telegram.CreateTelegramLink)
+			users.GET("/telegram", h.Telegram.GetTelegramLink)
+			users.DELETE("/telegram", h.Telegram.UnlinkTelegram)
+			users.GET("/waitlist", h.User.GetWaitlistStatus)
+			users.GET("/onboarding", h.User.GetOnboarding)
+			users.POST("/tickets", h.Support.CreateTicket)
+			users.GET("/tickets", h.Support.GetTickets)
+			users.GET("/tickets/:id", h.Support.GetTicket)
+			users.POST("/tickets/:id/replies", h.Support.ReplyToTicket)
+		}
+
+		// Matching routes
+		matches := v1.Group("/matches")
+		matches.Use(middleware.AuthRequired(redisClient), middleware.Presence(redisClient))
+		{
+			matches.POST("/like/:user_id", notWaitlisted, h.Match.LikeUser)
+			matches.GET("/likes", h.Match.GetLikesReceived)
+			matches.POST("/compliment/:user_id", notWaitlisted, h.Match.SendCompliment)
+			matches.GET("/compliments", h.Match.GetCompliments)
+			matches.POST("/compliments/:id/accept", h.Match.AcceptCompliment)
+			matches.POST("/compliments/:id/dismiss", h.Match.DismissCompliment)
+			matches.POST("/dislike/:user_id", notWaitlisted, h.Match.DislikeUser)
+			matches.GET("/challenge", h.Match.GetSwipeChallenge)
+			matches.POST("/challenge", h.Match.SolveSwipeChallenge)
+			matches.GET("/", h.Match.GetMatches)
+			matches.DELETE("/:match_id", h.Match.Unmatch)
+			matches.GET("/unmatches", h.Match.GetUnmatches)
+			matches.POST("/unmatches/:id/second-chance", h.Match.GiveSecondChance)
+			matches.POST("/:match_id/feedback", h.Match.SubmitMatchFeedback)
+			matches.GET("/:match_id/keys", h.Message.GetMatchKeyBundle)
+			matches.GET("/:match_id/date-suggestions", h.Message.GetDateSuggestions)
+			matches.GET("/:match_id/private-photos", h.Match.GetPrivateAlbum)
+			matches.POST("/:match_id/private-photos/grant", h.Match.GrantPrivateAlbum)
+			matches.DELETE("/:match_id/private-photos/grant", h.Match.RevokePrivateAlbum)
+			matches.GET("/private-photos/access", h.Match.GetPrivateAlbumAccess)
+		}
+
+		// Messaging routes
+		messages := v1.Group("/messages")
+		messages.Use(middleware.AuthRequired(redisClient), middleware.Presence(redisClient))
+		{
+			messages.GET("/conversations", h.Message.GetConversations)
+			messages.GET("/conversations/:conversation_id", h.Message.GetMessages)
+			messages.POST("/conversations/:conversation_id", h.Message.SendMessage)
+			messages.PUT("/conversations/:conversation_id/read", h.Message.MarkAsRead)
+			messages.GET("/conversations/:conversation_id/export", h.Message.ExportConversation)
+			messages.POST("/conversations/:conversation_id/restore", h.Message.RestoreArchivedMessages)
+			messages.PUT("/conversations/:conversation_id/disappearing", h.Message.SetDisappearingMessages)
+			messages.POST("/conversations/:conversation_id/polls", h.Message.CreatePoll)
+			messages.POST("/polls/:poll_id/vote", h.Message.VotePoll)
+			messages.POST("/conversations/:conversation_id/date-suggestions", h.Message.SuggestDate)
+			messages.PUT("/date-suggestions/:suggestion_id", h.Message.RespondDateSuggestion)
+			messages.GET("/exports/:job_id", h.Message.GetExport)
+			messages.PUT("/conversations/:conversation_id/encryption", h.Message.EnableEncryption)
+			messages.GET("/keys", h.Message.GetKeyStatus)
+			messages.PUT("/keys", h.Message.PublishKeys)
+		}
+
+		// Multi-device sync
+		v1.GET("/sync/messages", middleware.AuthRequired(redisClient), middleware.Presence(redisClient), h.Message.SyncMessages)
+
+		// Safety routes
+		safety := v1.Group("/safety")
+		safety.Use(middleware.AuthRequired(redisClient), middleware.Presence(redisClient))
+		{
+			safety.GET("/contacts", h.Safety.GetTrustedContacts)
+			safety.POST("/contacts", h.Safety.AddTrustedContact)
+			safety.DELETE("/contacts/:id", h.Safety.DeleteTrustedContact)
+			safety.GET("/dates", h.Safety.GetDatePlans)
+			safety.POST("/dates", h.Safety.CreateDatePlan)
+			safety.PUT("/dates/:id/complete", h.Safety.CompleteDatePlan)
+			safety.PUT("/dates/:id/cancel", h.Safety.CancelDatePlan)
+		}
+
+		// Room routes
+		rooms := v1.Group("/rooms")
+		rooms.Use(middleware.AuthRequired(redisClient), middleware.Presence(redisClient))
+		{
+			rooms.GET("/", h.Room.GetRooms)
+			rooms.POST("/:room_id/join", h.Room.JoinRoom)
+			rooms.POST("/:room_id/leave", h.Room.LeaveRoom)
+			rooms.GET("/:room_id/messages", h.Room.GetRoomMessages)
+			rooms.POST("/:room_id/messages", h.Room.SendRoomMessage)
+		}
+
+		// Feedback routes
+		v1.GET("/feedback/reasons", middleware.AuthRequired(redisClient), h.User.GetFeedbackReasons)
+		v1.POST("/feedback", middleware.AuthRequired(redisClient), h.User.SubmitFeedback)
+
+		// Deep links; resolving needs no session
+		v1.POST("/links", middleware.AuthRequired(redisClient), h.Link.CreateLink)
+		v1.GET("/links/:kind/:id", h.Link.ResolveLink)
+
+		// Telegram bot updates, authenticated by the webhook secret
+		v1.POST("/telegram/webhook", h.Telegram.Webhook)
+
+		// Search routes
+		v1.GET("/search", middleware.AuthRequired(redisClient), notWaitlisted, onboarded, h.Search.Search)
+
+		// WebSocket endpoint
+		v1.GET("/ws", middleware.AuthRequired(redisClient), h.Message.ConnectWebSocket)
+
+		// Admin sign-in, with credentials and tokens of their own
+		adminAuth := v1.Group("/admin/auth")
+		{
+			adminAuth.POST("/login", h.Admin.AdminLogin)
+			adminAuth.PUT("/password", middleware.AdminAuthRequired(), h.Admin.ChangeAdminPassword)
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AdminAuthRequired(), middleware.AdminRequired(db))
+		{
+			admin.GET("/admins", middleware.RoleRequired("super_admin"), h.Admin.GetAdmins)
+			admin.POST("/admins", middleware.RoleRequired("super_admin"), h.Admin.CreateAdmin)
+			admin.PUT("/admins/:id/role", middleware.RoleRequired("super_admin"), h.Admin.UpdateAdminRole)
+			admin.PUT("/admins/:id/status", middleware.RoleRequired("super_admin"), h.Admin.UpdateAdminStatus)
+			admin.GET("/users", h.Admin.GetUsers)
+			admin.GET("/users/:id", h.Admin.GetUser)
+			admin.GET("/users/:id/discovery-preview", h.Admin.GetDiscoveryPreview)
+			admin.GET("/users/:id/trust", h.Admin.GetUserTrust)
+			admin.POST("/users/:id/trust/recompute", h.Admin.RecomputeUserTrust)
+			admin.PUT("/users/:id/status", h.Admin.UpdateUserStatus)
+			admin.POST("/users/bulk-action", h.Admin.BulkUserAction)
+			admin.POST("/users/:id/merge", h.Admin.MergeUsers)
+			admin.PUT("/users/:id/photo-verification", h.Admin.SetPhotoVerification)
+			admin.GET("/reports", h.Admin.GetReports)
+			admin.PUT("/languages", h.Admin.UpdateLanguages)
+			admin.PUT("/reports/:id/status", h.Admin.UpdateReportStatus)
+			admin.POST("/reports/:id/claim", h.Admin.ClaimReport)
+			admin.POST("/reports/:id/release", h.Admin.ReleaseReport)
+			admin.POST("/reports/bulk-action", h.Admin.BulkReportAction)
+			admin.GET("/analytics", h.Admin.GetAnalytics)
+			admin.GET("/match-feedback", h.Admin.GetMatchFeedback)
+			admin.POST("/rooms", h.Room.CreateRoom)
+			admin.GET("/date-spots", h.Admin.GetDateSpots)
+			admin.POST("/date-spots", h.Admin.CreateDateSpot)
+			admin.PUT("/date-spots/:id", h.Admin.UpdateDateSpot)
+			admin.DELETE("/date-spots/:id", h.Admin.DeleteDateSpot)
+			admin.GET("/feature-windows", h.Admin.GetFeatureWindows)
+			admin.POST("/feature-windows", h.Admin.CreateFeatureWindow)
+			admin.PUT("/feature-windows/:id", h.Admin.UpdateFeatureWindow)
+			admin.DELETE("/feature-windows/:id", h.Admin.DeleteFeatureWindow)
+			admin.GET("/storage/report", h.Admin.GetStorageReport)
+			admin.GET("/users/:id/strikes", h.Admin.GetUserStrikes)
+			admin.POST("/users/:id/strikes", h.Admin.AddStrike)
+			admin.GET("/users/:id/deliveries", h.Admin.GetNotificationDeliveries)
+			admin.DELETE("/strikes/:id", h.Admin.RemoveStrike)
+			admin.GET("/appeals", h.Admin.GetAppeals)
+			admin.PUT("/appeals/:id/decision", h.Admin.DecideAppeal)
+			admin.GET("/underage", h.Admin.GetUnderageFlags)
+			admin.GET("/abuse-flags", h.Admin.GetAbuseFlags)
+			admin.PUT("/abuse-flags/:id/review", h.Admin.ReviewAbuseFlag)
+			admin.PUT("/underage/:id/decision", h.Admin.DecideUnderageFlag)
+			admin.GET("/verifications", h.Admin.GetIdentityVerifications)
+			admin.PUT("/verifications/:id/decision", h.Admin.DecideIdentityVerification)
+			admin.GET("/badges", h.Admin.GetBadgeRequests)
+			admin.PUT("/badges/:id/decision", h.Admin.DecideBadge)
+			admin.GET("/photos/pending", h.Admin.GetPendingPhotos)
+			admin.PUT("/photos/:id/decision", h.Admin.DecidePhoto)
+			admin.GET("/name-changes", h.Admin.GetPendingNameChanges)
+			admin.PUT("/name-changes/:id/decision", h.Admin.DecideNameChange)
+			admin.GET("/launch/cities", h.Admin.GetLaunchCities)
+			admin.PUT("/launch/cities", h.Admin.UpdateLaunchCity)
+			admin.GET("/launch/regions", h.Admin.GetLaunchRegions)
+			admin.PUT("/launch/regions/:region", h.Admin.UpdateLaunchRegion)
+			admin.PUT("/launch/regions/:region/dislike-expiry", h.Admin.UpdateRegionDislikeExpiry)
+			admin.GET("/system-messages", h.Admin.GetSystemMessages)
+			admin.PUT("/system-messages/:kind", h.Admin.UpdateSystemMessage)
+			admin.DELETE("/system-messages/:kind", h.Admin.ResetSystemMessage)
+			admin.GET("/tickets", middleware.RoleRequired("support", "super_admin"), h.Support.GetSupportTickets)
+			admin.GET("/tickets/:id", middleware.RoleRequired("support", "super_admin"), h.Support.GetSupportTicket)
+			admin.POST("/tickets/:id/replies", middleware.RoleRequired("support", "super_admin"), h.Support.AnswerTicket)
+			admin.PUT("/tickets/:id/status", middleware.RoleRequired("support", "super_admin"), h.Support.UpdateTicketStatus)
+			admin.GET("/maintenance", h.Admin.GetMaintenance)
+			admin.PUT("/maintenance", h.Admin.SetMaintenance)
+			admin.GET("/messages/search", middleware.RoleRequired("super_admin"), h.Admin.SearchMessages)
+			admin.GET("/messages/held", h.Message.GetHeldMessages)
+			admin.POST("/messages/:id/release", h.Message.ReleaseHeldMessage)
+			admin.POST("/messages/:id/reject", h.Message.RejectHeldMessage)
+			admin.GET("/ops/ws", func(c *gin.Context) {
+				websocket.HandleOpsFeed(redisClient, c)
+			})
+			admin.GET("/api-keys", middleware.RoleRequired("super_admin"), h.Admin.GetAPIKeys)
+			admin.POST("/api-keys", middleware.RoleRequired("super_admin"), h.Admin.CreateAPIKey)
+			admin.PUT("/api-keys/:id", middleware.RoleRequired("super_admin"), h.Admin.UpdateAPIKey)
+			admin.DELETE("/api-keys/:id", middleware.RoleRequired("super_admin"), h.Admin.RevokeAPIKey)
+		}
+	}
+
+	// Partner API, for approved integrations holding an API key. It sits
+	// outside /api/v1 so the mobile client version gate doesn't apply.
+	partner := router.Group("/partner/v1")
+	partner.Use(maintenance, middleware.APIKeyRequired(db, redisClient))
+	{
+		partner.GET("/events", middleware.ScopeRequired(models.ScopeEventsWrite), h.Partner.GetEvents)
+		partner.POST("/events", middleware.ScopeRequired(models.ScopeEventsWrite), h.Partner.CreateEvent)
+		partner.DELETE("/events/:id", middleware.ScopeRequired(models.ScopeEventsWrite), h.Partner.DeleteEvent)
+		partner.GET("/stats", middleware.ScopeRequired(models.ScopeStatsRead), h.Partner.GetStats)
+	}
+
+	return router
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"ethiopia-dating-app/internal/config"
+
+	"github.com/joho/godotenv"
+)
+
+// Main is the shared entry point of the server and worker binaries. The -mode
+// flag overrides defaultMode.
+func Main(defaultMode Mode) {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Load configuration
+	cfg := config.Load()
+
+	mode := defaultMode
+	flag.Func("mode", "what to run: all, api, or worker", func(value string) error {
+		mode = Mode(value)
+		return nil
+	})
+	flag.Parse()
+
+	application, err := New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer application.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Running in %s mode", mode)
+	if err := application.Run(ctx, mode); err != nil {
+		log.Fatal("Stopped:", err)
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+const shutdownTimeout = 15 * time.Second
+
+// Handlers are the HTTP handlers the router is built from
+type Handlers struct {
+	Auth         *handlers.AuthHandler
+	User         *handlers.UserHandler
+	Match        *handlers.MatchHandler
+	Message      *handlers.MessageHandler
+	Admin        *handlers.AdminHandler
+	Search       *handlers.SearchHandler
+	Safety       *handlers.SafetyHandler
+	Room         *handlers.RoomHandler
+	App          *handlers.AppHandler
+	Verification *handlers.VerificationHandler
+	Link         *handlers.LinkHandler
+	Telegram     *handlers.TelegramHandler
+	Partner      *handlers.PartnerHandler
+	Support      *handlers.SupportHandler
+}
+
+// NewHandlers builds every handler from the app's dependencies
+func NewHandlers(a *App) *Handlers {
+	// Conversation joins over the WebSocket get the same check as REST access
+	a.Hub.AuthorizeJoins(handlers.ConversationAccessChecker(a.DB, a.Redis))
+	a.Hub.TrackMutes(handlers.MuteChecker(a.DB))
+	a.Hub.ReportAbuse(handlers.WebSocketAbuseReporter(a.DB, a.Redis))
+
+	return &Handlers{
+		Auth:         handlers.NewAuthHandler(a.DB, a.Redis, a.Config, a.GeoIP, a.Telegram, a.Notifier, a.SMS, a.Mailer),
+		User:         handlers.NewUserHandler(a.DB, a.Redis, a.Config, a.PhotoLabeler, a.Translator, a.Storage, a.GeoIP),
+		Match:        handlers.NewMatchHandler(a.DB, a.Redis, a.Config, a.Hub, a.Notifier, a.Captcha, a.Storage),
+		Message:      handlers.NewMessageHandler(a.DB, a.Redis, a.Config, a.Hub, a.Notifier, a.Storage),
+		Admin:        handlers.NewAdminHandler(a.DB, a.Redis, a.Config, a.Mailer, a.Storage),
+		Search:       handlers.NewSearchHandler(a.DB, a.Redis, a.Config),
+		Safety:       handlers.NewSafetyHandler(a.DB, a.Redis, a.Config, a.SMS),
+		Room:         handlers.NewRoomHandler(a.DB, a.Redis, a.Config, a.Hub),
+		App:          handlers.NewAppHandler(a.DB, a.Redis, a.Config),
+		Verification: handlers.NewVerificationHandler(a.DB, a.Redis, a.Config, a.IdentityVerifier, a.Mailer, a.Storage),
+		Link:         handlers.NewLinkHandler(a.DB, a.Redis, a.Config, a.Links, a.Storage),
+		Telegram:     handlers.NewTelegramHandler(a.DB, a.Redis, a.Config, a.Telegram),
+		Partner:      handlers.NewPartnerHandler(a.DB, a.Redis, a.Config),
+		Support:      handlers.NewSupportHandler(a.DB, a.Redis, a.Config, a.Notifier, a.Storage),
+	}
+}
+
+// Server is the HTTP API. Handler exposes the router so it can be driven
+// with httptest without opening a port.
+type Server struct {
+	addr   string
+	router *gin.Engine
+}
+
+func NewServer(a *App) *Server {
+	return &Server{
+		addr:   ":" + a.Config.Port,
+		router: newRouter(a.Config, a.DB, a.Redis, NewHandlers(a), a.Hub, a.Activity),
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// Run serves until ctx is cancelled, then lets in-flight requests finish
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{Addr: s.addr, Handler: s.router}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on %s", s.addr)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
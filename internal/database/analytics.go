@@ -0,0 +1,38 @@
+package database
+
+import "gorm.io/gorm"
+
+// analyticsViews are the materialized views behind the admin analytics
+// endpoint. jobs.AnalyticsViews refreshes them; each row carries the time of
+// the refresh that produced it.
+var analyticsViews = []string{
+	`CREATE MATERIALIZED VIEW IF NOT EXISTS analytics_activity AS
+	SELECT
+		(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL) AS total_users,
+		(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND last_seen > NOW() - INTERVAL '7 days') AS active_users,
+		(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND created_at >= CURRENT_DATE) AS new_users_today,
+		(SELECT COUNT(*) FROM matches WHERE deleted_at IS NULL AND is_active) AS total_matches,
+		(SELECT COUNT(*) FROM matches WHERE deleted_at IS NULL AND is_active AND created_at >= CURRENT_DATE) AS matches_today,
+		(SELECT COUNT(*) FROM messages WHERE deleted_at IS NULL) AS total_messages,
+		(SELECT COUNT(*) FROM messages WHERE deleted_at IS NULL AND created_at >= CURRENT_DATE) AS messages_today,
+		NOW() AS computed_at`,
+	`CREATE MATERIALIZED VIEW IF NOT EXISTS analytics_daily_registrations AS
+	SELECT DATE(created_at) AS date, COUNT(*) AS count, NOW() AS computed_at
+	FROM users
+	WHERE deleted_at IS NULL AND created_at >= CURRENT_DATE - INTERVAL '30 days'
+	GROUP BY DATE(created_at)`,
+	`CREATE MATERIALIZED VIEW IF NOT EXISTS analytics_gender_distribution AS
+	SELECT gender, COUNT(*) AS count, NOW() AS computed_at
+	FROM users
+	WHERE deleted_at IS NULL
+	GROUP BY gender`,
+}
+
+func createAnalyticsViews(db *gorm.DB) error {
+	for _, view := range analyticsViews {
+		if err := db.Exec(view).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -48,8 +49,17 @@ func migrate(db *gorm.DB) error {
 		log.Printf("Warning: Could not create uuid-ossp extension: %v", err)
 	}
 
+	// Preferences saved before the weekly digest and SMS existed get their
+	// defaults. The columns have to exist before AutoMigrate, which can't add
+	// them as not null.
+	for _, column := range []string{"weekly_digest boolean NOT NULL DEFAULT true", "sms boolean NOT NULL DEFAULT false"} {
+		if err := db.Exec("ALTER TABLE IF EXISTS notification_preferences ADD COLUMN IF NOT EXISTS " + column).Error; err != nil {
+			return err
+		}
+	}
+
 	// Auto-migrate all models
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&models.User{},
 		&models.ProfilePhoto{},
 		&models.Interest{},
@@ -67,7 +77,107 @@ func migrate(db *gorm.DB) error {
 		&models.Notification{},
 		&models.Admin{},
 		&models.UserActivity{},
-	)
+		&models.AdminAuditLog{},
+		&models.TrustedContact{},
+		&models.DatePlan{},
+		&models.PhoneBlock{},
+		&models.MessageStats{},
+		&models.ContactHash{},
+		&models.Room{},
+		&models.RoomMember{},
+		&models.RoomMessage{},
+		&models.Poll{},
+		&models.PollOption{},
+		&models.PollVote{},
+		&models.Feedback{},
+		&models.Strike{},
+		&models.MatchMilestone{},
+		&models.NotificationPreference{},
+		&models.IdentityVerification{},
+		&models.ProfilePrompt{},
+		&models.TelegramLink{},
+		&models.MessageTombstone{},
+		&models.SyncDevice{},
+		&models.KeyBundle{},
+		&models.OneTimePreKey{},
+		&models.NameChangeRequest{},
+		&models.LaunchCity{},
+		&models.Waitlist{},
+		&models.WeeklyDigest{},
+		&models.PushToken{},
+		&models.NotificationDelivery{},
+		&models.APIKey{},
+		&models.Event{},
+		&models.SystemMessageTemplate{},
+		&models.AbuseFlag{},
+		&models.ConversationArchive{},
+		&models.MatchFeedback{},
+		&models.SupportTicket{},
+		&models.SupportReply{},
+		&models.Device{},
+		&models.OnboardingProgress{},
+		&models.ProfileBio{},
+		&models.Compliment{},
+		&models.DateSpot{},
+		&models.DateSuggestion{},
+		&models.Badge{},
+		&models.FeatureWindow{},
+		&models.StorageReport{},
+		&models.Unmatch{},
+		&models.Appeal{},
+		&models.PrivateAlbumGrant{},
+		&models.PrivatePhotoAccess{},
+		&models.LockFence{},
+	); err != nil {
+		return err
+	}
+
+	// Backfill phone hashes for users registered before hashing existed
+	if err := db.Exec("UPDATE users SET phone_hash = encode(sha256(phone::bytea), 'hex') WHERE phone IS NOT NULL AND phone_hash IS NULL").Error; err != nil {
+		return err
+	}
+
+	// Photos uploaded before alt text existed get the generic description
+	if err := db.Exec("UPDATE profile_photos SET alt_text = 'Profile photo' WHERE alt_text IS NULL OR alt_text = ''").Error; err != nil {
+		return err
+	}
+
+	// Accounts verified before channels were tracked confirmed their email
+	if err := db.Exec("UPDATE users SET email_verified_at = created_at WHERE is_verified AND email_verified_at IS NULL AND phone_verified_at IS NULL").Error; err != nil {
+		return err
+	}
+
+	// Blocks that phone blocks placed on their owners' accounts before they were
+	// marked as such
+	if err := db.Exec(`UPDATE blocked_users SET via_phone = true WHERE NOT via_phone AND EXISTS (
+		SELECT 1 FROM phone_blocks JOIN users ON users.phone_hash = phone_blocks.phone_hash
+		WHERE phone_blocks.blocker_id = blocked_users.blocker_id AND users.id = blocked_users.blocked_id)`).Error; err != nil {
+		return err
+	}
+
+	if err := migrateGenders(db); err != nil {
+		return err
+	}
+
+	return createAnalyticsViews(db)
+}
+
+// migrateGenders rewrites gender values stored before the gender list was
+// fixed and gives users without a preference the default of everyone
+func migrateGenders(db *gorm.DB) error {
+	var genders []string
+	if err := db.Model(&models.User{}).Distinct().Pluck("gender", &genders).Error; err != nil {
+		return err
+	}
+	for _, gender := range genders {
+		if normalized := utils.NormalizeGender(gender); normalized != gender {
+			if err := db.Model(&models.User{}).Where("gender = ?", gender).Update("gender", normalized).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return db.Model(&models.User{}).Where("seeking IS NULL OR seeking = ''").Update("seeking", "everyone").Error
 }
 
 func SeedInterests(db *gorm.DB) error {
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Messages are hash-partitioned on conversation_id: nearly every query reads
+// or writes one conversation, so it touches a single partition, and
+// archiving a conversation deletes from one partition only
+const (
+	partitionedMessages   = "messages_partitioned"
+	unpartitionedMessages = "messages_unpartitioned"
+)
+
+// MessagesPartitioned reports whether the messages table is partitioned
+func MessagesPartitioned(db *gorm.DB) (bool, error) {
+	var partitioned bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = to_regclass('messages'))").
+		Scan(&partitioned).Error
+	return partitioned, err
+}
+
+// PartitionMessages moves the messages table to one hash-partitioned on
+// conversation_id with the given number of partitions. Rows are copied in
+// batches of batchSize while the app keeps running; only the final catch-up
+// and rename lock the table. The old table is kept as messages_unpartitioned
+// to be dropped once the new one is checked.
+func PartitionMessages(db *gorm.DB, partitions, batchSize int) error {
+	if partitions < 2 {
+		return fmt.Errorf("need at least 2 partitions, got %d", partitions)
+	}
+	if partitioned, err := MessagesPartitioned(db); err != nil || partitioned {
+		return err
+	}
+
+	var sequence string
+	if err := db.Raw("SELECT pg_get_serial_sequence('messages', 'id')").Scan(&sequence).Error; err != nil {
+		return err
+	}
+
+	// A table left by an interrupted run may have missed changes, so start over
+	if err := db.Exec("DROP TABLE IF EXISTS " + partitionedMessages).Error; err != nil {
+		return err
+	}
+	if err := db.Exec(fmt.Sprintf(`CREATE TABLE %s (LIKE messages INCLUDING DEFAULTS, PRIMARY KEY (conversation_id, id))
+		PARTITION BY HASH (conversation_id)`, partitionedMessages)).Error; err != nil {
+		return err
+	}
+	for i := 0; i < partitions; i++ {
+		if err := db.Exec(fmt.Sprintf("CREATE TABLE messages_p%d PARTITION OF %s FOR VALUES WITH (MODULUS %d, REMAINDER %d)",
+			i, partitionedMessages, partitions, i)).Error; err != nil {
+			return err
+		}
+	}
+
+	// Changes made to copied rows during the backfill are caught up at the end
+	var startedAt string
+	if err := db.Raw("SELECT NOW()::text").Scan(&startedAt).Error; err != nil {
+		return err
+	}
+
+	lastID, copied := uint(0), 0
+	for {
+		var batch struct {
+			LastID uint
+			Count  int
+		}
+		if err := db.Raw(fmt.Sprintf(`WITH copied AS (
+				INSERT INTO %s SELECT * FROM messages WHERE id > ? ORDER BY id LIMIT ? RETURNING id
+			)
+			SELECT COALESCE(MAX(id), 0) AS last_id, COUNT(*) AS count FROM copied`, partitionedMessages),
+			lastID, batchSize).Scan(&batch).Error; err != nil {
+			return err
+		}
+		if batch.Count == 0 {
+			break
+		}
+		lastID = batch.LastID
+		copied += batch.Count
+		log.Printf("Copied %d messages (up to id %d)", copied, lastID)
+	}
+
+	columns, err := db.Migrator().ColumnTypes("messages")
+	if err != nil {
+		return err
+	}
+	updates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		updates = append(updates, fmt.Sprintf("%q = EXCLUDED.%q", column.Name(), column.Name()))
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE messages IN ACCESS EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+
+		// Catch up with messages sent, changed, and hard-deleted since the copy
+		if err := tx.Exec(fmt.Sprintf(`INSERT INTO %s SELECT * FROM messages WHERE id > ? OR updated_at >= ?
+			ON CONFLICT (conversation_id, id) DO UPDATE SET %s`, partitionedMessages, strings.Join(updates, ", ")),
+			lastID, startedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf(`DELETE FROM %s p WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = p.id)`,
+			partitionedMessages)).Error; err != nil {
+			return err
+		}
+
+		// Index names are unique per schema, so the old table's have to make way
+		var indexes []string
+		if err := tx.Raw("SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = 'messages' AND indexname != 'messages_pkey'").
+			Scan(&indexes).Error; err != nil {
+			return err
+		}
+		for _, index := range indexes {
+			renamed := unpartitionedMessages + "_" + strings.TrimPrefix(index, "idx_messages_")
+			if err := tx.Exec(fmt.Sprintf("ALTER INDEX %q RENAME TO %q", index, renamed)).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, statement := range []string{
+			"ALTER TABLE messages RENAME TO " + unpartitionedMessages,
+			fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT messages_pkey TO %s_pkey", unpartitionedMessages, unpartitionedMessages),
+			fmt.Sprintf("ALTER TABLE %s RENAME TO messages", partitionedMessages),
+			fmt.Sprintf("ALTER TABLE messages RENAME CONSTRAINT %s_pkey TO messages_pkey", partitionedMessages),
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		// The sequence would otherwise be dropped along with the old table
+		if sequence != "" {
+			if err := tx.Exec(fmt.Sprintf("ALTER SEQUENCE %s OWNED BY messages.id", sequence)).Error; err != nil {
+				return err
+			}
+		}
+
+		// Recreates the indexes and foreign keys on the new table
+		return tx.AutoMigrate(&models.Message{})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to switch to the partitioned table: %w", err)
+	}
+
+	log.Printf("Messages are partitioned into %d partitions; drop %s once the new table is checked", partitions, unpartitionedMessages)
+	return nil
+}
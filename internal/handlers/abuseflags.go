@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetAbuseFlags lists accounts the automated checks flagged, unreviewed ones
+// oldest first unless reviewed=true
+func (h *AdminHandler) GetAbuseFlags(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.AbuseFlag{})
+	order := "created_at ASC"
+	if c.Query("reviewed") == "true" {
+		query = query.Where("reviewed_at IS NOT NULL")
+		order = "reviewed_at DESC"
+	} else {
+		query = query.Where("reviewed_at IS NULL")
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var flags []models.AbuseFlag
+	if err := query.Order(order).Offset((page - 1) * limit).Limit(limit).Find(&flags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch abuse flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flags": flags,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// ReviewAbuseFlag marks a flag as looked at. Any action against the account
+// is taken separately, e.g. with a strike.
+func (h *AdminHandler) ReviewAbuseFlag(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	flagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID"})
+		return
+	}
+
+	var flag models.AbuseFlag
+	if err := h.db.WithContext(ctx).Where("id = ? AND reviewed_at IS NULL", flagID).First(&flag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Open abuse flag not found"})
+		return
+	}
+
+	now := time.Now()
+	reviewer := adminID.(uint)
+	flag.ReviewedAt = &now
+	flag.ReviewedBy = &reviewer
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&flag).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "abuse_flag_reviewed", "user", flag.UserID, flag.Kind)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update abuse flag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flag": flag})
+}
+
+// WebSocketAbuseReporter flags users whose WebSocket the hub closed for
+// flooding, the same way swiping like a bot is flagged
+func WebSocketAbuseReporter(db *gorm.DB, rc *redis.Client) func(ctx context.Context, userID uint, details string) {
+	return func(ctx context.Context, userID uint, details string) {
+		flag := models.AbuseFlag{UserID: userID, Kind: "websocket_flood", Details: details}
+		if err := db.WithContext(ctx).Create(&flag).Error; err != nil {
+			log.Printf("Failed to flag user %d for WebSocket flooding: %v", userID, err)
+			return
+		}
+		services.PublishOpsEvent(ctx, rc, services.OpsAbuseFlagged, gin.H{
+			"flag_id": flag.ID,
+			"user_id": userID,
+			"kind":    flag.Kind,
+		})
+	}
+}
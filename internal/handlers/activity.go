@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetActivity lists the caller's own account activity, newest first, so they
+// can spot sign-ins or changes they don't recognize
+func (h *UserHandler) GetActivity(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.UserActivity{}).Where("user_id = ?", userID)
+
+	var total int64
+	query.Count(&total)
+
+	var activities []models.UserActivity
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activities": activities,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// Heartbeat lets the app report that it is open, even when the user isn't
+// making other requests. The presence middleware counts the user as active;
+// last_seen is written at most once an hour.
+func (h *UserHandler) Heartbeat(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	due, err := h.redis.LastSeenDue(ctx, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+	if due {
+		if err := h.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("last_seen", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"next_heartbeat_seconds": int(redis.LastSeenInterval.Seconds())})
+}
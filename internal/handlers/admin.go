@@ -1,22 +1,28 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"ethiopia-dating-app/internal/config"
 	"ethiopia-dating-app/internal/models"
 	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type AdminHandler struct {
-	db    *gorm.DB
-	redis *redis.Client
-	cfg   *config.Config
+	db      *gorm.DB
+	redis   *redis.Client
+	cfg     *config.Config
+	mailer  services.Mailer
+	storage *services.StorageService
 }
 
 type UpdateUserStatusRequest struct {
@@ -27,6 +33,35 @@ type UpdateReportStatusRequest struct {
 	Status string `json:"status" binding:"required,oneof=pending reviewed resolved dismissed"`
 }
 
+type UpdateAdminLanguagesRequest struct {
+	Languages []string `json:"languages" binding:"dive,oneof=am en other"`
+}
+
+type BulkUserActionRequest struct {
+	IDs    []uint `json:"ids" binding:"required,min=1,max=500"`
+	Action string `json:"action" binding:"required,oneof=suspend activate"`
+}
+
+type BulkReportActionRequest struct {
+	IDs    []uint `json:"ids" binding:"required,min=1,max=500"`
+	Action string `json:"action" binding:"required,oneof=dismiss resolve"`
+}
+
+type MessageSearchResult struct {
+	ID             uint      `json:"id"`
+	ConversationID uint      `json:"conversation_id"`
+	SenderID       uint      `json:"sender_id"`
+	RecipientID    uint      `json:"recipient_id"`
+	MessageType    string    `json:"message_type"`
+	Content        *string   `json:"content"` // nil unless an open report links sender and recipient
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type BulkActionResult struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"` // updated, unchanged, not_found
+}
+
 type UserListResponse struct {
 	Users []models.User `json:"users"`
 	Total int64         `json:"total"`
@@ -41,15 +76,18 @@ type ReportListResponse struct {
 	Limit   int             `json:"limit"`
 }
 
-func NewAdminHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config) *AdminHandler {
+func NewAdminHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, mailer services.Mailer, storage *services.StorageService) *AdminHandler {
 	return &AdminHandler{
-		db:    db,
-		redis: redis,
-		cfg:   cfg,
+		db:      db,
+		redis:   redis,
+		cfg:     cfg,
+		mailer:  mailer,
+		storage: storage,
 	}
 }
 
 func (h *AdminHandler) GetUsers(c *gin.Context) {
+	ctx := c.Request.Context()
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	status := c.Query("status")
@@ -65,7 +103,7 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Build query
-	query := h.db.Model(&models.User{})
+	query := h.db.WithContext(ctx).Model(&models.User{})
 
 	// Filter by status
 	if status != "" {
@@ -78,6 +116,8 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 			query = query.Where("is_verified = ?", true)
 		case "unverified":
 			query = query.Where("is_verified = ?", false)
+		case "email_verified", "phone_verified", "photo_verified":
+			query = query.Where(status + "_at IS NOT NULL")
 		}
 	}
 
@@ -110,6 +150,7 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 }
 
 func (h *AdminHandler) GetUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -117,7 +158,7 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := h.db.Preload("ProfilePhotos").Preload("Interests").
+	if err := h.db.WithContext(ctx).Preload("ProfilePhotos").Preload("Interests").
 		Where("id = ?", userID).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -125,11 +166,11 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 
 	// Get user activity
 	var activities []models.UserActivity
-	h.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(10).Find(&activities)
+	h.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(10).Find(&activities)
 
 	// Get reports against this user
 	var reports []models.Report
-	h.db.Preload("Reporter").Where("reported_id = ?", userID).Find(&reports)
+	h.db.WithContext(ctx).Preload("Reporter").Where("reported_id = ?", userID).Find(&reports)
 
 	c.JSON(http.StatusOK, gin.H{
 		"user":       user,
@@ -138,7 +179,103 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 	})
 }
 
+// GetDiscoveryPreview runs discovery as the given user and returns the ranked
+// candidates with score breakdowns, plus how many users each exclusion removed.
+// viewer_status lists the gates that would refuse the user discovery outright
+// in blocked_by, and whether they are hidden from others themselves.
+func (h *AdminHandler) GetDiscoveryPreview(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Preload("Interests").Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Discover as the user would from their saved location
+	filters := DiscoverFilters{Latitude: user.Latitude, Longitude: user.Longitude}
+	if maxDistance, err := strconv.Atoi(c.Query("max_distance")); err == nil && maxDistance > 0 {
+		filters.MaxDistance = &maxDistance
+	}
+
+	candidates, err := topCandidates(buildDiscoverQuery(h.db.WithContext(ctx), h.cfg, user.ID, filters), &user, discoverRankPool, 0, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run discovery"})
+		return
+	}
+
+	// The gates discovery routes apply before any of this runs
+	onboarding, err := services.GetDiscoveryOnboarding(h.db.WithContext(ctx), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch onboarding"})
+		return
+	}
+	var waitlist []models.Waitlist
+	if err := h.db.WithContext(ctx).Where("user_id = ?", user.ID).Limit(1).Find(&waitlist).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	blockedBy := []string{}
+	if !user.IsActive {
+		blockedBy = append(blockedBy, "inactive")
+	}
+	if len(waitlist) > 0 {
+		blockedBy = append(blockedBy, "waitlisted")
+	}
+	if !onboarding.CanDiscover {
+		blockedBy = append(blockedBy, "onboarding_incomplete")
+	}
+	viewerStatus := gin.H{
+		"is_active":          user.IsActive,
+		"is_verified":        user.IsVerified,
+		"has_location":       hasCoordinates(&user),
+		"is_paused":          user.IsPaused,
+		"age_flagged":        user.AgeFlaggedAt != nil,
+		"waitlisted":         len(waitlist) > 0,
+		"onboarding_step":    onboarding.CurrentStep,
+		"can_discover":       onboarding.CanDiscover,
+		"discovery_blocked":  len(blockedBy) > 0,
+		"blocked_by":         blockedBy,
+		"hidden_from_others": user.IsPaused || user.AgeFlaggedAt != nil || len(waitlist) > 0,
+	}
+	if len(waitlist) > 0 {
+		viewerStatus["waitlist_city"] = waitlist[0].CityName
+	}
+
+	// Break down the pool so support can see where candidates drop out
+	var pool, blocked, liked, disliked int64
+	h.db.WithContext(ctx).Model(&models.User{}).Where("id != ? AND is_active = ? AND is_verified = ?", user.ID, true, true).Count(&pool)
+	h.db.WithContext(ctx).Model(&models.BlockedUser{}).Where("blocker_id = ?", user.ID).Count(&blocked)
+	h.db.WithContext(ctx).Model(&models.Like{}).Where("liker_id = ?", user.ID).Count(&liked)
+	h.db.WithContext(ctx).Model(&models.Dislike{}).
+		Where("disliker_id = ? AND created_at > ?", user.ID, dislikeCutoff(h.db.WithContext(ctx), h.cfg, &user, time.Now())).
+		Count(&disliked)
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":       user.ID,
+		"viewer_status": viewerStatus,
+		"pool": gin.H{
+			"eligible_users": pool,
+			"blocked":        blocked,
+			"liked":          liked,
+			"disliked":       disliked,
+		},
+		"candidates": rankCandidates(&user, candidates),
+	})
+}
+
 func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -152,7 +289,7 @@ func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -168,28 +305,39 @@ func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
 		// You might want to add a separate suspended field
 	}
 
-	if err := h.db.Save(&user).Error; err != nil {
+	// Bump the version so an in-flight profile edit can't undo the status change
+	user.Version++
+	if err := h.db.WithContext(ctx).Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
 		return
 	}
 
 	// Log admin action
-	adminID, _ := c.Get("user_id")
 	activity := models.UserActivity{
 		UserID:    uint(userID),
 		Action:    "status_updated",
 		IPAddress: c.ClientIP(),
 		UserAgent: c.GetHeader("User-Agent"),
 	}
-	h.db.Create(&activity)
+	h.db.WithContext(ctx).Create(&activity)
+	h.logAdminAction(h.db.WithContext(ctx), c, "user_status_updated", "user", uint(userID), req.Status)
+
+	if user.IsActive {
+		services.UnblockAccount(ctx, h.redis, user.ID)
+	} else {
+		services.BlockAccount(ctx, h.redis, user.ID, nil)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User status updated successfully"})
 }
 
 func (h *AdminHandler) GetReports(c *gin.Context) {
+	ctx := c.Request.Context()
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	status := c.Query("status")
+	language := c.Query("language")
+	assigned := c.Query("assigned")
 
 	if page < 1 {
 		page = 1
@@ -201,21 +349,61 @@ func (h *AdminHandler) GetReports(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Build query
-	query := h.db.Model(&models.Report{})
+	query := h.db.WithContext(ctx).Model(&models.Report{})
 
 	// Filter by status
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
 
+	// The queue: reports claimed by the caller, by nobody, or by one admin,
+	// and reports open past their SLA
+	switch assigned {
+	case "":
+	case "me":
+		adminID, _ := c.Get("user_id")
+		query = query.Where("assigned_to = ?", adminID)
+	case "unassigned":
+		query = query.Where("assigned_to IS NULL")
+	default:
+		adminID, err := strconv.ParseUint(assigned, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "assigned must be me, unassigned or an admin ID"})
+			return
+		}
+		query = query.Where("assigned_to = ?", adminID)
+	}
+	if c.Query("overdue") == "true" {
+		query = query.Where("status IN ? AND sla_due_at < ?", []string{"pending", "reviewed"}, time.Now())
+	}
+
+	// Moderators see reports in the languages they review, plus those whose
+	// language couldn't be told, unless they ask for one language or all
+	switch language {
+	case "all":
+	case "":
+		value, _ := c.Get("admin")
+		if admin, ok := value.(models.Admin); ok && len(admin.Languages) > 0 {
+			query = query.Where("language IN ? OR language IN ?", admin.Languages, []string{"", utils.LanguageOther})
+		}
+	default:
+		query = query.Where("language = ?", language)
+	}
+
 	// Get total count
 	var total int64
 	query.Count(&total)
 
 	// Get reports
+	// Working the queue goes by what is due soonest
+	order := "created_at DESC"
+	if c.Query("sort") == "sla" {
+		order = "sla_due_at ASC NULLS LAST, created_at ASC"
+	}
+
 	var reports []models.Report
 	if err := query.Preload("Reporter").Preload("Reported").
-		Order("created_at DESC").
+		Order(order).
 		Offset(offset).Limit(limit).
 		Find(&reports).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
@@ -230,7 +418,40 @@ func (h *AdminHandler) GetReports(c *gin.Context) {
 	})
 }
 
+// UpdateLanguages sets the content languages the calling admin reviews,
+// which decides the reports they see by default
+func (h *AdminHandler) UpdateLanguages(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req UpdateAdminLanguagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, _ := c.Get("admin")
+	admin, ok := value.(models.Admin)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	admin.Languages = req.Languages
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Select("languages").Updates(&admin).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "admin_languages_updated", "admin", admin.ID, strings.Join(req.Languages, ","))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update languages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"languages": admin.Languages})
+}
+
 func (h *AdminHandler) UpdateReportStatus(c *gin.Context) {
+	ctx := c.Request.Context()
 	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
@@ -244,68 +465,70 @@ func (h *AdminHandler) UpdateReportStatus(c *gin.Context) {
 	}
 
 	var report models.Report
-	if err := h.db.Where("id = ?", reportID).First(&report).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", reportID).First(&report).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
 		return
 	}
+	if reportClaimedByOther(c, report) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another moderator is handling this report", "code": "REPORT_CLAIMED"})
+		return
+	}
 
-	// Update status
+	// Update status; resolving a report counts as a strike against the reported user
+	wasResolved := report.Status == "resolved"
 	report.Status = req.Status
-	if err := h.db.Save(&report).Error; err != nil {
+	var strike models.Strike
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&report).Updates(reportClosure(c, req.Status)).Error; err != nil {
+			return err
+		}
+		if req.Status == "resolved" && !wasResolved {
+			var err error
+			if strike, err = h.strikeForReport(tx, c, report); err != nil {
+				return err
+			}
+		}
+		return h.logAdminAction(tx, c, "report_status_updated", "report", uint(reportID), req.Status)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report status"})
 		return
 	}
+	enforcePenalty(ctx, h.redis, strike)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Report status updated successfully"})
 }
 
+// GetAnalytics reads the counters and breakdowns precomputed by the
+// analytics_views job. computed_at says how fresh they are; pending reports,
+// feedback reasons, and match quality are counted live.
 func (h *AdminHandler) GetAnalytics(c *gin.Context) {
-	// Get analytics for the last 30 days
+	ctx := c.Request.Context()
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 
-	// Total users
-	var totalUsers int64
-	h.db.Model(&models.User{}).Count(&totalUsers)
-
-	// Active users (logged in within last 7 days)
-	var activeUsers int64
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
-	h.db.Model(&models.User{}).Where("last_seen > ?", sevenDaysAgo).Count(&activeUsers)
-
-	// New users today
-	var newUsersToday int64
-	today := time.Now().Truncate(24 * time.Hour)
-	h.db.Model(&models.User{}).Where("created_at >= ?", today).Count(&newUsersToday)
-
-	// Total matches
-	var totalMatches int64
-	h.db.Model(&models.Match{}).Where("is_active = ?", true).Count(&totalMatches)
-
-	// Matches today
-	var matchesToday int64
-	h.db.Model(&models.Match{}).Where("is_active = ? AND created_at >= ?", true, today).Count(&matchesToday)
-
-	// Total messages
-	var totalMessages int64
-	h.db.Model(&models.Message{}).Count(&totalMessages)
-
-	// Messages today
-	var messagesToday int64
-	h.db.Model(&models.Message{}).Where("created_at >= ?", today).Count(&messagesToday)
+	var analytics models.Analytics
+	if err := h.db.WithContext(ctx).Table("analytics_activity").
+		Select("total_users, active_users, new_users_today, total_matches, matches_today, total_messages, messages_today, computed_at AS date").
+		Scan(&analytics).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch analytics"})
+		return
+	}
 
 	// Pending reports
-	var pendingReports int64
-	h.db.Model(&models.Report{}).Where("status = ?", "pending").Count(&pendingReports)
+	h.db.WithContext(ctx).Model(&models.Report{}).Where("status = ?", "pending").Count(&analytics.PendingReports)
+
+	// Active users as counted by the presence middleware, live from Redis
+	analytics.DAU, _ = h.redis.ActiveUsers(ctx, 1)
+	analytics.WAU, _ = h.redis.ActiveUsers(ctx, 7)
+	analytics.MAU, _ = h.redis.ActiveUsers(ctx, 30)
 
 	// User registrations by day (last 30 days)
 	var dailyRegistrations []struct {
 		Date  string `json:"date"`
 		Count int64  `json:"count"`
 	}
-	h.db.Model(&models.User{}).
-		Select("DATE(created_at) as date, COUNT(*) as count").
-		Where("created_at >= ?", thirtyDaysAgo).
-		Group("DATE(created_at)").
+	h.db.WithContext(ctx).Table("analytics_daily_registrations").
+		Select("TO_CHAR(date, 'YYYY-MM-DD') AS date, count").
 		Order("date").
 		Scan(&dailyRegistrations)
 
@@ -314,26 +537,298 @@ func (h *AdminHandler) GetAnalytics(c *gin.Context) {
 		Gender string `json:"gender"`
 		Count  int64  `json:"count"`
 	}
-	h.db.Model(&models.User{}).
-		Select("gender, COUNT(*) as count").
-		Group("gender").
+	h.db.WithContext(ctx).Table("analytics_gender_distribution").
+		Select("gender, count").
+		Order("count DESC").
 		Scan(&genderDistribution)
 
-	analytics := models.Analytics{
-		TotalUsers:     totalUsers,
-		ActiveUsers:    activeUsers,
-		NewUsersToday:  newUsersToday,
-		TotalMatches:   totalMatches,
-		MatchesToday:   matchesToday,
-		TotalMessages:  totalMessages,
-		MessagesToday:  messagesToday,
-		PendingReports: pendingReports,
-		Date:           time.Now(),
+	// Unmatch, report, and exit survey reasons (last 30 days)
+	var feedbackReasons []struct {
+		Context string `json:"context"`
+		Reason  string `json:"reason"`
+		Count   int64  `json:"count"`
 	}
+	h.db.WithContext(ctx).Model(&models.Feedback{}).
+		Select("context, reason, COUNT(*) as count").
+		Where("created_at >= ?", thirtyDaysAgo).
+		Group("context, reason").
+		Order("context, count DESC").
+		Scan(&feedbackReasons)
 
 	c.JSON(http.StatusOK, gin.H{
 		"analytics":           analytics,
 		"daily_registrations": dailyRegistrations,
 		"gender_distribution": genderDistribution,
+		"feedback_reasons":    feedbackReasons,
+		"match_quality":       matchQuality(h.db.WithContext(ctx), thirtyDaysAgo),
+		"moderators":          moderatorThroughput(h.db.WithContext(ctx), thirtyDaysAgo),
+		"computed_at":         analytics.Date,
+	})
+}
+
+func (h *AdminHandler) BulkUserAction(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req BulkUserActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	isActive := req.Action == "activate"
+
+	var results []BulkActionResult
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		results = make([]BulkActionResult, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			var user models.User
+			if err := tx.Where("id = ?", id).First(&user).Error; err != nil {
+				results = append(results, BulkActionResult{ID: id, Status: "not_found"})
+				continue
+			}
+
+			if user.IsActive == isActive {
+				results = append(results, BulkActionResult{ID: id, Status: "unchanged"})
+				continue
+			}
+
+			if err := tx.Model(&user).Updates(map[string]interface{}{
+				"is_active": isActive,
+				"version":   gorm.Expr("version + 1"),
+			}).Error; err != nil {
+				return err
+			}
+			if err := h.logAdminAction(tx, c, "bulk_"+req.Action, "user", id, ""); err != nil {
+				return err
+			}
+			results = append(results, BulkActionResult{ID: id, Status: "updated"})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply bulk action"})
+		return
+	}
+	for _, result := range results {
+		if result.Status != "updated" {
+			continue
+		}
+		if isActive {
+			services.UnblockAccount(ctx, h.redis, result.ID)
+		} else {
+			services.BlockAccount(ctx, h.redis, result.ID, nil)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "results": results})
+}
+
+func (h *AdminHandler) BulkReportAction(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req BulkReportActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := map[string]string{"dismiss": "dismissed", "resolve": "resolved"}[req.Action]
+
+	var results []BulkActionResult
+	var strikes []models.Strike
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		results = make([]BulkActionResult, 0, len(req.IDs))
+		strikes = strikes[:0]
+		for _, id := range req.IDs {
+			var report models.Report
+			if err := tx.Where("id = ?", id).First(&report).Error; err != nil {
+				results = append(results, BulkActionResult{ID: id, Status: "not_found"})
+				continue
+			}
+
+			if report.Status == status {
+				results = append(results, BulkActionResult{ID: id, Status: "unchanged"})
+				continue
+			}
+			if reportClaimedByOther(c, report) {
+				results = append(results, BulkActionResult{ID: id, Status: "claimed"})
+				continue
+			}
+
+			if err := tx.Model(&report).Updates(reportClosure(c, status)).Error; err != nil {
+				return err
+			}
+			if status == "resolved" {
+				strike, err := h.strikeForReport(tx, c, report)
+				if err != nil {
+					return err
+				}
+				strikes = append(strikes, strike)
+			}
+			if err := h.logAdminAction(tx, c, "bulk_"+req.Action, "report", id, ""); err != nil {
+				return err
+			}
+			results = append(results, BulkActionResult{ID: id, Status: "updated"})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply bulk action"})
+		return
+	}
+	for _, strike := range strikes {
+		enforcePenalty(ctx, h.redis, strike)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "results": results})
+}
+
+// messageAccessReasons are the report reasons serious enough to let super
+// admins read the messages between the two users
+var messageAccessReasons = []string{"scam", "harassment", "threats"}
+
+// SearchMessages lets super admins review a single sender's messages within a
+// bounded date range. Content is only revealed for conversations where an open
+// report for one of messageAccessReasons links the two participants, and the
+// audit entry names those reports; everything else is metadata only.
+func (h *AdminHandler) SearchMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+	senderID, err := strconv.ParseUint(c.Query("sender_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sender_id is required"})
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+		return
+	}
+	to = to.AddDate(0, 0, 1) // inclusive end date
+
+	if !to.After(from) || to.Sub(from) > 31*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range must be between 1 and 31 days"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.WithContext(ctx).Table("messages").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Joins("JOIN matches ON matches.id = conversations.match_id").
+		Where("messages.sender_id = ? AND messages.created_at >= ? AND messages.created_at < ? AND messages.deleted_at IS NULL",
+			senderID, from, to)
+
+	var total int64
+	query.Count(&total)
+
+	var rows []struct {
+		MessageSearchResult
+		RawContent string
+	}
+	if err := query.
+		Select("messages.id, messages.conversation_id, messages.sender_id, messages.message_type, messages.created_at, " +
+			"messages.content AS raw_content, " +
+			"CASE WHEN matches.user1_id = messages.sender_id THEN matches.user2_id ELSE matches.user1_id END AS recipient_id").
+		Order("messages.created_at ASC").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		return
+	}
+
+	// Find which recipients are linked to the sender by an open serious report
+	recipientIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		recipientIDs = append(recipientIDs, row.RecipientID)
+	}
+
+	linked := make(map[uint]uint) // recipient -> the report justifying access
+	if len(recipientIDs) > 0 {
+		var reports []models.Report
+		h.db.WithContext(ctx).Where("status IN ? AND reason IN ?", []string{"pending", "reviewed"}, messageAccessReasons).
+			Where("(reporter_id = ? AND reported_id IN ?) OR (reported_id = ? AND reporter_id IN ?)",
+				senderID, recipientIDs, senderID, recipientIDs).
+			Order("id ASC").Find(&reports)
+		for _, report := range reports {
+			other := report.ReporterID
+			if report.ReporterID == uint(senderID) {
+				other = report.ReportedID
+			}
+			if _, ok := linked[other]; !ok {
+				linked[other] = report.ID
+			}
+		}
+	}
+
+	results := make([]MessageSearchResult, 0, len(rows))
+	revealedBy := make(map[uint]bool)
+	var reportIDs []string
+	for _, row := range rows {
+		result := row.MessageSearchResult
+		if reportID, ok := linked[result.RecipientID]; ok {
+			content := row.RawContent
+			result.Content = &content
+			if !revealedBy[reportID] {
+				revealedBy[reportID] = true
+				reportIDs = append(reportIDs, strconv.FormatUint(uint64(reportID), 10))
+			}
+		}
+		results = append(results, result)
+	}
+
+	details := fmt.Sprintf("from=%s to=%s page=%d", c.Query("from"), c.Query("to"), page)
+	if len(reportIDs) > 0 {
+		details += " content_reports=" + strings.Join(reportIDs, ",")
+	}
+	h.logAdminAction(h.db.WithContext(ctx), c, "message_search", "user", uint(senderID), details)
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": results,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
 	})
 }
+
+// strikeForReport issues a strike to the reported user for a resolved report
+func (h *AdminHandler) strikeForReport(tx *gorm.DB, c *gin.Context, report models.Report) (models.Strike, error) {
+	adminID, _ := c.Get("user_id")
+	issuer, _ := adminID.(uint)
+	strike := models.Strike{
+		UserID:   report.ReportedID,
+		Reason:   report.Reason,
+		Source:   "report",
+		SourceID: &report.ID,
+		IssuedBy: &issuer,
+	}
+	err := issueStrike(tx, h.cfg, &strike)
+	return strike, err
+}
+
+// logAdminAction records an admin action in the audit trail. Pass a
+// transaction as db to make the audit entry part of the same unit of work.
+func (h *AdminHandler) logAdminAction(db *gorm.DB, c *gin.Context, action, targetType string, targetID uint, details string) error {
+	adminID, _ := c.Get("user_id")
+	adminUserID, _ := adminID.(uint)
+
+	entry := models.AdminAuditLog{
+		AdminID:    adminUserID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		IPAddress:  c.ClientIP(),
+	}
+	return db.Create(&entry).Error
+}
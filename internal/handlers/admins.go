@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdminLoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangeAdminPasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=12"`
+}
+
+type CreateAdminRequest struct {
+	Email     string   `json:"email" binding:"required,email"`
+	FirstName string   `json:"first_name" binding:"required,max=100"`
+	LastName  string   `json:"last_name" binding:"required,max=100"`
+	Role      string   `json:"role" binding:"required,oneof=super_admin moderator support"`
+	Languages []string `json:"languages,omitempty"`
+}
+
+type UpdateAdminRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=super_admin moderator support"`
+}
+
+type UpdateAdminStatusRequest struct {
+	IsActive bool `json:"is_active"`
+}
+
+// AdminLogin signs an admin in with their own credentials. Admins still on
+// a temporary password get a token that is only good for changing it.
+func (h *AdminHandler) AdminLogin(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req AdminLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subject := "admin:" + strings.ToLower(req.Email)
+	if wait, err := h.redis.LoginLockedFor(ctx, subject); err == nil && wait > 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed logins, please try again later", "code": "LOGIN_LOCKED"})
+		return
+	}
+
+	var admin models.Admin
+	err := h.db.WithContext(ctx).Where("email = ? AND is_active = ?", req.Email, true).First(&admin).Error
+	valid := false
+	if err == nil {
+		valid, _ = utils.VerifyPassword(req.Password, admin.PasswordHash)
+	}
+	if !valid {
+		h.redis.RecordLoginFailure(ctx, subject, h.cfg.LoginMaxFailures, h.cfg.LoginLockout)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+	h.redis.ClearLoginFailures(ctx, subject)
+
+	token, err := utils.GenerateAdminToken(admin.ID, admin.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"last_login_at": now}
+	if utils.PasswordNeedsRehash(admin.PasswordHash) {
+		if rehashed, err := utils.HashPassword(req.Password); err == nil {
+			updates["password_hash"] = rehashed
+		}
+	}
+	h.db.WithContext(ctx).Model(&admin).Updates(updates)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":         token,
+		"admin":                admin,
+		"must_change_password": admin.MustChangePassword,
+	})
+}
+
+// ChangeAdminPassword replaces the signed-in admin's password, which is how
+// invited admins finish setting up their account
+func (h *AdminHandler) ChangeAdminPassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+
+	var req ChangeAdminPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Choose a password different from the current one"})
+		return
+	}
+
+	var admin models.Admin
+	if err := h.db.WithContext(ctx).Where("id = ? AND is_active = ?", adminID, true).First(&admin).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	if valid, _ := utils.VerifyPassword(req.CurrentPassword, admin.PasswordHash); !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	hash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Updates(map[string]interface{}{
+			"password_hash":        hash,
+			"must_change_password": false,
+		}).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "admin_password_changed", "admin", admin.ID, "")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated"})
+}
+
+// GetAdmins lists every admin, deactivated ones included
+func (h *AdminHandler) GetAdmins(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var admins []models.Admin
+	if err := h.db.WithContext(ctx).Order("created_at ASC").Find(&admins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch admins"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"admins": admins})
+}
+
+// CreateAdmin adds an admin with a temporary password and emails them an
+// invitation with it. They must choose their own password when they first
+// sign in. When the email can't be sent the password is returned instead,
+// for the inviting admin to pass on.
+func (h *AdminHandler) CreateAdmin(c *gin.Context) {
+	ctx := c.Request.Context()
+	inviterID, _ := c.Get("user_id")
+
+	var req CreateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	h.db.WithContext(ctx).Unscoped().Model(&models.Admin{}).Where("email = ?", req.Email).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "An admin with this email already exists"})
+		return
+	}
+
+	password, err := utils.GenerateTemporaryPassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate password"})
+		return
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	inviter := inviterID.(uint)
+	admin := models.Admin{
+		Email:              req.Email,
+		PasswordHash:       hash,
+		FirstName:          strings.TrimSpace(req.FirstName),
+		LastName:           strings.TrimSpace(req.LastName),
+		Role:               req.Role,
+		Languages:          req.Languages,
+		IsActive:           true,
+		MustChangePassword: true,
+		InvitedBy:          &inviter,
+	}
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&admin).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "admin_created", "admin", admin.ID, admin.Email+" as "+admin.Role)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create admin"})
+		return
+	}
+
+	response := gin.H{"admin": admin, "invitation_sent": true}
+	if err := h.sendAdminInvitation(ctx, admin, password); err != nil {
+		log.Printf("Failed to email invitation to admin %d: %v", admin.ID, err)
+		response["invitation_sent"] = false
+		response["temporary_password"] = password
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *AdminHandler) sendAdminInvitation(ctx context.Context, admin models.Admin, password string) error {
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"You've been added as a %s on the Ethiopia Dating admin panel.\n\n"+
+		"Sign in at %s with this email and the temporary password below. You'll be asked to choose your own password straight away.\n\n"+
+		"Temporary password: %s\n",
+		admin.FirstName, strings.ReplaceAll(admin.Role, "_", " "), h.cfg.AdminPanelURL, password)
+	return h.mailer.Send(ctx, admin.Email, "Your admin account", body)
+}
+
+// UpdateAdminRole changes another admin's role
+func (h *AdminHandler) UpdateAdminRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req UpdateAdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, ok := h.otherAdmin(c)
+	if !ok {
+		return
+	}
+
+	previous := admin.Role
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Update("role", req.Role).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "admin_role_changed", "admin", admin.ID, previous+" to "+req.Role)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"admin": admin})
+}
+
+// UpdateAdminStatus deactivates or reactivates another admin. Deactivated
+// admins are turned away on their next request.
+func (h *AdminHandler) UpdateAdminStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req UpdateAdminStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, ok := h.otherAdmin(c)
+	if !ok {
+		return
+	}
+
+	action := "admin_deactivated"
+	if req.IsActive {
+		action = "admin_reactivated"
+	}
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&admin).Update("is_active", req.IsActive).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, action, "admin", admin.ID, "")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update admin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"admin": admin})
+}
+
+// otherAdmin loads the admin named in the path, refusing the caller's own
+// account so a super admin can't lock themselves out
+func (h *AdminHandler) otherAdmin(c *gin.Context) (models.Admin, bool) {
+	var admin models.Admin
+	adminID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return admin, false
+	}
+	if callerID, _ := c.Get("user_id"); callerID == uint(adminID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't change your own admin account"})
+		return admin, false
+	}
+
+	err = h.db.WithContext(c.Request.Context()).Where("id = ?", adminID).First(&admin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return admin, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch admin"})
+		return admin, false
+	}
+	return admin, true
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefixLength     = 12
+	defaultAPIKeyRateLimit = 60
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required,max=100"`
+	Partner   string   `json:"partner" binding:"required,max=100"`
+	Scopes    []string `json:"scopes" binding:"required,min=1,dive,oneof=events:write stats:read"`
+	RateLimit int      `json:"rate_limit,omitempty" binding:"omitempty,min=1,max=6000"`
+}
+
+// UpdateAPIKeyRequest changes only the fields that are sent
+type UpdateAPIKeyRequest struct {
+	Name      *string  `json:"name,omitempty" binding:"omitempty,max=100"`
+	Scopes    []string `json:"scopes,omitempty" binding:"omitempty,min=1,dive,oneof=events:write stats:read"`
+	RateLimit *int     `json:"rate_limit,omitempty" binding:"omitempty,min=1,max=6000"`
+}
+
+// newAPIKey returns a random partner API key
+func newAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "pk_" + hex.EncodeToString(b), nil
+}
+
+// GetAPIKeys lists partner API keys, revoked ones included
+func (h *AdminHandler) GetAPIKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var keys []models.APIKey
+	if err := h.db.WithContext(ctx).Order("created_at DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// CreateAPIKey issues a key to a partner. The key is only in this response,
+// so the partner has to store it now.
+func (h *AdminHandler) CreateAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.RateLimit == 0 {
+		req.RateLimit = defaultAPIKeyRateLimit
+	}
+
+	raw, err := newAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	key := models.APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		Partner:   strings.TrimSpace(req.Partner),
+		Prefix:    raw[:apiKeyPrefixLength],
+		KeyHash:   utils.HashString(raw),
+		Scopes:    req.Scopes,
+		RateLimit: req.RateLimit,
+		IsActive:  true,
+		CreatedBy: adminID.(uint),
+	}
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "api_key_created", "api_key", key.ID,
+			fmt.Sprintf("%s for %s, scopes %s", key.Name, key.Partner, strings.Join(key.Scopes, ",")))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"api_key": key, "key": raw})
+}
+
+// UpdateAPIKey changes a key's name, scopes or rate limit
+func (h *AdminHandler) UpdateAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	var req UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var key models.APIKey
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", keyID).First(&key).Error; err != nil {
+			return err
+		}
+		if req.Name != nil {
+			key.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Scopes != nil {
+			key.Scopes = req.Scopes
+		}
+		if req.RateLimit != nil {
+			key.RateLimit = *req.RateLimit
+		}
+		if err := tx.Save(&key).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "api_key_updated", "api_key", key.ID,
+			fmt.Sprintf("scopes %s, %d requests per minute", strings.Join(key.Scopes, ","), key.RateLimit))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_key": key})
+}
+
+// RevokeAPIKey stops a key working for good; partners get a new key instead
+// of having an old one restored
+func (h *AdminHandler) RevokeAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	var key models.APIKey
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND is_active = ?", keyID, true).First(&key).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		key.IsActive = false
+		key.RevokedAt = &now
+		if err := tx.Save(&key).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "api_key_revoked", "api_key", key.ID, key.Name+" for "+key.Partner)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Active API key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_key": key})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AppHandler struct {
+	db    *gorm.DB
+	redis *redis.Client
+	cfg   *config.Config
+}
+
+func NewAppHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config) *AppHandler {
+	return &AppHandler{
+		db:    db,
+		redis: redis,
+		cfg:   cfg,
+	}
+}
+
+// GetAppConfig is fetched by clients on launch, before login, to decide
+// whether to force an upgrade and which features to show. Premium features
+// a feature window makes free for everyone are switched on until it closes;
+// windows for a segment only show in the user's own free features.
+func (h *AppHandler) GetAppConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	features := make(map[string]bool, len(h.cfg.FeatureFlags))
+	for _, flag := range h.cfg.FeatureFlags {
+		features[flag] = true
+	}
+	free := freeFeatures(h.db.WithContext(ctx), h.cfg, models.FeatureWindow.ForEveryone)
+	for feature := range free {
+		features[feature] = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"min_version":    h.cfg.MinClientVersion,
+		"latest_version": h.cfg.LatestClientVersion,
+		"features":       features,
+		"free_features":  free,
+		"maintenance":    services.LoadMaintenance(ctx, h.redis, h.cfg),
+	})
+}
+
+// GetJWKS publishes the public keys that verify access tokens, so internal
+// services can check tokens without calling this API. It is empty while
+// tokens are signed with an HMAC secret.
+func (h *AppHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.PublicJWKS()})
+}
+
+// GetPublicStats serves coarse platform numbers to the marketing site. They
+// are refreshed hourly by a job and computed here only before its first run.
+func (h *AppHandler) GetPublicStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	stats, ok := services.LoadPublicStats(ctx, h.redis)
+	if !ok {
+		var err error
+		if stats, err = services.ComputePublicStats(ctx, h.db); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Stats are not available yet"})
+			return
+		}
+		services.SavePublicStats(ctx, h.redis, stats)
+	}
+
+	c.Header("Cache-Control", "public, max-age=600")
+	c.JSON(http.StatusOK, stats)
+}
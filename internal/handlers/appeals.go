@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// penaltyDeactivation is a moderator switching an account off without a
+	// strike
+	penaltyDeactivation = "deactivation"
+	// reasonModeratorDecision is the reason category of penalties that no
+	// strike explains
+	reasonModeratorDecision = "moderator_decision"
+)
+
+// AppealRequest appeals the penalty keeping the caller out
+type AppealRequest struct {
+	Text     string   `json:"text" binding:"required,max=2000"`
+	Evidence []string `json:"evidence,omitempty" binding:"max=5,dive,url"`
+}
+
+// AppealDecisionRequest approves an appeal, lifting the penalty, or denies
+// it, confirming the penalty
+type AppealDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve deny"`
+	Note     string `json:"note,omitempty" binding:"max=500"`
+}
+
+// PendingAppeal pairs an appeal with the account making it
+type PendingAppeal struct {
+	Appeal models.Appeal    `json:"appeal"`
+	User   PendingPhotoUser `json:"user"`
+}
+
+// accountPenalty is what keeps an inactive user out
+type accountPenalty struct {
+	Kind     string     `json:"penalty"`         // suspension, ban, deactivation
+	Reason   string     `json:"reason"`          // strike reason, or moderator_decision
+	Since    *time.Time `json:"since,omitempty"` // nil when nothing records when it started
+	Until    *time.Time `json:"until,omitempty"`
+	StrikeID *uint      `json:"-"`
+}
+
+// currentPenalty works out why an inactive user is out: the latest strike
+// that suspended or banned them, or a moderator's later status change
+func currentPenalty(db *gorm.DB, user models.User) accountPenalty {
+	penalty := accountPenalty{Kind: penaltyDeactivation, Reason: reasonModeratorDecision, Until: user.SuspendedUntil}
+
+	var strike models.Strike
+	hasStrike := db.Where("user_id = ? AND penalty IN ?", user.ID, []string{"suspension", "ban"}).
+		Order("created_at DESC").First(&strike).Error == nil
+	var entry models.AdminAuditLog
+	hasEntry := db.Where("target_type = ? AND target_id = ? AND ((action = ? AND details IN ?) OR action = ?)",
+		"user", user.ID, "user_status_updated", []string{"inactive", "suspended"}, "bulk_suspend").
+		Order("created_at DESC").First(&entry).Error == nil
+
+	switch {
+	case hasStrike && (!hasEntry || strike.CreatedAt.After(entry.CreatedAt)):
+		penalty.Kind = strike.Penalty
+		penalty.Reason = strike.Reason
+		penalty.Since = &strike.CreatedAt
+		penalty.StrikeID = &strike.ID
+	case hasEntry:
+		if entry.Details == "suspended" || entry.Action == "bulk_suspend" {
+			penalty.Kind = "suspension"
+		}
+		penalty.Since = &entry.CreatedAt
+	}
+	return penalty
+}
+
+// respondInactive turns away a suspended or deactivated user with a token
+// that only opens their appeal
+func respondInactive(c *gin.Context, user models.User) {
+	resp := gin.H{"error": "Account is deactivated", "code": "ACCOUNT_DEACTIVATED"}
+	if user.SuspendedUntil != nil {
+		resp = gin.H{"error": "Account is suspended", "code": "ACCOUNT_SUSPENDED", "suspended_until": user.SuspendedUntil}
+	}
+	if token, err := utils.GenerateAppealToken(user.ID); err == nil {
+		resp["appeal_token"] = token
+	}
+	c.JSON(http.StatusUnauthorized, resp)
+}
+
+// loadPenalizedUser loads the caller of an appeal route, answering when
+// they're active again
+func loadPenalizedUser(c *gin.Context, db *gorm.DB) (models.User, bool) {
+	userID, _ := c.Get("user_id")
+	var user models.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	if user.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Your account is active; sign in again"})
+		return user, false
+	}
+	return user, true
+}
+
+// appealOf returns the caller's appeal of penalty, if they made one. When
+// nothing records when the penalty started, any earlier appeal counts.
+func appealOf(db *gorm.DB, userID uint, penalty accountPenalty) *models.Appeal {
+	query := db.Where("user_id = ?", userID)
+	if penalty.Since != nil {
+		query = query.Where("created_at >= ?", *penalty.Since)
+	}
+	var appeal models.Appeal
+	if err := query.Order("created_at DESC").First(&appeal).Error; err != nil {
+		return nil
+	}
+	return &appeal
+}
+
+// GetAppeal shows a suspended or banned user why they're out and their
+// appeal, if they made one. It takes an appeal token.
+func (h *AuthHandler) GetAppeal(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
+	user, ok := loadPenalizedUser(c, db)
+	if !ok {
+		return
+	}
+
+	penalty := currentPenalty(db, user)
+	appeal := appealOf(db, user.ID, penalty)
+	c.JSON(http.StatusOK, gin.H{
+		"penalty":    penalty,
+		"appeal":     appeal,
+		"can_appeal": appeal == nil,
+	})
+}
+
+// SubmitAppeal appeals the penalty keeping the caller out, with an
+// explanation and optional links as evidence. Each penalty can be appealed
+// once. It takes an appeal token.
+func (h *AuthHandler) SubmitAppeal(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
+
+	var req AppealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tell us why the penalty should be lifted"})
+		return
+	}
+
+	user, ok := loadPenalizedUser(c, db)
+	if !ok {
+		return
+	}
+	penalty := currentPenalty(db, user)
+	if existing := appealOf(db, user.ID, penalty); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "You've already appealed", "appeal": existing})
+		return
+	}
+
+	appeal := models.Appeal{
+		UserID:   user.ID,
+		StrikeID: penalty.StrikeID,
+		Penalty:  penalty.Kind,
+		Reason:   penalty.Reason,
+		Text:     text,
+		Evidence: req.Evidence,
+		Status:   "pending",
+	}
+	if err := db.Create(&appeal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit appeal"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"appeal": appeal})
+}
+
+// GetAppeals lists appeals, oldest first. status defaults to pending.
+func (h *AdminHandler) GetAppeals(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.Appeal{}).Where("status = ?", c.DefaultQuery("status", "pending"))
+
+	var total int64
+	query.Count(&total)
+
+	var appeals []models.Appeal
+	if err := query.Order("created_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&appeals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appeals"})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(appeals))
+	for _, appeal := range appeals {
+		userIDs = append(userIDs, appeal.UserID)
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		h.db.WithContext(ctx).Where("id IN ?", userIDs).Find(&users)
+	}
+	byID := make(map[uint]PendingPhotoUser, len(users))
+	for _, user := range users {
+		info := PendingPhotoUser{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Gender:    user.Gender,
+			IsActive:  user.IsActive,
+			CreatedAt: user.CreatedAt,
+		}
+		h.db.WithContext(ctx).Model(&models.Report{}).Where("reported_id = ? AND status = ?", user.ID, "pending").Count(&info.OpenReports)
+		byID[user.ID] = info
+	}
+
+	queue := make([]PendingAppeal, 0, len(appeals))
+	for _, appeal := range appeals {
+		queue = append(queue, PendingAppeal{Appeal: appeal, User: byID[appeal.UserID]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"appeals": queue,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// DecideAppeal approves or denies a pending appeal. Approving reactivates
+// the user and removes the strike behind the penalty, so it no longer
+// counts toward later ones; denying leaves the penalty in place. Either way
+// the user is told.
+func (h *AdminHandler) DecideAppeal(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	appealID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appeal ID"})
+		return
+	}
+
+	var req AppealDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var appeal models.Appeal
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND status = ?", appealID, "pending").First(&appeal).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		moderator := adminID.(uint)
+		appeal.ReviewedBy = &moderator
+		appeal.ReviewedAt = &now
+		if req.Note != "" {
+			appeal.ReviewNote = &req.Note
+		}
+
+		notification := models.Notification{
+			UserID: appeal.UserID,
+			Type:   "appeal_denied",
+			Title:  "Appeal reviewed",
+			Body:   "We reviewed your appeal and the penalty stays in place.",
+			Data:   `{"appeal_id": ` + strconv.FormatUint(uint64(appeal.ID), 10) + `}`,
+		}
+
+		if req.Decision == "approve" {
+			appeal.Status = "approved"
+			if err := tx.Model(&models.User{}).Where("id = ?", appeal.UserID).Updates(map[string]interface{}{
+				"is_active":       true,
+				"suspended_until": nil,
+				"version":         gorm.Expr("version + 1"),
+			}).Error; err != nil {
+				return err
+			}
+			if appeal.StrikeID != nil {
+				if err := tx.Where("id = ?", *appeal.StrikeID).Delete(&models.Strike{}).Error; err != nil {
+					return err
+				}
+			}
+			notification.Type = "appeal_approved"
+			notification.Body = "We reviewed your appeal and lifted the penalty. Welcome back."
+		} else {
+			appeal.Status = "denied"
+		}
+
+		if err := tx.Save(&appeal).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "appeal_"+appeal.Status, "user", appeal.UserID, req.Note)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending appeal not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	if appeal.Status == "approved" {
+		services.UnblockAccount(ctx, h.redis, appeal.UserID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"appeal": appeal})
+}
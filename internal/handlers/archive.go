@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const restoresPerHour = 5
+
+// archivedMessageCount returns how many of a conversation's messages are in
+// archives not yet restored
+func (h *MessageHandler) archivedMessageCount(ctx context.Context, conversationID uint) int64 {
+	var count int64
+	h.db.WithContext(ctx).Model(&models.ConversationArchive{}).
+		Where("conversation_id = ? AND restored_at IS NULL", conversationID).
+		Select("COALESCE(SUM(message_count), 0)").Scan(&count)
+	return count
+}
+
+// RestoreArchivedMessages brings the messages the retention job archived
+// back into the conversation
+func (h *MessageHandler) RestoreArchivedMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+
+	if !allowRequest(ctx, h.redis, fmt.Sprintf("ratelimit:restore:%d", userID), restoresPerHour, time.Hour) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many restores, please try again later"})
+		return
+	}
+
+	restored, err := services.RestoreConversation(ctx, h.db, h.storage, uint(conversationID))
+	if err != nil {
+		log.Printf("Failed to restore conversation %d: %v", conversationID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore archived messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"restored": restored})
+}
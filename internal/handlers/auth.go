@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"ethiopia-dating-app/internal/config"
 	"ethiopia-dating-app/internal/models"
 	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
 	"ethiopia-dating-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,9 +19,14 @@ import (
 )
 
 type AuthHandler struct {
-	db    *gorm.DB
-	redis *redis.Client
-	cfg   *config.Config
+	db       *gorm.DB
+	redis    *redis.Client
+	cfg      *config.Config
+	geo      services.GeoLocator
+	telegram services.TelegramBot
+	notifier *services.Dispatcher
+	sms      services.SMSSender
+	mailer   services.Mailer
 }
 
 type RegisterRequest struct {
@@ -27,12 +36,18 @@ type RegisterRequest struct {
 	FirstName   string `json:"first_name" binding:"required"`
 	LastName    string `json:"last_name" binding:"required"`
 	DateOfBirth string `json:"date_of_birth" binding:"required"`
-	Gender      string `json:"gender" binding:"required,oneof=male female other"`
+	Gender      string `json:"gender" binding:"required,oneof=male female non_binary other"`
+	Seeking     string `json:"seeking,omitempty" binding:"omitempty,oneof=men women everyone"`
+
+	RelationshipIntent string `json:"relationship_intent,omitempty" binding:"omitempty,oneof=serious casual friendship marriage_minded"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceID   string `json:"device_id,omitempty" binding:"omitempty,max=64"`
+	DeviceName string `json:"device_name,omitempty" binding:"omitempty,max=100"`
+	OTP        string `json:"otp,omitempty"` // confirms a login from a new device
 }
 
 type VerifyOTPRequest struct {
@@ -44,15 +59,21 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
-func NewAuthHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, geo services.GeoLocator, telegram services.TelegramBot, notifier *services.Dispatcher, sms services.SMSSender, mailer services.Mailer) *AuthHandler {
 	return &AuthHandler{
-		db:    db,
-		redis: redis,
-		cfg:   cfg,
+		db:       db,
+		redis:    redis,
+		cfg:      cfg,
+		geo:      geo,
+		telegram: telegram,
+		notifier: notifier,
+		sms:      sms,
+		mailer:   mailer,
 	}
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -67,27 +88,48 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Check if user is 18+
-	age := time.Since(dob).Hours() / 24 / 365
-	if age < 18 {
+	if utils.CalculateAge(dob, time.Now()) < 18 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "You must be 18 or older to use this app"})
 		return
 	}
 
+	req.FirstName = strings.TrimSpace(req.FirstName)
+	req.LastName = strings.TrimSpace(req.LastName)
+	if err := validateDisplayNames(req.FirstName, req.LastName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// A flagged name is held for review like a name change, and the account
+	// shows initials until a moderator approves it
+	var nameChange *models.NameChangeRequest
+	if flag := utils.DisplayNameFlag(req.FirstName + " " + req.LastName); flag != "" {
+		nameChange = &models.NameChangeRequest{
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+			Flag:      flag,
+			Status:    "pending",
+		}
+		req.FirstName = utils.NameInitial(req.FirstName)
+		req.LastName = utils.NameInitial(req.LastName)
+	}
+
 	// Check if user already exists
 	var existingUser models.User
-	if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := h.db.WithContext(ctx).Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User already exists with this email"})
 		return
 	}
 
 	// Format phone number if provided
-	var phone *string
+	var phone, phoneHash *string
 	if req.Phone != "" {
 		formattedPhone := utils.FormatPhoneNumber(req.Phone)
+		hashed := utils.HashPhone(formattedPhone)
 		phone = &formattedPhone
+		phoneHash = &hashed
 
 		// Check if phone already exists
-		if err := h.db.Where("phone = ?", formattedPhone).First(&existingUser).Error; err == nil {
+		if err := h.db.WithContext(ctx).Where("phone = ?", formattedPhone).First(&existingUser).Error; err == nil {
 			c.JSON(http.StatusConflict, gin.H{"error": "User already exists with this phone number"})
 			return
 		}
@@ -104,46 +146,92 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	user := models.User{
 		Email:        req.Email,
 		Phone:        phone,
+		PhoneHash:    phoneHash,
 		PasswordHash: hashedPassword,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
 		DateOfBirth:  dob,
 		Gender:       req.Gender,
-		IsVerified:   !h.cfg.OTPEnabled, // Auto-verify if OTP is disabled
-		IsActive:     true,
+		Seeking:      req.Seeking,
+
+		RelationshipIntent: req.RelationshipIntent,
+		IsVerified:         !h.cfg.OTPEnabled, // Auto-verify if OTP is disabled
+		IsActive:           true,
+	}
+	if !h.cfg.OTPEnabled {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if phone != nil {
+			user.PhoneVerifiedAt = &now
+		}
+	}
+
+	// The IP's city decides whether a new user waits for a launch
+	if h.cfg.LaunchGatingEnabled {
+		h.applyApproximateLocation(&user, c.ClientIP())
 	}
 
-	if err := h.db.Create(&user).Error; err != nil {
+	var waitlist *models.Waitlist
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := services.StartOnboarding(tx, user.ID, !h.cfg.OTPEnabled); err != nil {
+			return err
+		}
+		if nameChange != nil {
+			nameChange.UserID = user.ID
+			nameChange.PreviousFirstName = user.FirstName
+			nameChange.PreviousLastName = user.LastName
+			if err := tx.Create(nameChange).Error; err != nil {
+				return err
+			}
+		}
+		waitlist, err = waitlistNewUser(tx, h.cfg, &user)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
+	// Let the activity middleware attribute the registration
+	c.Set("user_id", user.ID)
+	services.PublishOpsEvent(ctx, h.redis, services.OpsUserRegistered, gin.H{
+		"user_id": user.ID,
+		"gender":  user.Gender,
+	})
+
+	// Honor blocks placed on this phone number before the account existed
+	if err := applyPhoneBlocks(h.db.WithContext(ctx), &user); err != nil {
+		log.Printf("Failed to apply phone blocks for user %d: %v", user.ID, err)
+	}
+
 	// Generate OTP if enabled
 	if h.cfg.OTPEnabled {
-		otp, err := utils.GenerateOTP()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate OTP"})
+		otp, err := h.issueOTP(h.db.WithContext(ctx), verificationEmail, req.Email, phone)
+		if errors.Is(err, errTooManyOTPs) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many codes requested, please try again later"})
 			return
 		}
-
-		otpRecord := models.OTP{
-			Email:     req.Email,
-			Phone:     phone,
-			Code:      otp,
-			ExpiresAt: time.Now().Add(h.cfg.OTPExpiry),
-		}
-
-		if err := h.db.Create(&otpRecord).Error; err != nil {
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create OTP"})
 			return
 		}
 
-		// TODO: Send OTP via SMS/Email
-		// For now, return OTP in response for development
-		c.JSON(http.StatusCreated, gin.H{
-			"message": "User created successfully. Please verify your account.",
-			"otp":     otp, // Remove this in production
-		})
+		response := gin.H{
+			"message":     "User created successfully. Please verify your account.",
+			"waitlisted":  waitlist != nil,
+			"name_change": nameChange,
+		}
+		// The account stands either way; the code can be sent again
+		if channel, sent := h.sendCode(ctx, &user, otp, "verification"); sent {
+			response["channel"] = channel
+		} else {
+			response["message"] = "User created successfully, but we couldn't send your code. Please request a new one."
+		}
+		h.exposeOTP(response, otp)
+		c.JSON(http.StatusCreated, response)
 		return
 	}
 
@@ -170,7 +258,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		"expires_at":    time.Now().Add(h.cfg.JWTExpiry).Unix(),
 	}
 
-	if err := h.redis.HSet(c.Request.Context(), sessionKey, sessionData); err != nil {
+	if err := h.redis.HSet(ctx, sessionKey, sessionData); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store session"})
 		return
 	}
@@ -180,36 +268,70 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		"access_token":  accessToken,
 		"refresh_token": refreshToken,
 		"user":          user,
+		"waitlisted":    waitlist != nil,
+		"name_change":   nameChange,
 	})
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Find user
-	var user models.User
-	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if h.respondIfLoginLocked(ctx, c, req.Email) {
 		return
 	}
 
-	// Check if user is active
-	if !user.IsActive {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is deactivated"})
+	// Find user
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("email = ?", req.Email).First(&user).Error; err != nil {
+		h.loginFailed(ctx, c, req.Email)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	// Verify password
 	valid, err := utils.VerifyPassword(req.Password, user.PasswordHash)
 	if err != nil || !valid {
+		h.loginFailed(ctx, c, req.Email)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// Suspended and banned users only get a token for appealing. The
+	// password is checked first so an account's status isn't given away.
+	if !user.IsActive {
+		respondInactive(c, user)
+		return
+	}
+
+	// Logins from a device the user hasn't used before must be confirmed
+	if err := h.checkLoginDevice(ctx, c, &user, req); err != nil {
+		if !errors.Is(err, errDeviceUnverified) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check device"})
+			return
+		}
+		if req.OTP != "" {
+			h.loginFailed(ctx, c, req.Email)
+		}
+		h.respondDeviceVerification(ctx, c, &user, req.OTP != "")
+		return
+	}
+	h.redis.ClearLoginFailures(ctx, loginAccountSubject(req.Email))
+
+	// Upgrade hashes made with another scheme or weaker parameters while the
+	// plain password is at hand; saved with the login update below
+	if utils.PasswordNeedsRehash(user.PasswordHash) {
+		if rehashed, err := utils.HashPassword(req.Password); err == nil {
+			user.PasswordHash = rehashed
+		} else {
+			log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		}
+	}
+
 	// Generate tokens
 	accessToken, err := utils.GenerateToken(user.ID, user.Email)
 	if err != nil {
@@ -233,15 +355,29 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		"expires_at":    time.Now().Add(h.cfg.JWTExpiry).Unix(),
 	}
 
-	if err := h.redis.HSet(c.Request.Context(), sessionKey, sessionData); err != nil {
+	if err := h.redis.HSet(ctx, sessionKey, sessionData); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store session"})
 		return
 	}
 
+	// Let the activity middleware attribute the login
+	c.Set("user_id", user.ID)
+
+	// Users who never shared GPS get a city-level guess from their IP so
+	// discovery has somewhere to start; a later GPS update replaces it
+	if !hasCoordinates(&user) || user.LocationApproximate {
+		h.applyApproximateLocation(&user, c.ClientIP())
+	}
+
+	// Only GPS positions go on the active-nearby map
+	if hasPreciseLocation(&user) {
+		h.redis.SetPresenceLocation(ctx, user.ID, *user.Latitude, *user.Longitude)
+	}
+
 	// Update last seen
 	user.LastSeen = &[]time.Time{time.Now()}[0]
 	user.IsOnline = true
-	h.db.Save(&user)
+	h.db.WithContext(ctx).Save(&user)
 
 	c.JSON(http.StatusOK, gin.H{
 		"access_token":  accessToken,
@@ -251,6 +387,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 func (h *AuthHandler) VerifyOTP(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req VerifyOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -259,30 +396,41 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 
 	// Find OTP record
 	var otp models.OTP
-	if err := h.db.Where("email = ? AND code = ? AND is_used = ?", req.Email, req.Code, false).First(&otp).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("email = ? AND channel = ? AND code = ? AND is_used = ?", req.Email, verificationEmail, req.Code, false).First(&otp).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired OTP"})
 		return
 	}
 
 	// Check if OTP is expired
-	if utils.IsOTPExpired(otp.CreatedAt, h.cfg.OTPExpiry) {
+	if !time.Now().Before(otp.ExpiresAt) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "OTP has expired"})
 		return
 	}
 
 	// Mark OTP as used
 	otp.IsUsed = true
-	h.db.Save(&otp)
+	h.db.WithContext(ctx).Save(&otp)
 
 	// Verify user
 	var user models.User
-	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("email = ?", req.Email).First(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User not found"})
 		return
 	}
 
+	// Suspended and deactivated users get the same answer as at login
+	// rather than a full session
+	if !user.IsActive {
+		respondInactive(c, user)
+		return
+	}
+
+	// The code went to the account's email, so it proves that address
 	user.IsVerified = true
-	h.db.Save(&user)
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &otp.CreatedAt
+	}
+	h.db.WithContext(ctx).Save(&user)
 
 	// Generate tokens
 	accessToken, err := utils.GenerateToken(user.ID, user.Email)
@@ -306,6 +454,7 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 }
 
 func (h *AuthHandler) ResendOTP(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req struct {
 		Email string `json:"email" binding:"required,email"`
 	}
@@ -317,39 +466,82 @@ func (h *AuthHandler) ResendOTP(c *gin.Context) {
 
 	// Check if user exists
 	var user models.User
-	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("email = ?", req.Email).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Generate new OTP
-	otp, err := utils.GenerateOTP()
+	otp, err := h.issueOTP(h.db.WithContext(ctx), verificationEmail, req.Email, user.Phone)
+	if errors.Is(err, errTooManyOTPs) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many codes requested, please try again later"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate OTP"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create OTP"})
 		return
 	}
 
-	// Create new OTP record
-	otpRecord := models.OTP{
-		Email:     req.Email,
-		Phone:     user.Phone,
-		Code:      otp,
-		ExpiresAt: time.Now().Add(h.cfg.OTPExpiry),
+	response := gin.H{"message": "OTP sent successfully"}
+	if channel, sent := h.sendCode(ctx, &user, otp, "verification"); sent {
+		response["channel"] = channel
+	} else if h.cfg.GinMode != "debug" {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send the code, please try again"})
+		return
 	}
+	h.exposeOTP(response, otp)
+	c.JSON(http.StatusOK, response)
+}
 
-	if err := h.db.Create(&otpRecord).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create OTP"})
-		return
+// exposeOTP adds a verification code to the response in development
+// (GIN_MODE=debug), where codes usually have nowhere to be sent. Other
+// channels' codes, such as new-device ones, are never returned.
+func (h *AuthHandler) exposeOTP(response gin.H, code string) {
+	if h.cfg.GinMode == "debug" {
+		response["otp"] = code
 	}
+}
 
-	// TODO: Send OTP via SMS/Email
-	c.JSON(http.StatusOK, gin.H{
-		"message": "OTP sent successfully",
-		"otp":     otp, // Remove this in production
+// errTooManyOTPs is returned when an account asked for too many codes this hour
+var errTooManyOTPs = errors.New("too many OTPs requested")
+
+// issueOTP creates a code for the account on the channel and expires any
+// earlier unused ones on it, so only the latest code works. At most
+// OTPMaxPerHour are issued per account an hour across channels.
+func (h *AuthHandler) issueOTP(db *gorm.DB, channel, email string, phone *string) (string, error) {
+	var issued int64
+	if err := db.Model(&models.OTP{}).Where("email = ? AND created_at > ?", email, time.Now().Add(-time.Hour)).
+		Count(&issued).Error; err != nil {
+		return "", err
+	}
+	if issued >= h.cfg.OTPMaxPerHour {
+		return "", errTooManyOTPs
+	}
+
+	code, err := utils.GenerateOTP()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OTP{}).Where("email = ? AND channel = ? AND is_used = ? AND expires_at > ?", email, channel, false, now).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OTP{
+			Email:     email,
+			Phone:     phone,
+			Channel:   channel,
+			Code:      code,
+			ExpiresAt: now.Add(h.cfg.OTPExpiry),
+		}).Error
 	})
+	return code, err
 }
 
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -358,18 +550,24 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 	// Validate refresh token
 	claims, err := utils.ValidateToken(req.RefreshToken)
-	if err != nil {
+	if err != nil || claims.Admin || claims.Scope != "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
 	}
 
 	// Find user
 	var user models.User
-	if err := h.db.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", claims.UserID).First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
+	// Suspended and deactivated users can't keep their session alive
+	if !user.IsActive {
+		respondInactive(c, user)
+		return
+	}
+
 	// Generate new tokens
 	accessToken, err := utils.GenerateToken(user.ID, user.Email)
 	if err != nil {
@@ -390,6 +588,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -398,15 +597,39 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	// Remove session from Redis
 	sessionKey := "session:" + strconv.FormatUint(uint64(userID.(uint)), 10)
-	h.redis.Del(c.Request.Context(), sessionKey)
+	h.redis.Del(ctx, sessionKey)
+	h.redis.ClearPresence(ctx, userID.(uint))
 
 	// Update user online status
 	var user models.User
-	if err := h.db.Where("id = ?", userID).First(&user).Error; err == nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err == nil {
 		user.IsOnline = false
 		user.LastSeen = &[]time.Time{time.Now()}[0]
-		h.db.Save(&user)
+		h.db.WithContext(ctx).Save(&user)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+// applyApproximateLocation places the user in the city their IP resolves to
+// and marks the coordinates as low-precision. Lookup failures leave the user
+// as they were.
+func (h *AuthHandler) applyApproximateLocation(user *models.User, ip string) {
+	if h.geo == nil {
+		return
+	}
+	location, err := h.geo.Locate(ip)
+	if err != nil {
+		if !errors.Is(err, services.ErrGeoIPDisabled) && !errors.Is(err, services.ErrLocationUnknown) {
+			log.Printf("GeoIP lookup failed for user %d: %v", user.ID, err)
+		}
+		return
+	}
+
+	user.Latitude = &location.Latitude
+	user.Longitude = &location.Longitude
+	user.LocationApproximate = true
+	if user.Location == nil || *user.Location == "" {
+		user.Location = &location.City
+	}
+}
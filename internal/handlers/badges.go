@@ -0,0 +1,482 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Badge kinds
+const (
+	BadgeStudent      = "student"
+	BadgeProfessional = "professional"
+)
+
+var badgeKinds = map[string]bool{BadgeStudent: true, BadgeProfessional: true}
+
+// studentBadgeCodeAttempts is how many wrong codes a student badge code
+// takes before it is thrown away and a new one has to be requested
+const studentBadgeCodeAttempts = 5
+
+type StudentBadgeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ProfessionalBadgeRequest struct {
+	Employer string `json:"employer" binding:"required,max=100"`
+	JobTitle string `json:"job_title" binding:"required,max=100"`
+	Evidence string `json:"evidence,omitempty" binding:"max=500"`
+}
+
+type UpdateBadgeRequest struct {
+	ShowOnProfile bool `json:"show_on_profile"`
+}
+
+// BadgeReviewItem is a pending professional badge as shown to admins
+type BadgeReviewItem struct {
+	Badge models.Badge     `json:"badge"`
+	User  PendingPhotoUser `json:"user"`
+}
+
+// profileBadges lists the badges a user shows on their profile
+func profileBadges(user *models.User) []string {
+	var badges []string
+	if user.StudentBadge {
+		badges = append(badges, BadgeStudent)
+	}
+	if user.ProfessionalBadge {
+		badges = append(badges, BadgeProfessional)
+	}
+	return badges
+}
+
+// syncBadgeFlags sets the user's profile badge flags from their verified
+// badges they chose to show
+func syncBadgeFlags(tx *gorm.DB, userID uint) error {
+	var kinds []string
+	if err := tx.Model(&models.Badge{}).
+		Where("user_id = ? AND status = ? AND show_on_profile = ?", userID, "verified", true).
+		Pluck("kind", &kinds).Error; err != nil {
+		return err
+	}
+	shown := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		shown[kind] = true
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"student_badge":      shown[BadgeStudent],
+		"professional_badge": shown[BadgeProfessional],
+		"version":            gorm.Expr("version + 1"),
+	}).Error
+}
+
+// studentEmailDomain returns the university domain of the address, or empty
+// when it isn't under one of STUDENT_EMAIL_DOMAINS
+func (h *VerificationHandler) studentEmailDomain(email string) string {
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return ""
+	}
+	for _, allowed := range h.cfg.StudentEmailDomains {
+		allowed = strings.ToLower(strings.TrimPrefix(allowed, "."))
+		if strings.HasSuffix(domain, "."+allowed) {
+			return domain
+		}
+	}
+	return ""
+}
+
+func studentBadgeCodeKey(userID uint) string {
+	return fmt.Sprintf("student_badge_code:%d", userID)
+}
+
+func studentBadgeAttemptsKey(userID uint) string {
+	return fmt.Sprintf("student_badge_attempts:%d", userID)
+}
+
+// GetBadges lists the caller's badges in every state
+func (h *VerificationHandler) GetBadges(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var badges []models.Badge
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("kind ASC").Find(&badges).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch badges"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"badges": badges, "student_email_domains": h.cfg.StudentEmailDomains})
+}
+
+// RequestStudentBadge emails a code to the caller's university address.
+// Confirming it earns the student badge.
+func (h *VerificationHandler) RequestStudentBadge(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req StudentBadgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if h.studentEmailDomain(email) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Use your university email address",
+			"domains": h.cfg.StudentEmailDomains,
+		})
+		return
+	}
+
+	var taken int64
+	h.db.WithContext(ctx).Model(&models.Badge{}).
+		Where("email = ? AND user_id != ? AND status = ?", email, userID, "verified").
+		Count(&taken)
+	if taken > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This email already verified another account"})
+		return
+	}
+
+	sends, err := h.redis.Incr(ctx, fmt.Sprintf("student_badge_sends:%d", userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send code"})
+		return
+	}
+	if sends == 1 {
+		h.redis.Expire(ctx, fmt.Sprintf("student_badge_sends:%d", userID), time.Hour)
+	}
+	if sends > h.cfg.OTPMaxPerHour {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many codes requested, please try again later"})
+		return
+	}
+
+	code, err := utils.GenerateOTP()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send code"})
+		return
+	}
+	// A new code replaces the last, along with the address it was sent to,
+	// and gets its own wrong guesses
+	if err := h.redis.Set(ctx, studentBadgeCodeKey(userID.(uint)), email+"|"+code, h.cfg.OTPExpiry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send code"})
+		return
+	}
+	h.redis.Del(ctx, studentBadgeAttemptsKey(userID.(uint)))
+
+	body := fmt.Sprintf("Your student badge code is %s. It expires in %s.", code, h.cfg.OTPExpiry)
+	if err := h.mailer.Send(ctx, email, "Confirm your university email", body); err != nil {
+		log.Printf("Failed to email student badge code to user %d: %v", userID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send the code, please try again"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "We sent a code to " + email, "expires_in": h.cfg.OTPExpiry.String()})
+}
+
+// ConfirmStudentBadge checks the code sent to the caller's university
+// address and gives them the student badge. After
+// studentBadgeCodeAttempts wrong codes the code is thrown away.
+func (h *VerificationHandler) ConfirmStudentBadge(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req ConfirmVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := studentBadgeCodeKey(userID.(uint))
+	attemptsKey := studentBadgeAttemptsKey(userID.(uint))
+	pending, err := h.redis.Get(ctx, key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+	email, code, _ := strings.Cut(pending, "|")
+	if code != req.Code {
+		attempts, err := h.redis.Incr(ctx, attemptsKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check code"})
+			return
+		}
+		if attempts >= studentBadgeCodeAttempts {
+			h.redis.Del(ctx, key, attemptsKey)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many wrong codes, please request a new one"})
+			return
+		}
+		if attempts == 1 {
+			h.redis.Expire(ctx, attemptsKey, h.cfg.OTPExpiry)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+	h.redis.Del(ctx, key, attemptsKey)
+
+	now := time.Now()
+	badge := models.Badge{
+		UserID:        userID.(uint),
+		Kind:          BadgeStudent,
+		Status:        "verified",
+		Email:         &email,
+		Institution:   h.studentEmailDomain(email),
+		ShowOnProfile: true,
+		VerifiedAt:    &now,
+	}
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "kind"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "email", "institution", "show_on_profile", "rejection_reason", "verified_at", "updated_at"}),
+		}).Create(&badge).Error; err != nil {
+			return err
+		}
+		return syncBadgeFlags(tx, badge.UserID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save badge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"badge": badge})
+}
+
+// RequestProfessionalBadge submits the caller's employer and role for a
+// moderator to confirm
+func (h *VerificationHandler) RequestProfessionalBadge(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req ProfessionalBadgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.Badge
+	err := h.db.WithContext(ctx).Where("user_id = ? AND kind = ?", userID, BadgeProfessional).First(&existing).Error
+	if err == nil && existing.Status != "rejected" {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a pending or verified professional badge"})
+		return
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit badge"})
+		return
+	}
+
+	badge := models.Badge{
+		UserID:        userID.(uint),
+		Kind:          BadgeProfessional,
+		Status:        "pending",
+		Institution:   strings.TrimSpace(req.Employer),
+		JobTitle:      strings.TrimSpace(req.JobTitle),
+		Evidence:      strings.TrimSpace(req.Evidence),
+		ShowOnProfile: true,
+	}
+	if err := h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"status", "institution", "job_title", "evidence", "show_on_profile",
+			"rejection_reason", "reviewed_by", "reviewed_at", "updated_at",
+		}),
+	}).Create(&badge).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit badge"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"badge": badge})
+}
+
+// UpdateBadge shows or hides one of the caller's badges on their profile
+func (h *VerificationHandler) UpdateBadge(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	kind := c.Param("kind")
+	if !badgeKinds[kind] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Badges are student or professional"})
+		return
+	}
+
+	var req UpdateBadgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var badge models.Badge
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND kind = ?", userID, kind).First(&badge).Error; err != nil {
+			return err
+		}
+		badge.ShowOnProfile = req.ShowOnProfile
+		if err := tx.Model(&badge).Update("show_on_profile", req.ShowOnProfile).Error; err != nil {
+			return err
+		}
+		return syncBadgeFlags(tx, badge.UserID)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update badge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"badge": badge})
+}
+
+// DeleteBadge removes one of the caller's badges, or withdraws a request
+func (h *VerificationHandler) DeleteBadge(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var deleted int64
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND kind = ?", userID, c.Param("kind")).Delete(&models.Badge{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return syncBadgeFlags(tx, userID.(uint))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete badge"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Badge removed"})
+}
+
+// GetBadgeRequests lists professional badges for admin review, oldest
+// first. Defaults to pending ones.
+func (h *AdminHandler) GetBadgeRequests(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.Badge{}).
+		Where("kind = ? AND status = ?", BadgeProfessional, c.DefaultQuery("status", "pending"))
+
+	var total int64
+	query.Count(&total)
+
+	var badges []models.Badge
+	if err := query.Order("created_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&badges).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch badges"})
+		return
+	}
+
+	items := make([]BadgeReviewItem, 0, len(badges))
+	for _, badge := range badges {
+		var user models.User
+		h.db.WithContext(ctx).Where("id = ?", badge.UserID).First(&user)
+		items = append(items, BadgeReviewItem{
+			Badge: badge,
+			User: PendingPhotoUser{
+				ID:        user.ID,
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+				Gender:    user.Gender,
+				IsActive:  user.IsActive,
+				CreatedAt: user.CreatedAt,
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"badges": items,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// DecideBadge approves or rejects a pending professional badge
+func (h *AdminHandler) DecideBadge(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	badgeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid badge ID"})
+		return
+	}
+
+	var req IdentityDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Decision == "reject" && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required when rejecting a badge"})
+		return
+	}
+
+	var badge models.Badge
+	if err := h.db.WithContext(ctx).Where("id = ? AND status = ?", badgeID, "pending").First(&badge).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending badge not found"})
+		return
+	}
+
+	now := time.Now()
+	reviewer := adminID.(uint)
+	badge.ReviewedBy = &reviewer
+	badge.ReviewedAt = &now
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		notification := models.Notification{
+			UserID: badge.UserID,
+			Type:   "badge_verified",
+			Title:  "Professional badge verified",
+			Body:   "Your professional badge has been verified and now shows on your profile.",
+			Data:   fmt.Sprintf(`{"kind":%q}`, badge.Kind),
+		}
+		if req.Decision == "approve" {
+			badge.Status = "verified"
+			badge.VerifiedAt = &now
+		} else {
+			badge.Status = "rejected"
+			badge.RejectionReason = &req.Reason
+			notification.Type = "badge_rejected"
+			notification.Title = "Professional badge unsuccessful"
+			notification.Body = fmt.Sprintf("We couldn't verify your professional badge: %s", req.Reason)
+		}
+		if err := tx.Save(&badge).Error; err != nil {
+			return err
+		}
+		if err := syncBadgeFlags(tx, badge.UserID); err != nil {
+			return err
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, notification.Type, "user", badge.UserID, req.Reason)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"badge": badge})
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bioLanguages are the languages bios can be written in and translated to,
+// the same ones the app can be used in
+var bioLanguages = map[string]bool{
+	utils.LanguageAmharic: true,
+	utils.LanguageEnglish: true,
+}
+
+type UpdateBioRequest struct {
+	Text string `json:"text" binding:"required,max=500"`
+}
+
+// BioResponse is a user's bio in the language a viewer asked for.
+// MachineTranslated is set when the user didn't write it in that language,
+// and OriginalLanguage is then the language they did.
+type BioResponse struct {
+	UserID            uint   `json:"user_id"`
+	Text              string `json:"text"`
+	Language          string `json:"language,omitempty"`
+	MachineTranslated bool   `json:"machine_translated"`
+	OriginalLanguage  string `json:"original_language,omitempty"`
+	Blurred           bool   `json:"blurred,omitempty"` // suggestive, for a viewer in clean mode blur
+}
+
+// GetBios lists the bios the caller wrote in other languages
+func (h *UserHandler) GetBios(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var bios []models.ProfileBio
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("language ASC").Find(&bios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bios"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bios": bios})
+}
+
+// PutBio saves the caller's bio in the language in the path, replacing any
+// they wrote in it before. Viewers who read that language see it instead of
+// a translation of the profile bio.
+func (h *UserHandler) PutBio(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	language := c.Param("language")
+	if !bioLanguages[language] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bios can be written in am or en"})
+		return
+	}
+
+	var req UpdateBioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bio can't be empty"})
+		return
+	}
+	if detected := utils.DetectLanguage(text); bioLanguages[detected] && detected != language {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This bio doesn't look like it's written in " + language})
+		return
+	}
+	if utils.ContainsProfanity(text, language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your bio contains words that aren't allowed"})
+		return
+	}
+
+	bio := models.ProfileBio{UserID: userID.(uint), Language: language, Text: text, Suggestive: utils.IsSuggestive(text, language)}
+	err := h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text", "suggestive", "updated_at"}),
+	}).Create(&bio).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save bio"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bio": bio})
+}
+
+// DeleteBio removes the caller's bio in the language in the path
+func (h *UserHandler) DeleteBio(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	result := h.db.WithContext(ctx).Where("user_id = ? AND language = ?", userID, c.Param("language")).Delete(&models.ProfileBio{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bio"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bio not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bio deleted"})
+}
+
+// GetBio returns a user's bio in the language asked for, else the caller's
+// app language. A bio the user wrote in it wins; otherwise the profile bio
+// is machine translated. When there is no translation to be had the profile
+// bio comes back as written. Clean mode applies as on profiles.
+func (h *UserHandler) GetBio(c *gin.Context) {
+	ctx := c.Request.Context()
+	viewerID, _ := c.Get("user_id")
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	db := h.db.WithContext(ctx)
+
+	var blocks int64
+	db.Model(&models.BlockedUser{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", viewerID, targetID, targetID, viewerID).
+		Count(&blocks)
+	var user models.User
+	if blocks > 0 || db.Select("id", "bio", "bio_language", "bio_suggestive").Where("id = ? AND is_active = ?", targetID, true).First(&user).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var viewer models.User
+	db.Select("id", "language", "clean_mode").Where("id = ?", viewerID).First(&viewer)
+	language := c.Query("language")
+	if language == "" {
+		language = viewer.Language
+	}
+	if !bioLanguages[language] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bios can be read in am or en"})
+		return
+	}
+
+	var written models.ProfileBio
+	err = db.Where("user_id = ? AND language = ?", user.ID, language).First(&written).Error
+	if err == nil {
+		c.JSON(http.StatusOK, cleanBio(BioResponse{UserID: user.ID, Text: written.Text, Language: language}, written.Suggestive, viewer.CleanMode))
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bio"})
+		return
+	}
+
+	original := BioResponse{UserID: user.ID, Language: user.BioLanguage}
+	if user.Bio == nil || strings.TrimSpace(*user.Bio) == "" {
+		c.JSON(http.StatusOK, cleanBio(original, user.BioSuggestive, viewer.CleanMode))
+		return
+	}
+	original.Text = *user.Bio
+	if user.BioLanguage == language {
+		c.JSON(http.StatusOK, cleanBio(original, user.BioSuggestive, viewer.CleanMode))
+		return
+	}
+
+	// Bios too short to tell, or in neither language, are left to the
+	// translation service to detect
+	from := user.BioLanguage
+	if !bioLanguages[from] {
+		from = ""
+	}
+	translated, err := h.translator.Translate(ctx, *user.Bio, from, language)
+	if err != nil {
+		if !errors.Is(err, services.ErrTranslatorDisabled) {
+			log.Printf("Failed to translate bio of user %d to %s: %v", user.ID, language, err)
+		}
+		c.JSON(http.StatusOK, cleanBio(original, user.BioSuggestive, viewer.CleanMode))
+		return
+	}
+
+	c.JSON(http.StatusOK, cleanBio(BioResponse{
+		UserID:            user.ID,
+		Text:              translated,
+		Language:          language,
+		MachineTranslated: true,
+		OriginalLanguage:  user.BioLanguage,
+	}, user.BioSuggestive, viewer.CleanMode))
+}
+
+// cleanBio blurs or empties a suggestive bio for a viewer in clean mode
+func cleanBio(bio BioResponse, suggestive bool, cleanMode string) BioResponse {
+	if !suggestive || bio.Text == "" {
+		return bio
+	}
+	switch cleanMode {
+	case cleanModeBlur:
+		bio.Blurred = true
+	case cleanModeHide:
+		bio.Text = ""
+	}
+	return bio
+}
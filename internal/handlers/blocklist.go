@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlockByPhoneRequest struct {
+	Phones []string `json:"phones" binding:"required,min=1,max=500"`
+}
+
+type BulkUnblockRequest struct {
+	UserIDs       []uint `json:"user_ids,omitempty"`
+	PhoneBlockIDs []uint `json:"phone_block_ids,omitempty"`
+}
+
+type BlockedUserResponse struct {
+	UserID    uint      `json:"user_id"`
+	FirstName string    `json:"first_name"`
+	PhotoURL  string    `json:"photo_url,omitempty"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// BlockByPhone blocks phone numbers pre-emptively. Numbers that already belong
+// to an account are blocked immediately; the rest take effect on registration.
+// The response never reveals which numbers are registered.
+func (h *UserHandler) BlockByPhone(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req BlockByPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	blocks := make([]models.PhoneBlock, 0, len(req.Phones))
+	hashes := make([]string, 0, len(req.Phones))
+	for _, phone := range req.Phones {
+		formatted := utils.FormatPhoneNumber(phone)
+		if len(formatted) < 8 {
+			continue
+		}
+		hash := utils.HashPhone(formatted)
+		hashes = append(hashes, hash)
+		blocks = append(blocks, models.PhoneBlock{
+			BlockerID: userID.(uint),
+			PhoneHash: hash,
+			PhoneHint: "••••" + formatted[len(formatted)-2:],
+		})
+	}
+
+	if len(blocks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid phone numbers provided"})
+		return
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&blocks).Error; err != nil {
+			return err
+		}
+
+		var existingIDs []uint
+		if err := tx.Model(&models.User{}).Where("phone_hash IN ? AND id != ?", hashes, userID).
+			Pluck("id", &existingIDs).Error; err != nil {
+			return err
+		}
+		for _, blockedID := range existingIDs {
+			if err := blockUser(tx, userID.(uint), blockedID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block phone numbers"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Phone numbers blocked successfully", "count": len(blocks)})
+}
+
+// GetBlockedUsers returns everyone the user has blocked, plus phone blocks
+func (h *UserHandler) GetBlockedUsers(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var blocked []models.BlockedUser
+	// Accounts blocked through a phone block are left out, so the list never
+	// shows who a blocked number belongs to
+	if err := h.db.WithContext(ctx).Preload("Blocked.ProfilePhotos", "is_primary = ?", true).
+		Where("blocker_id = ? AND via_phone = ?", userID, false).Order("created_at DESC").Find(&blocked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blocked users"})
+		return
+	}
+
+	users := make([]BlockedUserResponse, 0, len(blocked))
+	for _, b := range blocked {
+		resp := BlockedUserResponse{
+			UserID:    b.BlockedID,
+			FirstName: b.Blocked.FirstName,
+			BlockedAt: b.CreatedAt,
+		}
+		if len(b.Blocked.ProfilePhotos) > 0 {
+			resp.PhotoURL = b.Blocked.ProfilePhotos[0].URL
+		}
+		users = append(users, resp)
+	}
+
+	var phoneBlocks []models.PhoneBlock
+	h.db.WithContext(ctx).Where("blocker_id = ?", userID).Order("created_at DESC").Find(&phoneBlocks)
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":        users,
+		"phone_blocks": phoneBlocks,
+	})
+}
+
+func (h *UserHandler) BulkUnblock(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req BulkUnblockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.UserIDs) == 0 && len(req.PhoneBlockIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to unblock"})
+		return
+	}
+
+	var unblocked int64
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(req.UserIDs) > 0 {
+			result := tx.Where("blocker_id = ? AND blocked_id IN ? AND via_phone = ?", userID, req.UserIDs, false).Delete(&models.BlockedUser{})
+			if result.Error != nil {
+				return result.Error
+			}
+			unblocked += result.RowsAffected
+		}
+		if len(req.PhoneBlockIDs) > 0 {
+			var hashes []string
+			if err := tx.Model(&models.PhoneBlock{}).Where("blocker_id = ? AND id IN ?", userID, req.PhoneBlockIDs).
+				Pluck("phone_hash", &hashes).Error; err != nil {
+				return err
+			}
+			result := tx.Where("blocker_id = ? AND id IN ?", userID, req.PhoneBlockIDs).Delete(&models.PhoneBlock{})
+			if result.Error != nil {
+				return result.Error
+			}
+			unblocked += result.RowsAffected
+
+			// Lift the blocks the numbers placed on their owners' accounts
+			if len(hashes) > 0 {
+				if err := tx.Where("blocker_id = ? AND via_phone = ? AND blocked_id IN (?)", userID, true,
+					tx.Model(&models.User{}).Select("id").Where("phone_hash IN ?", hashes)).
+					Delete(&models.BlockedUser{}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unblocked successfully", "count": unblocked})
+}
+
+// applyPhoneBlocks turns pending phone blocks into user blocks once the phone
+// owner registers
+func applyPhoneBlocks(db *gorm.DB, user *models.User) error {
+	if user.PhoneHash == nil {
+		return nil
+	}
+
+	var blockerIDs []uint
+	if err := db.Model(&models.PhoneBlock{}).Where("phone_hash = ?", *user.PhoneHash).
+		Pluck("blocker_id", &blockerIDs).Error; err != nil {
+		return err
+	}
+
+	for _, blockerID := range blockerIDs {
+		if err := blockUser(db, blockerID, user.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockUser blocks the owner of a phone number the blocker blocked. The block
+// is marked as coming from the phone block, which is how it's listed.
+func blockUser(db *gorm.DB, blockerID, blockedID uint) error {
+	var count int64
+	db.Model(&models.BlockedUser{}).Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	return db.Create(&models.BlockedUser{BlockerID: blockerID, BlockedID: blockedID, ViaPhone: true}).Error
+}
+
+// endMatchBetween closes any active match between the two users and its
+// conversation
+func endMatchBetween(ctx context.Context, db *gorm.DB, rc *redis.Client, userA, userB uint) error {
+	var match models.Match
+	err := db.WithContext(ctx).Where("is_active = ? AND ((user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?))",
+		true, userA, userB, userB, userA).First(&match).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var conversationIDs []uint
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&match).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Conversation{}).Where("match_id = ?", match.ID).Pluck("id", &conversationIDs).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Conversation{}).Where("match_id = ?", match.ID).Update("is_active", false).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	rc.Del(ctx, "match:"+strconv.FormatUint(uint64(match.ID), 10))
+	rc.ForgetConversations(ctx, conversationIDs...)
+	return nil
+}
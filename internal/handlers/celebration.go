@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"math"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+)
+
+// MatchCelebration is what the "It's a match!" screen shows. The server
+// composes it once and both users get the same payload, in the match
+// response and in their notifications, so every client draws the same screen.
+type MatchCelebration struct {
+	MatchID         uint              `json:"match_id"`
+	Users           []CelebrationUser `json:"users"` // the user who made the match first
+	SharedInterests []models.Interest `json:"shared_interests"`
+	Compatibility   int               `json:"compatibility"` // 0-100
+	Opener          string            `json:"suggested_opener,omitempty"`
+}
+
+// CelebrationUser is one side of a new match
+type CelebrationUser struct {
+	ID        uint    `json:"id"`
+	FirstName string  `json:"first_name"`
+	PhotoURL  *string `json:"photo_url,omitempty"`
+	AltText   string  `json:"alt_text,omitempty"`
+	Blurred   bool    `json:"blurred,omitempty"` // suggestive, and the other user is in clean mode blur
+}
+
+// matchCelebration composes the celebration for a new match, which
+// initiatorID made with otherID. Each user's photo follows the clean mode of
+// the other, who sees it as their new match.
+func (h *MatchHandler) matchCelebration(ctx context.Context, match models.Match, initiatorID, otherID uint, shared []models.Interest, openers []string) MatchCelebration {
+	celebration := MatchCelebration{
+		MatchID:         match.ID,
+		Users:           []CelebrationUser{{ID: initiatorID}, {ID: otherID}},
+		SharedInterests: shared,
+	}
+	if len(openers) > 0 {
+		celebration.Opener = openers[0]
+	}
+
+	var users []models.User
+	h.db.WithContext(ctx).Preload("ProfilePhotos").Where("id IN ?", []uint{initiatorID, otherID}).Find(&users)
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	for i := range celebration.Users {
+		user, ok := byID[celebration.Users[i].ID]
+		if !ok {
+			continue
+		}
+		celebration.Users[i].FirstName = user.FirstName
+
+		cleanMode := byID[celebration.Users[1-i].ID].CleanMode
+		photos := user.ProfilePhotos
+		if cleanMode == cleanModeHide {
+			photos = make([]models.ProfilePhoto, 0, len(user.ProfilePhotos))
+			for _, photo := range user.ProfilePhotos {
+				if !photo.Suggestive {
+					photos = append(photos, photo)
+				}
+			}
+		}
+		if photo := primaryPhoto(photos); photo != nil {
+			celebration.Users[i].PhotoURL = &photo.URL
+			celebration.Users[i].AltText = photo.AltText
+			celebration.Users[i].Blurred = photo.Suggestive && cleanMode == cleanModeBlur
+		}
+	}
+
+	a, b := byID[initiatorID], byID[otherID]
+	celebration.Compatibility = compatibility(&a, &b, len(shared))
+	return celebration
+}
+
+// primaryPhoto returns the photo other users see first: the primary one, or
+// else the first public one
+func primaryPhoto(photos []models.ProfilePhoto) *models.ProfilePhoto {
+	public := publicPhotos(photos)
+	for i := range public {
+		if public[i].IsPrimary {
+			return &public[i]
+		}
+	}
+	if len(public) > 0 {
+		return &public[0]
+	}
+	return nil
+}
+
+// compatibility scores how well two users fit, the same either way round:
+// up to 40 for shared interests, 30 for relationship intents and 30 for
+// being close by. Unknown intents and locations, and locations only guessed
+// from IP addresses, count as halfway. Distances are rounded as they are
+// shown, so the score doesn't give away more than the profile does.
+func compatibility(a, b *models.User, shared int) int {
+	score := 10 * math.Min(float64(shared), 4)
+	score += 15 + intentCompatibility(a.RelationshipIntent, b.RelationshipIntent)
+
+	if hasPreciseLocation(a) && hasPreciseLocation(b) {
+		distance := utils.RoundDistance(utils.HaversineKm(*a.Latitude, *a.Longitude, *b.Latitude, *b.Longitude))
+		score += math.Max(0, 30-distance/2)
+	} else {
+		score += 15
+	}
+
+	return int(math.Round(math.Max(0, math.Min(100, score))))
+}
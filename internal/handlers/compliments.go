@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	complimentsPerDay = 3
+
+	// complimentMaxDismissed is how many of a user's compliments can be
+	// dismissed in a week before they can't send more until it passes
+	complimentMaxDismissed    = 5
+	complimentDismissalWindow = 7 * 24 * time.Hour
+)
+
+type SendComplimentRequest struct {
+	PhotoID uint   `json:"photo_id" binding:"required"`
+	Message string `json:"message" binding:"required,max=150"`
+}
+
+type ComplimentResponse struct {
+	ID        uint                `json:"id"`
+	User      PublicUserResponse  `json:"user"`
+	Photo     models.ProfilePhoto `json:"photo"`
+	Message   string              `json:"message"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// SendCompliment compliments one of another user's photos without liking
+// them. Each user can be complimented once by the same sender, and senders
+// have a small daily allowance, shut off for a week when too many of their
+// compliments are dismissed.
+func (h *MatchHandler) SendCompliment(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	recipientID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if uint(recipientID) == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't compliment yourself"})
+		return
+	}
+	if respondIfUnverified(c, h.db.WithContext(ctx), h.cfg, userID, "likes") {
+		return
+	}
+
+	var req SendComplimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Compliment can't be empty"})
+		return
+	}
+	if len(utils.DetectContactInfo(message)) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Compliments can't include contact details"})
+		return
+	}
+	if utils.ContainsProfanity(message, utils.DetectLanguage(message)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your compliment contains words that aren't allowed"})
+		return
+	}
+
+	db := h.db.WithContext(ctx)
+	var recipient models.User
+	if err := db.Where("id = ? AND is_active = ? AND is_paused = ? AND age_flagged_at IS NULL", recipientID, true, false).Scopes(notOnWaitlist).First(&recipient).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	var sender models.User
+	if err := db.Select("id", "gender", "seeking").Where("id = ?", userID).First(&sender).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !utils.GendersCompatible(sender.Gender, sender.Seeking, recipient.Gender, recipient.Seeking) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This profile doesn't match your or their gender preferences"})
+		return
+	}
+	if h.blockedEitherWay(ctx, sender.ID, recipient.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if h.matched(ctx, sender.ID, recipient.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You're already matched"})
+		return
+	}
+	if h.keptApart(ctx, sender.ID, recipient.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You unmatched recently and can't compliment each other yet"})
+		return
+	}
+
+	var photos int64
+	db.Model(&models.ProfilePhoto{}).
+		Where("id = ? AND user_id = ? AND moderation_status = ? AND is_private = ?", req.PhotoID, recipient.ID, "approved", false).
+		Count(&photos)
+	if photos == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photo not found on this profile"})
+		return
+	}
+
+	var sent int64
+	db.Model(&models.Compliment{}).Where("sender_id = ? AND recipient_id = ?", sender.ID, recipient.ID).Count(&sent)
+	if sent > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You've already complimented this user"})
+		return
+	}
+
+	now := time.Now()
+	var dismissed int64
+	db.Model(&models.Compliment{}).
+		Where("sender_id = ? AND status = ? AND responded_at > ?", sender.ID, "dismissed", now.Add(-complimentDismissalWindow)).
+		Count(&dismissed)
+	if dismissed >= complimentMaxDismissed {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many of your compliments were dismissed, please try again later", "code": "COMPLIMENTS_PAUSED"})
+		return
+	}
+	var today int64
+	db.Model(&models.Compliment{}).Where("sender_id = ? AND created_at > ?", sender.ID, now.Add(-24*time.Hour)).Count(&today)
+	if today >= complimentsPerDay {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "You've sent all your compliments for today", "code": "COMPLIMENT_LIMIT"})
+		return
+	}
+
+	compliment := models.Compliment{
+		SenderID:    sender.ID,
+		RecipientID: recipient.ID,
+		PhotoID:     req.PhotoID,
+		Message:     message,
+		Status:      "pending",
+	}
+	if err := db.Create(&compliment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send compliment"})
+		return
+	}
+	h.notifyCompliment(ctx, compliment)
+
+	c.JSON(http.StatusCreated, gin.H{"compliment": compliment})
+}
+
+func (h *MatchHandler) notifyCompliment(ctx context.Context, compliment models.Compliment) {
+	data, _ := json.Marshal(gin.H{
+		"compliment_id": compliment.ID,
+		"photo_id":      compliment.PhotoID,
+	})
+	notification := models.Notification{
+		UserID: compliment.RecipientID,
+		Type:   "photo_compliment",
+		Title:  "New compliment",
+		Body:   "Someone complimented one of your photos.",
+		Data:   string(data),
+	}
+	if err := h.db.WithContext(ctx).Create(&notification).Error; err != nil {
+		return
+	}
+	h.notifier.Dispatch(services.Notice{Notification: notification, Text: "Someone complimented one of your photos. Open the app to see it."})
+}
+
+// GetCompliments lists the compliments waiting on the caller, newest first
+func (h *MatchHandler) GetCompliments(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.Compliment{}).
+		Where("recipient_id = ? AND status = ?", userID, "pending").
+		Where("sender_id NOT IN (SELECT blocked_id FROM blocked_users WHERE blocker_id = ?)", userID).
+		Where("sender_id IN (SELECT id FROM users WHERE is_active = ? AND deleted_at IS NULL)", true)
+
+	var total int64
+	query.Count(&total)
+
+	var compliments []models.Compliment
+	if err := query.Preload("Sender.ProfilePhotos").Preload("Sender.Interests").Preload("Sender.Prompts", orderedPrompts).Preload("Photo").
+		Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&compliments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch compliments"})
+		return
+	}
+
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+	results := make([]ComplimentResponse, 0, len(compliments))
+	for _, compliment := range compliments {
+		results = append(results, ComplimentResponse{
+			ID:        compliment.ID,
+			User:      newPublicUser(compliment.Sender, viewer),
+			Photo:     compliment.Photo,
+			Message:   compliment.Message,
+			CreatedAt: compliment.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"compliments": results,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+	})
+}
+
+// AcceptCompliment matches the caller with the user who complimented them.
+// The compliment opens the conversation as a compliment message, which
+// doesn't count as the starter writing first.
+func (h *MatchHandler) AcceptCompliment(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	compliment, ok := h.pendingCompliment(c)
+	if !ok {
+		return
+	}
+
+	var recipient, sender models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&recipient).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := h.db.WithContext(ctx).Where("id = ? AND is_active = ?", compliment.SenderID, true).First(&sender).Error; err != nil ||
+		h.blockedEitherWay(ctx, sender.ID, recipient.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	lock, err := h.redis.Obtain(ctx, pairLockKey(sender.ID, recipient.ID), matchLockTTL, redis.LockOptions{Wait: matchLockWait})
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Please try again"})
+		return
+	}
+	defer lock.Release(context.Background())
+
+	if h.matched(ctx, sender.ID, recipient.ID) {
+		h.respondToCompliment(ctx, &compliment, "accepted", nil)
+		c.JSON(http.StatusOK, gin.H{"message": "You're already matched"})
+		return
+	}
+	if h.keptApart(ctx, sender.ID, recipient.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You unmatched recently and can't match again yet"})
+		return
+	}
+
+	response, err := h.openMatch(ctx, lock, recipient, sender, func(conversationID uint) {
+		message := models.Message{
+			ConversationID: conversationID,
+			SenderID:       compliment.SenderID,
+			Content:        compliment.Message,
+			MessageType:    "compliment",
+			CreatedAt:      compliment.CreatedAt,
+		}
+		h.db.WithContext(ctx).Create(&message)
+	})
+	if errors.Is(err, errStaleLock) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Please try again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create match"})
+		return
+	}
+	matchID := response["id"].(uint)
+	h.respondToCompliment(ctx, &compliment, "accepted", &matchID)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "It's a match!", "match": response})
+}
+
+// DismissCompliment declines a compliment. The sender isn't told.
+func (h *MatchHandler) DismissCompliment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	compliment, ok := h.pendingCompliment(c)
+	if !ok {
+		return
+	}
+	if err := h.respondToCompliment(ctx, &compliment, "dismissed", nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss compliment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Compliment dismissed"})
+}
+
+// pendingCompliment loads the compliment in the path, which must be waiting
+// on the caller
+func (h *MatchHandler) pendingCompliment(c *gin.Context) (models.Compliment, bool) {
+	userID, _ := c.Get("user_id")
+	var compliment models.Compliment
+	complimentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid compliment ID"})
+		return compliment, false
+	}
+
+	err = h.db.WithContext(c.Request.Context()).
+		Where("id = ? AND recipient_id = ? AND status = ?", complimentID, userID, "pending").
+		First(&compliment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Compliment not found"})
+		return compliment, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch compliment"})
+		return compliment, false
+	}
+	return compliment, true
+}
+
+func (h *MatchHandler) respondToCompliment(ctx context.Context, compliment *models.Compliment, status string, matchID *uint) error {
+	now := time.Now()
+	compliment.Status = status
+	compliment.MatchID = matchID
+	compliment.RespondedAt = &now
+	return h.db.WithContext(ctx).Model(compliment).Updates(map[string]interface{}{
+		"status":       status,
+		"match_id":     matchID,
+		"responded_at": now,
+	}).Error
+}
+
+// blockedEitherWay reports whether either user blocked the other
+func (h *MatchHandler) blockedEitherWay(ctx context.Context, a, b uint) bool {
+	var count int64
+	h.db.WithContext(ctx).Model(&models.BlockedUser{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count)
+	return count > 0
+}
+
+// matched reports whether the two users have an active match
+func (h *MatchHandler) matched(ctx context.Context, a, b uint) bool {
+	var count int64
+	h.db.WithContext(ctx).Model(&models.Match{}).
+		Where("((user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)) AND is_active = ?", a, b, b, a, true).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+)
+
+// contactGuardWarning is returned to senders whose early message contains contact details
+const contactGuardWarning = "For your safety, we recommend keeping the conversation in the app until you know your match better."
+
+// guardedContent returns msg's content as viewerID should see it. Contact
+// details in early messages are blurred for the recipient until the
+// conversation reaches the configured number of messages.
+func (h *MessageHandler) guardedContent(msg models.Message, viewerID uint, conversationSize int64) string {
+	if msg.HasContactInfo && msg.SenderID != viewerID &&
+		conversationSize < h.cfg.ContactGuardThreshold && h.cfg.HasContactGuardMode("blur") {
+		return utils.RedactContactInfo(msg.Content)
+	}
+	return msg.Content
+}
+
+// checkContactInfo marks message when it is an early message containing
+// contact details and returns the kinds detected
+func (h *MessageHandler) checkContactInfo(message *models.Message, conversationSize int64) []string {
+	if conversationSize >= h.cfg.ContactGuardThreshold {
+		return nil
+	}
+
+	kinds := utils.DetectContactInfo(message.Content)
+	if len(kinds) > 0 {
+		message.HasContactInfo = true
+		message.IsFlagged = h.cfg.HasContactGuardMode("flag")
+	}
+	return kinds
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SyncContactsRequest struct {
+	Hashes       []string `json:"hashes" binding:"required,max=5000"` // hex SHA-256 of E.164 numbers
+	HideContacts *bool    `json:"hide_contacts,omitempty"`
+}
+
+// SyncContacts replaces the user's uploaded address book. Clients send only
+// SHA-256 hashes of normalized numbers, which are matched against user phone
+// hashes to power "don't show me people I know" and mutual contact counts.
+func (h *UserHandler) SyncContacts(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req SyncContactsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := make(map[string]bool, len(req.Hashes))
+	contacts := make([]models.ContactHash, 0, len(req.Hashes))
+	for _, hash := range req.Hashes {
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != 32 || seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		contacts = append(contacts, models.ContactHash{UserID: userID.(uint), PhoneHash: hash})
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.ContactHash{}).Error; err != nil {
+			return err
+		}
+		if len(contacts) > 0 {
+			if err := tx.CreateInBatches(&contacts, 500).Error; err != nil {
+				return err
+			}
+		}
+		if req.HideContacts != nil {
+			return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+				"hide_contacts": *req.HideContacts,
+				"version":       gorm.Expr("version + 1"),
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contacts synced successfully", "count": len(contacts)})
+}
+
+func (h *UserHandler) DeleteContacts(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.ContactHash{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contacts deleted successfully"})
+}
+
+// withMutualContacts fills in how many address book entries the viewer shares
+// with each user. Only users who synced their own contacts can have mutuals.
+func withMutualContacts(db *gorm.DB, viewerID uint, users []PublicUserResponse) []PublicUserResponse {
+	if len(users) == 0 {
+		return users
+	}
+
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	var counts []struct {
+		UserID uint
+		Count  int
+	}
+	db.Table("contact_hashes AS mine").
+		Select("theirs.user_id, COUNT(*) AS count").
+		Joins("JOIN contact_hashes AS theirs ON theirs.phone_hash = mine.phone_hash").
+		Where("mine.user_id = ? AND theirs.user_id IN ?", viewerID, ids).
+		Group("theirs.user_id").
+		Scan(&counts)
+
+	byID := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		byID[count.UserID] = count.Count
+	}
+	for i := range users {
+		users[i].MutualContacts = byID[users[i].ID]
+	}
+	return users
+}
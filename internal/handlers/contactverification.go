@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Verification channels. Email and phone are proved with a code sent to
+// them; photos are confirmed by a moderator.
+const (
+	verificationEmail = "email"
+	verificationPhone = "phone"
+	verificationPhoto = "photo"
+)
+
+var verificationChannels = []string{verificationEmail, verificationPhone, verificationPhoto}
+
+// Features the verification policy can gate
+var verificationFeatures = []string{"messaging", "likes"}
+
+type ConfirmVerificationRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type PhoneVerificationRequest struct {
+	Phone string `json:"phone,omitempty"` // a new number to verify; the account's own when empty
+}
+
+type PhotoVerificationRequest struct {
+	Verified bool `json:"verified"`
+}
+
+// ChannelStatus is whether one contact channel is verified, and since when
+type ChannelStatus struct {
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+// FeatureVerification is what a feature needs verified and what the user
+// still lacks of it
+type FeatureVerification struct {
+	Required []string `json:"required"`
+	Missing  []string `json:"missing"`
+}
+
+// verifiedAt returns when the user verified the channel, nil if they haven't
+func verifiedAt(user *models.User, channel string) *time.Time {
+	switch channel {
+	case verificationEmail:
+		return user.EmailVerifiedAt
+	case verificationPhone:
+		return user.PhoneVerifiedAt
+	case verificationPhoto:
+		return user.PhotoVerifiedAt
+	}
+	return nil
+}
+
+// missingVerifications lists the channels the policy requires for feature
+// that the user hasn't verified
+func missingVerifications(cfg *config.Config, user *models.User, feature string) []string {
+	missing := []string{}
+	for _, channel := range cfg.RequiredVerifications(feature) {
+		if verifiedAt(user, channel) == nil {
+			missing = append(missing, channel)
+		}
+	}
+	return missing
+}
+
+// respondIfUnverified rejects the request when the user hasn't verified every
+// channel the policy requires for feature
+func respondIfUnverified(c *gin.Context, db *gorm.DB, cfg *config.Config, userID interface{}, feature string) bool {
+	if len(cfg.RequiredVerifications(feature)) == 0 {
+		return false
+	}
+	var user models.User
+	if err := db.Select("id", "email_verified_at", "phone_verified_at", "photo_verified_at").
+		Where("id = ?", userID).First(&user).Error; err != nil {
+		return false
+	}
+	missing := missingVerifications(cfg, &user, feature)
+	if len(missing) == 0 {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "Verify your account to use " + feature,
+		"code":    "VERIFICATION_REQUIRED",
+		"feature": feature,
+		"missing": missing,
+	})
+	return true
+}
+
+// markVerified records that the user verified the channel. Email and phone
+// also set the legacy is_verified flag, which means either of them.
+func markVerified(tx *gorm.DB, userID uint, channel string, updates map[string]interface{}) error {
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates[channel+"_verified_at"] = time.Now()
+	if channel != verificationPhoto {
+		updates["is_verified"] = true
+	}
+	updates["version"] = gorm.Expr("version + 1")
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error
+}
+
+// GetVerificationStatus returns which of the caller's contact channels are
+// verified and what each gated feature still needs
+func (h *AuthHandler) GetVerificationStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	channels := make(map[string]ChannelStatus, len(verificationChannels))
+	for _, channel := range verificationChannels {
+		at := verifiedAt(&user, channel)
+		channels[channel] = ChannelStatus{Verified: at != nil, VerifiedAt: at}
+	}
+	features := make(map[string]FeatureVerification, len(verificationFeatures))
+	for _, feature := range verificationFeatures {
+		required := h.cfg.RequiredVerifications(feature)
+		if required == nil {
+			required = []string{}
+		}
+		features[feature] = FeatureVerification{Required: required, Missing: missingVerifications(h.cfg, &user, feature)}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels":    channels,
+		"features":    features,
+		"is_verified": user.IsVerified,
+		"id_verified": user.IDVerified,
+		"phone":       user.Phone,
+	})
+}
+
+// SendEmailVerification emails the caller a code that verifies their address
+func (h *AuthHandler) SendEmailVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Your email is already verified"})
+		return
+	}
+
+	code, ok := h.issueVerificationCode(c, verificationEmail, &user, nil)
+	if !ok {
+		return
+	}
+	body := fmt.Sprintf("Your verification code is %s. It expires in %s.", code, h.cfg.OTPExpiry)
+	if err := h.mailer.Send(ctx, user.Email, "Verify your email", body); err != nil {
+		log.Printf("Failed to email verification code to user %d: %v", user.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send the code, please try again"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "We sent a code to your email", "expires_in": h.cfg.OTPExpiry.String()})
+}
+
+// ConfirmEmailVerification checks the code sent to the caller's email
+func (h *AuthHandler) ConfirmEmailVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req ConfirmVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := useVerificationCode(tx, verificationEmail, user.Email, req.Code); err != nil {
+			return err
+		}
+		return markVerified(tx, user.ID, verificationEmail, nil)
+	})
+	if !respondVerificationError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// SendPhoneVerification texts a code to the caller's phone, or to a new
+// number they want to use instead. The number only replaces the account's
+// once the code is confirmed.
+func (h *AuthHandler) SendPhoneVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	// The body is optional; a bare POST verifies the account's own number
+	var req PhoneVerificationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var phone string
+	switch {
+	case req.Phone != "":
+		phone = utils.FormatPhoneNumber(req.Phone)
+	case user.Phone != nil:
+		phone = *user.Phone
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Add a phone number to verify"})
+		return
+	}
+	if len(phone) < 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number"})
+		return
+	}
+	if user.Phone != nil && *user.Phone == phone && user.PhoneVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "This phone number is already verified"})
+		return
+	}
+	if phoneTaken(h.db.WithContext(ctx), phone, user.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another account uses this phone number"})
+		return
+	}
+
+	code, ok := h.issueVerificationCode(c, verificationPhone, &user, &phone)
+	if !ok {
+		return
+	}
+	body := fmt.Sprintf("Your verification code is %s. It expires in %s.", code, h.cfg.OTPExpiry)
+	if err := h.sms.Send(ctx, phone, body); err != nil {
+		log.Printf("Failed to text verification code to user %d: %v", user.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send the code, please try again"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "We sent a code to " + phone, "expires_in": h.cfg.OTPExpiry.String()})
+}
+
+// ConfirmPhoneVerification checks the code texted to the caller and makes
+// the number it was sent to the account's verified phone
+func (h *AuthHandler) ConfirmPhoneVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req ConfirmVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		otp, err := useVerificationCode(tx, verificationPhone, user.Email, req.Code)
+		if err != nil {
+			return err
+		}
+		if otp.Phone == nil {
+			return errVerificationCode
+		}
+		// The number may have been claimed since the code was sent
+		if phoneTaken(tx, *otp.Phone, user.ID) {
+			return errPhoneTaken
+		}
+		phoneHash := utils.HashPhone(*otp.Phone)
+		user.Phone, user.PhoneHash = otp.Phone, &phoneHash
+		return markVerified(tx, user.ID, verificationPhone, map[string]interface{}{
+			"phone":      *otp.Phone,
+			"phone_hash": phoneHash,
+		})
+	})
+	if !respondVerificationError(c, err) {
+		return
+	}
+
+	// Honor blocks placed on this number before it was on the account
+	if err := applyPhoneBlocks(h.db.WithContext(ctx), &user); err != nil {
+		log.Printf("Failed to apply phone blocks for user %d: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Phone verified", "phone": user.Phone})
+}
+
+// issueVerificationCode creates a code for the channel, answering the
+// request itself when none can be issued
+func (h *AuthHandler) issueVerificationCode(c *gin.Context, channel string, user *models.User, phone *string) (string, bool) {
+	code, err := h.issueOTP(h.db.WithContext(c.Request.Context()), channel, user.Email, phone)
+	if errors.Is(err, errTooManyOTPs) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many codes requested, please try again later"})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create OTP"})
+		return "", false
+	}
+	return code, true
+}
+
+var (
+	errVerificationCode = errors.New("invalid or expired verification code")
+	errPhoneTaken       = errors.New("phone number belongs to another account")
+)
+
+// useVerificationCode marks the account's latest code for the channel used
+// if it matches and hasn't expired
+func useVerificationCode(tx *gorm.DB, channel, email, code string) (*models.OTP, error) {
+	var otp models.OTP
+	err := tx.Where("email = ? AND channel = ? AND code = ? AND is_used = ? AND expires_at > ?", email, channel, code, false, time.Now()).
+		First(&otp).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errVerificationCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&otp).Update("is_used", true).Error; err != nil {
+		return nil, err
+	}
+	return &otp, nil
+}
+
+// phoneTaken reports whether another account has the phone number
+func phoneTaken(db *gorm.DB, phone string, userID uint) bool {
+	var count int64
+	db.Model(&models.User{}).Where("phone = ? AND id != ?", phone, userID).Count(&count)
+	return count > 0
+}
+
+// respondVerificationError answers a failed confirmation and reports whether
+// there was no error to answer
+func respondVerificationError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errVerificationCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+	case errors.Is(err, errPhoneTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "Another account uses this phone number"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify"})
+	}
+	return false
+}
+
+// SetPhotoVerification marks a user's photos as confirmed to show them, or
+// takes the badge away
+func (h *AdminHandler) SetPhotoVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req PhotoVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Select("id", "photo_verified_at").Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if req.Verified {
+			if err := markVerified(tx, user.ID, verificationPhoto, nil); err != nil {
+				return err
+			}
+			if user.PhotoVerifiedAt == nil {
+				if err := tx.Create(&models.Notification{
+					UserID: user.ID,
+					Type:   "photo_verified",
+					Title:  "Photos verified",
+					Body:   "Your photos have been verified. Your profile now shows the verified photo badge.",
+					Data:   `{}`,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			return h.logAdminAction(tx, c, "photo_verified", "user", user.ID, "")
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"photo_verified_at": nil,
+			"version":           gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "photo_verification_revoked", "user", user.ID, "")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update photo verification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": user.ID, "photo_verified": req.Verified})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+
+	"gorm.io/gorm"
+)
+
+// conversationMembers returns the two users of an active conversation,
+// from the Redis cache when it has them. ok is false when the conversation
+// doesn't exist or was closed.
+func conversationMembers(ctx context.Context, db *gorm.DB, rc *redis.Client, conversationID uint) (user1, user2 uint, ok bool) {
+	if user1, user2, ok := rc.ConversationMembers(ctx, conversationID); ok {
+		return user1, user2, true
+	}
+
+	var match models.Match
+	if err := db.WithContext(ctx).Joins("JOIN conversations ON conversations.match_id = matches.id").
+		Where("conversations.id = ? AND conversations.is_active = ?", conversationID, true).
+		First(&match).Error; err != nil {
+		return 0, 0, false
+	}
+
+	rc.CacheConversationMembers(ctx, conversationID, match.User1ID, match.User2ID)
+	return match.User1ID, match.User2ID, true
+}
+
+func hasConversationAccess(ctx context.Context, db *gorm.DB, rc *redis.Client, userID, conversationID uint) bool {
+	user1, user2, ok := conversationMembers(ctx, db, rc, conversationID)
+	return ok && (userID == user1 || userID == user2)
+}
+
+// ConversationAccessChecker returns the membership check the WebSocket hub
+// applies to conversation joins, the same one the message endpoints use
+func ConversationAccessChecker(db *gorm.DB, rc *redis.Client) func(ctx context.Context, userID, conversationID uint) bool {
+	return func(ctx context.Context, userID, conversationID uint) bool {
+		return hasConversationAccess(ctx, db, rc, userID, conversationID)
+	}
+}
+
+// userConversationIDs returns the IDs of every conversation the users have
+// had, deleted ones included
+func userConversationIDs(db *gorm.DB, userIDs ...uint) []uint {
+	var ids []uint
+	db.Unscoped().Model(&models.Conversation{}).
+		Joins("JOIN matches ON matches.id = conversations.match_id").
+		Where("matches.user1_id IN ? OR matches.user2_id IN ?", userIDs, userIDs).
+		Pluck("conversations.id", &ids)
+	return ids
+}
@@ -0,0 +1,476 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+	"ethiopia-dating-app/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxDateSpotSuggestions = 10
+	maxEventSuggestions    = 5
+	eventSuggestionWindow  = 14 * 24 * time.Hour
+
+	// Without a city, spots are looked for this far around the caller alone,
+	// so nothing about the match's whereabouts shapes the results
+	dateSpotRadiusKm    = 15
+	kmPerDegreeLatitude = 111.0
+)
+
+type DateSpotRequest struct {
+	Name        string  `json:"name" binding:"required,max=150"`
+	Category    string  `json:"category" binding:"required,oneof=cafe park restaurant other"`
+	City        string  `json:"city" binding:"required,max=100"`
+	Address     string  `json:"address" binding:"max=255"`
+	Description string  `json:"description" binding:"max=500"`
+	Latitude    float64 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude   float64 `json:"longitude" binding:"min=-180,max=180"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+}
+
+type SuggestDateRequest struct {
+	DateSpotID  uint       `json:"date_spot_id" binding:"required"`
+	ProposedFor *time.Time `json:"proposed_for,omitempty"`
+}
+
+type RespondDateSuggestionRequest struct {
+	Accept bool `json:"accept"`
+}
+
+// DateSpotResponse is a date spot with how far it is from the caller,
+// coarsened like distances to other users
+type DateSpotResponse struct {
+	models.DateSpot
+	DistanceKm      *float64 `json:"distance_km,omitempty"`
+	DistanceDisplay string   `json:"distance_display,omitempty"`
+}
+
+// dateSuggestionEvent is pushed to the conversation when a suggestion is
+// accepted or declined
+type dateSuggestionEvent struct {
+	Type           string                `json:"type"`
+	ConversationID uint                  `json:"conversation_id"`
+	UserID         uint                  `json:"user_id"`
+	DateSuggestion models.DateSuggestion `json:"date_suggestion"`
+}
+
+// GetDateSuggestions suggests where a match could meet: active date spots in
+// the caller's city (or the match's, when the caller has none), or around
+// the caller when neither has one, and upcoming events there. Neither which
+// spots are picked nor their order depends on where the other user is, and
+// distances are only given from the caller, so the other user's location
+// can't be worked out.
+func (h *MessageHandler) GetDateSuggestions(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	matchID, err := strconv.ParseUint(c.Param("match_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return
+	}
+
+	var match models.Match
+	if err := h.db.WithContext(ctx).Preload("User1").Preload("User2").
+		Where("id = ? AND (user1_id = ? OR user2_id = ?) AND is_active = ?", matchID, userID, userID, true).
+		First(&match).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	}
+	me, other := match.User1, match.User2
+	if me.ID != userID.(uint) {
+		me, other = other, me
+	}
+
+	city := userCity(&me)
+	if city == "" {
+		city = userCity(&other)
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.DateSpot{}).Where("is_active = ?", true)
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var spots []models.DateSpot
+	if city != "" {
+		if err := query.Where("LOWER(TRIM(city)) = LOWER(TRIM(?))", city).
+			Order("name ASC").Limit(maxDateSpotSuggestions).
+			Find(&spots).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch date spots"})
+			return
+		}
+	} else if hasPreciseLocation(&me) {
+		// A bounding box narrows the query; distances are checked after
+		latDelta := dateSpotRadiusKm / kmPerDegreeLatitude
+		lonDelta := dateSpotRadiusKm / (kmPerDegreeLatitude * math.Max(math.Cos(*me.Latitude*math.Pi/180), 0.01))
+		if err := query.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?",
+			*me.Latitude-latDelta, *me.Latitude+latDelta, *me.Longitude-lonDelta, *me.Longitude+lonDelta).
+			Order("name ASC").Find(&spots).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch date spots"})
+			return
+		}
+
+		nearby := spots[:0]
+		for _, spot := range spots {
+			if utils.HaversineKm(*me.Latitude, *me.Longitude, spot.Latitude, spot.Longitude) <= dateSpotRadiusKm {
+				nearby = append(nearby, spot)
+			}
+		}
+		spots = nearby
+		if len(spots) > maxDateSpotSuggestions {
+			spots = spots[:maxDateSpotSuggestions]
+		}
+	}
+
+	results := make([]DateSpotResponse, 0, len(spots))
+	for _, spot := range spots {
+		result := DateSpotResponse{DateSpot: spot}
+		if hasPreciseLocation(&me) {
+			distance := utils.HaversineKm(*me.Latitude, *me.Longitude, spot.Latitude, spot.Longitude)
+			rounded := utils.RoundDistance(distance)
+			result.DistanceKm = &rounded
+			result.DistanceDisplay = utils.FormatDistance(distance, me.DistanceUnit)
+		}
+		results = append(results, result)
+	}
+
+	events := []models.Event{}
+	if city != "" {
+		now := time.Now()
+		h.db.WithContext(ctx).Where("LOWER(TRIM(city)) = LOWER(TRIM(?)) AND starts_at BETWEEN ? AND ?", city, now, now.Add(eventSuggestionWindow)).
+			Order("starts_at ASC").Limit(maxEventSuggestions).
+			Find(&events)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"spots": results, "events": events, "city": city})
+}
+
+// SuggestDate proposes a date spot in a conversation as a "date_suggestion"
+// message, which the other user can accept or decline
+func (h *MessageHandler) SuggestDate(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req SuggestDateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ProposedFor != nil && !req.ProposedFor.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Propose a time in the future"})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+
+	if respondIfMuted(c, h.db.WithContext(ctx), userID) {
+		return
+	}
+
+	if respondIfUnverified(c, h.db.WithContext(ctx), h.cfg, userID, "messaging") {
+		return
+	}
+
+	if respondIfNotStarter(ctx, c, h.db, uint(conversationID), userID.(uint)) {
+		return
+	}
+
+	// The spot is stored in plaintext alongside the message
+	if h.conversationEncrypted(ctx, uint(conversationID)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Date suggestions aren't available in end-to-end encrypted chats"})
+		return
+	}
+
+	var spot models.DateSpot
+	if err := h.db.WithContext(ctx).Where("id = ? AND is_active = ?", req.DateSpotID, true).First(&spot).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Date spot not found"})
+		return
+	}
+
+	message := models.Message{
+		ConversationID: uint(conversationID),
+		SenderID:       userID.(uint),
+		Content:        "Let's meet at " + spot.Name,
+		MessageType:    "date_suggestion",
+	}
+	suggestion := models.DateSuggestion{
+		ConversationID: uint(conversationID),
+		SuggesterID:    userID.(uint),
+		DateSpotID:     spot.ID,
+		ProposedFor:    req.ProposedFor,
+		Status:         "pending",
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		suggestion.MessageID = message.ID
+		if err := tx.Create(&suggestion).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Conversation{}).Where("id = ?", conversationID).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest date"})
+		return
+	}
+	suggestion.DateSpot = spot
+
+	messageData := websocket.Message{
+		Type:           "message",
+		ConversationID: uint(conversationID),
+		SenderID:       userID.(uint),
+		Content:        message.Content,
+		MessageType:    "date_suggestion",
+		Timestamp:      message.CreatedAt.Format(time.RFC3339),
+	}
+	if messageBytes, err := json.Marshal(messageData); err == nil {
+		recipientID := h.conversationRecipient(ctx, uint(conversationID), userID.(uint))
+		h.hub.DeliverToConversation(uint(conversationID), []uint{userID.(uint), recipientID}, messageBytes)
+	}
+
+	h.createMessageNotification(ctx, uint(conversationID), userID.(uint), message.Content)
+
+	c.JSON(http.StatusCreated, gin.H{"message": message, "date_suggestion": suggestion})
+}
+
+// RespondDateSuggestion accepts or declines a date suggested to the caller
+func (h *MessageHandler) RespondDateSuggestion(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	suggestionID, err := strconv.ParseUint(c.Param("suggestion_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date suggestion ID"})
+		return
+	}
+
+	var req RespondDateSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var suggestion models.DateSuggestion
+	if err := h.db.WithContext(ctx).Preload("DateSpot", unscopedDateSpot).Where("id = ?", suggestionID).First(&suggestion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Date suggestion not found"})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), suggestion.ConversationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+	if suggestion.SuggesterID == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't answer your own suggestion"})
+		return
+	}
+	if suggestion.Status != "pending" {
+		c.JSON(http.StatusConflict, gin.H{"error": "This suggestion was already answered"})
+		return
+	}
+
+	status := "declined"
+	if req.Accept {
+		status = "accepted"
+	}
+	now := time.Now()
+	result := h.db.WithContext(ctx).Model(&models.DateSuggestion{}).
+		Where("id = ? AND status = ?", suggestion.ID, "pending").
+		Updates(map[string]interface{}{"status": status, "responded_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to answer date suggestion"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This suggestion was already answered"})
+		return
+	}
+	suggestion.Status = status
+	suggestion.RespondedAt = &now
+
+	event := dateSuggestionEvent{
+		Type:           "date_suggestion_update",
+		ConversationID: suggestion.ConversationID,
+		UserID:         userID.(uint),
+		DateSuggestion: suggestion,
+	}
+	if eventBytes, err := json.Marshal(event); err == nil {
+		h.hub.BroadcastToConversation(suggestion.ConversationID, eventBytes)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"date_suggestion": suggestion})
+}
+
+// unscopedDateSpot is a Preload condition that keeps spots deleted since
+// they were suggested
+func unscopedDateSpot(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// loadDateSuggestions returns the suggestions attached to the given
+// messages, keyed by message ID
+func (h *MessageHandler) loadDateSuggestions(ctx context.Context, messageIDs []uint) map[uint]*models.DateSuggestion {
+	suggestions := make(map[uint]*models.DateSuggestion)
+	if len(messageIDs) == 0 {
+		return suggestions
+	}
+
+	var rows []models.DateSuggestion
+	h.db.WithContext(ctx).Preload("DateSpot", unscopedDateSpot).Where("message_id IN ?", messageIDs).Find(&rows)
+	for i := range rows {
+		suggestions[rows[i].MessageID] = &rows[i]
+	}
+	return suggestions
+}
+
+// GetDateSpots lists date spots, optionally in one city, deactivated ones
+// included
+func (h *AdminHandler) GetDateSpots(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := h.db.WithContext(ctx).Model(&models.DateSpot{})
+	if city := c.Query("city"); city != "" {
+		query = query.Where("LOWER(TRIM(city)) = LOWER(TRIM(?))", city)
+	}
+
+	var spots []models.DateSpot
+	if err := query.Order("city ASC, name ASC").Find(&spots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch date spots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"date_spots": spots})
+}
+
+// CreateDateSpot adds a date spot
+func (h *AdminHandler) CreateDateSpot(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+
+	var req DateSpotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spot := models.DateSpot{IsActive: true, CreatedBy: adminID.(uint)}
+	req.apply(&spot)
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&spot).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "date_spot_created", "date_spot", spot.ID, spot.Name+", "+spot.City)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create date spot"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"date_spot": spot})
+}
+
+// UpdateDateSpot replaces a date spot's details. Send is_active false to
+// stop suggesting it without deleting it.
+func (h *AdminHandler) UpdateDateSpot(c *gin.Context) {
+	ctx := c.Request.Context()
+	spotID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date spot ID"})
+		return
+	}
+
+	var req DateSpotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var spot models.DateSpot
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", spotID).First(&spot).Error; err != nil {
+			return err
+		}
+		req.apply(&spot)
+		if err := tx.Save(&spot).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "date_spot_updated", "date_spot", spot.ID, fmt.Sprintf("%s, %s, active %t", spot.Name, spot.City, spot.IsActive))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Date spot not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update date spot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"date_spot": spot})
+}
+
+// DeleteDateSpot removes a date spot. Suggestions already sent keep showing
+// it.
+func (h *AdminHandler) DeleteDateSpot(c *gin.Context) {
+	ctx := c.Request.Context()
+	spotID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date spot ID"})
+		return
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", spotID).Delete(&models.DateSpot{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return h.logAdminAction(tx, c, "date_spot_deleted", "date_spot", uint(spotID), "")
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Date spot not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete date spot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Date spot deleted"})
+}
+
+func (r DateSpotRequest) apply(spot *models.DateSpot) {
+	spot.Name = strings.TrimSpace(r.Name)
+	spot.Category = r.Category
+	spot.City = strings.TrimSpace(r.City)
+	spot.Address = strings.TrimSpace(r.Address)
+	spot.Description = strings.TrimSpace(r.Description)
+	spot.Latitude = r.Latitude
+	spot.Longitude = r.Longitude
+	if r.IsActive != nil {
+		spot.IsActive = *r.IsActive
+	}
+}
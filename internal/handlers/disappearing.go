@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DisappearingMessagesRequest struct {
+	Hours int `json:"hours" binding:"min=0,max=168"` // 0 turns the mode off
+}
+
+// SetDisappearingMessages proposes, accepts, or turns off disappearing messages.
+// Turning the mode on needs both users to ask for the same window; either user
+// can turn it off on their own.
+func (h *MessageHandler) SetDisappearingMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req DisappearingMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+
+	var conversation models.Conversation
+	if err := h.db.WithContext(ctx).Where("id = ?", conversationID).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return
+	}
+
+	var systemKind string
+	updates := map[string]interface{}{
+		"disappear_proposed_by":    nil,
+		"disappear_proposed_hours": nil,
+	}
+
+	switch {
+	case req.Hours == 0:
+		if conversation.DisappearAfterHours != nil {
+			systemKind = services.SystemDisappearingOff
+		}
+		updates["disappear_after_hours"] = nil
+		updates["disappearing_since"] = nil
+
+	case conversation.DisappearProposedBy != nil && *conversation.DisappearProposedBy != userID.(uint) &&
+		conversation.DisappearProposedHours != nil && *conversation.DisappearProposedHours == req.Hours:
+		// The other user already asked for this window
+		systemKind = services.SystemDisappearingOn
+		updates["disappear_after_hours"] = req.Hours
+		updates["disappearing_since"] = time.Now()
+
+	default:
+		updates["disappear_proposed_by"] = userID
+		updates["disappear_proposed_hours"] = req.Hours
+	}
+
+	if err := h.db.WithContext(ctx).Model(&conversation).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update conversation"})
+		return
+	}
+
+	if systemKind != "" {
+		h.createSystemMessage(h.db.WithContext(ctx), uint(conversationID), userID.(uint), systemKind,
+			map[string]string{"hours": strconv.Itoa(req.Hours)})
+	}
+
+	h.db.WithContext(ctx).Where("id = ?", conversationID).First(&conversation)
+
+	status := "proposed"
+	if conversation.DisappearAfterHours != nil {
+		status = "on"
+	} else if conversation.DisappearProposedBy == nil {
+		status = "off"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status, "conversation": conversation})
+}
+
+// createSystemMessage posts a system message of the given kind in the
+// conversation and pushes it to connected clients
+func (h *MessageHandler) createSystemMessage(db *gorm.DB, conversationID, actorID uint, kind string, vars map[string]string) {
+	message, err := services.CreateSystemMessage(db, conversationID, actorID, kind, vars)
+	if err != nil {
+		log.Printf("Failed to post %s system message in conversation %d: %v", kind, conversationID, err)
+		return
+	}
+	if message != nil {
+		h.hub.BroadcastSystemMessage(conversationID, message.ID, actorID, message.Content, kind, message.CreatedAt)
+	}
+}
@@ -0,0 +1,508 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DiscoverFilters struct {
+	AgeMin      *int     `json:"age_min,omitempty"`
+	AgeMax      *int     `json:"age_max,omitempty"`
+	Gender      *string  `json:"gender,omitempty"`
+	Location    *string  `json:"location,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+	MaxDistance *int     `json:"max_distance,omitempty"` // in kilometers
+	Interests   []uint   `json:"interests,omitempty"`
+	Intents     []string `json:"intents,omitempty" binding:"omitempty,dive,oneof=serious casual friendship marriage_minded"`
+}
+
+type CreateDeckRequest struct {
+	DiscoverFilters
+	Size int `json:"size" binding:"omitempty,min=1,max=500"`
+}
+
+// buildDiscoverQuery returns the candidate query for the given viewer. It is
+// shared by the paginated discover feed and deck sessions so both apply the
+// same filters and exclusions.
+func buildDiscoverQuery(db *gorm.DB, cfg *config.Config, viewerID uint, filters DiscoverFilters) *gorm.DB {
+	viewer := loadViewer(db, viewerID)
+	if viewer != nil {
+		filters = withSavedFilters(filters, viewer)
+	}
+
+	query := db.Model(&models.User{}).Where("id != ? AND is_active = ? AND is_verified = ? AND is_paused = ?", viewerID, true, true, false).
+		Where("age_flagged_at IS NULL").
+		Scopes(notOnWaitlist)
+
+	// Age filter
+	if filters.AgeMin != nil || filters.AgeMax != nil {
+		now := time.Now()
+		if filters.AgeMin != nil {
+			maxBirthDate := now.AddDate(-*filters.AgeMin, 0, 0)
+			query = query.Where("date_of_birth <= ?", maxBirthDate)
+		}
+		if filters.AgeMax != nil {
+			minBirthDate := now.AddDate(-*filters.AgeMax-1, 0, 0)
+			query = query.Where("date_of_birth >= ?", minBirthDate)
+		}
+	}
+
+	// Both sides must fall within each other's gender preference
+	if viewer != nil {
+		if genders := utils.SeekingGenders(viewer.Seeking); genders != nil {
+			query = query.Where("gender IN ?", genders)
+		}
+		query = query.Where("seeking IN ?", utils.SeekersOf(viewer.Gender))
+	}
+
+	// Gender filter narrows the viewer's preference further
+	if filters.Gender != nil {
+		query = query.Where("gender = ?", *filters.Gender)
+	}
+
+	// Location filter
+	if filters.Location != nil {
+		query = query.Where("location ILIKE ?", "%"+*filters.Location+"%")
+	}
+
+	// Distance filter (if coordinates provided)
+	if filters.Latitude != nil && filters.Longitude != nil && filters.MaxDistance != nil {
+		// Simple distance calculation (not accurate for large distances)
+		query = query.Where(
+			"latitude IS NOT NULL AND longitude IS NOT NULL AND "+
+				"SQRT(POW(latitude - ?, 2) + POW(longitude - ?, 2)) * 111 <= ?",
+			*filters.Latitude, *filters.Longitude, *filters.MaxDistance,
+		)
+	}
+
+	// Intent filter; users who never chose an intent don't match it
+	if len(filters.Intents) > 0 {
+		query = query.Where("relationship_intent IN ?", filters.Intents)
+	}
+
+	// Interest filter (any shared interest)
+	if len(filters.Interests) > 0 {
+		query = query.Where("id IN (SELECT user_id FROM user_interests WHERE interest_id IN ?)", filters.Interests)
+	}
+
+	// Exclude blocked users
+	query = query.Where("id NOT IN (SELECT blocked_id FROM blocked_users WHERE blocker_id = ?)", viewerID)
+
+	// Exclude already liked users, and disliked ones until the dislike expires
+	query = query.Where("id NOT IN (SELECT liked_id FROM likes WHERE liker_id = ?)", viewerID)
+	query = query.Where("id NOT IN (SELECT disliked_id FROM dislikes WHERE disliker_id = ? AND created_at > ?)",
+		viewerID, dislikeCutoff(db, cfg, viewer, time.Now()))
+
+	// Exclude people the viewer unmatched, or who unmatched them, until the
+	// rematch cooldown is over
+	query = query.Where("id NOT IN ("+unmatchedUsersSQL+")", viewerID, viewerID, viewerID, time.Now())
+
+	// Exclude people from the viewer's address book if they asked for it
+	query = query.Where("id NOT IN (SELECT u.id FROM users u "+
+		"JOIN contact_hashes ch ON ch.phone_hash = u.phone_hash "+
+		"JOIN users v ON v.id = ch.user_id "+
+		"WHERE ch.user_id = ? AND v.hide_contacts)", viewerID)
+
+	return query
+}
+
+const (
+	newUserWindow  = 7 * 24 * time.Hour
+	dormantWindow  = 30 * 24 * time.Hour
+	recentlyActive = 24 * time.Hour
+)
+
+// relationshipIntents are the intents users can choose, in a fixed order
+var relationshipIntents = []string{"serious", "casual", "friendship", "marriage_minded"}
+
+// intentOrderStep is how many intentScores points make one step of
+// recencyOrder, so a matching intent weighs as much as being new
+const intentOrderStep = 7.5
+
+// recencyOrder ranks new and recently active users first and pushes dormant
+// accounts to the back instead of filtering them out. High-trust users move
+// up a step and low-trust ones down, and candidates whose intent fits the
+// viewer's move up while opposite ones move down.
+func recencyOrder(now time.Time, intent string) clause.OrderBy {
+	intentSQL := "0"
+	var intentVars []interface{}
+	if intent != "" {
+		intentSQL = "CASE relationship_intent"
+		for _, other := range relationshipIntents {
+			if weight := math.Round(intentCompatibility(intent, other) / intentOrderStep); weight != 0 {
+				intentSQL += " WHEN ? THEN ?"
+				intentVars = append(intentVars, other, weight)
+			}
+		}
+		intentSQL += " ELSE 0 END"
+	}
+
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL: "(CASE WHEN created_at >= ? THEN 2 ELSE 0 END + " +
+			"CASE WHEN is_online OR last_seen >= ? THEN 2 " +
+			"WHEN last_seen IS NULL OR last_seen < ? THEN -2 ELSE 0 END + " +
+			"CASE trust_level WHEN 'high' THEN 1 WHEN 'low' THEN -1 ELSE 0 END + " +
+			intentSQL + ") DESC, last_seen DESC NULLS LAST",
+		Vars:               append([]interface{}{now.Add(-newUserWindow), now.Add(-recentlyActive), now.Add(-dormantWindow)}, intentVars...),
+		WithoutParentheses: true,
+	}}
+}
+
+func isNewUser(user models.User, now time.Time) bool {
+	return now.Sub(user.CreatedAt) < newUserWindow
+}
+
+// preferenceChange lists which of gender and seeking differ between the
+// user as loaded and as saved
+func preferenceChange(before, after *models.User) []string {
+	var fields []string
+	if before.Gender != after.Gender {
+		fields = append(fields, "gender")
+	}
+	if before.Seeking != after.Seeking {
+		fields = append(fields, "seeking")
+	}
+	return fields
+}
+
+// queuePreferenceChange has the revalidation job drop the user's decks and
+// check their matches against their new gender or seeking
+func queuePreferenceChange(ctx context.Context, rc *redis.Client, userID uint, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	change := redis.PreferenceChange{UserID: userID, Fields: fields, At: time.Now()}
+	if err := rc.QueuePreferenceChange(ctx, change); err != nil {
+		log.Printf("Failed to queue preference change of user %d: %v", userID, err)
+	}
+}
+
+// CreateDeck runs the discovery query once, ranks the candidates, and freezes
+// the resulting candidate IDs in Redis, so subsequent swipes page through the
+// same deck without re-running the filters or showing a card twice.
+func (h *UserHandler) CreateDeck(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req CreateDeckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Size == 0 {
+		req.Size = 100
+	}
+
+	var viewer models.User
+	if err := h.db.WithContext(ctx).Preload("Interests").Where("id = ?", userID).First(&viewer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// The deck holds the best of a larger pool, ranked before it's cut
+	pool := discoverRankPool
+	if req.Size > pool {
+		pool = req.Size
+	}
+	candidates, err := topCandidates(buildDiscoverQuery(h.db.WithContext(ctx), h.cfg, viewer.ID, req.DiscoverFilters), &viewer, pool, 0, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build deck"})
+		return
+	}
+	candidateIDs := make([]uint, len(candidates))
+	for i, candidate := range candidates {
+		candidateIDs[i] = candidate.ID
+	}
+
+	deckID := uuid.New().String()
+	expiresAt := time.Now().Add(h.cfg.DeckSessionTTL)
+
+	if len(candidateIDs) > 0 {
+		members := make([]interface{}, len(candidateIDs))
+		for i, id := range candidateIDs {
+			members[i] = id
+		}
+
+		if err := h.redis.SaveDeck(ctx, userID.(uint), deckID, members, h.cfg.DeckSessionTTL); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store deck"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"deck_id":    deckID,
+		"size":       len(candidateIDs),
+		"expires_at": expiresAt,
+	})
+}
+
+// GetNextDeckCards pops the next N candidates off the deck. Popping is atomic
+// in Redis, so concurrent requests for the same deck never return the same card.
+func (h *UserHandler) GetNextDeckCards(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	deckID := c.Query("deck_id")
+	if deckID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "deck_id is required"})
+		return
+	}
+
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "10"))
+	if count < 1 || count > 50 {
+		count = 10
+	}
+
+	key := redis.DeckKey(userID.(uint), deckID)
+
+	ids, err := h.redis.LPopCount(ctx, key, count)
+	if err != nil || len(ids) == 0 {
+		// An emptied list is removed by Redis, so exhausted and expired decks look the same
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck is exhausted or expired"})
+		return
+	}
+
+	candidateIDs := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if parsed, err := strconv.ParseUint(id, 10, 32); err == nil {
+			candidateIDs = append(candidateIDs, uint(parsed))
+		}
+	}
+
+	// Users may have been deactivated, paused, or flagged since the deck was
+	// frozen. Private photos are left out of the cards.
+	var users []models.User
+	if err := h.db.WithContext(ctx).Preload("ProfilePhotos", "is_private = ?", false).Preload("Interests").Preload("Prompts", orderedPrompts).Preload("MessageStats").
+		Where("id IN ? AND is_active = ? AND is_paused = ? AND age_flagged_at IS NULL", candidateIDs, true, false).
+		Scopes(notOnWaitlist).
+		Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	// Preserve deck order
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	// Either side may have changed who they're looking for since then too
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+	cards := make([]PublicUserResponse, 0, len(users))
+	shown := make([]models.User, 0, len(users))
+	for _, id := range candidateIDs {
+		user, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if viewer != nil && !utils.GendersCompatible(viewer.Gender, viewer.Seeking, user.Gender, user.Seeking) {
+			continue
+		}
+		cards = append(cards, newPublicUser(user, viewer))
+		shown = append(shown, user)
+	}
+
+	// Only the cards handed out count as views
+	h.recordProfileViews(ctx, shown)
+	remaining, _ := h.redis.LLen(ctx, key)
+
+	c.JSON(http.StatusOK, gin.H{
+		"deck_id":   deckID,
+		"cards":     withMutualContacts(h.db.WithContext(ctx), userID.(uint), cards),
+		"remaining": remaining,
+	})
+}
+
+// ScoreBreakdown explains how a candidate was ranked for a viewer
+type ScoreBreakdown struct {
+	SharedInterests float64 `json:"shared_interests"`
+	Distance        float64 `json:"distance"`
+	Activity        float64 `json:"activity"`
+	Profile         float64 `json:"profile"`
+	Recency         float64 `json:"recency"`
+	Intent          float64 `json:"intent"`
+	Trust           float64 `json:"trust"`
+	Total           float64 `json:"total"`
+}
+
+type RankedCandidate struct {
+	User  PublicUserResponse `json:"user"`
+	Score ScoreBreakdown     `json:"score"`
+}
+
+// scoreCandidate ranks a candidate for the viewer. The viewer's interests must
+// be preloaded for the shared interest signal to count.
+func scoreCandidate(viewer *models.User, candidate models.User, now time.Time) ScoreBreakdown {
+	var score ScoreBreakdown
+
+	viewerInterests := make(map[uint]bool, len(viewer.Interests))
+	for _, interest := range viewer.Interests {
+		viewerInterests[interest.ID] = true
+	}
+	for _, interest := range candidate.Interests {
+		if viewerInterests[interest.ID] {
+			score.SharedInterests += 10
+		}
+	}
+
+	// Closer is better, fading out at 60 km
+	if hasCoordinates(viewer) && hasCoordinates(&candidate) {
+		distance := utils.HaversineKm(*viewer.Latitude, *viewer.Longitude, *candidate.Latitude, *candidate.Longitude)
+		score.Distance = math.Max(0, 30-distance/2)
+	}
+
+	switch {
+	case candidate.IsOnline:
+		score.Activity = 20
+	case candidate.LastSeen != nil && now.Sub(*candidate.LastSeen) < 24*time.Hour:
+		score.Activity = 15
+	case candidate.LastSeen != nil && now.Sub(*candidate.LastSeen) < 7*24*time.Hour:
+		score.Activity = 8
+	}
+
+	if len(candidate.ProfilePhotos) > 0 {
+		score.Profile += 5
+	}
+	score.Profile += photoTagSignal(viewer, candidate)
+	if candidate.Bio != nil && *candidate.Bio != "" {
+		score.Profile += 5
+	}
+
+	// Boost newcomers and demote dormant accounts rather than hiding them
+	if isNewUser(candidate, now) {
+		score.Recency += 15
+	}
+	if !candidate.IsOnline && (candidate.LastSeen == nil || now.Sub(*candidate.LastSeen) > dormantWindow) {
+		score.Recency -= 20
+	}
+
+	score.Intent = intentCompatibility(viewer.RelationshipIntent, candidate.RelationshipIntent)
+
+	switch candidate.TrustLevel {
+	case services.TrustHigh:
+		score.Trust = 10
+	case services.TrustLow:
+		score.Trust = -10
+	}
+
+	score.Total = score.SharedInterests + score.Distance + score.Activity + score.Profile + score.Recency + score.Intent + score.Trust
+	return score
+}
+
+// intentScores weighs how well two relationship intents fit together; pairs
+// not listed are neutral. Opposite goals are pushed down rather than hidden,
+// since the intent filter is there for users who want a hard cut.
+var intentScores = map[[2]string]float64{
+	{"serious", "serious"}:                 15,
+	{"marriage_minded", "marriage_minded"}: 15,
+	{"casual", "casual"}:                   15,
+	{"friendship", "friendship"}:           15,
+	{"serious", "marriage_minded"}:         10,
+	{"casual", "friendship"}:               3,
+	{"casual", "serious"}:                  -10,
+	{"casual", "marriage_minded"}:          -15,
+	{"friendship", "marriage_minded"}:      -10,
+}
+
+func intentCompatibility(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if score, ok := intentScores[[2]string{a, b}]; ok {
+		return score
+	}
+	return intentScores[[2]string{b, a}]
+}
+
+// photoTagSignal is a weak signal from photo tags that name one of the
+// viewer's interests, e.g. a "hiking" photo for a viewer into hiking. Label
+// detection is noisy, so it adds at most 3 points.
+func photoTagSignal(viewer *models.User, candidate models.User) float64 {
+	if len(viewer.Interests) == 0 {
+		return 0
+	}
+	interests := make(map[string]bool, len(viewer.Interests))
+	for _, interest := range viewer.Interests {
+		interests[strings.ToLower(interest.Name)] = true
+	}
+
+	var signal float64
+	for _, photo := range candidate.ProfilePhotos {
+		for _, tag := range photo.Tags {
+			if interests[tag] {
+				signal++
+			}
+		}
+	}
+	return math.Min(signal, 3)
+}
+
+// discoverRankPool is how many candidates, taken in recencyOrder, discovery
+// scores with scoreCandidate before handing out the best
+const discoverRankPool = 300
+
+// topCandidates loads the first pool candidates of the discovery query in
+// recencyOrder, ranks them with scoreCandidate and returns limit of them from
+// offset, best first. The viewer's interests must be preloaded. Private
+// photos aren't loaded, so they count toward neither the profile score nor
+// the photo tags.
+func topCandidates(query *gorm.DB, viewer *models.User, pool, offset, limit int) ([]models.User, error) {
+	now := time.Now()
+	var candidates []models.User
+	if err := query.Preload("ProfilePhotos", "is_private = ?", false).Preload("Interests").Preload("Prompts", orderedPrompts).Preload("MessageStats").
+		Order(recencyOrder(now, viewer.RelationshipIntent)).
+		Limit(pool).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint]float64, len(candidates))
+	for _, candidate := range candidates {
+		scores[candidate.ID] = scoreCandidate(viewer, candidate, now).Total
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].ID] > scores[candidates[j].ID]
+	})
+
+	if offset >= len(candidates) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(candidates) {
+		end = len(candidates)
+	}
+	return candidates[offset:end], nil
+}
+
+// rankCandidates scores candidates for the viewer and sorts them best first
+func rankCandidates(viewer *models.User, candidates []models.User) []RankedCandidate {
+	now := time.Now()
+	ranked := make([]RankedCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		ranked = append(ranked, RankedCandidate{
+			User:  newPublicUser(candidate, viewer),
+			Score: scoreCandidate(viewer, candidate, now),
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score.Total > ranked[j].Score.Total
+	})
+	return ranked
+}
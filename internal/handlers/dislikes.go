@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegionDislikeExpiryRequest sets how many days dislikes hide people in a
+// region. 0 hides them for good; null goes back to DISLIKE_EXPIRY.
+type RegionDislikeExpiryRequest struct {
+	Days *int `json:"days" binding:"omitempty,min=0,max=3650"`
+}
+
+// LaunchRegion is a region's cities and how many users can be shown in it
+type LaunchRegion struct {
+	Region   string             `json:"region"`
+	PoolSize int64              `json:"pool_size"`
+	Cities   []LaunchRegionCity `json:"cities"`
+}
+
+// LaunchRegionCity is a configured city with its discovery pool and the
+// dislike expiry in effect there
+type LaunchRegionCity struct {
+	ID                uint   `json:"id"`
+	CityKey           string `json:"city_key"`
+	Name              string `json:"name"`
+	IsLive            bool   `json:"is_live"`
+	PoolSize          int64  `json:"pool_size"`           // active users others can discover
+	DislikeExpiryDays int    `json:"dislike_expiry_days"` // 0 is forever
+	Overridden        bool   `json:"overridden"`          // set for the city rather than DISLIKE_EXPIRY
+}
+
+// dislikeExpiry is how long the viewer's dislikes hide people: what admins
+// set for the viewer's city, else DISLIKE_EXPIRY
+func dislikeExpiry(db *gorm.DB, cfg *config.Config, viewer *models.User) time.Duration {
+	if viewer != nil {
+		if key := cityKey(userCity(viewer)); key != "" {
+			var city models.LaunchCity
+			if err := db.Where("city_key = ? AND dislike_expiry_days IS NOT NULL", key).First(&city).Error; err == nil {
+				return time.Duration(*city.DislikeExpiryDays) * 24 * time.Hour
+			}
+		}
+	}
+	return cfg.DislikeExpiry
+}
+
+// dislikeCutoff returns when the viewer's dislikes must have been made to
+// still hide someone. Dislikes that never expire get the zero time.
+func dislikeCutoff(db *gorm.DB, cfg *config.Config, viewer *models.User, now time.Time) time.Time {
+	expiry := dislikeExpiry(db, cfg, viewer)
+	if expiry <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-expiry)
+}
+
+// GetLaunchRegions lists configured cities by region, with how many users
+// can be discovered in each and how long dislikes last there, so admins can
+// let passed profiles come back sooner where the pool is small
+func (h *AdminHandler) GetLaunchRegions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cities []models.LaunchCity
+	if err := h.db.WithContext(ctx).Order("region ASC, name ASC").Find(&cities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cities"})
+		return
+	}
+
+	keys := make([]string, 0, len(cities))
+	for _, city := range cities {
+		keys = append(keys, city.CityKey)
+	}
+	var pools []struct {
+		CityKey string
+		Count   int64
+	}
+	if len(keys) > 0 {
+		if err := h.db.WithContext(ctx).Model(&models.User{}).
+			Select(models.UserCityKeySQL+" AS city_key, COUNT(*) AS count").
+			Where("is_active = ? AND is_verified = ? AND is_paused = ? AND age_flagged_at IS NULL", true, true, false).
+			Scopes(notOnWaitlist).
+			Where(models.UserCityKeySQL+" IN ?", keys).
+			Group(models.UserCityKeySQL).
+			Scan(&pools).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+			return
+		}
+	}
+	poolOf := make(map[string]int64, len(pools))
+	for _, pool := range pools {
+		poolOf[pool.CityKey] = pool.Count
+	}
+
+	defaultDays := int(h.cfg.DislikeExpiry / (24 * time.Hour))
+	regions := []LaunchRegion{}
+	for _, city := range cities {
+		if len(regions) == 0 || regions[len(regions)-1].Region != city.Region {
+			regions = append(regions, LaunchRegion{Region: city.Region})
+		}
+		region := &regions[len(regions)-1]
+
+		entry := LaunchRegionCity{
+			ID:                city.ID,
+			CityKey:           city.CityKey,
+			Name:              city.Name,
+			IsLive:            city.IsLive,
+			PoolSize:          poolOf[city.CityKey],
+			DislikeExpiryDays: defaultDays,
+		}
+		if city.DislikeExpiryDays != nil {
+			entry.DislikeExpiryDays = *city.DislikeExpiryDays
+			entry.Overridden = true
+		}
+		region.Cities = append(region.Cities, entry)
+		region.PoolSize += entry.PoolSize
+	}
+
+	c.JSON(http.StatusOK, gin.H{"regions": regions, "default_dislike_expiry_days": defaultDays})
+}
+
+// UpdateRegionDislikeExpiry sets how long dislikes hide people for users in
+// every configured city of a region, or puts the region back on
+// DISLIKE_EXPIRY
+func (h *AdminHandler) UpdateRegionDislikeExpiry(c *gin.Context) {
+	ctx := c.Request.Context()
+	region := c.Param("region")
+
+	var req RegionDislikeExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var cities []models.LaunchCity
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.LaunchCity{}).Where("region = ?", region).Update("dislike_expiry_days", req.Days)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("region = ?", region).Order("name ASC").Find(&cities).Error; err != nil {
+			return err
+		}
+
+		days := "default"
+		if req.Days != nil {
+			days = fmt.Sprint(*req.Days)
+		}
+		return h.logAdminAction(tx, c, "region_dislike_expiry_updated", "region", 0, fmt.Sprintf("%s days=%s cities=%d", region, days, len(cities)))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No cities configured in this region"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update region"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cities": cities})
+}
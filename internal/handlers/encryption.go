@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxOneTimePreKeys = 100 // stored per user
+	lowOneTimePreKeys = 10  // clients should top up below this
+)
+
+var errTooManyPreKeys = errors.New("too many one-time prekeys")
+
+type PreKeyRequest struct {
+	KeyID     int    `json:"key_id" binding:"min=0"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+type SignedPreKeyRequest struct {
+	KeyID     int    `json:"key_id" binding:"min=0"`
+	PublicKey string `json:"public_key" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// PublishKeysRequest replaces the caller's identity and signed prekey and
+// adds one-time prekeys. The identity key may be left out when only adding
+// prekeys or rotating the signed prekey.
+type PublishKeysRequest struct {
+	IdentityKey    string               `json:"identity_key,omitempty"`
+	SignedPreKey   *SignedPreKeyRequest `json:"signed_prekey,omitempty"`
+	OneTimePreKeys []PreKeyRequest      `json:"one_time_prekeys,omitempty" binding:"max=100,dive"`
+}
+
+// KeyBundleResponse is what a peer needs to start a session with a user. The
+// one-time prekey is handed out once, so it is absent when none are left.
+type KeyBundleResponse struct {
+	UserID                uint           `json:"user_id"`
+	IdentityKey           string         `json:"identity_key"`
+	SignedPreKeyID        int            `json:"signed_prekey_id"`
+	SignedPreKey          string         `json:"signed_prekey"`
+	SignedPreKeySignature string         `json:"signed_prekey_signature"`
+	OneTimePreKey         *PreKeyRequest `json:"one_time_prekey,omitempty"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+}
+
+// validPublicKey accepts base64 Curve25519 public keys, bare or with the
+// one-byte type prefix Signal clients add
+func validPublicKey(value string) bool {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	return err == nil && (len(raw) == 32 || len(raw) == 33)
+}
+
+func validSignature(value string) bool {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	return err == nil && len(raw) == 64
+}
+
+// PublishKeys stores the caller's public encryption keys. A new identity key
+// invalidates the one-time prekeys signed for the old one.
+func (h *MessageHandler) PublishKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req PublishKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.IdentityKey != "" && !validPublicKey(req.IdentityKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "identity_key must be a base64 Curve25519 public key"})
+		return
+	}
+	if req.SignedPreKey != nil && (!validPublicKey(req.SignedPreKey.PublicKey) || !validSignature(req.SignedPreKey.Signature)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "signed_prekey needs a base64 public key and 64-byte signature"})
+		return
+	}
+	for _, key := range req.OneTimePreKeys {
+		if !validPublicKey(key.PublicKey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("one-time prekey %d is not a base64 Curve25519 public key", key.KeyID)})
+			return
+		}
+	}
+
+	var bundle models.KeyBundle
+	err := h.db.WithContext(ctx).Where("user_id = ?", userID).First(&bundle).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load keys"})
+		return
+	}
+	exists := err == nil
+	if !exists && (req.IdentityKey == "" || req.SignedPreKey == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "identity_key and signed_prekey are required the first time"})
+		return
+	}
+
+	identityChanged := req.IdentityKey != "" && req.IdentityKey != bundle.IdentityKey
+	if identityChanged && req.SignedPreKey == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A new identity_key needs a signed_prekey signed by it"})
+		return
+	}
+
+	bundle.UserID = userID.(uint)
+	if req.IdentityKey != "" {
+		bundle.IdentityKey = req.IdentityKey
+	}
+	if req.SignedPreKey != nil {
+		bundle.SignedPreKeyID = req.SignedPreKey.KeyID
+		bundle.SignedPreKey = req.SignedPreKey.PublicKey
+		bundle.SignedPreKeySignature = req.SignedPreKey.Signature
+	}
+
+	var remaining int64
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&bundle).Error; err != nil {
+			return err
+		}
+		if identityChanged && exists {
+			if err := tx.Where("user_id = ?", userID).Delete(&models.OneTimePreKey{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, key := range req.OneTimePreKeys {
+			prekey := models.OneTimePreKey{UserID: bundle.UserID, KeyID: key.KeyID, PublicKey: key.PublicKey}
+			if err := tx.Where("user_id = ? AND key_id = ?", prekey.UserID, prekey.KeyID).
+				Assign(models.OneTimePreKey{PublicKey: prekey.PublicKey}).
+				FirstOrCreate(&prekey).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.OneTimePreKey{}).Where("user_id = ?", userID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > maxOneTimePreKeys {
+			return errTooManyPreKeys
+		}
+		return nil
+	})
+	if errors.Is(err, errTooManyPreKeys) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d one-time prekeys can be stored", maxOneTimePreKeys)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identity_key":               bundle.IdentityKey,
+		"signed_prekey_id":           bundle.SignedPreKeyID,
+		"one_time_prekeys_remaining": remaining,
+	})
+}
+
+// GetKeyStatus tells a client whether its keys are published and how many
+// one-time prekeys are left, so it knows when to upload more
+func (h *MessageHandler) GetKeyStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var bundle models.KeyBundle
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).First(&bundle).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"published": false})
+		return
+	}
+
+	var remaining int64
+	h.db.WithContext(ctx).Model(&models.OneTimePreKey{}).Where("user_id = ?", userID).Count(&remaining)
+
+	c.JSON(http.StatusOK, gin.H{
+		"published":                  true,
+		"identity_key":               bundle.IdentityKey,
+		"signed_prekey_id":           bundle.SignedPreKeyID,
+		"one_time_prekeys_remaining": remaining,
+		"needs_prekeys":              remaining < lowOneTimePreKeys,
+		"updated_at":                 bundle.UpdatedAt,
+	})
+}
+
+// GetMatchKeyBundle returns the other user's key bundle for starting an
+// encrypted session, using up one of their one-time prekeys
+func (h *MessageHandler) GetMatchKeyBundle(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	matchID, err := strconv.ParseUint(c.Param("match_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return
+	}
+
+	var match models.Match
+	if err := h.db.WithContext(ctx).Where("id = ? AND (user1_id = ? OR user2_id = ?) AND is_active = ?", matchID, userID, userID, true).
+		First(&match).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	}
+	otherUserID := match.User1ID
+	if otherUserID == userID.(uint) {
+		otherUserID = match.User2ID
+	}
+
+	var bundle models.KeyBundle
+	if err := h.db.WithContext(ctx).Where("user_id = ?", otherUserID).First(&bundle).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Your match hasn't set up encryption yet"})
+		return
+	}
+
+	response := KeyBundleResponse{
+		UserID:                bundle.UserID,
+		IdentityKey:           bundle.IdentityKey,
+		SignedPreKeyID:        bundle.SignedPreKeyID,
+		SignedPreKey:          bundle.SignedPreKey,
+		SignedPreKeySignature: bundle.SignedPreKeySignature,
+		UpdatedAt:             bundle.UpdatedAt,
+	}
+
+	// Claim one prekey; concurrent fetches each get a different one
+	var prekeys []models.OneTimePreKey
+	if err := h.db.WithContext(ctx).Raw(`
+		DELETE FROM one_time_pre_keys
+		WHERE id = (
+			SELECT id FROM one_time_pre_keys WHERE user_id = ?
+			ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING key_id, public_key`, otherUserID).
+		Scan(&prekeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch keys"})
+		return
+	}
+	if len(prekeys) > 0 {
+		response.OneTimePreKey = &PreKeyRequest{KeyID: prekeys[0].KeyID, PublicKey: prekeys[0].PublicKey}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bundle": response})
+}
+
+// EnableEncryption turns on end-to-end encryption for a conversation once
+// both users have published keys, unless either of them is low trust while
+// their messages are held for review. From then on the server only accepts
+// ciphertext there, and it can't be turned off.
+func (h *MessageHandler) EnableEncryption(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+
+	var conversation models.Conversation
+	if err := h.db.WithContext(ctx).Where("id = ?", conversationID).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return
+	}
+	if conversation.EncryptedSince != nil {
+		c.JSON(http.StatusOK, gin.H{"conversation": conversation})
+		return
+	}
+
+	otherUserID := h.conversationRecipient(ctx, uint(conversationID), userID.(uint))
+
+	// Moderators couldn't read what low-trust users send once it's encrypted
+	if h.holdsMessages(ctx, userID.(uint)) || h.holdsMessages(ctx, otherUserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Encryption isn't available in this conversation yet", "code": "LOW_TRUST"})
+		return
+	}
+
+	var published int64
+	h.db.WithContext(ctx).Model(&models.KeyBundle{}).Where("user_id IN ?", []uint{userID.(uint), otherUserID}).Count(&published)
+	if published < 2 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Both of you need an app version with encryption set up first"})
+		return
+	}
+
+	now := time.Now()
+	result := h.db.WithContext(ctx).Model(&conversation).Where("encrypted_since IS NULL").Update("encrypted_since", now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update conversation"})
+		return
+	}
+	if result.RowsAffected > 0 {
+		h.createSystemMessage(h.db.WithContext(ctx), uint(conversationID), userID.(uint), services.SystemEncryptionOn, nil)
+	}
+
+	h.db.WithContext(ctx).Where("id = ?", conversationID).First(&conversation)
+	c.JSON(http.StatusOK, gin.H{"conversation": conversation})
+}
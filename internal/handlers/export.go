@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	exportsPerHour       = 5
+	exportAsyncThreshold = 1000 // messages
+	exportJobTTL         = time.Hour
+)
+
+type ExportedMessage struct {
+	SenderID    uint      `json:"sender_id"`
+	SenderName  string    `json:"sender_name"`
+	Content     string    `json:"content"`
+	MessageType string    `json:"message_type"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ConversationExport struct {
+	ConversationID uint              `json:"conversation_id"`
+	ExportedAt     time.Time         `json:"exported_at"`
+	Messages       []ExportedMessage `json:"messages"`
+}
+
+// ExportConversation returns the full chat history as JSON or plain text. Long
+// conversations are exported in the background and fetched via GetExport.
+func (h *MessageHandler) ExportConversation(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or text"})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+
+	if !allowRequest(ctx, h.redis, fmt.Sprintf("ratelimit:export:%d", userID), exportsPerHour, time.Hour) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many exports, please try again later"})
+		return
+	}
+
+	var messageCount int64
+	h.db.WithContext(ctx).Model(&models.Message{}).Where("conversation_id = ?", conversationID).Count(&messageCount)
+
+	if messageCount > exportAsyncThreshold {
+		jobID := uuid.New().String()
+		key := exportJobKey(userID.(uint), jobID)
+		h.redis.HSet(ctx, key, "status", "pending", "format", format)
+		h.redis.Expire(ctx, key, exportJobTTL)
+
+		go h.runExportJob(key, uint(conversationID), userID.(uint), format)
+
+		c.JSON(http.StatusAccepted, gin.H{"job_id": jobID, "status": "pending"})
+		return
+	}
+
+	body, err := h.buildExport(ctx, uint(conversationID), userID.(uint), format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export conversation"})
+		return
+	}
+
+	writeExport(c, uint(conversationID), format, body)
+}
+
+// GetExport returns a background export once it is ready
+func (h *MessageHandler) GetExport(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	job, err := h.redis.HGetAll(ctx, exportJobKey(userID.(uint), c.Param("job_id")))
+	if err != nil || len(job) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found or expired"})
+		return
+	}
+
+	switch job["status"] {
+	case "ready":
+		conversationID, _ := strconv.ParseUint(job["conversation_id"], 10, 32)
+		writeExport(c, uint(conversationID), job["format"], job["data"])
+	case "failed":
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Export failed"})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"job_id": c.Param("job_id"), "status": job["status"]})
+	}
+}
+
+func (h *MessageHandler) runExportJob(key string, conversationID, viewerID uint, format string) {
+	ctx := context.Background()
+
+	body, err := h.buildExport(ctx, conversationID, viewerID, format)
+	if err != nil {
+		h.redis.HSet(ctx, key, "status", "failed")
+		return
+	}
+
+	h.redis.HSet(ctx, key, "status", "ready", "conversation_id", conversationID, "data", body)
+	h.redis.Expire(ctx, key, exportJobTTL)
+}
+
+func (h *MessageHandler) buildExport(ctx context.Context, conversationID, viewerID uint, format string) (string, error) {
+	var messages []models.Message
+	if err := h.db.WithContext(ctx).Where("conversation_id = ?", conversationID).
+		Preload("Sender").
+		Order("created_at ASC").Find(&messages).Error; err != nil {
+		return "", err
+	}
+
+	export := ConversationExport{
+		ConversationID: conversationID,
+		ExportedAt:     time.Now(),
+		Messages:       make([]ExportedMessage, 0, len(messages)),
+	}
+	for _, msg := range messages {
+		export.Messages = append(export.Messages, ExportedMessage{
+			SenderID:    msg.SenderID,
+			SenderName:  msg.Sender.FirstName,
+			Content:     h.guardedContent(msg, viewerID, int64(len(messages))),
+			MessageType: msg.MessageType,
+			CreatedAt:   msg.CreatedAt,
+		})
+	}
+
+	if format == "text" {
+		var b strings.Builder
+		for _, msg := range export.Messages {
+			fmt.Fprintf(&b, "[%s] %s: %s\n", msg.CreatedAt.Format("2006-01-02 15:04"), msg.SenderName, msg.Content)
+		}
+		return b.String(), nil
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func writeExport(c *gin.Context, conversationID uint, format, body string) {
+	filename := fmt.Sprintf("conversation_%d", conversationID)
+	contentType := "application/json; charset=utf-8"
+	if format == "text" {
+		filename += ".txt"
+		contentType = "text/plain; charset=utf-8"
+	} else {
+		filename += ".json"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, []byte(body))
+}
+
+func exportJobKey(userID uint, jobID string) string {
+	return fmt.Sprintf("export:%d:%s", userID, jobID)
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FeatureWindowRequest creates or replaces a feature window
+type FeatureWindowRequest struct {
+	Name      string   `json:"name" binding:"required,max=100"`
+	Features  []string `json:"features" binding:"required,min=1"`
+	Weekday   *int     `json:"weekday" binding:"required,min=0,max=6"`
+	StartTime string   `json:"start_time" binding:"required"`
+	EndTime   string   `json:"end_time" binding:"required"`
+	Genders   []string `json:"genders,omitempty"`
+	Locations []string `json:"locations,omitempty"`
+	IsActive  *bool    `json:"is_active,omitempty"`
+	Announce  bool     `json:"announce"`
+}
+
+// Premium features the API gates. Admins can make them free with feature
+// windows while they are listed in PREMIUM_FEATURES.
+const (
+	featureSeeWhoLikedYou = "see_who_liked_you"
+	featureUnlimitedLikes = "unlimited_likes"
+)
+
+// hasPremiumFeature reports whether the user gets the feature: it isn't in
+// PREMIUM_FEATURES, they have premium, or a feature window open now makes it
+// free for them
+func hasPremiumFeature(db *gorm.DB, cfg *config.Config, user models.User, feature string) bool {
+	if !slices.Contains(cfg.PremiumFeatures, feature) || user.IsPremium {
+		return true
+	}
+	_, free := freeFeatures(db, cfg, func(window models.FeatureWindow) bool {
+		return window.Includes(user)
+	})[feature]
+	return free
+}
+
+// openFeatureWindows returns the active windows open now with when each
+// closes
+func openFeatureWindows(db *gorm.DB, cfg *config.Config, now time.Time) ([]models.FeatureWindow, []time.Time) {
+	var windows []models.FeatureWindow
+	db.Where("is_active = ?", true).Find(&windows)
+
+	var open []models.FeatureWindow
+	var closes []time.Time
+	for _, window := range windows {
+		if _, until, ok := window.Opening(now, cfg.TimeZone); ok {
+			open = append(open, window)
+			closes = append(closes, until)
+		}
+	}
+	return open, closes
+}
+
+// freeFeatures returns the premium features free right now for the windows
+// include accepts, with when each stops being free
+func freeFeatures(db *gorm.DB, cfg *config.Config, include func(models.FeatureWindow) bool) map[string]time.Time {
+	free := make(map[string]time.Time)
+	windows, closes := openFeatureWindows(db, cfg, time.Now())
+	for i, window := range windows {
+		if !include(window) {
+			continue
+		}
+		for _, feature := range window.Features {
+			if until, ok := free[feature]; !ok || closes[i].After(until) {
+				free[feature] = closes[i]
+			}
+		}
+	}
+	return free
+}
+
+// GetFreeFeatures lists the premium features a feature window makes free
+// for the caller right now, with when each stops being free
+func (h *UserHandler) GetFreeFeatures(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Select("id", "gender", "location", "is_premium").Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	free := freeFeatures(h.db.WithContext(ctx), h.cfg, func(window models.FeatureWindow) bool {
+		return window.Includes(user)
+	})
+	c.JSON(http.StatusOK, gin.H{"free_features": free, "is_premium": user.IsPremium})
+}
+
+// GetFeatureWindows lists every feature window, inactive ones included
+func (h *AdminHandler) GetFeatureWindows(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var windows []models.FeatureWindow
+	if err := h.db.WithContext(ctx).Order("weekday ASC, start_time ASC").Find(&windows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feature windows"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feature_windows": windows, "premium_features": h.cfg.PremiumFeatures, "time_zone": h.cfg.TimeZone.String()})
+}
+
+// CreateFeatureWindow schedules a weekly feature window
+func (h *AdminHandler) CreateFeatureWindow(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+
+	var req FeatureWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(h.cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window := models.FeatureWindow{IsActive: true, CreatedBy: adminID.(uint)}
+	req.apply(&window)
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&window).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "feature_window_created", "feature_window", window.ID, describeFeatureWindow(window))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feature window"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"feature_window": window})
+}
+
+// UpdateFeatureWindow replaces a feature window's schedule, features and
+// segment. Send is_active false to pause it without deleting it.
+func (h *AdminHandler) UpdateFeatureWindow(c *gin.Context) {
+	ctx := c.Request.Context()
+	windowID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feature window ID"})
+		return
+	}
+
+	var req FeatureWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(h.cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var window models.FeatureWindow
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", windowID).First(&window).Error; err != nil {
+			return err
+		}
+		req.apply(&window)
+		if err := tx.Save(&window).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "feature_window_updated", "feature_window", window.ID, describeFeatureWindow(window))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature window not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature window"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feature_window": window})
+}
+
+// DeleteFeatureWindow removes a feature window. An open one closes straight
+// away.
+func (h *AdminHandler) DeleteFeatureWindow(c *gin.Context) {
+	ctx := c.Request.Context()
+	windowID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feature window ID"})
+		return
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", windowID).Delete(&models.FeatureWindow{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return h.logAdminAction(tx, c, "feature_window_deleted", "feature_window", uint(windowID), "")
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature window not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete feature window"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feature window deleted"})
+}
+
+func (r FeatureWindowRequest) validate(cfg *config.Config) error {
+	for _, feature := range r.Features {
+		if !slices.Contains(cfg.PremiumFeatures, feature) {
+			return fmt.Errorf("%q isn't a premium feature; use one of %s", feature, strings.Join(cfg.PremiumFeatures, ", "))
+		}
+	}
+	for _, t := range []string{r.StartTime, r.EndTime} {
+		if _, err := time.Parse("15:04", t); err != nil {
+			return fmt.Errorf("times must be HH:MM, got %q", t)
+		}
+	}
+	if r.StartTime == r.EndTime {
+		return errors.New("a window must end at a different time than it starts")
+	}
+	for _, gender := range r.Genders {
+		if !slices.Contains(utils.Genders, gender) {
+			return fmt.Errorf("unknown gender %q", gender)
+		}
+	}
+	return nil
+}
+
+func (r FeatureWindowRequest) apply(window *models.FeatureWindow) {
+	window.Name = strings.TrimSpace(r.Name)
+	window.Features = r.Features
+	window.Weekday = *r.Weekday
+	window.StartTime = r.StartTime
+	window.EndTime = r.EndTime
+	window.Genders = r.Genders
+	window.Locations = nil
+	for _, location := range r.Locations {
+		if location = strings.TrimSpace(location); location != "" {
+			window.Locations = append(window.Locations, location)
+		}
+	}
+	window.Announce = r.Announce
+	if r.IsActive != nil {
+		window.IsActive = *r.IsActive
+	}
+}
+
+// describeFeatureWindow is the admin log line for a window, e.g.
+// "see_who_liked_you free Fri 20:00-22:00 for female, active true"
+func describeFeatureWindow(w models.FeatureWindow) string {
+	segment := "everyone"
+	if !w.ForEveryone() {
+		segment = strings.Join(append(append([]string{}, w.Genders...), w.Locations...), ", ")
+	}
+	return fmt.Sprintf("%s free %s %s-%s for %s, active %t",
+		strings.Join(w.Features, ","), time.Weekday(w.Weekday).String()[:3], w.StartTime, w.EndTime, segment, w.IsActive)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedbackReasons lists the allowed reasons for each feedback context
+var feedbackReasons = map[string][]string{
+	"unmatch":          {"no_chemistry", "inappropriate", "not_responsive", "met_in_person", "fake_profile", "other"},
+	"report":           {"resolved_quickly", "not_resolved", "hard_to_report", "other"},
+	"account_deletion": {"found_someone", "not_enough_matches", "too_expensive", "privacy_concerns", "bad_experience", "taking_a_break", "other"},
+	"general":          {"bug", "feature_request", "praise", "other"},
+}
+
+type SubmitFeedbackRequest struct {
+	Context  string  `json:"context" binding:"required,oneof=unmatch report account_deletion general"`
+	Reason   string  `json:"reason" binding:"required"`
+	Details  *string `json:"details,omitempty" binding:"omitempty,max=2000"`
+	TargetID *uint   `json:"target_id,omitempty"`
+}
+
+// SubmitFeedback stores an exit survey or general feedback entry. Unmatch and
+// report feedback must reference a match or report belonging to the caller.
+func (h *UserHandler) SubmitFeedback(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req SubmitFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isFeedbackReason(req.Context, req.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reason for this feedback context", "reasons": feedbackReasons[req.Context]})
+		return
+	}
+
+	var owned int64
+	switch req.Context {
+	case "unmatch":
+		if req.TargetID != nil {
+			h.db.WithContext(ctx).Model(&models.Match{}).Unscoped().
+				Where("id = ? AND (user1_id = ? OR user2_id = ?)", *req.TargetID, userID, userID).Count(&owned)
+		}
+	case "report":
+		if req.TargetID != nil {
+			h.db.WithContext(ctx).Model(&models.Report{}).Where("id = ? AND reporter_id = ?", *req.TargetID, userID).Count(&owned)
+		}
+	default:
+		owned = 1
+		req.TargetID = nil
+	}
+	if owned == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_id must reference your own match or report"})
+		return
+	}
+
+	feedback := models.Feedback{
+		UserID:   userID.(uint),
+		Context:  req.Context,
+		Reason:   req.Reason,
+		Details:  req.Details,
+		TargetID: req.TargetID,
+	}
+	if err := h.db.WithContext(ctx).Create(&feedback).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit feedback"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Thanks for your feedback"})
+}
+
+// GetFeedbackReasons lets clients render survey options without hardcoding them
+func (h *UserHandler) GetFeedbackReasons(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"reasons": feedbackReasons})
+}
+
+func isFeedbackReason(context, reason string) bool {
+	for _, allowed := range feedbackReasons[context] {
+		if allowed == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FirstMessageStatus tells a matched user who may open the conversation.
+// StartBy and SecondsLeft are only set while one side is waiting.
+type FirstMessageStatus struct {
+	WhoCanStart string     `json:"who_can_start"` // you, them, either
+	StartBy     *time.Time `json:"start_by,omitempty"`
+	SecondsLeft int64      `json:"seconds_left,omitempty"`
+}
+
+// applyFirstMessageRule sets who must start a new match's conversation under
+// FIRST_MESSAGE_MODE. In women_first mode the woman starts a woman/man match;
+// every other pairing can be started by either user.
+func applyFirstMessageRule(cfg *config.Config, match *models.Match, user1, user2 models.User) {
+	if cfg.FirstMessageMode != "women_first" {
+		return
+	}
+
+	var starter uint
+	switch {
+	case user1.Gender == "female" && user2.Gender == "male":
+		starter = user1.ID
+	case user2.Gender == "female" && user1.Gender == "male":
+		starter = user2.ID
+	default:
+		return
+	}
+
+	startBy := time.Now().Add(cfg.FirstMessageWindow)
+	match.StarterID = &starter
+	match.StartBy = &startBy
+}
+
+// firstMessageRuleActive reports whether the match is still waiting on its
+// starter: the window is open and they haven't written yet
+func firstMessageRuleActive(match models.Match, starterWrote bool) bool {
+	return match.StarterID != nil && match.StartBy != nil && time.Now().Before(*match.StartBy) && !starterWrote
+}
+
+func newFirstMessageStatus(match models.Match, viewerID uint, starterWrote bool) FirstMessageStatus {
+	if !firstMessageRuleActive(match, starterWrote) {
+		return FirstMessageStatus{WhoCanStart: "either"}
+	}
+
+	status := FirstMessageStatus{
+		WhoCanStart: "them",
+		StartBy:     match.StartBy,
+		SecondsLeft: int64(time.Until(*match.StartBy).Seconds()),
+	}
+	if *match.StarterID == viewerID {
+		status.WhoCanStart = "you"
+	}
+	return status
+}
+
+// nonOpeningMessageTypes are messages that don't count as the starter
+// writing first: notices, and comments left on likes before the match
+// and compliments, which are posted into the conversation when it opens
+var nonOpeningMessageTypes = []string{"system", "like_comment", "compliment"}
+
+// startersWhoWrote returns the IDs of matches whose starter has sent a
+// message in the conversation
+func startersWhoWrote(db *gorm.DB, matchIDs []uint) map[uint]bool {
+	wrote := make(map[uint]bool)
+	if len(matchIDs) == 0 {
+		return wrote
+	}
+
+	var ids []uint
+	db.Table("messages").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Joins("JOIN matches ON matches.id = conversations.match_id").
+		Where("matches.id IN ? AND messages.sender_id = matches.starter_id AND messages.message_type NOT IN ? AND messages.deleted_at IS NULL", matchIDs, nonOpeningMessageTypes).
+		Distinct().
+		Pluck("matches.id", &ids)
+	for _, id := range ids {
+		wrote[id] = true
+	}
+	return wrote
+}
+
+// respondIfNotStarter rejects a message from the user who has to wait for
+// their match to write first, and reports whether it did
+func respondIfNotStarter(ctx context.Context, c *gin.Context, db *gorm.DB, conversationID, senderID uint) bool {
+	var match models.Match
+	if err := db.WithContext(ctx).Joins("JOIN conversations ON conversations.match_id = matches.id").
+		Where("conversations.id = ?", conversationID).First(&match).Error; err != nil {
+		return false
+	}
+	if match.StarterID == nil || *match.StarterID == senderID {
+		return false
+	}
+
+	wrote := startersWhoWrote(db.WithContext(ctx), []uint{match.ID})
+	if !firstMessageRuleActive(match, wrote[match.ID]) {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":         "Your match gets to send the first message",
+		"code":          "FIRST_MESSAGE_RESTRICTED",
+		"first_message": newFirstMessageStatus(match, senderID, false),
+	})
+	return true
+}
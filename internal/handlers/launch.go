@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LaunchCityRequest struct {
+	City   string `json:"city" binding:"required,max=100"`
+	Region string `json:"region,omitempty" binding:"max=100"`
+	Live   *bool  `json:"live" binding:"required"`
+}
+
+type LaunchRegionRequest struct {
+	Live *bool `json:"live" binding:"required"`
+}
+
+// WaitlistCityCount is how many users are waiting in one city
+type WaitlistCityCount struct {
+	CityKey  string `json:"city_key"`
+	CityName string `json:"city_name"`
+	Count    int64  `json:"count"`
+}
+
+// cityKey normalizes a city name for matching, so "Addis Ababa " and
+// "addis  ababa" are the same city
+func cityKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func userCity(user *models.User) string {
+	if user.Location == nil {
+		return ""
+	}
+	return strings.TrimSpace(*user.Location)
+}
+
+func cityIsLive(db *gorm.DB, key string) bool {
+	if key == "" {
+		return false
+	}
+	var count int64
+	db.Model(&models.LaunchCity{}).Where("city_key = ? AND is_live = ?", key, true).Count(&count)
+	return count > 0
+}
+
+// waitlistNewUser puts a just-registered user on the waitlist when launch
+// gating is on and their city isn't live. Users whose city is unknown wait
+// too, until they set a location in a live city.
+func waitlistNewUser(db *gorm.DB, cfg *config.Config, user *models.User) (*models.Waitlist, error) {
+	if !cfg.LaunchGatingEnabled {
+		return nil, nil
+	}
+
+	city := userCity(user)
+	if cityIsLive(db, cityKey(city)) {
+		return nil, nil
+	}
+
+	entry := models.Waitlist{UserID: user.ID, CityKey: cityKey(city), CityName: city}
+	if err := db.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ipCity is the city an IP address resolves to, or empty when it can't be
+// told
+func ipCity(geo services.GeoLocator, ip string) string {
+	if geo == nil {
+		return ""
+	}
+	location, err := geo.Locate(ip)
+	if err != nil {
+		return ""
+	}
+	return location.City
+}
+
+// updateWaitlistCity follows a waitlisted user to the city their IP address
+// now resolves to, releasing them when it is live. The location users type
+// on their profile isn't trusted for this. Users who aren't waitlisted, or
+// whose city can't be told, are left alone, so moving never sends anyone
+// back to the waitlist.
+func updateWaitlistCity(db *gorm.DB, userID uint, city string) error {
+	if city == "" {
+		return nil
+	}
+
+	var entry models.Waitlist
+	if err := db.Where("user_id = ?", userID).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if cityIsLive(db, cityKey(city)) {
+		return db.Delete(&entry).Error
+	}
+	if cityKey(city) == entry.CityKey {
+		return nil
+	}
+	// A different city means a new place in line
+	return db.Model(&entry).Updates(map[string]interface{}{
+		"city_key":   cityKey(city),
+		"city_name":  city,
+		"created_at": time.Now(),
+	}).Error
+}
+
+// notOnWaitlist excludes waitlisted users from a users query
+func notOnWaitlist(db *gorm.DB) *gorm.DB {
+	return db.Where("users.id NOT IN (SELECT user_id FROM waitlists)")
+}
+
+// releaseWaitlist lets everyone waiting in the given cities in and tells them
+func releaseWaitlist(tx *gorm.DB, cities []models.LaunchCity) (int64, error) {
+	var released int64
+	for _, city := range cities {
+		var entries []models.Waitlist
+		if err := tx.Where("city_key = ?", city.CityKey).Find(&entries).Error; err != nil {
+			return released, err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		notifications := make([]models.Notification, 0, len(entries))
+		for _, entry := range entries {
+			notifications = append(notifications, models.Notification{
+				UserID: entry.UserID,
+				Type:   "city_launched",
+				Title:  fmt.Sprintf("We're live in %s", city.Name),
+				Body:   "You're off the waitlist. Your profile is now visible and you can start matching.",
+				Data:   fmt.Sprintf(`{"city": %q}`, city.Name),
+			})
+		}
+		if err := tx.CreateInBatches(&notifications, 500).Error; err != nil {
+			return released, err
+		}
+
+		result := tx.Where("city_key = ?", city.CityKey).Delete(&models.Waitlist{})
+		if result.Error != nil {
+			return released, result.Error
+		}
+		released += result.RowsAffected
+	}
+	return released, nil
+}
+
+// GetWaitlistStatus tells the caller whether they are waiting for their city
+// to launch and their place in line there
+func (h *UserHandler) GetWaitlistStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var entry models.Waitlist
+	err := h.db.WithContext(ctx).Where("user_id = ?", userID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"waitlisted": false})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist status"})
+		return
+	}
+
+	var ahead, waiting int64
+	h.db.WithContext(ctx).Model(&models.Waitlist{}).Where("city_key = ? AND (created_at, id) < (?, ?)", entry.CityKey, entry.CreatedAt, entry.ID).Count(&ahead)
+	h.db.WithContext(ctx).Model(&models.Waitlist{}).Where("city_key = ?", entry.CityKey).Count(&waiting)
+
+	c.JSON(http.StatusOK, gin.H{
+		"waitlisted":   true,
+		"city":         entry.CityName,
+		"joined_at":    entry.CreatedAt,
+		"position":     ahead + 1,
+		"waiting_here": waiting,
+	})
+}
+
+// GetLaunchCities lists configured cities and how many users wait in each
+// city, including cities nobody has configured yet
+func (h *AdminHandler) GetLaunchCities(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := h.db.WithContext(ctx).Model(&models.LaunchCity{})
+	if region := c.Query("region"); region != "" {
+		query = query.Where("region = ?", region)
+	}
+	var cities []models.LaunchCity
+	if err := query.Order("region ASC, name ASC").Find(&cities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cities"})
+		return
+	}
+
+	counts := []WaitlistCityCount{}
+	if err := h.db.WithContext(ctx).Model(&models.Waitlist{}).
+		Select("city_key, MIN(city_name) AS city_name, COUNT(*) AS count").
+		Group("city_key").Order("count DESC").
+		Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist counts"})
+		return
+	}
+
+	var total int64
+	for _, count := range counts {
+		total += count.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gating_enabled":   h.cfg.LaunchGatingEnabled,
+		"cities":           cities,
+		"waitlist":         counts,
+		"total_waitlisted": total,
+	})
+}
+
+// UpdateLaunchCity adds a city or changes whether it is live. Opening a city
+// lets everyone waiting there in; closing it only waitlists later sign-ups.
+func (h *AdminHandler) UpdateLaunchCity(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+
+	var req LaunchCityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key := cityKey(req.City)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "city is required"})
+		return
+	}
+
+	var city models.LaunchCity
+	var released int64
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("city_key = ?", key).Attrs(models.LaunchCity{Name: strings.TrimSpace(req.City)}).FirstOrInit(&city).Error; err != nil {
+			return err
+		}
+		if req.Region != "" {
+			city.Region = strings.TrimSpace(req.Region)
+		}
+		city.CityKey = key
+		setCityLive(&city, *req.Live, adminID.(uint))
+		if err := tx.Save(&city).Error; err != nil {
+			return err
+		}
+
+		if city.IsLive {
+			var err error
+			if released, err = releaseWaitlist(tx, []models.LaunchCity{city}); err != nil {
+				return err
+			}
+		}
+		return h.logAdminAction(tx, c, "launch_city_updated", "city", city.ID, fmt.Sprintf("%s live=%t released=%d", city.Name, city.IsLive, released))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update city"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"city": city, "released": released})
+}
+
+// UpdateLaunchRegion opens or closes every configured city in a region
+func (h *AdminHandler) UpdateLaunchRegion(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	region := c.Param("region")
+
+	var req LaunchRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var cities []models.LaunchCity
+	var released int64
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("region = ?", region).Find(&cities).Error; err != nil {
+			return err
+		}
+		for i := range cities {
+			setCityLive(&cities[i], *req.Live, adminID.(uint))
+			if err := tx.Save(&cities[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		if *req.Live {
+			var err error
+			if released, err = releaseWaitlist(tx, cities); err != nil {
+				return err
+			}
+		}
+		return h.logAdminAction(tx, c, "launch_region_updated", "region", 0, fmt.Sprintf("%s live=%t cities=%d released=%d", region, *req.Live, len(cities), released))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update region"})
+		return
+	}
+	if len(cities) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No cities configured in this region"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cities": cities, "released": released})
+}
+
+func setCityLive(city *models.LaunchCity, live bool, adminID uint) {
+	if live && !city.IsLive {
+		now := time.Now()
+		city.OpenedAt = &now
+		city.OpenedBy = &adminID
+	}
+	city.IsLive = live
+}
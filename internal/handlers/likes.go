@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LikeRequest is the optional body of a like. A like can point at one photo or
+// prompt answer of the liked user and carry a short comment.
+type LikeRequest struct {
+	PhotoID  *uint  `json:"photo_id,omitempty"`
+	PromptID *uint  `json:"prompt_id,omitempty"`
+	Comment  string `json:"comment,omitempty" binding:"max=200"`
+}
+
+type LikeReceivedResponse struct {
+	ID        uint                  `json:"id"`
+	User      PublicUserResponse    `json:"user"`
+	Photo     *models.ProfilePhoto  `json:"photo,omitempty"`
+	Prompt    *models.ProfilePrompt `json:"prompt,omitempty"`
+	Comment   *string               `json:"comment,omitempty"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+// validateLikeTarget checks that the photo or prompt belongs to the liked user
+// and that the comment is safe to deliver before a match exists
+func (h *MatchHandler) validateLikeTarget(ctx context.Context, likedID uint, req *LikeRequest) error {
+	if req.PhotoID != nil && req.PromptID != nil {
+		return errors.New("a like can target a photo or a prompt, not both")
+	}
+
+	if req.PhotoID != nil {
+		var count int64
+		h.db.WithContext(ctx).Model(&models.ProfilePhoto{}).
+			Where("id = ? AND user_id = ? AND moderation_status = ? AND is_private = ?", *req.PhotoID, likedID, "approved", false).
+			Count(&count)
+		if count == 0 {
+			return errors.New("photo not found on this profile")
+		}
+	}
+
+	if req.PromptID != nil {
+		var count int64
+		h.db.WithContext(ctx).Model(&models.ProfilePrompt{}).Where("id = ? AND user_id = ?", *req.PromptID, likedID).Count(&count)
+		if count == 0 {
+			return errors.New("prompt not found on this profile")
+		}
+	}
+
+	req.Comment = strings.TrimSpace(req.Comment)
+	if len(utils.DetectContactInfo(req.Comment)) > 0 {
+		return errors.New("comments can't include contact details")
+	}
+	return nil
+}
+
+// openWithLikeComments posts the comments left on the two likes as the first
+// messages of a new conversation, oldest first. They are like_comment
+// messages, which don't count as the starter writing first.
+func (h *MatchHandler) openWithLikeComments(ctx context.Context, conversationID uint, likes ...models.Like) {
+	for _, like := range likes {
+		if like.Comment == nil {
+			continue
+		}
+		message := models.Message{
+			ConversationID: conversationID,
+			SenderID:       like.LikerID,
+			Content:        *like.Comment,
+			MessageType:    "like_comment",
+			CreatedAt:      like.CreatedAt,
+		}
+		h.db.WithContext(ctx).Create(&message)
+	}
+}
+
+// GetLikesReceived lists pending likes on the caller's profile, newest first.
+// Likes the caller already answered with a like, pass, or block are left out,
+// though passes only until they expire. Users without see_who_liked_you only
+// get the count.
+func (h *MatchHandler) GetLikesReceived(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+	if viewer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.Like{}).
+		Where("liked_id = ?", userID).
+		Where("liker_id NOT IN (SELECT liked_id FROM likes WHERE liker_id = ?)", userID).
+		Where("liker_id NOT IN (SELECT disliked_id FROM dislikes WHERE disliker_id = ? AND created_at > ?)",
+			userID, dislikeCutoff(h.db.WithContext(ctx), h.cfg, viewer, time.Now())).
+		Where("liker_id NOT IN (SELECT blocked_id FROM blocked_users WHERE blocker_id = ?)", userID).
+		Where("liker_id IN (SELECT id FROM users WHERE is_active = ? AND deleted_at IS NULL)", true)
+
+	var total int64
+	query.Count(&total)
+
+	// Without see_who_liked_you, users only learn how many likes wait
+	if !hasPremiumFeature(h.db.WithContext(ctx), h.cfg, *viewer, featureSeeWhoLikedYou) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Get premium to see who liked you",
+			"code":    "PREMIUM_REQUIRED",
+			"feature": featureSeeWhoLikedYou,
+			"total":   total,
+		})
+		return
+	}
+
+	var likes []models.Like
+	if err := query.Preload("Liker.ProfilePhotos").Preload("Liker.Interests").Preload("Liker.Prompts", orderedPrompts).
+		Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&likes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch likes"})
+		return
+	}
+
+	results := make([]LikeReceivedResponse, 0, len(likes))
+	for _, like := range likes {
+		result := LikeReceivedResponse{
+			ID:        like.ID,
+			User:      newPublicUser(like.Liker, viewer),
+			Comment:   like.Comment,
+			CreatedAt: like.CreatedAt,
+		}
+		if like.PhotoID != nil {
+			var photo models.ProfilePhoto
+			if err := h.db.WithContext(ctx).Where("id = ?", *like.PhotoID).First(&photo).Error; err == nil {
+				result.Photo = &photo
+			}
+		}
+		if like.PromptID != nil {
+			var prompt models.ProfilePrompt
+			if err := h.db.WithContext(ctx).Where("id = ?", *like.PromptID).First(&prompt).Error; err == nil {
+				result.Prompt = &prompt
+			}
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"likes": results,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	qrCodeScale           = 8 // pixels per module
+	qrCodeCacheTTL        = 30 * 24 * time.Hour
+	linkResolvesPerMinute = 60
+)
+
+type LinkHandler struct {
+	db      *gorm.DB
+	redis   *redis.Client
+	cfg     *config.Config
+	links   *services.DeepLinker
+	storage *services.StorageService
+}
+
+func NewLinkHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, links *services.DeepLinker, storage *services.StorageService) *LinkHandler {
+	return &LinkHandler{
+		db:      db,
+		redis:   redis,
+		cfg:     cfg,
+		links:   links,
+		storage: storage,
+	}
+}
+
+type CreateLinkRequest struct {
+	Kind string `json:"kind" binding:"required,oneof=profile room invite"`
+	ID   uint   `json:"id,omitempty"` // room ID; profile and invite links are always the caller's own
+}
+
+// CreateLink returns a signed deep link and a QR code for the caller's
+// profile, a room, or a referral invite from the caller
+func (h *LinkHandler) CreateLink(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req CreateLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := userID.(uint)
+	if req.Kind == services.LinkRoom {
+		var room models.Room
+		if err := h.db.WithContext(ctx).Where("id = ? AND is_active = ?", req.ID, true).First(&room).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+		id = room.ID
+	}
+
+	url := h.links.URL(req.Kind, id)
+	qrCodeURL, err := h.qrCode(ctx, req.Kind, id, url)
+	if err != nil {
+		log.Printf("Failed to create QR code for %s %d: %v", req.Kind, id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create QR code"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"kind":        req.Kind,
+		"id":          id,
+		"url":         url,
+		"qr_code_url": qrCodeURL,
+	})
+}
+
+// qrCode renders the link as a PNG and stores it. Links never change for a
+// resource, so the stored image is reused.
+func (h *LinkHandler) qrCode(ctx context.Context, kind string, id uint, url string) (string, error) {
+	key := fmt.Sprintf("qr:%s:%d", kind, id)
+	if cached, err := h.redis.Get(ctx, key); err == nil && cached != "" {
+		return cached, nil
+	}
+
+	image, err := utils.QRCodePNG(url, qrCodeScale)
+	if err != nil {
+		return "", err
+	}
+
+	// The signature prefix keeps stored codes from being guessed by ID
+	filename := fmt.Sprintf("qr/%s/%d-%s.png", kind, id, h.links.Sign(kind, id)[:8])
+	stored, err := h.storage.UploadFile(ctx, bytes.NewReader(image), filename, "image/png")
+	if err != nil {
+		return "", err
+	}
+
+	h.redis.Set(ctx, key, stored, qrCodeCacheTTL)
+	return stored, nil
+}
+
+// ResolveLink checks a deep link's signature and returns the resource it
+// points at. It needs no session, so links opened before sign-up still
+// resolve; bad signatures and hidden resources look the same.
+func (h *LinkHandler) ResolveLink(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if !allowRequest(ctx, h.redis, "ratelimit:links:"+c.ClientIP(), linkResolvesPerMinute, time.Minute) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+		return
+	}
+
+	kind := c.Param("kind")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || !services.ValidLinkKind(kind) || !h.links.Verify(kind, uint(id), c.Query("sig")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return
+	}
+
+	switch kind {
+	case services.LinkRoom:
+		var room models.Room
+		if err := h.db.WithContext(ctx).Where("id = ? AND is_active = ?", id, true).First(&room).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"kind": kind, "room": room})
+
+	case services.LinkProfile, services.LinkInvite:
+		var user models.User
+		if err := shareableUsers(h.db.WithContext(ctx)).Preload("ProfilePhotos").Preload("Interests").Preload("Prompts", orderedPrompts).
+			Where("id = ?", id).First(&user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+
+		if kind == services.LinkProfile {
+			c.JSON(http.StatusOK, gin.H{"kind": kind, "user": newPublicUser(user, nil)})
+			return
+		}
+
+		// An invite only introduces who sent it
+		inviter := gin.H{"id": user.ID, "first_name": user.FirstName}
+		for _, photo := range publicPhotos(user.ProfilePhotos) {
+			if photo.IsPrimary {
+				inviter["photo_url"] = photo.URL
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"kind": kind, "inviter": inviter})
+	}
+}
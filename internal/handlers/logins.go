@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func loginAccountSubject(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func loginIPSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// loginDeviceID is the device a login comes from: the ID the app sent, or
+// for clients that send none, one derived from the user agent
+func loginDeviceID(c *gin.Context, deviceID string) string {
+	if deviceID != "" {
+		return deviceID
+	}
+	sum := sha256.Sum256([]byte(c.GetHeader("User-Agent")))
+	return "ua:" + hex.EncodeToString(sum[:16])
+}
+
+// respondIfLoginLocked turns logins away while the account or the caller's
+// IP is locked out after too many failures, and reports whether it did.
+// Redis errors let the login through.
+func (h *AuthHandler) respondIfLoginLocked(ctx context.Context, c *gin.Context, email string) bool {
+	wait, err := h.redis.LoginLockedFor(ctx, loginAccountSubject(email), loginIPSubject(c.ClientIP()))
+	if err != nil || wait <= 0 {
+		return false
+	}
+	seconds := int(wait.Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed logins, please try again later",
+		"code":        "LOGIN_LOCKED",
+		"retry_after": seconds,
+	})
+	return true
+}
+
+// loginFailed counts a failed login against the account and the caller's IP
+func (h *AuthHandler) loginFailed(ctx context.Context, c *gin.Context, email string) {
+	if locked, err := h.redis.RecordLoginFailure(ctx, loginAccountSubject(email), h.cfg.LoginMaxFailures, h.cfg.LoginLockout); err != nil {
+		log.Printf("Failed to record failed login for %s: %v", email, err)
+	} else if locked {
+		log.Printf("Locked out logins to %s for %s", email, h.cfg.LoginLockout)
+	}
+	if locked, err := h.redis.RecordLoginFailure(ctx, loginIPSubject(c.ClientIP()), h.cfg.LoginMaxFailuresPerIP, h.cfg.LoginLockout); err != nil {
+		log.Printf("Failed to record failed login from %s: %v", c.ClientIP(), err)
+	} else if locked {
+		log.Printf("Locked out logins from %s for %s", c.ClientIP(), h.cfg.LoginLockout)
+	}
+}
+
+// loginDeviceChannel is the OTP channel of codes confirming a new device.
+// Only the account's own Telegram, phone or inbox gets them, and email
+// verification codes, which anyone can have resent, don't count for it.
+const loginDeviceChannel = "login_device"
+
+// errDeviceUnverified is returned for logins from a new device that still
+// have to be confirmed with an OTP
+var errDeviceUnverified = errors.New("device not verified")
+
+// checkLoginDevice lets a login from a device the user signed in from before
+// through. A new device is trusted if it is the user's first, or once the
+// login carries the OTP sent to confirm it; the user is told about it either
+// way. Without OTPs new devices are trusted straight away.
+func (h *AuthHandler) checkLoginDevice(ctx context.Context, c *gin.Context, user *models.User, req LoginRequest) error {
+	db := h.db.WithContext(ctx)
+	deviceID := loginDeviceID(c, req.DeviceID)
+	now := time.Now()
+
+	result := db.Model(&models.Device{}).Where("user_id = ? AND device_id = ?", user.ID, deviceID).
+		Updates(map[string]interface{}{"last_login_at": now, "ip_address": c.ClientIP()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var known int64
+	if err := db.Model(&models.Device{}).Where("user_id = ?", user.ID).Count(&known).Error; err != nil {
+		return err
+	}
+
+	if known > 0 && h.cfg.OTPEnabled {
+		if req.OTP == "" {
+			return errDeviceUnverified
+		}
+		confirmed := db.Model(&models.OTP{}).
+			Where("email = ? AND channel = ? AND code = ? AND is_used = ? AND expires_at > ?", user.Email, loginDeviceChannel, req.OTP, false, now).
+			Update("is_used", true)
+		if confirmed.Error != nil {
+			return confirmed.Error
+		}
+		if confirmed.RowsAffected == 0 {
+			return errDeviceUnverified
+		}
+	}
+
+	name := req.DeviceName
+	if name == "" {
+		name = c.GetHeader("User-Agent")
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	device := models.Device{
+		UserID:      user.ID,
+		DeviceID:    deviceID,
+		Name:        name,
+		City:        h.loginCity(c.ClientIP()),
+		IPAddress:   c.ClientIP(),
+		LastLoginAt: now,
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&device).Error; err != nil {
+		return err
+	}
+
+	if known > 0 {
+		h.notifyNewDevice(db, user.ID, device)
+	}
+	return nil
+}
+
+// loginCity is where a login comes from, as far as the IP address tells
+func (h *AuthHandler) loginCity(ip string) string {
+	return ipCity(h.geo, ip)
+}
+
+// notifyNewDevice tells the user their account was signed in to from a new
+// device, so they can act if it wasn't them
+func (h *AuthHandler) notifyNewDevice(db *gorm.DB, userID uint, device models.Device) {
+	text := "New login from a new device"
+	if device.City != "" {
+		text += " in " + device.City
+	}
+	data := fmt.Sprintf(`{"device_id":%q}`, device.DeviceID)
+	notification := models.Notification{
+		UserID: userID,
+		Type:   "new_device_login",
+		Title:  "New login",
+		Body:   text + ". If this wasn't you, change your password now.",
+		Data:   data,
+	}
+	if err := db.Create(&notification).Error; err != nil {
+		log.Printf("Failed to save new device notification for user %d: %v", userID, err)
+		return
+	}
+	h.notifier.Dispatch(services.Notice{Notification: notification, Text: notification.Body})
+}
+
+// respondDeviceVerification sends a code to confirm a login from a new
+// device and asks the app to log in again with it
+func (h *AuthHandler) respondDeviceVerification(ctx context.Context, c *gin.Context, user *models.User, retried bool) {
+	otp, err := h.issueOTP(h.db.WithContext(ctx), loginDeviceChannel, user.Email, user.Phone)
+	if errors.Is(err, errTooManyOTPs) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many codes requested, please try again later"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create OTP"})
+		return
+	}
+
+	channel, ok := h.sendCode(ctx, user, otp, "login")
+	if !ok {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send the code, please try again"})
+		return
+	}
+
+	message := "We sent you a code to confirm it's you logging in from a new device"
+	if retried {
+		message = "That code is wrong or has expired. We sent you a new one."
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   message,
+		"code":    "DEVICE_VERIFICATION_REQUIRED",
+		"channel": channel,
+	})
+}
+
+// sendCode sends a code, such as a "login" or "verification" one, over the
+// user's linked Telegram, else by SMS, else by email. It returns the channel
+// the code went out on.
+func (h *AuthHandler) sendCode(ctx context.Context, user *models.User, code, purpose string) (string, bool) {
+	if sendTelegramOTP(ctx, h.db, h.telegram, user.ID, code, h.cfg.OTPExpiry) {
+		return "telegram", true
+	}
+
+	body := fmt.Sprintf("Your %s code is %s. It expires in %s.", purpose, code, h.cfg.OTPExpiry)
+	if user.Phone != nil {
+		err := h.sms.Send(ctx, *user.Phone, body)
+		if err == nil {
+			return "sms", true
+		}
+		log.Printf("Failed to text %s code to user %d: %v", purpose, user.ID, err)
+	}
+	if err := h.mailer.Send(ctx, user.Email, "Your "+purpose+" code", body); err != nil {
+		log.Printf("Failed to email %s code to user %d: %v", purpose, user.ID, err)
+		return "", false
+	}
+	return "email", true
+}
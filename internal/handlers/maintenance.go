@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceRequest struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message,omitempty" binding:"max=500"`
+	RetryAfterMinutes int    `json:"retry_after_minutes,omitempty" binding:"min=0,max=1440"`
+}
+
+func (h *AdminHandler) GetMaintenance(c *gin.Context) {
+	ctx := c.Request.Context()
+	c.JSON(http.StatusOK, gin.H{"maintenance": services.LoadMaintenance(ctx, h.redis, h.cfg)})
+}
+
+// SetMaintenance turns maintenance mode on or off for every instance. An
+// empty message falls back to the localized default.
+func (h *AdminHandler) SetMaintenance(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	state := services.MaintenanceState{
+		Enabled:    req.Enabled,
+		Message:    req.Message,
+		RetryAfter: req.RetryAfterMinutes * 60,
+	}
+	if state.RetryAfter == 0 {
+		state.RetryAfter = 300
+	}
+
+	if err := services.SaveMaintenance(ctx, h.redis, state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update maintenance mode"})
+		return
+	}
+
+	h.logAdminAction(h.db.WithContext(ctx), c, "maintenance_updated", "system", 0, fmt.Sprintf("enabled=%t", req.Enabled))
+
+	c.JSON(http.StatusOK, gin.H{"maintenance": services.LoadMaintenance(ctx, h.redis, h.cfg)})
+}
@@ -2,6 +2,10 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,32 +13,46 @@ import (
 	"ethiopia-dating-app/internal/config"
 	"ethiopia-dating-app/internal/models"
 	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+	"ethiopia-dating-app/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MatchHandler struct {
-	db    *gorm.DB
-	redis *redis.Client
-	cfg   *config.Config
+	db       *gorm.DB
+	redis    *redis.Client
+	cfg      *config.Config
+	hub      *websocket.Hub
+	notifier *services.Dispatcher
+	captcha  services.CaptchaVerifier
+	storage  *services.StorageService
 }
 
 type MatchResponse struct {
-	ID        uint        `json:"id"`
-	User      models.User `json:"user"`
-	CreatedAt time.Time   `json:"created_at"`
+	ID           uint               `json:"id"`
+	User         PublicUserResponse `json:"user"`
+	FirstMessage FirstMessageStatus `json:"first_message"`
+	CreatedAt    time.Time          `json:"created_at"`
 }
 
-func NewMatchHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config) *MatchHandler {
+func NewMatchHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, hub *websocket.Hub, notifier *services.Dispatcher, captcha services.CaptchaVerifier, storage *services.StorageService) *MatchHandler {
 	return &MatchHandler{
-		db:    db,
-		redis: redis,
-		cfg:   cfg,
+		db:       db,
+		redis:    redis,
+		cfg:      cfg,
+		hub:      hub,
+		notifier: notifier,
+		captcha:  captcha,
+		storage:  storage,
 	}
 }
 
 func (h *MatchHandler) LikeUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	likedID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
@@ -42,86 +60,241 @@ func (h *MatchHandler) LikeUser(c *gin.Context) {
 		return
 	}
 
-	// Check if user exists and is active
+	// The body is optional; a bare POST is a plain profile like
+	var req LikeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if respondIfUnverified(c, h.db.WithContext(ctx), h.cfg, userID, "likes") {
+		return
+	}
+
+	if h.respondIfSwipingTooFast(ctx, c, userID.(uint), uint(likedID)) {
+		return
+	}
+
+	if h.respondIfOutOfLikes(ctx, c, userID.(uint)) {
+		return
+	}
+
+	// Check if user exists, is active, and isn't paused
 	var likedUser models.User
-	if err := h.db.Where("id = ? AND is_active = ?", likedID, true).First(&likedUser).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ? AND is_active = ? AND is_paused = ? AND age_flagged_at IS NULL", likedID, true, false).Scopes(notOnWaitlist).First(&likedUser).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Likes outside either user's gender preference can never become a match
+	var liker models.User
+	if err := h.db.WithContext(ctx).Select("id", "gender", "seeking").Where("id = ?", userID).First(&liker).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	if !utils.GendersCompatible(liker.Gender, liker.Seeking, likedUser.Gender, likedUser.Seeking) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This profile doesn't match your or their gender preferences"})
+		return
+	}
+
+	if err := h.validateLikeTarget(ctx, uint(likedID), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Serialize likes between the same two users across instances, so two
+	// simultaneous mutual likes can't both see each other and create two matches
+	lock, err := h.redis.Obtain(ctx, pairLockKey(userID.(uint), uint(likedID)), matchLockTTL, redis.LockOptions{Wait: matchLockWait})
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Please try again"})
+		return
+	}
+	defer lock.Release(context.Background())
 
 	// Check if already liked
 	var existingLike models.Like
-	if err := h.db.Where("liker_id = ? AND liked_id = ?", userID, likedID).First(&existingLike).Error; err == nil {
+	if err := h.db.WithContext(ctx).Where("liker_id = ? AND liked_id = ?", userID, likedID).First(&existingLike).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User already liked"})
 		return
 	}
 
+	if h.keptApart(ctx, userID.(uint), uint(likedID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You unmatched recently and can't like each other yet"})
+		return
+	}
+
 	// Check if user is blocked
 	var blocked models.BlockedUser
-	if err := h.db.Where("blocker_id = ? AND blocked_id = ?", userID, likedID).First(&blocked).Error; err == nil {
+	if err := h.db.WithContext(ctx).Where("blocker_id = ? AND blocked_id = ?", userID, likedID).First(&blocked).Error; err == nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot like blocked user"})
 		return
 	}
 
 	// Create like
 	like := models.Like{
-		LikerID: userID.(uint),
-		LikedID: uint(likedID),
+		LikerID:  userID.(uint),
+		LikedID:  uint(likedID),
+		PhotoID:  req.PhotoID,
+		PromptID: req.PromptID,
+	}
+	if req.Comment != "" {
+		like.Comment = &req.Comment
 	}
 
-	if err := h.db.Create(&like).Error; err != nil {
+	if err := h.db.WithContext(ctx).Create(&like).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create like"})
 		return
 	}
 
 	// Check for mutual like (match)
 	var mutualLike models.Like
-	if err := h.db.Where("liker_id = ? AND liked_id = ?", likedID, userID).First(&mutualLike).Error; err == nil {
-		// Create match
-		match := models.Match{
-			User1ID:  userID.(uint),
-			User2ID:  uint(likedID),
-			IsActive: true,
+	if err := h.db.WithContext(ctx).Where("liker_id = ? AND liked_id = ?", likedID, userID).First(&mutualLike).Error; err == nil {
+		if h.matched(ctx, userID.(uint), uint(likedID)) {
+			c.JSON(http.StatusOK, gin.H{"message": "You're already matched"})
+			return
 		}
 
-		if err := h.db.Create(&match).Error; err != nil {
+		response, err := h.openMatch(ctx, lock, liker, likedUser, func(conversationID uint) {
+			// Comments left on either like open the conversation, dated
+			// when they were left, so the match notice follows them
+			h.openWithLikeComments(ctx, conversationID, mutualLike, like)
+		})
+		if errors.Is(err, errStaleLock) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Please try again"})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create match"})
 			return
 		}
 
-		// Create conversation
-		conversation := models.Conversation{
+		c.JSON(http.StatusCreated, gin.H{"message": "It's a match!", "match": response})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User liked successfully"})
+}
+
+// openMatch matches initiator, whose like or accepted compliment made the
+// match, with other. It creates the match and its conversation, lets
+// opening post what should start the conversation, and tells both users.
+// It returns the match as shown to initiator, who learns of it from the
+// response rather than over the WebSocket. lock must be the pair's lock; the
+// match isn't created if a later holder of it has already written.
+func (h *MatchHandler) openMatch(ctx context.Context, lock *redis.Lock, initiator, other models.User, opening func(conversationID uint)) (gin.H, error) {
+	match := models.Match{
+		User1ID:  initiator.ID,
+		User2ID:  other.ID,
+		IsActive: true,
+	}
+	applyFirstMessageRule(h.cfg, &match, initiator, other)
+
+	var conversation models.Conversation
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFence(tx, lock); err != nil {
+			return err
+		}
+		if err := tx.Create(&match).Error; err != nil {
+			return err
+		}
+		conversation = models.Conversation{
 			MatchID:  match.ID,
 			IsActive: true,
 		}
+		return tx.Create(&conversation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		if err := h.db.Create(&conversation).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create conversation"})
-			return
-		}
+	if opening != nil {
+		opening(conversation.ID)
+	}
+	if message, err := services.CreateSystemMessage(h.db.WithContext(ctx), conversation.ID, initiator.ID, services.SystemMatchCreated, nil); err != nil {
+		log.Printf("Failed to post match system message in conversation %d: %v", conversation.ID, err)
+	} else if message != nil {
+		h.hub.BroadcastSystemMessage(conversation.ID, message.ID, initiator.ID, message.Content, message.SystemKind, message.CreatedAt)
+	}
 
-		// Create notifications for both users
-		h.createMatchNotification(userID.(uint), uint(likedID), match.ID)
-		h.createMatchNotification(uint(likedID), userID.(uint), match.ID)
+	// Shared interests and openers for the "It's a match!" screen
+	sharedInterests := h.sharedInterests(ctx, initiator.ID, other.ID)
+	openers := services.ConversationStarters(sharedInterests, match.ID)
+	celebration := h.matchCelebration(ctx, match, initiator.ID, other.ID, sharedInterests, openers)
+
+	// Create notifications for both users
+	h.createMatchNotification(ctx, initiator.ID, other.ID, celebration, openers)
+	h.createMatchNotification(ctx, other.ID, initiator.ID, celebration, openers)
+
+	// The initiator learns from the response; the other user may be offline
+	matchEvent := websocket.MatchEvent{
+		Type:           "match",
+		MatchID:        match.ID,
+		ConversationID: conversation.ID,
+		UserID:         initiator.ID,
+		Timestamp:      match.CreatedAt.Format(time.RFC3339),
+	}
+	if eventBytes, err := json.Marshal(matchEvent); err == nil {
+		h.hub.DeliverToUser(other.ID, eventBytes)
+	}
 
-		// Cache match data in Redis
-		h.cacheMatchData(match.ID, userID.(uint), uint(likedID))
+	// Cache match data in Redis
+	h.cacheMatchData(ctx, match.ID, initiator.ID, other.ID)
+
+	return gin.H{
+		"id":               match.ID,
+		"user":             newPublicUser(other, loadViewer(h.db.WithContext(ctx), initiator.ID)),
+		"shared_interests": sharedInterests,
+		"openers":          openers,
+		"celebration":      celebration,
+		"first_message":    newFirstMessageStatus(match, initiator.ID, startersWhoWrote(h.db.WithContext(ctx), []uint{match.ID})[match.ID]),
+		"created_at":       match.CreatedAt,
+	}, nil
+}
 
-		c.JSON(http.StatusCreated, gin.H{
-			"message": "It's a match!",
-			"match": gin.H{
-				"id":         match.ID,
-				"user":       likedUser,
-				"created_at": match.CreatedAt,
-			},
-		})
-		return
+const (
+	matchLockTTL  = 10 * time.Second
+	matchLockWait = 2 * time.Second
+)
+
+// errStaleLock is returned when a lock expired and someone else took it and
+// wrote before its holder did
+var errStaleLock = errors.New("lock taken over by a later holder")
+
+// checkFence records lock's fencing token as the latest to write under its
+// key, or returns errStaleLock if a later token already has. Tokens stored
+// longer ago than the fence counter lives are replaced, since the counter
+// may have started over since. It must run in the transaction of the write
+// it guards.
+func checkFence(tx *gorm.DB, lock *redis.Lock) error {
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "lock_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "lock_fences.token < ? OR lock_fences.updated_at < ?", Vars: []interface{}{lock.Token(), time.Now().Add(-redis.FenceTTL)}},
+		}},
+	}).Create(&models.LockFence{LockKey: lock.Key(), Token: lock.Token()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errStaleLock
 	}
+	return nil
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User liked successfully"})
+// pairLockKey names the lock for likes between two users, whichever of them
+// is liking
+func pairLockKey(a, b uint) string {
+	if a > b {
+		a, b = b, a
+	}
+	return fmt.Sprintf("lock:match:%d:%d", a, b)
 }
 
 func (h *MatchHandler) DislikeUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	dislikedID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
@@ -129,10 +302,23 @@ func (h *MatchHandler) DislikeUser(c *gin.Context) {
 		return
 	}
 
-	// Check if already disliked
+	if h.respondIfSwipingTooFast(ctx, c, userID.(uint), uint(dislikedID)) {
+		return
+	}
+
+	// Check if already disliked. A dislike that expired is made again.
 	var existingDislike models.Dislike
-	if err := h.db.Where("disliker_id = ? AND disliked_id = ?", userID, dislikedID).First(&existingDislike).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User already disliked"})
+	if err := h.db.WithContext(ctx).Where("disliker_id = ? AND disliked_id = ?", userID, dislikedID).First(&existingDislike).Error; err == nil {
+		cutoff := dislikeCutoff(h.db.WithContext(ctx), h.cfg, loadViewer(h.db.WithContext(ctx), userID), time.Now())
+		if existingDislike.CreatedAt.After(cutoff) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User already disliked"})
+			return
+		}
+		if err := h.db.WithContext(ctx).Model(&existingDislike).Update("created_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create dislike"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "User disliked successfully"})
 		return
 	}
 
@@ -142,7 +328,7 @@ func (h *MatchHandler) DislikeUser(c *gin.Context) {
 		DislikedID: uint(dislikedID),
 	}
 
-	if err := h.db.Create(&dislike).Error; err != nil {
+	if err := h.db.WithContext(ctx).Create(&dislike).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create dislike"})
 		return
 	}
@@ -151,18 +337,29 @@ func (h *MatchHandler) DislikeUser(c *gin.Context) {
 }
 
 func (h *MatchHandler) GetMatches(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	// Get matches where user is either user1 or user2
 	var matches []models.Match
-	if err := h.db.Where("(user1_id = ? OR user2_id = ?) AND is_active = ?", userID, userID, true).
-		Preload("User1.ProfilePhotos").Preload("User1.Interests").
-		Preload("User2.ProfilePhotos").Preload("User2.Interests").
+	if err := h.db.WithContext(ctx).Where("(user1_id = ? OR user2_id = ?) AND is_active = ? AND hidden_at IS NULL", userID, userID, true).
+		Preload("User1.ProfilePhotos").Preload("User1.Interests").Preload("User1.MessageStats").
+		Preload("User2.ProfilePhotos").Preload("User2.Interests").Preload("User2.MessageStats").
 		Order("created_at DESC").Find(&matches).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch matches"})
 		return
 	}
 
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+
+	var waiting []uint
+	for _, match := range matches {
+		if firstMessageRuleActive(match, false) {
+			waiting = append(waiting, match.ID)
+		}
+	}
+	starterWrote := startersWhoWrote(h.db.WithContext(ctx), waiting)
+
 	var matchResponses []MatchResponse
 	for _, match := range matches {
 		var otherUser models.User
@@ -173,9 +370,10 @@ func (h *MatchHandler) GetMatches(c *gin.Context) {
 		}
 
 		matchResponses = append(matchResponses, MatchResponse{
-			ID:        match.ID,
-			User:      otherUser,
-			CreatedAt: match.CreatedAt,
+			ID:           match.ID,
+			User:         newPublicUser(otherUser, viewer),
+			FirstMessage: newFirstMessageStatus(match, userID.(uint), starterWrote[match.ID]),
+			CreatedAt:    match.CreatedAt,
 		})
 	}
 
@@ -183,6 +381,7 @@ func (h *MatchHandler) GetMatches(c *gin.Context) {
 }
 
 func (h *MatchHandler) Unmatch(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	matchID, err := strconv.ParseUint(c.Param("match_id"), 10, 32)
 	if err != nil {
@@ -192,49 +391,78 @@ func (h *MatchHandler) Unmatch(c *gin.Context) {
 
 	// Find match
 	var match models.Match
-	if err := h.db.Where("id = ? AND (user1_id = ? OR user2_id = ?) AND is_active = ?",
+	if err := h.db.WithContext(ctx).Where("id = ? AND (user1_id = ? OR user2_id = ?) AND is_active = ?",
 		matchID, userID, userID, true).First(&match).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
 		return
 	}
 
-	// Deactivate match
+	// Deactivate match and keep the pair apart for the rematch cooldown
 	match.IsActive = false
-	if err := h.db.Save(&match).Error; err != nil {
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&match).Error; err != nil {
+			return err
+		}
+		return recordUnmatch(tx, h.cfg, match, userID.(uint))
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmatch"})
 		return
 	}
 
 	// Deactivate conversation
 	var conversation models.Conversation
-	if err := h.db.Where("match_id = ?", matchID).First(&conversation).Error; err == nil {
+	if err := h.db.WithContext(ctx).Where("match_id = ?", matchID).First(&conversation).Error; err == nil {
 		conversation.IsActive = false
-		h.db.Save(&conversation)
+		h.db.WithContext(ctx).Save(&conversation)
+		h.redis.ForgetConversations(ctx, conversation.ID)
 	}
 
 	// Remove from Redis cache
-	h.redis.Del(c.Request.Context(), "match:"+strconv.FormatUint(matchID, 10))
+	h.redis.Del(ctx, "match:"+strconv.FormatUint(matchID, 10))
+
+	if _, err := services.AskForMatchFeedback(h.db.WithContext(ctx), h.notifier, match.ID, services.FeedbackAfterUnmatch); err != nil {
+		log.Printf("Failed to ask for feedback on match %d: %v", match.ID, err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Unmatched successfully"})
 }
 
 // Helper methods
-func (h *MatchHandler) createMatchNotification(userID, otherUserID, matchID uint) {
+func (h *MatchHandler) createMatchNotification(ctx context.Context, userID, otherUserID uint, celebration MatchCelebration, openers []string) {
+	data, _ := json.Marshal(gin.H{
+		"match_id":         celebration.MatchID,
+		"user_id":          otherUserID,
+		"shared_interests": celebration.SharedInterests,
+		"openers":          openers,
+		"celebration":      celebration,
+	})
+
 	notification := models.Notification{
 		UserID: userID,
 		Type:   "match",
 		Title:  "New Match!",
 		Body:   "You have a new match! Start chatting now.",
-		Data:   `{"match_id": ` + strconv.FormatUint(uint64(matchID), 10) + `}`,
+		Data:   string(data),
 	}
 
-	h.db.Create(&notification)
+	if err := h.db.WithContext(ctx).Create(&notification).Error; err != nil {
+		return
+	}
+	h.notifier.Dispatch(services.Notice{Notification: notification, Text: "You have a new match! Open the app to start chatting."})
+}
 
-	// TODO: Send push notification
-	// h.sendPushNotification(userID, notification.Title, notification.Body, notification.Data)
+// sharedInterests returns the interests both users picked, alphabetically
+func (h *MatchHandler) sharedInterests(ctx context.Context, userID, otherUserID uint) []models.Interest {
+	shared := []models.Interest{}
+	h.db.WithContext(ctx).Where("id IN (SELECT interest_id FROM user_interests WHERE user_id = ?)", userID).
+		Where("id IN (SELECT interest_id FROM user_interests WHERE user_id = ?)", otherUserID).
+		Order("name ASC").
+		Find(&shared)
+	return shared
 }
 
-func (h *MatchHandler) cacheMatchData(matchID, user1ID, user2ID uint) {
+func (h *MatchHandler) cacheMatchData(ctx context.Context, matchID, user1ID, user2ID uint) {
 	// Cache match data in Redis for quick access
 	matchKey := "match:" + strconv.FormatUint(uint64(matchID), 10)
 	matchData := map[string]interface{}{
@@ -244,7 +472,6 @@ func (h *MatchHandler) cacheMatchData(matchID, user1ID, user2ID uint) {
 		"created_at": time.Now().Unix(),
 	}
 
-	ctx := context.Background()
 	h.redis.HSet(ctx, matchKey, matchData)
 	h.redis.Expire(ctx, matchKey, 24*time.Hour)
 }
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MatchFeedbackRequest struct {
+	Rating  int     `json:"rating" binding:"required,min=1,max=5"`
+	Outcome string  `json:"outcome" binding:"omitempty,oneof=met_in_person still_talking no_spark stopped_replying felt_unsafe"`
+	Comment *string `json:"comment" binding:"omitempty,max=500"`
+}
+
+// MatchQuality summarizes match feedback for admin analytics
+type MatchQuality struct {
+	Ratings                int64          `json:"ratings"`
+	AverageRating          float64        `json:"average_rating"`
+	UnmatchedAverageRating float64        `json:"unmatched_average_rating"`
+	OngoingAverageRating   float64        `json:"ongoing_average_rating"`
+	ByRating               []RatingCount  `json:"by_rating" gorm:"-"`
+	ByOutcome              []OutcomeCount `json:"by_outcome" gorm:"-"`
+}
+
+type RatingCount struct {
+	Rating int   `json:"rating"`
+	Count  int64 `json:"count"`
+}
+
+type OutcomeCount struct {
+	Outcome       string  `json:"outcome"`
+	Count         int64   `json:"count"`
+	AverageRating float64 `json:"average_rating"`
+}
+
+// feedbackFeatures are the signals a rating is a label for: the ranking score
+// the rater would have seen for the other user, and how the match went
+func feedbackFeatures(score ScoreBreakdown, match models.Match, messages int64, now time.Time) map[string]float64 {
+	return map[string]float64{
+		"shared_interests": score.SharedInterests,
+		"distance":         score.Distance,
+		"activity":         score.Activity,
+		"profile":          score.Profile,
+		"recency":          score.Recency,
+		"intent":           score.Intent,
+		"total":            score.Total,
+		"match_age_days":   now.Sub(match.CreatedAt).Hours() / 24,
+		"messages":         float64(messages),
+	}
+}
+
+// SubmitMatchFeedback records how the caller felt a match went, whether or
+// not it is still active. Each user rates a match once.
+func (h *MatchHandler) SubmitMatchFeedback(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	matchID, err := strconv.ParseUint(c.Param("match_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return
+	}
+
+	var req MatchFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var match models.Match
+	if err := h.db.WithContext(ctx).Where("id = ? AND (user1_id = ? OR user2_id = ?)", matchID, userID, userID).
+		First(&match).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	}
+
+	otherID := match.User1ID
+	if otherID == userID.(uint) {
+		otherID = match.User2ID
+	}
+
+	var rater, other models.User
+	if err := h.db.WithContext(ctx).Preload("Interests").Where("id = ?", userID).First(&rater).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		return
+	}
+	if err := h.db.WithContext(ctx).Unscoped().Preload("Interests").Preload("ProfilePhotos").
+		Where("id = ?", otherID).First(&other).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load match"})
+		return
+	}
+
+	var messages int64
+	h.db.WithContext(ctx).Model(&models.Message{}).
+		Where("conversation_id IN (SELECT id FROM conversations WHERE match_id = ?) AND message_type != ?", match.ID, "system").
+		Count(&messages)
+
+	now := time.Now()
+	feedback := models.MatchFeedback{
+		MatchID:   match.ID,
+		UserID:    userID.(uint),
+		Rating:    req.Rating,
+		Outcome:   req.Outcome,
+		Comment:   req.Comment,
+		Unmatched: !match.IsActive,
+		Features:  feedbackFeatures(scoreCandidate(&rater, other, now), match, messages, now),
+	}
+	result := h.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&feedback)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save feedback"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already rated this match"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Thanks for letting us know", "feedback": feedback})
+}
+
+// GetMatchFeedback lists match ratings with the signals they label, oldest
+// first, for training the recommendation model. since limits it to newer
+// ratings.
+func (h *AdminHandler) GetMatchFeedback(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.MatchFeedback{})
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time"})
+			return
+		}
+		query = query.Where("created_at > ?", t)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var feedback []models.MatchFeedback
+	if err := query.Order("created_at ASC, id ASC").Offset((page - 1) * limit).Limit(limit).Find(&feedback).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch match feedback"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"feedback": feedback,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// matchQuality aggregates match ratings given since the time
+func matchQuality(db *gorm.DB, since time.Time) MatchQuality {
+	var quality MatchQuality
+	recent := func() *gorm.DB {
+		return db.Model(&models.MatchFeedback{}).Where("created_at >= ?", since)
+	}
+
+	recent().Select("COUNT(*) AS ratings, COALESCE(AVG(rating), 0) AS average_rating, " +
+		"COALESCE(AVG(rating) FILTER (WHERE unmatched), 0) AS unmatched_average_rating, " +
+		"COALESCE(AVG(rating) FILTER (WHERE NOT unmatched), 0) AS ongoing_average_rating").
+		Scan(&quality)
+	recent().Select("rating, COUNT(*) AS count").Group("rating").Order("rating").Scan(&quality.ByRating)
+	recent().Select("outcome, COUNT(*) AS count, AVG(rating) AS average_rating").
+		Where("outcome != ''").Group("outcome").Order("count DESC").Scan(&quality.ByOutcome)
+	return quality
+}
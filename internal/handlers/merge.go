@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errMergeSelfMatch = errors.New("accounts are matched with each other")
+
+type MergeUsersRequest struct {
+	DuplicateID uint   `json:"duplicate_id" binding:"required"`
+	Reason      string `json:"reason" binding:"required,max=500"`
+}
+
+// mergedColumns point at a user and move to the surviving account as they are
+var mergedColumns = []struct{ table, column string }{
+	{"messages", "sender_id"},
+	{"room_messages", "sender_id"},
+	{"notifications", "user_id"},
+	{"reports", "reporter_id"},
+	{"reports", "reported_id"},
+	{"strikes", "user_id"},
+	{"appeals", "user_id"},
+	{"feedbacks", "user_id"},
+	{"user_activities", "user_id"},
+	{"trusted_contacts", "user_id"},
+	{"date_plans", "user_id"},
+	{"date_plans", "match_user_id"},
+	{"identity_verifications", "user_id"},
+	{"name_change_requests", "user_id"},
+	{"unmatches", "user_id"},
+	{"unmatches", "other_user_id"},
+	{"private_album_grants", "owner_id"},
+	{"private_album_grants", "viewer_id"},
+	{"private_photo_accesses", "owner_id"},
+	{"private_photo_accesses", "viewer_id"},
+	{"support_tickets", "user_id"},
+	{"notification_deliveries", "user_id"},
+}
+
+// mergedPairs are rows keyed by a user and something else. They move unless
+// the survivor already has a row for the same thing, and pairs that would
+// point the survivor at itself are dropped.
+var mergedPairs = []struct {
+	table, column, other string
+	otherIsUser          bool
+}{
+	{"likes", "liker_id", "liked_id", true},
+	{"likes", "liked_id", "liker_id", true},
+	{"dislikes", "disliker_id", "disliked_id", true},
+	{"dislikes", "disliked_id", "disliker_id", true},
+	{"blocked_users", "blocker_id", "blocked_id", true},
+	{"blocked_users", "blocked_id", "blocker_id", true},
+	{"favorites", "user_id", "favorite_id", true},
+	{"favorites", "favorite_id", "user_id", true},
+	{"compliments", "sender_id", "recipient_id", true},
+	{"compliments", "recipient_id", "sender_id", true},
+	{"user_interests", "user_id", "interest_id", false},
+	{"room_members", "user_id", "room_id", false},
+	{"poll_votes", "user_id", "poll_id", false},
+	{"phone_blocks", "blocker_id", "phone_hash", false},
+	{"profile_bios", "user_id", "language", false},
+	{"badges", "user_id", "kind", false},
+	{"match_feedbacks", "user_id", "match_id", false},
+}
+
+// droppedTables hold per-device or derived state the duplicate doesn't need
+var droppedTables = []string{
+	"user_sessions", "sync_devices", "key_bundles", "one_time_pre_keys",
+	"contact_hashes", "notification_preferences", "message_stats", "waitlists",
+	"devices", "push_tokens", "weekly_digests",
+}
+
+// MergeUsers folds a duplicate account into the one in the path: photos,
+// matches and their conversations, likes, blocks, reports and premium status
+// move to the survivor, then the duplicate is deactivated and soft-deleted.
+// It all happens in one transaction.
+func (h *AdminHandler) MergeUsers(c *gin.Context) {
+	ctx := c.Request.Context()
+	survivorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req MergeUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DuplicateID == uint(survivorID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Can't merge an account into itself"})
+		return
+	}
+
+	// Conversations change hands or close, so their cached members go stale
+	conversationIDs := userConversationIDs(h.db.WithContext(ctx), uint(survivorID), req.DuplicateID)
+
+	var survivor models.User
+	var moved map[string]int64
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{uint(survivorID), req.DuplicateID}).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+		duplicate := users[0]
+		survivor = users[1]
+		if survivor.ID != uint(survivorID) {
+			survivor, duplicate = duplicate, survivor
+		}
+
+		var err error
+		if moved, err = mergeAccounts(tx, &survivor, &duplicate); err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "users_merged", "user", survivor.ID,
+			fmt.Sprintf("merged user %d: %s", duplicate.ID, req.Reason))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if errors.Is(err, errMergeSelfMatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": "These accounts have an active match with each other; unmatch them first"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge accounts"})
+		return
+	}
+
+	h.redis.ForgetConversations(ctx, conversationIDs...)
+
+	// Sign the duplicate out everywhere
+	h.redis.Del(ctx, "session:"+strconv.FormatUint(uint64(req.DuplicateID), 10))
+	h.redis.ClearPresence(ctx, req.DuplicateID)
+	services.BlockAccount(ctx, h.redis, req.DuplicateID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"user": survivor, "moved": moved})
+}
+
+// mergeAccounts moves everything of duplicate's onto survivor and deletes
+// duplicate. It returns how many rows moved per kind.
+func mergeAccounts(tx *gorm.DB, survivor, duplicate *models.User) (map[string]int64, error) {
+	moved := make(map[string]int64)
+
+	var active int64
+	if err := tx.Model(&models.Match{}).
+		Where("is_active = ? AND ((user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?))",
+			true, survivor.ID, duplicate.ID, duplicate.ID, survivor.ID).
+		Count(&active).Error; err != nil {
+		return nil, err
+	}
+	if active > 0 {
+		return nil, errMergeSelfMatch
+	}
+
+	// Photos go after the survivor's own, which stay primary if they have one
+	var photoCount int64
+	if err := tx.Model(&models.ProfilePhoto{}).Where("user_id = ?", survivor.ID).Count(&photoCount).Error; err != nil {
+		return nil, err
+	}
+	result := tx.Model(&models.ProfilePhoto{}).Where("user_id = ?", duplicate.ID).Updates(map[string]interface{}{
+		"user_id":    survivor.ID,
+		"order":      gorm.Expr(`"order" + ?`, photoCount),
+		"is_primary": gorm.Expr("is_primary AND ? = 0", photoCount),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	moved["photos"] = result.RowsAffected
+
+	// Prompts only come along when the survivor has none of their own
+	var promptCount int64
+	if err := tx.Model(&models.ProfilePrompt{}).Where("user_id = ?", survivor.ID).Count(&promptCount).Error; err != nil {
+		return nil, err
+	}
+	if promptCount == 0 {
+		if err := tx.Model(&models.ProfilePrompt{}).Where("user_id = ?", duplicate.ID).Update("user_id", survivor.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("user_id = ?", duplicate.ID).Delete(&models.ProfilePrompt{}).Error; err != nil {
+		return nil, err
+	}
+
+	matches, err := mergeMatches(tx, survivor.ID, duplicate.ID)
+	if err != nil {
+		return nil, err
+	}
+	moved["matches"] = matches
+
+	for _, ref := range mergedColumns {
+		result := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", ref.table, ref.column, ref.column), survivor.ID, duplicate.ID)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		moved[ref.table] += result.RowsAffected
+	}
+
+	for _, pair := range mergedPairs {
+		query := fmt.Sprintf("UPDATE %[1]s SET %[2]s = ? WHERE %[2]s = ? AND %[3]s NOT IN (SELECT %[3]s FROM %[1]s WHERE %[2]s = ?)",
+			pair.table, pair.column, pair.other)
+		args := []interface{}{survivor.ID, duplicate.ID, survivor.ID}
+		if pair.otherIsUser {
+			query += fmt.Sprintf(" AND %s <> ?", pair.other)
+			args = append(args, survivor.ID)
+		}
+		result := tx.Exec(query, args...)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		moved[pair.table] += result.RowsAffected
+
+		// Whatever is left duplicates the survivor's rows or points at them
+		if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", pair.table, pair.column), duplicate.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// One Telegram chat per account; the survivor's own link wins
+	var links int64
+	if err := tx.Model(&models.TelegramLink{}).Where("user_id = ?", survivor.ID).Count(&links).Error; err != nil {
+		return nil, err
+	}
+	if links == 0 {
+		if err := tx.Model(&models.TelegramLink{}).Where("user_id = ?", duplicate.ID).Update("user_id", survivor.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("user_id = ?", duplicate.ID).Delete(&models.TelegramLink{}).Error; err != nil {
+		return nil, err
+	}
+
+	for _, table := range droppedTables {
+		if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE user_id = ?", table), duplicate.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Entitlements and verifications carry over
+	survivor.IsPremium = survivor.IsPremium || duplicate.IsPremium
+	survivor.IsVerified = survivor.IsVerified || duplicate.IsVerified
+	survivor.IDVerified = survivor.IDVerified || duplicate.IDVerified
+	survivor.StudentBadge = survivor.StudentBadge || duplicate.StudentBadge
+	survivor.ProfessionalBadge = survivor.ProfessionalBadge || duplicate.ProfessionalBadge
+	if survivor.PhotoVerifiedAt == nil {
+		survivor.PhotoVerifiedAt = duplicate.PhotoVerifiedAt
+	}
+	if survivor.Phone == nil && duplicate.Phone != nil {
+		// The duplicate keeps the number until it is deleted, so free it first
+		survivor.Phone, survivor.PhoneHash = duplicate.Phone, duplicate.PhoneHash
+		survivor.PhoneVerifiedAt = duplicate.PhoneVerifiedAt
+		if err := tx.Model(duplicate).Updates(map[string]interface{}{"phone": nil, "phone_hash": nil}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := saveUserVersioned(tx, survivor, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(duplicate).Updates(map[string]interface{}{
+		"is_active": false,
+		"is_online": false,
+		"version":   gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Delete(duplicate).Error; err != nil {
+		return nil, err
+	}
+
+	return moved, nil
+}
+
+// mergeMatches hands the duplicate's matches to the survivor, with their
+// conversations, unless the survivor already matched the same person. Those
+// and any old match between the two accounts are closed instead.
+func mergeMatches(tx *gorm.DB, survivorID, duplicateID uint) (int64, error) {
+	var matches []models.Match
+	if err := tx.Where("user1_id = ? OR user2_id = ?", duplicateID, duplicateID).Find(&matches).Error; err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, match := range matches {
+		other := match.User1ID
+		if other == duplicateID {
+			other = match.User2ID
+		}
+
+		var existing int64
+		if other != survivorID {
+			if err := tx.Model(&models.Match{}).
+				Where("(user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)", survivorID, other, other, survivorID).
+				Count(&existing).Error; err != nil {
+				return moved, err
+			}
+		}
+		if other == survivorID || existing > 0 {
+			if err := tx.Model(&match).Update("is_active", false).Error; err != nil {
+				return moved, err
+			}
+			if err := tx.Model(&models.Conversation{}).Where("match_id = ?", match.ID).Update("is_active", false).Error; err != nil {
+				return moved, err
+			}
+			continue
+		}
+
+		updates := map[string]interface{}{}
+		if match.User1ID == duplicateID {
+			updates["user1_id"] = survivorID
+		} else {
+			updates["user2_id"] = survivorID
+		}
+		if match.StarterID != nil && *match.StarterID == duplicateID {
+			updates["starter_id"] = survivorID
+		}
+		if err := tx.Model(&match).Updates(updates).Error; err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"ethiopia-dating-app/internal/config"
 	"ethiopia-dating-app/internal/models"
 	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
 	"ethiopia-dating-app/internal/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -16,64 +19,83 @@ import (
 )
 
 type MessageHandler struct {
-	db    *gorm.DB
-	redis *redis.Client
-	cfg   *config.Config
-	hub   *websocket.Hub
+	db       *gorm.DB
+	redis    *redis.Client
+	cfg      *config.Config
+	hub      *websocket.Hub
+	notifier *services.Dispatcher
+	storage  *services.StorageService
 }
 
 type SendMessageRequest struct {
 	Content     string `json:"content" binding:"required"`
 	MessageType string `json:"message_type" binding:"omitempty,oneof=text image emoji"`
+	// Encrypted must be set, with ciphertext as the content, exactly when the
+	// conversation is end-to-end encrypted
+	Encrypted bool `json:"encrypted,omitempty"`
 }
 
 type ConversationResponse struct {
-	ID          uint            `json:"id"`
-	MatchID     uint            `json:"match_id"`
-	OtherUser   models.User     `json:"other_user"`
-	LastMessage *models.Message `json:"last_message,omitempty"`
-	UnreadCount int64           `json:"unread_count"`
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
+	ID          uint               `json:"id"`
+	MatchID     uint               `json:"match_id"`
+	OtherUser   PublicUserResponse `json:"other_user"`
+	LastMessage *models.Message    `json:"last_message,omitempty"`
+	UnreadCount int64              `json:"unread_count"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
 }
 
 type MessageResponse struct {
-	ID          uint        `json:"id"`
-	SenderID    uint        `json:"sender_id"`
-	Content     string      `json:"content"`
-	MessageType string      `json:"message_type"`
-	IsRead      bool        `json:"is_read"`
-	ReadAt      *time.Time  `json:"read_at,omitempty"`
-	CreatedAt   time.Time   `json:"created_at"`
-	Sender      models.User `json:"sender,omitempty"`
+	ID             uint `json:"id"`
+	ConversationID uint `json:"conversation_id,omitempty"` // set in sync pages, which span conversations
+
+	SenderID    uint               `json:"sender_id"`
+	Content     string             `json:"content"`
+	MessageType string             `json:"message_type"`
+	SystemKind  string             `json:"system_kind,omitempty"` // set on system messages, which clients show apart
+	IsRead      bool               `json:"is_read"`
+	ReadAt      *time.Time         `json:"read_at,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Sender      PublicUserResponse `json:"sender,omitempty"`
+
+	HasContactInfo bool                   `json:"has_contact_info"`
+	IsEncrypted    bool                   `json:"is_encrypted,omitempty"`
+	Poll           *PollResponse          `json:"poll,omitempty"`
+	DateSuggestion *models.DateSuggestion `json:"date_suggestion,omitempty"`
 }
 
-func NewMessageHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, hub *websocket.Hub) *MessageHandler {
+func NewMessageHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, hub *websocket.Hub, notifier *services.Dispatcher, storage *services.StorageService) *MessageHandler {
 	return &MessageHandler{
-		db:    db,
-		redis: redis,
-		cfg:   cfg,
-		hub:   hub,
+		db:       db,
+		redis:    redis,
+		cfg:      cfg,
+		hub:      hub,
+		notifier: notifier,
+		storage:  storage,
 	}
 }
 
 func (h *MessageHandler) GetConversations(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	// Get all matches for the user
 	var matches []models.Match
-	if err := h.db.Where("(user1_id = ? OR user2_id = ?) AND is_active = ?", userID, userID, true).
-		Preload("User1.ProfilePhotos").Preload("User2.ProfilePhotos").
+	if err := h.db.WithContext(ctx).Where("(user1_id = ? OR user2_id = ?) AND is_active = ? AND hidden_at IS NULL", userID, userID, true).
+		Preload("User1.ProfilePhotos").Preload("User1.MessageStats").
+		Preload("User2.ProfilePhotos").Preload("User2.MessageStats").
 		Find(&matches).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch matches"})
 		return
 	}
 
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+
 	var conversations []ConversationResponse
 	for _, match := range matches {
 		// Get conversation for this match
 		var conversation models.Conversation
-		if err := h.db.Where("match_id = ? AND is_active = ?", match.ID, true).First(&conversation).Error; err != nil {
+		if err := h.db.WithContext(ctx).Where("match_id = ? AND is_active = ?", match.ID, true).First(&conversation).Error; err != nil {
 			continue // Skip if no conversation exists
 		}
 
@@ -87,19 +109,25 @@ func (h *MessageHandler) GetConversations(c *gin.Context) {
 
 		// Get last message
 		var lastMessage models.Message
-		h.db.Where("conversation_id = ?", conversation.ID).
+		h.db.WithContext(ctx).Where("conversation_id = ?", conversation.ID).Scopes(visibleMessages(userID.(uint))).
 			Order("created_at DESC").First(&lastMessage)
 
-		// Get unread count
+		if lastMessage.HasContactInfo {
+			var conversationSize int64
+			h.db.WithContext(ctx).Model(&models.Message{}).Where("conversation_id = ?", conversation.ID).Count(&conversationSize)
+			lastMessage.Content = h.guardedContent(lastMessage, userID.(uint), conversationSize)
+		}
+
+		// Get unread count; system messages never count
 		var unreadCount int64
-		h.db.Model(&models.Message{}).
-			Where("conversation_id = ? AND sender_id != ? AND is_read = ?",
-				conversation.ID, userID, false).Count(&unreadCount)
+		h.db.WithContext(ctx).Model(&models.Message{}).
+			Where("conversation_id = ? AND sender_id != ? AND is_read = ? AND message_type != ?",
+				conversation.ID, userID, false, "system").Scopes(visibleMessages(userID.(uint))).Count(&unreadCount)
 
 		conversations = append(conversations, ConversationResponse{
 			ID:          conversation.ID,
 			MatchID:     match.ID,
-			OtherUser:   otherUser,
+			OtherUser:   newPublicUser(otherUser, viewer),
 			LastMessage: &lastMessage,
 			UnreadCount: unreadCount,
 			CreatedAt:   conversation.CreatedAt,
@@ -122,6 +150,7 @@ func (h *MessageHandler) GetConversations(c *gin.Context) {
 }
 
 func (h *MessageHandler) GetMessages(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
 	if err != nil {
@@ -130,14 +159,14 @@ func (h *MessageHandler) GetMessages(c *gin.Context) {
 	}
 
 	// Verify user has access to this conversation
-	if !h.userHasAccessToConversation(userID.(uint), uint(conversationID)) {
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
 		return
 	}
 
 	// Get messages
 	var messages []models.Message
-	if err := h.db.Where("conversation_id = ?", conversationID).
+	if err := h.db.WithContext(ctx).Where("conversation_id = ?", conversationID).Scopes(visibleMessages(userID.(uint))).
 		Preload("Sender").
 		Order("created_at ASC").Find(&messages).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
@@ -145,32 +174,56 @@ func (h *MessageHandler) GetMessages(c *gin.Context) {
 	}
 
 	// Mark messages as read
-	h.db.Model(&models.Message{}).
-		Where("conversation_id = ? AND sender_id != ? AND is_read = ?",
+	h.db.WithContext(ctx).Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id != ? AND is_read = ? AND held_at IS NULL",
 			conversationID, userID, false).
 		Updates(map[string]interface{}{
 			"is_read": true,
 			"read_at": time.Now(),
 		})
 
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+	conversationSize := int64(len(messages))
+
+	var pollMessageIDs, suggestionMessageIDs []uint
+	for _, msg := range messages {
+		switch msg.MessageType {
+		case "poll":
+			pollMessageIDs = append(pollMessageIDs, msg.ID)
+		case "date_suggestion":
+			suggestionMessageIDs = append(suggestionMessageIDs, msg.ID)
+		}
+	}
+	polls := h.loadPolls(ctx, pollMessageIDs, userID.(uint))
+	suggestions := h.loadDateSuggestions(ctx, suggestionMessageIDs)
+
 	var messageResponses []MessageResponse
 	for _, msg := range messages {
 		messageResponses = append(messageResponses, MessageResponse{
-			ID:          msg.ID,
-			SenderID:    msg.SenderID,
-			Content:     msg.Content,
-			MessageType: msg.MessageType,
-			IsRead:      msg.IsRead,
-			ReadAt:      msg.ReadAt,
-			CreatedAt:   msg.CreatedAt,
-			Sender:      msg.Sender,
+			ID:             msg.ID,
+			SenderID:       msg.SenderID,
+			Content:        h.guardedContent(msg, userID.(uint), conversationSize),
+			MessageType:    msg.MessageType,
+			SystemKind:     msg.SystemKind,
+			IsRead:         msg.IsRead,
+			ReadAt:         msg.ReadAt,
+			CreatedAt:      msg.CreatedAt,
+			Sender:         newPublicUser(msg.Sender, viewer),
+			HasContactInfo: msg.HasContactInfo,
+			IsEncrypted:    msg.IsEncrypted,
+			Poll:           polls[msg.ID],
+			DateSuggestion: suggestions[msg.ID],
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"messages": messageResponses})
+	c.JSON(http.StatusOK, gin.H{
+		"messages":          messageResponses,
+		"archived_messages": h.archivedMessageCount(ctx, uint(conversationID)),
+	})
 }
 
 func (h *MessageHandler) SendMessage(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
 	if err != nil {
@@ -190,11 +243,34 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 	}
 
 	// Verify user has access to this conversation
-	if !h.userHasAccessToConversation(userID.(uint), uint(conversationID)) {
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
 		return
 	}
 
+	if respondIfMuted(c, h.db.WithContext(ctx), userID) {
+		return
+	}
+
+	if respondIfUnverified(c, h.db.WithContext(ctx), h.cfg, userID, "messaging") {
+		return
+	}
+
+	if respondIfNotStarter(ctx, c, h.db, uint(conversationID), userID.(uint)) {
+		return
+	}
+
+	// A client that missed the switch to encryption must not send plaintext
+	encrypted := h.conversationEncrypted(ctx, uint(conversationID))
+	if encrypted != req.Encrypted {
+		if encrypted {
+			c.JSON(http.StatusConflict, gin.H{"error": "This conversation is end-to-end encrypted", "code": "ENCRYPTION_REQUIRED"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This conversation is not end-to-end encrypted"})
+		}
+		return
+	}
+
 	// Create message
 	message := models.Message{
 		ConversationID: uint(conversationID),
@@ -202,54 +278,121 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 		Content:        req.Content,
 		MessageType:    req.MessageType,
 		IsRead:         false,
+		IsEncrypted:    encrypted,
 	}
 
-	if err := h.db.Create(&message).Error; err != nil {
+	// Guard against moving off-platform in the first messages; ciphertext
+	// can't be checked
+	var conversationSize int64
+	h.db.WithContext(ctx).Model(&models.Message{}).Where("conversation_id = ?", conversationID).Count(&conversationSize)
+	var contactKinds []string
+	if !encrypted {
+		contactKinds = h.checkContactInfo(&message, conversationSize)
+	}
+
+	// Low-trust senders' messages wait for a moderator. Ciphertext can't be
+	// read, so encrypted ones wait unread and are judged on the sender.
+	if h.holdsMessages(ctx, userID.(uint)) {
+		now := time.Now()
+		message.HeldAt = &now
+	}
+
+	if err := h.db.WithContext(ctx).Create(&message).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
 		return
 	}
 
 	// Load sender information
-	h.db.Preload("Sender").First(&message, message.ID)
+	h.db.WithContext(ctx).Preload("Sender").Where("conversation_id = ?", message.ConversationID).First(&message, message.ID)
 
 	// Update conversation timestamp
-	h.db.Model(&models.Conversation{}).
+	h.db.WithContext(ctx).Model(&models.Conversation{}).
 		Where("id = ?", conversationID).
 		Update("updated_at", time.Now())
 
-	// Broadcast message via WebSocket
+	if message.HeldAt == nil {
+		h.deliverMessage(ctx, message, conversationSize+1)
+		if slices.Contains(contactKinds, "phone") {
+			h.postSafetyTip(ctx, uint(conversationID), userID.(uint))
+		}
+	}
+
+	// Return the created message
+	messageResponse := MessageResponse{
+		ID:             message.ID,
+		SenderID:       message.SenderID,
+		Content:        message.Content,
+		MessageType:    message.MessageType,
+		IsRead:         message.IsRead,
+		ReadAt:         message.ReadAt,
+		CreatedAt:      message.CreatedAt,
+		Sender:         newPublicUser(message.Sender, loadViewer(h.db.WithContext(ctx), userID)),
+		HasContactInfo: message.HasContactInfo,
+		IsEncrypted:    message.IsEncrypted,
+	}
+
+	response := gin.H{"message": messageResponse}
+	if message.HeldAt != nil {
+		response["held_for_review"] = true
+	}
+	if len(contactKinds) > 0 && h.cfg.HasContactGuardMode("warn") {
+		response["warning"] = gin.H{
+			"type":    "contact_info",
+			"kinds":   contactKinds,
+			"message": contactGuardWarning,
+		}
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// deliverMessage tells the conversation about a new message, as the
+// recipient should see it, and notifies the recipient. Encrypted messages
+// only announce themselves.
+func (h *MessageHandler) deliverMessage(ctx context.Context, message models.Message, conversationSize int64) {
+	recipientContent := h.guardedContent(message, 0, conversationSize)
+	if message.IsEncrypted {
+		recipientContent = ""
+	}
 	messageData := websocket.Message{
 		Type:           "message",
-		ConversationID: uint(conversationID),
-		SenderID:       userID.(uint),
-		Content:        req.Content,
-		MessageType:    req.MessageType,
+		MessageID:      message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Content:        recipientContent,
+		MessageType:    message.MessageType,
+		Encrypted:      message.IsEncrypted,
 		Timestamp:      message.CreatedAt.Format(time.RFC3339),
 	}
 
 	if messageBytes, err := json.Marshal(messageData); err == nil {
-		h.hub.BroadcastToConversation(uint(conversationID), messageBytes)
+		recipientID := h.conversationRecipient(ctx, message.ConversationID, message.SenderID)
+		h.hub.DeliverToConversation(message.ConversationID, []uint{message.SenderID, recipientID}, messageBytes)
 	}
 
-	// Create notification for the other user
-	h.createMessageNotification(uint(conversationID), userID.(uint), req.Content)
+	h.notifySync(ctx, message.ConversationID)
 
-	// Return the created message
-	messageResponse := MessageResponse{
-		ID:          message.ID,
-		SenderID:    message.SenderID,
-		Content:     message.Content,
-		MessageType: message.MessageType,
-		IsRead:      message.IsRead,
-		ReadAt:      message.ReadAt,
-		CreatedAt:   message.CreatedAt,
-		Sender:      message.Sender,
+	// Create notification for the other user
+	notificationBody := recipientContent
+	if message.IsEncrypted {
+		notificationBody = "Encrypted message"
 	}
+	h.createMessageNotification(ctx, message.ConversationID, message.SenderID, notificationBody)
+}
 
-	c.JSON(http.StatusCreated, gin.H{"message": messageResponse})
+// postSafetyTip posts the safety tip the first time a phone number comes up
+// in a conversation
+func (h *MessageHandler) postSafetyTip(ctx context.Context, conversationID, senderID uint) {
+	result := h.db.WithContext(ctx).Model(&models.Conversation{}).
+		Where("id = ? AND safety_tip_sent_at IS NULL", conversationID).
+		Update("safety_tip_sent_at", time.Now())
+	if result.Error == nil && result.RowsAffected > 0 {
+		h.createSystemMessage(h.db.WithContext(ctx), conversationID, senderID, services.SystemSafetyTip, nil)
+	}
 }
 
 func (h *MessageHandler) MarkAsRead(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
 	if err != nil {
@@ -258,14 +401,14 @@ func (h *MessageHandler) MarkAsRead(c *gin.Context) {
 	}
 
 	// Verify user has access to this conversation
-	if !h.userHasAccessToConversation(userID.(uint), uint(conversationID)) {
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
 		return
 	}
 
 	// Mark all messages in this conversation as read
-	if err := h.db.Model(&models.Message{}).
-		Where("conversation_id = ? AND sender_id != ? AND is_read = ?",
+	if err := h.db.WithContext(ctx).Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id != ? AND is_read = ? AND held_at IS NULL",
 			conversationID, userID, false).
 		Updates(map[string]interface{}{
 			"is_read": true,
@@ -274,32 +417,41 @@ func (h *MessageHandler) MarkAsRead(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages as read"})
 		return
 	}
+	h.notifySync(ctx, uint(conversationID))
 
 	c.JSON(http.StatusOK, gin.H{"message": "Messages marked as read"})
 }
 
 // Helper methods
-func (h *MessageHandler) userHasAccessToConversation(userID, conversationID uint) bool {
-	// Check if user is part of the match that owns this conversation
+func (h *MessageHandler) userHasAccessToConversation(ctx context.Context, userID, conversationID uint) bool {
+	return hasConversationAccess(ctx, h.db, h.redis, userID, conversationID)
+}
+
+// conversationEncrypted reports whether the conversation is end-to-end
+// encrypted
+func (h *MessageHandler) conversationEncrypted(ctx context.Context, conversationID uint) bool {
 	var count int64
-	h.db.Table("conversations").
-		Joins("JOIN matches ON conversations.match_id = matches.id").
-		Where("conversations.id = ? AND (matches.user1_id = ? OR matches.user2_id = ?) AND conversations.is_active = ?",
-			conversationID, userID, userID, true).
+	h.db.WithContext(ctx).Model(&models.Conversation{}).
+		Where("id = ? AND encrypted_since IS NOT NULL", conversationID).
 		Count(&count)
-
 	return count > 0
 }
 
-func (h *MessageHandler) createMessageNotification(conversationID, senderID uint, content string) {
-	// Get the other user in the conversation
-	var otherUserID uint
-	h.db.Table("conversations").
-		Joins("JOIN matches ON conversations.match_id = matches.id").
-		Select("CASE WHEN matches.user1_id = ? THEN matches.user2_id ELSE matches.user1_id END", senderID).
-		Where("conversations.id = ?", conversationID).
-		Scan(&otherUserID)
+// conversationRecipient returns the other user in the conversation, or 0 if
+// it doesn't exist or was closed
+func (h *MessageHandler) conversationRecipient(ctx context.Context, conversationID, senderID uint) uint {
+	user1, user2, ok := conversationMembers(ctx, h.db, h.redis, conversationID)
+	if !ok {
+		return 0
+	}
+	if user1 == senderID {
+		return user2
+	}
+	return user1
+}
 
+func (h *MessageHandler) createMessageNotification(ctx context.Context, conversationID, senderID uint, content string) {
+	otherUserID := h.conversationRecipient(ctx, conversationID, senderID)
 	if otherUserID == 0 {
 		return
 	}
@@ -313,8 +465,10 @@ func (h *MessageHandler) createMessageNotification(conversationID, senderID uint
 		Data:   `{"conversation_id": ` + strconv.FormatUint(uint64(conversationID), 10) + `}`,
 	}
 
-	h.db.Create(&notification)
+	if err := h.db.WithContext(ctx).Create(&notification).Error; err != nil {
+		return
+	}
 
-	// TODO: Send push notification
-	// h.sendPushNotification(otherUserID, notification.Title, notification.Body, notification.Data)
+	// Message content stays in the app; other channels only hear that one arrived
+	h.notifier.Dispatch(services.Notice{Notification: notification, Text: "You have a new message. Open the app to reply."})
 }
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Report severities, which set how soon a report must be closed
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// highSeverityWeight is the strike weight from which a report reason is
+// urgent, such as scams and threats
+const highSeverityWeight = 4
+
+// ModeratorThroughput is how many reports one admin closed and how fast
+type ModeratorThroughput struct {
+	AdminID        uint    `json:"admin_id"`
+	Name           string  `json:"name"`
+	Closed         int64   `json:"closed"`
+	WithinSLA      int64   `json:"within_sla"`
+	AverageMinutes float64 `json:"average_minutes"` // from filing to closing
+}
+
+// reportSeverity ranks a report by its reason: suspected minors and reasons
+// weighted like scams or worse are high, other reasons that cost more than
+// the minimum strike medium, and the rest low
+func reportSeverity(cfg *config.Config, reason string) string {
+	if reason == underageReportReason {
+		return SeverityHigh
+	}
+	switch weight := cfg.StrikeWeight(reason); {
+	case weight >= highSeverityWeight:
+		return SeverityHigh
+	case weight > 1:
+		return SeverityMedium
+	}
+	return SeverityLow
+}
+
+// setReportSLA ranks a new report and starts its SLA timer
+func setReportSLA(cfg *config.Config, report *models.Report, now time.Time) {
+	report.Severity = reportSeverity(cfg, report.Reason)
+	due := now.Add(cfg.ReportSLA(report.Severity))
+	report.SLADueAt = &due
+}
+
+// reportClosed reports whether a status takes a report off the queue
+func reportClosed(status string) bool {
+	return status == "resolved" || status == "dismissed"
+}
+
+// reportClosure is the columns to set when a report moves to status: who
+// closed it and when, cleared again if it is reopened
+func reportClosure(c *gin.Context, status string) map[string]interface{} {
+	if !reportClosed(status) {
+		return map[string]interface{}{"status": status, "closed_by": nil, "closed_at": nil}
+	}
+	adminID, _ := c.Get("user_id")
+	return map[string]interface{}{"status": status, "closed_by": adminID, "closed_at": time.Now()}
+}
+
+// reportClaimedByOther reports whether another moderator has claimed the
+// report. Super admins may act on any report.
+func reportClaimedByOther(c *gin.Context, report models.Report) bool {
+	if report.AssignedTo == nil {
+		return false
+	}
+	value, _ := c.Get("admin")
+	if admin, ok := value.(models.Admin); ok && (admin.ID == *report.AssignedTo || admin.Role == "super_admin") {
+		return false
+	}
+	return true
+}
+
+// ClaimReport assigns an open report to the calling admin, so other
+// moderators leave it to them
+func (h *AdminHandler) ClaimReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	var report models.Report
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", reportID).First(&report).Error; err != nil {
+			return err
+		}
+		if reportClosed(report.Status) {
+			return errReportClosed
+		}
+		now := time.Now()
+		result := tx.Model(&report).
+			Where("assigned_to IS NULL OR assigned_to = ?", adminID).
+			Updates(map[string]interface{}{"assigned_to": adminID, "assigned_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReportClaimed
+		}
+		return h.logAdminAction(tx, c, "report_claimed", "report", report.ID, "")
+	})
+	if !h.respondReportQueueError(c, err, "Failed to claim report") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// ReleaseReport puts a claimed report back in the queue for anyone to take.
+// Only the moderator holding it or a super admin can release it.
+func (h *AdminHandler) ReleaseReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	var report models.Report
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", reportID).First(&report).Error; err != nil {
+			return err
+		}
+		if reportClaimedByOther(c, report) {
+			return errReportClaimed
+		}
+		if report.AssignedTo == nil {
+			return nil
+		}
+		if err := tx.Model(&report).Updates(map[string]interface{}{"assigned_to": nil, "assigned_at": nil}).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "report_released", "report", report.ID, "")
+	})
+	if !h.respondReportQueueError(c, err, "Failed to release report") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+var (
+	errReportClaimed = errors.New("report claimed by another moderator")
+	errReportClosed  = errors.New("report already closed")
+)
+
+// respondReportQueueError answers a failed claim or release and reports
+// whether there was no error to answer
+func (h *AdminHandler) respondReportQueueError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+	case errors.Is(err, errReportClaimed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Another moderator is handling this report", "code": "REPORT_CLAIMED"})
+	case errors.Is(err, errReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "This report is already closed"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return false
+}
+
+// moderatorThroughput counts the reports each admin closed since the time,
+// busiest first
+func moderatorThroughput(db *gorm.DB, since time.Time) []ModeratorThroughput {
+	throughput := []ModeratorThroughput{}
+	db.Model(&models.Report{}).
+		Select("reports.closed_by AS admin_id, admins.first_name || ' ' || admins.last_name AS name, "+
+			"COUNT(*) AS closed, "+
+			"COUNT(*) FILTER (WHERE reports.sla_due_at IS NULL OR reports.closed_at <= reports.sla_due_at) AS within_sla, "+
+			"COALESCE(AVG(EXTRACT(EPOCH FROM reports.closed_at - reports.created_at) / 60), 0) AS average_minutes").
+		Joins("JOIN admins ON admins.id = reports.closed_by").
+		Where("reports.closed_at >= ?", since).
+		Group("reports.closed_by, admins.first_name, admins.last_name").
+		Order("closed DESC").
+		Scan(&throughput)
+	return throughput
+}
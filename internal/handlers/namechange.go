@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type NameChangeDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Reason   string `json:"reason,omitempty" binding:"max=500"`
+}
+
+// PendingNameChange pairs a name change with the account making it
+type PendingNameChange struct {
+	Request models.NameChangeRequest `json:"request"`
+	User    PendingPhotoUser         `json:"user"`
+}
+
+func validateDisplayNames(firstName, lastName string) error {
+	if err := utils.ValidateDisplayName(firstName); err != nil {
+		return fmt.Errorf("first_name: %w", err)
+	}
+	if err := utils.ValidateDisplayName(lastName); err != nil {
+		return fmt.Errorf("last_name: %w", err)
+	}
+	return nil
+}
+
+// prepareNameChange applies a requested name to user when it passes the
+// lists. A flagged name is returned as a pending review instead and the user
+// keeps their current name, so nobody can rebrand mid-conversation as
+// "Support" or worse.
+func (h *UserHandler) prepareNameChange(user *models.User, firstName, lastName string) (*models.NameChangeRequest, error) {
+	if firstName == "" && lastName == "" {
+		return nil, nil
+	}
+
+	first, last := user.FirstName, user.LastName
+	if firstName != "" {
+		first = strings.TrimSpace(firstName)
+	}
+	if lastName != "" {
+		last = strings.TrimSpace(lastName)
+	}
+	if err := validateDisplayNames(first, last); err != nil {
+		return nil, err
+	}
+	if first == user.FirstName && last == user.LastName {
+		return nil, nil
+	}
+
+	flag := utils.DisplayNameFlag(first + " " + last)
+	if flag == "" {
+		user.FirstName = first
+		user.LastName = last
+		return nil, nil
+	}
+
+	return &models.NameChangeRequest{
+		UserID:            user.ID,
+		FirstName:         first,
+		LastName:          last,
+		PreviousFirstName: user.FirstName,
+		PreviousLastName:  user.LastName,
+		Flag:              flag,
+		Status:            "pending",
+	}, nil
+}
+
+// replaceNameChange drops the user's pending name change, since a newer name
+// supersedes it, and queues change when one is given
+func replaceNameChange(tx *gorm.DB, userID uint, change *models.NameChangeRequest) error {
+	if err := tx.Where("user_id = ? AND status = ?", userID, "pending").Delete(&models.NameChangeRequest{}).Error; err != nil {
+		return err
+	}
+	if change == nil {
+		return nil
+	}
+	return tx.Create(change).Error
+}
+
+// GetPendingNameChanges lists flagged name changes awaiting review, oldest first
+func (h *AdminHandler) GetPendingNameChanges(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.NameChangeRequest{}).Where("status = ?", "pending")
+	if flag := c.Query("flag"); flag != "" {
+		query = query.Where("flag = ?", flag)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var requests []models.NameChangeRequest
+	if err := query.Order("created_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch name changes"})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(requests))
+	for _, request := range requests {
+		userIDs = append(userIDs, request.UserID)
+	}
+
+	var users []models.User
+	if len(userIDs) > 0 {
+		h.db.WithContext(ctx).Where("id IN ?", userIDs).Find(&users)
+	}
+	byID := make(map[uint]PendingPhotoUser, len(users))
+	for _, user := range users {
+		info := PendingPhotoUser{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Gender:    user.Gender,
+			IsActive:  user.IsActive,
+			CreatedAt: user.CreatedAt,
+		}
+		h.db.WithContext(ctx).Model(&models.Report{}).Where("reported_id = ? AND status = ?", user.ID, "pending").Count(&info.OpenReports)
+		byID[user.ID] = info
+	}
+
+	pending := make([]PendingNameChange, 0, len(requests))
+	for _, request := range requests {
+		pending = append(pending, PendingNameChange{Request: request, User: byID[request.UserID]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name_changes": pending,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// DecideNameChange approves or rejects a flagged name change. Approving
+// replaces the displayed name; either way the user is told the outcome.
+func (h *AdminHandler) DecideNameChange(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	requestID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid name change ID"})
+		return
+	}
+
+	var req NameChangeDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Decision == "reject" && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required when rejecting a name"})
+		return
+	}
+
+	var request models.NameChangeRequest
+	if err := h.db.WithContext(ctx).Where("id = ? AND status = ?", requestID, "pending").First(&request).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending name change not found"})
+		return
+	}
+
+	now := time.Now()
+	moderator := adminID.(uint)
+	request.ReviewedBy = &moderator
+	request.ReviewedAt = &now
+	if req.Reason != "" {
+		request.ReviewNote = &req.Reason
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		notification := models.Notification{
+			UserID: request.UserID,
+			Type:   "name_change_rejected",
+			Title:  "Name change declined",
+			Body:   fmt.Sprintf("Your new name wasn't approved: %s", req.Reason),
+			Data:   `{"name_change_id": ` + strconv.FormatUint(uint64(request.ID), 10) + `}`,
+		}
+
+		if req.Decision == "approve" {
+			var user models.User
+			if err := tx.Where("id = ?", request.UserID).First(&user).Error; err != nil {
+				return err
+			}
+			user.FirstName = request.FirstName
+			user.LastName = request.LastName
+			if err := saveUserVersioned(tx, &user, nil); err != nil {
+				return err
+			}
+			request.Status = "approved"
+			notification.Type = "name_change_approved"
+			notification.Title = "Name change approved"
+			notification.Body = fmt.Sprintf("Your profile now shows %s %s", request.FirstName, request.LastName)
+		} else {
+			request.Status = "rejected"
+		}
+
+		if err := tx.Save(&request).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "name_change_"+request.Status, "user", request.UserID, req.Reason)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"name_change": request})
+}
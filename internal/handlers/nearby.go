@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNearbyRadiusKm = 10
+	minNearbyRadiusKm     = 1
+	maxNearbyRadiusKm     = 50
+)
+
+// GetActiveNearby lists verified users who were active in the last 15
+// minutes within radius_km of the caller's saved location, nearest first.
+// Both the radius and the order go by rounded distance, so the feed never
+// tells more about where someone is than the distance shown on their
+// profile. Like the online badge, this is reciprocal: users hiding their
+// online status neither appear here nor can see the feed.
+func (h *UserHandler) GetActiveNearby(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	radius, err := strconv.ParseFloat(c.DefaultQuery("radius_km", strconv.Itoa(defaultNearbyRadiusKm)), 64)
+	if err != nil || radius < minNearbyRadiusKm || radius > maxNearbyRadiusKm {
+		radius = defaultNearbyRadiusKm
+	}
+	radius = utils.RoundDistance(radius)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	var viewer models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&viewer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if viewer.HideOnline {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Show your online status to see who's active nearby"})
+		return
+	}
+
+	if !hasCoordinates(&viewer) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Share your location to see who's active nearby"})
+		return
+	}
+
+	// Search past the radius so whether someone is in it depends only on
+	// their rounded distance, and order by that rather than the exact one
+	nearby, err := h.redis.ActiveNearby(ctx, *viewer.Latitude, *viewer.Longitude, radius*1.5+1, limit*2)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find active users"})
+		return
+	}
+	for i := range nearby {
+		nearby[i].DistanceKm = utils.RoundDistance(nearby[i].DistanceKm)
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		if nearby[i].DistanceKm != nearby[j].DistanceKm {
+			return nearby[i].DistanceKm < nearby[j].DistanceKm
+		}
+		return nearby[i].UserID < nearby[j].UserID
+	})
+
+	ids := make([]uint, 0, len(nearby))
+	for _, user := range nearby {
+		if user.DistanceKm <= radius {
+			ids = append(ids, user.UserID)
+		}
+	}
+
+	var users []models.User
+	if len(ids) > 0 {
+		if err := buildDiscoverQuery(h.db.WithContext(ctx), h.cfg, viewer.ID, DiscoverFilters{}).
+			Where("id IN ? AND hide_online = ?", ids, false).
+			Preload("ProfilePhotos").Preload("Interests").Preload("MessageStats").
+			Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+	}
+
+	// Keep the nearest-first order
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	ordered := make([]models.User, 0, len(users))
+	for _, id := range ids {
+		if user, ok := byID[id]; ok && len(ordered) < limit {
+			ordered = append(ordered, user)
+		}
+	}
+
+	h.recordProfileViews(ctx, ordered)
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":     newPublicUsers(ordered, &viewer),
+		"radius_km": radius,
+	})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationPreferencesRequest struct {
+	Matches      *bool `json:"matches,omitempty"`
+	Messages     *bool `json:"messages,omitempty"`
+	Likes        *bool `json:"likes,omitempty"`
+	Milestones   *bool `json:"milestones,omitempty"`
+	WeeklyDigest *bool `json:"weekly_digest,omitempty"`
+	Marketing    *bool `json:"marketing,omitempty"`
+	SMS          *bool `json:"sms,omitempty"`
+}
+
+type PushTokenRequest struct {
+	Token    string `json:"token" binding:"required,max=512"`
+	Platform string `json:"platform" binding:"required,oneof=android ios web"`
+}
+
+// loadNotificationPreferences returns the user's preferences, defaulting to
+// everything on when none were saved
+func loadNotificationPreferences(db *gorm.DB, userID uint) models.NotificationPreference {
+	prefs := models.DefaultNotificationPreferences(userID)
+	db.Where("user_id = ?", userID).First(&prefs)
+	return prefs
+}
+
+func (h *UserHandler) GetNotificationPreferences(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	c.JSON(http.StatusOK, gin.H{"preferences": loadNotificationPreferences(h.db.WithContext(c.Request.Context()), userID.(uint))})
+}
+
+func (h *UserHandler) UpdateNotificationPreferences(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs := loadNotificationPreferences(h.db.WithContext(ctx), userID.(uint))
+	if req.Matches != nil {
+		prefs.Matches = *req.Matches
+	}
+	if req.Messages != nil {
+		prefs.Messages = *req.Messages
+	}
+	if req.Likes != nil {
+		prefs.Likes = *req.Likes
+	}
+	if req.Milestones != nil {
+		prefs.Milestones = *req.Milestones
+	}
+	if req.WeeklyDigest != nil {
+		prefs.WeeklyDigest = *req.WeeklyDigest
+	}
+	if req.Marketing != nil {
+		prefs.Marketing = *req.Marketing
+	}
+	if req.SMS != nil {
+		prefs.SMS = *req.SMS
+	}
+
+	// Select all columns so false values are written too
+	if err := h.db.WithContext(ctx).Select("*").Save(&prefs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// RegisterPushToken registers the caller's device for push notifications. A
+// token registered by another account moves to the caller, since the device
+// changed hands or signed in again.
+func (h *UserHandler) RegisterPushToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req PushTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token := models.PushToken{UserID: userID.(uint), Token: req.Token, Platform: req.Platform}
+	if err := h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "updated_at"}),
+	}).Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register push token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Push token registered"})
+}
+
+// DeletePushToken stops push notifications to a device, e.g. on sign out
+func (h *UserHandler) DeletePushToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.WithContext(ctx).Where("user_id = ? AND token = ?", userID, req.Token).Delete(&models.PushToken{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove push token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Push token removed"})
+}
+
+// GetNotificationDeliveries lists a user's recent delivery attempts outside
+// the app, newest first, to answer "I never got the notification"
+func (h *AdminHandler) GetNotificationDeliveries(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	var tokens int64
+	var telegram int64
+	h.db.WithContext(ctx).Model(&models.PushToken{}).Where("user_id = ?", userID).Count(&tokens)
+	h.db.WithContext(ctx).Model(&models.TelegramLink{}).Where("user_id = ?", userID).Count(&telegram)
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"channels":   h.cfg.NotificationChannels,
+		"reachable": gin.H{
+			"push_devices": tokens,
+			"telegram":     telegram > 0,
+		},
+		"page":  page,
+		"limit": limit,
+	})
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOnboarding tells the app which onboarding step to show the caller and
+// how far along they are
+func (h *UserHandler) GetOnboarding(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	onboarding, err := services.GetOnboarding(h.db.WithContext(ctx), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch onboarding"})
+		return
+	}
+
+	c.JSON(http.StatusOK, onboarding)
+}
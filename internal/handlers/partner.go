@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	partnerStatsCacheKey = "partner:stats"
+	partnerStatsCacheTTL = time.Hour
+	// Cities with fewer users than this are left out of partner stats, so
+	// small towns can't be used to single anyone out
+	partnerStatsMinCount = 20
+)
+
+// PartnerHandler serves the partner API, authenticated by API key rather
+// than a user session
+type PartnerHandler struct {
+	db    *gorm.DB
+	redis *redis.Client
+	cfg   *config.Config
+}
+
+func NewPartnerHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config) *PartnerHandler {
+	return &PartnerHandler{
+		db:    db,
+		redis: redis,
+		cfg:   cfg,
+	}
+}
+
+type CreateEventRequest struct {
+	Title       string     `json:"title" binding:"required,max=150"`
+	Description string     `json:"description,omitempty" binding:"max=2000"`
+	City        string     `json:"city" binding:"required,max=100"`
+	Venue       string     `json:"venue,omitempty" binding:"max=200"`
+	URL         string     `json:"url,omitempty" binding:"omitempty,url,max=500"`
+	StartsAt    time.Time  `json:"starts_at" binding:"required"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+}
+
+// PartnerStats are app-wide aggregates; no figure describes fewer than
+// partnerStatsMinCount users
+type PartnerStats struct {
+	ActiveUsers       int64             `json:"active_users"` // seen in the last 30 days
+	NewUsers          int64             `json:"new_users"`    // joined in the last 30 days
+	MatchesLast30Days int64             `json:"matches_last_30_days"`
+	Cities            []PartnerCityStat `json:"cities"`
+	GeneratedAt       time.Time         `json:"generated_at"`
+}
+
+type PartnerCityStat struct {
+	City        string `json:"city"`
+	ActiveUsers int64  `json:"active_users"`
+}
+
+func partnerKey(c *gin.Context) models.APIKey {
+	value, _ := c.Get("api_key")
+	key, _ := value.(models.APIKey)
+	return key
+}
+
+// CreateEvent publishes an event under the partner's name
+func (h *PartnerHandler) CreateEvent(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := partnerKey(c)
+
+	var req CreateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.StartsAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "starts_at must be in the future"})
+		return
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(req.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+
+	event := models.Event{
+		APIKeyID:    key.ID,
+		Organizer:   key.Partner,
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		City:        strings.TrimSpace(req.City),
+		Venue:       strings.TrimSpace(req.Venue),
+		URL:         req.URL,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+	}
+	if err := h.db.WithContext(ctx).Create(&event).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"event": event})
+}
+
+// GetEvents lists the events published with the calling key, upcoming first
+func (h *PartnerHandler) GetEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := partnerKey(c)
+
+	var events []models.Event
+	if err := h.db.WithContext(ctx).Where("api_key_id = ?", key.ID).
+		Order("starts_at DESC").Limit(100).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// DeleteEvent cancels one of the partner's own events
+func (h *PartnerHandler) DeleteEvent(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := partnerKey(c)
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	result := h.db.WithContext(ctx).Where("id = ? AND api_key_id = ?", eventID, key.ID).Delete(&models.Event{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event deleted"})
+}
+
+// GetStats returns aggregate app stats, refreshed hourly
+func (h *PartnerHandler) GetStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if cached, err := h.redis.Get(ctx, partnerStatsCacheKey); err == nil {
+		var stats PartnerStats
+		if json.Unmarshal([]byte(cached), &stats) == nil {
+			c.JSON(http.StatusOK, gin.H{"stats": stats})
+			return
+		}
+	}
+
+	db := h.db.WithContext(ctx)
+	since := time.Now().AddDate(0, 0, -30)
+	stats := PartnerStats{Cities: []PartnerCityStat{}, GeneratedAt: time.Now()}
+
+	db.Model(&models.User{}).Where("is_active = ? AND last_seen >= ?", true, since).Count(&stats.ActiveUsers)
+	db.Model(&models.User{}).Where("created_at >= ?", since).Count(&stats.NewUsers)
+	db.Model(&models.Match{}).Where("created_at >= ?", since).Count(&stats.MatchesLast30Days)
+	if err := db.Model(&models.User{}).
+		Select("MIN(TRIM(location)) AS city, COUNT(*) AS active_users").
+		Where("is_active = ? AND last_seen >= ? AND location IS NOT NULL AND TRIM(location) <> ''", true, since).
+		Group("LOWER(TRIM(location))").
+		Having("COUNT(*) >= ?", partnerStatsMinCount).
+		Order("active_users DESC").
+		Scan(&stats.Cities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute stats"})
+		return
+	}
+
+	if encoded, err := json.Marshal(stats); err == nil {
+		h.redis.Set(ctx, partnerStatsCacheKey, encoded, partnerStatsCacheTTL)
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// GetEvents lists upcoming partner events, in the caller's city unless
+// another city is asked for
+func (h *UserHandler) GetEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	city := c.Query("city")
+	if city == "" {
+		var user models.User
+		if err := h.db.WithContext(ctx).Select("id", "location").Where("id = ?", userID).First(&user).Error; err == nil {
+			city = userCity(&user)
+		}
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.Event{}).Where("starts_at > ?", time.Now())
+	if city != "" {
+		query = query.Where("LOWER(TRIM(city)) = LOWER(TRIM(?))", city)
+	}
+
+	var events []models.Event
+	if err := query.Order("starts_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "city": city, "page": page, "limit": limit})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PauseAccountRequest struct {
+	Paused bool       `json:"paused"`
+	Until  *time.Time `json:"until,omitempty"` // resume automatically at this time
+	// Version the client last saw; may also be sent as an If-Match header
+	Version *int `json:"version,omitempty"`
+}
+
+// PauseAccount hides the user from discovery and new likes without touching
+// existing matches and conversations. Unlike deactivation, the user can keep
+// chatting while paused.
+func (h *UserHandler) PauseAccount(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req PauseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Paused && req.Until != nil && !req.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	user.IsPaused = req.Paused
+	user.PausedUntil = nil
+	if req.Paused {
+		user.PausedUntil = req.Until
+	}
+
+	err := saveUserVersioned(h.db.WithContext(ctx), &user, expectedVersion(c, req.Version))
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, h.db.WithContext(ctx), userID)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pause setting"})
+		return
+	}
+
+	setVersionETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"paused":       user.IsPaused,
+		"paused_until": user.PausedUntil,
+		"version":      user.Version,
+	})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PhotoDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Reason   string `json:"reason,omitempty" binding:"max=500"`
+}
+
+type PendingPhoto struct {
+	Photo models.ProfilePhoto `json:"photo"`
+	User  PendingPhotoUser    `json:"user"`
+}
+
+// PendingPhotoUser gives moderators enough context to judge a photo
+type PendingPhotoUser struct {
+	ID             uint      `json:"id"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Gender         string    `json:"gender"`
+	IsActive       bool      `json:"is_active"`
+	CreatedAt      time.Time `json:"created_at"`
+	ApprovedPhotos int64     `json:"approved_photos"`
+	OpenReports    int64     `json:"open_reports"`
+}
+
+// GetPendingPhotos lists photos awaiting review, oldest first
+func (h *AdminHandler) GetPendingPhotos(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.ProfilePhoto{}).Where("moderation_status = ?", "pending")
+	if source := c.Query("source"); source != "" {
+		query = query.Where("flag_source = ?", source)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var photos []models.ProfilePhoto
+	if err := query.Order("created_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&photos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch photos"})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(photos))
+	for _, photo := range photos {
+		userIDs = append(userIDs, photo.UserID)
+	}
+
+	var users []models.User
+	if len(userIDs) > 0 {
+		h.db.WithContext(ctx).Where("id IN ?", userIDs).Find(&users)
+	}
+	byID := make(map[uint]PendingPhotoUser, len(users))
+	for _, user := range users {
+		info := PendingPhotoUser{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Gender:    user.Gender,
+			IsActive:  user.IsActive,
+			CreatedAt: user.CreatedAt,
+		}
+		h.db.WithContext(ctx).Model(&models.ProfilePhoto{}).Where("user_id = ? AND moderation_status = ?", user.ID, "approved").Count(&info.ApprovedPhotos)
+		h.db.WithContext(ctx).Model(&models.Report{}).Where("reported_id = ? AND status = ?", user.ID, "pending").Count(&info.OpenReports)
+		byID[user.ID] = info
+	}
+
+	pending := make([]PendingPhoto, 0, len(photos))
+	for _, photo := range photos {
+		pending = append(pending, PendingPhoto{Photo: photo, User: byID[photo.UserID]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"photos": pending,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// DecidePhoto approves or rejects a pending photo. Rejected photos are removed
+// from storage and the owner is told why.
+func (h *AdminHandler) DecidePhoto(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	photoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+
+	var req PhotoDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Decision == "reject" && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required when rejecting a photo"})
+		return
+	}
+
+	var photo models.ProfilePhoto
+	if err := h.db.WithContext(ctx).Where("id = ? AND moderation_status = ?", photoID, "pending").First(&photo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending photo not found"})
+		return
+	}
+
+	now := time.Now()
+	moderator := adminID.(uint)
+	photo.ModeratedBy = &moderator
+	photo.ModeratedAt = &now
+	if req.Reason != "" {
+		photo.ModerationReason = &req.Reason
+	}
+
+	var strike models.Strike
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if req.Decision == "approve" {
+			photo.ModerationStatus = "approved"
+			if err := tx.Save(&photo).Error; err != nil {
+				return err
+			}
+			return h.logAdminAction(tx, c, "photo_approved", "photo", photo.ID, "")
+		}
+
+		photo.ModerationStatus = "rejected"
+		if err := tx.Save(&photo).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&photo).Error; err != nil {
+			return err
+		}
+
+		// Promote another photo if the rejected one was primary
+		if photo.IsPrimary {
+			var next models.ProfilePhoto
+			if err := tx.Where("user_id = ? AND moderation_status = ? AND is_private = ?", photo.UserID, "approved", false).
+				Order(`"order" ASC`).First(&next).Error; err == nil {
+				if err := tx.Model(&next).Update("is_primary", true).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		notification := models.Notification{
+			UserID: photo.UserID,
+			Type:   "photo_rejected",
+			Title:  "Photo removed",
+			Body:   fmt.Sprintf("One of your photos was removed: %s", req.Reason),
+			Data:   `{"photo_id": ` + strconv.FormatUint(uint64(photo.ID), 10) + `}`,
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		strike = models.Strike{
+			UserID:   photo.UserID,
+			Reason:   "inappropriate_photo",
+			Source:   "moderation",
+			SourceID: &photo.ID,
+			IssuedBy: &moderator,
+			Note:     &req.Reason,
+		}
+		if err := issueStrike(tx, h.cfg, &strike); err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "photo_rejected", "photo", photo.ID, req.Reason)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	if req.Decision == "reject" {
+		enforcePenalty(ctx, h.redis, strike)
+		if err := h.storage.DeleteFile(ctx, photo.URL); err != nil {
+			log.Printf("Failed to delete rejected photo %d from storage: %v", photo.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"photo": photo})
+}
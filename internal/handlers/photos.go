@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxPhotosPerUpload also sizes the body limit of the upload route
+const MaxPhotosPerUpload = 6
+
+const (
+	photoUploadWorkers = 3
+	photoLabelTimeout  = 30 * time.Second
+)
+
+// PhotoUploadRounds is how many uploads one after another UploadPhotos may
+// wait on, which sizes the route's timeout
+const PhotoUploadRounds = (MaxPhotosPerUpload + photoUploadWorkers - 1) / photoUploadWorkers
+
+// UploadPhotos stores several photos from one multipart request (field
+// "photos"). Every file is validated before any is stored, uploads run on a
+// small worker pool, and the rows are created in one transaction in the order
+// the files were sent. Nothing is kept if any step fails.
+func (h *UserHandler) UploadPhotos(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+
+	headers := form.File["photos"]
+	if len(headers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No photos provided"})
+		return
+	}
+	if len(headers) > MaxPhotosPerUpload {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("You can upload at most %d photos at once", MaxPhotosPerUpload)})
+		return
+	}
+
+	contentTypes := make([]string, len(headers))
+	for i, header := range headers {
+		contentTypes[i], err = validateImageFile(h.cfg, header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: %s", header.Filename, err.Error())})
+			return
+		}
+	}
+
+	urls := make([]string, len(headers))
+	errs := make([]error, len(headers))
+	slots := make(chan struct{}, photoUploadWorkers)
+	var wg sync.WaitGroup
+	for i := range headers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			file, err := headers[i].Open()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer file.Close()
+
+			filename := fmt.Sprintf("profile_photos/%d_%s%s", userID, uuid.New().String(), imageExtension(contentTypes[i]))
+			urls[i], errs[i] = h.storage.UploadFile(ctx, file, filename, contentTypes[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			h.discardUploads(ctx, urls)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload photos"})
+			return
+		}
+	}
+
+	photos := make([]models.ProfilePhoto, 0, len(urls))
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the user so concurrent uploads can't hand out the same positions
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&models.User{}).Error; err != nil {
+			return err
+		}
+
+		var photoCount int64
+		if err := tx.Model(&models.ProfilePhoto{}).Where("user_id = ?", userID).Count(&photoCount).Error; err != nil {
+			return err
+		}
+
+		for i, url := range urls {
+			photo := models.ProfilePhoto{
+				UserID:    userID.(uint),
+				URL:       url,
+				IsPrimary: photoCount == 0 && i == 0,
+				Order:     int(photoCount) + i,
+			}
+			if h.cfg.PhotoReviewRequired {
+				source := "upload"
+				photo.ModerationStatus = "pending"
+				photo.FlagSource = &source
+			}
+			photo.AltText = photoAltText(photo)
+			if err := tx.Create(&photo).Error; err != nil {
+				return err
+			}
+			photos = append(photos, photo)
+		}
+		return nil
+	})
+	if err != nil {
+		h.discardUploads(ctx, urls)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save photo records"})
+		return
+	}
+	h.labelPhotos(photos)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Photos uploaded successfully", "photos": photos})
+}
+
+// discardUploads removes stored files whose rows were never created
+func (h *UserHandler) discardUploads(ctx context.Context, urls []string) {
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		if err := h.storage.DeleteFile(ctx, url); err != nil {
+			log.Printf("Failed to delete orphaned upload %s: %v", url, err)
+		}
+	}
+}
+
+type PhotoCaptionRequest struct {
+	Caption string `json:"caption" binding:"max=200"`
+}
+
+// photoAltText describes a photo for screen readers: the owner's caption,
+// else what label detection saw, else a generic description
+func photoAltText(photo models.ProfilePhoto) string {
+	if photo.Caption != nil && *photo.Caption != "" {
+		return *photo.Caption
+	}
+	if len(photo.Tags) > 0 {
+		return "Photo that may show " + strings.Join(photo.Tags, ", ")
+	}
+	return "Profile photo"
+}
+
+// labelPhotos tags new photos in the background so a slow or missing label
+// service never holds up an upload
+func (h *UserHandler) labelPhotos(photos []models.ProfilePhoto) {
+	go func() {
+		for _, photo := range photos {
+			ctx, cancel := context.WithTimeout(context.Background(), photoLabelTimeout)
+			labels, err := h.labeler.Label(ctx, photo.URL)
+			if err != nil {
+				cancel()
+				if !errors.Is(err, services.ErrPhotoLabelerDisabled) {
+					log.Printf("Failed to label photo %d: %v", photo.ID, err)
+				}
+				continue
+			}
+
+			// Reload in case the owner captioned it meanwhile
+			if err := h.db.WithContext(ctx).Where("id = ?", photo.ID).First(&photo).Error; err != nil {
+				cancel()
+				continue
+			}
+			photo.Tags = labels.Tags
+			photo.AltText = photoAltText(photo)
+			photo.SuggestiveScore = &labels.Suggestive
+			photo.Suggestive = labels.Suggestive >= h.cfg.CleanModePhotoThreshold
+			if err := h.db.WithContext(ctx).Model(&photo).Select("tags", "alt_text", "suggestive_score", "suggestive").Updates(&photo).Error; err != nil {
+				log.Printf("Failed to save labels for photo %d: %v", photo.ID, err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// UpdatePhotoCaption sets or clears the caption on one of the caller's
+// photos. The caption becomes the photo's alt text.
+func (h *UserHandler) UpdatePhotoCaption(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req PhotoCaptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var photo models.ProfilePhoto
+	if err := h.db.WithContext(ctx).Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&photo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+
+	photo.Caption = nil
+	if caption := strings.TrimSpace(req.Caption); caption != "" {
+		photo.Caption = &caption
+	}
+	photo.AltText = photoAltText(photo)
+
+	if err := h.db.WithContext(ctx).Model(&photo).Updates(map[string]interface{}{
+		"caption":  photo.Caption,
+		"alt_text": photo.AltText,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update caption"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"photo": photo})
+}
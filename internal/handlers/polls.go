@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CreatePollRequest struct {
+	Kind     string   `json:"kind" binding:"omitempty,oneof=poll would_you_rather"`
+	Question string   `json:"question" binding:"required,max=200"`
+	Options  []string `json:"options" binding:"required,min=2,max=4,dive,required,max=100"`
+}
+
+type VotePollRequest struct {
+	OptionID uint `json:"option_id" binding:"required"`
+}
+
+type PollOptionResponse struct {
+	ID    uint   `json:"id"`
+	Text  string `json:"text"`
+	Votes int64  `json:"votes"`
+}
+
+type PollResponse struct {
+	ID       uint                 `json:"id"`
+	Kind     string               `json:"kind"`
+	Question string               `json:"question"`
+	Options  []PollOptionResponse `json:"options"`
+	MyVote   *uint                `json:"my_vote,omitempty"`
+}
+
+// pollVoteEvent is pushed to the conversation whenever someone votes
+type pollVoteEvent struct {
+	Type           string       `json:"type"`
+	ConversationID uint         `json:"conversation_id"`
+	UserID         uint         `json:"user_id"`
+	Poll           PollResponse `json:"poll"`
+}
+
+// CreatePoll sends a poll or "would you rather" question as a message
+func (h *MessageHandler) CreatePoll(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	conversationID, err := strconv.ParseUint(c.Param("conversation_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req CreatePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = "poll"
+	}
+	if req.Kind == "would_you_rather" && len(req.Options) != 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Would you rather questions need exactly 2 options"})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), uint(conversationID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+
+	if respondIfMuted(c, h.db.WithContext(ctx), userID) {
+		return
+	}
+
+	if respondIfUnverified(c, h.db.WithContext(ctx), h.cfg, userID, "messaging") {
+		return
+	}
+
+	if respondIfNotStarter(ctx, c, h.db, uint(conversationID), userID.(uint)) {
+		return
+	}
+
+	// Poll questions and options are stored in plaintext
+	if h.conversationEncrypted(ctx, uint(conversationID)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Polls aren't available in end-to-end encrypted chats"})
+		return
+	}
+
+	message := models.Message{
+		ConversationID: uint(conversationID),
+		SenderID:       userID.(uint),
+		Content:        strings.TrimSpace(req.Question),
+		MessageType:    "poll",
+	}
+	poll := models.Poll{
+		ConversationID: uint(conversationID),
+		CreatorID:      userID.(uint),
+		Kind:           req.Kind,
+		Question:       message.Content,
+	}
+	for i, option := range req.Options {
+		poll.Options = append(poll.Options, models.PollOption{Text: strings.TrimSpace(option), Position: i})
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		poll.MessageID = message.ID
+		if err := tx.Create(&poll).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Conversation{}).Where("id = ?", conversationID).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create poll"})
+		return
+	}
+
+	messageData := websocket.Message{
+		Type:           "message",
+		ConversationID: uint(conversationID),
+		SenderID:       userID.(uint),
+		Content:        message.Content,
+		MessageType:    "poll",
+		Timestamp:      message.CreatedAt.Format(time.RFC3339),
+	}
+	if messageBytes, err := json.Marshal(messageData); err == nil {
+		recipientID := h.conversationRecipient(ctx, uint(conversationID), userID.(uint))
+		h.hub.DeliverToConversation(uint(conversationID), []uint{userID.(uint), recipientID}, messageBytes)
+	}
+
+	h.createMessageNotification(ctx, uint(conversationID), userID.(uint), message.Content)
+
+	polls := h.loadPolls(ctx, []uint{message.ID}, userID.(uint))
+	c.JSON(http.StatusCreated, gin.H{"message": message, "poll": polls[message.ID]})
+}
+
+// VotePoll records or changes the caller's vote and pushes updated totals
+func (h *MessageHandler) VotePoll(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	pollID, err := strconv.ParseUint(c.Param("poll_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid poll ID"})
+		return
+	}
+
+	var req VotePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var poll models.Poll
+	if err := h.db.WithContext(ctx).Where("id = ?", pollID).First(&poll).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Poll not found"})
+		return
+	}
+
+	if !h.userHasAccessToConversation(ctx, userID.(uint), poll.ConversationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this conversation"})
+		return
+	}
+
+	var option models.PollOption
+	if err := h.db.WithContext(ctx).Where("id = ? AND poll_id = ?", req.OptionID, poll.ID).First(&option).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid option"})
+		return
+	}
+
+	vote := models.PollVote{PollID: poll.ID, UserID: userID.(uint)}
+	if err := h.db.WithContext(ctx).Where(vote).Assign(models.PollVote{OptionID: option.ID}).FirstOrCreate(&vote).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	// Totals are the same for everyone; only my_vote differs per viewer
+	result := h.loadPolls(ctx, []uint{poll.MessageID}, userID.(uint))[poll.MessageID]
+	event := pollVoteEvent{
+		Type:           "poll_vote",
+		ConversationID: poll.ConversationID,
+		UserID:         userID.(uint),
+		Poll:           *result,
+	}
+	event.Poll.MyVote = nil
+	if eventBytes, err := json.Marshal(event); err == nil {
+		h.hub.BroadcastToConversation(poll.ConversationID, eventBytes)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"poll": result})
+}
+
+// loadPolls returns the polls attached to the given messages, keyed by message ID
+func (h *MessageHandler) loadPolls(ctx context.Context, messageIDs []uint, viewerID uint) map[uint]*PollResponse {
+	polls := make(map[uint]*PollResponse)
+	if len(messageIDs) == 0 {
+		return polls
+	}
+
+	var rows []models.Poll
+	h.db.WithContext(ctx).Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Where("message_id IN ?", messageIDs).Find(&rows)
+	if len(rows) == 0 {
+		return polls
+	}
+
+	pollIDs := make([]uint, 0, len(rows))
+	for _, poll := range rows {
+		pollIDs = append(pollIDs, poll.ID)
+	}
+
+	var counts []struct {
+		OptionID uint
+		Count    int64
+	}
+	h.db.WithContext(ctx).Model(&models.PollVote{}).Select("option_id, COUNT(*) AS count").
+		Where("poll_id IN ?", pollIDs).Group("option_id").Scan(&counts)
+	votes := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		votes[count.OptionID] = count.Count
+	}
+
+	var mine []models.PollVote
+	h.db.WithContext(ctx).Where("poll_id IN ? AND user_id = ?", pollIDs, viewerID).Find(&mine)
+	myVotes := make(map[uint]uint, len(mine))
+	for _, vote := range mine {
+		myVotes[vote.PollID] = vote.OptionID
+	}
+
+	for _, poll := range rows {
+		resp := &PollResponse{ID: poll.ID, Kind: poll.Kind, Question: poll.Question}
+		for _, option := range poll.Options {
+			resp.Options = append(resp.Options, PollOptionResponse{ID: option.ID, Text: option.Text, Votes: votes[option.ID]})
+		}
+		if optionID, ok := myVotes[poll.ID]; ok {
+			resp.MyVote = &optionID
+		}
+		polls[poll.MessageID] = resp
+	}
+	return polls
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// privateAlbumAccessLimit is how many recent views of the caller's private
+// photos the access log lists
+const privateAlbumAccessLimit = 100
+
+// PhotoPrivacyRequest moves a photo into or out of the private album
+type PhotoPrivacyRequest struct {
+	IsPrivate *bool `json:"is_private" binding:"required"`
+}
+
+// PrivatePhoto is a private album photo with a link that expires
+type PrivatePhoto struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Caption   *string   `json:"caption,omitempty"`
+	AltText   string    `json:"alt_text"`
+	Order     int       `json:"order"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UpdatePhotoPrivacy moves one of the caller's photos into or out of their
+// private album. Private photos are left out of discovery and profiles, and
+// only matches given access can open them. The primary photo stays public.
+// The file is copied to a new key that is only readable when public, so
+// links to it handed out before stop working.
+func (h *UserHandler) UpdatePhotoPrivacy(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req PhotoPrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var photo models.ProfilePhoto
+	if err := h.db.WithContext(ctx).Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&photo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+	if *req.IsPrivate && photo.IsPrimary {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your primary photo can't be private"})
+		return
+	}
+
+	if photo.IsPrivate == *req.IsPrivate {
+		c.JSON(http.StatusOK, gin.H{"photo": photo})
+		return
+	}
+
+	prefix := "profile_photos"
+	if *req.IsPrivate {
+		prefix = "private_photos"
+	}
+	key := fmt.Sprintf("%s/%d_%s%s", prefix, photo.UserID, uuid.New().String(), path.Ext(photo.URL))
+	url, err := h.storage.CopyFile(ctx, photo.URL, key, !*req.IsPrivate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update photo"})
+		return
+	}
+
+	oldURL := photo.URL
+	photo.URL = url
+	photo.IsPrivate = *req.IsPrivate
+	if err := h.db.WithContext(ctx).Model(&photo).Updates(map[string]interface{}{
+		"url":        photo.URL,
+		"is_private": photo.IsPrivate,
+	}).Error; err != nil {
+		h.discardUploads(ctx, []string{url})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update photo"})
+		return
+	}
+	h.discardUploads(ctx, []string{oldURL})
+
+	c.JSON(http.StatusOK, gin.H{"photo": photo})
+}
+
+// albumMatch loads an active match of the caller's from the path, answering
+// when there isn't one. It returns the match and the other user's ID.
+func (h *MatchHandler) albumMatch(c *gin.Context, userID uint) (models.Match, uint, bool) {
+	var match models.Match
+	matchID, err := strconv.ParseUint(c.Param("match_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return match, 0, false
+	}
+
+	if err := h.db.WithContext(c.Request.Context()).
+		Where("id = ? AND (user1_id = ? OR user2_id = ?) AND is_active = ?", matchID, userID, userID, true).
+		First(&match).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return match, 0, false
+	}
+
+	otherID := match.User1ID
+	if otherID == userID {
+		otherID = match.User2ID
+	}
+	return match, otherID, true
+}
+
+// GrantPrivateAlbum lets the other user of a match see the caller's private
+// photos. Access ends when the caller revokes it or either of them unmatches.
+func (h *MatchHandler) GrantPrivateAlbum(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	match, otherID, ok := h.albumMatch(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var grant models.PrivateAlbumGrant
+	granted := false
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("match_id = ? AND owner_id = ?", match.ID, userID).First(&grant).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			grant = models.PrivateAlbumGrant{MatchID: match.ID, OwnerID: userID.(uint), ViewerID: otherID, GrantedAt: time.Now()}
+			granted = true
+			return tx.Create(&grant).Error
+		}
+		if err != nil || grant.RevokedAt == nil {
+			return err
+		}
+
+		grant.GrantedAt = time.Now()
+		grant.RevokedAt = nil
+		granted = true
+		return tx.Model(&grant).Updates(map[string]interface{}{"granted_at": grant.GrantedAt, "revoked_at": nil}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share private album"})
+		return
+	}
+
+	// Granting again while access stands doesn't notify again
+	if granted {
+		var owner models.User
+		h.db.WithContext(ctx).Select("id", "first_name").Where("id = ?", userID).First(&owner)
+		notification := models.Notification{
+			UserID: otherID,
+			Type:   "private_album_granted",
+			Title:  "Private photos shared",
+			Body:   owner.FirstName + " shared their private photos with you.",
+			Data:   `{"match_id": ` + strconv.FormatUint(uint64(match.ID), 10) + `}`,
+		}
+		if err := h.db.WithContext(ctx).Create(&notification).Error; err == nil {
+			h.notifier.Dispatch(services.Notice{Notification: notification, Text: notification.Body})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grant": grant})
+}
+
+// RevokePrivateAlbum takes back the other user's access to the caller's
+// private photos. Links already handed out stop working within
+// PRIVATE_PHOTO_URL_TTL.
+func (h *MatchHandler) RevokePrivateAlbum(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	match, _, ok := h.albumMatch(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	result := h.db.WithContext(ctx).Model(&models.PrivateAlbumGrant{}).
+		Where("match_id = ? AND owner_id = ? AND revoked_at IS NULL", match.ID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You haven't shared your private photos with this match"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access revoked"})
+}
+
+// GetPrivateAlbum hands the caller links to the private photos of the other
+// user of a match, when they have been given access. Links expire after
+// PRIVATE_PHOTO_URL_TTL, and each one handed out is logged for the owner.
+func (h *MatchHandler) GetPrivateAlbum(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	match, otherID, ok := h.albumMatch(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var sharedByMe int64
+	h.db.WithContext(ctx).Model(&models.PrivateAlbumGrant{}).
+		Where("match_id = ? AND owner_id = ? AND revoked_at IS NULL", match.ID, userID).Count(&sharedByMe)
+
+	var photos []models.ProfilePhoto
+	if err := h.db.WithContext(ctx).Where("user_id = ? AND is_private = ? AND moderation_status = ?", otherID, true, "approved").
+		Order(`"order" ASC`).Find(&photos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch private photos"})
+		return
+	}
+
+	var grants int64
+	h.db.WithContext(ctx).Model(&models.PrivateAlbumGrant{}).
+		Where("match_id = ? AND owner_id = ? AND revoked_at IS NULL", match.ID, otherID).Count(&grants)
+	if grants == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         "They haven't shared their private photos with you",
+			"code":          "PRIVATE_ALBUM_LOCKED",
+			"photo_count":   len(photos),
+			"shared_by_you": sharedByMe > 0,
+		})
+		return
+	}
+
+	expiresAt := time.Now().Add(h.cfg.PrivatePhotoURLTTL)
+	resp := make([]PrivatePhoto, 0, len(photos))
+	accesses := make([]models.PrivatePhotoAccess, 0, len(photos))
+	for _, photo := range photos {
+		url, err := h.storage.GeneratePresignedURL(ctx, h.storage.ObjectKey(photo.URL), h.cfg.PrivatePhotoURLTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open private photos"})
+			return
+		}
+		resp = append(resp, PrivatePhoto{
+			ID:        photo.ID,
+			URL:       url,
+			Caption:   photo.Caption,
+			AltText:   photo.AltText,
+			Order:     photo.Order,
+			ExpiresAt: expiresAt,
+		})
+		accesses = append(accesses, models.PrivatePhotoAccess{
+			PhotoID:  photo.ID,
+			OwnerID:  otherID,
+			ViewerID: userID.(uint),
+			MatchID:  match.ID,
+		})
+	}
+	if len(accesses) > 0 {
+		if err := h.db.WithContext(ctx).Create(&accesses).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open private photos"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"photos": resp, "shared_by_you": sharedByMe > 0})
+}
+
+// GetPrivateAlbumAccess shows the caller who can see their private photos,
+// whose access was revoked, and who was recently handed links to them
+func (h *MatchHandler) GetPrivateAlbumAccess(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var grants []models.PrivateAlbumGrant
+	if err := h.db.WithContext(ctx).Where("owner_id = ?", userID).Order("granted_at DESC").Find(&grants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album access"})
+		return
+	}
+
+	var accesses []models.PrivatePhotoAccess
+	if err := h.db.WithContext(ctx).Where("owner_id = ?", userID).
+		Order("created_at DESC").Limit(privateAlbumAccessLimit).Find(&accesses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album access"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grants": grants, "accesses": accesses})
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// PublicUserResponse is the view of a user shown to other users. Contact
+// details and raw coordinates are never included.
+type PublicUserResponse struct {
+	ID              uint                   `json:"id"`
+	Username        *string                `json:"username,omitempty"`
+	FirstName       string                 `json:"first_name"`
+	LastName        string                 `json:"last_name"`
+	Age             *int                   `json:"age,omitempty"`
+	Gender          string                 `json:"gender"`
+	Intent          string                 `json:"relationship_intent,omitempty"`
+	Bio             *string                `json:"bio,omitempty"`
+	BioBlurred      bool                   `json:"bio_blurred,omitempty"` // suggestive, for a viewer in clean mode blur
+	Location        *string                `json:"location,omitempty"`
+	IsVerified      bool                   `json:"is_verified"`
+	IDVerified      bool                   `json:"id_verified"`
+	PhotoVerified   bool                   `json:"photo_verified"`
+	Badges          []string               `json:"badges,omitempty"` // student, professional
+	IsNew           bool                   `json:"is_new,omitempty"`
+	IsOnline        *bool                  `json:"is_online,omitempty"`
+	LastActive      string                 `json:"last_active,omitempty"`
+	ProfilePhotos   []models.ProfilePhoto  `json:"profile_photos,omitempty"`
+	Prompts         []models.ProfilePrompt `json:"prompts,omitempty"`
+	Interests       []models.Interest      `json:"interests,omitempty"`
+	DistanceKm      *float64               `json:"distance_km,omitempty"`
+	DistanceDisplay string                 `json:"distance_display,omitempty"`
+	ResponseBadge   string                 `json:"response_badge,omitempty"`
+	MutualContacts  int                    `json:"mutual_contacts,omitempty"`
+}
+
+// What clean mode does with suggestive bios and photos of other users
+const (
+	cleanModeOff  = "off"
+	cleanModeBlur = "blur"
+	cleanModeHide = "hide"
+)
+
+// newPublicUser projects user as seen by viewer
+func newPublicUser(user models.User, viewer *models.User) PublicUserResponse {
+	resp := PublicUserResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Gender:        user.Gender,
+		Intent:        user.RelationshipIntent,
+		Bio:           user.Bio,
+		Location:      user.Location,
+		IsVerified:    user.IsVerified,
+		IDVerified:    user.IDVerified,
+		PhotoVerified: user.PhotoVerifiedAt != nil,
+		Badges:        profileBadges(&user),
+		IsNew:         isNewUser(user, time.Now()),
+		ProfilePhotos: publicPhotos(user.ProfilePhotos),
+		Prompts:       user.Prompts,
+		Interests:     user.Interests,
+	}
+
+	// Birthdates are never shown to other users; age can be hidden by premium users
+	if !(user.IsPremium && user.HideAge) {
+		age := utils.CalculateAge(user.DateOfBirth, time.Now())
+		resp.Age = &age
+	}
+
+	// Activity visibility is reciprocal: hiding your own status hides everyone else's from you
+	if viewer != nil && !user.HideOnline && !viewer.HideOnline {
+		isOnline := user.IsOnline
+		resp.IsOnline = &isOnline
+	}
+	if viewer != nil && !user.HideLastSeen && !viewer.HideLastSeen {
+		online := resp.IsOnline != nil && *resp.IsOnline
+		resp.LastActive = lastActiveBucket(user.LastSeen, online, time.Now())
+	}
+
+	resp.ResponseBadge = responseBadge(user.MessageStats)
+
+	if viewer != nil && viewer.ID != user.ID {
+		applyCleanMode(&resp, user, viewer.CleanMode)
+	}
+
+	// Approximate locations are only a city guess, too coarse for a distance
+	if viewer != nil && hasPreciseLocation(viewer) && hasPreciseLocation(&user) {
+		distance := utils.HaversineKm(*viewer.Latitude, *viewer.Longitude, *user.Latitude, *user.Longitude)
+		rounded := utils.RoundDistance(distance)
+		resp.DistanceKm = &rounded
+		resp.DistanceDisplay = utils.FormatDistance(distance, viewer.DistanceUnit)
+	}
+
+	return resp
+}
+
+func newPublicUsers(users []models.User, viewer *models.User) []PublicUserResponse {
+	resp := make([]PublicUserResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, newPublicUser(user, viewer))
+	}
+	return resp
+}
+
+// lastActiveBucket turns an exact last-seen timestamp into a coarse label.
+// online must only be true when the viewer is allowed to see online status.
+func lastActiveBucket(lastSeen *time.Time, online bool, now time.Time) string {
+	if online {
+		return "active now"
+	}
+	if lastSeen == nil {
+		return ""
+	}
+
+	since := now.Sub(*lastSeen)
+	switch {
+	case since < 24*time.Hour:
+		return "active today"
+	case since < 7*24*time.Hour:
+		return "active this week"
+	case since < 30*24*time.Hour:
+		return "active this month"
+	default:
+		return "active a while ago"
+	}
+}
+
+// responseBadge labels users who reliably answer their matches. Stats must be
+// preloaded; users with too few conversations get no badge.
+func responseBadge(stats *models.MessageStats) string {
+	if stats == nil || stats.ConversationsOpened < 3 {
+		return ""
+	}
+	if stats.ReplyRate >= 0.5 && stats.MedianReplyMinutes != nil && *stats.MedianReplyMinutes <= 60 {
+		return "replies_quickly"
+	}
+	if stats.ReplyRate >= 0.7 {
+		return "replies_often"
+	}
+	return ""
+}
+
+// applyCleanMode blurs or drops the user's suggestive bio and photos for a
+// viewer who turned clean mode on. Blurring leaves it to the app to show
+// them on tap.
+func applyCleanMode(resp *PublicUserResponse, user models.User, cleanMode string) {
+	if cleanMode != cleanModeBlur && cleanMode != cleanModeHide {
+		return
+	}
+
+	if user.BioSuggestive && resp.Bio != nil {
+		if cleanMode == cleanModeHide {
+			resp.Bio = nil
+		} else {
+			resp.BioBlurred = true
+		}
+	}
+
+	photos := make([]models.ProfilePhoto, 0, len(resp.ProfilePhotos))
+	for _, photo := range resp.ProfilePhotos {
+		if photo.Suggestive {
+			if cleanMode == cleanModeHide {
+				continue
+			}
+			photo.Blurred = true
+		}
+		photos = append(photos, photo)
+	}
+	resp.ProfilePhotos = photos
+}
+
+// publicPhotos drops photos that are pending review, were rejected, or are
+// in the private album
+func publicPhotos(photos []models.ProfilePhoto) []models.ProfilePhoto {
+	public := make([]models.ProfilePhoto, 0, len(photos))
+	for _, photo := range photos {
+		if (photo.ModerationStatus == "" || photo.ModerationStatus == "approved") && !photo.IsPrivate {
+			public = append(public, photo)
+		}
+	}
+	return public
+}
+
+func hasCoordinates(user *models.User) bool {
+	return user.Latitude != nil && user.Longitude != nil
+}
+
+// hasPreciseLocation reports whether the user's coordinates came from GPS
+// rather than an IP lookup
+func hasPreciseLocation(user *models.User) bool {
+	return hasCoordinates(user) && !user.LocationApproximate
+}
+
+// loadViewer fetches the requesting user for projections. A nil viewer only
+// disables viewer-relative fields, so lookup errors are not fatal.
+func loadViewer(db *gorm.DB, userID interface{}) *models.User {
+	var viewer models.User
+	if err := db.Where("id = ?", userID).First(&viewer).Error; err != nil {
+		return nil
+	}
+	return &viewer
+}
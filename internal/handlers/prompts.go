@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxProfilePrompts = 3
+
+type PromptAnswer struct {
+	Question string `json:"question" binding:"required,max=150"`
+	Answer   string `json:"answer" binding:"required,max=300"`
+}
+
+type UpdatePromptsRequest struct {
+	Prompts []PromptAnswer `json:"prompts" binding:"max=3,dive"`
+}
+
+// orderedPrompts is a Preload condition returning prompts in profile order
+func orderedPrompts(db *gorm.DB) *gorm.DB {
+	return db.Order(`"order" ASC`)
+}
+
+// UpdatePrompts replaces the caller's prompt answers. Likes on removed
+// prompts keep their comment but lose the prompt reference.
+func (h *UserHandler) UpdatePrompts(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req UpdatePromptsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prompts := make([]models.ProfilePrompt, 0, maxProfilePrompts)
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var oldIDs []uint
+		if err := tx.Model(&models.ProfilePrompt{}).Where("user_id = ?", userID).Pluck("id", &oldIDs).Error; err != nil {
+			return err
+		}
+		if len(oldIDs) > 0 {
+			if err := tx.Model(&models.Like{}).Where("prompt_id IN ?", oldIDs).Update("prompt_id", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", oldIDs).Delete(&models.ProfilePrompt{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for i, answer := range req.Prompts {
+			prompt := models.ProfilePrompt{
+				UserID:   userID.(uint),
+				Question: answer.Question,
+				Answer:   answer.Answer,
+				Order:    i,
+			}
+			if err := tx.Create(&prompt).Error; err != nil {
+				return err
+			}
+			prompts = append(prompts, prompt)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prompts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompts": prompts})
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+)
+
+// allowRequest counts a hit against key in a fixed window and reports whether
+// the caller is still within limit. Redis errors fail open.
+func allowRequest(ctx context.Context, rc *redis.Client, key string, limit int64, window time.Duration) bool {
+	count, err := rc.Incr(ctx, key)
+	if err != nil {
+		return true
+	}
+	if count == 1 {
+		rc.Expire(ctx, key, window)
+	}
+	return count <= limit
+}
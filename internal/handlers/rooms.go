@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoomHandler struct {
+	db    *gorm.DB
+	redis *redis.Client
+	cfg   *config.Config
+	hub   *websocket.Hub
+}
+
+type CreateRoomRequest struct {
+	Name        string  `json:"name" binding:"required,max=80"`
+	Slug        string  `json:"slug" binding:"required,max=80"`
+	Description *string `json:"description,omitempty"`
+	InterestID  *uint   `json:"interest_id,omitempty"`
+}
+
+type SendRoomMessageRequest struct {
+	Content string `json:"content" binding:"required,max=1000"`
+}
+
+type RoomMessageResponse struct {
+	ID        uint               `json:"id"`
+	RoomID    uint               `json:"room_id"`
+	Content   string             `json:"content"`
+	CreatedAt time.Time          `json:"created_at"`
+	Sender    PublicUserResponse `json:"sender"`
+}
+
+func NewRoomHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, hub *websocket.Hub) *RoomHandler {
+	return &RoomHandler{
+		db:    db,
+		redis: redis,
+		cfg:   cfg,
+		hub:   hub,
+	}
+}
+
+func (h *RoomHandler) GetRooms(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var rooms []models.Room
+	if err := h.db.WithContext(ctx).Where("is_active = ?", true).Order("member_count DESC, name ASC").Find(&rooms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
+		return
+	}
+
+	var joined []uint
+	h.db.WithContext(ctx).Model(&models.RoomMember{}).Where("user_id = ?", userID).Pluck("room_id", &joined)
+
+	c.JSON(http.StatusOK, gin.H{"rooms": rooms, "joined_room_ids": joined})
+}
+
+func (h *RoomHandler) JoinRoom(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	room, ok := h.findRoom(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		member := models.RoomMember{RoomID: room.ID, UserID: userID.(uint), JoinedAt: time.Now()}
+		result := tx.Where("room_id = ? AND user_id = ?", room.ID, userID).FirstOrCreate(&member)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.Room{}).Where("id = ?", room.ID).
+			UpdateColumn("member_count", gorm.Expr("member_count + 1")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join room"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Joined room successfully"})
+}
+
+func (h *RoomHandler) LeaveRoom(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	room, ok := h.findRoom(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("room_id = ? AND user_id = ?", room.ID, userID).Delete(&models.RoomMember{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.Room{}).Where("id = ? AND member_count > 0", room.ID).
+			UpdateColumn("member_count", gorm.Expr("member_count - 1")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave room"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left room successfully"})
+}
+
+// GetRoomMessages returns room history newest first. Messages from users the
+// caller has blocked are left out.
+func (h *RoomHandler) GetRoomMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	room, ok := h.findRoom(c)
+	if !ok {
+		return
+	}
+
+	if !h.isMember(ctx, room.ID, userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Join the room to read its messages"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	var messages []models.RoomMessage
+	if err := h.db.WithContext(ctx).Preload("Sender").Preload("Sender.ProfilePhotos").
+		Where("room_id = ?", room.ID).
+		Where("sender_id NOT IN (SELECT blocked_id FROM blocked_users WHERE blocker_id = ?)", userID).
+		Order("created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+	response := make([]RoomMessageResponse, 0, len(messages))
+	for _, msg := range messages {
+		response = append(response, newRoomMessageResponse(msg, viewer))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": response,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+func (h *RoomHandler) SendRoomMessage(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	room, ok := h.findRoom(c)
+	if !ok {
+		return
+	}
+
+	if !h.isMember(ctx, room.ID, userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Join the room to send messages"})
+		return
+	}
+
+	if respondIfMuted(c, h.db.WithContext(ctx), userID) {
+		return
+	}
+
+	if respondIfUnverified(c, h.db.WithContext(ctx), h.cfg, userID, "messaging") {
+		return
+	}
+
+	var req SendRoomMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := models.RoomMessage{
+		RoomID:   room.ID,
+		SenderID: userID.(uint),
+		Content:  strings.TrimSpace(req.Content),
+	}
+	if err := h.db.WithContext(ctx).Create(&message).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	// Route to connected members only, skipping anyone who blocked the sender
+	var memberIDs []uint
+	h.db.WithContext(ctx).Model(&models.RoomMember{}).
+		Where("room_id = ?", room.ID).
+		Where("user_id NOT IN (SELECT blocker_id FROM blocked_users WHERE blocked_id = ?)", userID).
+		Pluck("user_id", &memberIDs)
+
+	messageData := websocket.Message{
+		Type:        "room_message",
+		RoomID:      room.ID,
+		SenderID:    userID.(uint),
+		Content:     message.Content,
+		MessageType: "text",
+		Timestamp:   message.CreatedAt.Format(time.RFC3339),
+	}
+	if messageBytes, err := json.Marshal(messageData); err == nil {
+		h.hub.BroadcastToUsers(memberIDs, messageBytes)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": message})
+}
+
+// CreateRoom lets admins open a new topic room
+func (h *RoomHandler) CreateRoom(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req CreateRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	room := models.Room{
+		Name:        req.Name,
+		Slug:        strings.ToLower(strings.TrimSpace(req.Slug)),
+		Description: req.Description,
+		InterestID:  req.InterestID,
+		IsActive:    true,
+	}
+
+	var count int64
+	h.db.WithContext(ctx).Model(&models.Room{}).Where("slug = ?", room.Slug).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A room with this slug already exists"})
+		return
+	}
+
+	if err := h.db.WithContext(ctx).Create(&room).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"room": room})
+}
+
+func (h *RoomHandler) findRoom(c *gin.Context) (models.Room, bool) {
+	ctx := c.Request.Context()
+	var room models.Room
+	roomID, err := strconv.ParseUint(c.Param("room_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return room, false
+	}
+
+	if err := h.db.WithContext(ctx).Where("id = ? AND is_active = ?", roomID, true).First(&room).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return room, false
+	}
+	return room, true
+}
+
+func (h *RoomHandler) isMember(ctx context.Context, roomID, userID uint) bool {
+	var count int64
+	h.db.WithContext(ctx).Model(&models.RoomMember{}).Where("room_id = ? AND user_id = ?", roomID, userID).Count(&count)
+	return count > 0
+}
+
+func newRoomMessageResponse(msg models.RoomMessage, viewer *models.User) RoomMessageResponse {
+	return RoomMessageResponse{
+		ID:        msg.ID,
+		RoomID:    msg.RoomID,
+		Content:   msg.Content,
+		CreatedAt: msg.CreatedAt,
+		Sender:    newPublicUser(msg.Sender, viewer),
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxTrustedContacts = 5
+
+type SafetyHandler struct {
+	db    *gorm.DB
+	redis *redis.Client
+	cfg   *config.Config
+	sms   services.SMSSender
+}
+
+type TrustedContactRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Phone        string `json:"phone" binding:"required"`
+	Relationship string `json:"relationship,omitempty"`
+}
+
+type CreateDatePlanRequest struct {
+	MatchUserID       *uint      `json:"match_user_id,omitempty"`
+	WithName          string     `json:"with_name" binding:"required"`
+	Location          string     `json:"location" binding:"required"`
+	ScheduledAt       time.Time  `json:"scheduled_at" binding:"required"`
+	CheckInAt         *time.Time `json:"check_in_at,omitempty"` // defaults to 3 hours after scheduled_at
+	ShareWithContacts bool       `json:"share_with_contacts"`
+}
+
+func NewSafetyHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, sms services.SMSSender) *SafetyHandler {
+	return &SafetyHandler{
+		db:    db,
+		redis: redis,
+		cfg:   cfg,
+		sms:   sms,
+	}
+}
+
+func (h *SafetyHandler) GetTrustedContacts(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var contacts []models.TrustedContact
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trusted contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contacts": contacts})
+}
+
+func (h *SafetyHandler) AddTrustedContact(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req TrustedContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	h.db.WithContext(ctx).Model(&models.TrustedContact{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxTrustedContacts {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("You can have at most %d trusted contacts", maxTrustedContacts)})
+		return
+	}
+
+	contact := models.TrustedContact{
+		UserID:       userID.(uint),
+		Name:         req.Name,
+		Phone:        utils.FormatPhoneNumber(req.Phone),
+		Relationship: req.Relationship,
+	}
+
+	if err := h.db.WithContext(ctx).Create(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add trusted contact"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"contact": contact})
+}
+
+func (h *SafetyHandler) DeleteTrustedContact(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	contactID := c.Param("id")
+
+	result := h.db.WithContext(ctx).Where("id = ? AND user_id = ?", contactID, userID).Delete(&models.TrustedContact{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete trusted contact"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trusted contact not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trusted contact removed"})
+}
+
+func (h *SafetyHandler) GetDatePlans(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var plans []models.DatePlan
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("scheduled_at DESC").Limit(50).Find(&plans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch date plans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dates": plans})
+}
+
+// CreateDatePlan records who the user is meeting, where, and when. A check-in
+// reminder is sent at check_in_at; if the plan isn't completed within the
+// grace period after that, trusted contacts are alerted by SMS.
+func (h *SafetyHandler) CreateDatePlan(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req CreateDatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checkInAt := req.ScheduledAt.Add(3 * time.Hour)
+	if req.CheckInAt != nil {
+		checkInAt = *req.CheckInAt
+	}
+	if !checkInAt.After(time.Now()) || checkInAt.Before(req.ScheduledAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check_in_at must be in the future and after scheduled_at"})
+		return
+	}
+
+	plan := models.DatePlan{
+		UserID:            userID.(uint),
+		MatchUserID:       req.MatchUserID,
+		WithName:          req.WithName,
+		Location:          req.Location,
+		ScheduledAt:       req.ScheduledAt,
+		CheckInAt:         checkInAt,
+		Status:            "active",
+		ShareWithContacts: req.ShareWithContacts,
+	}
+
+	if err := h.db.WithContext(ctx).Create(&plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create date plan"})
+		return
+	}
+
+	if req.ShareWithContacts {
+		var user models.User
+		h.db.WithContext(ctx).Where("id = ?", userID).First(&user)
+
+		body := fmt.Sprintf("%s shared a date plan with you: meeting %s at %s on %s. You'll be alerted if they miss their check-in.",
+			user.FirstName, plan.WithName, plan.Location, plan.ScheduledAt.Format("Jan 2 15:04"))
+		h.notifyTrustedContacts(c, userID.(uint), body)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"date": plan})
+}
+
+func (h *SafetyHandler) CompleteDatePlan(c *gin.Context) {
+	h.closeDatePlan(c, "completed")
+}
+
+func (h *SafetyHandler) CancelDatePlan(c *gin.Context) {
+	h.closeDatePlan(c, "cancelled")
+}
+
+func (h *SafetyHandler) closeDatePlan(c *gin.Context, status string) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	planID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date plan ID"})
+		return
+	}
+
+	// Missed plans can still be completed so contacts get an all-clear
+	var plan models.DatePlan
+	if err := h.db.WithContext(ctx).Where("id = ? AND user_id = ? AND status IN ?", planID, userID, []string{"active", "missed"}).
+		First(&plan).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Date plan not found"})
+		return
+	}
+
+	wasMissed := plan.Status == "missed"
+	now := time.Now()
+	plan.Status = status
+	plan.CompletedAt = &now
+
+	if err := h.db.WithContext(ctx).Save(&plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update date plan"})
+		return
+	}
+
+	if wasMissed && status == "completed" {
+		var user models.User
+		h.db.WithContext(ctx).Where("id = ?", userID).First(&user)
+		h.notifyTrustedContacts(c, userID.(uint), fmt.Sprintf("%s has checked in and is safe.", user.FirstName))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"date": plan})
+}
+
+func (h *SafetyHandler) notifyTrustedContacts(c *gin.Context, userID uint, body string) {
+	ctx := c.Request.Context()
+	var contacts []models.TrustedContact
+	h.db.WithContext(ctx).Where("user_id = ?", userID).Find(&contacts)
+
+	for _, contact := range contacts {
+		if err := h.sms.Send(ctx, contact.Phone, body); err != nil {
+			log.Printf("Failed to notify trusted contact %d: %v", contact.ID, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SearchHandler struct {
+	db    *gorm.DB
+	redis *redis.Client
+	cfg   *config.Config
+}
+
+type MatchSearchResult struct {
+	MatchID uint               `json:"match_id"`
+	User    PublicUserResponse `json:"user"`
+}
+
+type MessageSearchHit struct {
+	MessageID      uint      `json:"message_id"`
+	ConversationID uint      `json:"conversation_id"`
+	SenderID       uint      `json:"sender_id"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SearchResultGroup struct {
+	Type    string      `json:"type"` // matches, messages
+	Results interface{} `json:"results"`
+	Total   int64       `json:"total"`
+	Page    int         `json:"page"`
+	Limit   int         `json:"limit"`
+}
+
+func NewSearchHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config) *SearchHandler {
+	return &SearchHandler{
+		db:    db,
+		redis: redis,
+		cfg:   cfg,
+	}
+}
+
+// Search looks through the caller's matches by name and their conversations by
+// message content. Pass type=matches or type=messages to page a single group.
+func (h *SearchHandler) Search(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	q := strings.TrimSpace(c.Query("q"))
+	if len([]rune(q)) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query must be at least 2 characters"})
+		return
+	}
+
+	groupType := c.Query("type")
+	if groupType != "" && groupType != "matches" && groupType != "messages" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be matches or messages"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	pattern := "%" + q + "%"
+	var groups []SearchResultGroup
+
+	if groupType == "" || groupType == "matches" {
+		group, err := h.searchMatches(ctx, userID.(uint), pattern, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search matches"})
+			return
+		}
+		groups = append(groups, group)
+	}
+
+	if groupType == "" || groupType == "messages" {
+		group, err := h.searchMessages(ctx, userID.(uint), pattern, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+			return
+		}
+		groups = append(groups, group)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"query": q, "groups": groups})
+}
+
+func (h *SearchHandler) searchMatches(ctx context.Context, userID uint, pattern string, page, limit int) (SearchResultGroup, error) {
+	query := h.db.WithContext(ctx).Table("matches").
+		Joins("JOIN users ON users.id = CASE WHEN matches.user1_id = ? THEN matches.user2_id ELSE matches.user1_id END", userID).
+		Where("(matches.user1_id = ? OR matches.user2_id = ?) AND matches.is_active = ? AND matches.deleted_at IS NULL",
+			userID, userID, true).
+		Where("users.first_name ILIKE ? OR users.last_name ILIKE ?", pattern, pattern)
+
+	var total int64
+	query.Count(&total)
+
+	var rows []struct {
+		MatchID uint
+		UserID  uint
+	}
+	if err := query.Select("matches.id AS match_id, users.id AS user_id").
+		Order("matches.created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return SearchResultGroup{}, err
+	}
+
+	userIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		userIDs = append(userIDs, row.UserID)
+	}
+
+	var users []models.User
+	if len(userIDs) > 0 {
+		h.db.WithContext(ctx).Preload("ProfilePhotos").Where("id IN ?", userIDs).Find(&users)
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+	results := make([]MatchSearchResult, 0, len(rows))
+	for _, row := range rows {
+		if user, ok := byID[row.UserID]; ok {
+			results = append(results, MatchSearchResult{MatchID: row.MatchID, User: newPublicUser(user, viewer)})
+		}
+	}
+
+	return SearchResultGroup{Type: "matches", Results: results, Total: total, Page: page, Limit: limit}, nil
+}
+
+func (h *SearchHandler) searchMessages(ctx context.Context, userID uint, pattern string, page, limit int) (SearchResultGroup, error) {
+	query := h.db.WithContext(ctx).Table("messages").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Joins("JOIN matches ON matches.id = conversations.match_id").
+		Where("(matches.user1_id = ? OR matches.user2_id = ?) AND conversations.is_active = ?", userID, userID, true).
+		Where("messages.deleted_at IS NULL AND messages.message_type != ? AND messages.is_encrypted = ? AND messages.content ILIKE ?", "system", false, pattern)
+
+	var total int64
+	query.Count(&total)
+
+	results := make([]MessageSearchHit, 0, limit)
+	if err := query.Select("messages.id AS message_id, messages.conversation_id, messages.sender_id, messages.content, messages.created_at").
+		Order("messages.created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&results).Error; err != nil {
+		return SearchResultGroup{}, err
+	}
+
+	return SearchResultGroup{Type: "messages", Results: results, Total: total, Page: page, Limit: limit}, nil
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Discovery defaults for users who haven't saved their own
+const (
+	defaultAgeRangeMin = 18
+	defaultAgeRangeMax = 99
+)
+
+// UserSettings gathers every user-controlled setting in one document. Values
+// the user never set are filled with the server defaults.
+type UserSettings struct {
+	Discovery     DiscoverySettings             `json:"discovery"`
+	Privacy       PrivacySettings               `json:"privacy"`
+	Notifications models.NotificationPreference `json:"notifications"`
+	Language      string                        `json:"language"`
+	Units         string                        `json:"units"`      // km, mi
+	CleanMode     string                        `json:"clean_mode"` // off, blur, hide
+	Version       int                           `json:"version"`
+}
+
+type DiscoverySettings struct {
+	Seeking       string `json:"seeking"`
+	AgeMin        int    `json:"age_min"`
+	AgeMax        int    `json:"age_max"`
+	MaxDistanceKm *int   `json:"max_distance_km"` // null means any distance
+	Paused        bool   `json:"paused"`          // read-only here; see PUT /users/settings/pause
+}
+
+type PrivacySettings struct {
+	HideAge      bool `json:"hide_age"` // premium only
+	HideOnline   bool `json:"hide_online"`
+	HideLastSeen bool `json:"hide_last_seen"`
+	HideContacts bool `json:"hide_contacts"`
+}
+
+// UpdateSettingsRequest changes only the fields that are sent
+type UpdateSettingsRequest struct {
+	Discovery *struct {
+		Seeking *string `json:"seeking,omitempty" binding:"omitempty,oneof=men women everyone"`
+		AgeMin  *int    `json:"age_min,omitempty" binding:"omitempty,min=18,max=99"`
+		AgeMax  *int    `json:"age_max,omitempty" binding:"omitempty,min=18,max=99"`
+		// 0 clears the limit
+		MaxDistanceKm *int `json:"max_distance_km,omitempty" binding:"omitempty,min=0,max=500"`
+	} `json:"discovery,omitempty"`
+	Privacy *struct {
+		HideAge      *bool `json:"hide_age,omitempty"`
+		HideOnline   *bool `json:"hide_online,omitempty"`
+		HideLastSeen *bool `json:"hide_last_seen,omitempty"`
+		HideContacts *bool `json:"hide_contacts,omitempty"`
+	} `json:"privacy,omitempty"`
+	Notifications *NotificationPreferencesRequest `json:"notifications,omitempty"`
+	Language      *string                         `json:"language,omitempty" binding:"omitempty,oneof=en am"`
+	Units         *string                         `json:"units,omitempty" binding:"omitempty,oneof=km mi"`
+	CleanMode     *string                         `json:"clean_mode,omitempty" binding:"omitempty,oneof=off blur hide"`
+
+	// Version the client last saw; may also be sent as an If-Match header
+	Version *int `json:"version,omitempty"`
+}
+
+func newUserSettings(user *models.User, prefs models.NotificationPreference) UserSettings {
+	settings := UserSettings{
+		Discovery: DiscoverySettings{
+			Seeking:       user.Seeking,
+			AgeMin:        defaultAgeRangeMin,
+			AgeMax:        defaultAgeRangeMax,
+			MaxDistanceKm: user.MaxDistanceKm,
+			Paused:        user.IsPaused,
+		},
+		Privacy: PrivacySettings{
+			HideAge:      user.HideAge,
+			HideOnline:   user.HideOnline,
+			HideLastSeen: user.HideLastSeen,
+			HideContacts: user.HideContacts,
+		},
+		Notifications: prefs,
+		Language:      user.Language,
+		Units:         user.DistanceUnit,
+		CleanMode:     user.CleanMode,
+		Version:       user.Version,
+	}
+	if user.AgeRangeMin != nil {
+		settings.Discovery.AgeMin = *user.AgeRangeMin
+	}
+	if user.AgeRangeMax != nil {
+		settings.Discovery.AgeMax = *user.AgeRangeMax
+	}
+	if settings.Language == "" {
+		settings.Language = "en"
+	}
+	if settings.Units == "" {
+		settings.Units = "km"
+	}
+	if settings.CleanMode == "" {
+		settings.CleanMode = cleanModeOff
+	}
+	return settings
+}
+
+// withSavedFilters fills discovery filters the request left out with the
+// viewer's saved settings
+func withSavedFilters(filters DiscoverFilters, viewer *models.User) DiscoverFilters {
+	if filters.AgeMin == nil {
+		filters.AgeMin = viewer.AgeRangeMin
+	}
+	if filters.AgeMax == nil {
+		filters.AgeMax = viewer.AgeRangeMax
+	}
+	if filters.MaxDistance == nil {
+		filters.MaxDistance = viewer.MaxDistanceKm
+	}
+	if filters.Latitude == nil || filters.Longitude == nil {
+		filters.Latitude, filters.Longitude = viewer.Latitude, viewer.Longitude
+	}
+	return filters
+}
+
+// GetSettings returns the caller's settings document. Its ETag is the user
+// version, to send back as If-Match when updating.
+func (h *UserHandler) GetSettings(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	setVersionETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{"settings": newUserSettings(&user, loadNotificationPreferences(h.db.WithContext(ctx), user.ID))})
+}
+
+// UpdateSettings applies a partial settings document. User settings and
+// notification preferences are saved together, and a stale version fails
+// the whole update with 409 VERSION_CONFLICT.
+func (h *UserHandler) UpdateSettings(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	before := user
+	if d := req.Discovery; d != nil {
+		if d.Seeking != nil {
+			user.Seeking = *d.Seeking
+		}
+		if d.AgeMin != nil {
+			user.AgeRangeMin = d.AgeMin
+		}
+		if d.AgeMax != nil {
+			user.AgeRangeMax = d.AgeMax
+		}
+		if d.MaxDistanceKm != nil {
+			user.MaxDistanceKm = d.MaxDistanceKm
+			if *d.MaxDistanceKm == 0 {
+				user.MaxDistanceKm = nil
+			}
+		}
+	}
+	settings := newUserSettings(&user, models.NotificationPreference{})
+	if settings.Discovery.AgeMin > settings.Discovery.AgeMax {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "discovery.age_min can't be above age_max"})
+		return
+	}
+
+	if p := req.Privacy; p != nil {
+		if p.HideAge != nil {
+			if *p.HideAge && !user.IsPremium {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Hiding your age requires a premium account"})
+				return
+			}
+			user.HideAge = *p.HideAge
+		}
+		if p.HideOnline != nil {
+			user.HideOnline = *p.HideOnline
+		}
+		if p.HideLastSeen != nil {
+			user.HideLastSeen = *p.HideLastSeen
+		}
+		if p.HideContacts != nil {
+			user.HideContacts = *p.HideContacts
+		}
+	}
+	if req.Language != nil {
+		user.Language = *req.Language
+	}
+	if req.Units != nil {
+		user.DistanceUnit = *req.Units
+	}
+	if req.CleanMode != nil {
+		user.CleanMode = *req.CleanMode
+	}
+
+	prefs := loadNotificationPreferences(h.db.WithContext(ctx), user.ID)
+	if n := req.Notifications; n != nil {
+		if n.Matches != nil {
+			prefs.Matches = *n.Matches
+		}
+		if n.Messages != nil {
+			prefs.Messages = *n.Messages
+		}
+		if n.Likes != nil {
+			prefs.Likes = *n.Likes
+		}
+		if n.Milestones != nil {
+			prefs.Milestones = *n.Milestones
+		}
+		if n.WeeklyDigest != nil {
+			prefs.WeeklyDigest = *n.WeeklyDigest
+		}
+		if n.Marketing != nil {
+			prefs.Marketing = *n.Marketing
+		}
+		if n.SMS != nil {
+			prefs.SMS = *n.SMS
+		}
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveUserVersioned(tx, &user, expectedVersion(c, req.Version)); err != nil {
+			return err
+		}
+		if req.Discovery != nil {
+			if err := services.MarkOnboardingStep(tx, user.ID, services.OnboardingSetPreferences); err != nil {
+				return err
+			}
+		}
+		if req.Notifications != nil {
+			// Select all columns so false values are written too
+			return tx.Select("*").Save(&prefs).Error
+		}
+		return nil
+	})
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, h.db.WithContext(ctx), userID)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	queuePreferenceChange(ctx, h.redis, user.ID, preferenceChange(&before, &user))
+
+	setVersionETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{"settings": newUserSettings(&user, prefs)})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	statsCacheTTL     = 10 * time.Minute
+	matchRateWindow   = 30 * 24 * time.Hour
+	photoStatsWindow  = 30 * 24 * time.Hour
+	weeklyStatsWindow = 7 * 24 * time.Hour
+)
+
+// UserStats is the caller's own swipe feedback. Weekly figures cover the
+// last 7 days; match rate and best photo the last 30.
+type UserStats struct {
+	LikesSentThisWeek     int64       `json:"likes_sent_this_week"`
+	LikesReceivedThisWeek int64       `json:"likes_received_this_week"`
+	ProfileViewsThisWeek  int64       `json:"profile_views_this_week"`
+	MatchRate             float64     `json:"match_rate"` // share of likes sent that became matches
+	BestPhoto             *PhotoStats `json:"best_photo,omitempty"`
+	ComputedAt            time.Time   `json:"computed_at"`
+}
+
+// PhotoStats is how many likes pointed at one of the caller's photos
+type PhotoStats struct {
+	PhotoID uint   `json:"photo_id"`
+	URL     string `json:"url"`
+	Likes   int64  `json:"likes"`
+}
+
+func statsCacheKey(userID uint) string {
+	return fmt.Sprintf("stats:%d", userID)
+}
+
+// GetStats returns the caller's swipe statistics. They are cached for a few
+// minutes since they only move slowly and cost several aggregate queries.
+func (h *UserHandler) GetStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	stats, err := loadUserStats(ctx, h.db, h.redis, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute stats"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// loadUserStats returns the user's cached stats, computing them if the cache
+// is empty
+func loadUserStats(ctx context.Context, db *gorm.DB, rc *redis.Client, userID uint) (UserStats, error) {
+	key := statsCacheKey(userID)
+	if cached, err := rc.Get(ctx, key); err == nil {
+		var stats UserStats
+		if json.Unmarshal([]byte(cached), &stats) == nil {
+			return stats, nil
+		}
+	}
+
+	stats, err := computeStats(ctx, db, rc, userID)
+	if err != nil {
+		return stats, err
+	}
+
+	if data, err := json.Marshal(stats); err == nil {
+		rc.Set(ctx, key, data, statsCacheTTL)
+	}
+	return stats, nil
+}
+
+func computeStats(ctx context.Context, db *gorm.DB, rc *redis.Client, userID uint) (UserStats, error) {
+	db = db.WithContext(ctx)
+	now := time.Now()
+	weekAgo := now.Add(-weeklyStatsWindow)
+	stats := UserStats{ComputedAt: now}
+
+	if err := db.Model(&models.Like{}).Where("liker_id = ? AND created_at >= ?", userID, weekAgo).
+		Count(&stats.LikesSentThisWeek).Error; err != nil {
+		return stats, err
+	}
+	if err := db.Model(&models.Like{}).Where("liked_id = ? AND created_at >= ?", userID, weekAgo).
+		Count(&stats.LikesReceivedThisWeek).Error; err != nil {
+		return stats, err
+	}
+
+	var rate struct {
+		Sent    int64
+		Matched int64
+	}
+	if err := db.Raw(`
+		SELECT COUNT(*) AS sent,
+			COUNT(*) FILTER (WHERE EXISTS (
+				SELECT 1 FROM matches m
+				WHERE m.deleted_at IS NULL
+				AND ((m.user1_id = l.liker_id AND m.user2_id = l.liked_id) OR (m.user1_id = l.liked_id AND m.user2_id = l.liker_id))
+			)) AS matched
+		FROM likes l
+		WHERE l.liker_id = ? AND l.created_at >= ?`, userID, now.Add(-matchRateWindow)).
+		Scan(&rate).Error; err != nil {
+		return stats, err
+	}
+	if rate.Sent > 0 {
+		stats.MatchRate = math.Round(float64(rate.Matched)/float64(rate.Sent)*100) / 100
+	}
+
+	var best PhotoStats
+	result := db.Raw(`
+		SELECT p.id AS photo_id, p.url, COUNT(*) AS likes
+		FROM likes l
+		JOIN profile_photos p ON p.id = l.photo_id AND p.user_id = l.liked_id AND p.deleted_at IS NULL
+		WHERE l.liked_id = ? AND l.created_at >= ?
+		GROUP BY p.id, p.url
+		ORDER BY likes DESC, p.id
+		LIMIT 1`, userID, now.Add(-photoStatsWindow)).
+		Scan(&best)
+	if result.Error != nil {
+		return stats, result.Error
+	}
+	if result.RowsAffected > 0 {
+		stats.BestPhoto = &best
+	}
+
+	views, err := rc.ProfileViews(ctx, userID, 7)
+	if err != nil {
+		return stats, err
+	}
+	stats.ProfileViewsThisWeek = views
+
+	return stats, nil
+}
+
+// recordProfileViews counts each user shown to the viewer as a profile view
+func (h *UserHandler) recordProfileViews(ctx context.Context, users []models.User) {
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	h.redis.RecordProfileViews(ctx, ids)
+}
+
+// GetLatestDigest returns the caller's most recent weekly digest
+func (h *UserHandler) GetLatestDigest(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var digest models.WeeklyDigest
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("week_start DESC").First(&digest).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No digest yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"digest": digest})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetStorageReport shows the latest check of stored media against the
+// database, with samples of missing and orphaned files, and the counts of
+// earlier checks so drift can be followed over time
+func (h *AdminHandler) GetStorageReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	count, _ := strconv.Atoi(c.DefaultQuery("history", "30"))
+	if count < 1 || count > 365 {
+		count = 30
+	}
+
+	var latest models.StorageReport
+	err := h.db.WithContext(ctx).Order("created_at DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Storage hasn't been checked yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch storage report"})
+		return
+	}
+
+	var history []models.StorageReport
+	if err := h.db.WithContext(ctx).Omit("missing", "unresolvable", "orphaned").
+		Order("created_at DESC").Limit(count).Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch storage report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": latest, "history": history})
+}
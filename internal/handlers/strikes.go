@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// penaltyLadder lists penalties from mildest to harshest
+var penaltyLadder = []string{"warning", "mute", "suspension", "ban"}
+
+const (
+	muteDuration       = 24 * time.Hour
+	suspensionDuration = 7 * 24 * time.Hour
+)
+
+type AddStrikeRequest struct {
+	Reason string  `json:"reason" binding:"required,max=100"`
+	Weight *int    `json:"weight,omitempty" binding:"omitempty,min=1,max=100"` // defaults to the reason's configured weight
+	Note   *string `json:"note,omitempty" binding:"omitempty,max=1000"`
+}
+
+// issueStrike records strike and applies the harshest penalty whose threshold
+// the user's total just crossed. Weight defaults to the reason's configured
+// weight. Run it inside the transaction that caused the strike.
+func issueStrike(tx *gorm.DB, cfg *config.Config, strike *models.Strike) error {
+	if strike.Weight == 0 {
+		strike.Weight = cfg.StrikeWeight(strike.Reason)
+	}
+
+	var before int
+	if err := tx.Model(&models.Strike{}).Where("user_id = ?", strike.UserID).
+		Select("COALESCE(SUM(weight), 0)").Scan(&before).Error; err != nil {
+		return err
+	}
+	after := before + strike.Weight
+
+	strike.Penalty = "none"
+	for _, penalty := range penaltyLadder {
+		threshold := cfg.StrikeThresholds[penalty]
+		if threshold > 0 && before < threshold && after >= threshold {
+			strike.Penalty = penalty
+		}
+	}
+
+	if err := tx.Create(strike).Error; err != nil {
+		return err
+	}
+	return applyPenalty(tx, strike.UserID, strike.Penalty)
+}
+
+func applyPenalty(tx *gorm.DB, userID uint, penalty string) error {
+	var title, body string
+	updates := map[string]interface{}{}
+	now := time.Now()
+
+	switch penalty {
+	case "warning":
+		title = "Community guidelines warning"
+		body = "Your account received a warning for behavior that goes against our community guidelines."
+	case "mute":
+		updates["muted_until"] = now.Add(muteDuration)
+		title = "Messaging paused"
+		body = "You can't send messages for the next 24 hours because of repeated guideline violations."
+	case "suspension":
+		updates["is_active"] = false
+		updates["is_online"] = false
+		updates["suspended_until"] = now.Add(suspensionDuration)
+		title = "Account suspended"
+		body = "Your account has been suspended for 7 days because of repeated guideline violations."
+	case "ban":
+		updates["is_active"] = false
+		updates["is_online"] = false
+		updates["suspended_until"] = nil
+		title = "Account banned"
+		body = "Your account has been permanently banned for violating our community guidelines."
+	default:
+		return nil
+	}
+
+	if len(updates) > 0 {
+		updates["version"] = gorm.Expr("version + 1")
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	notification := models.Notification{
+		UserID: userID,
+		Type:   "strike_" + penalty,
+		Title:  title,
+		Body:   body,
+		Data:   `{"penalty": "` + penalty + `"}`,
+	}
+	return tx.Create(&notification).Error
+}
+
+// enforcePenalty makes a penalty take effect on sessions already open: muted
+// users' connections stop relaying what they send, and suspended or banned
+// users are signed out everywhere. Call it once the strike is committed.
+func enforcePenalty(ctx context.Context, rc *redis.Client, strike models.Strike) {
+	now := time.Now()
+	switch strike.Penalty {
+	case "mute":
+		services.MuteAccount(ctx, rc, strike.UserID, now.Add(muteDuration))
+	case "suspension":
+		until := now.Add(suspensionDuration)
+		services.BlockAccount(ctx, rc, strike.UserID, &until)
+	case "ban":
+		services.BlockAccount(ctx, rc, strike.UserID, nil)
+	}
+}
+
+// MuteChecker returns the lookup the WebSocket hub uses to learn whether a
+// connecting user is muted
+func MuteChecker(db *gorm.DB) func(ctx context.Context, userID uint) time.Time {
+	return func(ctx context.Context, userID uint) time.Time {
+		var user models.User
+		if err := db.WithContext(ctx).Select("id", "muted_until").Where("id = ?", userID).First(&user).Error; err != nil || user.MutedUntil == nil {
+			return time.Time{}
+		}
+		return *user.MutedUntil
+	}
+}
+
+// respondIfMuted rejects the request when the user's messaging is muted
+func respondIfMuted(c *gin.Context, db *gorm.DB, userID interface{}) bool {
+	var user models.User
+	if err := db.Select("id", "muted_until").Where("id = ?", userID).First(&user).Error; err != nil {
+		return false
+	}
+	if user.MutedUntil == nil || !user.MutedUntil.After(time.Now()) {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":       "Your messaging is temporarily paused because of guideline violations",
+		"code":        "MESSAGING_MUTED",
+		"muted_until": user.MutedUntil,
+	})
+	return true
+}
+
+func (h *AdminHandler) GetUserStrikes(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var strikes []models.Strike
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&strikes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strikes"})
+		return
+	}
+
+	total := 0
+	for _, strike := range strikes {
+		total += strike.Weight
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"strikes":    strikes,
+		"total":      total,
+		"thresholds": h.cfg.StrikeThresholds,
+	})
+}
+
+// AddStrike lets admins issue a strike by hand, e.g. for behavior seen
+// outside the report flow
+func (h *AdminHandler) AddStrike(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req AddStrikeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	h.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	issuer := adminID.(uint)
+	strike := models.Strike{
+		UserID:   uint(userID),
+		Reason:   req.Reason,
+		Source:   "manual",
+		IssuedBy: &issuer,
+		Note:     req.Note,
+	}
+	if req.Weight != nil {
+		strike.Weight = *req.Weight
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := issueStrike(tx, h.cfg, &strike); err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "strike_added", "user", uint(userID),
+			fmt.Sprintf("reason=%s weight=%d penalty=%s", strike.Reason, strike.Weight, strike.Penalty))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add strike"})
+		return
+	}
+	enforcePenalty(ctx, h.redis, strike)
+
+	c.JSON(http.StatusCreated, gin.H{"strike": strike})
+}
+
+// RemoveStrike takes a strike off the ledger. Penalties already applied are
+// not reverted; use the user status endpoint for that.
+func (h *AdminHandler) RemoveStrike(c *gin.Context) {
+	ctx := c.Request.Context()
+	strikeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid strike ID"})
+		return
+	}
+
+	var strike models.Strike
+	if err := h.db.WithContext(ctx).Where("id = ?", strikeID).First(&strike).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strike not found"})
+		return
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&strike).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "strike_removed", "user", strike.UserID, fmt.Sprintf("strike=%d", strike.ID))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove strike"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strike removed"})
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxTicketAttachments also sizes the body limit and timeout of the ticket
+// route, whose attachments are stored one after another
+const MaxTicketAttachments = 3
+
+const (
+	ticketsPerDay = 5
+	// attachmentURLTTL is how long links to ticket screenshots work
+	attachmentURLTTL = 10 * time.Minute
+)
+
+var ticketCategories = map[string]bool{
+	"account": true, "safety": true, "billing": true, "technical": true, "other": true,
+}
+
+// SupportHandler serves support tickets to the users who open them and to
+// admins with the support role who answer them
+type SupportHandler struct {
+	db       *gorm.DB
+	redis    *redis.Client
+	cfg      *config.Config
+	notifier *services.Dispatcher
+	storage  *services.StorageService
+}
+
+type SupportReplyRequest struct {
+	Body string `json:"body" binding:"required,max=2000"`
+}
+
+type AdminSupportReplyRequest struct {
+	Body   string `json:"body" binding:"required,max=2000"`
+	Status string `json:"status" binding:"omitempty,oneof=pending solved"` // pending by default
+}
+
+type TicketStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=open pending solved"`
+}
+
+func NewSupportHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, notifier *services.Dispatcher, storage *services.StorageService) *SupportHandler {
+	return &SupportHandler{
+		db:       db,
+		redis:    redis,
+		cfg:      cfg,
+		notifier: notifier,
+		storage:  storage,
+	}
+}
+
+// CreateTicket opens a ticket from a multipart form with category,
+// description, and up to three screenshots as attachments
+func (h *SupportHandler) CreateTicket(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	// Forms without attachments may be sent URL-encoded
+	form, err := c.MultipartForm()
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body too large, maximum is %d bytes", tooLarge.Limit)})
+		return
+	case err != nil && !errors.Is(err, http.ErrNotMultipart):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+
+	category := c.PostForm("category")
+	if !ticketCategories[category] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category must be account, safety, billing, technical, or other"})
+		return
+	}
+	description := strings.TrimSpace(c.PostForm("description"))
+	if description == "" || len([]rune(description)) > 2000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description is required and must be at most 2000 characters"})
+		return
+	}
+
+	var headers []*multipart.FileHeader
+	if form != nil {
+		headers = form.File["attachments"]
+	}
+	if len(headers) > MaxTicketAttachments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d attachments are allowed", MaxTicketAttachments)})
+		return
+	}
+	contentTypes := make([]string, len(headers))
+	for i, header := range headers {
+		contentType, err := validateImageFile(h.cfg, header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("attachment %d: %v", i+1, err)})
+			return
+		}
+		contentTypes[i] = contentType
+	}
+
+	if !allowRequest(ctx, h.redis, fmt.Sprintf("ratelimit:tickets:%d", userID), ticketsPerDay, 24*time.Hour) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many tickets, please try again later"})
+		return
+	}
+
+	attachments := make([]string, 0, len(headers))
+	for i, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read attachment"})
+			return
+		}
+		filename := fmt.Sprintf("support/%d_%s%s", userID, uuid.New().String(), imageExtension(contentTypes[i]))
+		err = h.storage.UploadPrivateFile(ctx, file, filename, contentTypes[i])
+		file.Close()
+		if err != nil {
+			h.discardAttachments(ctx, attachments)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload attachment"})
+			return
+		}
+		attachments = append(attachments, filename)
+	}
+
+	ticket := models.SupportTicket{
+		UserID:      userID.(uint),
+		Category:    category,
+		Description: description,
+		Attachments: attachments,
+		Status:      "open",
+	}
+	if err := h.db.WithContext(ctx).Create(&ticket).Error; err != nil {
+		h.discardAttachments(ctx, attachments)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"ticket": ticket})
+}
+
+// GetTickets lists the caller's tickets, newest first
+func (h *SupportHandler) GetTickets(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.SupportTicket{}).Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var tickets []models.SupportTicket
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&tickets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tickets": tickets,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetTicket returns one of the caller's tickets with its replies
+func (h *SupportHandler) GetTicket(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	ticket, ok := h.loadTicket(c, userID)
+	if !ok {
+		return
+	}
+	h.signAttachments(c.Request.Context(), &ticket)
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket})
+}
+
+// ReplyToTicket adds the caller's reply to their ticket and hands it back
+// to support, reopening it if it was solved
+func (h *SupportHandler) ReplyToTicket(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req SupportReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ticket, ok := h.loadTicket(c, userID)
+	if !ok {
+		return
+	}
+
+	reply := models.SupportReply{TicketID: ticket.ID, Body: req.Body}
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&reply).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ticket).Updates(map[string]interface{}{"status": "open", "solved_at": nil}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reply"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"reply": reply})
+}
+
+// signAttachments fills in expiring links to the ticket's screenshots
+func (h *SupportHandler) signAttachments(ctx context.Context, ticket *models.SupportTicket) {
+	for _, key := range ticket.Attachments {
+		url, err := h.storage.GeneratePresignedURL(ctx, key, attachmentURLTTL)
+		if err != nil {
+			log.Printf("Failed to sign attachment of ticket %d: %v", ticket.ID, err)
+			continue
+		}
+		ticket.AttachmentURLs = append(ticket.AttachmentURLs, url)
+	}
+}
+
+// discardAttachments removes stored screenshots whose ticket was never
+// created
+func (h *SupportHandler) discardAttachments(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := h.storage.DeleteObject(ctx, key); err != nil {
+			log.Printf("Failed to delete orphaned attachment %s: %v", key, err)
+		}
+	}
+}
+
+// loadTicket loads a ticket with its replies, the caller's own unless userID
+// is nil, and responds with 404 when there is none
+func (h *SupportHandler) loadTicket(c *gin.Context, userID interface{}) (models.SupportTicket, bool) {
+	var ticket models.SupportTicket
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		return ticket, false
+	}
+
+	query := h.db.WithContext(c.Request.Context()).
+		Preload("Replies", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("id = ?", ticketID)
+	if userID != nil {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return ticket, false
+	}
+	return ticket, true
+}
+
+// GetSupportTickets lists tickets for support, those waiting on support
+// oldest first unless a status is given
+func (h *SupportHandler) GetSupportTickets(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	status := c.DefaultQuery("status", "open")
+	query := h.db.WithContext(ctx).Model(&models.SupportTicket{}).Where("status = ?", status)
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var tickets []models.SupportTicket
+	if err := query.Order("updated_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&tickets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tickets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tickets": tickets,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetSupportTicket returns any ticket with its replies
+func (h *SupportHandler) GetSupportTicket(c *gin.Context) {
+	ticket, ok := h.loadTicket(c, nil)
+	if !ok {
+		return
+	}
+	h.signAttachments(c.Request.Context(), &ticket)
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket})
+}
+
+// AnswerTicket adds a support reply, which leaves the ticket waiting on the
+// user or solves it, and notifies the user
+func (h *SupportHandler) AnswerTicket(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+
+	var req AdminSupportReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := req.Status
+	if status == "" {
+		status = "pending"
+	}
+
+	ticket, ok := h.loadTicket(c, nil)
+	if !ok {
+		return
+	}
+
+	author := adminID.(uint)
+	reply := models.SupportReply{TicketID: ticket.ID, AdminID: &author, Body: req.Body}
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&reply).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ticket).Updates(ticketStatusUpdates(status)).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reply"})
+		return
+	}
+
+	h.notifyTicketReply(ctx, ticket, status)
+	c.JSON(http.StatusCreated, gin.H{"reply": reply, "status": status})
+}
+
+// UpdateTicketStatus moves a ticket between open, pending, and solved
+// without replying
+func (h *SupportHandler) UpdateTicketStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req TicketStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ticket, ok := h.loadTicket(c, nil)
+	if !ok {
+		return
+	}
+
+	if err := h.db.WithContext(ctx).Model(&ticket).Updates(ticketStatusUpdates(req.Status)).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ticket"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket})
+}
+
+func ticketStatusUpdates(status string) map[string]interface{} {
+	updates := map[string]interface{}{"status": status, "solved_at": nil}
+	if status == "solved" {
+		updates["solved_at"] = time.Now()
+	}
+	return updates
+}
+
+func (h *SupportHandler) notifyTicketReply(ctx context.Context, ticket models.SupportTicket, status string) {
+	body := "Support replied to your request. Tap to read it."
+	if status == "solved" {
+		body = "Support replied and marked your request as solved. Reply if you still need help."
+	}
+	data, _ := json.Marshal(gin.H{"ticket_id": ticket.ID, "status": status})
+
+	notification := models.Notification{
+		UserID: ticket.UserID,
+		Type:   "support_reply",
+		Title:  "Support replied",
+		Body:   body,
+		Data:   string(data),
+	}
+	if err := h.db.WithContext(ctx).Create(&notification).Error; err != nil {
+		return
+	}
+	h.notifier.Dispatch(services.Notice{Notification: notification, Text: "Support replied to your request. Open the app to read it."})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// More than one swipe a second, kept up for half a minute, is faster than
+	// anyone reads a profile
+	swipeVelocityLimit  = 30
+	swipeVelocityWindow = 30 * time.Second
+
+	// Each time a user goes over the limit within an hour they wait twice as
+	// long, and from the third time they must pass a challenge
+	swipeThrottleBase   = 5 * time.Second
+	swipeThrottleMax    = 5 * time.Minute
+	swipeChallengeLevel = 3
+
+	swipeChallengeTTL          = 10 * time.Minute
+	swipeChallengeAttemptLimit = 10
+)
+
+type SwipeChallengeRequest struct {
+	Token  string `json:"token,omitempty"`  // from the captcha widget
+	Answer string `json:"answer,omitempty"` // to the built-in question
+}
+
+func swipeChallengeKey(userID uint) string {
+	return fmt.Sprintf("swipe_challenge:%d", userID)
+}
+
+// swipeThrottleFor returns how long swipes are held back at a throttle level
+// and whether a challenge is required
+func swipeThrottleFor(level int) (time.Duration, bool) {
+	// Past a few levels the delay is capped anyway, and the shift would overflow
+	delay := swipeThrottleMax
+	if level <= 7 {
+		delay = min(swipeThrottleBase<<(level-1), swipeThrottleMax)
+	}
+	return delay, level >= swipeChallengeLevel
+}
+
+// respondIfSwipingTooFast holds back likes and passes from users swiping
+// faster than a person can, and reports whether it did. Redis errors let
+// the swipe through.
+func (h *MatchHandler) respondIfSwipingTooFast(ctx context.Context, c *gin.Context, userID, targetID uint) bool {
+	throttle, err := h.redis.GetSwipeThrottle(ctx, userID)
+	if err != nil {
+		return false
+	}
+	if throttle.Challenge {
+		respondSwipeChallenge(c)
+		return true
+	}
+	if wait := time.Until(throttle.Until); wait > 0 {
+		respondSwipeThrottled(c, wait)
+		return true
+	}
+
+	count, err := h.redis.RecordSwipe(ctx, userID, targetID, swipeVelocityWindow)
+	if err != nil || count <= swipeVelocityLimit {
+		return false
+	}
+
+	level, err := h.redis.EscalateSwipeThrottle(ctx, userID, swipeThrottleFor)
+	if err != nil {
+		return false
+	}
+	delay, challenge := swipeThrottleFor(level)
+	if level == swipeChallengeLevel {
+		h.flagSwipeVelocity(ctx, userID, count, level)
+	}
+	if challenge {
+		respondSwipeChallenge(c)
+	} else {
+		respondSwipeThrottled(c, delay)
+	}
+	return true
+}
+
+func respondSwipeThrottled(c *gin.Context, wait time.Duration) {
+	seconds := int(wait.Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "You're swiping too fast. Take a moment to look at profiles.",
+		"code":        "SWIPING_TOO_FAST",
+		"retry_after": seconds,
+	})
+}
+
+func respondSwipeChallenge(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": "Please confirm you're not a bot to keep swiping",
+		"code":  "CHALLENGE_REQUIRED",
+	})
+}
+
+// flagSwipeVelocity records the account for the anti-abuse team and tells
+// the admin dashboard
+func (h *MatchHandler) flagSwipeVelocity(ctx context.Context, userID uint, count int64, level int) {
+	flag := models.AbuseFlag{
+		UserID:  userID,
+		Kind:    "swipe_velocity",
+		Details: fmt.Sprintf("%d swipes in %s, throttled %d times within an hour", count, swipeVelocityWindow, level),
+	}
+	if err := h.db.WithContext(ctx).Create(&flag).Error; err != nil {
+		log.Printf("Failed to flag user %d for swipe velocity: %v", userID, err)
+		return
+	}
+	services.PublishOpsEvent(ctx, h.redis, services.OpsAbuseFlagged, gin.H{
+		"flag_id": flag.ID,
+		"user_id": userID,
+		"kind":    flag.Kind,
+	})
+}
+
+// GetSwipeChallenge says whether the caller must pass a challenge before
+// swiping again, and which: the configured captcha, or a built-in question
+// when there is none
+func (h *MatchHandler) GetSwipeChallenge(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	throttle, err := h.redis.GetSwipeThrottle(ctx, userID.(uint))
+	if err != nil || !throttle.Challenge {
+		c.JSON(http.StatusOK, gin.H{"required": false})
+		return
+	}
+
+	if h.cfg.CaptchaVerifyURL != "" {
+		c.JSON(http.StatusOK, gin.H{"required": true, "type": "captcha", "site_key": h.cfg.CaptchaSiteKey})
+		return
+	}
+
+	a, b := rand.Intn(9)+1, rand.Intn(9)+1
+	if err := h.redis.Set(ctx, swipeChallengeKey(userID.(uint)), strconv.Itoa(a+b), swipeChallengeTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create challenge"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"required": true, "type": "question", "question": fmt.Sprintf("What is %d + %d?", a, b)})
+}
+
+// SolveSwipeChallenge checks the caller's captcha token or answer and lifts
+// their swipe throttle when it is right
+func (h *MatchHandler) SolveSwipeChallenge(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req SwipeChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !allowRequest(ctx, h.redis, fmt.Sprintf("ratelimit:swipe_challenge:%d", userID), swipeChallengeAttemptLimit, swipeChallengeTTL) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, please try again later"})
+		return
+	}
+
+	passed, err := h.captcha.Verify(ctx, req.Token, c.ClientIP())
+	if errors.Is(err, services.ErrCaptchaDisabled) {
+		answer, getErr := h.redis.GetDel(ctx, swipeChallengeKey(userID.(uint)))
+		passed, err = getErr == nil && answer == req.Answer, nil
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Couldn't check the challenge, please try again"})
+		return
+	}
+	if !passed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge failed", "code": "CHALLENGE_FAILED"})
+		return
+	}
+
+	if err := h.redis.ClearSwipeThrottle(ctx, userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lift the limit"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Thanks! You can keep swiping."})
+}
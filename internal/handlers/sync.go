@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxDeviceIDLength = 64
+	syncHistoryWindow = 30 * 24 * time.Hour // history a new device starts with
+	// Timestamps come from the app servers, so a write can commit slightly
+	// after a later-stamped one. Changes younger than this wait for the next
+	// sync instead of risking a cursor that skips them.
+	syncSettleDelay = 2 * time.Second
+)
+
+var errInvalidSyncCursor = errors.New("invalid sync cursor")
+
+// syncCursor is the change time and message ID of the last change a device
+// has seen. Changes are ordered by both, so equal timestamps aren't skipped.
+type syncCursor struct {
+	At        time.Time
+	MessageID uint
+}
+
+func (c syncCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.At.UnixMicro(), c.MessageID)))
+}
+
+func (c syncCursor) before(at time.Time, messageID uint) bool {
+	return c.At.Before(at) || (c.At.Equal(at) && c.MessageID < messageID)
+}
+
+func parseSyncCursor(value string) (syncCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return syncCursor{}, errInvalidSyncCursor
+	}
+	at, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return syncCursor{}, errInvalidSyncCursor
+	}
+	micros, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return syncCursor{}, errInvalidSyncCursor
+	}
+	messageID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return syncCursor{}, errInvalidSyncCursor
+	}
+	return syncCursor{At: time.UnixMicro(micros), MessageID: uint(messageID)}, nil
+}
+
+// MessageSyncPage is the message changes in the user's conversations since a
+// cursor, oldest first
+type MessageSyncPage struct {
+	New     []MessageResponse         `json:"new"`
+	Updated []MessageResponse         `json:"updated"` // e.g. read since the cursor
+	Deleted []models.MessageTombstone `json:"deleted"`
+	Cursor  string                    `json:"cursor"`
+	HasMore bool                      `json:"has_more"`
+	// Reset means the cursor was older than deletions are kept, so the device
+	// should drop its local messages and apply this page from scratch
+	Reset bool `json:"reset,omitempty"`
+}
+
+// SyncMessages returns what changed in the caller's conversations since the
+// device's cursor: new and updated messages and deletions. The cursor is the
+// one passed, else the one this device last got, else the start of recent
+// history for a new device. Each page's cursor is saved for the device.
+func (h *MessageHandler) SyncMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	deviceID := c.Query("device_id")
+	if deviceID == "" || len(deviceID) > maxDeviceIDLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "device_id is required (at most 64 characters)"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	var cursor *syncCursor
+	if value := c.Query("cursor"); value != "" {
+		parsed, err := parseSyncCursor(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cursor = &parsed
+	} else {
+		var device models.SyncDevice
+		if err := h.db.WithContext(ctx).Where("user_id = ? AND device_id = ?", userID, deviceID).First(&device).Error; err == nil {
+			if parsed, err := parseSyncCursor(device.Cursor); err == nil {
+				cursor = &parsed
+			}
+		}
+	}
+
+	page, err := h.syncPage(ctx, userID.(uint), cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync messages"})
+		return
+	}
+	if err := h.saveSyncCursor(ctx, userID.(uint), deviceID, page.Cursor); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save sync cursor"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// syncPage collects up to limit changes after cursor, or the recent history
+// when cursor is nil
+func (h *MessageHandler) syncPage(ctx context.Context, userID uint, cursor *syncCursor, limit int) (MessageSyncPage, error) {
+	db := h.db.WithContext(ctx)
+	now := time.Now()
+	page := MessageSyncPage{
+		New:     []MessageResponse{},
+		Updated: []MessageResponse{},
+		Deleted: []models.MessageTombstone{},
+	}
+
+	since := syncCursor{At: now.Add(-syncHistoryWindow)}
+	if cursor != nil {
+		since = *cursor
+		if since.At.Before(now.Add(-models.MessageTombstoneRetention)) {
+			since = syncCursor{At: now.Add(-syncHistoryWindow)}
+			page.Reset = true
+		}
+	}
+	until := now.Add(-syncSettleDelay)
+
+	conversationIDs := db.Table("conversations").
+		Joins("JOIN matches ON conversations.match_id = matches.id").
+		Where("(matches.user1_id = ? OR matches.user2_id = ?) AND conversations.is_active = ?", userID, userID, true).
+		Select("conversations.id")
+
+	// Fetch one extra of each to know whether more remain
+	var messages []models.Message
+	if err := db.Where("conversation_id IN (?)", conversationIDs).Scopes(visibleMessages(userID)).
+		Where("(updated_at, id) > (?, ?) AND updated_at < ?", since.At, since.MessageID, until).
+		Order("updated_at ASC, id ASC").Limit(limit + 1).
+		Preload("Sender").
+		Find(&messages).Error; err != nil {
+		return page, err
+	}
+
+	var tombstones []models.MessageTombstone
+	if err := db.Where("conversation_id IN (?)", conversationIDs).
+		Where("(deleted_at, message_id) > (?, ?) AND deleted_at < ?", since.At, since.MessageID, until).
+		Order("deleted_at ASC, message_id ASC").Limit(limit + 1).
+		Find(&tombstones).Error; err != nil {
+		return page, err
+	}
+
+	// Merge both change streams in order and keep the first limit
+	var changed []models.Message
+	last := since
+	m, t := 0, 0
+	for m+t < limit && (m < len(messages) || t < len(tombstones)) {
+		if t == len(tombstones) || (m < len(messages) && !syncCursorAt(tombstones[t].DeletedAt, tombstones[t].MessageID).before(messages[m].UpdatedAt, messages[m].ID)) {
+			changed = append(changed, messages[m])
+			last = syncCursorAt(messages[m].UpdatedAt, messages[m].ID)
+			m++
+		} else {
+			page.Deleted = append(page.Deleted, tombstones[t])
+			last = syncCursorAt(tombstones[t].DeletedAt, tombstones[t].MessageID)
+			t++
+		}
+	}
+	page.HasMore = m < len(messages) || t < len(tombstones)
+	page.Cursor = last.String()
+
+	if len(changed) == 0 {
+		return page, nil
+	}
+
+	// Contact details stay blurred until a conversation is long enough
+	conversationSizes := make(map[uint]int64)
+	var guarded []uint
+	var pollMessageIDs, suggestionMessageIDs []uint
+	for _, msg := range changed {
+		if msg.HasContactInfo {
+			guarded = append(guarded, msg.ConversationID)
+		}
+		switch msg.MessageType {
+		case "poll":
+			pollMessageIDs = append(pollMessageIDs, msg.ID)
+		case "date_suggestion":
+			suggestionMessageIDs = append(suggestionMessageIDs, msg.ID)
+		}
+	}
+	if len(guarded) > 0 {
+		var sizes []struct {
+			ConversationID uint
+			Count          int64
+		}
+		if err := db.Model(&models.Message{}).Select("conversation_id, COUNT(*) AS count").
+			Where("conversation_id IN ?", guarded).Group("conversation_id").
+			Scan(&sizes).Error; err != nil {
+			return page, err
+		}
+		for _, size := range sizes {
+			conversationSizes[size.ConversationID] = size.Count
+		}
+	}
+	polls := h.loadPolls(ctx, pollMessageIDs, userID)
+	suggestions := h.loadDateSuggestions(ctx, suggestionMessageIDs)
+	viewer := loadViewer(db, userID)
+
+	for _, msg := range changed {
+		response := MessageResponse{
+			ID:             msg.ID,
+			ConversationID: msg.ConversationID,
+			SenderID:       msg.SenderID,
+			Content:        h.guardedContent(msg, userID, conversationSizes[msg.ConversationID]),
+			MessageType:    msg.MessageType,
+			SystemKind:     msg.SystemKind,
+			IsRead:         msg.IsRead,
+			ReadAt:         msg.ReadAt,
+			CreatedAt:      msg.CreatedAt,
+			Sender:         newPublicUser(msg.Sender, viewer),
+			HasContactInfo: msg.HasContactInfo,
+			IsEncrypted:    msg.IsEncrypted,
+			Poll:           polls[msg.ID],
+			DateSuggestion: suggestions[msg.ID],
+		}
+		if since.before(msg.CreatedAt, msg.ID) {
+			page.New = append(page.New, response)
+		} else {
+			page.Updated = append(page.Updated, response)
+		}
+	}
+
+	return page, nil
+}
+
+func syncCursorAt(at time.Time, messageID uint) syncCursor {
+	return syncCursor{At: at, MessageID: messageID}
+}
+
+func (h *MessageHandler) saveSyncCursor(ctx context.Context, userID uint, deviceID, cursor string) error {
+	device := models.SyncDevice{
+		UserID:       userID,
+		DeviceID:     deviceID,
+		Cursor:       cursor,
+		LastSyncedAt: time.Now(),
+	}
+	return h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cursor", "last_synced_at"}),
+	}).Create(&device).Error
+}
+
+// ConnectWebSocket opens the caller's WebSocket. A device reconnecting with
+// device_id and cursor first gets a sync frame with what it missed, the same
+// page GET /sync/messages would return; if has_more is set it fetches the
+// rest over REST. A client reconnecting after a drop passes the resume_token
+// from its last session frame and the last event_id it got, and has the
+// events it missed replayed instead.
+func (h *MessageHandler) ConnectWebSocket(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	deviceID := c.Query("device_id")
+	if len(deviceID) > maxDeviceIDLength {
+		deviceID = ""
+	}
+
+	var initial [][]byte
+	if deviceID != "" {
+		if cursor, err := parseSyncCursor(c.Query("cursor")); err == nil {
+			if page, err := h.syncPage(ctx, userID.(uint), &cursor, 100); err == nil {
+				frame, err := json.Marshal(struct {
+					Type string `json:"type"`
+					MessageSyncPage
+				}{"sync", page})
+				if err == nil && h.saveSyncCursor(ctx, userID.(uint), deviceID, page.Cursor) == nil {
+					initial = append(initial, frame)
+				}
+			}
+		}
+	}
+
+	resume := websocket.ResumeRequest{Token: c.Query("resume_token")}
+	resume.LastEventID, _ = strconv.ParseInt(c.Query("last_event_id"), 10, 64)
+
+	websocket.HandleWebSocket(h.hub, c, deviceID, resume, initial...)
+}
+
+// notifySync tells every connected device of both users in the conversation
+// that there are changes to sync, so a user's other devices catch up with
+// messages sent or read on this one
+func (h *MessageHandler) notifySync(ctx context.Context, conversationID uint) {
+	var match models.Match
+	if err := h.db.WithContext(ctx).Joins("JOIN conversations ON conversations.match_id = matches.id").
+		Where("conversations.id = ?", conversationID).First(&match).Error; err != nil {
+		return
+	}
+	if frame, err := json.Marshal(gin.H{"type": "sync_available", "conversation_id": conversationID}); err == nil {
+		h.hub.BroadcastToUsers([]uint{match.User1ID, match.User2ID}, frame)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SystemMessageRequest struct {
+	Content   string `json:"content" binding:"required,max=500"`
+	IsEnabled bool   `json:"is_enabled"`
+}
+
+// SystemMessageSetting is one kind of system message as admins see it: the
+// text in use and whether it was changed from the built-in one
+type SystemMessageSetting struct {
+	Kind           string `json:"kind"`
+	Content        string `json:"content"`
+	DefaultContent string `json:"default_content"`
+	IsEnabled      bool   `json:"is_enabled"`
+	IsCustomized   bool   `json:"is_customized"`
+}
+
+// GetSystemMessages lists every kind of system message with its current text
+func (h *AdminHandler) GetSystemMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var templates []models.SystemMessageTemplate
+	if err := h.db.WithContext(ctx).Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch system messages"})
+		return
+	}
+	overrides := make(map[string]models.SystemMessageTemplate, len(templates))
+	for _, template := range templates {
+		overrides[template.Kind] = template
+	}
+
+	settings := make([]SystemMessageSetting, 0, len(services.DefaultSystemMessages))
+	for kind, content := range services.DefaultSystemMessages {
+		setting := SystemMessageSetting{Kind: kind, Content: content, DefaultContent: content, IsEnabled: true}
+		if override, ok := overrides[kind]; ok {
+			setting.Content = override.Content
+			setting.IsEnabled = override.IsEnabled
+			setting.IsCustomized = true
+		}
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Kind < settings[j].Kind })
+
+	c.JSON(http.StatusOK, gin.H{"system_messages": settings})
+}
+
+// UpdateSystemMessage sets the text of one kind of system message, or turns
+// it off. Placeholders such as {hours} are filled in where the kind has them.
+func (h *AdminHandler) UpdateSystemMessage(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	kind := c.Param("kind")
+
+	if _, ok := services.DefaultSystemMessages[kind]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown system message"})
+		return
+	}
+
+	var req SystemMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := models.SystemMessageTemplate{
+		Kind:      kind,
+		Content:   req.Content,
+		IsEnabled: req.IsEnabled,
+		UpdatedBy: adminID.(uint),
+	}
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&template).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "system_message_updated", "system_message", 0, fmt.Sprintf("%s enabled=%t", kind, req.IsEnabled))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update system message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"system_message": template})
+}
+
+// ResetSystemMessage goes back to the built-in text for one kind
+func (h *AdminHandler) ResetSystemMessage(c *gin.Context) {
+	ctx := c.Request.Context()
+	kind := c.Param("kind")
+
+	if _, ok := services.DefaultSystemMessages[kind]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown system message"})
+		return
+	}
+
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kind = ?", kind).Delete(&models.SystemMessageTemplate{}).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "system_message_reset", "system_message", 0, kind)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset system message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "System message reset"})
+}
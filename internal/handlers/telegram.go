@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const telegramLinkCodeTTL = 10 * time.Minute
+
+const telegramHelp = `Commands:
+/stats - your likes, views and match rate this week
+/pause - hide your profile from discovery
+/resume - show your profile again
+/stop - stop messages and unlink this chat`
+
+func telegramLinkKey(code string) string {
+	return "telegram:link:" + code
+}
+
+type TelegramHandler struct {
+	db    *gorm.DB
+	redis *redis.Client
+	cfg   *config.Config
+	bot   services.TelegramBot
+}
+
+func NewTelegramHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, bot services.TelegramBot) *TelegramHandler {
+	return &TelegramHandler{
+		db:    db,
+		redis: redis,
+		cfg:   cfg,
+		bot:   bot,
+	}
+}
+
+// CreateTelegramLink returns a t.me link carrying a one-time code. Opening it
+// and pressing Start sends the code to the bot, which links that chat to the
+// caller's account.
+func (h *TelegramHandler) CreateTelegramLink(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	if h.bot.Username() == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Telegram is not available"})
+		return
+	}
+
+	// Start parameters may only hold letters, digits, _ and -
+	code := strings.ReplaceAll(uuid.New().String(), "-", "")
+	if err := h.redis.Set(ctx, telegramLinkKey(code), userID.(uint), telegramLinkCodeTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        fmt.Sprintf("https://t.me/%s?start=%s", h.bot.Username(), code),
+		"code":       code,
+		"expires_at": time.Now().Add(telegramLinkCodeTTL),
+	})
+}
+
+func (h *TelegramHandler) GetTelegramLink(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var link models.TelegramLink
+	err := h.db.WithContext(ctx).Where("user_id = ?", userID).First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"linked": false})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch Telegram link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"linked": true, "link": link})
+}
+
+func (h *TelegramHandler) UnlinkTelegram(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var link models.TelegramLink
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Telegram is not linked"})
+		return
+	}
+	if err := h.db.WithContext(ctx).Delete(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink Telegram"})
+		return
+	}
+
+	if err := h.bot.SendMessage(ctx, link.ChatID, "This chat was unlinked from your account. You won't get messages here anymore."); err != nil {
+		log.Printf("Failed to send Telegram unlink notice to user %d: %v", link.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram unlinked"})
+}
+
+// Webhook receives bot updates from Telegram. Commands are answered in the
+// chat, and the webhook succeeds even for ones it can't act on, since
+// Telegram redelivers any update that gets an error.
+func (h *TelegramHandler) Webhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	secret := h.cfg.TelegramWebhookSecret
+	token := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook secret"})
+		return
+	}
+
+	var update services.TelegramUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only private chats are linked, so group messages are ignored
+	if msg := update.Message; msg != nil && msg.Chat.Type == "private" && strings.HasPrefix(msg.Text, "/") {
+		if err := h.bot.SendMessage(ctx, msg.Chat.ID, h.handleCommand(ctx, msg)); err != nil {
+			log.Printf("Failed to answer Telegram chat %d: %v", msg.Chat.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// handleCommand runs a bot command and returns the reply
+func (h *TelegramHandler) handleCommand(ctx context.Context, msg *services.TelegramMessage) string {
+	command, arg, _ := strings.Cut(strings.TrimSpace(msg.Text), " ")
+	command, _, _ = strings.Cut(command, "@") // e.g. /stats@SomeBot
+
+	if command == "/start" && strings.TrimSpace(arg) != "" {
+		return h.linkChat(ctx, msg, strings.TrimSpace(arg))
+	}
+
+	var link models.TelegramLink
+	if err := h.db.WithContext(ctx).Where("chat_id = ?", msg.Chat.ID).First(&link).Error; err != nil {
+		return "This chat isn't linked to an account yet. Open Settings > Telegram in the app to link it."
+	}
+
+	switch command {
+	case "/stats":
+		return h.statsReply(ctx, link.UserID)
+	case "/pause":
+		return h.setPaused(ctx, link.UserID, true)
+	case "/resume":
+		return h.setPaused(ctx, link.UserID, false)
+	case "/stop":
+		if err := h.db.WithContext(ctx).Delete(&link).Error; err != nil {
+			return "Something went wrong. Please try again."
+		}
+		return "This chat was unlinked. You won't get messages here anymore."
+	default:
+		return telegramHelp
+	}
+}
+
+// linkChat redeems a code from CreateTelegramLink. A chat belongs to one
+// account and an account to one chat, so older links on either side go.
+func (h *TelegramHandler) linkChat(ctx context.Context, msg *services.TelegramMessage, code string) string {
+	value, err := h.redis.GetDel(ctx, telegramLinkKey(code))
+	if err != nil {
+		return "This link has expired. Create a new one in the app under Settings > Telegram."
+	}
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return "This link has expired. Create a new one in the app under Settings > Telegram."
+	}
+
+	link := models.TelegramLink{UserID: uint(userID), ChatID: msg.Chat.ID}
+	if msg.From != nil {
+		link.TelegramUsername = msg.From.Username
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? OR chat_id = ?", link.UserID, link.ChatID).Delete(&models.TelegramLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&link).Error
+	})
+	if err != nil {
+		return "Something went wrong linking your account. Please try again."
+	}
+
+	return "Your account is linked. You'll get verification codes, new matches, and message alerts here.\n\n" + telegramHelp
+}
+
+func (h *TelegramHandler) statsReply(ctx context.Context, userID uint) string {
+	stats, err := loadUserStats(ctx, h.db, h.redis, userID)
+	if err != nil {
+		return "Couldn't load your stats right now. Please try again later."
+	}
+
+	reply := fmt.Sprintf("This week:\nLikes sent: %d\nLikes received: %d\nProfile views: %d\n\nMatch rate (30 days): %.0f%%",
+		stats.LikesSentThisWeek, stats.LikesReceivedThisWeek, stats.ProfileViewsThisWeek, stats.MatchRate*100)
+	if stats.BestPhoto != nil {
+		reply += fmt.Sprintf("\nYour most liked photo got %d likes", stats.BestPhoto.Likes)
+	}
+	return reply
+}
+
+func (h *TelegramHandler) setPaused(ctx context.Context, userID uint, paused bool) string {
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		return "Couldn't find your account."
+	}
+
+	user.IsPaused = paused
+	user.PausedUntil = nil
+	if err := saveUserVersioned(h.db.WithContext(ctx), &user, nil); err != nil {
+		return "Something went wrong. Please try again."
+	}
+
+	if paused {
+		return "Your account is paused. You're hidden from discovery, and your matches and chats stay as they are. Send /resume to show your profile again."
+	}
+	return "Your account is active again and you'll show up in discovery."
+}
+
+// sendTelegramOTP sends a verification code to the user's linked Telegram
+// chat and reports whether it went out
+func sendTelegramOTP(ctx context.Context, db *gorm.DB, bot services.TelegramBot, userID uint, code string, expiry time.Duration) bool {
+	var link models.TelegramLink
+	if err := db.WithContext(ctx).Where("user_id = ?", userID).First(&link).Error; err != nil {
+		return false
+	}
+
+	text := fmt.Sprintf("Your verification code is %s. It expires in %d minutes. Never share it with anyone.", code, int(expiry.Minutes()))
+	if err := bot.SendMessage(ctx, link.ChatID, text); err != nil {
+		log.Printf("Failed to send Telegram OTP to user %d: %v", userID, err)
+		return false
+	}
+	return true
+}
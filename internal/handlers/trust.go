@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HeldMessage is a message waiting for review, with its sender's trust
+type HeldMessage struct {
+	ID             uint      `json:"id"`
+	ConversationID uint      `json:"conversation_id"`
+	SenderID       uint      `json:"sender_id"`
+	RecipientID    uint      `json:"recipient_id"`
+	Content        string    `json:"content"`
+	MessageType    string    `json:"message_type"`
+	HasContactInfo bool      `json:"has_contact_info"`
+	IsEncrypted    bool      `json:"is_encrypted"` // content is ciphertext
+	SenderTrust    *int      `json:"sender_trust"`
+	HeldAt         time.Time `json:"held_at"`
+}
+
+// trustLevel returns the user's stored trust level
+func trustLevel(db *gorm.DB, userID uint) string {
+	var user models.User
+	if err := db.Select("id", "trust_level").Where("id = ?", userID).First(&user).Error; err != nil {
+		return services.TrustNormal
+	}
+	return user.TrustLevel
+}
+
+// holdsMessages reports whether the user's messages are held for review
+func (h *MessageHandler) holdsMessages(ctx context.Context, userID uint) bool {
+	return h.cfg.LowTrustHoldMessages && trustLevel(h.db.WithContext(ctx), userID) == services.TrustLow
+}
+
+// visibleMessages leaves out other people's messages held for review
+func visibleMessages(viewerID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("held_at IS NULL OR sender_id = ?", viewerID)
+	}
+}
+
+// respondIfOutOfLikes stops users at their daily like allowance, and reports
+// whether it did: FREE_DAILY_LIKES without unlimited_likes, and
+// LOW_TRUST_DAILY_LIKES for low-trust users whatever else they have. Days
+// start at midnight in the app's time zone.
+func (h *MatchHandler) respondIfOutOfLikes(ctx context.Context, c *gin.Context, userID uint) bool {
+	db := h.db.WithContext(ctx)
+	limit := int64(-1)
+	if h.cfg.FreeDailyLikes > 0 {
+		var user models.User
+		if err := db.Select("id", "gender", "location", "is_premium").Where("id = ?", userID).First(&user).Error; err == nil &&
+			!hasPremiumFeature(db, h.cfg, user, featureUnlimitedLikes) {
+			limit = h.cfg.FreeDailyLikes
+		}
+	}
+	if trustLevel(db, userID) == services.TrustLow && (limit < 0 || h.cfg.LowTrustDailyLikes < limit) {
+		limit = h.cfg.LowTrustDailyLikes
+	}
+	if limit < 0 {
+		return false
+	}
+
+	now := time.Now().In(h.cfg.TimeZone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, h.cfg.TimeZone)
+	var likes int64
+	db.Model(&models.Like{}).Where("liker_id = ? AND created_at >= ?", userID, today).Count(&likes)
+	if likes < limit {
+		return false
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":     "You've used today's likes",
+		"code":      "DAILY_LIKE_LIMIT",
+		"limit":     limit,
+		"resets_at": today.AddDate(0, 0, 1),
+	})
+	return true
+}
+
+// GetUserTrust explains a user's trust score: what is stored, and what it
+// would be now with each signal's value, weight and points
+func (h *AdminHandler) GetUserTrust(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	trust, err := services.ComputeTrust(ctx, h.db, h.cfg, user, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute trust score"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id": user.ID,
+		"stored": gin.H{
+			"score":       user.TrustScore,
+			"level":       user.TrustLevel,
+			"computed_at": user.TrustComputedAt,
+		},
+		"current":    trust,
+		"thresholds": h.cfg.TrustThresholds,
+		"throttles": gin.H{
+			"daily_likes":   h.cfg.LowTrustDailyLikes,
+			"hold_messages": h.cfg.LowTrustHoldMessages,
+		},
+	})
+}
+
+// RecomputeUserTrust scores a user again straight away, such as after
+// clearing a strike, instead of waiting for the daily job
+func (h *AdminHandler) RecomputeUserTrust(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	now := time.Now()
+	trust, err := services.ComputeTrust(ctx, h.db, h.cfg, user, now)
+	if err == nil {
+		err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := services.SaveTrust(ctx, tx, user.ID, trust, now); err != nil {
+				return err
+			}
+			return h.logAdminAction(tx, c, "trust_recomputed", "user", user.ID, trust.Level+" "+strconv.Itoa(trust.Score))
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute trust score"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trust": trust})
+}
+
+// GetHeldMessages lists messages held for review, oldest first
+func (h *MessageHandler) GetHeldMessages(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var held []HeldMessage
+	if err := h.db.WithContext(ctx).Table("messages").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Joins("JOIN matches ON matches.id = conversations.match_id").
+		Joins("JOIN users ON users.id = messages.sender_id").
+		Where("messages.held_at IS NOT NULL AND messages.deleted_at IS NULL").
+		Select("messages.id, messages.conversation_id, messages.sender_id, messages.content, messages.message_type, " +
+			"messages.has_contact_info, messages.is_encrypted, messages.held_at, users.trust_score AS sender_trust, " +
+			"CASE WHEN matches.user1_id = messages.sender_id THEN matches.user2_id ELSE matches.user1_id END AS recipient_id").
+		Order("messages.held_at ASC").Limit(limit).
+		Scan(&held).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch held messages"})
+		return
+	}
+
+	var total int64
+	h.db.WithContext(ctx).Model(&models.Message{}).Where("held_at IS NOT NULL").Count(&total)
+
+	c.JSON(http.StatusOK, gin.H{"messages": held, "total": total})
+}
+
+// ReleaseHeldMessage delivers a held message to its recipient
+func (h *MessageHandler) ReleaseHeldMessage(c *gin.Context) {
+	ctx := c.Request.Context()
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var message models.Message
+	if err := h.db.WithContext(ctx).Where("id = ? AND held_at IS NOT NULL", messageID).First(&message).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Held message not found"})
+		return
+	}
+
+	// Bumping updated_at lets the recipient's devices sync it
+	result := h.db.WithContext(ctx).Model(&message).Where("held_at IS NOT NULL").
+		Updates(map[string]interface{}{"held_at": nil, "updated_at": time.Now()})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release message"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Held message not found"})
+		return
+	}
+
+	var conversationSize int64
+	h.db.WithContext(ctx).Model(&models.Message{}).Where("conversation_id = ?", message.ConversationID).Count(&conversationSize)
+	h.deliverMessage(ctx, message, conversationSize)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message released"})
+}
+
+// RejectHeldMessage deletes a held message without delivering it. It leaves
+// a tombstone so the sender's devices drop it too.
+func (h *MessageHandler) RejectHeldMessage(c *gin.Context) {
+	ctx := c.Request.Context()
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var message models.Message
+		if err := tx.Where("id = ? AND held_at IS NOT NULL", messageID).First(&message).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&message).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.MessageTombstone{
+			MessageID:      message.ID,
+			ConversationID: message.ConversationID,
+			DeletedAt:      time.Now(),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Held message not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message rejected"})
+}
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// underageReportReason is the report reason that hides the reported user
+// immediately, before any admin has looked at the report
+const underageReportReason = "underage"
+
+type UnderageDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=clear confirm"`
+	Notes    string `json:"notes,omitempty" binding:"max=500"`
+}
+
+// UnderageReviewItem is a flagged account as shown to admins
+type UnderageReviewItem struct {
+	User         PendingPhotoUser             `json:"user"`
+	DateOfBirth  time.Time                    `json:"date_of_birth"`
+	Age          int                          `json:"age"`
+	IDVerified   bool                         `json:"id_verified"`
+	FlaggedAt    time.Time                    `json:"flagged_at"`
+	Reports      int64                        `json:"reports"`
+	Verification *models.IdentityVerification `json:"verification,omitempty"`
+}
+
+// flagUnderage hides a suspected minor from discovery and likes and asks them
+// to verify their identity. Already flagged users are left alone.
+func flagUnderage(tx *gorm.DB, userID uint) error {
+	result := tx.Model(&models.User{}).
+		Where("id = ? AND age_flagged_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"age_flagged_at": time.Now(),
+			"version":        gorm.Expr("version + 1"),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	notification := models.Notification{
+		UserID: userID,
+		Type:   "age_verification_required",
+		Title:  "Please verify your age",
+		Body:   "Your profile is hidden while we confirm you are 18 or older. Verify your identity to restore it.",
+		Data:   `{}`,
+	}
+	return tx.Create(&notification).Error
+}
+
+// GetUnderageFlags lists accounts flagged as possibly underage, oldest first,
+// with their latest identity verification
+func (h *AdminHandler) GetUnderageFlags(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.User{}).Where("age_flagged_at IS NOT NULL")
+
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	if err := query.Order("age_flagged_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch flagged users"})
+		return
+	}
+
+	now := time.Now()
+	items := make([]UnderageReviewItem, 0, len(users))
+	for _, user := range users {
+		item := UnderageReviewItem{
+			User: PendingPhotoUser{
+				ID:        user.ID,
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+				Gender:    user.Gender,
+				IsActive:  user.IsActive,
+				CreatedAt: user.CreatedAt,
+			},
+			DateOfBirth: user.DateOfBirth,
+			Age:         utils.CalculateAge(user.DateOfBirth, now),
+			IDVerified:  user.IDVerified,
+			FlaggedAt:   *user.AgeFlaggedAt,
+		}
+		h.db.WithContext(ctx).Model(&models.Report{}).
+			Where("reported_id = ? AND reason = ? AND status IN ?", user.ID, underageReportReason, []string{"pending", "reviewed"}).
+			Count(&item.Reports)
+
+		var verification models.IdentityVerification
+		if err := h.db.WithContext(ctx).Where("user_id = ?", user.ID).Order("created_at DESC").First(&verification).Error; err == nil {
+			item.Verification = &verification
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// DecideUnderageFlag resolves an underage flag. Clearing restores the profile
+// and requires a verified identity showing the user is 18 or older.
+// Confirming deletes the account and everything attached to it.
+func (h *AdminHandler) DecideUnderageFlag(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UnderageDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Preload("ProfilePhotos").
+		Where("id = ? AND age_flagged_at IS NOT NULL", userID).
+		First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flagged user not found"})
+		return
+	}
+
+	if req.Decision == "clear" {
+		if !user.IDVerified {
+			c.JSON(http.StatusConflict, gin.H{"error": "The user must verify their identity before the flag can be cleared"})
+			return
+		}
+		if utils.CalculateAge(user.DateOfBirth, time.Now()) < 18 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The user's date of birth shows they are under 18"})
+			return
+		}
+
+		err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(map[string]interface{}{
+				"age_flagged_at": nil,
+				"version":        gorm.Expr("version + 1"),
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Report{}).
+				Where("reported_id = ? AND reason = ? AND status IN ?", user.ID, underageReportReason, []string{"pending", "reviewed"}).
+				Update("status", "dismissed").Error; err != nil {
+				return err
+			}
+			notification := models.Notification{
+				UserID: user.ID,
+				Type:   "age_verified",
+				Title:  "Your profile is visible again",
+				Body:   "Thanks for verifying your age. Your profile is back in discovery.",
+				Data:   `{}`,
+			}
+			if err := tx.Create(&notification).Error; err != nil {
+				return err
+			}
+			return h.logAdminAction(tx, c, "underage_flag_cleared", "user", user.ID, req.Notes)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear flag"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Flag cleared and profile restored"})
+		return
+	}
+
+	conversationIDs := userConversationIDs(h.db.WithContext(ctx), user.ID)
+	var objectKeys []string
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		keys, err := purgeUserData(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		objectKeys = keys
+		return h.logAdminAction(tx, c, "underage_account_deleted", "user", user.ID, req.Notes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+	h.redis.ForgetConversations(ctx, conversationIDs...)
+
+	for _, photo := range user.ProfilePhotos {
+		if err := h.storage.DeleteFile(ctx, photo.URL); err != nil {
+			log.Printf("Failed to delete photo %d of user %d: %v", photo.ID, user.ID, err)
+		}
+	}
+	for _, key := range objectKeys {
+		if err := h.storage.DeleteObject(ctx, key); err != nil {
+			log.Printf("Failed to delete object %s of user %d: %v", key, user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account and data deleted"})
+}
+
+// purgeUserData permanently removes a user and every row that references
+// them, including whole conversations with their matches. It returns the
+// keys of the conversation archives and support attachments it removed,
+// which the caller deletes from storage, along with the profile photos,
+// after the transaction commits.
+func purgeUserData(tx *gorm.DB, userID uint) ([]string, error) {
+	var user models.User
+	if err := tx.Unscoped().Select("id", "email").Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	var matchIDs []uint
+	if err := tx.Unscoped().Model(&models.Match{}).
+		Where("user1_id = ? OR user2_id = ?", userID, userID).
+		Pluck("id", &matchIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var conversationIDs []uint
+	if len(matchIDs) > 0 {
+		if err := tx.Unscoped().Model(&models.Conversation{}).
+			Where("match_id IN ?", matchIDs).
+			Pluck("id", &conversationIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var objectKeys []string
+	if len(conversationIDs) > 0 {
+		if err := tx.Model(&models.ConversationArchive{}).Where("conversation_id IN ?", conversationIDs).
+			Pluck("object_key", &objectKeys).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var tickets []models.SupportTicket
+	if err := tx.Unscoped().Where("user_id = ?", userID).Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	ticketIDs := make([]uint, 0, len(tickets))
+	for _, ticket := range tickets {
+		ticketIDs = append(ticketIDs, ticket.ID)
+		objectKeys = append(objectKeys, ticket.Attachments...)
+	}
+	if len(ticketIDs) > 0 {
+		if err := tx.Where("ticket_id IN ?", ticketIDs).Delete(&models.SupportReply{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if len(conversationIDs) > 0 {
+		var pollIDs []uint
+		if err := tx.Model(&models.Poll{}).Where("conversation_id IN ?", conversationIDs).Pluck("id", &pollIDs).Error; err != nil {
+			return nil, err
+		}
+		if len(pollIDs) > 0 {
+			if err := tx.Where("poll_id IN ?", pollIDs).Delete(&models.PollVote{}).Error; err != nil {
+				return nil, err
+			}
+			if err := tx.Where("poll_id IN ?", pollIDs).Delete(&models.PollOption{}).Error; err != nil {
+				return nil, err
+			}
+			if err := tx.Where("id IN ?", pollIDs).Delete(&models.Poll{}).Error; err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Where("conversation_id IN ?", conversationIDs).Delete(&models.DateSuggestion{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("conversation_id IN ?", conversationIDs).Delete(&models.ConversationArchive{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("conversation_id IN ?", conversationIDs).Delete(&models.MessageTombstone{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Unscoped().Where("conversation_id IN ?", conversationIDs).Delete(&models.Message{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Unscoped().Where("id IN ?", conversationIDs).Delete(&models.Conversation{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if len(matchIDs) > 0 {
+		if err := tx.Where("match_id IN ?", matchIDs).Delete(&models.MatchMilestone{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("match_id IN ?", matchIDs).Delete(&models.MatchFeedback{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Unscoped().Where("id IN ?", matchIDs).Delete(&models.Match{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	deletes := []struct {
+		model interface{}
+		where string
+	}{
+		{&models.Like{}, "liker_id = ? OR liked_id = ?"},
+		{&models.Dislike{}, "disliker_id = ? OR disliked_id = ?"},
+		{&models.Unmatch{}, "user_id = ? OR other_user_id = ?"},
+		{&models.PrivateAlbumGrant{}, "owner_id = ? OR viewer_id = ?"},
+		{&models.PrivatePhotoAccess{}, "owner_id = ? OR viewer_id = ?"},
+		{&models.BlockedUser{}, "blocker_id = ? OR blocked_id = ?"},
+		{&models.Favorite{}, "user_id = ? OR favorite_id = ?"},
+		{&models.Compliment{}, "sender_id = ? OR recipient_id = ?"},
+		{&models.Report{}, "reporter_id = ? OR reported_id = ?"},
+		{&models.DatePlan{}, "user_id = ? OR match_user_id = ?"},
+		{&models.RoomMessage{}, "sender_id = ?"},
+		{&models.RoomMember{}, "user_id = ?"},
+		{&models.PollVote{}, "user_id = ?"},
+		{&models.PhoneBlock{}, "blocker_id = ?"},
+		{&models.ContactHash{}, "user_id = ?"},
+		{&models.TrustedContact{}, "user_id = ?"},
+		{&models.NotificationDelivery{}, "user_id = ?"},
+		{&models.Notification{}, "user_id = ?"},
+		{&models.NotificationPreference{}, "user_id = ?"},
+		{&models.MessageStats{}, "user_id = ?"},
+		{&models.Strike{}, "user_id = ?"},
+		{&models.Appeal{}, "user_id = ?"},
+		{&models.IdentityVerification{}, "user_id = ?"},
+		{&models.Feedback{}, "user_id = ?"},
+		{&models.UserActivity{}, "user_id = ?"},
+		{&models.UserSession{}, "user_id = ?"},
+		{&models.UserInterest{}, "user_id = ?"},
+		{&models.ProfilePrompt{}, "user_id = ?"},
+		{&models.ProfileBio{}, "user_id = ?"},
+		{&models.Badge{}, "user_id = ?"},
+		{&models.MatchFeedback{}, "user_id = ?"},
+		{&models.TelegramLink{}, "user_id = ?"},
+		{&models.SyncDevice{}, "user_id = ?"},
+		{&models.KeyBundle{}, "user_id = ?"},
+		{&models.OneTimePreKey{}, "user_id = ?"},
+		{&models.NameChangeRequest{}, "user_id = ?"},
+		{&models.Waitlist{}, "user_id = ?"},
+		{&models.WeeklyDigest{}, "user_id = ?"},
+		{&models.PushToken{}, "user_id = ?"},
+		{&models.AbuseFlag{}, "user_id = ?"},
+		{&models.SupportTicket{}, "user_id = ?"},
+		{&models.Device{}, "user_id = ?"},
+		{&models.OnboardingProgress{}, "user_id = ?"},
+		{&models.ProfilePhoto{}, "user_id = ?"},
+	}
+	for _, d := range deletes {
+		args := make([]interface{}, strings.Count(d.where, "?"))
+		for i := range args {
+			args[i] = userID
+		}
+		if err := tx.Unscoped().Where(d.where, args...).Delete(d.model).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("email = ?", user.Email).Delete(&models.OTP{}).Error; err != nil {
+		return nil, err
+	}
+
+	return objectKeys, tx.Unscoped().Delete(&models.User{}, userID).Error
+}
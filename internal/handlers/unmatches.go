@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// unmatchedUsersSQL selects the users kept apart from the viewer by an
+// unmatch whose cooldown isn't over. Its arguments are the viewer's ID three
+// times and the current time.
+const unmatchedUsersSQL = "SELECT CASE WHEN user_id = ? THEN other_user_id ELSE user_id END FROM unmatches " +
+	"WHERE (user_id = ? OR other_user_id = ?) AND (cooldown_until IS NULL OR cooldown_until > ?) " +
+	"AND (user_second_chance IS NULL OR other_second_chance IS NULL)"
+
+// unblockedUnmatches leaves out unmatches between users where either has
+// since blocked the other
+func unblockedUnmatches(db *gorm.DB) *gorm.DB {
+	return db.Where("NOT EXISTS (SELECT 1 FROM blocked_users WHERE " +
+		"(blocker_id = unmatches.user_id AND blocked_id = unmatches.other_user_id) OR " +
+		"(blocker_id = unmatches.other_user_id AND blocked_id = unmatches.user_id))")
+}
+
+// UnmatchResponse is an entry in the caller's unmatch history
+type UnmatchResponse struct {
+	ID             uint               `json:"id"`
+	User           PublicUserResponse `json:"user"`
+	UnmatchedByMe  bool               `json:"unmatched_by_me"`
+	MatchedAt      time.Time          `json:"matched_at"`
+	UnmatchedAt    time.Time          `json:"unmatched_at"`
+	CooldownUntil  *time.Time         `json:"cooldown_until,omitempty"` // nil when the pair is kept apart for good
+	SecondChanceAt time.Time          `json:"second_chance_at"`         // when either may opt in to a second chance
+	OptedIn        bool               `json:"opted_in"`
+	ShownAgain     bool               `json:"shown_again"`
+}
+
+// recordUnmatch keeps userID and the other user in match out of each
+// other's decks for the rematch cooldown. Their likes are cleared, so once
+// the cooldown is over they can like each other again.
+func recordUnmatch(tx *gorm.DB, cfg *config.Config, match models.Match, userID uint) error {
+	otherID := match.User1ID
+	if otherID == userID {
+		otherID = match.User2ID
+	}
+
+	unmatch := models.Unmatch{
+		MatchID:     match.ID,
+		UserID:      userID,
+		OtherUserID: otherID,
+		MatchedAt:   match.CreatedAt,
+	}
+	if cfg.RematchCooldown > 0 {
+		until := time.Now().Add(cfg.RematchCooldown)
+		unmatch.CooldownUntil = &until
+	}
+	if err := tx.Create(&unmatch).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("(liker_id = ? AND liked_id = ?) OR (liker_id = ? AND liked_id = ?)", userID, otherID, otherID, userID).
+		Delete(&models.Like{}).Error
+}
+
+// keptApart reports whether an unmatch still keeps the two users from
+// liking or complimenting each other
+func (h *MatchHandler) keptApart(ctx context.Context, a, b uint) bool {
+	var count int64
+	h.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND id IN ("+unmatchedUsersSQL+")", b, a, a, a, time.Now()).
+		Count(&count)
+	return count > 0
+}
+
+// GetUnmatches lists the caller's unmatches, newest first, with when each
+// pair may see each other again. Users blocked either way aren't listed.
+func (h *MatchHandler) GetUnmatches(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var unmatches []models.Unmatch
+	if err := h.db.WithContext(ctx).Where("user_id = ? OR other_user_id = ?", userID, userID).
+		Scopes(unblockedUnmatches).
+		Order("created_at DESC").Find(&unmatches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unmatches"})
+		return
+	}
+
+	otherIDs := make([]uint, 0, len(unmatches))
+	for _, unmatch := range unmatches {
+		otherIDs = append(otherIDs, otherUnmatchUser(unmatch, userID.(uint)))
+	}
+	var others []models.User
+	if len(otherIDs) > 0 {
+		h.db.WithContext(ctx).Preload("ProfilePhotos").Where("id IN ?", otherIDs).Find(&others)
+	}
+	byID := make(map[uint]models.User, len(others))
+	for _, other := range others {
+		byID[other.ID] = other
+	}
+
+	viewer := loadViewer(h.db.WithContext(ctx), userID)
+	now := time.Now()
+	resp := make([]UnmatchResponse, 0, len(unmatches))
+	for _, unmatch := range unmatches {
+		other, ok := byID[otherUnmatchUser(unmatch, userID.(uint))]
+		if !ok {
+			continue
+		}
+		byMe := unmatch.UserID == userID.(uint)
+		optedIn := unmatch.OtherSecondChance != nil
+		if byMe {
+			optedIn = unmatch.UserSecondChance != nil
+		}
+		resp = append(resp, UnmatchResponse{
+			ID:             unmatch.ID,
+			User:           newPublicUser(other, viewer),
+			UnmatchedByMe:  byMe,
+			MatchedAt:      unmatch.MatchedAt,
+			UnmatchedAt:    unmatch.CreatedAt,
+			CooldownUntil:  unmatch.CooldownUntil,
+			SecondChanceAt: unmatch.CreatedAt.Add(h.cfg.SecondChanceAfter),
+			OptedIn:        optedIn,
+			ShownAgain:     unmatch.Over(now),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unmatches": resp})
+}
+
+// GiveSecondChance opts the caller in to being shown the other user of an
+// unmatch again. It opens once SECOND_CHANCE_AFTER has passed, and the pair
+// reappears in each other's decks when both have opted in.
+func (h *MatchHandler) GiveSecondChance(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+	unmatchID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unmatch ID"})
+		return
+	}
+
+	var unmatch models.Unmatch
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND (user_id = ? OR other_user_id = ?)", unmatchID, userID, userID).
+			Scopes(unblockedUnmatches).First(&unmatch).Error; err != nil {
+			return err
+		}
+		if unmatch.Over(time.Now()) {
+			return nil
+		}
+		if opens := unmatch.CreatedAt.Add(h.cfg.SecondChanceAfter); time.Now().Before(opens) {
+			return errSecondChanceNotOpen
+		}
+
+		column := "other_second_chance"
+		if unmatch.UserID == userID.(uint) {
+			column = "user_second_chance"
+		}
+		if err := tx.Model(&unmatch).Where(column+" IS NULL").Update(column, time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", unmatch.ID).First(&unmatch).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unmatch not found"})
+		return
+	}
+	if errors.Is(err, errSecondChanceNotOpen) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":            "It's too soon for a second chance",
+			"second_chance_at": unmatch.CreatedAt.Add(h.cfg.SecondChanceAfter),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to opt in"})
+		return
+	}
+
+	if unmatch.Over(time.Now()) {
+		c.JSON(http.StatusOK, gin.H{"message": "You can be shown to each other again", "shown_again": true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You'll be shown to each other again if they opt in too", "shown_again": false})
+}
+
+var errSecondChanceNotOpen = errors.New("second chance not open yet")
+
+func otherUnmatchUser(unmatch models.Unmatch, userID uint) uint {
+	if unmatch.UserID == userID {
+		return unmatch.OtherUserID
+	}
+	return unmatch.UserID
+}
@@ -1,10 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -12,6 +13,8 @@ import (
 	"ethiopia-dating-app/internal/config"
 	"ethiopia-dating-app/internal/models"
 	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -19,9 +22,13 @@ import (
 )
 
 type UserHandler struct {
-	db    *gorm.DB
-	redis *redis.Client
-	cfg   *config.Config
+	db         *gorm.DB
+	redis      *redis.Client
+	cfg        *config.Config
+	labeler    services.PhotoLabeler
+	translator services.Translator
+	storage    *services.StorageService
+	geo        services.GeoLocator
 }
 
 type UpdateProfileRequest struct {
@@ -32,48 +39,61 @@ type UpdateProfileRequest struct {
 	Latitude  *float64 `json:"latitude,omitempty"`
 	Longitude *float64 `json:"longitude,omitempty"`
 	Interests []uint   `json:"interests,omitempty"`
+	Gender    *string  `json:"gender,omitempty" binding:"omitempty,oneof=male female non_binary other"`
+	Seeking   *string  `json:"seeking,omitempty" binding:"omitempty,oneof=men women everyone"`
+
+	RelationshipIntent *string `json:"relationship_intent,omitempty" binding:"omitempty,oneof=serious casual friendship marriage_minded"`
+
+	DistanceUnit *string `json:"distance_unit,omitempty" binding:"omitempty,oneof=km mi"`
+	HideAge      *bool   `json:"hide_age,omitempty"`
+	HideOnline   *bool   `json:"hide_online,omitempty"`
+	HideLastSeen *bool   `json:"hide_last_seen,omitempty"`
+
+	// Version the client last saw; may also be sent as an If-Match header
+	Version *int `json:"version,omitempty"`
 }
 
 type DiscoverUsersRequest struct {
-	AgeMin      *int     `json:"age_min,omitempty"`
-	AgeMax      *int     `json:"age_max,omitempty"`
-	Gender      *string  `json:"gender,omitempty"`
-	Location    *string  `json:"location,omitempty"`
-	Latitude    *float64 `json:"latitude,omitempty"`
-	Longitude   *float64 `json:"longitude,omitempty"`
-	MaxDistance *int     `json:"max_distance,omitempty"` // in kilometers
-	Interests   []uint   `json:"interests,omitempty"`
-	Page        int      `json:"page" binding:"min=1"`
-	Limit       int      `json:"limit" binding:"min=1,max=50"`
+	DiscoverFilters
+	Page  int `json:"page" binding:"min=1"`
+	Limit int `json:"limit" binding:"min=1,max=50"`
 }
 
 type ReportUserRequest struct {
 	ReportedID  uint   `json:"reported_id" binding:"required"`
 	Reason      string `json:"reason" binding:"required"`
 	Description string `json:"description,omitempty"`
+	MessageID   *uint  `json:"message_id,omitempty"` // a message from the reported user in a conversation with them
 }
 
-func NewUserHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config) *UserHandler {
+func NewUserHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, labeler services.PhotoLabeler, translator services.Translator, storage *services.StorageService, geo services.GeoLocator) *UserHandler {
 	return &UserHandler{
-		db:    db,
-		redis: redis,
-		cfg:   cfg,
+		db:         db,
+		redis:      redis,
+		cfg:        cfg,
+		labeler:    labeler,
+		translator: translator,
+		storage:    storage,
+		geo:        geo,
 	}
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	var user models.User
-	if err := h.db.Preload("ProfilePhotos").Preload("Interests").Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Preload("ProfilePhotos").Preload("Interests").Preload("Prompts", orderedPrompts).Where("id = ?", userID).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
+	setVersionETag(c, user.Version)
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	var req UpdateProfileRequest
@@ -83,20 +103,27 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Update fields
-	if req.FirstName != "" {
-		user.FirstName = req.FirstName
-	}
-	if req.LastName != "" {
-		user.LastName = req.LastName
+	nameChange, err := h.prepareNameChange(&user, req.FirstName, req.LastName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+
+	// Update fields
 	if req.Bio != nil {
+		language := utils.DetectLanguage(*req.Bio)
+		if utils.ContainsProfanity(*req.Bio, language) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Your bio contains words that aren't allowed"})
+			return
+		}
 		user.Bio = req.Bio
+		user.BioLanguage = language
+		user.BioSuggestive = utils.IsSuggestive(*req.Bio, language)
 	}
 	if req.Location != nil {
 		user.Location = req.Location
@@ -107,34 +134,107 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	if req.Longitude != nil {
 		user.Longitude = req.Longitude
 	}
+	if req.Latitude != nil || req.Longitude != nil {
+		user.LocationApproximate = false
+	}
+	before := user
+	if req.Gender != nil {
+		user.Gender = *req.Gender
+	}
+	if req.Seeking != nil {
+		user.Seeking = *req.Seeking
+	}
+	if req.RelationshipIntent != nil {
+		user.RelationshipIntent = *req.RelationshipIntent
+	}
+	if req.DistanceUnit != nil {
+		user.DistanceUnit = *req.DistanceUnit
+	}
+	if req.HideAge != nil {
+		if *req.HideAge && !user.IsPremium {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Hiding your age requires a premium account"})
+			return
+		}
+		user.HideAge = *req.HideAge
+	}
+	if req.HideOnline != nil {
+		user.HideOnline = *req.HideOnline
+	}
+	if req.HideLastSeen != nil {
+		user.HideLastSeen = *req.HideLastSeen
+	}
 
-	// Update interests if provided
-	if len(req.Interests) > 0 {
-		// Remove existing interests
-		h.db.Where("user_id = ?", userID).Delete(&models.UserInterest{})
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveUserVersioned(tx, &user, expectedVersion(c, req.Version)); err != nil {
+			return err
+		}
+		if req.FirstName != "" || req.LastName != "" {
+			if err := replaceNameChange(tx, user.ID, nameChange); err != nil {
+				return err
+			}
+		}
+		if req.Location != nil || req.Latitude != nil || req.Longitude != nil {
+			if err := updateWaitlistCity(tx, user.ID, ipCity(h.geo, c.ClientIP())); err != nil {
+				return err
+			}
+		}
+		if req.Seeking != nil {
+			if err := services.MarkOnboardingStep(tx, user.ID, services.OnboardingSetPreferences); err != nil {
+				return err
+			}
+		}
+
+		// Update interests if provided
+		if len(req.Interests) > 0 {
+			// Remove existing interests
+			if err := tx.Where("user_id = ?", userID).Delete(&models.UserInterest{}).Error; err != nil {
+				return err
+			}
 
-		// Add new interests
-		for _, interestID := range req.Interests {
-			userInterest := models.UserInterest{
-				UserID:     userID.(uint),
-				InterestID: interestID,
+			// Add new interests
+			for _, interestID := range req.Interests {
+				userInterest := models.UserInterest{
+					UserID:     userID.(uint),
+					InterestID: interestID,
+				}
+				if err := tx.Create(&userInterest).Error; err != nil {
+					return err
+				}
 			}
-			h.db.Create(&userInterest)
 		}
+		return nil
+	})
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, h.db.WithContext(ctx), userID)
+		return
 	}
-
-	if err := h.db.Save(&user).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
 		return
 	}
 
 	// Reload user with relations
-	h.db.Preload("ProfilePhotos").Preload("Interests").Where("id = ?", userID).First(&user)
+	h.db.WithContext(ctx).Preload("ProfilePhotos").Preload("Interests").Preload("Prompts", orderedPrompts).Where("id = ?", userID).First(&user)
+
+	if (req.Latitude != nil || req.Longitude != nil) && hasPreciseLocation(&user) {
+		h.redis.SetPresenceLocation(ctx, user.ID, *user.Latitude, *user.Longitude)
+	}
+	queuePreferenceChange(ctx, h.redis, user.ID, preferenceChange(&before, &user))
 
+	setVersionETag(c, user.Version)
+	if nameChange != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "Profile updated. Your new name will show once it has been reviewed",
+			"user":        user,
+			"name_change": nameChange,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully", "user": user})
 }
 
 func (h *UserHandler) UploadPhoto(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	file, header, err := c.Request.FormFile("photo")
@@ -145,17 +245,17 @@ func (h *UserHandler) UploadPhoto(c *gin.Context) {
 	defer file.Close()
 
 	// Validate file
-	if err := h.validateImageFile(header); err != nil {
+	contentType, err := validateImageFile(h.cfg, header)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
-	filename := fmt.Sprintf("profile_photos/%d_%s%s", userID, uuid.New().String(), ext)
+	filename := fmt.Sprintf("profile_photos/%d_%s%s", userID, uuid.New().String(), imageExtension(contentType))
 
 	// Upload to S3/MinIO
-	url, err := h.uploadToStorage(file, filename, header.Header.Get("Content-Type"))
+	url, err := h.storage.UploadFile(ctx, file, filename, contentType)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload photo"})
 		return
@@ -163,7 +263,7 @@ func (h *UserHandler) UploadPhoto(c *gin.Context) {
 
 	// Check if this is the first photo (make it primary)
 	var photoCount int64
-	h.db.Model(&models.ProfilePhoto{}).Where("user_id = ?", userID).Count(&photoCount)
+	h.db.WithContext(ctx).Model(&models.ProfilePhoto{}).Where("user_id = ?", userID).Count(&photoCount)
 
 	// Create photo record
 	photo := models.ProfilePhoto{
@@ -173,32 +273,42 @@ func (h *UserHandler) UploadPhoto(c *gin.Context) {
 		Order:     int(photoCount),
 	}
 
-	if err := h.db.Create(&photo).Error; err != nil {
+	// New photos stay hidden from other users until an admin approves them
+	if h.cfg.PhotoReviewRequired {
+		source := "upload"
+		photo.ModerationStatus = "pending"
+		photo.FlagSource = &source
+	}
+
+	photo.AltText = photoAltText(photo)
+	if err := h.db.WithContext(ctx).Create(&photo).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save photo record"})
 		return
 	}
+	h.labelPhotos([]models.ProfilePhoto{photo})
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Photo uploaded successfully", "photo": photo})
 }
 
 func (h *UserHandler) DeletePhoto(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	photoID := c.Param("id")
 
 	var photo models.ProfilePhoto
-	if err := h.db.Where("id = ? AND user_id = ?", photoID, userID).First(&photo).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ? AND user_id = ?", photoID, userID).First(&photo).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
 		return
 	}
 
 	// Delete from storage
-	if err := h.deleteFromStorage(photo.URL); err != nil {
+	if err := h.storage.DeleteFile(ctx, photo.URL); err != nil {
 		// Log error but continue with database deletion
 		fmt.Printf("Failed to delete photo from storage: %v\n", err)
 	}
 
 	// Delete from database
-	if err := h.db.Delete(&photo).Error; err != nil {
+	if err := h.db.WithContext(ctx).Delete(&photo).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete photo"})
 		return
 	}
@@ -206,9 +316,9 @@ func (h *UserHandler) DeletePhoto(c *gin.Context) {
 	// If this was the primary photo, make another one primary
 	if photo.IsPrimary {
 		var nextPhoto models.ProfilePhoto
-		if err := h.db.Where("user_id = ? AND id != ?", userID, photoID).First(&nextPhoto).Error; err == nil {
+		if err := h.db.WithContext(ctx).Where("user_id = ? AND id != ? AND is_private = ?", userID, photoID, false).First(&nextPhoto).Error; err == nil {
 			nextPhoto.IsPrimary = true
-			h.db.Save(&nextPhoto)
+			h.db.WithContext(ctx).Save(&nextPhoto)
 		}
 	}
 
@@ -216,6 +326,7 @@ func (h *UserHandler) DeletePhoto(c *gin.Context) {
 }
 
 func (h *UserHandler) DiscoverUsers(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	var req DiscoverUsersRequest
@@ -234,93 +345,44 @@ func (h *UserHandler) DiscoverUsers(c *gin.Context) {
 
 	// Get current user
 	var currentUser models.User
-	if err := h.db.Where("id = ?", userID).First(&currentUser).Error; err != nil {
+	if err := h.db.WithContext(ctx).Preload("Interests").Where("id = ?", userID).First(&currentUser).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Build query
-	query := h.db.Model(&models.User{}).Where("id != ? AND is_active = ? AND is_verified = ?", userID, true, true)
-
-	// Age filter
-	if req.AgeMin != nil || req.AgeMax != nil {
-		now := time.Now()
-		if req.AgeMin != nil {
-			maxBirthDate := now.AddDate(-*req.AgeMin, 0, 0)
-			query = query.Where("date_of_birth <= ?", maxBirthDate)
-		}
-		if req.AgeMax != nil {
-			minBirthDate := now.AddDate(-*req.AgeMax-1, 0, 0)
-			query = query.Where("date_of_birth >= ?", minBirthDate)
-		}
+	// Without coordinates in the request, search from the saved location,
+	// which may be the approximate one from login
+	if req.Latitude == nil || req.Longitude == nil {
+		req.Latitude, req.Longitude = currentUser.Latitude, currentUser.Longitude
 	}
 
-	// Gender filter
-	if req.Gender != nil {
-		query = query.Where("gender = ?", *req.Gender)
-	}
-
-	// Location filter
-	if req.Location != nil {
-		query = query.Where("location ILIKE ?", "%"+*req.Location+"%")
-	}
-
-	// Distance filter (if coordinates provided)
-	if req.Latitude != nil && req.Longitude != nil && req.MaxDistance != nil {
-		// Simple distance calculation (not accurate for large distances)
-		query = query.Where(
-			"latitude IS NOT NULL AND longitude IS NOT NULL AND "+
-				"SQRT(POW(latitude - ?, 2) + POW(longitude - ?, 2)) * 111 <= ?",
-			*req.Latitude, *req.Longitude, *req.MaxDistance,
-		)
-	}
-
-	// Exclude blocked users
-	query = query.Where("id NOT IN (SELECT blocked_id FROM blocked_users WHERE blocker_id = ?)", userID)
-
-	// Exclude already liked/disliked users
-	query = query.Where("id NOT IN (SELECT liked_id FROM likes WHERE liker_id = ?)", userID)
-	query = query.Where("id NOT IN (SELECT disliked_id FROM dislikes WHERE disliker_id = ?)", userID)
+	query := buildDiscoverQuery(h.db.WithContext(ctx), h.cfg, currentUser.ID, req.DiscoverFilters)
 
 	// Get total count
 	var total int64
 	query.Count(&total)
 
-	// Apply pagination
+	// Apply pagination. Pages within the ranked pool come best first; later
+	// ones carry on in recencyOrder.
 	offset := (req.Page - 1) * req.Limit
 	var users []models.User
-	if err := query.Preload("ProfilePhotos").Preload("Interests").
-		Offset(offset).Limit(req.Limit).Find(&users).Error; err != nil {
+	var err error
+	if offset+req.Limit <= discoverRankPool {
+		users, err = topCandidates(query, &currentUser, discoverRankPool, offset, req.Limit)
+	} else {
+		err = query.Preload("ProfilePhotos", "is_private = ?", false).Preload("Interests").Preload("Prompts", orderedPrompts).Preload("MessageStats").
+			Order(recencyOrder(time.Now(), currentUser.RelationshipIntent)).
+			Offset(offset).Limit(req.Limit).Find(&users).Error
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
 
-	// Filter by interests if provided
-	if len(req.Interests) > 0 {
-		var filteredUsers []models.User
-		for _, user := range users {
-			userInterests := make(map[uint]bool)
-			for _, interest := range user.Interests {
-				userInterests[interest.ID] = true
-			}
-
-			hasMatchingInterest := false
-			for _, interestID := range req.Interests {
-				if userInterests[interestID] {
-					hasMatchingInterest = true
-					break
-				}
-			}
-
-			if hasMatchingInterest {
-				filteredUsers = append(filteredUsers, user)
-			}
-		}
-		users = filteredUsers
-	}
+	h.recordProfileViews(ctx, users)
 
 	c.JSON(http.StatusOK, gin.H{
-		"users": users,
+		"users": withMutualContacts(h.db.WithContext(ctx), currentUser.ID, newPublicUsers(users, &currentUser)),
 		"pagination": gin.H{
 			"page":        req.Page,
 			"limit":       req.Limit,
@@ -331,10 +393,11 @@ func (h *UserHandler) DiscoverUsers(c *gin.Context) {
 }
 
 func (h *UserHandler) GetFavorites(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	var favorites []models.Favorite
-	if err := h.db.Preload("Favorite.ProfilePhotos").Preload("Favorite.Interests").
+	if err := h.db.WithContext(ctx).Preload("Favorite.ProfilePhotos").Preload("Favorite.Interests").
 		Where("user_id = ?", userID).Find(&favorites).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favorites"})
 		return
@@ -345,10 +408,11 @@ func (h *UserHandler) GetFavorites(c *gin.Context) {
 		users = append(users, fav.Favorite)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"favorites": users})
+	c.JSON(http.StatusOK, gin.H{"favorites": newPublicUsers(users, loadViewer(h.db.WithContext(ctx), userID))})
 }
 
 func (h *UserHandler) AddToFavorites(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	favoriteID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
@@ -358,14 +422,14 @@ func (h *UserHandler) AddToFavorites(c *gin.Context) {
 
 	// Check if user exists
 	var user models.User
-	if err := h.db.Where("id = ?", favoriteID).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", favoriteID).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Check if already in favorites
 	var existing models.Favorite
-	if err := h.db.Where("user_id = ? AND favorite_id = ?", userID, favoriteID).First(&existing).Error; err == nil {
+	if err := h.db.WithContext(ctx).Where("user_id = ? AND favorite_id = ?", userID, favoriteID).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User already in favorites"})
 		return
 	}
@@ -376,7 +440,7 @@ func (h *UserHandler) AddToFavorites(c *gin.Context) {
 		FavoriteID: uint(favoriteID),
 	}
 
-	if err := h.db.Create(&favorite).Error; err != nil {
+	if err := h.db.WithContext(ctx).Create(&favorite).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to favorites"})
 		return
 	}
@@ -385,6 +449,7 @@ func (h *UserHandler) AddToFavorites(c *gin.Context) {
 }
 
 func (h *UserHandler) RemoveFromFavorites(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	favoriteID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
@@ -392,7 +457,7 @@ func (h *UserHandler) RemoveFromFavorites(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("user_id = ? AND favorite_id = ?", userID, favoriteID).Delete(&models.Favorite{}).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("user_id = ? AND favorite_id = ?", userID, favoriteID).Delete(&models.Favorite{}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from favorites"})
 		return
 	}
@@ -401,6 +466,7 @@ func (h *UserHandler) RemoveFromFavorites(c *gin.Context) {
 }
 
 func (h *UserHandler) BlockUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	blockedID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
@@ -410,36 +476,49 @@ func (h *UserHandler) BlockUser(c *gin.Context) {
 
 	// Check if user exists
 	var user models.User
-	if err := h.db.Where("id = ?", blockedID).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", blockedID).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Check if already blocked
+	// Check if already blocked. A block placed by a phone block becomes a
+	// direct one, without saying the number was theirs.
 	var existing models.BlockedUser
-	if err := h.db.Where("blocker_id = ? AND blocked_id = ?", userID, blockedID).First(&existing).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User already blocked"})
-		return
-	}
-
-	// Block user
-	blocked := models.BlockedUser{
-		BlockerID: userID.(uint),
-		BlockedID: uint(blockedID),
-	}
+	if err := h.db.WithContext(ctx).Where("blocker_id = ? AND blocked_id = ?", userID, blockedID).First(&existing).Error; err == nil {
+		if !existing.ViaPhone {
+			c.JSON(http.StatusConflict, gin.H{"error": "User already blocked"})
+			return
+		}
+		if err := h.db.WithContext(ctx).Model(&existing).Update("via_phone", false).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+			return
+		}
+	} else {
+		// Block user
+		blocked := models.BlockedUser{
+			BlockerID: userID.(uint),
+			BlockedID: uint(blockedID),
+		}
 
-	if err := h.db.Create(&blocked).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
-		return
+		if err := h.db.WithContext(ctx).Create(&blocked).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+			return
+		}
 	}
 
 	// Remove from favorites if exists
-	h.db.Where("user_id = ? AND favorite_id = ?", userID, blockedID).Delete(&models.Favorite{})
+	h.db.WithContext(ctx).Where("user_id = ? AND favorite_id = ?", userID, blockedID).Delete(&models.Favorite{})
+
+	// A match ends with the block, so neither can keep writing
+	if err := endMatchBetween(ctx, h.db, h.redis, userID.(uint), uint(blockedID)); err != nil {
+		log.Printf("Failed to end match between %d and blocked user %d: %v", userID, blockedID, err)
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "User blocked successfully"})
 }
 
 func (h *UserHandler) UnblockUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 	blockedID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
@@ -447,7 +526,8 @@ func (h *UserHandler) UnblockUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("blocker_id = ? AND blocked_id = ?", userID, blockedID).Delete(&models.BlockedUser{}).Error; err != nil {
+	// Blocks placed by a phone block are lifted by removing the phone block
+	if err := h.db.WithContext(ctx).Where("blocker_id = ? AND blocked_id = ? AND via_phone = ?", userID, blockedID, false).Delete(&models.BlockedUser{}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
 		return
 	}
@@ -456,6 +536,7 @@ func (h *UserHandler) UnblockUser(c *gin.Context) {
 }
 
 func (h *UserHandler) ReportUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, _ := c.Get("user_id")
 
 	var req ReportUserRequest
@@ -466,14 +547,14 @@ func (h *UserHandler) ReportUser(c *gin.Context) {
 
 	// Check if reported user exists
 	var user models.User
-	if err := h.db.Where("id = ?", req.ReportedID).First(&user).Error; err != nil {
+	if err := h.db.WithContext(ctx).Where("id = ?", req.ReportedID).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Check if already reported
 	var existing models.Report
-	if err := h.db.Where("reporter_id = ? AND reported_id = ?", userID, req.ReportedID).First(&existing).Error; err == nil {
+	if err := h.db.WithContext(ctx).Where("reporter_id = ? AND reported_id = ?", userID, req.ReportedID).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User already reported"})
 		return
 	}
@@ -486,46 +567,116 @@ func (h *UserHandler) ReportUser(c *gin.Context) {
 		Description: &req.Description,
 		Status:      "pending",
 	}
+	setReportSLA(h.cfg, &report, time.Now())
+
+	// The language routes the report to moderators who read it; a reported
+	// message says more about that than the reporter's description
+	content := req.Description
+	if req.MessageID != nil {
+		// Looking in the pair's conversations keeps the lookup to their partitions
+		var conversationIDs []uint
+		h.db.WithContext(ctx).Model(&models.Conversation{}).
+			Joins("JOIN matches ON matches.id = conversations.match_id").
+			Where("(matches.user1_id = ? AND matches.user2_id = ?) OR (matches.user1_id = ? AND matches.user2_id = ?)",
+				userID, req.ReportedID, req.ReportedID, userID).
+			Pluck("conversations.id", &conversationIDs)
+
+		var message models.Message
+		if err := h.db.WithContext(ctx).Where("id = ? AND sender_id = ? AND conversation_id IN ?", *req.MessageID, req.ReportedID, conversationIDs).First(&message).Error; err != nil ||
+			!hasConversationAccess(ctx, h.db, h.redis, userID.(uint), message.ConversationID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		report.MessageID = req.MessageID
+		if !message.IsEncrypted {
+			content = message.Content
+		}
+	}
+	report.Language = utils.DetectLanguage(content)
+	report.Profanity = utils.ContainsProfanity(content, report.Language)
 
-	if err := h.db.Create(&report).Error; err != nil {
+	// Suspected minors are hidden right away rather than waiting for review
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		if req.Reason == underageReportReason {
+			return flagUnderage(tx, req.ReportedID)
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
 		return
 	}
 
+	services.PublishOpsEvent(ctx, h.redis, services.OpsReportFiled, gin.H{
+		"report_id":   report.ID,
+		"reporter_id": report.ReporterID,
+		"reported_id": report.ReportedID,
+		"reason":      report.Reason,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{"message": "User reported successfully"})
 }
 
 // Helper methods for file handling
-func (h *UserHandler) validateImageFile(header *multipart.FileHeader) error {
+func validateImageFile(cfg *config.Config, header *multipart.FileHeader) (string, error) {
 	// Check file size
-	if header.Size > h.cfg.MaxFileSize {
-		return fmt.Errorf("file too large, maximum size is %d bytes", h.cfg.MaxFileSize)
+	if header.Size > cfg.MaxFileSize {
+		return "", fmt.Errorf("file too large, maximum size is %d bytes", cfg.MaxFileSize)
+	}
+
+	// The client's Content-Type and file name are ignored; the type comes from
+	// the file's own bytes
+	file, err := header.Open()
+	if err != nil {
+		return "", fmt.Errorf("could not read file")
+	}
+	defer file.Close()
+
+	info, err := utils.InspectImage(file)
+	if err != nil {
+		return "", fmt.Errorf("invalid image: %v", err)
 	}
 
-	// Check file type
-	contentType := header.Header.Get("Content-Type")
 	allowed := false
-	for _, allowedType := range h.cfg.AllowedImageTypes {
-		if contentType == allowedType {
+	for _, allowedType := range cfg.AllowedImageTypes {
+		if info.ContentType == allowedType {
 			allowed = true
 			break
 		}
 	}
-
 	if !allowed {
-		return fmt.Errorf("invalid file type, allowed types are: %s", strings.Join(h.cfg.AllowedImageTypes, ", "))
+		return "", fmt.Errorf("invalid file type, allowed types are: %s", strings.Join(cfg.AllowedImageTypes, ", "))
 	}
 
-	return nil
-}
+	// Check dimensions and shape
+	short, long := info.Width, info.Height
+	if short > long {
+		short, long = long, short
+	}
+	if int64(short) < cfg.MinImageDimension {
+		return "", fmt.Errorf("image too small, minimum size is %dx%d pixels", cfg.MinImageDimension, cfg.MinImageDimension)
+	}
+	if int64(long) > cfg.MaxImageDimension {
+		return "", fmt.Errorf("image too large, maximum side is %d pixels", cfg.MaxImageDimension)
+	}
+	if float64(long)/float64(short) > cfg.MaxImageAspectRatio {
+		return "", fmt.Errorf("image is too narrow, maximum aspect ratio is %.1f:1", cfg.MaxImageAspectRatio)
+	}
 
-func (h *UserHandler) uploadToStorage(file multipart.File, filename, contentType string) (string, error) {
-	// TODO: Implement actual S3/MinIO upload
-	// For now, return a placeholder URL
-	return fmt.Sprintf("https://storage.example.com/%s", filename), nil
+	return info.ContentType, nil
 }
 
-func (h *UserHandler) deleteFromStorage(url string) error {
-	// TODO: Implement actual S3/MinIO deletion
-	return nil
+// imageExtension picks the stored file extension from the sniffed type
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".jpg"
+	}
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// usernameChangeCooldown stops handles being cycled to dodge reports or
+	// to squat on names
+	usernameChangeCooldown = 30 * 24 * time.Hour
+
+	usernameChecksPerMinute     = 30
+	publicProfileViewsPerMinute = 60
+)
+
+type SetUsernameRequest struct {
+	Username string `json:"username" binding:"required"`
+	Version  *int   `json:"version,omitempty"`
+}
+
+// CheckUsername reports whether a username is valid and free
+func (h *UserHandler) CheckUsername(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	if !allowRequest(ctx, h.redis, fmt.Sprintf("ratelimit:username:%d", userID), usernameChecksPerMinute, time.Minute) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many checks, please try again later"})
+		return
+	}
+
+	username := utils.NormalizeUsername(c.Query("username"))
+	if err := utils.ValidateUsername(username); err != nil {
+		c.JSON(http.StatusOK, gin.H{"username": username, "available": false, "reason": err.Error()})
+		return
+	}
+
+	var count int64
+	if err := h.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("username = ? AND id != ?", username, userID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check username"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusOK, gin.H{"username": username, "available": false, "reason": "that username is taken"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"username": username, "available": true})
+}
+
+// SetUsername claims a username for the caller. It can be changed once every
+// 30 days.
+func (h *UserHandler) SetUsername(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var req SetUsernameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username := utils.NormalizeUsername(req.Username)
+	if err := utils.ValidateUsername(username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.Username != nil && *user.Username == username {
+		c.JSON(http.StatusOK, gin.H{"username": username, "version": user.Version})
+		return
+	}
+	if user.UsernameChangedAt != nil {
+		if next := user.UsernameChangedAt.Add(usernameChangeCooldown); time.Now().Before(next) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "You can only change your username once every 30 days", "retry_at": next})
+			return
+		}
+	}
+
+	// Handles of deleted accounts stay taken so nobody can step into them
+	var count int64
+	h.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "That username is taken"})
+		return
+	}
+
+	now := time.Now()
+	user.Username = &username
+	user.UsernameChangedAt = &now
+
+	err := saveUserVersioned(h.db.WithContext(ctx), &user, expectedVersion(c, req.Version))
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, h.db.WithContext(ctx), userID)
+		return
+	}
+	if err != nil && strings.Contains(err.Error(), "duplicate key") {
+		// Lost a race for the same name
+		c.JSON(http.StatusConflict, gin.H{"error": "That username is taken"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set username"})
+		return
+	}
+
+	setVersionETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"username":    username,
+		"profile_url": "/u/" + username,
+		"version":     user.Version,
+	})
+}
+
+// GetPublicProfile resolves a shared /u/:handle link to the public profile
+// projection. It needs no session, so it is rate limited per IP and only
+// shows profiles that discovery would.
+func (h *UserHandler) GetPublicProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if !allowRequest(ctx, h.redis, "ratelimit:public_profile:"+c.ClientIP(), publicProfileViewsPerMinute, time.Minute) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+		return
+	}
+
+	var user models.User
+	if err := shareableUsers(h.db.WithContext(ctx)).Preload("ProfilePhotos").Preload("Interests").Preload("Prompts", orderedPrompts).
+		Where("username = ?", utils.NormalizeUsername(c.Param("handle"))).
+		First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": newPublicUser(user, nil)})
+}
+
+// shareableUsers limits a query to profiles that may be shown outside the
+// app, which are the ones discovery would show
+func shareableUsers(db *gorm.DB) *gorm.DB {
+	return db.Where("is_active = ? AND is_verified = ? AND is_paused = ? AND age_flagged_at IS NULL", true, true, false).Scopes(notOnWaitlist)
+}
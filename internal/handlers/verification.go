@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// faydaNumberPattern accepts a 12-digit FIN or a 16-digit FAN
+var faydaNumberPattern = regexp.MustCompile(`^(\d{12}|\d{16})$`)
+
+var identityDocumentTypes = map[string]bool{
+	"national_id":     true,
+	"passport":        true,
+	"kebele_id":       true,
+	"drivers_license": true,
+}
+
+type VerificationHandler struct {
+	db       *gorm.DB
+	redis    *redis.Client
+	cfg      *config.Config
+	verifier services.IdentityVerifier
+	mailer   services.Mailer
+	storage  *services.StorageService
+}
+
+type FaydaVerificationRequest struct {
+	FaydaNumber string `json:"fayda_number" binding:"required"`
+}
+
+type IdentityDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Reason   string `json:"reason,omitempty" binding:"max=500"`
+}
+
+// identityDocumentURLTTL is how long a moderator's link to an ID document
+// works
+const identityDocumentURLTTL = 10 * time.Minute
+
+// IdentityReviewItem is a pending verification as shown to admins. The
+// document link expires after identityDocumentURLTTL.
+type IdentityReviewItem struct {
+	Verification models.IdentityVerification `json:"verification"`
+	DocumentURL  *string                     `json:"document_url,omitempty"`
+	User         PendingPhotoUser            `json:"user"`
+	DateOfBirth  time.Time                   `json:"date_of_birth"`
+}
+
+func NewVerificationHandler(db *gorm.DB, redis *redis.Client, cfg *config.Config, verifier services.IdentityVerifier, mailer services.Mailer, storage *services.StorageService) *VerificationHandler {
+	return &VerificationHandler{
+		db:       db,
+		redis:    redis,
+		cfg:      cfg,
+		verifier: verifier,
+		mailer:   mailer,
+		storage:  storage,
+	}
+}
+
+// GetIdentityVerification returns the caller's latest verification request
+func (h *VerificationHandler) GetIdentityVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Select("id", "id_verified").Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var latest models.IdentityVerification
+	response := gin.H{"id_verified": user.IDVerified, "fayda_available": h.cfg.FaydaAPIURL != ""}
+	if err := h.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").First(&latest).Error; err == nil {
+		response["verification"] = latest
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SubmitIdentityDocument accepts a photo of an ID document for admin review
+func (h *VerificationHandler) SubmitIdentityDocument(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	documentType := c.PostForm("document_type")
+	if !identityDocumentTypes[documentType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "document_type must be national_id, passport, kebele_id, or drivers_license"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("document")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No document provided"})
+		return
+	}
+	defer file.Close()
+
+	contentType, err := validateImageFile(h.cfg, header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.canSubmit(c, userID) {
+		return
+	}
+
+	filename := fmt.Sprintf("identity_documents/%d_%s%s", userID, uuid.New().String(), imageExtension(contentType))
+	// ID documents are never publicly readable; moderators get short-lived links
+	if err := h.storage.UploadPrivateFile(ctx, file, filename, contentType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload document"})
+		return
+	}
+
+	verification := models.IdentityVerification{
+		UserID:       userID.(uint),
+		Method:       "document",
+		Status:       "pending",
+		DocumentType: &documentType,
+		DocumentKey:  &filename,
+	}
+	if err := h.db.WithContext(ctx).Create(&verification).Error; err != nil {
+		if err := h.storage.DeleteObject(ctx, filename); err != nil {
+			log.Printf("Failed to delete orphaned identity document %s: %v", filename, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit verification"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"verification": verification})
+}
+
+// SubmitFaydaVerification checks the caller's profile details against the
+// Fayda registry. The check runs in the background; clients poll
+// GetIdentityVerification for the outcome. The full ID number is never stored.
+func (h *VerificationHandler) SubmitFaydaVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, _ := c.Get("user_id")
+
+	if h.cfg.FaydaAPIURL == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Fayda verification is not available. Please upload an ID document instead."})
+		return
+	}
+
+	var req FaydaVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !faydaNumberPattern.MatchString(req.FaydaNumber) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fayda number must be 12 or 16 digits"})
+		return
+	}
+
+	if !h.canSubmit(c, userID) {
+		return
+	}
+
+	// One national ID can only verify one account
+	numberHash := utils.HashString(req.FaydaNumber)
+	var reused int64
+	h.db.WithContext(ctx).Model(&models.IdentityVerification{}).
+		Where("fayda_number_hash = ? AND status = ? AND user_id != ?", numberHash, "verified", userID).
+		Count(&reused)
+	if reused > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This ID is already linked to another account"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	last4 := req.FaydaNumber[len(req.FaydaNumber)-4:]
+	verification := models.IdentityVerification{
+		UserID:           user.ID,
+		Method:           "fayda",
+		Status:           "pending",
+		FaydaNumberHash:  &numberHash,
+		FaydaNumberLast4: &last4,
+	}
+	if err := h.db.WithContext(ctx).Create(&verification).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit verification"})
+		return
+	}
+
+	go h.runFaydaCheck(verification, services.IdentityCheck{
+		FaydaNumber: req.FaydaNumber,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		DateOfBirth: user.DateOfBirth,
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"verification": verification})
+}
+
+func (h *VerificationHandler) runFaydaCheck(verification models.IdentityVerification, check services.IdentityCheck) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.verifier.Verify(ctx, check)
+	if err != nil {
+		log.Printf("Fayda check for verification %d failed: %v", verification.ID, err)
+		reason := "We couldn't reach the national ID service. Please try again later."
+		h.db.Model(&verification).Updates(map[string]interface{}{"status": "rejected", "rejection_reason": reason})
+		return
+	}
+
+	if !result.Matched {
+		reason := "The details on your profile don't match your national ID."
+		if result.Reason != "" {
+			reason = result.Reason
+		}
+		h.db.Model(&verification).Updates(map[string]interface{}{
+			"status":             "rejected",
+			"rejection_reason":   reason,
+			"provider_reference": result.Reference,
+		})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&verification).Updates(map[string]interface{}{
+			"status":             "verified",
+			"provider_reference": result.Reference,
+			"reviewed_at":        time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return markIdentityVerified(tx, verification.UserID)
+	})
+	if err != nil {
+		log.Printf("Failed to record Fayda verification %d: %v", verification.ID, err)
+	}
+}
+
+// canSubmit rejects new requests while one is pending or the user is verified
+func (h *VerificationHandler) canSubmit(c *gin.Context, userID interface{}) bool {
+	ctx := c.Request.Context()
+	var count int64
+	h.db.WithContext(ctx).Model(&models.IdentityVerification{}).
+		Where("user_id = ? AND status IN ?", userID, []string{"pending", "verified"}).
+		Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a pending or approved identity verification"})
+		return false
+	}
+	return true
+}
+
+func markIdentityVerified(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"id_verified": true,
+		"version":     gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+
+	notification := models.Notification{
+		UserID: userID,
+		Type:   "identity_verified",
+		Title:  "Identity verified",
+		Body:   "Your identity has been verified. Your profile now shows the verified ID badge.",
+		Data:   `{}`,
+	}
+	return tx.Create(&notification).Error
+}
+
+// GetIdentityVerifications lists verification requests for admin review,
+// oldest first. Defaults to pending document reviews.
+func (h *AdminHandler) GetIdentityVerifications(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.WithContext(ctx).Model(&models.IdentityVerification{}).Where("status = ?", c.DefaultQuery("status", "pending"))
+	if method := c.Query("method"); method != "" {
+		query = query.Where("method = ?", method)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var verifications []models.IdentityVerification
+	if err := query.Order("created_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&verifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch verifications"})
+		return
+	}
+
+	items := make([]IdentityReviewItem, 0, len(verifications))
+	for _, verification := range verifications {
+		var user models.User
+		h.db.WithContext(ctx).Where("id = ?", verification.UserID).First(&user)
+		items = append(items, IdentityReviewItem{
+			Verification: verification,
+			DocumentURL:  h.identityDocumentURL(ctx, verification),
+			User: PendingPhotoUser{
+				ID:        user.ID,
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+				Gender:    user.Gender,
+				IsActive:  user.IsActive,
+				CreatedAt: user.CreatedAt,
+			},
+			DateOfBirth: user.DateOfBirth,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"verifications": items,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+// DecideIdentityVerification approves or rejects a pending verification
+func (h *AdminHandler) DecideIdentityVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	adminID, _ := c.Get("user_id")
+	verificationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification ID"})
+		return
+	}
+
+	var req IdentityDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Decision == "reject" && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required when rejecting a verification"})
+		return
+	}
+
+	var verification models.IdentityVerification
+	if err := h.db.WithContext(ctx).Where("id = ? AND status = ?", verificationID, "pending").First(&verification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending verification not found"})
+		return
+	}
+
+	now := time.Now()
+	reviewer := adminID.(uint)
+	verification.ReviewedBy = &reviewer
+	verification.ReviewedAt = &now
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if req.Decision == "approve" {
+			verification.Status = "verified"
+			if err := tx.Save(&verification).Error; err != nil {
+				return err
+			}
+			if err := markIdentityVerified(tx, verification.UserID); err != nil {
+				return err
+			}
+			return h.logAdminAction(tx, c, "identity_verified", "user", verification.UserID, "")
+		}
+
+		verification.Status = "rejected"
+		verification.RejectionReason = &req.Reason
+		if err := tx.Save(&verification).Error; err != nil {
+			return err
+		}
+		notification := models.Notification{
+			UserID: verification.UserID,
+			Type:   "identity_rejected",
+			Title:  "Identity verification unsuccessful",
+			Body:   fmt.Sprintf("We couldn't verify your identity: %s", req.Reason),
+			Data:   `{}`,
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		return h.logAdminAction(tx, c, "identity_rejected", "user", verification.UserID, req.Reason)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"verification": verification})
+}
+
+// identityDocumentURL returns a short-lived link to the verification's ID
+// document, or nil when it has none or the link can't be made
+func (h *AdminHandler) identityDocumentURL(ctx context.Context, verification models.IdentityVerification) *string {
+	key := ""
+	switch {
+	case verification.DocumentKey != nil:
+		key = *verification.DocumentKey
+	case verification.DocumentURL != nil:
+		key = h.storage.ObjectKey(*verification.DocumentURL)
+	}
+	if key == "" {
+		return nil
+	}
+
+	url, err := h.storage.GeneratePresignedURL(ctx, key, identityDocumentURLTTL)
+	if err != nil {
+		log.Printf("Failed to sign identity document %d: %v", verification.ID, err)
+		return nil
+	}
+	return &url
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errVersionConflict is returned when a write is based on a stale version
+var errVersionConflict = errors.New("resource was modified by another request")
+
+// expectedVersion returns the version the client based its changes on, from
+// the If-Match header or a version field in the body. Nil means the client
+// didn't send one; concurrent writes are still detected in that case.
+func expectedVersion(c *gin.Context, bodyVersion *int) *int {
+	if bodyVersion != nil {
+		return bodyVersion
+	}
+
+	etag := strings.Trim(strings.TrimPrefix(c.GetHeader("If-Match"), "W/"), `"`)
+	if version, err := strconv.Atoi(etag); err == nil {
+		return &version
+	}
+	return nil
+}
+
+func setVersionETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// saveUserVersioned writes all of user's columns and bumps its version, but
+// only if nobody else has written the row since it was read
+func saveUserVersioned(tx *gorm.DB, user *models.User, expected *int) error {
+	current := user.Version
+	if expected != nil && *expected != current {
+		return errVersionConflict
+	}
+
+	user.Version = current + 1
+	result := tx.Model(user).Where("version = ?", current).
+		Select("*").Omit(clause.Associations, "created_at").
+		Updates(user)
+	if result.Error != nil {
+		user.Version = current
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		user.Version = current
+		return errVersionConflict
+	}
+	return nil
+}
+
+// respondVersionConflict answers 409 with the current state of the user so
+// the client can merge and retry
+func respondVersionConflict(c *gin.Context, db *gorm.DB, userID interface{}) {
+	var current models.User
+	db.Preload("ProfilePhotos").Preload("Interests").Where("id = ?", userID).First(&current)
+	setVersionETag(c, current.Version)
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error": "Your profile was changed on another device. Review the latest version and try again.",
+		"code":  "VERSION_CONFLICT",
+		"user":  current,
+	})
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AnalyticsViews refreshes the materialized views read by the admin analytics
+// endpoint, so the dashboard never scans the users, matches, and messages
+// tables on request
+func AnalyticsViews(db *gorm.DB) Job {
+	return Job{
+		Name:     "analytics_views",
+		Interval: 15 * time.Minute,
+		Run: func(ctx context.Context) error {
+			for _, view := range []string{"analytics_activity", "analytics_daily_registrations", "analytics_gender_distribution"} {
+				if err := db.WithContext(ctx).Exec("REFRESH MATERIALIZED VIEW " + view).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// DateCheckIns reminds users to check in when their date plan's check-in time
+// arrives, and alerts their trusted contacts by SMS once the grace period passes
+// without a check-in, if the plan was shared with them.
+func DateCheckIns(db *gorm.DB, sms services.SMSSender, gracePeriod time.Duration) Job {
+	return Job{
+		Name:     "date_check_ins",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+			now := time.Now()
+
+			var due []models.DatePlan
+			if err := db.Where("status = ? AND check_in_at <= ? AND reminder_sent_at IS NULL", "active", now).
+				Find(&due).Error; err != nil {
+				return err
+			}
+			for _, plan := range due {
+				notification := models.Notification{
+					UserID: plan.UserID,
+					Type:   "safety_check_in",
+					Title:  "Time to check in",
+					Body:   "How is your date going? Let us know you're safe.",
+					Data:   `{"date_plan_id": ` + strconv.FormatUint(uint64(plan.ID), 10) + `}`,
+				}
+				db.Create(&notification)
+				db.Model(&plan).Update("reminder_sent_at", now)
+			}
+
+			var missed []models.DatePlan
+			if err := db.Preload("User").
+				Where("status = ? AND check_in_at <= ?", "active", now.Add(-gracePeriod)).
+				Find(&missed).Error; err != nil {
+				return err
+			}
+			for _, plan := range missed {
+				var contacts []models.TrustedContact
+				if plan.ShareWithContacts {
+					db.Where("user_id = ?", plan.UserID).Find(&contacts)
+				}
+
+				body := fmt.Sprintf("%s hasn't checked in after meeting %s at %s (%s). Please reach out to them.",
+					plan.User.FirstName, plan.WithName, plan.Location, plan.ScheduledAt.Format("Jan 2 15:04"))
+				for _, contact := range contacts {
+					if err := sms.Send(ctx, contact.Phone, body); err != nil {
+						log.Printf("Failed to alert trusted contact %d: %v", contact.ID, err)
+					}
+				}
+
+				updates := map[string]interface{}{"status": "missed"}
+				if plan.ShareWithContacts {
+					updates["contacts_notified_at"] = now
+				}
+				db.Model(&plan).Updates(updates)
+			}
+
+			return nil
+		},
+	}
+}
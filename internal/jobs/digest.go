@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const digestBatchSize = 500
+
+// digestWeekStart returns the start of the last full week, Monday 00:00 UTC
+func digestWeekStart(now time.Time) time.Time {
+	now = now.UTC()
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	thisWeek := time.Date(now.Year(), now.Month(), now.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	return thisWeek.AddDate(0, 0, -7)
+}
+
+// WeeklyDigests compiles each user's summary of the last full week: likes
+// received, new matches, matches they haven't written to yet and profile
+// views. Users who were active that week and have something to see get an
+// in-app notification, also sent over the notifier's channels, unless they
+// turned the digest off. The digest is recorded either way, so each
+// user gets at most one per week.
+func WeeklyDigests(db *gorm.DB, rc *redis.Client, notifier *services.Dispatcher) Job {
+	return Job{
+		Name:     "weekly_digests",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+			weekStart := digestWeekStart(time.Now())
+			weekEnd := weekStart.AddDate(0, 0, 7)
+
+			for {
+				var userIDs []uint
+				if err := db.Model(&models.User{}).
+					Where("is_active = ? AND last_seen >= ? AND created_at < ?", true, weekStart, weekEnd).
+					Where("id NOT IN (SELECT user_id FROM weekly_digests WHERE week_start = ?)", weekStart).
+					Order("id").Limit(digestBatchSize).
+					Pluck("id", &userIDs).Error; err != nil {
+					return err
+				}
+
+				for _, userID := range userIDs {
+					if err := sendWeeklyDigest(ctx, db, rc, notifier, userID, weekStart, weekEnd); err != nil {
+						return err
+					}
+				}
+				if len(userIDs) < digestBatchSize {
+					return nil
+				}
+			}
+		},
+	}
+}
+
+func sendWeeklyDigest(ctx context.Context, db *gorm.DB, rc *redis.Client, notifier *services.Dispatcher, userID uint, weekStart, weekEnd time.Time) error {
+	digest := models.WeeklyDigest{UserID: userID, WeekStart: weekStart}
+
+	if err := db.Model(&models.Like{}).
+		Where("liked_id = ? AND created_at >= ? AND created_at < ?", userID, weekStart, weekEnd).
+		Count(&digest.NewLikes).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.Match{}).
+		Where("(user1_id = ? OR user2_id = ?) AND created_at >= ? AND created_at < ?", userID, userID, weekStart, weekEnd).
+		Count(&digest.NewMatches).Error; err != nil {
+		return err
+	}
+	if err := db.Raw(`
+		SELECT COUNT(*) FROM matches m
+		JOIN conversations c ON c.match_id = m.id
+		WHERE (m.user1_id = ? OR m.user2_id = ?) AND m.is_active AND m.deleted_at IS NULL
+		AND m.created_at >= ? AND m.created_at < ?
+		AND NOT EXISTS (SELECT 1 FROM messages msg WHERE msg.conversation_id = c.id AND msg.sender_id = ?
+			AND msg.message_type != 'system' AND msg.deleted_at IS NULL)`,
+		userID, userID, weekStart, weekEnd, userID).Scan(&digest.UnopenedMatches).Error; err != nil {
+		return err
+	}
+	// Views are kept per day for a week, so these are the last seven days
+	// rather than exactly the digest's week
+	if views, err := rc.ProfileViews(ctx, userID, 7); err == nil {
+		digest.ProfileViews = views
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&digest)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	if digest.NewLikes == 0 && digest.NewMatches == 0 && digest.UnopenedMatches == 0 && digest.ProfileViews == 0 {
+		return nil
+	}
+
+	var prefs models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).First(&prefs).Error; err == nil && !prefs.WeeklyDigest {
+		return nil
+	}
+
+	body := digestBody(digest)
+	notification := models.Notification{
+		UserID: userID,
+		Type:   "weekly_digest",
+		Title:  "Your week on the app",
+		Body:   body,
+		Data:   `{"digest_id": ` + strconv.FormatUint(uint64(digest.ID), 10) + `}`,
+	}
+	if err := db.Create(&notification).Error; err != nil {
+		return err
+	}
+	notifier.Deliver(ctx, services.Notice{Notification: notification, Text: "Your week on the app: " + body})
+	return nil
+}
+
+// digestBody lists the week's non-zero numbers, e.g. "12 people viewed your
+// profile, 3 new likes and 1 match waiting for your first message."
+func digestBody(d models.WeeklyDigest) string {
+	var parts []string
+	if d.ProfileViews > 0 {
+		parts = append(parts, plural(d.ProfileViews, "person", "people")+" viewed your profile")
+	}
+	if d.NewLikes > 0 {
+		parts = append(parts, plural(d.NewLikes, "new like", "new likes"))
+	}
+	if d.NewMatches > 0 {
+		parts = append(parts, plural(d.NewMatches, "new match", "new matches"))
+	}
+	if d.UnopenedMatches > 0 {
+		parts = append(parts, plural(d.UnopenedMatches, "match", "matches")+" waiting for your first message")
+	}
+
+	body := parts[len(parts)-1]
+	if len(parts) > 1 {
+		body = strings.Join(parts[:len(parts)-1], ", ") + " and " + body
+	}
+	return strings.ToUpper(body[:1]) + body[1:] + "."
+}
+
+func plural(n int64, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DisappearingMessages hard-deletes read messages in conversations with
+// disappearing mode enabled once they are older than the conversation's window.
+// Only messages sent after the mode was switched on are affected, and system
+// messages are kept so both users can see when the mode changed. Deleted
+// messages leave a tombstone so other devices drop them on their next sync.
+func DisappearingMessages(db *gorm.DB) Job {
+	return Job{
+		Name:     "disappearing_messages",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			result := db.WithContext(ctx).Exec(`
+				WITH deleted AS (
+					DELETE FROM messages
+					USING conversations
+					WHERE messages.conversation_id = conversations.id
+					AND conversations.disappear_after_hours IS NOT NULL
+					AND messages.message_type != 'system'
+					AND messages.created_at >= conversations.disappearing_since
+					AND messages.read_at IS NOT NULL
+					AND messages.read_at < NOW() - conversations.disappear_after_hours * INTERVAL '1 hour'
+					RETURNING messages.id, messages.conversation_id
+				)
+				INSERT INTO message_tombstones (message_id, conversation_id, deleted_at)
+				SELECT id, conversation_id, NOW() FROM deleted`)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected > 0 {
+				log.Printf("Deleted %d disappearing messages", result.RowsAffected)
+			}
+
+			return db.WithContext(ctx).Where("deleted_at < ?", time.Now().Add(-models.MessageTombstoneRetention)).
+				Delete(&models.MessageTombstone{}).Error
+		},
+	}
+}
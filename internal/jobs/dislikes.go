@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExpiredDislikes deletes dislikes that no longer hide anyone from
+// discovery. Users in a city with its own expiry go by that, everyone else
+// by expiry; an expiry of 0 keeps dislikes for good.
+func ExpiredDislikes(db *gorm.DB, expiry time.Duration) Job {
+	return Job{
+		Name:     "expired_dislikes",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+			now := time.Now()
+
+			var cities []models.LaunchCity
+			if err := db.Where("dislike_expiry_days IS NOT NULL").Find(&cities).Error; err != nil {
+				return err
+			}
+			overridden := make([]string, 0, len(cities))
+			for _, city := range cities {
+				overridden = append(overridden, city.CityKey)
+				if *city.DislikeExpiryDays == 0 {
+					continue
+				}
+				cutoff := now.Add(-time.Duration(*city.DislikeExpiryDays) * 24 * time.Hour)
+				if err := db.Where("created_at < ? AND disliker_id IN (SELECT id FROM users WHERE "+models.UserCityKeySQL+" = ?)", cutoff, city.CityKey).
+					Delete(&models.Dislike{}).Error; err != nil {
+					return err
+				}
+			}
+
+			if expiry <= 0 {
+				return nil
+			}
+			query := db.Where("created_at < ?", now.Add(-expiry))
+			if len(overridden) > 0 {
+				query = query.Where("disliker_id NOT IN (SELECT id FROM users WHERE "+models.UserCityKeySQL+" IN ?)", overridden)
+			}
+			return query.Delete(&models.Dislike{}).Error
+		},
+	}
+}
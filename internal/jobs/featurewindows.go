@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+const (
+	featureWindowBatchSize  = 500
+	featureWindowBatchPause = time.Second
+)
+
+// FeatureWindowAnnouncements tells users a feature window that announces
+// itself has opened: active users without premium in its segment get an
+// in-app notification, also sent over the notifier's channels in the
+// background, a batch at a time. Each opening is announced once; progress is
+// saved with each batch, so an announcement cut short carries on from the
+// next user on the next run while the window is still open.
+func FeatureWindowAnnouncements(db *gorm.DB, notifier *services.Dispatcher, loc *time.Location) Job {
+	return Job{
+		Name:     "feature_window_announcements",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+			now := time.Now()
+
+			var windows []models.FeatureWindow
+			if err := db.Where("is_active = ? AND announce = ?", true, true).Find(&windows).Error; err != nil {
+				return err
+			}
+			for _, window := range windows {
+				opens, closes, ok := window.Opening(now, loc)
+				if !ok {
+					continue
+				}
+				var after uint
+				switch {
+				case window.AnnouncedAt == nil || window.AnnouncedAt.Before(opens):
+					result := db.Model(&models.FeatureWindow{}).
+						Where("id = ? AND (announced_at IS NULL OR announced_at < ?)", window.ID, opens).
+						Updates(map[string]interface{}{"announced_at": opens, "announced_through": 0})
+					if result.Error != nil {
+						return result.Error
+					}
+					if result.RowsAffected == 0 {
+						continue
+					}
+				case window.AnnouncedThrough != nil:
+					after = *window.AnnouncedThrough
+				default:
+					continue
+				}
+				if err := announceFeatureWindow(ctx, db, notifier, window, after, closes.In(loc)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// announceFeatureWindow notifies the window's segment from the user after
+// lastID on
+func announceFeatureWindow(ctx context.Context, db *gorm.DB, notifier *services.Dispatcher, window models.FeatureWindow, lastID uint, closes time.Time) error {
+	title := window.Name
+	body := fmt.Sprintf("%s free for everyone until %s", featureList(window.Features), closes.Format("15:04"))
+	if !window.ForEveryone() {
+		body = fmt.Sprintf("%s free for you until %s", featureList(window.Features), closes.Format("15:04"))
+	}
+	data := `{"feature_window_id": ` + strconv.FormatUint(uint64(window.ID), 10) + `}`
+
+	for {
+		query := db.Model(&models.User{}).
+			Where("id > ? AND is_active = ? AND is_premium = ?", lastID, true, false)
+		if len(window.Genders) > 0 {
+			query = query.Where("gender IN ?", window.Genders)
+		}
+		if len(window.Locations) > 0 {
+			locations := make([]string, len(window.Locations))
+			for i, location := range window.Locations {
+				locations[i] = strings.ToLower(strings.TrimSpace(location))
+			}
+			query = query.Where("LOWER(TRIM(location)) IN ?", locations)
+		}
+
+		var userIDs []uint
+		if err := query.Order("id").Limit(featureWindowBatchSize).Pluck("id", &userIDs).Error; err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return db.Model(&models.FeatureWindow{}).Where("id = ?", window.ID).Update("announced_through", nil).Error
+		}
+
+		notifications := make([]models.Notification, len(userIDs))
+		for i, userID := range userIDs {
+			notifications[i] = models.Notification{
+				UserID: userID,
+				Type:   "feature_window",
+				Title:  title,
+				Body:   body,
+				Data:   data,
+			}
+		}
+		lastID = userIDs[len(userIDs)-1]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&notifications).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.FeatureWindow{}).Where("id = ?", window.ID).Update("announced_through", lastID).Error
+		})
+		if err != nil {
+			return err
+		}
+		for _, notification := range notifications {
+			notifier.Dispatch(services.Notice{Notification: notification, Text: title + ": " + body})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(featureWindowBatchPause):
+		}
+	}
+}
+
+// featureList names features for people, e.g. "See who liked you and
+// unlimited likes"
+func featureList(features []string) string {
+	names := make([]string, len(features))
+	for i, feature := range features {
+		names[i] = strings.ReplaceAll(feature, "_", " ")
+	}
+	list := names[len(names)-1]
+	if len(names) > 1 {
+		list = strings.Join(names[:len(names)-1], ", ") + " and " + list
+	}
+	return strings.ToUpper(list[:1]) + list[1:]
+}
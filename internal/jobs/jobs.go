@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+)
+
+// jobLockTTL is how long a job run holds its lock between renewals, so a
+// replica that dies mid-run frees the job quickly
+const jobLockTTL = time.Minute
+
+// Job is a periodic background task
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start runs each job on its own ticker until ctx is cancelled. Every
+// replica ticks, but only one of them runs a job each interval, and never
+// while another run of it is still going.
+func Start(ctx context.Context, rc *redis.Client, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, rc, job)
+	}
+}
+
+func run(ctx context.Context, rc *redis.Client, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	log.Printf("Job %s scheduled every %s", job.Name, job.Interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := runOnce(ctx, rc, job); err != nil {
+				log.Printf("Job %s failed: %v", job.Name, err)
+			}
+		}
+	}
+}
+
+// runOnce runs the job unless another replica already ran it this interval
+// or is still running it
+func runOnce(ctx context.Context, rc *redis.Client, job Job) error {
+	// The claim lapses a little before the next tick so drift between the
+	// tickers of different replicas doesn't skip intervals
+	claimed, err := rc.SetNX(ctx, "job_tick:"+job.Name, time.Now().Unix(), job.Interval*9/10)
+	if err != nil || !claimed {
+		return err
+	}
+
+	lock, err := rc.Obtain(ctx, "job_lock:"+job.Name, jobLockTTL, redis.LockOptions{AutoRenew: true})
+	if errors.Is(err, redis.ErrLockNotAcquired) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Release(context.Background())
+
+	return job.Run(ctx)
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// MatchFeedbackPrompts asks users how a match went once they have been
+// chatting for a month. Matches that ended earlier were asked on unmatch.
+// Only matches from the last two months qualify, so turning the job on
+// doesn't ask about every old match at once.
+func MatchFeedbackPrompts(db *gorm.DB, notifier *services.Dispatcher) Job {
+	return Job{
+		Name:     "match_feedback_prompts",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+
+			var matchIDs []uint
+			if err := db.Raw(`
+				SELECT m.id FROM matches m
+				JOIN conversations c ON c.match_id = m.id
+				WHERE m.is_active AND m.deleted_at IS NULL AND m.feedback_asked_at IS NULL
+				AND m.created_at <= NOW() - INTERVAL '30 days' AND m.created_at > NOW() - INTERVAL '60 days'
+				AND EXISTS (SELECT 1 FROM messages msg WHERE msg.conversation_id = c.id AND msg.sender_id = m.user1_id
+					AND msg.deleted_at IS NULL)
+				AND EXISTS (SELECT 1 FROM messages msg WHERE msg.conversation_id = c.id AND msg.sender_id = m.user2_id
+					AND msg.deleted_at IS NULL)`).Scan(&matchIDs).Error; err != nil {
+				return err
+			}
+
+			for _, matchID := range matchIDs {
+				if _, err := services.AskForMatchFeedback(db, notifier, matchID, services.FeedbackAfterMonth); err != nil {
+					log.Printf("Failed to ask for feedback on match %d: %v", matchID, err)
+				}
+			}
+			return nil
+		},
+	}
+}
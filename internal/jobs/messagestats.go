@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MessageStats recomputes every user's reply rate and median first-reply time
+// from the last 30 days of messages. A conversation counts toward a user when
+// their match sent the first message; it counts as replied once the user sent
+// any message back.
+func MessageStats(db *gorm.DB) Job {
+	return Job{
+		Name:     "message_stats",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			return db.WithContext(ctx).Exec(`
+				WITH firsts AS (
+					SELECT conversation_id, sender_id, MIN(created_at) AS first_at
+					FROM messages
+					WHERE message_type != 'system' AND deleted_at IS NULL
+					AND created_at >= NOW() - INTERVAL '30 days'
+					GROUP BY conversation_id, sender_id
+				),
+				openers AS (
+					SELECT DISTINCT ON (conversation_id) conversation_id, sender_id AS opener_id, first_at
+					FROM firsts
+					ORDER BY conversation_id, first_at
+				),
+				pairs AS (
+					SELECT o.conversation_id, o.first_at AS opened_at,
+						CASE WHEN m.user1_id = o.opener_id THEN m.user2_id ELSE m.user1_id END AS responder_id
+					FROM openers o
+					JOIN conversations c ON c.id = o.conversation_id
+					JOIN matches m ON m.id = c.match_id
+				)
+				INSERT INTO message_stats (user_id, conversations_opened, replied, reply_rate, median_reply_minutes, computed_at)
+				SELECT p.responder_id,
+					COUNT(*),
+					COUNT(f.first_at),
+					COUNT(f.first_at)::float / COUNT(*),
+					percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM (f.first_at - p.opened_at)) / 60),
+					NOW()
+				FROM pairs p
+				LEFT JOIN firsts f ON f.conversation_id = p.conversation_id AND f.sender_id = p.responder_id
+				GROUP BY p.responder_id
+				ON CONFLICT (user_id) DO UPDATE SET
+					conversations_opened = EXCLUDED.conversations_opened,
+					replied = EXCLUDED.replied,
+					reply_rate = EXCLUDED.reply_rate,
+					median_reply_minutes = EXCLUDED.median_reply_minutes,
+					computed_at = EXCLUDED.computed_at`).Error
+		},
+	}
+}
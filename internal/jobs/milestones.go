@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type milestone struct {
+	name  string
+	title string
+	body  string // formatted with the other user's first name
+	query string // selects match_id for matches that just reached the milestone
+}
+
+// Only matches from the last two weeks qualify for one_week, so turning the
+// job on doesn't congratulate every old match at once
+var milestones = []milestone{
+	{
+		name:  "one_week",
+		title: "One week together 🎉",
+		body:  "You and %s matched a week ago and you're still chatting. Maybe it's time to plan a coffee?",
+		query: `
+			SELECT m.id AS match_id FROM matches m
+			JOIN conversations c ON c.match_id = m.id
+			WHERE m.is_active AND m.deleted_at IS NULL
+			AND m.created_at <= NOW() - INTERVAL '7 days' AND m.created_at > NOW() - INTERVAL '14 days'
+			AND EXISTS (SELECT 1 FROM messages msg WHERE msg.conversation_id = c.id AND msg.sender_id = m.user1_id
+				AND msg.created_at >= NOW() - INTERVAL '3 days' AND msg.deleted_at IS NULL)
+			AND EXISTS (SELECT 1 FROM messages msg WHERE msg.conversation_id = c.id AND msg.sender_id = m.user2_id
+				AND msg.created_at >= NOW() - INTERVAL '3 days' AND msg.deleted_at IS NULL)
+			AND NOT EXISTS (SELECT 1 FROM match_milestones mm WHERE mm.match_id = m.id AND mm.milestone = 'one_week')`,
+	},
+	{
+		name:  "hundred_messages",
+		title: "100 messages! 💬",
+		body:  "You and %s just passed 100 messages. Looks like you have a lot to talk about!",
+		query: `
+			SELECT m.id AS match_id FROM matches m
+			JOIN conversations c ON c.match_id = m.id
+			WHERE m.is_active AND m.deleted_at IS NULL
+			AND (SELECT COUNT(*) FROM messages msg WHERE msg.conversation_id = c.id
+				AND msg.message_type != 'system' AND msg.deleted_at IS NULL) >= 100
+			AND NOT EXISTS (SELECT 1 FROM match_milestones mm WHERE mm.match_id = m.id AND mm.milestone = 'hundred_messages')`,
+	},
+}
+
+// MatchMilestones sends playful notifications to both users when a match
+// reaches a milestone. Users who turned off milestone notifications are skipped,
+// but the milestone is still recorded so it won't fire later.
+func MatchMilestones(db *gorm.DB) Job {
+	return Job{
+		Name:     "match_milestones",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+
+			for _, m := range milestones {
+				var matchIDs []uint
+				if err := db.Raw(m.query).Scan(&matchIDs).Error; err != nil {
+					return err
+				}
+
+				for _, matchID := range matchIDs {
+					record := models.MatchMilestone{MatchID: matchID, Milestone: m.name}
+					result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+					if result.Error != nil || result.RowsAffected == 0 {
+						continue
+					}
+
+					var match models.Match
+					if err := db.Preload("User1").Preload("User2").Where("id = ?", matchID).First(&match).Error; err != nil {
+						continue
+					}
+					notifyMilestone(db, m, match.User1ID, match.User2.FirstName, matchID)
+					notifyMilestone(db, m, match.User2ID, match.User1.FirstName, matchID)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func notifyMilestone(db *gorm.DB, m milestone, userID uint, otherName string, matchID uint) {
+	var prefs models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).First(&prefs).Error; err == nil && !prefs.Milestones {
+		return
+	}
+
+	notification := models.Notification{
+		UserID: userID,
+		Type:   "milestone",
+		Title:  m.title,
+		Body:   fmt.Sprintf(m.body, otherName),
+		Data:   `{"match_id": ` + strconv.FormatUint(uint64(matchID), 10) + `, "milestone": "` + m.name + `"}`,
+	}
+	db.Create(&notification)
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PausedAccounts resumes paused accounts whose chosen resume date has passed
+func PausedAccounts(db *gorm.DB) Job {
+	return Job{
+		Name:     "paused_accounts",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			result := db.WithContext(ctx).Exec(`
+				UPDATE users
+				SET is_paused = false, paused_until = NULL, version = version + 1
+				WHERE is_paused AND paused_until IS NOT NULL AND paused_until <= NOW()`)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected > 0 {
+				log.Printf("Resumed %d paused accounts", result.RowsAffected)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const preferenceChangeBatch = 100
+
+// PreferenceRevalidation works through users who changed their gender or who
+// they're seeking: their swipe decks are dropped so the next one is built
+// for the new preferences, and the change goes in their activity log. With
+// hideIncompatible, matches the change made incompatible are hidden from
+// both users' lists, and hidden ones that fit again come back.
+func PreferenceRevalidation(db *gorm.DB, rc *redis.Client, hideIncompatible bool) Job {
+	return Job{
+		Name:     "preference_revalidation",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			// Changes a previous run failed on are tried again first
+			if err := rc.RequeuePreferenceChanges(ctx); err != nil {
+				return err
+			}
+
+			var revalidated, hidden, shown int
+			for {
+				changes, err := rc.TakePreferenceChanges(ctx, preferenceChangeBatch)
+				if err != nil {
+					return err
+				}
+				for _, change := range changes {
+					h, s, err := revalidatePreferences(ctx, db, rc, change, hideIncompatible)
+					if err != nil {
+						log.Printf("Failed to revalidate preferences of user %d: %v", change.UserID, err)
+						continue
+					}
+					if err := rc.AckPreferenceChange(ctx, change); err != nil {
+						log.Printf("Failed to acknowledge preference change of user %d: %v", change.UserID, err)
+					}
+					revalidated++
+					hidden += h
+					shown += s
+				}
+				if len(changes) < preferenceChangeBatch {
+					break
+				}
+			}
+
+			if revalidated > 0 {
+				log.Printf("Revalidated %d preference changes, hid %d matches and restored %d", revalidated, hidden, shown)
+			}
+			return nil
+		},
+	}
+}
+
+// revalidatePreferences applies one change and returns how many matches it
+// hid and restored
+func revalidatePreferences(ctx context.Context, db *gorm.DB, rc *redis.Client, change redis.PreferenceChange, hideIncompatible bool) (int, int, error) {
+	if _, err := rc.DropDecks(ctx, change.UserID); err != nil {
+		return 0, 0, err
+	}
+
+	for _, field := range change.Fields {
+		if err := db.WithContext(ctx).Create(&models.UserActivity{
+			UserID:    change.UserID,
+			Action:    field + "_changed",
+			CreatedAt: change.At,
+		}).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	if !hideIncompatible {
+		return 0, 0, nil
+	}
+
+	var user models.User
+	if err := db.WithContext(ctx).Select("id", "gender", "seeking").Where("id = ?", change.UserID).First(&user).Error; err != nil {
+		return 0, 0, err
+	}
+	var matches []models.Match
+	if err := db.WithContext(ctx).Where("(user1_id = ? OR user2_id = ?) AND is_active = ?", user.ID, user.ID, true).
+		Preload("User1", selectPreferences).Preload("User2", selectPreferences).
+		Find(&matches).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var hide, show []uint
+	for _, match := range matches {
+		other := match.User1
+		if other.ID == user.ID {
+			other = match.User2
+		}
+		compatible := utils.GendersCompatible(user.Gender, user.Seeking, other.Gender, other.Seeking)
+		switch {
+		case !compatible && match.HiddenAt == nil:
+			hide = append(hide, match.ID)
+		case compatible && match.HiddenAt != nil:
+			show = append(show, match.ID)
+		}
+	}
+	if len(hide) > 0 {
+		if err := db.WithContext(ctx).Model(&models.Match{}).Where("id IN ?", hide).Update("hidden_at", time.Now()).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(show) > 0 {
+		if err := db.WithContext(ctx).Model(&models.Match{}).Where("id IN ?", show).Update("hidden_at", nil).Error; err != nil {
+			return len(hide), 0, err
+		}
+	}
+	return len(hide), len(show), nil
+}
+
+func selectPreferences(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "gender", "seeking")
+}
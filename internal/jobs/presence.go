@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+)
+
+// StalePresence takes users who have gone quiet off the active-nearby map
+func StalePresence(rc *redis.Client) Job {
+	return Job{
+		Name:     "stale_presence",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			removed, err := rc.PruneStalePresence(ctx)
+			if err != nil {
+				return err
+			}
+
+			if removed > 0 {
+				log.Printf("Removed %d inactive users from the nearby map", removed)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// PublicStats refreshes the numbers behind the public stats endpoint, so the
+// marketing site never causes a query
+func PublicStats(db *gorm.DB, rc *redis.Client) Job {
+	return Job{
+		Name:     "public_stats",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			stats, err := services.ComputePublicStats(ctx, db)
+			if err != nil {
+				return err
+			}
+			return services.SavePublicStats(ctx, rc, stats)
+		},
+	}
+}
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// maxEscalatedReports caps how many overdue reports one run escalates; the
+// rest wait for the next run
+const maxEscalatedReports = 100
+
+// ReportSLAs escalates open reports that are past their SLA. Each is put on
+// the ops feed and every active super admin is emailed the list, once per
+// report.
+func ReportSLAs(db *gorm.DB, rc *redis.Client, mailer services.Mailer, adminPanelURL string) Job {
+	return Job{
+		Name:     "report_slas",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			now := time.Now()
+			var reports []models.Report
+			if err := db.WithContext(ctx).
+				Where("status IN ? AND sla_due_at < ? AND escalated_at IS NULL", []string{"pending", "reviewed"}, now).
+				Order("sla_due_at ASC").Limit(maxEscalatedReports).
+				Find(&reports).Error; err != nil {
+				return err
+			}
+			if len(reports) == 0 {
+				return nil
+			}
+
+			// Marked first, so a failed email isn't retried into a flood
+			ids := make([]uint, 0, len(reports))
+			for _, report := range reports {
+				ids = append(ids, report.ID)
+			}
+			if err := db.WithContext(ctx).Model(&models.Report{}).Where("id IN ?", ids).Update("escalated_at", now).Error; err != nil {
+				return err
+			}
+
+			var lines []string
+			for _, report := range reports {
+				services.PublishOpsEvent(ctx, rc, services.OpsSLABreached, map[string]interface{}{
+					"report_id":   report.ID,
+					"severity":    report.Severity,
+					"sla_due_at":  report.SLADueAt,
+					"assigned_to": report.AssignedTo,
+				})
+
+				assignee := "unassigned"
+				if report.AssignedTo != nil {
+					assignee = fmt.Sprintf("claimed by admin %d", *report.AssignedTo)
+				}
+				lines = append(lines, fmt.Sprintf("- #%d (%s, %s): due %s, %s",
+					report.ID, report.Severity, report.Reason, report.SLADueAt.UTC().Format("Jan 2 15:04 MST"), assignee))
+			}
+
+			var superAdmins []models.Admin
+			if err := db.WithContext(ctx).Where("role = ? AND is_active = ?", "super_admin", true).Find(&superAdmins).Error; err != nil {
+				return err
+			}
+			body := fmt.Sprintf("%d reports are past their SLA:\n\n%s\n\nReview them at %s\n",
+				len(reports), strings.Join(lines, "\n"), adminPanelURL)
+			for _, admin := range superAdmins {
+				err := mailer.Send(ctx, admin.Email, "Reports past their SLA", body)
+				if err != nil && !errors.Is(err, services.ErrEmailDisabled) {
+					log.Printf("Failed to email SLA breaches to admin %d: %v", admin.ID, err)
+				}
+			}
+
+			log.Printf("Escalated %d reports past their SLA", len(reports))
+			return nil
+		},
+	}
+}
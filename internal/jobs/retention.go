@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// archiveBatchSize bounds how many conversations one run archives
+const archiveBatchSize = 100
+
+// MessageRetention archives the messages of conversations nobody wrote in for
+// longer than retention to storage and deletes them from the database.
+// Conversations restored within the retention period are left alone so a
+// restore isn't undone on the next run.
+func MessageRetention(db *gorm.DB, storage *services.StorageService, retention time.Duration) Job {
+	return Job{
+		Name:     "message_retention",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().Add(-retention)
+
+			var conversationIDs []uint
+			if err := db.WithContext(ctx).Raw(`
+				SELECT conversation_id FROM messages
+				WHERE conversation_id NOT IN (
+					SELECT conversation_id FROM conversation_archives WHERE restored_at > ?
+				)
+				GROUP BY conversation_id
+				HAVING MAX(created_at) < ?
+				LIMIT ?`, cutoff, cutoff, archiveBatchSize).Scan(&conversationIDs).Error; err != nil {
+				return err
+			}
+
+			archived := 0
+			for _, conversationID := range conversationIDs {
+				count, err := services.ArchiveConversation(ctx, db, storage, conversationID)
+				if err != nil {
+					log.Printf("Failed to archive conversation %d: %v", conversationID, err)
+					continue
+				}
+				archived += count
+			}
+
+			if archived > 0 {
+				log.Printf("Archived %d messages from %d inactive conversations", archived, len(conversationIDs))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/websocket"
+
+	"gorm.io/gorm"
+)
+
+// startWarningLead is how long before a first-message window closes the
+// conversation is told
+const startWarningLead = 2 * time.Hour
+
+// FirstMessageWarnings posts a system message in conversations whose
+// first-message window closes within the next two hours while the starter
+// hasn't written yet. Each match is warned at most once, and the warning
+// reaches open connections through the API instances' hubs.
+func FirstMessageWarnings(db *gorm.DB, relay *websocket.Relay) Job {
+	return Job{
+		Name:     "first_message_warnings",
+		Interval: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+			now := time.Now()
+
+			var due []struct {
+				MatchID        uint
+				ConversationID uint
+				StarterID      uint
+				User1ID        uint
+				User2ID        uint
+				StartBy        time.Time
+			}
+			if err := db.Raw(`
+				SELECT m.id AS match_id, c.id AS conversation_id, m.starter_id, m.user1_id, m.user2_id, m.start_by FROM matches m
+				JOIN conversations c ON c.match_id = m.id
+				WHERE m.is_active AND m.deleted_at IS NULL AND m.starter_id IS NOT NULL
+				AND m.start_by > ? AND m.start_by <= ? AND m.start_warned_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM messages msg WHERE msg.conversation_id = c.id AND msg.sender_id = m.starter_id
+					AND msg.message_type != 'system' AND msg.deleted_at IS NULL)`,
+				now, now.Add(startWarningLead)).Scan(&due).Error; err != nil {
+				return err
+			}
+
+			for _, d := range due {
+				result := db.Model(&models.Match{}).Where("id = ? AND start_warned_at IS NULL", d.MatchID).Update("start_warned_at", now)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					continue
+				}
+
+				vars := map[string]string{"time_left": formatTimeLeft(d.StartBy.Sub(now))}
+				message, err := services.CreateSystemMessage(db, d.ConversationID, d.StarterID, services.SystemStartExpiring, vars)
+				if err != nil {
+					log.Printf("Failed to warn conversation %d about its first-message window: %v", d.ConversationID, err)
+					continue
+				}
+				if message != nil {
+					relay.DeliverSystemMessage(ctx, d.ConversationID, []uint{d.User1ID, d.User2ID},
+						message.ID, d.StarterID, message.Content, message.SystemKind, message.CreatedAt)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// formatTimeLeft rounds up to whole hours, or minutes under an hour
+func formatTimeLeft(d time.Duration) string {
+	if d < time.Hour {
+		minutes := int((d + time.Minute - 1) / time.Minute)
+		return plural(int64(minutes), "minute", "minutes")
+	}
+	hours := int((d + time.Hour - 1) / time.Hour)
+	return plural(int64(hours), "hour", "hours")
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// StorageConsistency checks stored media against the database once a day
+// and saves the report for the admin dashboard. Nothing is deleted; orphaned
+// objects are removed with the storage-cleanup command.
+func StorageConsistency(db *gorm.DB, storage *services.StorageService, grace time.Duration) Job {
+	return Job{
+		Name:     "storage_consistency",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			audit, err := services.AuditStorage(ctx, db, storage, grace)
+			if err != nil {
+				return err
+			}
+			if err := db.WithContext(ctx).Create(&audit.Report).Error; err != nil {
+				return err
+			}
+
+			report := audit.Report
+			if report.MissingCount > 0 || report.UnresolvableCount > 0 || report.OrphanedCount > 0 {
+				log.Printf("Storage drift: %d of %d referenced files missing, %d unresolvable, %d placeholders, %d orphaned objects (%d bytes)",
+					report.MissingCount, report.ReferenceCount, report.UnresolvableCount, report.PlaceholderCount, report.OrphanedCount, report.OrphanedBytes)
+			}
+			return nil
+		},
+	}
+}
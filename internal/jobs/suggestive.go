@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+	"ethiopia-dating-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	suggestiveBioBatch   = 500
+	photoLabelBatch      = 100
+	photoLabelJobTimeout = 30 * time.Second
+
+	// Photos younger than this are left to the labeling done at upload
+	photoLabelBackfillAge = 10 * time.Minute
+)
+
+// SuggestiveBios checks every bio against the suggestive word lists once a
+// day, so bios written before clean mode existed, or before a word was
+// added to the lists, are blurred or hidden for users in clean mode
+func SuggestiveBios(db *gorm.DB) Job {
+	return Job{
+		Name:     "suggestive_bios",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			var lastID uint
+			for {
+				var users []models.User
+				if err := db.WithContext(ctx).Select("id", "bio", "bio_language", "bio_suggestive").
+					Where("id > ? AND bio IS NOT NULL AND bio != ''", lastID).
+					Order("id").Limit(suggestiveBioBatch).Find(&users).Error; err != nil {
+					return err
+				}
+				for _, user := range users {
+					// Bios saved before languages were detected have none
+					language := user.BioLanguage
+					if language == "" {
+						language = utils.DetectLanguage(*user.Bio)
+					}
+					if suggestive := utils.IsSuggestive(*user.Bio, language); suggestive != user.BioSuggestive {
+						if err := db.WithContext(ctx).Model(&models.User{}).Where("id = ?", user.ID).
+							Update("bio_suggestive", suggestive).Error; err != nil {
+							return err
+						}
+					}
+				}
+				if len(users) < suggestiveBioBatch {
+					break
+				}
+				lastID = users[len(users)-1].ID
+			}
+
+			lastID = 0
+			for {
+				var bios []models.ProfileBio
+				if err := db.WithContext(ctx).Select("id", "text", "language", "suggestive").
+					Where("id > ?", lastID).
+					Order("id").Limit(suggestiveBioBatch).Find(&bios).Error; err != nil {
+					return err
+				}
+				for _, bio := range bios {
+					if suggestive := utils.IsSuggestive(bio.Text, bio.Language); suggestive != bio.Suggestive {
+						if err := db.WithContext(ctx).Model(&models.ProfileBio{}).Where("id = ?", bio.ID).
+							Update("suggestive", suggestive).Error; err != nil {
+							return err
+						}
+					}
+				}
+				if len(bios) < suggestiveBioBatch {
+					return nil
+				}
+				lastID = bios[len(bios)-1].ID
+			}
+		},
+	}
+}
+
+// PhotoLabelBackfill scores photos that were never labeled, such as those
+// uploaded before clean mode existed or while the label service was down,
+// a batch at a time. Nothing happens while no label service is configured.
+func PhotoLabelBackfill(db *gorm.DB, labeler services.PhotoLabeler, threshold float64) Job {
+	return Job{
+		Name:     "photo_label_backfill",
+		Interval: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			var photos []models.ProfilePhoto
+			if err := db.WithContext(ctx).Select("id", "url").
+				Where("suggestive_score IS NULL AND created_at < ?", time.Now().Add(-photoLabelBackfillAge)).
+				Order("id").Limit(photoLabelBatch).Find(&photos).Error; err != nil {
+				return err
+			}
+
+			for _, photo := range photos {
+				labelCtx, cancel := context.WithTimeout(ctx, photoLabelJobTimeout)
+				labels, err := labeler.Label(labelCtx, photo.URL)
+				cancel()
+				if errors.Is(err, services.ErrPhotoLabelerDisabled) {
+					return nil
+				}
+				if err != nil {
+					// Left unscored, so the next run tries it again
+					continue
+				}
+
+				if err := db.WithContext(ctx).Model(&models.ProfilePhoto{}).Where("id = ?", photo.ID).
+					Updates(map[string]interface{}{
+						"suggestive_score": labels.Suggestive,
+						"suggestive":       labels.Suggestive >= threshold,
+					}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Suspensions reactivates users whose temporary suspension has run out. Bans
+// have no end date and are left alone.
+func Suspensions(db *gorm.DB) Job {
+	return Job{
+		Name:     "suspensions",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			result := db.WithContext(ctx).Exec(`
+				UPDATE users
+				SET is_active = true, suspended_until = NULL, version = version + 1
+				WHERE suspended_until IS NOT NULL AND suspended_until <= NOW()`)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected > 0 {
+				log.Printf("Lifted %d expired suspensions", result.RowsAffected)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"gorm.io/gorm"
+)
+
+const trustBatchSize = 500
+
+// TrustScores recomputes every active user's trust score and level, which
+// throttle low-trust users and rank high-trust ones higher
+func TrustScores(db *gorm.DB, cfg *config.Config) Job {
+	return Job{
+		Name:     "trust_scores",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			now := time.Now()
+			var lastID uint
+			for {
+				var users []models.User
+				if err := db.WithContext(ctx).
+					Select("id", "phone_verified_at", "email_verified_at", "photo_verified_at", "id_verified", "created_at").
+					Where("id > ? AND is_active = ?", lastID, true).
+					Order("id").Limit(trustBatchSize).Find(&users).Error; err != nil {
+					return err
+				}
+				for _, user := range users {
+					trust, err := services.ComputeTrust(ctx, db, cfg, user, now)
+					if err != nil {
+						return err
+					}
+					if err := services.SaveTrust(ctx, db, user.ID, trust, now); err != nil {
+						return err
+					}
+				}
+				if len(users) < trustBatchSize {
+					return nil
+				}
+				lastID = users[len(users)-1].ID
+			}
+		},
+	}
+}
@@ -0,0 +1,156 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// virtualUser is one registered account driving requests against the server
+type virtualUser struct {
+	id     uint
+	email  string
+	token  string
+	gender string
+
+	http    *http.Client
+	baseURL string
+	stats   *Stats
+
+	// busy serializes message actions, which rely on the user's single
+	// websocket being joined to the conversation being written to
+	busy    sync.Mutex
+	ws      *websocket.Conn
+	pending sync.Map // message tag -> send time
+}
+
+// call sends a JSON request and decodes the JSON response into out. The
+// latency is recorded under op whether or not the call succeeds, except for
+// calls cut short by the end of the run.
+func (u *virtualUser) call(ctx context.Context, op, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.baseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return u.send(ctx, op, req, out)
+}
+
+// upload posts a file as the multipart form field and decodes the JSON
+// response into out, recording the latency like call
+func (u *virtualUser) upload(ctx context.Context, op, path, field, filename string, data []byte, out interface{}) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(field, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.baseURL+"/api/v1"+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return u.send(ctx, op, req, out)
+}
+
+func (u *virtualUser) send(ctx context.Context, op string, req *http.Request, out interface{}) error {
+	method, path := req.Method, strings.TrimPrefix(req.URL.Path, "/api/v1")
+	if u.token != "" {
+		req.Header.Set("Authorization", "Bearer "+u.token)
+	}
+
+	start := time.Now()
+	resp, err := u.http.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			err = fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, strings.TrimSpace(string(message)))
+		} else if out != nil {
+			err = json.NewDecoder(resp.Body).Decode(out)
+		}
+	}
+	if ctx.Err() == nil {
+		u.stats.Record(op, time.Since(start), err)
+	}
+	return err
+}
+
+// connect opens the user's websocket and starts reading from it
+func (u *virtualUser) connect(ctx context.Context) error {
+	wsURL, err := url.Parse(u.baseURL + "/api/v1/ws")
+	if err != nil {
+		return err
+	}
+	wsURL.Scheme = strings.Replace(wsURL.Scheme, "http", "ws", 1)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+u.token)
+
+	start := time.Now()
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), header)
+	u.stats.Record("ws_connect", time.Since(start), err)
+	if err != nil {
+		return err
+	}
+
+	u.ws = conn
+	go u.readLoop()
+	return nil
+}
+
+// readLoop matches echoed chat messages to the time they were sent, giving
+// the latency from the REST call until the websocket delivered the message
+func (u *virtualUser) readLoop() {
+	for {
+		_, data, err := u.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var event struct {
+			Type     string `json:"type"`
+			SenderID uint   `json:"sender_id"`
+			Content  string `json:"content"`
+		}
+		if json.Unmarshal(data, &event) != nil || event.Type != "message" || event.SenderID != u.id {
+			continue
+		}
+		if sent, ok := u.pending.LoadAndDelete(event.Content); ok {
+			u.stats.Record("ws_delivery", time.Since(sent.(time.Time)), nil)
+		}
+	}
+}
+
+func (u *virtualUser) close() {
+	if u.ws != nil {
+		u.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		u.ws.Close()
+	}
+}
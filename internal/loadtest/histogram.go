@@ -0,0 +1,146 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// bucketBounds are the upper bounds of the latency buckets. Anything slower
+// than the last bound lands in an overflow bucket.
+var bucketBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// Histogram counts latencies in fixed buckets, so memory stays constant no
+// matter how long a run lasts
+type Histogram struct {
+	counts []int64
+	count  int64
+	errors int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func newHistogram() *Histogram {
+	return &Histogram{counts: make([]int64, len(bucketBounds)+1)}
+}
+
+func (h *Histogram) record(d time.Duration, failed bool) {
+	if failed {
+		h.errors++
+		return
+	}
+
+	i := sort.Search(len(bucketBounds), func(i int) bool { return d <= bucketBounds[i] })
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Quantile returns the upper bound of the bucket holding the q-th quantile,
+// e.g. 0.99 for p99
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	target := int64(q * float64(h.count))
+	if target >= h.count {
+		target = h.count - 1
+	}
+
+	var seen int64
+	for i, n := range h.counts {
+		seen += n
+		if seen > target {
+			if i == len(bucketBounds) {
+				return h.max
+			}
+			return bucketBounds[i]
+		}
+	}
+	return h.max
+}
+
+// Stats holds one histogram per operation, e.g. "register" or "ws_delivery"
+type Stats struct {
+	mu      sync.Mutex
+	started time.Time
+	ops     map[string]*Histogram
+}
+
+func NewStats() *Stats {
+	return &Stats{started: time.Now(), ops: make(map[string]*Histogram)}
+}
+
+// Record adds one sample for op. Failed samples are counted but kept out of
+// the latency figures.
+func (s *Stats) Record(op string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.ops[op]
+	if !ok {
+		h = newHistogram()
+		s.ops[op] = h
+	}
+	h.record(d, err != nil)
+}
+
+// Succeeded returns how many samples of op completed without error
+func (s *Stats) Succeeded(op string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if h, ok := s.ops[op]; ok {
+		return h.count
+	}
+	return 0
+}
+
+// Report writes a table of throughput and latency percentiles per operation
+func (s *Stats) Report(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.started).Seconds()
+	names := make([]string, 0, len(s.ops))
+	for name := range s.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\tok\terrors\trate/s\tmean\tp50\tp90\tp99\tmax\t")
+	for _, name := range names {
+		h := s.ops[name]
+		var mean time.Duration
+		if h.count > 0 {
+			mean = h.sum / time.Duration(h.count)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%v\t≤%v\t≤%v\t≤%v\t%v\t\n",
+			name, h.count, h.errors, float64(h.count)/elapsed,
+			mean.Round(time.Microsecond), h.Quantile(0.5), h.Quantile(0.9), h.Quantile(0.99), h.max.Round(time.Microsecond))
+	}
+	tw.Flush()
+}
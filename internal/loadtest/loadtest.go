@@ -0,0 +1,313 @@
+// Package loadtest drives synthetic registration, swiping, and messaging
+// traffic against a running server and measures latency per operation. It is
+// meant for staging and local environments before launch, never production.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errNotDelivered = errors.New("message not delivered over websocket")
+
+// Config controls the shape of the generated load. Rates are totals per
+// second across all virtual users.
+type Config struct {
+	BaseURL      string        // e.g. http://localhost:8080
+	Users        int           // virtual users to register
+	RegisterRate float64       // registrations per second while ramping up
+	SwipeRate    float64       // discover + like/dislike per second
+	MessageRate  float64       // messages per second
+	Duration     time.Duration // how long to swipe and message after ramp-up
+	Timeout      time.Duration // per-request timeout
+}
+
+// Run registers cfg.Users accounts, connects each to the websocket, then
+// swipes and messages at the configured rates until cfg.Duration has passed
+// or ctx is cancelled. The returned stats are complete even when ctx ends
+// the run early.
+func Run(ctx context.Context, cfg Config) (*Stats, error) {
+	stats := NewStats()
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        cfg.Users,
+			MaxIdleConnsPerHost: cfg.Users,
+		},
+	}
+
+	users := register(ctx, cfg, client, stats)
+	if len(users) < 2 {
+		return stats, errors.New("fewer than two users registered and onboarded; is the server running with GIN_MODE=debug and storage configured?")
+	}
+	defer func() {
+		for _, u := range users {
+			u.busy.Lock()
+			u.close()
+			u.busy.Unlock()
+
+			// Messages the websocket never delivered count as failures
+			u.pending.Range(func(tag, _ interface{}) bool {
+				stats.Record("ws_delivery", 0, errNotDelivered)
+				return true
+			})
+		}
+	}()
+	log.Printf("Registered %d users, generating load for %s", len(users), cfg.Duration)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		every(ctx, cfg.SwipeRate, func() { swipe(ctx, users[rand.Intn(len(users))]) })
+	}()
+	go func() {
+		defer wg.Done()
+		every(ctx, cfg.MessageRate, func() { message(ctx, users[rand.Intn(len(users))]) })
+	}()
+	wg.Wait()
+
+	// Without discovery results nothing else the run measures happens, so
+	// an empty run is a broken setup rather than a result
+	if cfg.SwipeRate > 0 && stats.Succeeded("discover") == 0 {
+		return stats, errors.New("no discover request succeeded; see the discover errors in the report")
+	}
+	return stats, nil
+}
+
+// every calls fn in its own goroutine rate times per second until ctx ends,
+// and then waits for the calls still in flight. The schedule does not slow
+// down when the server does, so queueing shows up in the latencies.
+func every(ctx context.Context, rate float64, fn func()) {
+	if rate <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			inFlight.Add(1)
+			go func() {
+				defer inFlight.Done()
+				fn()
+			}()
+		}
+	}
+}
+
+// register creates the virtual users at cfg.RegisterRate and takes each
+// through the onboarding discovery needs. With OTP enabled the server returns
+// the code in development, which is used to verify.
+func register(ctx context.Context, cfg Config, client *http.Client, stats *Stats) []*virtualUser {
+	runID := time.Now().Unix()
+	var (
+		mu    sync.Mutex
+		users []*virtualUser
+		next  int64 = -1
+	)
+
+	rate := cfg.RegisterRate
+	if rate <= 0 {
+		rate = float64(cfg.Users)
+	}
+	rampCtx, cancel := context.WithCancel(ctx)
+	every(rampCtx, rate, func() {
+		i := atomic.AddInt64(&next, 1)
+		if i >= int64(cfg.Users) {
+			cancel()
+			return
+		}
+
+		u := &virtualUser{
+			email:   fmt.Sprintf("loadgen.%d.%d@example.com", runID, i),
+			gender:  []string{"male", "female"}[i%2],
+			http:    client,
+			baseURL: cfg.BaseURL,
+			stats:   stats,
+		}
+		if err := signUp(ctx, u); err != nil {
+			log.Printf("Failed to register %s: %v", u.email, err)
+			return
+		}
+		if err := u.connect(ctx); err != nil {
+			log.Printf("Failed to connect websocket for %s: %v", u.email, err)
+		}
+
+		mu.Lock()
+		users = append(users, u)
+		mu.Unlock()
+	})
+	cancel()
+
+	return users
+}
+
+func signUp(ctx context.Context, u *virtualUser) error {
+	seeking := map[string]string{"male": "women", "female": "men"}[u.gender]
+	dob := time.Now().AddDate(-(20 + rand.Intn(20)), 0, -rand.Intn(365))
+
+	var resp authResponse
+	err := u.call(ctx, "register", http.MethodPost, "/auth/register", map[string]string{
+		"email":         u.email,
+		"password":      "loadgen-password",
+		"first_name":    "Load",
+		"last_name":     "Test",
+		"date_of_birth": dob.Format("2006-01-02"),
+		"gender":        u.gender,
+		"seeking":       seeking,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.AccessToken == "" && resp.OTP != "" {
+		if err := u.call(ctx, "verify_otp", http.MethodPost, "/auth/verify-otp", map[string]string{
+			"email": u.email,
+			"code":  resp.OTP,
+		}, &resp); err != nil {
+			return err
+		}
+	}
+	if resp.AccessToken == "" {
+		return errors.New("no access token returned")
+	}
+
+	u.id, u.token = resp.User.ID, resp.AccessToken
+	return onboard(ctx, u, seeking)
+}
+
+// onboard does the onboarding steps discovery requires: adding a photo and
+// setting preferences. It fails if the server still won't let the user
+// discover anyone, since every swipe would then be refused.
+func onboard(ctx context.Context, u *virtualUser, seeking string) error {
+	photo, err := profilePhoto()
+	if err != nil {
+		return err
+	}
+	if err := u.upload(ctx, "upload_photo", "/users/profile/photo", "photo", "loadgen.png", photo, nil); err != nil {
+		return err
+	}
+	if err := u.call(ctx, "set_preferences", http.MethodPut, "/users/profile", map[string]string{"seeking": seeking}, nil); err != nil {
+		return err
+	}
+
+	var onboarding struct {
+		CurrentStep string `json:"current_step"`
+		CanDiscover bool   `json:"can_discover"`
+	}
+	if err := u.call(ctx, "onboarding", http.MethodGet, "/users/onboarding", nil, &onboarding); err != nil {
+		return err
+	}
+	if !onboarding.CanDiscover {
+		return fmt.Errorf("onboarding still blocks discovery at step %q", onboarding.CurrentStep)
+	}
+	return nil
+}
+
+var (
+	photoOnce sync.Once
+	photoPNG  []byte
+	photoErr  error
+)
+
+// profilePhoto returns a PNG large enough to pass the server's minimum image
+// size, made once per run
+func profilePhoto() ([]byte, error) {
+	photoOnce.Do(func() {
+		img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+		for y := 0; y < 400; y++ {
+			for x := 0; x < 400; x++ {
+				img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+			}
+		}
+		var buf bytes.Buffer
+		photoErr = png.Encode(&buf, img)
+		photoPNG = buf.Bytes()
+	})
+	return photoPNG, photoErr
+}
+
+type authResponse struct {
+	AccessToken string `json:"access_token"`
+	OTP         string `json:"otp"`
+	User        struct {
+		ID uint `json:"id"`
+	} `json:"user"`
+}
+
+// swipe loads a discovery page and likes or passes on one of the profiles
+func swipe(ctx context.Context, u *virtualUser) {
+	var page struct {
+		Users []struct {
+			ID uint `json:"id"`
+		} `json:"users"`
+	}
+	if err := u.call(ctx, "discover", http.MethodGet, "/users/discover", map[string]int{"page": 1, "limit": 10}, &page); err != nil || len(page.Users) == 0 {
+		return
+	}
+
+	target := page.Users[rand.Intn(len(page.Users))].ID
+	if rand.Intn(3) == 0 {
+		u.call(ctx, "dislike", http.MethodPost, fmt.Sprintf("/matches/dislike/%d", target), nil, nil)
+		return
+	}
+	u.call(ctx, "like", http.MethodPost, fmt.Sprintf("/matches/like/%d", target), nil, nil)
+}
+
+var messageSeq uint64
+
+// message sends a chat message in one of the user's conversations. The user's
+// websocket joins the conversation first so the broadcast comes back to it.
+func message(ctx context.Context, u *virtualUser) {
+	if !u.busy.TryLock() {
+		return // still waiting on its previous message
+	}
+	defer u.busy.Unlock()
+
+	var list struct {
+		Conversations []struct {
+			ID uint `json:"id"`
+		} `json:"conversations"`
+	}
+	if err := u.call(ctx, "conversations", http.MethodGet, "/messages/conversations", nil, &list); err != nil || len(list.Conversations) == 0 {
+		return
+	}
+	conversationID := list.Conversations[rand.Intn(len(list.Conversations))].ID
+
+	if u.ws != nil {
+		if err := u.ws.WriteJSON(map[string]interface{}{"type": "join_conversation", "conversation_id": conversationID}); err != nil {
+			u.stats.Record("ws_delivery", 0, err)
+			return
+		}
+	}
+
+	tag := fmt.Sprintf("loadgen message %d", atomic.AddUint64(&messageSeq, 1))
+	if u.ws != nil {
+		u.pending.Store(tag, time.Now())
+	}
+	if err := u.call(ctx, "send_message", http.MethodPost, fmt.Sprintf("/messages/conversations/%d", conversationID),
+		map[string]string{"content": tag}, nil); err != nil {
+		u.pending.Delete(tag)
+	}
+}
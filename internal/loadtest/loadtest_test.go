@@ -0,0 +1,111 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer answers the endpoints the load generator calls, and like the
+// real server refuses discovery until a user has added a photo and set their
+// preferences
+type fakeServer struct {
+	mu      sync.Mutex
+	nextID  int
+	photo   map[string]bool
+	seeking map[string]bool
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	onboarded := s.photo[token] && s.seeking[token]
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	switch route := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/api/v1"); {
+	case route == "POST /auth/register":
+		s.nextID++
+		reply(http.StatusCreated, map[string]interface{}{
+			"access_token": "token-" + strconv.Itoa(s.nextID),
+			"user":         map[string]int{"id": s.nextID},
+		})
+	case route == "POST /users/profile/photo":
+		file, _, err := r.FormFile("photo")
+		if err != nil {
+			reply(http.StatusBadRequest, map[string]string{"error": "No photo provided"})
+			return
+		}
+		defer file.Close()
+		if config, err := png.DecodeConfig(file); err != nil || config.Width < 200 || config.Height < 200 {
+			reply(http.StatusBadRequest, map[string]string{"error": "invalid image"})
+			return
+		}
+		s.photo[token] = true
+		reply(http.StatusCreated, map[string]string{"message": "Photo uploaded successfully"})
+	case route == "PUT /users/profile":
+		var req struct {
+			Seeking *string `json:"seeking"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Seeking != nil {
+			s.seeking[token] = true
+		}
+		reply(http.StatusOK, map[string]string{"message": "Profile updated"})
+	case route == "GET /users/onboarding":
+		reply(http.StatusOK, map[string]interface{}{"can_discover": onboarded})
+	case !onboarded && (route == "GET /users/discover" || strings.HasPrefix(route, "POST /matches/")):
+		reply(http.StatusForbidden, map[string]string{"code": "ONBOARDING_INCOMPLETE"})
+	case route == "GET /users/discover":
+		reply(http.StatusOK, map[string]interface{}{"users": []map[string]int{{"id": 1}, {"id": 2}}})
+	case strings.HasPrefix(route, "POST /matches/"):
+		reply(http.StatusOK, map[string]string{"message": "ok"})
+	case route == "GET /messages/conversations":
+		reply(http.StatusOK, map[string]interface{}{"conversations": []interface{}{}})
+	default:
+		reply(http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func TestRunOnboardsUsersBeforeSwiping(t *testing.T) {
+	server := httptest.NewServer(&fakeServer{photo: map[string]bool{}, seeking: map[string]bool{}})
+	defer server.Close()
+
+	stats, err := Run(context.Background(), Config{
+		BaseURL:      server.URL,
+		Users:        4,
+		RegisterRate: 100,
+		SwipeRate:    100,
+		MessageRate:  10,
+		Duration:     300 * time.Millisecond,
+		Timeout:      time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	for _, op := range []string{"register", "upload_photo", "set_preferences", "onboarding", "discover"} {
+		h, ok := stats.ops[op]
+		if !ok || h.count == 0 {
+			t.Errorf("no successful %s requests", op)
+			continue
+		}
+		if h.errors > 0 {
+			t.Errorf("%d %s requests failed", h.errors, op)
+		}
+	}
+}
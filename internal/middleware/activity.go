@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Activity records action in the signed-in user's activity history once the
+// handler has succeeded. Routes without a session, like login, set user_id
+// themselves when they succeed.
+func Activity(recorder *services.ActivityRecorder, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		userID, exists := c.Get("user_id")
+		if recorder == nil || !exists || c.Writer.Status() >= 400 {
+			return
+		}
+
+		recorder.Record(models.UserActivity{
+			UserID:     userID.(uint),
+			Action:     action,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.GetHeader("User-Agent"),
+			AppVersion: c.GetHeader(ClientVersionHeader),
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeyRequired authenticates partner requests by their X-API-Key header and
+// holds each key to its per-minute rate limit. The key is stored in the
+// context as "api_key".
+func APIKeyRequired(db *gorm.DB, rc *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		raw := c.GetHeader("X-API-Key")
+		if raw == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "X-API-Key header required"})
+			c.Abort()
+			return
+		}
+
+		var key models.APIKey
+		if err := db.WithContext(ctx).Where("key_hash = ? AND is_active = ?", utils.HashString(raw), true).First(&key).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			c.Abort()
+			return
+		}
+
+		if key.RateLimit > 0 {
+			window := time.Now().Truncate(time.Minute)
+			counter := fmt.Sprintf("ratelimit:apikey:%d:%d", key.ID, window.Unix())
+			count, err := rc.Incr(ctx, counter)
+			// Redis errors fail open, like the other rate limits
+			if err == nil {
+				if count == 1 {
+					rc.Expire(ctx, counter, 2*time.Minute)
+					// Once per window is often enough to show which keys are in use
+					db.WithContext(ctx).Model(&key).UpdateColumn("last_used_at", time.Now())
+				}
+
+				remaining := int64(key.RateLimit) - count
+				if remaining < 0 {
+					remaining = 0
+				}
+				c.Header("X-RateLimit-Limit", strconv.Itoa(key.RateLimit))
+				c.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+				c.Header("X-RateLimit-Reset", strconv.FormatInt(window.Add(time.Minute).Unix(), 10))
+
+				if count > int64(key.RateLimit) {
+					c.Header("Retry-After", strconv.Itoa(int(time.Until(window.Add(time.Minute)).Seconds())+1))
+					c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded", "code": "RATE_LIMITED"})
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		c.Set("api_key", key)
+		c.Next()
+	}
+}
+
+// ScopeRequired restricts a partner route to keys granted scope. It must run
+// after APIKeyRequired.
+func ScopeRequired(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("api_key")
+		key, ok := value.(models.APIKey)
+		if !ok || !key.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This API key lacks the " + scope + " scope"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -5,62 +5,123 @@ import (
 	"strings"
 
 	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
 	"ethiopia-dating-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
-func AuthRequired() gin.HandlerFunc {
+// bearerClaims validates the request's bearer token, answering and aborting
+// the request when it has no valid one
+func bearerClaims(c *gin.Context) (*utils.Claims, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		c.Abort()
+		return nil, false
+	}
+
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
+		c.Abort()
+		return nil, false
+	}
+
+	// Parse and validate token against the configured key set
+	claims, err := utils.ValidateToken(tokenString)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return nil, false
+	}
+
+	if claims.UserID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+		c.Abort()
+		return nil, false
+	}
+	return claims, true
+}
+
+// AuthRequired lets requests with a valid user token through, except from
+// users suspended or banned since the token was issued. Redis errors let the
+// request through.
+func AuthRequired(rc *redis.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+		claims, ok := bearerClaims(c)
+		if !ok {
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
+		// Admin IDs aren't user IDs, so admin tokens don't act as users, and
+		// restricted tokens only open their own routes
+		if claims.Admin || claims.Scope != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(utils.GetJWTSecret()), nil
-		})
+		if rc != nil {
+			if blocked, err := services.AccountBlocked(c.Request.Context(), rc, claims.UserID); err == nil && blocked {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended", "code": "ACCOUNT_BLOCKED"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Set user ID in context
+		c.Set("user_id", claims.UserID)
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+// AppealAuthRequired accepts only appeal tokens, issued to suspended and
+// banned users when they sign in, and puts the user's ID in the context
+func AppealAuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := bearerClaims(c)
+		if !ok {
 			return
 		}
 
-		// Extract user ID from claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		if claims.Scope != utils.ScopeAppeal {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Appeal token required"})
 			c.Abort()
 			return
 		}
 
-		userID, ok := claims["user_id"].(float64)
+		c.Set("user_id", claims.UserID)
+		c.Next()
+	}
+}
+
+// AdminAuthRequired accepts only admin tokens and puts the admin's ID in the
+// context as user_id
+func AdminAuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := bearerClaims(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+			return
+		}
+
+		if !claims.Admin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
 		}
 
-		// Set user ID in context
-		c.Set("user_id", uint(userID))
+		c.Set("user_id", claims.UserID)
 		c.Next()
 	}
 }
 
-func AdminRequired() gin.HandlerFunc {
+// AdminRequired loads the signed-in admin into the context, turning away
+// deactivated admins and those who still have to replace a temporary
+// password. It must run after AdminAuthRequired.
+func AdminRequired(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("user_id")
 		if !exists {
@@ -69,18 +130,16 @@ func AdminRequired() gin.HandlerFunc {
 			return
 		}
 
-		// Get database from context (you'll need to pass this through)
-		db, exists := c.Get("db")
-		if !exists {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not available"})
+		// Check if user is admin
+		var admin models.Admin
+		if err := db.WithContext(c.Request.Context()).Where("id = ? AND is_active = ?", userID, true).First(&admin).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
 		}
 
-		// Check if user is admin
-		var admin models.Admin
-		if err := db.(*gorm.DB).Where("id = ? AND is_active = ?", userID, true).First(&admin).Error; err != nil {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if admin.MustChangePassword {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Choose a new password first", "code": "PASSWORD_CHANGE_REQUIRED"})
 			c.Abort()
 			return
 		}
@@ -90,6 +149,30 @@ func AdminRequired() gin.HandlerFunc {
 	}
 }
 
+// RoleRequired restricts a route to admins with one of the given roles. It must
+// run after AdminRequired, which loads the admin into the context.
+func RoleRequired(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("admin")
+		admin, ok := value.(models.Admin)
+		if !exists || !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if admin.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient admin role"})
+		c.Abort()
+	}
+}
+
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
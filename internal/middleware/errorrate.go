@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorRate counts server errors per minute across all instances and posts
+// an error_rate_spike event to the admin operations feed the moment a minute
+// reaches threshold. A threshold of zero turns the check off.
+func ErrorRate(rc *redis.Client, threshold int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if rc == nil || threshold <= 0 || c.Writer.Status() < http.StatusInternalServerError {
+			return
+		}
+
+		// The request context may be the reason for the error, so count with a
+		// fresh one
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		minute := time.Now().Truncate(time.Minute)
+		key := fmt.Sprintf("ops:errors:%d", minute.Unix())
+		count, err := rc.Incr(ctx, key)
+		if err != nil {
+			return
+		}
+		if count == 1 {
+			rc.Expire(ctx, key, 2*time.Minute)
+		}
+
+		// Exactly one request crosses the threshold, so the spike is reported once
+		if count == threshold {
+			services.PublishOpsEvent(ctx, rc, services.OpsErrorRateSpike, gin.H{
+				"errors":    count,
+				"minute":    minute,
+				"threshold": threshold,
+			})
+		}
+	}
+}
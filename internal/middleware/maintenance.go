@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceMessages are the default messages per language when an admin
+// hasn't set a custom one
+var maintenanceMessages = map[string]string{
+	"en": "We're doing some maintenance and will be back shortly.",
+	"am": "ጥገና እየተካሄደ ነው። እባክዎ ትንሽ ቆይተው ይሞክሩ።",
+}
+
+// maintenanceBypass lists paths that keep working during maintenance so
+// admins can sign in at /admin/auth/login and turn it off again, and clients
+// can learn about it from the app config. User sign-in is blocked like
+// everything else. /health and /.well-known/jwks.json are the only routes
+// this middleware isn't attached to.
+var maintenanceBypass = []string{
+	"/api/v1/admin/",
+	"/api/v1/app-config",
+}
+
+// Maintenance answers 503 with a Retry-After header while maintenance mode is
+// on. Admin routes and the app config endpoint are never blocked.
+func Maintenance(rc *redis.Client, cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, prefix := range maintenanceBypass {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		state := services.LoadMaintenance(c.Request.Context(), rc, cfg)
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+
+		message := state.Message
+		if message == "" || message == cfg.MaintenanceMessage {
+			message = maintenanceMessage(c.GetHeader("Accept-Language"))
+		}
+
+		c.Header("Retry-After", strconv.Itoa(state.RetryAfter))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":       message,
+			"code":        "MAINTENANCE",
+			"retry_after": state.RetryAfter,
+		})
+		c.Abort()
+	}
+}
+
+func maintenanceMessage(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		lang := strings.ToLower(strings.TrimSpace(strings.SplitN(tag, ";", 2)[0]))
+		if i := strings.Index(lang, "-"); i >= 0 {
+			lang = lang[:i]
+		}
+		if message, ok := maintenanceMessages[lang]; ok {
+			return message
+		}
+	}
+	return maintenanceMessages["en"]
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Onboarded keeps users who haven't finished the required onboarding steps
+// out of discovery. Must run after AuthRequired.
+func Onboarded(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		onboarding, err := services.GetDiscoveryOnboarding(db.WithContext(c.Request.Context()), userID.(uint))
+		if err == nil && !onboarding.CanDiscover {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":        "Finish setting up your profile to start discovering people",
+				"code":         "ONBOARDING_INCOMPLETE",
+				"current_step": onboarding.CurrentStep,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
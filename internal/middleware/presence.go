@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"ethiopia-dating-app/internal/redis"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Presence marks the signed-in user as active now for the active-nearby
+// feed and counts them in today's active users. Must run after AuthRequired.
+// Presence is best effort, so Redis errors never fail the request.
+func Presence(rc *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		userID, exists := c.Get("user_id")
+		if rc == nil || !exists {
+			return
+		}
+		rc.TouchPresence(c.Request.Context(), userID.(uint))
+		rc.RecordActiveUser(c.Request.Context(), userID.(uint))
+	}
+}
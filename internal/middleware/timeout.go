@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout bounds every request's context by d, or by routeTimeouts[route]
+// for routes that wait on uploads, keyed by the registered path like
+// BodyLimit. Database and Redis calls made with the context are cancelled
+// once the client has waited too long or gone away. A handler whose calls
+// failed because of the deadline answers 504 instead of its usual 500.
+// WebSocket upgrades are long-lived and never time out.
+func Timeout(d time.Duration, routeTimeouts map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := d
+		if routeTimeout, ok := routeTimeouts[c.FullPath()]; ok {
+			timeout = routeTimeout
+		}
+		if timeout <= 0 || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		}
+	}
+}
+
+// timeoutWriter replaces a server error written after the deadline passed
+// with a 504, since the error was almost certainly the cancelled query
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timedOut bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		code = http.StatusGatewayTimeout
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.timedOut {
+		return w.writeTimeout(len(data))
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.timedOut {
+		return w.writeTimeout(len(s))
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// writeTimeout sends the timeout body once and swallows the handler's own
+// error body, reporting it as written so the handler carries on normally
+func (w *timeoutWriter) writeTimeout(n int) (int, error) {
+	if !w.ResponseWriter.Written() {
+		if _, err := w.ResponseWriter.WriteString(`{"error":"Request timed out"}`); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
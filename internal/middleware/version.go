@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"ethiopia-dating-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientVersionHeader carries the app version of mobile clients
+const ClientVersionHeader = "X-App-Version"
+
+// MinClientVersion rejects clients older than minVersion with a structured
+// UPGRADE_REQUIRED error. Requests without a version header (web, internal
+// tools) are let through.
+func MinClientVersion(minVersion string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := c.GetHeader(ClientVersionHeader)
+		if version == "" || minVersion == "" || utils.CompareVersions(version, minVersion) >= 0 {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusUpgradeRequired, gin.H{
+			"error":          "This version of the app is no longer supported. Please update to continue.",
+			"code":           "UPGRADE_REQUIRED",
+			"client_version": version,
+			"min_version":    minVersion,
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"ethiopia-dating-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NotWaitlisted keeps users waiting for their city to launch out of
+// discovery and matching; they can still set up their profile meanwhile.
+// When the waitlist can't be read the request is refused rather than let
+// through. Must run after AuthRequired.
+func NotWaitlisted(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var entries []models.Waitlist
+		if err := db.WithContext(c.Request.Context()).Where("user_id = ?", userID).Limit(1).Find(&entries).Error; err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Please try again"})
+			c.Abort()
+			return
+		}
+		if len(entries) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "We haven't launched in your city yet. We'll let you know as soon as we do.",
+				"code":  "WAITLISTED",
+				"city":  entries[0].CityName,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
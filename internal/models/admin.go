@@ -7,16 +7,20 @@ import (
 )
 
 type Admin struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
-	PasswordHash string         `json:"-" gorm:"not null"`
-	FirstName    string         `json:"first_name" gorm:"not null"`
-	LastName     string         `json:"last_name" gorm:"not null"`
-	Role         string         `json:"role" gorm:"not null"` // super_admin, moderator, support
-	IsActive     bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Email              string         `json:"email" gorm:"uniqueIndex;not null"`
+	PasswordHash       string         `json:"-" gorm:"not null"`
+	FirstName          string         `json:"first_name" gorm:"not null"`
+	LastName           string         `json:"last_name" gorm:"not null"`
+	Role               string         `json:"role" gorm:"not null"`             // super_admin, moderator, support
+	Languages          []string       `json:"languages" gorm:"serializer:json"` // content languages this admin reviews; empty means all
+	IsActive           bool           `json:"is_active" gorm:"default:true"`
+	MustChangePassword bool           `json:"must_change_password"` // still on a temporary password
+	InvitedBy          *uint          `json:"invited_by,omitempty"`
+	LastLoginAt        *time.Time     `json:"last_login_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type Analytics struct {
@@ -28,15 +32,41 @@ type Analytics struct {
 	TotalMessages  int64     `json:"total_messages"`
 	MessagesToday  int64     `json:"messages_today"`
 	PendingReports int64     `json:"pending_reports"`
+	DAU            int64     `json:"dau" gorm:"-"` // distinct users making requests today
+	WAU            int64     `json:"wau" gorm:"-"`
+	MAU            int64     `json:"mau" gorm:"-"`
 	Date           time.Time `json:"date"`
 }
 
 type UserActivity struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"not null"` // login, logout, profile_update, etc.
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	AppVersion string    `json:"app_version,omitempty"` // X-App-Version of mobile clients
+	CreatedAt  time.Time `json:"created_at"`
+	User       User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type AdminAuditLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AdminID    uint      `json:"admin_id" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"not null"`      // user_status_updated, report_status_updated, etc.
+	TargetType string    `json:"target_type" gorm:"not null"` // user, report
+	TargetID   uint      `json:"target_id" gorm:"not null"`
+	Details    string    `json:"details,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Feedback is a structured survey answer, e.g. why a user unmatched or left
+type Feedback struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id" gorm:"not null"`
-	Action    string    `json:"action" gorm:"not null"` // login, logout, profile_update, etc.
-	IPAddress string    `json:"ip_address,omitempty"`
-	UserAgent string    `json:"user_agent,omitempty"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Context   string    `json:"context" gorm:"not null;index"` // unmatch, report, account_deletion, general
+	Reason    string    `json:"reason" gorm:"not null"`
+	Details   *string   `json:"details,omitempty"`
+	TargetID  *uint     `json:"target_id,omitempty"` // match or report the feedback is about
 	CreatedAt time.Time `json:"created_at"`
-	User      User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DateSpot is a place admins recommend for a first date in a city, such as
+// a café, park or restaurant
+type DateSpot struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"size:150;not null"`
+	Category    string         `json:"category" gorm:"not null;index"` // cafe, park, restaurant, other
+	City        string         `json:"city" gorm:"not null;index"`
+	Address     string         `json:"address"`
+	Description string         `json:"description"`
+	Latitude    float64        `json:"latitude"`
+	Longitude   float64        `json:"longitude"`
+	IsActive    bool           `json:"is_active" gorm:"not null;index"`
+	CreatedBy   uint           `json:"-"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// DateSuggestion is the structured payload of a "date_suggestion" message:
+// a date spot one user proposes to the other, who can accept or decline it
+type DateSuggestion struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	MessageID      uint       `json:"message_id" gorm:"uniqueIndex;not null"`
+	ConversationID uint       `json:"conversation_id" gorm:"not null;index"`
+	SuggesterID    uint       `json:"suggester_id" gorm:"not null"`
+	DateSpotID     uint       `json:"date_spot_id" gorm:"not null"`
+	ProposedFor    *time.Time `json:"proposed_for,omitempty"`
+	Status         string     `json:"status" gorm:"default:pending"` // pending, accepted, declined
+	RespondedAt    *time.Time `json:"responded_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DateSpot       DateSpot   `json:"date_spot" gorm:"foreignKey:DateSpotID"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// KeyBundle is a user's published end-to-end encryption keys, Signal-style:
+// a long-term identity key and a signed prekey. Only public keys are stored.
+type KeyBundle struct {
+	UserID                uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	IdentityKey           string    `json:"identity_key" gorm:"not null"` // base64
+	SignedPreKeyID        int       `json:"signed_prekey_id" gorm:"not null"`
+	SignedPreKey          string    `json:"signed_prekey" gorm:"not null"`           // base64
+	SignedPreKeySignature string    `json:"signed_prekey_signature" gorm:"not null"` // base64, by the identity key
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// OneTimePreKey is a public prekey handed out to at most one peer starting a
+// session with the user
+type OneTimePreKey struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	UserID    uint      `json:"-" gorm:"not null;uniqueIndex:idx_one_time_prekey"`
+	KeyID     int       `json:"key_id" gorm:"not null;uniqueIndex:idx_one_time_prekey"`
+	PublicKey string    `json:"public_key" gorm:"not null"` // base64
+	CreatedAt time.Time `json:"-"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// FeatureWindow is a weekly happy hour during which premium features are
+// free for everyone or a segment of users, such as Friday 20:00 to 22:00.
+// Times are in the app's time zone, and a window that ends before it
+// starts runs past midnight.
+type FeatureWindow struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name" gorm:"size:100;not null"`
+	Features    []string   `json:"features" gorm:"serializer:json"`   // premium features made free
+	Weekday     int        `json:"weekday"`                           // 0 is Sunday
+	StartTime   string     `json:"start_time" gorm:"size:5;not null"` // HH:MM
+	EndTime     string     `json:"end_time" gorm:"size:5;not null"`
+	Genders     []string   `json:"genders" gorm:"serializer:json"`   // empty means everyone
+	Locations   []string   `json:"locations" gorm:"serializer:json"` // empty means everywhere
+	IsActive    bool       `json:"is_active" gorm:"not null;index"`
+	Announce    bool       `json:"announce"`               // notify the segment when the window opens
+	AnnouncedAt *time.Time `json:"announced_at,omitempty"` // start of the last opening announced
+	// AnnouncedThrough is the last user ID told about the opening at
+	// AnnouncedAt while the announcement is under way, and nil once done
+	AnnouncedThrough *uint     `json:"-"`
+	CreatedBy        uint      `json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Opening returns the start and end of the window's opening that covers
+// now, if it is open
+func (w FeatureWindow) Opening(now time.Time, loc *time.Location) (time.Time, time.Time, bool) {
+	start, errStart := time.Parse("15:04", w.StartTime)
+	end, errEnd := time.Parse("15:04", w.EndTime)
+	if errStart != nil || errEnd != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	length := end.Sub(start)
+	if length <= 0 {
+		length += 24 * time.Hour
+	}
+
+	now = now.In(loc)
+	// An opening that runs past midnight may have started yesterday
+	for _, daysAgo := range []int{0, 1} {
+		day := now.AddDate(0, 0, -daysAgo)
+		if int(day.Weekday()) != w.Weekday {
+			continue
+		}
+		opens := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		closes := opens.Add(length)
+		if !now.Before(opens) && now.Before(closes) {
+			return opens, closes, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// Includes reports whether the user is in the window's segment
+func (w FeatureWindow) Includes(user User) bool {
+	if len(w.Genders) > 0 && !containsFold(w.Genders, user.Gender) {
+		return false
+	}
+	if len(w.Locations) > 0 && (user.Location == nil || !containsFold(w.Locations, *user.Location)) {
+		return false
+	}
+	return true
+}
+
+// ForEveryone reports whether the window has no segment
+func (w FeatureWindow) ForEveryone() bool {
+	return len(w.Genders) == 0 && len(w.Locations) == 0
+}
+
+func containsFold(values []string, value string) bool {
+	value = strings.TrimSpace(value)
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
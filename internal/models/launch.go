@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// UserCityKeySQL normalizes users.location the way city keys are, to match
+// users to launch cities in SQL
+const UserCityKeySQL = `LOWER(REGEXP_REPLACE(TRIM(location), '\s+', ' ', 'g'))`
+
+// LaunchCity is a city an admin has configured for launch. Only live cities
+// admit new users while launch gating is on.
+type LaunchCity struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	CityKey           string     `json:"city_key" gorm:"not null;uniqueIndex"` // normalized name used for matching
+	Name              string     `json:"name" gorm:"not null"`
+	Region            string     `json:"region" gorm:"index"`
+	IsLive            bool       `json:"is_live"`
+	OpenedAt          *time.Time `json:"opened_at,omitempty"`
+	OpenedBy          *uint      `json:"-"`
+	DislikeExpiryDays *int       `json:"dislike_expiry_days,omitempty"` // overrides DISLIKE_EXPIRY for users in the city; 0 keeps dislikes for good
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Waitlist holds a user who registered in a city that hasn't launched. They
+// are hidden from discovery and can't match until the city opens, when the
+// entry is removed.
+type Waitlist struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex"`
+	CityKey   string    `json:"city_key" gorm:"index"` // empty when the city is unknown
+	CityName  string    `json:"city_name"`
+	CreatedAt time.Time `json:"joined_at"`
+}
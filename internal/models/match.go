@@ -7,26 +7,81 @@ import (
 )
 
 type Match struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	User1ID   uint           `json:"user1_id" gorm:"not null"`
-	User2ID   uint           `json:"user2_id" gorm:"not null"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
-	User1     User           `json:"user1,omitempty" gorm:"foreignKey:User1ID"`
-	User2     User           `json:"user2,omitempty" gorm:"foreignKey:User2ID"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	User1ID         uint           `json:"user1_id" gorm:"not null"`
+	User2ID         uint           `json:"user2_id" gorm:"not null"`
+	IsActive        bool           `json:"is_active" gorm:"default:true"`
+	StarterID       *uint          `json:"starter_id,omitempty"` // the only user who may send the first message, until StartBy
+	StartBy         *time.Time     `json:"start_by,omitempty"`
+	StartWarnedAt   *time.Time     `json:"-"`                                // when the warning that StartBy is near went out
+	FeedbackAskedAt *time.Time     `json:"-"`                                // when both users were asked how the match went
+	HiddenAt        *time.Time     `json:"hidden_at,omitempty" gorm:"index"` // left out of match lists since a gender or seeking change made the pair incompatible
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	User1           User           `json:"user1,omitempty" gorm:"foreignKey:User1ID"`
+	User2           User           `json:"user2,omitempty" gorm:"foreignKey:User2ID"`
+}
+
+// LockFence keeps the latest fencing token that wrote under a Redis lock,
+// so a holder whose lock expired mid-write can be turned away
+type LockFence struct {
+	LockKey   string `gorm:"primaryKey;size:100"`
+	Token     int64  `gorm:"not null"`
+	UpdatedAt time.Time
 }
 
 type Like struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	LikerID   uint      `json:"liker_id" gorm:"not null"`
 	LikedID   uint      `json:"liked_id" gorm:"not null"`
+	PhotoID   *uint     `json:"photo_id,omitempty"`  // photo of the liked user this like is about
+	PromptID  *uint     `json:"prompt_id,omitempty"` // prompt answer of the liked user this like is about
+	Comment   *string   `json:"comment,omitempty"`   // becomes the first message if they match
 	CreatedAt time.Time `json:"created_at"`
 	Liker     User      `json:"liker,omitempty" gorm:"foreignKey:LikerID"`
 	Liked     User      `json:"liked,omitempty" gorm:"foreignKey:LikedID"`
 }
 
+// Compliment is a one-off note on one of another user's photos, sent without
+// liking them. The recipient can accept it, which matches them, or dismiss
+// it. Each user can compliment another only once.
+type Compliment struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	SenderID    uint         `json:"sender_id" gorm:"not null;uniqueIndex:idx_compliment_pair"`
+	RecipientID uint         `json:"recipient_id" gorm:"not null;uniqueIndex:idx_compliment_pair;index"`
+	PhotoID     uint         `json:"photo_id" gorm:"not null"`
+	Message     string       `json:"message" gorm:"size:150;not null"`
+	Status      string       `json:"status" gorm:"default:pending;index"` // pending, accepted, dismissed
+	MatchID     *uint        `json:"match_id,omitempty"`                  // set once accepted
+	RespondedAt *time.Time   `json:"responded_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Sender      User         `json:"-" gorm:"foreignKey:SenderID"`
+	Photo       ProfilePhoto `json:"-" gorm:"foreignKey:PhotoID"`
+}
+
+// PrivateAlbumGrant lets the other user of a match see the owner's private
+// photos, until the owner revokes it or the match ends
+type PrivateAlbumGrant struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	MatchID   uint       `json:"match_id" gorm:"not null;uniqueIndex:idx_album_grant"`
+	OwnerID   uint       `json:"owner_id" gorm:"not null;uniqueIndex:idx_album_grant"`
+	ViewerID  uint       `json:"viewer_id" gorm:"not null;index"`
+	GrantedAt time.Time  `json:"granted_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PrivatePhotoAccess records a viewer being handed a link to a private photo
+type PrivatePhotoAccess struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PhotoID   uint      `json:"photo_id" gorm:"not null;index"`
+	OwnerID   uint      `json:"owner_id" gorm:"not null;index"`
+	ViewerID  uint      `json:"viewer_id" gorm:"not null"`
+	MatchID   uint      `json:"match_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Dislike struct {
 	ID         uint      `json:"id" gorm:"primaryKey"`
 	DislikerID uint      `json:"disliker_id" gorm:"not null"`
@@ -37,14 +92,20 @@ type Dislike struct {
 }
 
 type Conversation struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	MatchID   uint           `json:"match_id" gorm:"not null"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
-	Match     Match          `json:"match,omitempty" gorm:"foreignKey:MatchID"`
-	Messages  []Message      `json:"messages,omitempty"`
+	ID                     uint           `json:"id" gorm:"primaryKey"`
+	MatchID                uint           `json:"match_id" gorm:"not null"`
+	IsActive               bool           `json:"is_active" gorm:"default:true"`
+	DisappearAfterHours    *int           `json:"disappear_after_hours,omitempty"` // nil when disappearing messages are off
+	DisappearingSince      *time.Time     `json:"disappearing_since,omitempty"`
+	DisappearProposedBy    *uint          `json:"disappear_proposed_by,omitempty"`
+	DisappearProposedHours *int           `json:"disappear_proposed_hours,omitempty"`
+	EncryptedSince         *time.Time     `json:"encrypted_since,omitempty"` // nil unless end-to-end encrypted
+	SafetyTipSentAt        *time.Time     `json:"-"`                         // the safety tip is posted once per conversation
+	CreatedAt              time.Time      `json:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
+	DeletedAt              gorm.DeletedAt `json:"-" gorm:"index"`
+	Match                  Match          `json:"match,omitempty" gorm:"foreignKey:MatchID"`
+	Messages               []Message      `json:"messages,omitempty"`
 }
 
 type Message struct {
@@ -52,16 +113,44 @@ type Message struct {
 	ConversationID uint           `json:"conversation_id" gorm:"not null"`
 	SenderID       uint           `json:"sender_id" gorm:"not null"`
 	Content        string         `json:"content" gorm:"not null"`
-	MessageType    string         `json:"message_type" gorm:"default:text"` // text, image, emoji
+	MessageType    string         `json:"message_type" gorm:"default:text"` // text, image, emoji, poll, system, like_comment, compliment
+	SystemKind     string         `json:"system_kind,omitempty"`            // what a system message is about, e.g. match_created
 	IsRead         bool           `json:"is_read" gorm:"default:false"`
 	ReadAt         *time.Time     `json:"read_at,omitempty"`
+	HasContactInfo bool           `json:"has_contact_info" gorm:"default:false"`
+	IsFlagged      bool           `json:"is_flagged" gorm:"default:false;index"` // queued for moderation
+	IsEncrypted    bool           `json:"is_encrypted" gorm:"default:false"`     // Content is ciphertext only the participants can read
+	HeldAt         *time.Time     `json:"held_at,omitempty" gorm:"index"`        // held for review because the sender's trust is low; hidden from the recipient until released
 	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"index"` // sync cursors walk this
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 	Conversation   Conversation   `json:"conversation,omitempty" gorm:"foreignKey:ConversationID"`
 	Sender         User           `json:"sender,omitempty" gorm:"foreignKey:SenderID"`
 }
 
+// ConversationArchive is a batch of messages the retention job moved out of
+// the database into storage. Restoring it puts the messages back.
+type ConversationArchive struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	ConversationID uint       `json:"conversation_id" gorm:"not null;index"`
+	ObjectKey      string     `json:"-" gorm:"not null"`
+	MessageCount   int        `json:"message_count"`
+	FirstMessageAt time.Time  `json:"first_message_at"`
+	LastMessageAt  time.Time  `json:"last_message_at"`
+	RestoredAt     *time.Time `json:"restored_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// SystemMessageTemplate is an admin's override of one kind of system
+// message. Kinds without a row use the built-in text.
+type SystemMessageTemplate struct {
+	Kind      string    `json:"kind" gorm:"primaryKey"`
+	Content   string    `json:"content" gorm:"not null"`
+	IsEnabled bool      `json:"is_enabled" gorm:"not null"`
+	UpdatedBy uint      `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type Notification struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	UserID    uint      `json:"user_id" gorm:"not null"`
@@ -73,3 +162,168 @@ type Notification struct {
 	CreatedAt time.Time `json:"created_at"`
 	User      User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
+
+// MessageTombstoneRetention is how long deletions are kept for sync. Devices
+// that last synced before that must start over.
+const MessageTombstoneRetention = 30 * 24 * time.Hour
+
+// MessageTombstone remembers a hard-deleted message long enough for every
+// device to sync the deletion
+type MessageTombstone struct {
+	MessageID      uint      `json:"message_id" gorm:"primaryKey;autoIncrement:false"`
+	ConversationID uint      `json:"conversation_id" gorm:"not null;index"`
+	DeletedAt      time.Time `json:"deleted_at" gorm:"not null;index"`
+}
+
+// SyncDevice is where one of a user's devices got to in message sync
+type SyncDevice struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	UserID       uint      `json:"-" gorm:"not null;uniqueIndex:idx_sync_device"`
+	DeviceID     string    `json:"device_id" gorm:"not null;size:64;uniqueIndex:idx_sync_device"`
+	Cursor       string    `json:"cursor"`
+	LastSyncedAt time.Time `json:"last_synced_at"`
+}
+
+// MessageStats is the nightly rollup of how a user responds to conversations
+// opened by their matches, over the trailing 30 days
+type MessageStats struct {
+	UserID              uint      `json:"user_id" gorm:"primaryKey"`
+	ConversationsOpened int       `json:"conversations_opened"` // opened by the other user
+	Replied             int       `json:"replied"`
+	ReplyRate           float64   `json:"reply_rate"`
+	MedianReplyMinutes  *float64  `json:"median_reply_minutes,omitempty"`
+	ComputedAt          time.Time `json:"computed_at"`
+}
+
+// MatchMilestone records that a milestone notification was sent for a match,
+// so each milestone fires once
+type MatchMilestone struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MatchID   uint      `json:"match_id" gorm:"not null;uniqueIndex:idx_match_milestone"`
+	Milestone string    `json:"milestone" gorm:"not null;uniqueIndex:idx_match_milestone"` // one_week, hundred_messages
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MatchFeedback is one user's rating of how a match went. Together with the
+// ranking signals for the pair it is a training label for recommendations.
+type MatchFeedback struct {
+	ID        uint               `json:"id" gorm:"primaryKey"`
+	MatchID   uint               `json:"match_id" gorm:"not null;uniqueIndex:idx_match_feedback"`
+	UserID    uint               `json:"user_id" gorm:"not null;uniqueIndex:idx_match_feedback"`
+	Rating    int                `json:"rating" gorm:"not null"`         // 1 to 5
+	Outcome   string             `json:"outcome,omitempty" gorm:"index"` // met_in_person, still_talking, no_spark, stopped_replying, felt_unsafe
+	Comment   *string            `json:"comment,omitempty"`
+	Unmatched bool               `json:"unmatched"`                       // whether the match had ended when rated
+	Features  map[string]float64 `json:"features" gorm:"serializer:json"` // ranking signals for the pair when rated
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// NotificationPreference holds a user's opt-outs per notification kind. Users
+// without a row get every kind. The columns have no database defaults because
+// GORM would replace an explicit false with a default of true on insert.
+type NotificationPreference struct {
+	UserID       uint      `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Matches      bool      `json:"matches" gorm:"not null"`
+	Messages     bool      `json:"messages" gorm:"not null"`
+	Likes        bool      `json:"likes" gorm:"not null"`
+	Milestones   bool      `json:"milestones" gorm:"not null"`
+	WeeklyDigest bool      `json:"weekly_digest" gorm:"not null"`
+	Marketing    bool      `json:"marketing" gorm:"not null"` // promotions such as feature windows; never texted
+	SMS          bool      `json:"sms" gorm:"not null"`       // opt-in: texts cost the user and us
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DefaultNotificationPreferences is what users get before saving their own:
+// every kind on, SMS off
+func DefaultNotificationPreferences(userID uint) NotificationPreference {
+	return NotificationPreference{UserID: userID, Matches: true, Messages: true, Likes: true, Milestones: true, WeeklyDigest: true, Marketing: true}
+}
+
+// Allows reports whether the user wants notifications of this kind outside
+// the app. Kinds without a preference are always allowed.
+func (p NotificationPreference) Allows(kind string) bool {
+	switch kind {
+	case "match":
+		return p.Matches
+	case "message":
+		return p.Messages
+	case "like", "photo_compliment":
+		return p.Likes
+	case "milestone":
+		return p.Milestones
+	case "weekly_digest":
+		return p.WeeklyDigest
+	case "feature_window":
+		return p.Marketing
+	}
+	return true
+}
+
+// IsMarketing reports whether notifications of this kind promote the app
+// rather than tell the user about something that happened to them. They are
+// never sent by SMS.
+func IsMarketing(kind string) bool {
+	return kind == "feature_window"
+}
+
+// PushToken is a device registered for push notifications
+type PushToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"-" gorm:"not null;index"`
+	Token     string    `json:"token" gorm:"not null;uniqueIndex"`
+	Platform  string    `json:"platform" gorm:"not null"` // android, ios, web
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotificationDelivery records one attempt to deliver a notification over a
+// channel outside the app
+type NotificationDelivery struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	NotificationID uint      `json:"notification_id" gorm:"not null;index"`
+	UserID         uint      `json:"user_id" gorm:"not null;index"`
+	Channel        string    `json:"channel" gorm:"not null"` // push, telegram, sms, email
+	Status         string    `json:"status" gorm:"not null"`  // sent, failed, unreachable, opted_out
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WeeklyDigest is one user's summary of a week, Monday to Monday UTC. It is
+// kept even when the user opted out of the notification, so the app can
+// still show it.
+type WeeklyDigest struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	UserID          uint      `json:"-" gorm:"not null;uniqueIndex:idx_user_digest_week"`
+	WeekStart       time.Time `json:"week_start" gorm:"not null;uniqueIndex:idx_user_digest_week"`
+	NewLikes        int64     `json:"new_likes"`
+	NewMatches      int64     `json:"new_matches"`
+	UnopenedMatches int64     `json:"unopened_matches"` // matches from the week the user hasn't written to
+	ProfileViews    int64     `json:"profile_views"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Unmatch records that UserID ended a match with OtherUserID. The pair isn't
+// shown to each other again until CooldownUntil, or ever when it is nil,
+// unless both opt in to a second chance once enough time has passed.
+type Unmatch struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	MatchID           uint       `json:"match_id" gorm:"not null"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	OtherUserID       uint       `json:"other_user_id" gorm:"not null;index"`
+	MatchedAt         time.Time  `json:"matched_at"`
+	CooldownUntil     *time.Time `json:"cooldown_until,omitempty"`
+	UserSecondChance  *time.Time `json:"-"` // when UserID opted in to being shown OtherUserID again
+	OtherSecondChance *time.Time `json:"-"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// SecondChance reports whether both users have opted in to seeing each other
+// again
+func (u Unmatch) SecondChance() bool {
+	return u.UserSecondChance != nil && u.OtherSecondChance != nil
+}
+
+// Over reports whether the pair may be shown to each other again
+func (u Unmatch) Over(now time.Time) bool {
+	return u.SecondChance() || (u.CooldownUntil != nil && !now.Before(*u.CooldownUntil))
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// OnboardingProgress records when a new user finished each step of setting
+// up their profile. Users who signed up before onboarding have no row and
+// count as done.
+type OnboardingProgress struct {
+	UserID            uint       `json:"-" gorm:"primaryKey"`
+	ContactVerifiedAt *time.Time `json:"contact_verified_at,omitempty"`
+	PhotosAddedAt     *time.Time `json:"photos_added_at,omitempty"`
+	PreferencesSetAt  *time.Time `json:"preferences_set_at,omitempty"`
+	InterestsPickedAt *time.Time `json:"interests_picked_at,omitempty"`
+	PromptsAnsweredAt *time.Time `json:"prompts_answered_at,omitempty"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"` // when the required steps were all done
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
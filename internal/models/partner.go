@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Partner API scopes
+const (
+	ScopeEventsWrite = "events:write"
+	ScopeStatsRead   = "stats:read"
+)
+
+// APIKey lets an approved partner call the partner API. Only a hash of the
+// key is stored; the key itself is shown once when it is created.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name" gorm:"not null"`    // what the key is for, e.g. "Addis Events production"
+	Partner    string     `json:"partner" gorm:"not null"` // organization it was issued to
+	Prefix     string     `json:"prefix" gorm:"not null"`  // first characters of the key, to tell keys apart
+	KeyHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	RateLimit  int        `json:"rate_limit"` // requests per minute
+	IsActive   bool       `json:"is_active" gorm:"not null"`
+	CreatedBy  uint       `json:"created_by" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is an in-person event published by a partner, e.g. a singles mixer
+type Event struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	APIKeyID    uint           `json:"-" gorm:"not null;index"`
+	Organizer   string         `json:"organizer" gorm:"not null"`
+	Title       string         `json:"title" gorm:"not null"`
+	Description string         `json:"description"`
+	City        string         `json:"city" gorm:"not null;index"`
+	Venue       string         `json:"venue"`
+	URL         string         `json:"url,omitempty"` // tickets or details on the partner's site
+	StartsAt    time.Time      `json:"starts_at" gorm:"not null;index"`
+	EndsAt      *time.Time     `json:"ends_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Poll is the structured payload of a "poll" message in a conversation
+type Poll struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	MessageID      uint         `json:"message_id" gorm:"uniqueIndex;not null"`
+	ConversationID uint         `json:"conversation_id" gorm:"not null;index"`
+	CreatorID      uint         `json:"creator_id" gorm:"not null"`
+	Kind           string       `json:"kind" gorm:"default:poll"` // poll, would_you_rather
+	Question       string       `json:"question" gorm:"not null"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Options        []PollOption `json:"options" gorm:"foreignKey:PollID"`
+}
+
+type PollOption struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	PollID   uint   `json:"poll_id" gorm:"not null;index"`
+	Text     string `json:"text" gorm:"not null"`
+	Position int    `json:"position"`
+}
+
+type PollVote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PollID    uint      `json:"poll_id" gorm:"not null;uniqueIndex:idx_poll_vote"`
+	OptionID  uint      `json:"option_id" gorm:"not null"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_poll_vote"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Room is a public topic chat room users can join to meet outside of 1:1 matches
+type Room struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`
+	Slug        string         `json:"slug" gorm:"uniqueIndex;not null"`
+	Description *string        `json:"description,omitempty"`
+	InterestID  *uint          `json:"interest_id,omitempty"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	MemberCount int            `json:"member_count" gorm:"default:0"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type RoomMember struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	RoomID   uint      `json:"room_id" gorm:"not null;uniqueIndex:idx_room_member"`
+	UserID   uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_room_member;index"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type RoomMessage struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	RoomID    uint           `json:"room_id" gorm:"not null;index"`
+	SenderID  uint           `json:"sender_id" gorm:"not null"`
+	Content   string         `json:"content" gorm:"not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	Sender    User           `json:"-" gorm:"foreignKey:SenderID"`
+}
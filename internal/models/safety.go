@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TrustedContact struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	UserID       uint           `json:"user_id" gorm:"not null;index"`
+	Name         string         `json:"name" gorm:"not null"`
+	Phone        string         `json:"phone" gorm:"not null"`
+	Relationship string         `json:"relationship,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type DatePlan struct {
+	ID                 uint       `json:"id" gorm:"primaryKey"`
+	UserID             uint       `json:"user_id" gorm:"not null;index"`
+	MatchUserID        *uint      `json:"match_user_id,omitempty"`
+	WithName           string     `json:"with_name" gorm:"not null"`
+	Location           string     `json:"location" gorm:"not null"`
+	ScheduledAt        time.Time  `json:"scheduled_at" gorm:"not null"`
+	CheckInAt          time.Time  `json:"check_in_at" gorm:"not null;index"`
+	Status             string     `json:"status" gorm:"default:active;index"` // active, completed, cancelled, missed
+	ShareWithContacts  bool       `json:"share_with_contacts" gorm:"default:false"`
+	ReminderSentAt     *time.Time `json:"reminder_sent_at,omitempty"`
+	ContactsNotifiedAt *time.Time `json:"contacts_notified_at,omitempty"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	User               User       `json:"-" gorm:"foreignKey:UserID"`
+}
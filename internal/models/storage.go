@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// StorageReport is one check of stored media against the database: files
+// rows point at that are missing from the bucket, and objects in the bucket
+// nothing points at. Only a sample of each is kept; the counts are complete.
+type StorageReport struct {
+	ID                uint               `json:"id" gorm:"primaryKey"`
+	ObjectCount       int64              `json:"object_count"`
+	ObjectBytes       int64              `json:"object_bytes"`
+	ReferenceCount    int64              `json:"reference_count"`
+	MissingCount      int64              `json:"missing_count"`
+	UnresolvableCount int64              `json:"unresolvable_count"` // URLs outside the bucket
+	PlaceholderCount  int64              `json:"placeholder_count"`  // placeholder URLs saved before uploads reached the bucket
+	OrphanedCount     int64              `json:"orphaned_count"`
+	OrphanedBytes     int64              `json:"orphaned_bytes"`
+	Missing           []StorageReference `json:"missing" gorm:"serializer:json"`
+	Unresolvable      []StorageReference `json:"unresolvable" gorm:"serializer:json"`
+	Orphaned          []StorageOrphan    `json:"orphaned" gorm:"serializer:json"`
+	DurationMs        int64              `json:"duration_ms"`
+	CreatedAt         time.Time          `json:"created_at" gorm:"index"`
+}
+
+// StorageReference is a row that points at a stored file
+type StorageReference struct {
+	Table string `json:"table"`
+	RowID uint   `json:"row_id"`
+	URL   string `json:"url"`
+}
+
+// StorageOrphan is an object no row points at
+type StorageOrphan struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Strike is a weighted entry in a user's abuse ledger. Penalty records the
+// escalation triggered when this strike crossed a threshold, if any.
+type Strike struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	Reason    string         `json:"reason" gorm:"not null"`
+	Weight    int            `json:"weight" gorm:"not null"`
+	Source    string         `json:"source" gorm:"not null"` // report, moderation, manual
+	SourceID  *uint          `json:"source_id,omitempty"`
+	IssuedBy  *uint          `json:"issued_by,omitempty"`
+	Note      *string        `json:"note,omitempty"`
+	Penalty   string         `json:"penalty" gorm:"default:none"` // none, warning, mute, suspension, ban
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// AbuseFlag marks an account an automated check caught behaving like a bot
+// or spammer, for the anti-abuse team to look at
+type AbuseFlag struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Kind       string     `json:"kind" gorm:"not null"` // swipe_velocity, websocket_flood
+	Details    string     `json:"details"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" gorm:"index"`
+	ReviewedBy *uint      `json:"reviewed_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Appeal is a suspended or banned user's request to have their penalty
+// lifted. A user appeals each penalty once; approving it reactivates them.
+type Appeal struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	StrikeID   *uint      `json:"strike_id,omitempty"`     // the strike whose penalty is appealed; nil for a moderator's status change
+	Penalty    string     `json:"penalty" gorm:"not null"` // suspension, ban, deactivation
+	Reason     string     `json:"reason" gorm:"not null"`  // the reason category shown to the user
+	Text       string     `json:"text" gorm:"size:2000;not null"`
+	Evidence   []string   `json:"evidence,omitempty" gorm:"serializer:json"` // links the user offered
+	Status     string     `json:"status" gorm:"default:pending;index"`       // pending, approved, denied
+	ReviewNote *string    `json:"review_note,omitempty"`
+	ReviewedBy *uint      `json:"-"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
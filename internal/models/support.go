@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// SupportTicket is a user's request for help. It is open while waiting on
+// support, pending while waiting on the user, and solved once closed; a
+// reply from the user reopens it.
+type SupportTicket struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	UserID         uint           `json:"user_id" gorm:"not null;index"`
+	Category       string         `json:"category" gorm:"not null;index"` // account, safety, billing, technical, other
+	Description    string         `json:"description" gorm:"not null"`
+	Attachments    []string       `json:"-" gorm:"serializer:json"`           // object keys of screenshots, stored without public access
+	AttachmentURLs []string       `json:"attachment_urls,omitempty" gorm:"-"` // expiring links to them, on single ticket views
+	Status         string         `json:"status" gorm:"not null;default:open;index"`
+	SolvedAt       *time.Time     `json:"solved_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Replies        []SupportReply `json:"replies,omitempty" gorm:"foreignKey:TicketID"`
+}
+
+// SupportReply is one message in a ticket's thread, from the user or an admin
+type SupportReply struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TicketID  uint      `json:"ticket_id" gorm:"not null;index"`
+	AdminID   *uint     `json:"admin_id,omitempty"` // nil when the user wrote it
+	Body      string    `json:"body" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -7,27 +7,63 @@ import (
 )
 
 type User struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	Email         string         `json:"email" gorm:"uniqueIndex;not null"`
-	Phone         *string        `json:"phone,omitempty" gorm:"uniqueIndex"`
-	PasswordHash  string         `json:"-" gorm:"not null"`
-	FirstName     string         `json:"first_name" gorm:"not null"`
-	LastName      string         `json:"last_name" gorm:"not null"`
-	DateOfBirth   time.Time      `json:"date_of_birth" gorm:"not null"`
-	Gender        string         `json:"gender" gorm:"not null"` // male, female, other
-	Bio           *string        `json:"bio,omitempty"`
-	Location      *string        `json:"location,omitempty"`
-	Latitude      *float64       `json:"latitude,omitempty"`
-	Longitude     *float64       `json:"longitude,omitempty"`
-	IsVerified    bool           `json:"is_verified" gorm:"default:false"`
-	IsActive      bool           `json:"is_active" gorm:"default:true"`
-	IsOnline      bool           `json:"is_online" gorm:"default:false"`
-	LastSeen      *time.Time     `json:"last_seen,omitempty"`
-	ProfilePhotos []ProfilePhoto `json:"profile_photos,omitempty"`
-	Interests     []Interest     `json:"interests,omitempty" gorm:"many2many:user_interests;"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                  uint            `json:"id" gorm:"primaryKey"`
+	Email               string          `json:"email" gorm:"uniqueIndex;not null"`
+	Username            *string         `json:"username,omitempty" gorm:"uniqueIndex"` // public handle, stored lowercase
+	UsernameChangedAt   *time.Time      `json:"-"`
+	Phone               *string         `json:"phone,omitempty" gorm:"uniqueIndex"`
+	PhoneHash           *string         `json:"-" gorm:"index"` // SHA-256 of the normalized phone
+	PasswordHash        string          `json:"-" gorm:"not null"`
+	FirstName           string          `json:"first_name" gorm:"not null"`
+	LastName            string          `json:"last_name" gorm:"not null"`
+	DateOfBirth         time.Time       `json:"date_of_birth" gorm:"not null"`
+	Gender              string          `json:"gender" gorm:"not null"`           // male, female, non_binary, other
+	Seeking             string          `json:"seeking" gorm:"default:everyone"`  // men, women, everyone
+	RelationshipIntent  string          `json:"relationship_intent" gorm:"index"` // serious, casual, friendship, marriage_minded; empty for accounts from before it was asked
+	Bio                 *string         `json:"bio,omitempty"`
+	BioLanguage         string          `json:"-" gorm:"index"`         // detected from the bio: am, en, other; empty when too short to tell
+	BioSuggestive       bool            `json:"-" gorm:"default:false"` // the bio matched the suggestive word list; hidden from users in clean mode
+	Location            *string         `json:"location,omitempty"`
+	Latitude            *float64        `json:"latitude,omitempty"`
+	Longitude           *float64        `json:"longitude,omitempty"`
+	LocationApproximate bool            `json:"location_approximate" gorm:"default:false"` // city-level guess from the login IP, not GPS
+	IsVerified          bool            `json:"is_verified" gorm:"default:false"`          // email or phone verified; kept for older clients
+	EmailVerifiedAt     *time.Time      `json:"email_verified_at,omitempty"`
+	PhoneVerifiedAt     *time.Time      `json:"phone_verified_at,omitempty"`
+	PhotoVerifiedAt     *time.Time      `json:"photo_verified_at,omitempty"`             // photos confirmed by a moderator to show this person
+	StudentBadge        bool            `json:"student_badge" gorm:"default:false"`      // a verified student badge shown on the profile
+	ProfessionalBadge   bool            `json:"professional_badge" gorm:"default:false"` // a verified professional badge shown on the profile
+	IDVerified          bool            `json:"id_verified" gorm:"default:false"`        // identity checked by document or Fayda
+	IsActive            bool            `json:"is_active" gorm:"default:true"`
+	IsOnline            bool            `json:"is_online" gorm:"default:false"`
+	LastSeen            *time.Time      `json:"last_seen,omitempty"`
+	DistanceUnit        string          `json:"distance_unit" gorm:"default:km"` // km, mi
+	Language            string          `json:"language" gorm:"default:en"`      // en, am
+	AgeRangeMin         *int            `json:"age_range_min,omitempty"`         // saved discovery filters; nil uses the default
+	AgeRangeMax         *int            `json:"age_range_max,omitempty"`
+	MaxDistanceKm       *int            `json:"max_distance_km,omitempty"` // nil means any distance
+	IsPremium           bool            `json:"is_premium" gorm:"default:false"`
+	HideAge             bool            `json:"hide_age" gorm:"default:false"` // premium only
+	HideOnline          bool            `json:"hide_online" gorm:"default:false"`
+	HideLastSeen        bool            `json:"hide_last_seen" gorm:"default:false"`
+	HideContacts        bool            `json:"hide_contacts" gorm:"default:false"` // exclude synced contacts from discovery
+	CleanMode           string          `json:"clean_mode" gorm:"default:off"`      // off, blur, hide: what to do with suggestive bios and photos of others
+	IsPaused            bool            `json:"is_paused" gorm:"default:false"`     // hidden from discovery and new likes
+	PausedUntil         *time.Time      `json:"paused_until,omitempty"`
+	MutedUntil          *time.Time      `json:"muted_until,omitempty"`                 // messaging muted by a strike penalty
+	SuspendedUntil      *time.Time      `json:"suspended_until,omitempty"`             // temporary suspension; bans have none
+	AgeFlaggedAt        *time.Time      `json:"age_flagged_at,omitempty" gorm:"index"` // hidden until an admin reviews the user's ID
+	TrustScore          *int            `json:"-"`                                     // 0-100 from verification, reports, strikes, replies and account age; nil until computed
+	TrustLevel          string          `json:"-" gorm:"default:normal;index"`         // low, normal, high
+	TrustComputedAt     *time.Time      `json:"-"`
+	Version             int             `json:"version" gorm:"not null;default:1"` // bumped on every profile or settings write
+	ProfilePhotos       []ProfilePhoto  `json:"profile_photos,omitempty"`
+	Prompts             []ProfilePrompt `json:"prompts,omitempty"`
+	Interests           []Interest      `json:"interests,omitempty" gorm:"many2many:user_interests;"`
+	MessageStats        *MessageStats   `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt  `json:"-" gorm:"index"`
 }
 
 type ProfilePhoto struct {
@@ -39,7 +75,47 @@ type ProfilePhoto struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
-	User      User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
+
+	Caption *string  `json:"caption,omitempty"`                     // written by the owner
+	Tags    []string `json:"tags,omitempty" gorm:"serializer:json"` // from label detection
+	AltText string   `json:"alt_text"`                              // for screen readers: the caption, else the tags
+
+	SuggestiveScore *float64 `json:"-"`                          // from label detection; nil until labeled
+	Suggestive      bool     `json:"-" gorm:"default:false"`     // scored at or above CLEAN_MODE_PHOTO_THRESHOLD
+	Blurred         bool     `json:"blurred,omitempty" gorm:"-"` // set for viewers in clean mode blur
+
+	IsPrivate bool `json:"is_private" gorm:"default:false;index"` // in the private album, shown only to matches given access
+
+	ModerationStatus string     `json:"moderation_status" gorm:"default:approved;index"` // pending, approved, rejected
+	FlagSource       *string    `json:"flag_source,omitempty"`                           // upload, nsfw, manual
+	ModerationReason *string    `json:"moderation_reason,omitempty"`
+	ModeratedBy      *uint      `json:"-"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	User             User       `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// ProfilePrompt is a user's answer to a profile question such as "My ideal
+// Sunday"
+type ProfilePrompt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"-" gorm:"not null;index"`
+	Question  string    `json:"question" gorm:"not null"`
+	Answer    string    `json:"answer" gorm:"not null"`
+	Order     int       `json:"order" gorm:"default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProfileBio is a user's bio written in another language than the one on
+// their profile, for viewers who read that language
+type ProfileBio struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	UserID     uint      `json:"-" gorm:"not null;uniqueIndex:idx_user_bio_language"`
+	Language   string    `json:"language" gorm:"size:10;not null;uniqueIndex:idx_user_bio_language"` // am, en
+	Text       string    `json:"text" gorm:"not null"`
+	Suggestive bool      `json:"-" gorm:"default:false"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 type Interest struct {
@@ -57,10 +133,24 @@ type UserInterest struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// Device is a device a user has signed in from. Logins from other devices
+// have to be confirmed with an OTP.
+type Device struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"-" gorm:"not null;uniqueIndex:idx_user_device"`
+	DeviceID    string    `json:"device_id" gorm:"not null;size:64;uniqueIndex:idx_user_device"`
+	Name        string    `json:"name" gorm:"size:100"`
+	City        string    `json:"city,omitempty"`
+	IPAddress   string    `json:"ip_address"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 type OTP struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	Email     string    `json:"email" gorm:"not null"`
+	Email     string    `json:"email" gorm:"not null;index"`
 	Phone     *string   `json:"phone,omitempty"`
+	Channel   string    `json:"channel" gorm:"not null;default:email"` // email or phone: where the code was sent and what it proves; login_device confirms a new device
 	Code      string    `json:"code" gorm:"not null"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	IsUsed    bool      `json:"is_used" gorm:"default:false"`
@@ -80,22 +170,79 @@ type BlockedUser struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	BlockerID uint      `json:"blocker_id" gorm:"not null"`
 	BlockedID uint      `json:"blocked_id" gorm:"not null"`
+	ViaPhone  bool      `json:"-" gorm:"not null;default:false"` // placed by a phone block, and only listed as that
 	CreatedAt time.Time `json:"created_at"`
 	Blocker   User      `json:"blocker,omitempty" gorm:"foreignKey:BlockerID"`
 	Blocked   User      `json:"blocked,omitempty" gorm:"foreignKey:BlockedID"`
 }
 
+// PhoneBlock blocks a phone number before (or regardless of whether) its
+// owner has an account. Only the hash and last digits are stored.
+type PhoneBlock struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	BlockerID uint      `json:"blocker_id" gorm:"not null;uniqueIndex:idx_phone_block"`
+	PhoneHash string    `json:"-" gorm:"not null;uniqueIndex:idx_phone_block;index"`
+	PhoneHint string    `json:"phone_hint"` // e.g. ••••34
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ContactHash is a SHA-256 hashed phone number from a user's address book
+type ContactHash struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_contact_hash"`
+	PhoneHash string    `json:"-" gorm:"not null;uniqueIndex:idx_contact_hash;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TelegramLink connects a user to their private chat with the Telegram bot
+type TelegramLink struct {
+	UserID           uint      `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	ChatID           int64     `json:"-" gorm:"not null;uniqueIndex"`
+	TelegramUsername string    `json:"telegram_username"`
+	CreatedAt        time.Time `json:"linked_at"`
+}
+
+// NameChangeRequest holds a display name change that matched the profanity or
+// impersonation lists. The user keeps showing their previous name until a
+// moderator approves it.
+type NameChangeRequest struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	FirstName         string     `json:"first_name" gorm:"not null"`
+	LastName          string     `json:"last_name" gorm:"not null"`
+	PreviousFirstName string     `json:"previous_first_name"`
+	PreviousLastName  string     `json:"previous_last_name"`
+	Flag              string     `json:"flag" gorm:"not null"`                // profanity, impersonation
+	Status            string     `json:"status" gorm:"default:pending;index"` // pending, approved, rejected
+	ReviewNote        *string    `json:"review_note,omitempty"`
+	ReviewedBy        *uint      `json:"-"`
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
 type Report struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
 	ReporterID  uint      `json:"reporter_id" gorm:"not null"`
 	ReportedID  uint      `json:"reported_id" gorm:"not null"`
 	Reason      string    `json:"reason" gorm:"not null"`
 	Description *string   `json:"description,omitempty"`
-	Status      string    `json:"status" gorm:"default:pending"` // pending, reviewed, resolved, dismissed
+	MessageID   *uint     `json:"message_id,omitempty"`           // the reported message, if the report is about one
+	Language    string    `json:"language" gorm:"index"`          // detected from the message or description, for routing
+	Profanity   bool      `json:"profanity" gorm:"default:false"` // the content filter matched the reported text
+	Status      string    `json:"status" gorm:"default:pending"`  // pending, reviewed, resolved, dismissed
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Reporter    User      `json:"reporter,omitempty" gorm:"foreignKey:ReporterID"`
 	Reported    User      `json:"reported,omitempty" gorm:"foreignKey:ReportedID"`
+
+	// Moderation queue
+	Severity    string     `json:"severity" gorm:"index"`             // high, medium, low; empty for reports from before the queue
+	SLADueAt    *time.Time `json:"sla_due_at,omitempty" gorm:"index"` // when it should be closed by
+	AssignedTo  *uint      `json:"assigned_to,omitempty" gorm:"index"`
+	AssignedAt  *time.Time `json:"assigned_at,omitempty"`
+	ClosedBy    *uint      `json:"closed_by,omitempty" gorm:"index"` // admin who resolved or dismissed it
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+	EscalatedAt *time.Time `json:"escalated_at,omitempty"` // when super admins were told it was overdue
 }
 
 type Favorite struct {
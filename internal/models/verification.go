@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// IdentityVerification is a request to verify a user's legal identity, either
+// by an uploaded ID document reviewed by an admin or through the Fayda
+// national digital ID. It is separate from phone and photo verification.
+type IdentityVerification struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	Method            string     `json:"method" gorm:"not null"`              // document, fayda
+	Status            string     `json:"status" gorm:"default:pending;index"` // pending, verified, rejected
+	DocumentType      *string    `json:"document_type,omitempty"`             // national_id, passport, kebele_id, drivers_license
+	DocumentURL       *string    `json:"-"`                                   // private; never returned to clients
+	DocumentKey       *string    `json:"-"`                                   // object key of a document stored without public access
+	FaydaNumberHash   *string    `json:"-" gorm:"index"`                      // SHA-256 of the FIN, used to spot reuse
+	FaydaNumberLast4  *string    `json:"fayda_number_last4,omitempty"`        // shown to the user and admins
+	ProviderReference *string    `json:"provider_reference,omitempty"`        // Fayda transaction ID
+	RejectionReason   *string    `json:"rejection_reason,omitempty"`
+	ReviewedBy        *uint      `json:"-"`
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Badge is an optional community trust signal on a profile: a university
+// student confirmed through their university email, or a professional
+// confirmed by a moderator. Users can hide a verified badge without losing it.
+type Badge struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_user_badge"`
+	Kind            string     `json:"kind" gorm:"not null;uniqueIndex:idx_user_badge"` // student, professional
+	Status          string     `json:"status" gorm:"default:pending;index"`             // pending, verified, rejected
+	Email           *string    `json:"email,omitempty" gorm:"index"`                    // the university address of a student
+	Institution     string     `json:"institution"`                                     // university email domain, or employer
+	JobTitle        string     `json:"job_title,omitempty"`
+	Evidence        string     `json:"evidence,omitempty" gorm:"size:500"` // what a professional offered as proof, such as a work profile link
+	ShowOnProfile   bool       `json:"show_on_profile" gorm:"not null"`
+	RejectionReason *string    `json:"rejection_reason,omitempty"`
+	ReviewedBy      *uint      `json:"-"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	VerifiedAt      *time.Time `json:"verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Daily active users are kept long enough to count a 30-day MAU
+	activeUserRetention = 32 * 24 * time.Hour
+	// LastSeenInterval is how often a user's last_seen is written to the
+	// database while they keep using the app
+	LastSeenInterval = time.Hour
+)
+
+func activeUsersKey(day time.Time) string {
+	return "active_users:" + day.UTC().Format("2006-01-02")
+}
+
+func lastSeenKey(userID uint) string {
+	return fmt.Sprintf("last_seen_written:%d", userID)
+}
+
+// RecordActiveUser counts the user in today's active users. Each day is a
+// HyperLogLog, so counts are approximate (within about 1%) and cost 12KB a day.
+func (c *Client) RecordActiveUser(ctx context.Context, userID uint) error {
+	key := activeUsersKey(time.Now())
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.PFAdd(ctx, key, strconv.FormatUint(uint64(userID), 10))
+		pipe.Expire(ctx, key, activeUserRetention)
+		return nil
+	})
+	return err
+}
+
+// ActiveUsers returns how many distinct users were active over the last days
+// days, today included: 1 for DAU, 7 for WAU, 30 for MAU
+func (c *Client) ActiveUsers(ctx context.Context, days int) (int64, error) {
+	now := time.Now()
+	keys := make([]string, days)
+	for i := range keys {
+		keys[i] = activeUsersKey(now.AddDate(0, 0, -i))
+	}
+	return c.rdb.PFCount(ctx, keys...).Result()
+}
+
+// LastSeenDue reports whether the user's last_seen should be written now,
+// at most once per LastSeenInterval
+func (c *Client) LastSeenDue(ctx context.Context, userID uint) (bool, error) {
+	return c.rdb.SetNX(ctx, lastSeenKey(userID), 1, LastSeenInterval).Result()
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// conversationMembersTTL bounds how long a membership written just before
+// its conversation was closed can outlive the invalidation
+const conversationMembersTTL = 10 * time.Minute
+
+func conversationMembersKey(conversationID uint) string {
+	return fmt.Sprintf("conversation:%d:members", conversationID)
+}
+
+// ConversationMembers returns the two users of an active conversation from
+// the cache. ok is false on a miss.
+func (c *Client) ConversationMembers(ctx context.Context, conversationID uint) (user1, user2 uint, ok bool) {
+	value, err := c.rdb.Get(ctx, conversationMembersKey(conversationID)).Result()
+	if err != nil {
+		return 0, 0, false
+	}
+	first, second, found := strings.Cut(value, ":")
+	if !found {
+		return 0, 0, false
+	}
+	a, errA := strconv.ParseUint(first, 10, 32)
+	b, errB := strconv.ParseUint(second, 10, 32)
+	if errA != nil || errB != nil {
+		return 0, 0, false
+	}
+	return uint(a), uint(b), true
+}
+
+// CacheConversationMembers remembers the users of an active conversation
+func (c *Client) CacheConversationMembers(ctx context.Context, conversationID, user1, user2 uint) error {
+	return c.rdb.Set(ctx, conversationMembersKey(conversationID), fmt.Sprintf("%d:%d", user1, user2), conversationMembersTTL).Err()
+}
+
+// ForgetConversations drops cached memberships, for conversations that were
+// closed or changed hands
+func (c *Client) ForgetConversations(ctx context.Context, conversationIDs ...uint) error {
+	if len(conversationIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, len(conversationIDs))
+	for i, id := range conversationIDs {
+		keys[i] = conversationMembersKey(id)
+	}
+	return c.rdb.Del(ctx, keys...).Err()
+}
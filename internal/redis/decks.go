@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	preferenceChangesKey = "preference_changes"
+	// Changes being worked on wait here until they are acknowledged, so a
+	// failed or interrupted run doesn't lose them
+	preferenceChangesProcessingKey = "preference_changes:processing"
+)
+
+// PreferenceChange is a user's change to who they are or who they want to
+// meet, waiting for their decks and matches to be checked against it
+type PreferenceChange struct {
+	UserID uint      `json:"user_id"`
+	Fields []string  `json:"fields"` // gender, seeking
+	At     time.Time `json:"at"`
+
+	payload string // as queued, to acknowledge it by
+}
+
+// DeckKey is where the candidate IDs of one of the user's swipe decks are kept
+func DeckKey(userID uint, deckID string) string {
+	return fmt.Sprintf("deck:%d:%s", userID, deckID)
+}
+
+func userDecksKey(userID uint) string {
+	return fmt.Sprintf("decks:%d", userID)
+}
+
+// SaveDeck stores a deck's candidate IDs for ttl and remembers it among the
+// user's decks, so they can all be dropped at once
+func (c *Client) SaveDeck(ctx context.Context, userID uint, deckID string, candidateIDs []interface{}, ttl time.Duration) error {
+	key := DeckKey(userID, deckID)
+	decks := userDecksKey(userID)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, candidateIDs...)
+		pipe.Expire(ctx, key, ttl)
+		pipe.SAdd(ctx, decks, deckID)
+		pipe.Expire(ctx, decks, ttl)
+		return nil
+	})
+	return err
+}
+
+// DropDecks deletes every deck the user has open and returns how many there
+// were. The app builds a new one when the next cards aren't found.
+func (c *Client) DropDecks(ctx context.Context, userID uint) (int, error) {
+	decks := userDecksKey(userID)
+	deckIDs, err := c.rdb.SMembers(ctx, decks).Result()
+	if err != nil {
+		return 0, err
+	}
+	keys := []string{decks}
+	for _, deckID := range deckIDs {
+		keys = append(keys, DeckKey(userID, deckID))
+	}
+	if err := c.rdb.Del(ctx, keys...).Err(); err != nil {
+		return 0, err
+	}
+	return len(deckIDs), nil
+}
+
+// QueuePreferenceChange holds a preference change for the revalidation job
+func (c *Client) QueuePreferenceChange(ctx context.Context, change PreferenceChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return c.rdb.RPush(ctx, preferenceChangesKey, payload).Err()
+}
+
+// TakePreferenceChanges moves up to count queued changes, oldest first, to
+// the processing list and returns them. Each stays there until
+// AckPreferenceChange; RequeuePreferenceChanges puts back the ones left.
+func (c *Client) TakePreferenceChanges(ctx context.Context, count int) ([]PreferenceChange, error) {
+	changes := make([]PreferenceChange, 0, count)
+	for taken := 0; taken < count; taken++ {
+		payload, err := c.rdb.LMove(ctx, preferenceChangesKey, preferenceChangesProcessingKey, "LEFT", "RIGHT").Result()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var change PreferenceChange
+		if err := json.Unmarshal([]byte(payload), &change); err != nil {
+			// It can never be applied, so don't keep it around
+			c.rdb.LRem(ctx, preferenceChangesProcessingKey, 1, payload)
+			continue
+		}
+		change.payload = payload
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// AckPreferenceChange drops a change that has been applied from the
+// processing list
+func (c *Client) AckPreferenceChange(ctx context.Context, change PreferenceChange) error {
+	return c.rdb.LRem(ctx, preferenceChangesProcessingKey, 1, change.payload).Err()
+}
+
+// RequeuePreferenceChanges moves changes left on the processing list by a
+// failed or interrupted run back to the front of the queue, in their order
+func (c *Client) RequeuePreferenceChanges(ctx context.Context) error {
+	for {
+		err := c.rdb.LMove(ctx, preferenceChangesProcessingKey, preferenceChangesKey, "RIGHT", "LEFT").Err()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLockNotAcquired is returned by Obtain when another holder has the lock
+// for longer than the caller was willing to wait
+var ErrLockNotAcquired = errors.New("lock not acquired")
+
+// ErrLockLost is returned when a lock expired and was taken by someone else
+// before it was refreshed or released
+var ErrLockLost = errors.New("lock no longer held")
+
+// Only the holder whose token is stored may release or extend the lock
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+const lockRetryInterval = 50 * time.Millisecond
+
+// FenceTTL is how long a key's fence counter outlives its last acquisition.
+// It outlives any sane lock TTL, so tokens only need to grow within this
+// window and idle keys don't pile up forever. A token stored longer ago than
+// this may be from a counter that has since started over.
+const FenceTTL = 24 * time.Hour
+
+type LockOptions struct {
+	Wait      time.Duration // keep retrying this long while the lock is held; zero fails at once
+	AutoRenew bool          // extend the TTL in the background until Release
+}
+
+// Lock is a distributed mutex held across instances. Its fencing token grows
+// with every acquisition of the same key, so writers that store the latest
+// token next to what they protect can reject work from a holder whose lock
+// has since expired.
+type Lock struct {
+	client *Client
+	key    string
+	token  int64
+	ttl    time.Duration
+
+	stopRenew chan struct{}
+	renewDone sync.WaitGroup
+}
+
+// Obtain acquires the lock at key for ttl. The lock is stored with SET NX and
+// a fencing token from a counter kept next to it.
+func (c *Client) Obtain(ctx context.Context, key string, ttl time.Duration, opts LockOptions) (*Lock, error) {
+	deadline := time.Now().Add(opts.Wait)
+	for {
+		var incr *redis.IntCmd
+		if _, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			incr = pipe.Incr(ctx, key+":fence")
+			pipe.Expire(ctx, key+":fence", FenceTTL)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		token := incr.Val()
+
+		ok, err := c.rdb.SetNX(ctx, key, token, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			lock := &Lock{client: c, key: key, token: token, ttl: ttl}
+			if opts.AutoRenew {
+				lock.startRenewing()
+			}
+			return lock, nil
+		}
+
+		if time.Now().Add(lockRetryInterval).After(deadline) {
+			return nil, ErrLockNotAcquired
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Key returns the key the lock is held at
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token returns the fencing token of this acquisition
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Refresh extends the lock's TTL, failing with ErrLockLost if it expired
+func (l *Lock) Refresh(ctx context.Context) error {
+	extended, err := refreshScript.Run(ctx, l.client.rdb, []string{l.key}, l.value(), l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if extended == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release stops auto-renewal and frees the lock if it is still held
+func (l *Lock) Release(ctx context.Context) error {
+	if l.stopRenew != nil {
+		close(l.stopRenew)
+		l.renewDone.Wait()
+		l.stopRenew = nil
+	}
+
+	released, err := releaseScript.Run(ctx, l.client.rdb, []string{l.key}, l.value()).Int()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// startRenewing refreshes the lock at a third of its TTL until released or
+// lost
+func (l *Lock) startRenewing() {
+	l.stopRenew = make(chan struct{})
+	l.renewDone.Add(1)
+	go func() {
+		defer l.renewDone.Done()
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.stopRenew:
+				return
+			case <-ticker.C:
+				if err := l.Refresh(context.Background()); errors.Is(err, ErrLockLost) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (l *Lock) value() string {
+	return strconv.FormatInt(l.token, 10)
+}
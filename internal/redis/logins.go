@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Failed logins are counted per subject, an account or an IP address, e.g.
+// "account:someone@example.com" or "ip:203.0.113.7"
+
+func loginFailuresKey(subject string) string {
+	return "login_failures:" + subject
+}
+
+func loginLockKey(subject string) string {
+	return "login_lock:" + subject
+}
+
+// LoginLockedFor returns how much longer the most locked out of the
+// subjects stays locked, zero when none is
+func (c *Client) LoginLockedFor(ctx context.Context, subjects ...string) (time.Duration, error) {
+	var locked time.Duration
+	for _, subject := range subjects {
+		ttl, err := c.rdb.PTTL(ctx, loginLockKey(subject)).Result()
+		if err != nil {
+			return 0, err
+		}
+		locked = max(locked, ttl)
+	}
+	return locked, nil
+}
+
+// RecordLoginFailure counts a failed login against the subject and locks it
+// out for lockout once limit failures were made within that long. It
+// reports whether this failure locked the subject.
+func (c *Client) RecordLoginFailure(ctx context.Context, subject string, limit int64, lockout time.Duration) (bool, error) {
+	key := loginFailuresKey(subject)
+	count, err := c.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		c.rdb.Expire(ctx, key, lockout)
+	}
+	if count < limit {
+		return false, nil
+	}
+
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, loginLockKey(subject), 1, lockout)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	return err == nil, err
+}
+
+// ClearLoginFailures forgets the subject's failed logins after a good one
+func (c *Client) ClearLoginFailures(ctx context.Context, subject string) error {
+	return c.rdb.Del(ctx, loginFailuresKey(subject)).Err()
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// PendingEventLimit is how many undelivered events are kept per user;
+	// older ones are dropped first
+	PendingEventLimit = 100
+	// PendingEventTTL is how long undelivered events wait for a reconnect
+	PendingEventTTL = 7 * 24 * time.Hour
+)
+
+func pendingEventsKey(userID uint) string {
+	return fmt.Sprintf("pending_events:%d", userID)
+}
+
+// QueueEvent holds a realtime event for a user who isn't connected
+func (c *Client) QueueEvent(ctx context.Context, userID uint, event []byte) error {
+	key := pendingEventsKey(userID)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, event)
+		pipe.LTrim(ctx, key, -PendingEventLimit, -1)
+		pipe.Expire(ctx, key, PendingEventTTL)
+		return nil
+	})
+	return err
+}
+
+// PendingEvents returns the user's queued events, oldest first, without
+// removing them
+func (c *Client) PendingEvents(ctx context.Context, userID uint) ([]string, error) {
+	return c.rdb.LRange(ctx, pendingEventsKey(userID), 0, -1).Result()
+}
+
+// AckEvents removes the oldest count queued events once they were delivered
+func (c *Client) AckEvents(ctx context.Context, userID uint, count int) error {
+	return c.rdb.LTrim(ctx, pendingEventsKey(userID), int64(count), -1).Err()
+}
@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PresenceWindow is how recently a user must have made a request to count as
+// active now
+const PresenceWindow = 15 * time.Minute
+
+const (
+	presenceGeoKey      = "presence:geo"       // GEO set of active users' positions
+	presenceSeenKey     = "presence:seen"      // sorted set of user IDs by last request time
+	presenceLocationKey = "presence:locations" // last known "lon,lat" per user, kept while inactive
+)
+
+// touchScript marks a user active and puts them back on the map from their
+// last known location, in case they were pruned while inactive
+var touchScript = redis.NewScript(`
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[1])
+local location = redis.call("HGET", KEYS[3], ARGV[1])
+if location then
+	local sep = string.find(location, ",", 1, true)
+	redis.call("GEOADD", KEYS[2], string.sub(location, 1, sep - 1), string.sub(location, sep + 1), ARGV[1])
+end
+return 1`)
+
+// NearbyUser is an active user found by ActiveNearby
+type NearbyUser struct {
+	UserID     uint
+	DistanceKm float64
+}
+
+// TouchPresence marks the user as active now
+func (c *Client) TouchPresence(ctx context.Context, userID uint) error {
+	keys := []string{presenceSeenKey, presenceGeoKey, presenceLocationKey}
+	return touchScript.Run(ctx, c.rdb, keys, strconv.FormatUint(uint64(userID), 10), time.Now().Unix()).Err()
+}
+
+// SetPresenceLocation records where the user is and marks them active
+func (c *Client) SetPresenceLocation(ctx context.Context, userID uint, latitude, longitude float64) error {
+	member := strconv.FormatUint(uint64(userID), 10)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, presenceLocationKey, member, strconv.FormatFloat(longitude, 'f', -1, 64)+","+strconv.FormatFloat(latitude, 'f', -1, 64))
+		pipe.GeoAdd(ctx, presenceGeoKey, &redis.GeoLocation{Name: member, Latitude: latitude, Longitude: longitude})
+		pipe.ZAdd(ctx, presenceSeenKey, redis.Z{Score: float64(time.Now().Unix()), Member: member})
+		return nil
+	})
+	return err
+}
+
+// ClearPresence takes the user off the map and forgets their location
+func (c *Client) ClearPresence(ctx context.Context, userID uint) error {
+	member := strconv.FormatUint(uint64(userID), 10)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, presenceLocationKey, member)
+		pipe.ZRem(ctx, presenceGeoKey, member)
+		pipe.ZRem(ctx, presenceSeenKey, member)
+		return nil
+	})
+	return err
+}
+
+// ActiveNearby returns up to limit users within radiusKm of the point who
+// were active within PresenceWindow, nearest first
+func (c *Client) ActiveNearby(ctx context.Context, latitude, longitude, radiusKm float64, limit int) ([]NearbyUser, error) {
+	// Over-fetch, since some of the nearest positions belong to users who
+	// have since gone quiet
+	locations, err := c.rdb.GeoSearchLocation(ctx, presenceGeoKey, &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{
+			Longitude:  longitude,
+			Latitude:   latitude,
+			Radius:     radiusKm,
+			RadiusUnit: "km",
+			Sort:       "ASC",
+			Count:      limit * 4,
+		},
+		WithDist: true,
+	}).Result()
+	if err != nil || len(locations) == 0 {
+		return nil, err
+	}
+
+	members := make([]string, len(locations))
+	for i, location := range locations {
+		members[i] = location.Name
+	}
+	seen, err := c.rdb.ZMScore(ctx, presenceSeenKey, members...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := float64(time.Now().Add(-PresenceWindow).Unix())
+	nearby := make([]NearbyUser, 0, limit)
+	for i, location := range locations {
+		if seen[i] < cutoff {
+			continue
+		}
+		id, err := strconv.ParseUint(location.Name, 10, 64)
+		if err != nil {
+			continue
+		}
+		nearby = append(nearby, NearbyUser{UserID: uint(id), DistanceKm: location.Dist})
+		if len(nearby) == limit {
+			break
+		}
+	}
+	return nearby, nil
+}
+
+// PruneStalePresence takes users who haven't been active for PresenceWindow
+// off the map, so nearby searches only walk active positions. Their location
+// is kept and restored by their next TouchPresence. Returns how many were
+// removed.
+func (c *Client) PruneStalePresence(ctx context.Context) (int, error) {
+	cutoff := strconv.FormatInt(time.Now().Add(-PresenceWindow).Unix(), 10)
+	stale, err := c.rdb.ZRangeByScore(ctx, presenceSeenKey, &redis.ZRangeBy{Min: "-inf", Max: "(" + cutoff}).Result()
+	if err != nil || len(stale) == 0 {
+		return 0, err
+	}
+
+	members := make([]interface{}, len(stale))
+	for i, member := range stale {
+		members[i] = member
+	}
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, presenceGeoKey, members...)
+		pipe.ZRem(ctx, presenceSeenKey, members...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(stale), nil
+}
@@ -39,6 +39,11 @@ func (c *Client) Get(ctx context.Context, key string) (string, error) {
 	return c.rdb.Get(ctx, key).Result()
 }
 
+// GetDel returns the value at key and deletes it, so only one caller gets it
+func (c *Client) GetDel(ctx context.Context, key string) (string, error) {
+	return c.rdb.GetDel(ctx, key).Result()
+}
+
 func (c *Client) Del(ctx context.Context, keys ...string) error {
 	return c.rdb.Del(ctx, keys...).Err()
 }
@@ -95,6 +100,18 @@ func (c *Client) SIsMember(ctx context.Context, key string, member interface{})
 	return c.rdb.SIsMember(ctx, key, member).Result()
 }
 
+func (c *Client) RPush(ctx context.Context, key string, values ...interface{}) error {
+	return c.rdb.RPush(ctx, key, values...).Err()
+}
+
+func (c *Client) LPopCount(ctx context.Context, key string, count int) ([]string, error) {
+	return c.rdb.LPopCount(ctx, key, count).Result()
+}
+
+func (c *Client) LLen(ctx context.Context, key string) (int64, error) {
+	return c.rdb.LLen(ctx, key).Result()
+}
+
 func (c *Client) ZAdd(ctx context.Context, key string, members ...redis.Z) error {
 	return c.rdb.ZAdd(ctx, key, members...).Err()
 }
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// EventLogLimit is how many recent events are kept per user for
+	// connections resuming after a drop. A replay has to fit in a
+	// connection's send buffer.
+	EventLogLimit = 200
+	// EventLogTTL is how long a user's event log outlives their last event
+	EventLogTTL = 24 * time.Hour
+	// ResumeSessionTTL is how long after a disconnect its resume token works
+	ResumeSessionTTL = 10 * time.Minute
+)
+
+// ResumeSession is what a dropped connection had, so the next one can pick
+// up where it left off
+type ResumeSession struct {
+	UserID         uint `json:"user_id"`
+	ConversationID uint `json:"conversation_id,omitempty"` // the joined conversation
+}
+
+func eventSeqKey(userID uint) string {
+	return fmt.Sprintf("event_seq:%d", userID)
+}
+
+func eventLogKey(userID uint) string {
+	return fmt.Sprintf("event_log:%d", userID)
+}
+
+func resumeSessionKey(token string) string {
+	return "ws_resume:" + token
+}
+
+// NextEventID returns the user's next event ID. IDs count up from 1 per user
+// and start over once the log expires.
+func (c *Client) NextEventID(ctx context.Context, userID uint) (int64, error) {
+	key := eventSeqKey(userID)
+	var incr *redis.IntCmd
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, EventLogTTL)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// LogEvent keeps an event under its ID, dropping the oldest past the limit
+func (c *Client) LogEvent(ctx context.Context, userID uint, id int64, event []byte) error {
+	key := eventLogKey(userID)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(id), Member: event})
+		pipe.ZRemRangeByRank(ctx, key, 0, -EventLogLimit-1)
+		pipe.Expire(ctx, key, EventLogTTL)
+		return nil
+	})
+	return err
+}
+
+// EventsSince returns the user's logged events after the given ID, oldest
+// first. complete is false when some of them are no longer in the log, and
+// the caller has to fall back to a full refresh.
+func (c *Client) EventsSince(ctx context.Context, userID uint, after int64) (events []string, complete bool, err error) {
+	latest, err := c.rdb.Get(ctx, eventSeqKey(userID)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, false, err
+	}
+	if after > latest {
+		// The IDs started over, so after refers to events long gone
+		return nil, false, nil
+	}
+	if after == latest {
+		return nil, true, nil
+	}
+
+	logged, err := c.rdb.ZRangeByScoreWithScores(ctx, eventLogKey(userID), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(after, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(logged) == 0 || int64(logged[0].Score) != after+1 {
+		return nil, false, nil
+	}
+
+	events = make([]string, len(logged))
+	for i, z := range logged {
+		events[i] = z.Member.(string)
+	}
+	return events, true, nil
+}
+
+// SaveResumeSession stores a connection's state under its resume token
+func (c *Client) SaveResumeSession(ctx context.Context, token string, session ResumeSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Set(ctx, resumeSessionKey(token), data, ResumeSessionTTL).Err()
+}
+
+// TakeResumeSession returns and removes the session saved under token, or
+// nil if there is none. Tokens work once.
+func (c *Client) TakeResumeSession(ctx context.Context, token string) (*ResumeSession, error) {
+	data, err := c.rdb.GetDel(ctx, resumeSessionKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session ResumeSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
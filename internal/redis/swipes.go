@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SwipeThrottleTTL is how long a user's throttle level is remembered after
+// their last escalation
+const SwipeThrottleTTL = time.Hour
+
+// SwipeThrottle is how hard a user's swiping is currently held back
+type SwipeThrottle struct {
+	Level     int       // escalations within the last hour
+	Until     time.Time // no swipes before this
+	Challenge bool      // no swipes until a challenge is passed
+}
+
+func swipesKey(userID uint) string {
+	return fmt.Sprintf("swipes:%d", userID)
+}
+
+func swipeThrottleKey(userID uint) string {
+	return fmt.Sprintf("swipe_throttle:%d", userID)
+}
+
+// RecordSwipe notes a like or pass on targetID and returns how many profiles
+// the user swiped within the window, this one included. Repeated swipes on
+// the same profile, e.g. a double tap, count once.
+func (c *Client) RecordSwipe(ctx context.Context, userID, targetID uint, window time.Duration) (int64, error) {
+	key := swipesKey(userID)
+	now := time.Now()
+	var count *redis.IntCmd
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixMilli()), Member: targetID})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixMilli(), 10))
+		count = pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// GetSwipeThrottle returns the user's throttle, the zero value if none
+func (c *Client) GetSwipeThrottle(ctx context.Context, userID uint) (SwipeThrottle, error) {
+	values, err := c.rdb.HGetAll(ctx, swipeThrottleKey(userID)).Result()
+	if err != nil {
+		return SwipeThrottle{}, err
+	}
+
+	var throttle SwipeThrottle
+	throttle.Level, _ = strconv.Atoi(values["level"])
+	if until, err := strconv.ParseInt(values["until"], 10, 64); err == nil {
+		throttle.Until = time.UnixMilli(until)
+	}
+	throttle.Challenge = values["challenge"] == "1"
+	return throttle, nil
+}
+
+// EscalateSwipeThrottle raises the user's throttle level and holds their
+// swipes back for as long as delayFor says for that level, and until they
+// pass a challenge when it asks for one. It returns the new level.
+func (c *Client) EscalateSwipeThrottle(ctx context.Context, userID uint, delayFor func(level int) (time.Duration, bool)) (int, error) {
+	key := swipeThrottleKey(userID)
+	level, err := c.rdb.HIncrBy(ctx, key, "level", 1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	delay, challenge := delayFor(int(level))
+	fields := map[string]interface{}{"until": time.Now().Add(delay).UnixMilli()}
+	if challenge {
+		fields["challenge"] = "1"
+	}
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, fields)
+		pipe.Expire(ctx, key, SwipeThrottleTTL)
+		return nil
+	})
+	return int(level), err
+}
+
+// ClearSwipeThrottle lifts the user's throttle, e.g. after a passed challenge
+func (c *Client) ClearSwipeThrottle(ctx context.Context, userID uint) error {
+	return c.rdb.Del(ctx, swipeThrottleKey(userID), swipesKey(userID)).Err()
+}
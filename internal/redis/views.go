@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Profile views are counted in daily buckets that expire once no stats
+// window needs them
+const profileViewRetention = 8 * 24 * time.Hour
+
+func profileViewKey(userID uint, day time.Time) string {
+	return fmt.Sprintf("profile_views:%d:%s", userID, day.UTC().Format("2006-01-02"))
+}
+
+// RecordProfileViews counts one view for each user shown to a viewer
+func (c *Client) RecordProfileViews(ctx context.Context, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range userIDs {
+			key := profileViewKey(id, now)
+			pipe.Incr(ctx, key)
+			pipe.Expire(ctx, key, profileViewRetention)
+		}
+		return nil
+	})
+	return err
+}
+
+// ProfileViews returns how many times the user was shown over the last days
+// days, today included
+func (c *Client) ProfileViews(ctx context.Context, userID uint, days int) (int64, error) {
+	now := time.Now()
+	keys := make([]string, days)
+	for i := range keys {
+		keys[i] = profileViewKey(userID, now.AddDate(0, 0, -i))
+	}
+
+	counts, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, count := range counts {
+		if s, ok := count.(string); ok {
+			n, _ := strconv.ParseInt(s, 10, 64)
+			total += n
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,275 @@
+// Package seed fills a development database with realistic fake users,
+// likes, matches, and conversations so discovery and chat can be demoed and
+// load-tested locally. It must never be pointed at production.
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+	"ethiopia-dating-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Password is the password of every seeded user
+const Password = "password123"
+
+type Options struct {
+	Users        int     // number of users to create
+	LikesPerUser int     // likes each user sends to compatible users
+	Seed         int64   // random seed; the same seed gives the same data
+	MatchChance  float64 // chance that a like is returned, creating a match
+}
+
+type city struct {
+	name      string
+	latitude  float64
+	longitude float64
+}
+
+var cities = []city{
+	{"Addis Ababa", 9.0300, 38.7400},
+	{"Addis Ababa", 9.0300, 38.7400}, // weighted: most users live in the capital
+	{"Addis Ababa", 9.0300, 38.7400},
+	{"Dire Dawa", 9.6009, 41.8501},
+	{"Bahir Dar", 11.5742, 37.3614},
+	{"Hawassa", 7.0621, 38.4764},
+	{"Mekelle", 13.4967, 39.4753},
+	{"Gondar", 12.6030, 37.4521},
+	{"Adama", 8.5400, 39.2700},
+	{"Jimma", 7.6733, 36.8344},
+}
+
+var (
+	maleNames   = []string{"Abebe", "Dawit", "Yonas", "Henok", "Biruk", "Samuel", "Kaleb", "Natnael", "Robel", "Mikias", "Tewodros", "Ermias", "Fitsum", "Nahom", "Bereket", "Yared"}
+	femaleNames = []string{"Hanna", "Selam", "Meron", "Bethlehem", "Liya", "Mahlet", "Saron", "Tigist", "Rahel", "Eden", "Hiwot", "Marta", "Feven", "Blen", "Tsion", "Kidist"}
+	otherNames  = []string{"Amen", "Nati", "Sami", "Lulu"}
+	lastNames   = []string{"Tesfaye", "Bekele", "Alemu", "Girma", "Haile", "Tadesse", "Mengistu", "Wolde", "Kebede", "Gebre", "Assefa", "Mulugeta", "Negash", "Desta", "Ayele", "Worku"}
+	bios        = []string{
+		"Coffee ceremony enthusiast. Looking for someone to share buna and long talks.",
+		"Engineer by day, amateur photographer by weekend.",
+		"Love hiking Entoto and trying new restaurants in Bole.",
+		"Teacher, reader, and terrible dancer. Let's go to an Azmari bet.",
+		"Family first. Looking for something real.",
+		"Always planning the next trip to Lalibela or the Simien Mountains.",
+		"Startup life, gym in the morning, injera every day.",
+		"Music lover — Teddy Afro, Aster Aweke, and a bit of jazz.",
+	}
+	openers = []string{
+		"Selam! How's your week going?",
+		"Hi! I loved your photos from the trip.",
+		"Hey, coffee this weekend?",
+		"Your bio made me laugh 😄",
+		"Which part of the city are you in?",
+	}
+	replies = []string{
+		"Selam! Pretty good, busy with work. Yours?",
+		"Thanks! That was Bahir Dar last year.",
+		"Sure, do you know a good place in Bole?",
+		"Haha glad someone appreciates it",
+		"Near Piassa. You?",
+		"Sounds great, let's do it.",
+		"I'm free Saturday afternoon.",
+	}
+)
+
+// Run creates opts.Users users with photos, interests, and locations, then
+// has them like each other and opens conversations for the resulting matches.
+// Interests must already be seeded.
+func Run(db *gorm.DB, opts Options) error {
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	var interests []models.Interest
+	if err := db.Find(&interests).Error; err != nil {
+		return err
+	}
+	if len(interests) == 0 {
+		return fmt.Errorf("no interests found; seed interests first")
+	}
+
+	passwordHash, err := utils.HashPassword(Password)
+	if err != nil {
+		return err
+	}
+
+	users := make([]models.User, 0, opts.Users)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		batch := time.Now().Unix()
+		for i := 0; i < opts.Users; i++ {
+			user := fakeUser(rng, batch, i, passwordHash)
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+
+			photoCount := 1 + rng.Intn(3)
+			for p := 0; p < photoCount; p++ {
+				photo := models.ProfilePhoto{
+					UserID:    user.ID,
+					URL:       fmt.Sprintf("https://picsum.photos/seed/%d-%d-%d/600/800", batch, i, p),
+					IsPrimary: p == 0,
+					Order:     p,
+				}
+				if err := tx.Create(&photo).Error; err != nil {
+					return err
+				}
+			}
+
+			for _, idx := range rng.Perm(len(interests))[:min(3+rng.Intn(4), len(interests))] {
+				if err := tx.Create(&models.UserInterest{UserID: user.ID, InterestID: interests[idx].ID}).Error; err != nil {
+					return err
+				}
+			}
+
+			users = append(users, user)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create users: %w", err)
+	}
+
+	matches, err := seedLikes(db, rng, users, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create likes and matches: %w", err)
+	}
+
+	fmt.Printf("Seeded %d users and %d matches (password %q)\n", len(users), matches, Password)
+	return nil
+}
+
+func fakeUser(rng *rand.Rand, batch int64, i int, passwordHash string) models.User {
+	var gender, seeking, firstName string
+	switch roll := rng.Intn(100); {
+	case roll < 48:
+		gender, seeking, firstName = "male", "women", pick(rng, maleNames)
+	case roll < 96:
+		gender, seeking, firstName = "female", "men", pick(rng, femaleNames)
+	default:
+		gender, seeking, firstName = "non_binary", "everyone", pick(rng, otherNames)
+	}
+	if rng.Intn(10) == 0 {
+		seeking = "everyone"
+	}
+
+	home := cities[rng.Intn(len(cities))]
+	latitude := home.latitude + (rng.Float64()-0.5)*0.1
+	longitude := home.longitude + (rng.Float64()-0.5)*0.1
+	location := home.name
+	bio := pick(rng, bios)
+	lastSeen := time.Now().Add(-time.Duration(rng.Intn(14*24)) * time.Hour)
+	verifiedAt := time.Now()
+
+	return models.User{
+		Email:           fmt.Sprintf("seed.%d.%d@example.com", batch, i),
+		PasswordHash:    passwordHash,
+		FirstName:       firstName,
+		LastName:        pick(rng, lastNames),
+		DateOfBirth:     time.Now().AddDate(-(18 + rng.Intn(28)), -rng.Intn(12), -rng.Intn(28)),
+		Gender:          gender,
+		Seeking:         seeking,
+		Bio:             &bio,
+		Location:        &location,
+		Latitude:        &latitude,
+		Longitude:       &longitude,
+		IsVerified:      true,
+		EmailVerifiedAt: &verifiedAt,
+		IsActive:        true,
+		IsOnline:        rng.Intn(5) == 0,
+		LastSeen:        &lastSeen,
+		DistanceUnit:    "km",
+	}
+}
+
+// seedLikes sends likes between compatible users. Returned likes become
+// matches with a short conversation.
+func seedLikes(db *gorm.DB, rng *rand.Rand, users []models.User, opts Options) (int, error) {
+	liked := make(map[[2]uint]bool)
+	matches := 0
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, liker := range users {
+			sent := 0
+			for _, idx := range rng.Perm(len(users)) {
+				if sent >= opts.LikesPerUser {
+					break
+				}
+				target := users[idx]
+				pair := [2]uint{liker.ID, target.ID}
+				if target.ID == liker.ID || liked[pair] ||
+					!utils.GendersCompatible(liker.Gender, liker.Seeking, target.Gender, target.Seeking) {
+					continue
+				}
+
+				if err := tx.Create(&models.Like{LikerID: liker.ID, LikedID: target.ID}).Error; err != nil {
+					return err
+				}
+				liked[pair] = true
+				sent++
+
+				// Either the target already liked back, or they return the like
+				reverse := [2]uint{target.ID, liker.ID}
+				if !liked[reverse] {
+					if rng.Float64() >= opts.MatchChance {
+						continue
+					}
+					if err := tx.Create(&models.Like{LikerID: target.ID, LikedID: liker.ID}).Error; err != nil {
+						return err
+					}
+					liked[reverse] = true
+				}
+
+				if err := seedConversation(tx, rng, liker.ID, target.ID); err != nil {
+					return err
+				}
+				matches++
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+func seedConversation(tx *gorm.DB, rng *rand.Rand, user1ID, user2ID uint) error {
+	match := models.Match{User1ID: user1ID, User2ID: user2ID, IsActive: true}
+	if err := tx.Create(&match).Error; err != nil {
+		return err
+	}
+
+	conversation := models.Conversation{MatchID: match.ID, IsActive: true}
+	if err := tx.Create(&conversation).Error; err != nil {
+		return err
+	}
+
+	// Some matches never get past hello
+	count := rng.Intn(9)
+	sentAt := time.Now().Add(-time.Duration(24+rng.Intn(24*10)) * time.Hour)
+	for i := 0; i < count; i++ {
+		senderID, content := user1ID, pick(rng, openers)
+		if i%2 == 1 {
+			senderID, content = user2ID, pick(rng, replies)
+		} else if i > 0 {
+			content = pick(rng, replies)
+		}
+		sentAt = sentAt.Add(time.Duration(1+rng.Intn(180)) * time.Minute)
+
+		message := models.Message{
+			ConversationID: conversation.ID,
+			SenderID:       senderID,
+			Content:        content,
+			MessageType:    "text",
+			IsRead:         i < count-1,
+			CreatedAt:      sentAt,
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	activityQueueSize     = 4096
+	activityBatchSize     = 200
+	activityFlushInterval = 2 * time.Second
+)
+
+// ActivityRecorder writes user activity in batches off the request path.
+// Record never blocks; if the queue is full the entry is dropped and logged,
+// since losing an audit row is better than slowing down every request.
+type ActivityRecorder struct {
+	db    *gorm.DB
+	queue chan models.UserActivity
+	stop  chan struct{}
+	done  sync.WaitGroup
+}
+
+func NewActivityRecorder(db *gorm.DB) *ActivityRecorder {
+	return &ActivityRecorder{
+		db:    db,
+		queue: make(chan models.UserActivity, activityQueueSize),
+		stop:  make(chan struct{}),
+	}
+}
+
+// Record queues an activity for the next batch
+func (r *ActivityRecorder) Record(activity models.UserActivity) {
+	if activity.CreatedAt.IsZero() {
+		activity.CreatedAt = time.Now()
+	}
+
+	select {
+	case r.queue <- activity:
+	default:
+		log.Printf("Activity queue full, dropping %s for user %d", activity.Action, activity.UserID)
+	}
+}
+
+// Start writes batches until Stop is called
+func (r *ActivityRecorder) Start() {
+	r.done.Add(1)
+	go func() {
+		defer r.done.Done()
+		ticker := time.NewTicker(activityFlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]models.UserActivity, 0, activityBatchSize)
+		for {
+			select {
+			case activity := <-r.queue:
+				batch = append(batch, activity)
+				if len(batch) >= activityBatchSize {
+					batch = r.flush(batch)
+				}
+			case <-ticker.C:
+				batch = r.flush(batch)
+			case <-r.stop:
+				// Drain whatever was recorded before shutdown
+				for {
+					select {
+					case activity := <-r.queue:
+						batch = append(batch, activity)
+					default:
+						r.flush(batch)
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop writes the remaining activity and waits for it to be saved
+func (r *ActivityRecorder) Stop() {
+	close(r.stop)
+	r.done.Wait()
+}
+
+func (r *ActivityRecorder) flush(batch []models.UserActivity) []models.UserActivity {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := r.db.CreateInBatches(batch, activityBatchSize).Error; err != nil {
+		log.Printf("Failed to save %d activity entries: %v", len(batch), err)
+	}
+	return batch[:0]
+}
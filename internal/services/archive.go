@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// archivedMessage is how a message is kept in an archive. IDs are kept so
+// restored messages match what clients and reports already refer to.
+type archivedMessage struct {
+	ID             uint       `json:"id"`
+	SenderID       uint       `json:"sender_id"`
+	Content        string     `json:"content"`
+	MessageType    string     `json:"message_type"`
+	SystemKind     string     `json:"system_kind,omitempty"`
+	IsRead         bool       `json:"is_read"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	HasContactInfo bool       `json:"has_contact_info"`
+	IsFlagged      bool       `json:"is_flagged"`
+	IsEncrypted    bool       `json:"is_encrypted"`
+	HeldAt         *time.Time `json:"held_at,omitempty"` // still waiting for review
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type messageArchive struct {
+	ConversationID uint              `json:"conversation_id"`
+	ArchivedAt     time.Time         `json:"archived_at"`
+	Messages       []archivedMessage `json:"messages"`
+}
+
+// ArchiveConversation moves every message of a conversation into a JSON
+// object in storage and deletes them from the database, messages users had
+// deleted included, which are dropped rather than archived. It returns how
+// many messages were archived.
+func ArchiveConversation(ctx context.Context, db *gorm.DB, storage *StorageService, conversationID uint) (int, error) {
+	var messages []models.Message
+	if err := db.WithContext(ctx).Where("conversation_id = ?", conversationID).
+		Order("id ASC").Find(&messages).Error; err != nil {
+		return 0, err
+	}
+
+	if len(messages) == 0 {
+		return 0, db.WithContext(ctx).Unscoped().Where("conversation_id = ?", conversationID).
+			Delete(&models.Message{}).Error
+	}
+
+	now := time.Now()
+	archive := messageArchive{ConversationID: conversationID, ArchivedAt: now}
+	for _, msg := range messages {
+		archive.Messages = append(archive.Messages, archivedMessage{
+			ID:             msg.ID,
+			SenderID:       msg.SenderID,
+			Content:        msg.Content,
+			MessageType:    msg.MessageType,
+			SystemKind:     msg.SystemKind,
+			IsRead:         msg.IsRead,
+			ReadAt:         msg.ReadAt,
+			HasContactInfo: msg.HasContactInfo,
+			IsFlagged:      msg.IsFlagged,
+			IsEncrypted:    msg.IsEncrypted,
+			HeldAt:         msg.HeldAt,
+			CreatedAt:      msg.CreatedAt,
+		})
+	}
+	body, err := json.Marshal(archive)
+	if err != nil {
+		return 0, err
+	}
+
+	key := fmt.Sprintf("archives/conversations/%d/%d.json", conversationID, now.UnixNano())
+	if err := storage.UploadPrivateFile(ctx, bytes.NewReader(body), key, "application/json"); err != nil {
+		return 0, err
+	}
+
+	last := messages[len(messages)-1]
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.ConversationArchive{
+			ConversationID: conversationID,
+			ObjectKey:      key,
+			MessageCount:   len(messages),
+			FirstMessageAt: messages[0].CreatedAt,
+			LastMessageAt:  last.CreatedAt,
+		}).Error; err != nil {
+			return err
+		}
+		// Messages sent after the archive was read stay in the database
+		return tx.Unscoped().Where("conversation_id = ? AND id <= ?", conversationID, last.ID).
+			Delete(&models.Message{}).Error
+	})
+	if err != nil {
+		// Without the row the object is never read again, so don't leave it behind
+		storage.store.remove(context.WithoutCancel(ctx), key)
+		return 0, err
+	}
+	return len(messages), nil
+}
+
+// RestoreConversation puts the messages of every archive of a conversation
+// not yet restored back into the database and returns how many came back.
+// Restored messages count as just updated so every device syncs them, and
+// the archived copies are deleted.
+func RestoreConversation(ctx context.Context, db *gorm.DB, storage *StorageService, conversationID uint) (int, error) {
+	var archives []models.ConversationArchive
+	if err := db.WithContext(ctx).Where("conversation_id = ? AND restored_at IS NULL", conversationID).
+		Order("id ASC").Find(&archives).Error; err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	for _, record := range archives {
+		data, err := storage.DownloadFile(ctx, record.ObjectKey)
+		if err != nil {
+			return restored, err
+		}
+		var archive messageArchive
+		if err := json.Unmarshal(data, &archive); err != nil {
+			return restored, fmt.Errorf("failed to decode archive %d: %w", record.ID, err)
+		}
+
+		now := time.Now()
+		messages := make([]models.Message, 0, len(archive.Messages))
+		for _, msg := range archive.Messages {
+			messages = append(messages, models.Message{
+				ID:             msg.ID,
+				ConversationID: conversationID,
+				SenderID:       msg.SenderID,
+				Content:        msg.Content,
+				MessageType:    msg.MessageType,
+				SystemKind:     msg.SystemKind,
+				IsRead:         msg.IsRead,
+				ReadAt:         msg.ReadAt,
+				HasContactInfo: msg.HasContactInfo,
+				IsFlagged:      msg.IsFlagged,
+				IsEncrypted:    msg.IsEncrypted,
+				HeldAt:         msg.HeldAt,
+				CreatedAt:      msg.CreatedAt,
+				UpdatedAt:      now,
+			})
+		}
+
+		err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if len(messages) > 0 {
+				if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).
+					CreateInBatches(&messages, 500).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&record).Update("restored_at", now).Error
+		})
+		if err != nil {
+			return restored, err
+		}
+		restored += len(messages)
+
+		// The messages are back in the database, so the copy is no longer needed
+		storage.store.remove(ctx, record.ObjectKey)
+	}
+	return restored, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+// ErrCaptchaDisabled is returned when no captcha provider is configured
+var ErrCaptchaDisabled = errors.New("captcha provider not configured")
+
+// CaptchaVerifier checks a token a client got from solving a captcha
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// NewCaptchaVerifier returns a client for the siteverify endpoint at
+// CAPTCHA_VERIFY_URL, which Turnstile and hCaptcha both offer, or a verifier
+// that always fails with ErrCaptchaDisabled when it is unset
+func NewCaptchaVerifier(cfg *config.Config) CaptchaVerifier {
+	if cfg.CaptchaVerifyURL == "" {
+		return disabledCaptchaVerifier{}
+	}
+	return &httpCaptchaVerifier{
+		url:    cfg.CaptchaVerifyURL,
+		secret: cfg.CaptchaSecret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type httpCaptchaVerifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (v *httpCaptchaVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {v.secret}, "response": {token}, "remoteip": {remoteIP}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("failed to build captcha request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach captcha provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("captcha provider returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode captcha response: %w", err)
+	}
+	return result.Success, nil
+}
+
+type disabledCaptchaVerifier struct{}
+
+func (disabledCaptchaVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	return false, ErrCaptchaDisabled
+}
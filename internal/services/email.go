@@ -0,0 +1,78 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+// ErrEmailDisabled is returned when no email API is configured
+var ErrEmailDisabled = fmt.Errorf("email API not configured: %w", ErrChannelDisabled)
+
+// Mailer sends plain text emails
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// NewMailer returns a client for the transactional email API at
+// EMAIL_API_URL, or a mailer that always fails with ErrEmailDisabled when it
+// is unset
+func NewMailer(cfg *config.Config) Mailer {
+	if cfg.EmailAPIURL == "" {
+		return disabledMailer{}
+	}
+	return &httpMailer{
+		url:    cfg.EmailAPIURL,
+		apiKey: cfg.EmailAPIKey,
+		from:   cfg.EmailFrom,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type httpMailer struct {
+	url    string
+	apiKey string
+	from   string
+	client *http.Client
+}
+
+func (m *httpMailer) Send(ctx context.Context, to, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{
+		"from":    m.from,
+		"to":      to,
+		"subject": subject,
+		"text":    body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build email request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("email API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type disabledMailer struct{}
+
+func (disabledMailer) Send(ctx context.Context, to, subject, body string) error {
+	return ErrEmailDisabled
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+// ErrIdentityProviderDisabled is returned when no Fayda gateway is configured
+var ErrIdentityProviderDisabled = errors.New("identity provider not configured")
+
+// IdentityCheck is the data matched against the national ID registry
+type IdentityCheck struct {
+	FaydaNumber string    `json:"fin"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	DateOfBirth time.Time `json:"date_of_birth"`
+}
+
+// IdentityResult is the registry's answer. Matched is false when the number
+// exists but the personal details differ.
+type IdentityResult struct {
+	Matched   bool   `json:"matched"`
+	Reference string `json:"reference"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// IdentityVerifier checks a user's details against the Fayda national ID
+type IdentityVerifier interface {
+	Verify(ctx context.Context, check IdentityCheck) (IdentityResult, error)
+}
+
+// NewIdentityVerifier returns a Fayda gateway client, or a verifier that
+// always fails with ErrIdentityProviderDisabled when FAYDA_API_URL is unset
+func NewIdentityVerifier(cfg *config.Config) IdentityVerifier {
+	if cfg.FaydaAPIURL == "" {
+		return disabledIdentityVerifier{}
+	}
+	return &faydaVerifier{
+		url:    cfg.FaydaAPIURL,
+		apiKey: cfg.FaydaAPIKey,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type faydaVerifier struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func (v *faydaVerifier) Verify(ctx context.Context, check IdentityCheck) (IdentityResult, error) {
+	payload, err := json.Marshal(map[string]string{
+		"fin":           check.FaydaNumber,
+		"first_name":    check.FirstName,
+		"last_name":     check.LastName,
+		"date_of_birth": check.DateOfBirth.Format("2006-01-02"),
+	})
+	if err != nil {
+		return IdentityResult{}, fmt.Errorf("failed to encode identity check: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, bytes.NewReader(payload))
+	if err != nil {
+		return IdentityResult{}, fmt.Errorf("failed to build identity request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+v.apiKey)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return IdentityResult{}, fmt.Errorf("failed to reach fayda gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return IdentityResult{}, fmt.Errorf("fayda gateway returned status %d", resp.StatusCode)
+	}
+
+	var result IdentityResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return IdentityResult{}, fmt.Errorf("failed to decode fayda response: %w", err)
+	}
+	return result, nil
+}
+
+type disabledIdentityVerifier struct{}
+
+func (disabledIdentityVerifier) Verify(ctx context.Context, check IdentityCheck) (IdentityResult, error) {
+	return IdentityResult{}, ErrIdentityProviderDisabled
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+var (
+	// ErrGeoIPDisabled is returned when no GeoIP database is configured
+	ErrGeoIPDisabled = errors.New("geoip database not configured")
+	// ErrLocationUnknown is returned when an address can't be placed in a city
+	ErrLocationUnknown = errors.New("no city-level location for address")
+)
+
+// GeoLocation is the approximate position of an IP address. It is only ever
+// city-level, and AccuracyKm is the database's own radius for the guess.
+type GeoLocation struct {
+	City       string
+	Country    string
+	Latitude   float64
+	Longitude  float64
+	AccuracyKm int
+}
+
+// GeoLocator guesses where an IP address is
+type GeoLocator interface {
+	Locate(ip string) (GeoLocation, error)
+}
+
+// NewGeoLocator opens the MaxMind City database at GEOIP_DATABASE, or returns
+// a locator that always fails with ErrGeoIPDisabled when it is unset
+func NewGeoLocator(cfg *config.Config) (GeoLocator, error) {
+	if cfg.GeoIPDatabase == "" {
+		return disabledGeoLocator{}, nil
+	}
+	reader, err := openMMDB(cfg.GeoIPDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &mmdbGeoLocator{reader: reader}, nil
+}
+
+type mmdbGeoLocator struct {
+	reader *mmdbReader
+}
+
+func (l *mmdbGeoLocator) Locate(ip string) (GeoLocation, error) {
+	address := net.ParseIP(ip)
+	if address == nil || address.IsLoopback() || address.IsPrivate() {
+		return GeoLocation{}, ErrLocationUnknown
+	}
+
+	record, err := l.reader.lookup(address)
+	if err != nil {
+		return GeoLocation{}, err
+	}
+
+	// Field names follow the GeoLite2/GeoIP2 City layout
+	city, _ := mmdbPath(record, "city", "names", "en").(string)
+	country, _ := mmdbPath(record, "country", "iso_code").(string)
+	latitude, hasLatitude := mmdbPath(record, "location", "latitude").(float64)
+	longitude, hasLongitude := mmdbPath(record, "location", "longitude").(float64)
+	accuracy, _ := mmdbPath(record, "location", "accuracy_radius").(uint64)
+
+	// Country-only results point at the country's centroid, which would put
+	// everyone in the same spot
+	if city == "" || !hasLatitude || !hasLongitude {
+		return GeoLocation{}, ErrLocationUnknown
+	}
+
+	return GeoLocation{
+		City:       city,
+		Country:    country,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		AccuracyKm: int(accuracy),
+	}, nil
+}
+
+type disabledGeoLocator struct{}
+
+func (disabledGeoLocator) Locate(string) (GeoLocation, error) {
+	return GeoLocation{}, ErrGeoIPDisabled
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/redis"
+)
+
+const maintenanceKey = "maintenance"
+
+// defaultRetryAfter is sent to clients when no explicit value was set
+const defaultRetryAfter = 5 * time.Minute
+
+// MaintenanceState is the current maintenance switch, shared by all instances
+// through Redis. MAINTENANCE_MODE only applies until an admin sets the flag.
+type MaintenanceState struct {
+	Enabled    bool       `json:"enabled"`
+	Message    string     `json:"message,omitempty"`
+	RetryAfter int        `json:"retry_after"` // seconds
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// LoadMaintenance reads the maintenance flag, falling back to config when
+// Redis has no value or is unreachable
+func LoadMaintenance(ctx context.Context, rc *redis.Client, cfg *config.Config) MaintenanceState {
+	state := MaintenanceState{
+		Enabled:    cfg.MaintenanceMode,
+		Message:    cfg.MaintenanceMessage,
+		RetryAfter: int(defaultRetryAfter.Seconds()),
+	}
+	if rc == nil {
+		return state
+	}
+
+	values, err := rc.HGetAll(ctx, maintenanceKey)
+	if err != nil || len(values) == 0 {
+		return state
+	}
+
+	state.Enabled = values["enabled"] == "1"
+	if values["message"] != "" {
+		state.Message = values["message"]
+	}
+	if retryAfter, err := strconv.Atoi(values["retry_after"]); err == nil && retryAfter > 0 {
+		state.RetryAfter = retryAfter
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, values["updated_at"]); err == nil {
+		state.UpdatedAt = &updatedAt
+	}
+	return state
+}
+
+// SaveMaintenance stores the maintenance flag for all instances
+func SaveMaintenance(ctx context.Context, rc *redis.Client, state MaintenanceState) error {
+	enabled := "0"
+	if state.Enabled {
+		enabled = "1"
+	}
+	return rc.HSet(ctx, maintenanceKey,
+		"enabled", enabled,
+		"message", state.Message,
+		"retry_after", strconv.Itoa(state.RetryAfter),
+		"updated_at", time.Now().Format(time.RFC3339),
+	)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Why a match's users are asked for feedback
+const (
+	FeedbackAfterUnmatch = "unmatch"
+	FeedbackAfterMonth   = "thirty_days"
+)
+
+// AskForMatchFeedback asks both users of a match how it went, with a
+// notification pointing the app at the feedback endpoint. Each match is
+// asked about once, whichever comes first of an unmatch or a month of
+// chatting; it reports whether the users were asked now.
+func AskForMatchFeedback(db *gorm.DB, notifier *Dispatcher, matchID uint, trigger string) (bool, error) {
+	result := db.Model(&models.Match{}).Where("id = ? AND feedback_asked_at IS NULL", matchID).
+		Update("feedback_asked_at", gorm.Expr("NOW()"))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	var match models.Match
+	if err := db.Unscoped().Preload("User1").Preload("User2").Where("id = ?", matchID).First(&match).Error; err != nil {
+		return false, err
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"match_id": matchID,
+		"trigger":  trigger,
+		"path":     fmt.Sprintf("/api/v1/matches/%d/feedback", matchID),
+	})
+	for _, pair := range [][2]models.User{{match.User1, match.User2}, {match.User2, match.User1}} {
+		user, other := pair[0], pair[1]
+		notification := models.Notification{
+			UserID: user.ID,
+			Type:   "match_feedback",
+			Title:  "How did it go?",
+			Body:   fmt.Sprintf("How was your match with %s? Your answer stays private and helps us suggest better matches.", other.FirstName),
+			Data:   string(data),
+		}
+		if err := db.Create(&notification).Error; err != nil {
+			return true, err
+		}
+		notifier.Dispatch(Notice{Notification: notification, Text: fmt.Sprintf("How was your match with %s? Open the app to tell us.", other.FirstName)})
+	}
+	return true, nil
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+)
+
+// mmdbMetadataMarker precedes the metadata map at the end of a MaxMind DB file
+var mmdbMetadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+var errMMDBCorrupt = errors.New("corrupt MaxMind DB data")
+
+// mmdbReader is a minimal reader for the MaxMind DB format used by GeoLite2
+// and GeoIP2. The whole file is held in memory; lookups walk the binary
+// search tree one address bit at a time and decode the record it points to.
+type mmdbReader struct {
+	tree       []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	markerAt := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if markerAt < 0 {
+		return nil, fmt.Errorf("%s is not a MaxMind DB file", path)
+	}
+	metadata, _, err := decodeMMDB(buf[markerAt+len(mmdbMetadataMarker):], 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata: %w", err)
+	}
+	fields, ok := metadata.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata")
+	}
+
+	r := &mmdbReader{
+		nodeCount:  mmdbUint(fields["node_count"]),
+		recordSize: mmdbUint(fields["record_size"]),
+		ipVersion:  mmdbUint(fields["ip_version"]),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", r.recordSize)
+	}
+
+	// Each node holds two records; the data section follows the tree after
+	// 16 zero bytes
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(markerAt) {
+		return nil, errMMDBCorrupt
+	}
+	r.tree = buf[:treeSize]
+	r.data = buf[treeSize+16 : markerAt]
+
+	// IPv4 addresses live under ::/96 in IPv6 databases
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+
+	return r, nil
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a node
+func (r *mmdbReader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.tree[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(r.tree[node*8+bit*4:]))
+	}
+}
+
+// lookup returns the decoded record for ip, or nil when the database has none
+func (r *mmdbReader) lookup(ip net.IP) (interface{}, error) {
+	var address []byte
+	node := uint(0)
+	if v4 := ip.To4(); v4 != nil {
+		address, node = v4, r.ipv4Start
+	} else if r.ipVersion == 6 {
+		address = ip.To16()
+	} else {
+		return nil, nil
+	}
+
+	for i := 0; i < len(address)*8 && node < r.nodeCount; i++ {
+		bit := uint(address[i/8]>>(7-uint(i%8))) & 1
+		node = r.record(node, bit)
+	}
+
+	switch {
+	case node == r.nodeCount:
+		return nil, nil
+	case node < r.nodeCount:
+		return nil, errMMDBCorrupt
+	}
+
+	offset := node - r.nodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return nil, errMMDBCorrupt
+	}
+	value, _, err := decodeMMDB(r.data, offset)
+	return value, err
+}
+
+// decodeMMDB decodes the value at offset and returns the offset after it.
+// Maps decode to map[string]interface{}, arrays to []interface{}, unsigned
+// integers to uint64 (uint128 to *big.Int), and floats to float64.
+func decodeMMDB(buf []byte, offset uint) (interface{}, uint, error) {
+	if offset >= uint(len(buf)) {
+		return nil, 0, errMMDBCorrupt
+	}
+	control := buf[offset]
+	offset++
+	kind := uint(control >> 5)
+
+	if kind == 1 {
+		// Pointers hold a data section offset in 1-4 bytes, with bias
+		n := uint(control>>3)&0x3 + 1
+		if offset+n > uint(len(buf)) {
+			return nil, 0, errMMDBCorrupt
+		}
+		b := buf[offset : offset+n]
+		v := uint(control & 0x7)
+		var target uint
+		switch n {
+		case 1:
+			target = v<<8 | uint(b[0])
+		case 2:
+			target = (v<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 3:
+			target = (v<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		default:
+			target = uint(binary.BigEndian.Uint32(b))
+		}
+		value, _, err := decodeMMDB(buf, target)
+		return value, offset + n, err
+	}
+
+	if kind == 0 {
+		if offset >= uint(len(buf)) {
+			return nil, 0, errMMDBCorrupt
+		}
+		kind = 7 + uint(buf[offset])
+		offset++
+	}
+
+	size := uint(control & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(buf)) {
+			return nil, 0, errMMDBCorrupt
+		}
+		b := buf[offset : offset+n]
+		switch size {
+		case 29:
+			size = 29 + uint(b[0])
+		case 30:
+			size = 285 + (uint(b[0])<<8 | uint(b[1]))
+		default:
+			size = 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+		}
+		offset += n
+	}
+
+	switch kind {
+	case 7: // map
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := decodeMMDB(buf, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errMMDBCorrupt
+			}
+			value, next, err := decodeMMDB(buf, next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+			offset = next
+		}
+		return m, offset, nil
+	case 11: // array
+		values := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := decodeMMDB(buf, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			offset = next
+		}
+		return values, offset, nil
+	case 14: // boolean, stored in the size
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(buf)) {
+		return nil, 0, errMMDBCorrupt
+	}
+	b := buf[offset : offset+size]
+	offset += size
+
+	switch kind {
+	case 2: // UTF-8 string
+		return string(b), offset, nil
+	case 3: // double
+		if size != 8 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case 15: // float
+		if size != 4 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	case 4: // bytes
+		return append([]byte(nil), b...), offset, nil
+	case 5, 6, 9: // uint16, uint32, uint64
+		if size > 8 {
+			return nil, 0, errMMDBCorrupt
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, offset, nil
+	case 8: // int32
+		if size > 4 {
+			return nil, 0, errMMDBCorrupt
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), offset, nil
+	case 10: // uint128
+		return new(big.Int).SetBytes(b), offset, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported MaxMind DB type %d", kind)
+	}
+}
+
+// mmdbPath follows map keys into a decoded record, returning nil if any is missing
+func mmdbPath(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+func mmdbUint(value interface{}) uint {
+	v, _ := value.(uint64)
+	return uint(v)
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+// mmdbWriter builds small MaxMind DB files for tests, following the layout
+// the reader expects: search tree, 16 zero bytes, data section, then the
+// metadata marker and map.
+type mmdbWriter struct {
+	ipVersion  uint
+	recordSize uint
+	nodes      [][2]mmdbTestRecord
+	data       []byte
+}
+
+// mmdbTestRecord points at another node, at data, or nowhere
+type mmdbTestRecord struct {
+	kind  int // 0 empty, 1 node, 2 data
+	value uint
+}
+
+func newMMDBWriter(ipVersion, recordSize uint) *mmdbWriter {
+	return &mmdbWriter{ipVersion: ipVersion, recordSize: recordSize, nodes: make([][2]mmdbTestRecord, 1)}
+}
+
+// insert stores value for every address in cidr. IPv4 networks go under
+// ::/96 in IPv6 databases.
+func (w *mmdbWriter) insert(t *testing.T, cidr string, value interface{}) {
+	t.Helper()
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := []byte(network.IP)
+	prefix, _ := network.Mask.Size()
+	if len(address) == 4 && w.ipVersion == 6 {
+		address = append(make([]byte, 12), address...)
+		prefix += 96
+	}
+
+	offset := uint(len(w.data))
+	w.data = append(w.data, encodeMMDB(value)...)
+
+	node := uint(0)
+	for i := 0; i < prefix; i++ {
+		bit := uint(address[i/8]>>(7-uint(i%8))) & 1
+		if i == prefix-1 {
+			w.nodes[node][bit] = mmdbTestRecord{kind: 2, value: offset}
+			break
+		}
+		if w.nodes[node][bit].kind != 1 {
+			w.nodes = append(w.nodes, [2]mmdbTestRecord{})
+			w.nodes[node][bit] = mmdbTestRecord{kind: 1, value: uint(len(w.nodes) - 1)}
+		}
+		node = w.nodes[node][bit].value
+	}
+}
+
+func (w *mmdbWriter) bytes() []byte {
+	nodeCount := uint(len(w.nodes))
+	resolve := func(r mmdbTestRecord) uint {
+		switch r.kind {
+		case 1:
+			return r.value
+		case 2:
+			return nodeCount + 16 + r.value
+		}
+		return nodeCount
+	}
+
+	var buf []byte
+	for _, node := range w.nodes {
+		left, right := resolve(node[0]), resolve(node[1])
+		switch w.recordSize {
+		case 24:
+			buf = append(buf, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			buf = append(buf, byte(left>>16), byte(left>>8), byte(left),
+				byte(left>>24&0x0f)<<4|byte(right>>24&0x0f),
+				byte(right>>16), byte(right>>8), byte(right))
+		default:
+			buf = binary.BigEndian.AppendUint32(buf, uint32(left))
+			buf = binary.BigEndian.AppendUint32(buf, uint32(right))
+		}
+	}
+	buf = append(buf, make([]byte, 16)...)
+	buf = append(buf, w.data...)
+	buf = append(buf, mmdbMetadataMarker...)
+	return append(buf, encodeMMDB(map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(w.recordSize),
+		"ip_version":    uint16(w.ipVersion),
+		"database_type": "GeoLite2-City",
+	})...)
+}
+
+func (w *mmdbWriter) file(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, w.bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// mmdbControl encodes a field's type and size
+func mmdbControl(kind, size uint) []byte {
+	var out []byte
+	var extra []byte
+	switch {
+	case size < 29:
+	case size < 285:
+		extra = []byte{byte(size - 29)}
+		size = 29
+	case size < 65821:
+		extra = []byte{byte((size - 285) >> 8), byte(size - 285)}
+		size = 30
+	default:
+		extra = []byte{byte((size - 65821) >> 16), byte((size - 65821) >> 8), byte(size - 65821)}
+		size = 31
+	}
+	if kind > 7 {
+		out = append(out, byte(size), byte(kind-7))
+	} else {
+		out = append(out, byte(kind<<5|size))
+	}
+	return append(out, extra...)
+}
+
+func encodeMMDB(value interface{}) []byte {
+	switch v := value.(type) {
+	case string:
+		return append(mmdbControl(2, uint(len(v))), v...)
+	case float64:
+		return binary.BigEndian.AppendUint64(mmdbControl(3, 8), math.Float64bits(v))
+	case uint16:
+		return binary.BigEndian.AppendUint16(mmdbControl(5, 2), v)
+	case uint32:
+		return binary.BigEndian.AppendUint32(mmdbControl(6, 4), v)
+	case bool:
+		size := uint(0)
+		if v {
+			size = 1
+		}
+		return mmdbControl(14, size)
+	case []interface{}:
+		out := mmdbControl(11, uint(len(v)))
+		for _, item := range v {
+			out = append(out, encodeMMDB(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := mmdbControl(7, uint(len(v)))
+		for _, key := range keys {
+			out = append(out, encodeMMDB(key)...)
+			out = append(out, encodeMMDB(v[key])...)
+		}
+		return out
+	}
+	panic("unsupported test value")
+}
+
+func cityRecord(city, country string, latitude, longitude float64, accuracy uint16) map[string]interface{} {
+	return map[string]interface{}{
+		"city":    map[string]interface{}{"names": map[string]interface{}{"en": city, "am": "ከተማ"}},
+		"country": map[string]interface{}{"iso_code": country},
+		"location": map[string]interface{}{
+			"latitude":        latitude,
+			"longitude":       longitude,
+			"accuracy_radius": accuracy,
+		},
+	}
+}
+
+func TestMMDBGeoLocatorLocate(t *testing.T) {
+	countryOnly := map[string]interface{}{
+		"country":  map[string]interface{}{"iso_code": "ZA"},
+		"location": map[string]interface{}{"latitude": -29.0, "longitude": 24.0, "accuracy_radius": uint16(1000)},
+	}
+
+	for _, tc := range []struct {
+		ipVersion  uint
+		recordSize uint
+	}{{4, 24}, {6, 24}, {6, 28}, {6, 32}} {
+		w := newMMDBWriter(tc.ipVersion, tc.recordSize)
+		w.insert(t, "196.188.0.0/16", cityRecord("Addis Ababa", "ET", 9.0249, 38.7469, 50))
+		w.insert(t, "197.156.64.0/18", cityRecord("Bahir Dar", "ET", 11.5936, 37.3908, 100))
+		w.insert(t, "41.0.0.0/8", countryOnly)
+		if tc.ipVersion == 6 {
+			w.insert(t, "2c0f:f5c0::/32", cityRecord("Hawassa", "ET", 7.0621, 38.4764, 200))
+		}
+
+		locator, err := NewGeoLocator(&config.Config{GeoIPDatabase: w.file(t)})
+		if err != nil {
+			t.Fatalf("IPv%d/%d: open: %v", tc.ipVersion, tc.recordSize, err)
+		}
+
+		found := map[string]GeoLocation{
+			"196.188.12.34": {City: "Addis Ababa", Country: "ET", Latitude: 9.0249, Longitude: 38.7469, AccuracyKm: 50},
+			"197.156.100.1": {City: "Bahir Dar", Country: "ET", Latitude: 11.5936, Longitude: 37.3908, AccuracyKm: 100},
+		}
+		if tc.ipVersion == 6 {
+			found["2c0f:f5c0:1::1"] = GeoLocation{City: "Hawassa", Country: "ET", Latitude: 7.0621, Longitude: 38.4764, AccuracyKm: 200}
+		}
+		for ip, want := range found {
+			got, err := locator.Locate(ip)
+			if err != nil {
+				t.Errorf("IPv%d/%d: Locate(%s): %v", tc.ipVersion, tc.recordSize, ip, err)
+				continue
+			}
+			if got != want {
+				t.Errorf("IPv%d/%d: Locate(%s) = %+v, want %+v", tc.ipVersion, tc.recordSize, ip, got, want)
+			}
+		}
+
+		for _, ip := range []string{
+			"41.1.2.3",    // country only
+			"8.8.8.8",     // not in the database
+			"197.156.0.1", // next to a network that is
+			"2001:db8::1",
+			"127.0.0.1",
+			"10.1.2.3",
+			"not an address",
+		} {
+			if _, err := locator.Locate(ip); !errors.Is(err, ErrLocationUnknown) {
+				t.Errorf("IPv%d/%d: Locate(%s) error = %v, want ErrLocationUnknown", tc.ipVersion, tc.recordSize, ip, err)
+			}
+		}
+	}
+}
+
+func TestNewGeoLocatorDisabled(t *testing.T) {
+	locator, err := NewGeoLocator(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := locator.Locate("196.188.12.34"); !errors.Is(err, ErrGeoIPDisabled) {
+		t.Errorf("Locate error = %v, want ErrGeoIPDisabled", err)
+	}
+}
+
+func TestOpenMMDBRejectsBadFiles(t *testing.T) {
+	w := newMMDBWriter(6, 24)
+	w.insert(t, "196.188.0.0/16", cityRecord("Addis Ababa", "ET", 9.0249, 38.7469, 50))
+	valid := w.bytes()
+	markerAt := len(valid) - len(encodeMMDB(map[string]interface{}{
+		"node_count":    uint32(len(w.nodes)),
+		"record_size":   uint16(24),
+		"ip_version":    uint16(6),
+		"database_type": "GeoLite2-City",
+	})) - len(mmdbMetadataMarker)
+
+	badRecordSize := append(append([]byte(nil), valid[:markerAt]...), mmdbMetadataMarker...)
+	badRecordSize = append(badRecordSize, encodeMMDB(map[string]interface{}{
+		"node_count":  uint32(len(w.nodes)),
+		"record_size": uint16(20),
+		"ip_version":  uint16(6),
+	})...)
+
+	tooManyNodes := append(append([]byte(nil), valid[:markerAt]...), mmdbMetadataMarker...)
+	tooManyNodes = append(tooManyNodes, encodeMMDB(map[string]interface{}{
+		"node_count":  uint32(1 << 20),
+		"record_size": uint16(24),
+		"ip_version":  uint16(6),
+	})...)
+
+	for name, buf := range map[string][]byte{
+		"no marker":        valid[:markerAt],
+		"bad metadata":     append(append([]byte(nil), valid[:markerAt]...), append(mmdbMetadataMarker, 0xff)...),
+		"bad record size":  badRecordSize,
+		"tree past marker": tooManyNodes,
+	} {
+		path := filepath.Join(t.TempDir(), "test.mmdb")
+		if err := os.WriteFile(path, buf, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := openMMDB(path); err == nil {
+			t.Errorf("%s: openMMDB succeeded", name)
+		}
+	}
+
+	if _, err := openMMDB(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("missing file: openMMDB succeeded")
+	}
+}
+
+func TestDecodeMMDB(t *testing.T) {
+	long := string(make([]byte, 300))
+
+	for _, tc := range []struct {
+		name string
+		buf  []byte
+		want interface{}
+	}{
+		{"string", encodeMMDB("Adama"), "Adama"},
+		{"string over 28 bytes", encodeMMDB(long[:40]), long[:40]},
+		{"string over 284 bytes", encodeMMDB(long), long},
+		{"double", encodeMMDB(9.0249), 9.0249},
+		{"float", append(mmdbControl(15, 4), 0x3f, 0xc0, 0x00, 0x00), 1.5},
+		{"bytes", append(mmdbControl(4, 2), 0xde, 0xad), []byte{0xde, 0xad}},
+		{"uint16", encodeMMDB(uint16(1000)), uint64(1000)},
+		{"uint32", encodeMMDB(uint32(70000)), uint64(70000)},
+		{"uint64", append(mmdbControl(9, 5), 0x01, 0x00, 0x00, 0x00, 0x00), uint64(1 << 32)},
+		{"short uint32", append(mmdbControl(6, 1), 0x07), uint64(7)},
+		{"empty uint16", mmdbControl(5, 0), uint64(0)},
+		{"negative int32", append(mmdbControl(8, 4), 0xff, 0xff, 0xff, 0xfe), int64(-2)},
+		{"uint128", append(mmdbControl(10, 3), 0x01, 0x00, 0x00), big.NewInt(1 << 16)},
+		{"true", encodeMMDB(true), true},
+		{"false", encodeMMDB(false), false},
+		{"array", encodeMMDB([]interface{}{"en", "am"}), []interface{}{"en", "am"}},
+		{"map", encodeMMDB(map[string]interface{}{"iso_code": "ET", "geoname_id": uint32(337996)}),
+			map[string]interface{}{"iso_code": "ET", "geoname_id": uint64(337996)}},
+	} {
+		got, next, err := decodeMMDB(tc.buf, 0)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.name, got, tc.want)
+		}
+		if next != uint(len(tc.buf)) {
+			t.Errorf("%s: next offset %d, want %d", tc.name, next, len(tc.buf))
+		}
+	}
+}
+
+func TestDecodeMMDBPointers(t *testing.T) {
+	// Records commonly share values through pointers into the data section
+	shared := encodeMMDB("Ethiopia")
+	for _, tc := range []struct {
+		name    string
+		pointer func(target uint) []byte
+		target  uint
+	}{
+		{"1 byte", func(target uint) []byte { return []byte{0x20 | byte(target>>8), byte(target)} }, 0},
+		{"1 byte with high bits", func(target uint) []byte { return []byte{0x20 | byte(target>>8), byte(target)} }, 300},
+		{"2 bytes", func(target uint) []byte {
+			v := target - 2048
+			return []byte{0x28 | byte(v>>16), byte(v >> 8), byte(v)}
+		}, 3000},
+		{"3 bytes", func(target uint) []byte {
+			v := target - 526336
+			return []byte{0x30 | byte(v>>24), byte(v >> 16), byte(v >> 8), byte(v)}
+		}, 530000},
+		{"4 bytes", func(target uint) []byte {
+			return binary.BigEndian.AppendUint32([]byte{0x38}, uint32(target))
+		}, 600000},
+	} {
+		pointer := tc.pointer(tc.target)
+		record := encodeMMDB(map[string]interface{}{"en": ""})
+		record = record[:len(record)-1] // value replaced by the pointer
+		record = append(record, pointer...)
+
+		buf := make([]byte, tc.target+uint(len(shared)))
+		copy(buf[tc.target:], shared)
+		start := uint(len(buf))
+		buf = append(buf, record...)
+
+		got, next, err := decodeMMDB(buf, start)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if want := map[string]interface{}{"en": "Ethiopia"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, want %#v", tc.name, got, want)
+		}
+		if next != uint(len(buf)) {
+			t.Errorf("%s: next offset %d, want %d", tc.name, next, len(buf))
+		}
+	}
+}
+
+func TestDecodeMMDBCorrupt(t *testing.T) {
+	for name, buf := range map[string][]byte{
+		"empty":                {},
+		"truncated string":     append(mmdbControl(2, 10), "Adama"...),
+		"truncated size":       {2<<5 | 30, 0x01},
+		"truncated extended":   {0x00},
+		"truncated pointer":    {0x28, 0x00},
+		"pointer past end":     {0x20, 0x40},
+		"double of 4 bytes":    append(mmdbControl(3, 4), 0, 0, 0, 0),
+		"float of 8 bytes":     append(mmdbControl(15, 8), make([]byte, 8)...),
+		"int32 of 5 bytes":     append(mmdbControl(8, 5), make([]byte, 5)...),
+		"uint64 of 9 bytes":    append(mmdbControl(9, 9), make([]byte, 9)...),
+		"map with integer key": append(mmdbControl(7, 1), append(encodeMMDB(uint16(1)), encodeMMDB("x")...)...),
+		"map missing value":    append(mmdbControl(7, 1), encodeMMDB("en")...),
+		"array missing item":   mmdbControl(11, 2),
+		"unknown type":         append(mmdbControl(12, 1), 0),
+	} {
+		if _, _, err := decodeMMDB(buf, 0); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+)
+
+const moderationChannel = "moderation"
+
+// Access tokens live a day and deactivated users can't refresh them, so a
+// block needn't outlast that
+const accessTokenLifetime = 24 * time.Hour
+
+// What a moderation event asks every instance to do with a user's open
+// connections
+const (
+	ModerationMute       = "mute"       // stop relaying what they send, such as typing
+	ModerationDisconnect = "disconnect" // close them
+)
+
+// ModerationEvent tells every instance about a penalty that takes effect now
+type ModerationEvent struct {
+	UserID uint       `json:"user_id"`
+	Action string     `json:"action"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+func accountBlockKey(userID uint) string {
+	return fmt.Sprintf("account_blocked:%d", userID)
+}
+
+// BlockAccount ends a suspended, banned or deactivated user's sessions: the
+// tokens they hold are refused from now on and their open connections are
+// closed on every instance. until is when a suspension ends, nil if it
+// doesn't.
+func BlockAccount(ctx context.Context, rc *redis.Client, userID uint, until *time.Time) {
+	ttl := accessTokenLifetime
+	if until != nil {
+		ttl = min(ttl, time.Until(*until))
+	}
+	if ttl > 0 {
+		if err := rc.Set(ctx, accountBlockKey(userID), 1, ttl); err != nil {
+			log.Printf("Failed to block sessions of user %d: %v", userID, err)
+		}
+	}
+	publishModerationEvent(ctx, rc, ModerationEvent{UserID: userID, Action: ModerationDisconnect, Until: until})
+}
+
+// UnblockAccount lets a reactivated user's tokens through again
+func UnblockAccount(ctx context.Context, rc *redis.Client, userID uint) {
+	if err := rc.Del(ctx, accountBlockKey(userID)); err != nil {
+		log.Printf("Failed to unblock sessions of user %d: %v", userID, err)
+	}
+}
+
+// AccountBlocked reports whether the user's sessions were ended by a block
+func AccountBlocked(ctx context.Context, rc *redis.Client, userID uint) (bool, error) {
+	n, err := rc.Exists(ctx, accountBlockKey(userID))
+	return n > 0, err
+}
+
+// MuteAccount tells every instance to stop relaying what a muted user sends
+// over their open connections. Requests are refused by the mute stored on
+// the user.
+func MuteAccount(ctx context.Context, rc *redis.Client, userID uint, until time.Time) {
+	publishModerationEvent(ctx, rc, ModerationEvent{UserID: userID, Action: ModerationMute, Until: &until})
+}
+
+func publishModerationEvent(ctx context.Context, rc *redis.Client, event ModerationEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if err := rc.Publish(ctx, moderationChannel, payload); err != nil {
+		log.Printf("Failed to publish %s for user %d: %v", event.Action, event.UserID, err)
+	}
+}
+
+// SubscribeModerationEvents returns moderation events published by any
+// instance until ctx is cancelled
+func SubscribeModerationEvents(ctx context.Context, rc *redis.Client) <-chan ModerationEvent {
+	pubsub := rc.Subscribe(ctx, moderationChannel)
+	events := make(chan ModerationEvent)
+
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event ModerationEvent
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const notifierTimeout = 30 * time.Second
+
+var (
+	// ErrUnreachable means the user has no address on a channel, e.g. no
+	// linked Telegram chat or no phone number
+	ErrUnreachable = errors.New("user not reachable on this channel")
+	// ErrChannelDisabled means the channel has no provider configured
+	ErrChannelDisabled = errors.New("channel not configured")
+)
+
+// Notice is a stored notification on its way out of the app. Text is what
+// channels outside the app show, which may say less than the notification
+// body, e.g. that a message arrived but not what it says.
+type Notice struct {
+	Notification models.Notification
+	Text         string
+}
+
+// Notifier delivers notices to users over one channel
+type Notifier interface {
+	Channel() string
+	Send(ctx context.Context, userID uint, notice Notice) error
+}
+
+// Dispatcher delivers each notice over the first channel in its chain that
+// works: channels the user can't be reached on or hasn't opted into are
+// skipped, and a failure falls through to the next. Every attempt is
+// recorded as a NotificationDelivery.
+type Dispatcher struct {
+	db        *gorm.DB
+	chain     []string
+	notifiers map[string]Notifier
+}
+
+// NewDispatcher builds a dispatcher trying channels in the given order, e.g.
+// push, telegram, sms. Channels without a notifier are ignored.
+func NewDispatcher(db *gorm.DB, chain []string, notifiers ...Notifier) *Dispatcher {
+	d := &Dispatcher{db: db, notifiers: make(map[string]Notifier)}
+	for _, n := range notifiers {
+		d.notifiers[n.Channel()] = n
+	}
+	for _, channel := range chain {
+		if _, ok := d.notifiers[channel]; ok {
+			d.chain = append(d.chain, channel)
+		} else {
+			log.Printf("Ignoring unknown notification channel %q", channel)
+		}
+	}
+	return d
+}
+
+// Dispatch delivers the notice in the background, so a slow provider never
+// holds up the request
+func (d *Dispatcher) Dispatch(notice Notice) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifierTimeout)
+		defer cancel()
+		d.Deliver(ctx, notice)
+	}()
+}
+
+// Deliver tries the chain in order until a channel sends the notice and
+// returns that channel, or "" when none did or the user opted out of the kind
+func (d *Dispatcher) Deliver(ctx context.Context, notice Notice) string {
+	db := d.db.WithContext(ctx)
+	userID := notice.Notification.UserID
+
+	prefs := models.DefaultNotificationPreferences(userID)
+	db.Where("user_id = ?", userID).First(&prefs)
+	if !prefs.Allows(notice.Notification.Type) {
+		return ""
+	}
+
+	for _, channel := range d.chain {
+		delivery := models.NotificationDelivery{
+			NotificationID: notice.Notification.ID,
+			UserID:         userID,
+			Channel:        channel,
+		}
+
+		var err error
+		if channel == "sms" && (!prefs.SMS || models.IsMarketing(notice.Notification.Type)) {
+			delivery.Status = "opted_out"
+		} else {
+			err = d.notifiers[channel].Send(ctx, userID, notice)
+			switch {
+			case err == nil:
+				delivery.Status = "sent"
+			case errors.Is(err, ErrChannelDisabled):
+				// Not a delivery problem; the channel just isn't set up here
+				continue
+			case errors.Is(err, ErrUnreachable):
+				delivery.Status = "unreachable"
+			default:
+				delivery.Status = "failed"
+				delivery.Error = err.Error()
+				log.Printf("Failed to send %s notification %d to user %d over %s: %v",
+					notice.Notification.Type, notice.Notification.ID, userID, channel, err)
+			}
+		}
+
+		if err := db.Create(&delivery).Error; err != nil {
+			log.Printf("Failed to record notification delivery: %v", err)
+		}
+		if delivery.Status == "sent" {
+			return channel
+		}
+	}
+	return ""
+}
+
+// NewPushNotifier sends to every device the user registered and succeeds if
+// any of them got it. Tokens the gateway rejects are forgotten.
+func NewPushNotifier(db *gorm.DB, sender PushSender) Notifier {
+	return &pushNotifier{db: db, sender: sender}
+}
+
+type pushNotifier struct {
+	db     *gorm.DB
+	sender PushSender
+}
+
+func (n *pushNotifier) Channel() string { return "push" }
+
+func (n *pushNotifier) Send(ctx context.Context, userID uint, notice Notice) error {
+	var tokens []models.PushToken
+	if err := n.db.WithContext(ctx).Where("user_id = ?", userID).Find(&tokens).Error; err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return ErrUnreachable
+	}
+
+	var lastErr error = ErrUnreachable
+	for _, token := range tokens {
+		err := n.sender.Send(ctx, token.Token, notice.Notification.Title, notice.Text, notice.Notification.Data)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrPushTokenInvalid) {
+			n.db.WithContext(ctx).Delete(&token)
+			continue
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// NewTelegramNotifier sends to the user's linked Telegram chat
+func NewTelegramNotifier(db *gorm.DB, bot TelegramBot) Notifier {
+	return &telegramNotifier{db: db, bot: bot}
+}
+
+type telegramNotifier struct {
+	db  *gorm.DB
+	bot TelegramBot
+}
+
+func (n *telegramNotifier) Channel() string { return "telegram" }
+
+func (n *telegramNotifier) Send(ctx context.Context, userID uint, notice Notice) error {
+	var link models.TelegramLink
+	if err := n.db.WithContext(ctx).Where("user_id = ?", userID).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnreachable
+		}
+		return err
+	}
+	return n.bot.SendMessage(ctx, link.ChatID, notice.Text)
+}
+
+// NewSMSNotifier texts the user's phone number
+func NewSMSNotifier(db *gorm.DB, sms SMSSender) Notifier {
+	return &smsNotifier{db: db, sms: sms}
+}
+
+type smsNotifier struct {
+	db  *gorm.DB
+	sms SMSSender
+}
+
+func (n *smsNotifier) Channel() string { return "sms" }
+
+func (n *smsNotifier) Send(ctx context.Context, userID uint, notice Notice) error {
+	var user models.User
+	if err := n.db.WithContext(ctx).Select("id", "phone").Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	if user.Phone == nil || *user.Phone == "" {
+		return ErrUnreachable
+	}
+	return n.sms.Send(ctx, *user.Phone, notice.Text)
+}
+
+// NewEmailNotifier emails the user's account address
+func NewEmailNotifier(db *gorm.DB, mailer Mailer) Notifier {
+	return &emailNotifier{db: db, mailer: mailer}
+}
+
+type emailNotifier struct {
+	db     *gorm.DB
+	mailer Mailer
+}
+
+func (n *emailNotifier) Channel() string { return "email" }
+
+func (n *emailNotifier) Send(ctx context.Context, userID uint, notice Notice) error {
+	var user models.User
+	if err := n.db.WithContext(ctx).Select("id", "email").Where("id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return ErrUnreachable
+	}
+	return n.mailer.Send(ctx, user.Email, notice.Notification.Title, notice.Text)
+}
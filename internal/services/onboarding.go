@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Onboarding steps, in the order the app walks new users through them
+const (
+	OnboardingVerifyContact  = "verify_contact"
+	OnboardingAddPhotos      = "add_photos"
+	OnboardingSetPreferences = "set_preferences"
+	OnboardingPickInterests  = "pick_interests"
+	OnboardingAnswerPrompts  = "answer_prompts"
+)
+
+// OnboardingStep is one step of onboarding and whether it is done. Discovery
+// opens once the required steps are.
+type OnboardingStep struct {
+	Name     string     `json:"name"`
+	Required bool       `json:"required"`
+	Done     bool       `json:"done"`
+	DoneAt   *time.Time `json:"done_at,omitempty"`
+}
+
+// Onboarding is where a user is in onboarding. CurrentStep is the first
+// required step left, else the first optional one, and empty when every step
+// is done.
+type Onboarding struct {
+	CurrentStep string           `json:"current_step"`
+	Steps       []OnboardingStep `json:"steps"`
+	Completed   int              `json:"completed"`
+	Percent     int              `json:"percent"`
+	CanDiscover bool             `json:"can_discover"`
+}
+
+// onboardingStep is a step with where its completion is recorded
+type onboardingStep struct {
+	name     string
+	required bool
+	at       **time.Time
+}
+
+func onboardingSteps(p *models.OnboardingProgress) []onboardingStep {
+	return []onboardingStep{
+		{OnboardingVerifyContact, true, &p.ContactVerifiedAt},
+		{OnboardingAddPhotos, true, &p.PhotosAddedAt},
+		{OnboardingSetPreferences, true, &p.PreferencesSetAt},
+		{OnboardingPickInterests, false, &p.InterestsPickedAt},
+		{OnboardingAnswerPrompts, false, &p.PromptsAnsweredAt},
+	}
+}
+
+// StartOnboarding opens onboarding for a new user. Without OTPs there is
+// nothing to verify, so the contact step starts done.
+func StartOnboarding(db *gorm.DB, userID uint, contactVerified bool) error {
+	progress := models.OnboardingProgress{UserID: userID}
+	if contactVerified {
+		now := time.Now()
+		progress.ContactVerifiedAt = &now
+	}
+	return db.Create(&progress).Error
+}
+
+// MarkOnboardingStep records a step the user's data can't show was done,
+// such as saving discovery preferences. Users not onboarding are left alone.
+func MarkOnboardingStep(db *gorm.DB, userID uint, step string) error {
+	var column string
+	switch step {
+	case OnboardingSetPreferences:
+		column = "preferences_set_at"
+	default:
+		return nil
+	}
+	return db.Model(&models.OnboardingProgress{}).
+		Where("user_id = ? AND "+column+" IS NULL", userID).
+		Update(column, time.Now()).Error
+}
+
+// GetOnboarding returns the user's onboarding, first recording the steps
+// their profile shows they have done since it was last checked
+func GetOnboarding(db *gorm.DB, userID uint) (Onboarding, error) {
+	return loadOnboarding(db, userID, false)
+}
+
+// GetDiscoveryOnboarding returns the user's onboarding for deciding whether
+// they may discover people. Once the required steps are done nothing more
+// is checked, so the optional ones aren't synced, and written, on every
+// request.
+func GetDiscoveryOnboarding(db *gorm.DB, userID uint) (Onboarding, error) {
+	return loadOnboarding(db, userID, true)
+}
+
+func loadOnboarding(db *gorm.DB, userID uint, requiredOnly bool) (Onboarding, error) {
+	var progress models.OnboardingProgress
+	err := db.Where("user_id = ?", userID).First(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Signed up before onboarding existed
+		now := time.Now()
+		progress = models.OnboardingProgress{
+			ContactVerifiedAt: &now, PhotosAddedAt: &now, PreferencesSetAt: &now,
+			InterestsPickedAt: &now, PromptsAnsweredAt: &now, CompletedAt: &now,
+		}
+		return newOnboarding(&progress), nil
+	}
+	if err != nil {
+		return Onboarding{}, err
+	}
+
+	optionalOpen := progress.InterestsPickedAt == nil || progress.PromptsAnsweredAt == nil
+	if progress.CompletedAt == nil || (optionalOpen && !requiredOnly) {
+		if err := syncOnboarding(db, &progress); err != nil {
+			return Onboarding{}, err
+		}
+	}
+	return newOnboarding(&progress), nil
+}
+
+// syncOnboarding marks the steps the user's profile shows are done
+func syncOnboarding(db *gorm.DB, progress *models.OnboardingProgress) error {
+	var user models.User
+	if err := db.Select("id", "is_verified").Where("id = ?", progress.UserID).First(&user).Error; err != nil {
+		return err
+	}
+	var photos, interests, prompts int64
+	if progress.PhotosAddedAt == nil {
+		db.Model(&models.ProfilePhoto{}).Where("user_id = ? AND moderation_status != ?", progress.UserID, "rejected").Count(&photos)
+	}
+	if progress.InterestsPickedAt == nil {
+		db.Model(&models.UserInterest{}).Where("user_id = ?", progress.UserID).Count(&interests)
+	}
+	if progress.PromptsAnsweredAt == nil {
+		db.Model(&models.ProfilePrompt{}).Where("user_id = ?", progress.UserID).Count(&prompts)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	mark := func(at **time.Time, column string, done bool) {
+		if *at == nil && done {
+			*at = &now
+			updates[column] = now
+		}
+	}
+	mark(&progress.ContactVerifiedAt, "contact_verified_at", user.IsVerified)
+	mark(&progress.PhotosAddedAt, "photos_added_at", photos > 0)
+	mark(&progress.InterestsPickedAt, "interests_picked_at", interests > 0)
+	mark(&progress.PromptsAnsweredAt, "prompts_answered_at", prompts > 0)
+
+	requiredDone := true
+	for _, step := range onboardingSteps(progress) {
+		if step.required && *step.at == nil {
+			requiredDone = false
+		}
+	}
+	mark(&progress.CompletedAt, "completed_at", requiredDone)
+
+	if len(updates) == 0 {
+		return nil
+	}
+	return db.Model(progress).Updates(updates).Error
+}
+
+func newOnboarding(progress *models.OnboardingProgress) Onboarding {
+	onboarding := Onboarding{CanDiscover: progress.CompletedAt != nil}
+	firstOptional := ""
+	for _, step := range onboardingSteps(progress) {
+		done := *step.at != nil
+		onboarding.Steps = append(onboarding.Steps, OnboardingStep{
+			Name:     step.name,
+			Required: step.required,
+			Done:     done,
+			DoneAt:   *step.at,
+		})
+		switch {
+		case done:
+			onboarding.Completed++
+		case step.required && onboarding.CurrentStep == "":
+			onboarding.CurrentStep = step.name
+		case !step.required && firstOptional == "":
+			firstOptional = step.name
+		}
+	}
+	if onboarding.CurrentStep == "" {
+		onboarding.CurrentStep = firstOptional
+	}
+	onboarding.Percent = onboarding.Completed * 100 / len(onboarding.Steps)
+	return onboarding
+}
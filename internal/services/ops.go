@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+)
+
+// opsChannel carries the admin operations feed between instances
+const opsChannel = "ops:events"
+
+// Operational event types shown on the admin dashboard
+const (
+	OpsUserRegistered = "user_registered"
+	OpsReportFiled    = "report_filed"
+	OpsPaymentFailed  = "payment_failed" // for the payment integration to publish
+	OpsErrorRateSpike = "error_rate_spike"
+	OpsAbuseFlagged   = "abuse_flagged"
+	OpsSLABreached    = "report_sla_breached"
+)
+
+// OpsEvent is one entry in the live admin operations feed
+type OpsEvent struct {
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// PublishOpsEvent sends an event to every connected admin dashboard. The feed
+// is best effort: nothing is stored, and failures are only logged.
+func PublishOpsEvent(ctx context.Context, rc *redis.Client, eventType string, data interface{}) {
+	if rc == nil {
+		return
+	}
+
+	payload, err := json.Marshal(OpsEvent{Type: eventType, Data: data, Timestamp: time.Now()})
+	if err != nil {
+		return
+	}
+	if err := rc.Publish(ctx, opsChannel, payload); err != nil {
+		log.Printf("Failed to publish %s ops event: %v", eventType, err)
+	}
+}
+
+// SubscribeOpsEvents returns the operations feed as JSON payloads until ctx
+// is cancelled
+func SubscribeOpsEvents(ctx context.Context, rc *redis.Client) <-chan string {
+	pubsub := rc.Subscribe(ctx, opsChannel)
+	events := make(chan string)
+
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case events <- message.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+const (
+	maxPhotoLabels      = 10
+	minLabelConfidence  = 0.7
+	photoLabelerTimeout = 20 * time.Second
+)
+
+// ErrPhotoLabelerDisabled is returned when no label detection service is
+// configured
+var ErrPhotoLabelerDisabled = errors.New("photo labeler not configured")
+
+// PhotoLabels is what label detection saw in a photo
+type PhotoLabels struct {
+	Tags       []string // confident labels, most confident first
+	Suggestive float64  // highest confidence of a SUGGESTIVE_PHOTO_LABELS label, 0 when none
+}
+
+// PhotoLabeler detects what a photo shows, e.g. "beach", "dog", "coffee"
+type PhotoLabeler interface {
+	Label(ctx context.Context, imageURL string) (PhotoLabels, error)
+}
+
+// NewPhotoLabeler returns a client for the label detection service at
+// PHOTO_LABELS_API_URL, or a labeler that always fails with
+// ErrPhotoLabelerDisabled when it is unset
+func NewPhotoLabeler(cfg *config.Config) PhotoLabeler {
+	if cfg.PhotoLabelsAPIURL == "" {
+		return disabledPhotoLabeler{}
+	}
+	suggestive := make(map[string]bool, len(cfg.SuggestivePhotoLabels))
+	for _, label := range cfg.SuggestivePhotoLabels {
+		suggestive[strings.ToLower(label)] = true
+	}
+	return &httpPhotoLabeler{
+		url:        cfg.PhotoLabelsAPIURL,
+		apiKey:     cfg.PhotoLabelsAPIKey,
+		suggestive: suggestive,
+		client:     &http.Client{Timeout: photoLabelerTimeout},
+	}
+}
+
+type httpPhotoLabeler struct {
+	url        string
+	apiKey     string
+	suggestive map[string]bool
+	client     *http.Client
+}
+
+// Label sends the photo URL and keeps the confident labels, lowercased and
+// de-duplicated, most confident first as the service returns them. Any
+// suggestive label counts towards the score, however unsure the service is.
+func (l *httpPhotoLabeler) Label(ctx context.Context, imageURL string) (PhotoLabels, error) {
+	payload, err := json.Marshal(map[string]string{"url": imageURL})
+	if err != nil {
+		return PhotoLabels{}, fmt.Errorf("failed to encode label request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(payload))
+	if err != nil {
+		return PhotoLabels{}, fmt.Errorf("failed to build label request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+l.apiKey)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return PhotoLabels{}, fmt.Errorf("failed to reach label service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return PhotoLabels{}, fmt.Errorf("label service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Labels []struct {
+			Name       string  `json:"name"`
+			Confidence float64 `json:"confidence"`
+		} `json:"labels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return PhotoLabels{}, fmt.Errorf("failed to decode label response: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	labels := PhotoLabels{Tags: make([]string, 0, maxPhotoLabels)}
+	for _, label := range result.Labels {
+		name := strings.ToLower(strings.TrimSpace(label.Name))
+		if l.suggestive[name] && label.Confidence > labels.Suggestive {
+			labels.Suggestive = label.Confidence
+		}
+		if name == "" || label.Confidence < minLabelConfidence || seen[name] || len(labels.Tags) == maxPhotoLabels {
+			continue
+		}
+		seen[name] = true
+		labels.Tags = append(labels.Tags, name)
+	}
+	return labels, nil
+}
+
+type disabledPhotoLabeler struct{}
+
+func (disabledPhotoLabeler) Label(ctx context.Context, imageURL string) (PhotoLabels, error) {
+	return PhotoLabels{}, ErrPhotoLabelerDisabled
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+
+	"gorm.io/gorm"
+)
+
+const (
+	publicStatsKey = "public_stats"
+	// Kept past the hourly refresh so a missed run doesn't empty the site
+	publicStatsTTL = 3 * time.Hour
+	// A city counts once this many active users are in it
+	publicStatsCityMinUsers = 10
+)
+
+// PublicStats are coarse platform numbers for the marketing site. Counts are
+// rounded down so they don't reveal exact figures or change with each sign-up.
+type PublicStats struct {
+	Users     int64     `json:"users"`
+	Matches   int64     `json:"matches"`
+	Cities    int64     `json:"cities"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// roundDown keeps the two leading digits of n, so 12,345 becomes 12,000
+func roundDown(n int64) int64 {
+	if n < 100 {
+		return n / 10 * 10
+	}
+	step := int64(math.Pow10(int(math.Log10(float64(n))) - 1))
+	return n / step * step
+}
+
+// ComputePublicStats counts active users, matches ever made, and cities with
+// enough users to count as covered
+func ComputePublicStats(ctx context.Context, db *gorm.DB) (PublicStats, error) {
+	stats := PublicStats{UpdatedAt: time.Now()}
+	err := db.WithContext(ctx).Raw(`
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND is_active) AS users,
+			(SELECT COUNT(*) FROM matches) AS matches,
+			(SELECT COUNT(*) FROM (
+				SELECT 1 FROM users
+				WHERE deleted_at IS NULL AND is_active AND TRIM(COALESCE(location, '')) != ''
+				GROUP BY LOWER(REGEXP_REPLACE(TRIM(location), '\s+', ' ', 'g'))
+				HAVING COUNT(*) >= ?
+			) covered) AS cities`, publicStatsCityMinUsers).
+		Scan(&stats).Error
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Users = roundDown(stats.Users)
+	stats.Matches = roundDown(stats.Matches)
+	return stats, nil
+}
+
+// SavePublicStats stores the numbers the public endpoint serves
+func SavePublicStats(ctx context.Context, rc *redis.Client, stats PublicStats) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return rc.Set(ctx, publicStatsKey, data, publicStatsTTL)
+}
+
+// LoadPublicStats returns the stored numbers; ok is false when there are none
+func LoadPublicStats(ctx context.Context, rc *redis.Client) (stats PublicStats, ok bool) {
+	cached, err := rc.Get(ctx, publicStatsKey)
+	if err != nil {
+		return stats, false
+	}
+	return stats, json.Unmarshal([]byte(cached), &stats) == nil
+}
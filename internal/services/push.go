@@ -0,0 +1,84 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+var (
+	// ErrPushDisabled is returned when no push gateway is configured
+	ErrPushDisabled = fmt.Errorf("push gateway not configured: %w", ErrChannelDisabled)
+	// ErrPushTokenInvalid means the device uninstalled the app or the token
+	// expired, so it should be forgotten
+	ErrPushTokenInvalid = errors.New("push token no longer valid")
+)
+
+// PushSender delivers push notifications to device tokens
+type PushSender interface {
+	Send(ctx context.Context, token, title, body, data string) error
+}
+
+// NewPushSender returns a client for the push gateway at PUSH_GATEWAY_URL, or
+// a sender that always fails with ErrPushDisabled when it is unset
+func NewPushSender(cfg *config.Config) PushSender {
+	if cfg.PushGatewayURL == "" {
+		return disabledPushSender{}
+	}
+	return &httpPushSender{
+		url:    cfg.PushGatewayURL,
+		apiKey: cfg.PushAPIKey,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type httpPushSender struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func (s *httpPushSender) Send(ctx context.Context, token, title, body, data string) error {
+	payload, err := json.Marshal(map[string]string{
+		"token": token,
+		"title": title,
+		"body":  body,
+		"data":  data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode push notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrPushTokenInvalid
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type disabledPushSender struct{}
+
+func (disabledPushSender) Send(ctx context.Context, token, title, body, data string) error {
+	return ErrPushDisabled
+}
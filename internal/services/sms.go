@@ -0,0 +1,76 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+// ErrSMSDisabled is returned when no SMS gateway is configured
+var ErrSMSDisabled = fmt.Errorf("SMS gateway not configured: %w", ErrChannelDisabled)
+
+// SMSSender delivers text messages to phone numbers
+type SMSSender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// NewSMSSender returns an HTTP gateway sender when SMS_GATEWAY_URL is set,
+// or a sender that always fails with ErrSMSDisabled when it is unset
+func NewSMSSender(cfg *config.Config) SMSSender {
+	if cfg.SMSGatewayURL == "" {
+		return disabledSMSSender{}
+	}
+	return &httpSMSSender{
+		url:    cfg.SMSGatewayURL,
+		apiKey: cfg.SMSAPIKey,
+		sender: cfg.SMSSenderID,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type httpSMSSender struct {
+	url    string
+	apiKey string
+	sender string
+	client *http.Client
+}
+
+func (s *httpSMSSender) Send(ctx context.Context, to, body string) error {
+	payload, err := json.Marshal(map[string]string{
+		"to":      to,
+		"from":    s.sender,
+		"message": body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode SMS: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type disabledSMSSender struct{}
+
+func (disabledSMSSender) Send(ctx context.Context, to, body string) error {
+	return ErrSMSDisabled
+}
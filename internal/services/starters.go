@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+
+	"ethiopia-dating-app/internal/models"
+)
+
+// interestOpeners take one shared interest name
+var interestOpeners = []string{
+	"You both love %s! What got you into it?",
+	"Okay, important question: what's your favorite thing about %s?",
+	"Fellow %s fan here 👋 Any recommendations?",
+}
+
+// pairOpeners take two shared interest names
+var pairOpeners = []string{
+	"%s and %s? Looks like we have a lot in common. Which one should we talk about first?",
+}
+
+// genericOpeners are used when two users share no interests
+var genericOpeners = []string{
+	"What's the best meal you've had in Addis?",
+	"Coffee ceremony or macchiato: which one are you?",
+	"What does your perfect weekend look like?",
+}
+
+// ConversationStarters returns up to three openers referencing the interests
+// two users share, falling back to general questions when they share none.
+// seed rotates the templates so matches with the same interests don't all get
+// identical openers.
+func ConversationStarters(shared []models.Interest, seed uint) []string {
+	if len(shared) == 0 {
+		return pick(genericOpeners, seed, 3)
+	}
+
+	var openers []string
+	for i, interest := range shared {
+		if i == 2 {
+			break
+		}
+		template := interestOpeners[(int(seed)+i)%len(interestOpeners)]
+		openers = append(openers, fmt.Sprintf(template, interest.Name))
+	}
+
+	if len(shared) >= 2 {
+		openers = append(openers, fmt.Sprintf(pairOpeners[int(seed)%len(pairOpeners)], shared[0].Name, shared[1].Name))
+	} else {
+		openers = append(openers, pick(genericOpeners, seed, 1)...)
+	}
+	return openers
+}
+
+func pick(templates []string, seed uint, n int) []string {
+	if n > len(templates) {
+		n = len(templates)
+	}
+	picked := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		picked = append(picked, templates[(int(seed)+i)%len(templates)])
+	}
+	return picked
+}
@@ -1,28 +1,51 @@
 package services
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"ethiopia-dating-app/internal/config"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awscreds "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	miniocreds "github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+// objectStore is implemented by the S3 and MinIO backends
+type objectStore interface {
+	upload(ctx context.Context, file io.Reader, key, contentType string, public bool) error
+	download(ctx context.Context, key string) (io.ReadCloser, error)
+	remove(ctx context.Context, key string) error
+	list(ctx context.Context, fn func(StoredObject) error) error
+	presign(ctx context.Context, key string, expiration time.Duration) (string, error)
+	createBucket(ctx context.Context) error
+	publicURL(key string) string
+}
+
+// StoredObject is one object in the bucket
+type StoredObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// StorageService stores user uploads in S3 or, when MINIO_ENDPOINT is set, in
+// MinIO. Every call is bounded by STORAGE_TIMEOUT on top of the caller's
+// context.
 type StorageService struct {
-	cfg         *config.Config
-	s3Client    *s3.S3
-	minioClient *minio.Client
-	useMinIO    bool
+	cfg   *config.Config
+	store objectStore
 }
 
 func NewStorageService(cfg *config.Config) (*StorageService, error) {
@@ -30,119 +53,141 @@ func NewStorageService(cfg *config.Config) (*StorageService, error) {
 
 	// Check if MinIO is configured
 	if cfg.MinIOEndpoint != "" {
-		service.useMinIO = true
 		minioClient, err := minio.New(cfg.MinIOEndpoint, &minio.Options{
-			Creds:  credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
+			Creds:  miniocreds.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
 			Secure: cfg.MinIOUseSSL,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create MinIO client: %w", err)
 		}
-		service.minioClient = minioClient
-	} else {
-		// Use AWS S3
-		sess, err := session.NewSession(&aws.Config{
-			Region: aws.String(cfg.AWSRegion),
-			Credentials: credentials.NewStaticCredentials(
-				cfg.AWSAccessKeyID,
-				cfg.AWSSecretAccessKey,
-				"",
-			),
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create AWS session: %w", err)
-		}
-		service.s3Client = s3.New(sess)
+		service.store = &minioStore{cfg: cfg, client: minioClient}
+		return service, nil
 	}
 
+	// Use AWS S3
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(),
+		awsconfig.WithRegion(cfg.AWSRegion),
+		awsconfig.WithCredentialsProvider(awscreds.NewStaticCredentialsProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg)
+	service.store = &s3Store{
+		cfg:    cfg,
+		client: client,
+		uploader: manager.NewUploader(client, func(u *manager.Uploader) {
+			u.PartSize = cfg.S3UploadPartSize
+		}),
+		presigner: s3.NewPresignClient(client),
+	}
 	return service, nil
 }
 
-func (s *StorageService) UploadFile(file io.Reader, filename, contentType string) (string, error) {
-	if s.useMinIO {
-		return s.uploadToMinIO(file, filename, contentType)
+// UploadFile streams file to storage and returns its public URL. Large files
+// are sent as a multipart upload without being buffered in memory.
+func (s *StorageService) UploadFile(ctx context.Context, file io.Reader, filename, contentType string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+
+	if err := s.store.upload(ctx, file, filename, contentType, true); err != nil {
+		return "", err
 	}
-	return s.uploadToS3(file, filename, contentType)
+	return s.store.publicURL(filename), nil
+}
+
+// UploadPrivateFile stores file under key without making it publicly
+// readable, for data only the server reads back, such as message archives
+func (s *StorageService) UploadPrivateFile(ctx context.Context, file io.Reader, key, contentType string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+	return s.store.upload(ctx, file, key, contentType, false)
 }
 
-func (s *StorageService) DeleteFile(url string) error {
+// DownloadFile reads the whole object stored under key
+func (s *StorageService) DownloadFile(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+
+	body, err := s.store.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return data, nil
+}
+
+func (s *StorageService) DeleteFile(ctx context.Context, url string) error {
 	// Extract key from URL
 	key := s.extractKeyFromURL(url)
 	if key == "" {
 		return fmt.Errorf("invalid file URL")
 	}
 
-	if s.useMinIO {
-		return s.deleteFromMinIO(key)
-	}
-	return s.deleteFromS3(key)
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+	return s.store.remove(ctx, key)
 }
 
-func (s *StorageService) uploadToS3(file io.Reader, filename, contentType string) (string, error) {
-	// Read file content
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+// CopyFile copies the object a stored file's URL points at to key, publicly
+// readable or not, and returns the copy's URL. Access is set per object on
+// S3; MinIO buckets decide it by prefix in their policy.
+func (s *StorageService) CopyFile(ctx context.Context, url, key string, public bool) (string, error) {
+	source := s.extractKeyFromURL(url)
+	if source == "" {
+		return "", fmt.Errorf("invalid file URL")
 	}
 
-	// Upload to S3
-	_, err = s.s3Client.PutObject(&s3.PutObjectInput{
-		Bucket:      aws.String(s.cfg.S3Bucket),
-		Key:         aws.String(filename),
-		Body:        bytes.NewReader(fileBytes),
-		ContentType: aws.String(contentType),
-		ACL:         aws.String("public-read"),
-	})
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+
+	body, err := s.store.download(ctx, source)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload to S3: %w", err)
+		return "", err
 	}
+	defer body.Close()
+	if err := s.store.upload(ctx, body, key, mime.TypeByExtension(filepath.Ext(key)), public); err != nil {
+		return "", err
+	}
+	return s.store.publicURL(key), nil
+}
 
-	// Return public URL
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.cfg.S3Bucket, s.cfg.AWSRegion, filename)
-	return url, nil
+// DeleteObject removes the object stored under key
+func (s *StorageService) DeleteObject(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+	return s.store.remove(ctx, key)
 }
 
-func (s *StorageService) uploadToMinIO(file io.Reader, filename, contentType string) (string, error) {
-	// Upload to MinIO
-	_, err := s.minioClient.PutObject(
-		s.cfg.S3Bucket,
-		filename,
-		file,
-		-1,
-		minio.PutObjectOptions{
-			ContentType: contentType,
-		},
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload to MinIO: %w", err)
-	}
+// ListObjects calls fn with every object in the bucket until fn returns an
+// error. Listing isn't bounded by STORAGE_TIMEOUT, since a large bucket
+// takes many requests.
+func (s *StorageService) ListObjects(ctx context.Context, fn func(StoredObject) error) error {
+	return s.store.list(ctx, fn)
+}
 
-	// Return public URL
-	protocol := "http"
-	if s.cfg.MinIOUseSSL {
-		protocol = "https"
-	}
-	url := fmt.Sprintf("%s://%s/%s/%s", protocol, s.cfg.MinIOEndpoint, s.cfg.S3Bucket, filename)
-	return url, nil
+// ObjectKey returns the key of the object a stored file's URL points at, or
+// "" when the URL isn't in this bucket
+func (s *StorageService) ObjectKey(url string) string {
+	return s.extractKeyFromURL(url)
 }
 
-func (s *StorageService) deleteFromS3(key string) error {
-	_, err := s.s3Client.DeleteObject(&s3.DeleteObjectInput{
-		Bucket: aws.String(s.cfg.S3Bucket),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to delete from S3: %w", err)
-	}
-	return nil
+func (s *StorageService) GeneratePresignedURL(ctx context.Context, filename string, expiration time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+	return s.store.presign(ctx, filename, expiration)
 }
 
-func (s *StorageService) deleteFromMinIO(key string) error {
-	err := s.minioClient.RemoveObject(s.cfg.S3Bucket, key, minio.RemoveObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to delete from MinIO: %w", err)
-	}
-	return nil
+func (s *StorageService) CreateBucket(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.StorageTimeout)
+	defer cancel()
+	return s.store.createBucket(ctx)
 }
 
 func (s *StorageService) extractKeyFromURL(url string) string {
@@ -154,82 +199,191 @@ func (s *StorageService) extractKeyFromURL(url string) string {
 		}
 	}
 
-	// Extract key from MinIO URL
-	if strings.Contains(url, s.cfg.MinIOEndpoint) {
+	// Extract key from MinIO URL; the first path segment is the bucket
+	if s.cfg.MinIOEndpoint != "" && strings.Contains(url, s.cfg.MinIOEndpoint) {
 		parts := strings.Split(url, "/")
-		if len(parts) > 3 {
-			return strings.Join(parts[3:], "/")
+		if len(parts) > 4 {
+			return strings.Join(parts[4:], "/")
 		}
 	}
 
 	return ""
 }
 
-func (s *StorageService) GeneratePresignedURL(filename string, expiration time.Duration) (string, error) {
-	if s.useMinIO {
-		return s.generateMinIOPresignedURL(filename, expiration)
+type s3Store struct {
+	cfg       *config.Config
+	client    *s3.Client
+	uploader  *manager.Uploader
+	presigner *s3.PresignClient
+}
+
+func (s *s3Store) upload(ctx context.Context, file io.Reader, key, contentType string, public bool) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.cfg.S3Bucket),
+		Key:         aws.String(key),
+		Body:        file,
+		ContentType: aws.String(contentType),
+	}
+	if public {
+		input.ACL = s3types.ObjectCannedACLPublicRead
+	}
+	if _, err := s.uploader.Upload(ctx, input); err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", err)
 	}
-	return s.generateS3PresignedURL(filename, expiration)
+	return nil
 }
 
-func (s *StorageService) generateS3PresignedURL(filename string, expiration time.Duration) (string, error) {
-	req, _ := s.s3Client.GetObjectRequest(&s3.GetObjectInput{
+func (s *s3Store) download(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.cfg.S3Bucket),
-		Key:    aws.String(filename),
+		Key:    aws.String(key),
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+	return out.Body, nil
+}
 
-	url, err := req.Presign(expiration)
+func (s *s3Store) remove(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+		return fmt.Errorf("failed to delete from S3: %w", err)
 	}
+	return nil
+}
 
-	return url, nil
+func (s *s3Store) list(ctx context.Context, fn func(StoredObject) error) error {
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{Bucket: aws.String(s.cfg.S3Bucket)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			stored := StoredObject{Key: aws.ToString(object.Key), Size: aws.ToInt64(object.Size)}
+			if object.LastModified != nil {
+				stored.LastModified = *object.LastModified
+			}
+			if err := fn(stored); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (s *StorageService) generateMinIOPresignedURL(filename string, expiration time.Duration) (string, error) {
-	url, err := s.minioClient.PresignedGetObject(s.cfg.S3Bucket, filename, expiration, nil)
+func (s *s3Store) presign(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	req, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.cfg.S3Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiration))
 	if err != nil {
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
-
-	return url.String(), nil
+	return req.URL, nil
 }
 
-func (s *StorageService) CreateBucket() error {
-	if s.useMinIO {
-		return s.createMinIOBucket()
+func (s *s3Store) createBucket(ctx context.Context) error {
+	input := &s3.CreateBucketInput{Bucket: aws.String(s.cfg.S3Bucket)}
+	// us-east-1 is the default location and must not be named explicitly
+	if s.cfg.AWSRegion != "us-east-1" {
+		input.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(s.cfg.AWSRegion),
+		}
+	}
+
+	_, err := s.client.CreateBucket(ctx, input)
+	var owned *s3types.BucketAlreadyOwnedByYou
+	if err != nil && !errors.As(err, &owned) {
+		return fmt.Errorf("failed to create S3 bucket: %w", err)
 	}
-	return s.createS3Bucket()
+	return nil
 }
 
-func (s *StorageService) createS3Bucket() error {
-	_, err := s.s3Client.CreateBucket(&s3.CreateBucketInput{
-		Bucket: aws.String(s.cfg.S3Bucket),
+func (s *s3Store) publicURL(key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.cfg.S3Bucket, s.cfg.AWSRegion, key)
+}
+
+type minioStore struct {
+	cfg    *config.Config
+	client *minio.Client
+}
+
+// MinIO has no per-object ACLs; what is public is decided by the bucket policy
+func (m *minioStore) upload(ctx context.Context, file io.Reader, key, contentType string, public bool) error {
+	// A size of -1 makes the client stream the body as a multipart upload
+	_, err := m.client.PutObject(ctx, m.cfg.S3Bucket, key, file, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+		PartSize:    uint64(m.cfg.S3UploadPartSize),
 	})
 	if err != nil {
-		// Check if bucket already exists
-		if !strings.Contains(err.Error(), "BucketAlreadyOwnedByYou") {
-			return fmt.Errorf("failed to create S3 bucket: %w", err)
+		return fmt.Errorf("failed to upload to MinIO: %w", err)
+	}
+	return nil
+}
+
+func (m *minioStore) download(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := m.client.GetObject(ctx, m.cfg.S3Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from MinIO: %w", err)
+	}
+	return object, nil
+}
+
+func (m *minioStore) remove(ctx context.Context, key string) error {
+	if err := m.client.RemoveObject(ctx, m.cfg.S3Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete from MinIO: %w", err)
+	}
+	return nil
+}
+
+func (m *minioStore) list(ctx context.Context, fn func(StoredObject) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops the listing when fn fails
+	for object := range m.client.ListObjects(ctx, m.cfg.S3Bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("failed to list MinIO objects: %w", object.Err)
+		}
+		if err := fn(StoredObject{Key: object.Key, Size: object.Size, LastModified: object.LastModified}); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (s *StorageService) createMinIOBucket() error {
-	exists, err := s.minioClient.BucketExists(s.cfg.S3Bucket)
+func (m *minioStore) presign(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	url, err := m.client.PresignedGetObject(ctx, m.cfg.S3Bucket, key, expiration, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+	return url.String(), nil
+}
+
+func (m *minioStore) createBucket(ctx context.Context) error {
+	exists, err := m.client.BucketExists(ctx, m.cfg.S3Bucket)
 	if err != nil {
 		return fmt.Errorf("failed to check bucket existence: %w", err)
 	}
 
 	if !exists {
-		err = s.minioClient.MakeBucket(s.cfg.S3Bucket, "")
-		if err != nil {
+		if err := m.client.MakeBucket(ctx, m.cfg.S3Bucket, minio.MakeBucketOptions{}); err != nil {
 			return fmt.Errorf("failed to create MinIO bucket: %w", err)
 		}
 	}
 	return nil
 }
 
+func (m *minioStore) publicURL(key string) string {
+	protocol := "http"
+	if m.cfg.MinIOUseSSL {
+		protocol = "https"
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", protocol, m.cfg.MinIOEndpoint, m.cfg.S3Bucket, key)
+}
+
 // Helper function to generate unique filename
 func GenerateUniqueFilename(originalName string) string {
 	ext := filepath.Ext(originalName)
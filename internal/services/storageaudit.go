@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// storageReportSample is how many missing, unresolvable and orphaned files a
+// saved report lists
+const storageReportSample = 200
+
+// storageUntrackedPrefixes hold objects the database doesn't point at by
+// design: QR codes are cached in Redis and drawn again when missing
+var storageUntrackedPrefixes = []string{"qr/"}
+
+// storagePlaceholderHost is where rows saved before uploads reached the
+// bucket point. Their files never existed, so they are counted apart from
+// URLs that should resolve and don't.
+const storagePlaceholderHost = "://storage.example.com/"
+
+// StorageAudit is a consistency check. Orphaned lists every orphaned object,
+// not just the report's sample, so they can be cleaned up.
+type StorageAudit struct {
+	Report   models.StorageReport
+	Orphaned []StoredObject
+}
+
+type storedReference struct {
+	models.StorageReference
+	key string
+}
+
+// AuditStorage compares the files the database points at with the objects
+// in the bucket. Objects younger than grace aren't counted as orphaned, so
+// uploads whose rows aren't saved yet are left alone.
+func AuditStorage(ctx context.Context, db *gorm.DB, storage *StorageService, grace time.Duration) (StorageAudit, error) {
+	started := time.Now()
+	var audit StorageAudit
+	report := &audit.Report
+
+	references, err := storageReferences(ctx, db, storage)
+	if err != nil {
+		return audit, err
+	}
+
+	objects := make(map[string]StoredObject)
+	if err := storage.ListObjects(ctx, func(object StoredObject) error {
+		objects[object.Key] = object
+		report.ObjectCount++
+		report.ObjectBytes += object.Size
+		return nil
+	}); err != nil {
+		return audit, err
+	}
+
+	referenced := make(map[string]bool, len(references))
+	for _, ref := range references {
+		report.ReferenceCount++
+		if ref.key == "" && strings.Contains(ref.URL, storagePlaceholderHost) {
+			report.PlaceholderCount++
+			continue
+		}
+		if ref.key == "" {
+			report.UnresolvableCount++
+			report.Unresolvable = appendSample(report.Unresolvable, ref.StorageReference)
+			continue
+		}
+		referenced[ref.key] = true
+		if _, stored := objects[ref.key]; !stored {
+			report.MissingCount++
+			report.Missing = appendSample(report.Missing, ref.StorageReference)
+		}
+	}
+
+	cutoff := started.Add(-grace)
+	for key, object := range objects {
+		if referenced[key] || untracked(key) || object.LastModified.After(cutoff) {
+			continue
+		}
+		audit.Orphaned = append(audit.Orphaned, object)
+		report.OrphanedCount++
+		report.OrphanedBytes += object.Size
+	}
+	sort.Slice(audit.Orphaned, func(i, j int) bool { return audit.Orphaned[i].Key < audit.Orphaned[j].Key })
+	for _, object := range audit.Orphaned {
+		if len(report.Orphaned) == storageReportSample {
+			break
+		}
+		report.Orphaned = append(report.Orphaned, models.StorageOrphan{Key: object.Key, Size: object.Size, LastModified: object.LastModified})
+	}
+
+	report.DurationMs = time.Since(started).Milliseconds()
+	return audit, nil
+}
+
+// storageReferences returns every stored file a row points at: profile
+// photos, identity documents, support ticket screenshots and message
+// archives
+func storageReferences(ctx context.Context, db *gorm.DB, storage *StorageService) ([]storedReference, error) {
+	db = db.WithContext(ctx)
+	var references []storedReference
+	add := func(table string, rowID uint, url, key string) {
+		references = append(references, storedReference{
+			StorageReference: models.StorageReference{Table: table, RowID: rowID, URL: url},
+			key:              key,
+		})
+	}
+
+	var photos []models.ProfilePhoto
+	if err := db.Select("id", "url").Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	for _, photo := range photos {
+		add("profile_photos", photo.ID, photo.URL, storage.ObjectKey(photo.URL))
+	}
+
+	// Documents stored without public access keep their key rather than a URL
+	var documents []models.IdentityVerification
+	if err := db.Select("id", "document_url", "document_key").
+		Where("(document_url IS NOT NULL AND document_url != '') OR document_key IS NOT NULL").Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	for _, document := range documents {
+		if document.DocumentKey != nil {
+			add("identity_verifications", document.ID, *document.DocumentKey, *document.DocumentKey)
+			continue
+		}
+		add("identity_verifications", document.ID, *document.DocumentURL, storage.ObjectKey(*document.DocumentURL))
+	}
+
+	var tickets []models.SupportTicket
+	if err := db.Select("id", "attachments").Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	for _, ticket := range tickets {
+		// Screenshots are stored by key; early tickets kept a URL
+		for _, attachment := range ticket.Attachments {
+			key := storage.ObjectKey(attachment)
+			if key == "" && !strings.Contains(attachment, "://") {
+				key = attachment
+			}
+			add("support_tickets", ticket.ID, attachment, key)
+		}
+	}
+
+	// Archives store their key rather than a URL
+	var archives []models.ConversationArchive
+	if err := db.Select("id", "object_key").Find(&archives).Error; err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		add("conversation_archives", archive.ID, archive.ObjectKey, archive.ObjectKey)
+	}
+
+	return references, nil
+}
+
+func appendSample(sample []models.StorageReference, ref models.StorageReference) []models.StorageReference {
+	if len(sample) == storageReportSample {
+		return sample
+	}
+	return append(sample, ref)
+}
+
+func untracked(key string) bool {
+	for _, prefix := range storageUntrackedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
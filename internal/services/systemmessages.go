@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strings"
+
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of system message the server posts into conversations
+const (
+	SystemMatchCreated    = "match_created"
+	SystemStartExpiring   = "start_expiring"
+	SystemSafetyTip       = "safety_tip"
+	SystemDisappearingOn  = "disappearing_on"
+	SystemDisappearingOff = "disappearing_off"
+	SystemEncryptionOn    = "encryption_on"
+)
+
+// DefaultSystemMessages is the built-in text of each kind, used until an
+// admin overrides it. {hours} and {time_left} are filled in where they apply.
+var DefaultSystemMessages = map[string]string{
+	SystemMatchCreated:    "You matched! Say hello.",
+	SystemStartExpiring:   "{time_left} left to send the first message. After that, either of you can start the chat.",
+	SystemSafetyTip:       "Safety tip: take your time before sharing your phone number, and meet somewhere public the first time.",
+	SystemDisappearingOn:  "Disappearing messages turned on: messages are deleted {hours} hours after being read",
+	SystemDisappearingOff: "Disappearing messages turned off",
+	SystemEncryptionOn:    "Messages in this chat are now end-to-end encrypted",
+}
+
+// SystemMessageText returns the text for a kind with its placeholders
+// filled in, and false when an admin turned the kind off
+func SystemMessageText(db *gorm.DB, kind string, vars map[string]string) (string, bool) {
+	text := DefaultSystemMessages[kind]
+
+	// Without a readable override the built-in text is used
+	var template models.SystemMessageTemplate
+	if err := db.Where("kind = ?", kind).First(&template).Error; err == nil {
+		if !template.IsEnabled {
+			return "", false
+		}
+		text = template.Content
+	}
+	if text == "" {
+		return "", false
+	}
+
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text), true
+}
+
+// CreateSystemMessage records a system message of the given kind in the
+// conversation. actorID is the user whose action caused it; clients show
+// system messages apart from the conversation and they never count as
+// unread. It returns nil when the kind is turned off.
+func CreateSystemMessage(db *gorm.DB, conversationID, actorID uint, kind string, vars map[string]string) (*models.Message, error) {
+	content, ok := SystemMessageText(db, kind, vars)
+	if !ok {
+		return nil, nil
+	}
+
+	message := models.Message{
+		ConversationID: conversationID,
+		SenderID:       actorID,
+		Content:        content,
+		MessageType:    "system",
+		SystemKind:     kind,
+	}
+	if err := db.Create(&message).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
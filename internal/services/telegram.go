@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+)
+
+const telegramAPIURL = "https://api.telegram.org"
+
+// ErrTelegramDisabled is returned when no Telegram bot is configured
+var ErrTelegramDisabled = fmt.Errorf("Telegram bot not configured: %w", ErrChannelDisabled)
+
+// TelegramBot sends messages to chats with the app's Telegram bot
+type TelegramBot interface {
+	SendMessage(ctx context.Context, chatID int64, text string) error
+	// Username is the bot's handle, used to build t.me links. Empty when the
+	// bot is not configured.
+	Username() string
+}
+
+// TelegramUpdate is the part of a Bot API update the webhook reads
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message"`
+}
+
+type TelegramMessage struct {
+	MessageID int64 `json:"message_id"`
+	From      *struct {
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"from"`
+	Chat struct {
+		ID   int64  `json:"id"`
+		Type string `json:"type"` // private, group, supergroup, channel
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+// NewTelegramBot returns a Bot API client when TELEGRAM_BOT_TOKEN is set, or
+// a bot that always fails with ErrTelegramDisabled when it is unset
+func NewTelegramBot(cfg *config.Config) TelegramBot {
+	if cfg.TelegramBotToken == "" {
+		return &disabledTelegramBot{username: cfg.TelegramBotUsername}
+	}
+	return &httpTelegramBot{
+		token:    cfg.TelegramBotToken,
+		username: cfg.TelegramBotUsername,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type httpTelegramBot struct {
+	token    string
+	username string
+	client   *http.Client
+}
+
+func (b *httpTelegramBot) SendMessage(ctx context.Context, chatID int64, text string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Telegram message: %w", err)
+	}
+
+	endpoint := telegramAPIURL + "/bot" + b.token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build Telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// The URL holds the token, so keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send Telegram message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || !result.OK {
+		return fmt.Errorf("Telegram returned status %d: %s", resp.StatusCode, result.Description)
+	}
+	return nil
+}
+
+func (b *httpTelegramBot) Username() string {
+	return b.username
+}
+
+type disabledTelegramBot struct {
+	username string
+}
+
+func (b *disabledTelegramBot) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return ErrTelegramDisabled
+}
+
+func (b *disabledTelegramBot) Username() string {
+	return b.username
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/redis"
+)
+
+const translatorTimeout = 10 * time.Second
+
+// ErrTranslatorDisabled is returned when no translation service is
+// configured
+var ErrTranslatorDisabled = errors.New("translator not configured")
+
+// Translator translates text into another language. from is the language
+// the text is in, or empty for the service to detect it.
+type Translator interface {
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// NewTranslator returns a client for the translation service at
+// TRANSLATION_API_URL, or a translator that always fails with
+// ErrTranslatorDisabled when it is unset
+func NewTranslator(cfg *config.Config) Translator {
+	if cfg.TranslationAPIURL == "" {
+		return disabledTranslator{}
+	}
+	return &httpTranslator{
+		url:    cfg.TranslationAPIURL,
+		apiKey: cfg.TranslationAPIKey,
+		client: &http.Client{Timeout: translatorTimeout},
+	}
+}
+
+type httpTranslator struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func (t *httpTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"text": text, "source": from, "target": to})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode translation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to build translation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach translation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("translation service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		TranslatedText string `json:"translated_text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode translation response: %w", err)
+	}
+	translated := strings.TrimSpace(result.TranslatedText)
+	if translated == "" {
+		return "", errors.New("translation service returned no text")
+	}
+	return translated, nil
+}
+
+type disabledTranslator struct{}
+
+func (disabledTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	return "", ErrTranslatorDisabled
+}
+
+// CacheTranslations keeps what translator returns in Redis for ttl, so each
+// text is only sent once per target language however many people read it.
+// Entries are keyed by the text itself, so an edited bio is translated
+// afresh.
+func CacheTranslations(rc *redis.Client, translator Translator, ttl time.Duration) Translator {
+	return &cachedTranslator{rc: rc, translator: translator, ttl: ttl}
+}
+
+type cachedTranslator struct {
+	rc         *redis.Client
+	translator Translator
+	ttl        time.Duration
+}
+
+func (t *cachedTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	sum := sha256.Sum256([]byte(text))
+	key := fmt.Sprintf("translation:%s:%s", to, hex.EncodeToString(sum[:]))
+	if cached, err := t.rc.Get(ctx, key); err == nil {
+		return cached, nil
+	}
+
+	translated, err := t.translator.Translate(ctx, text, from, to)
+	if err != nil {
+		return "", err
+	}
+	if err := t.rc.Set(ctx, key, translated, t.ttl); err != nil {
+		log.Printf("Failed to cache translation: %v", err)
+	}
+	return translated, nil
+}
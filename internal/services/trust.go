@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"ethiopia-dating-app/internal/config"
+	"ethiopia-dating-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Trust levels
+const (
+	TrustLow    = "low"
+	TrustNormal = "normal"
+	TrustHigh   = "high"
+)
+
+const (
+	// trustAccountMonths caps the months of account age that add trust
+	trustAccountMonths = 12
+	// trustReplyMinimum is how many conversations others must have opened
+	// with a user before their reply rate counts
+	trustReplyMinimum = 3
+)
+
+// trustSignals are the signals a trust score is made of, in the order they
+// are explained. Each is worth its TRUST_WEIGHTS entry for every unit of it
+// the user has.
+var trustSignals = []string{
+	"base", "phone_verified", "email_verified", "photo_verified", "id_verified", "badge",
+	"account_month", "reply_rate", "report_pending", "report_upheld", "strike",
+}
+
+// TrustFactor is one signal's part in a trust score
+type TrustFactor struct {
+	Signal string  `json:"signal"`
+	Value  float64 `json:"value"` // how much of the signal the user has, e.g. 2 upheld reports
+	Weight int     `json:"weight"`
+	Points int     `json:"points"`
+}
+
+// Trust is a trust score with how it was reached
+type Trust struct {
+	Score   int           `json:"score"`
+	Level   string        `json:"level"`
+	Factors []TrustFactor `json:"factors"`
+}
+
+// ComputeTrust scores how far a user can be trusted, from 0 to 100: each
+// verification and verified badge, month of account age (up to a year) and
+// their reply rate add to it, and reports against them and strike weight
+// take from it. Upheld reports are those resolved by a moderator.
+func ComputeTrust(ctx context.Context, db *gorm.DB, cfg *config.Config, user models.User, now time.Time) (Trust, error) {
+	db = db.WithContext(ctx)
+
+	var badges, pendingReports, upheldReports int64
+	if err := db.Model(&models.Badge{}).Where("user_id = ? AND status = ?", user.ID, "verified").Count(&badges).Error; err != nil {
+		return Trust{}, err
+	}
+	if err := db.Model(&models.Report{}).Where("reported_id = ? AND status IN ?", user.ID, []string{"pending", "reviewed"}).Count(&pendingReports).Error; err != nil {
+		return Trust{}, err
+	}
+	if err := db.Model(&models.Report{}).Where("reported_id = ? AND status = ?", user.ID, "resolved").Count(&upheldReports).Error; err != nil {
+		return Trust{}, err
+	}
+	var strikeWeight int64
+	if err := db.Model(&models.Strike{}).Where("user_id = ?", user.ID).Select("COALESCE(SUM(weight), 0)").Scan(&strikeWeight).Error; err != nil {
+		return Trust{}, err
+	}
+	var replyRate float64
+	var stats models.MessageStats
+	if err := db.Where("user_id = ? AND conversations_opened >= ?", user.ID, trustReplyMinimum).Limit(1).Find(&stats).Error; err != nil {
+		return Trust{}, err
+	}
+	if stats.UserID != 0 {
+		replyRate = stats.ReplyRate
+	}
+
+	months := math.Min(math.Floor(now.Sub(user.CreatedAt).Hours()/(24*30)), trustAccountMonths)
+	values := map[string]float64{
+		"base":           1,
+		"phone_verified": flag(user.PhoneVerifiedAt != nil),
+		"email_verified": flag(user.EmailVerifiedAt != nil),
+		"photo_verified": flag(user.PhotoVerifiedAt != nil),
+		"id_verified":    flag(user.IDVerified),
+		"badge":          float64(badges),
+		"account_month":  math.Max(months, 0),
+		"reply_rate":     replyRate,
+		"report_pending": float64(pendingReports),
+		"report_upheld":  float64(upheldReports),
+		"strike":         float64(strikeWeight),
+	}
+
+	trust := Trust{Factors: make([]TrustFactor, 0, len(trustSignals))}
+	for _, signal := range trustSignals {
+		weight := cfg.TrustWeights[signal]
+		points := int(math.Round(values[signal] * float64(weight)))
+		trust.Factors = append(trust.Factors, TrustFactor{Signal: signal, Value: values[signal], Weight: weight, Points: points})
+		trust.Score += points
+	}
+	trust.Score = max(0, min(trust.Score, 100))
+	trust.Level = cfg.TrustLevel(trust.Score)
+	return trust, nil
+}
+
+// SaveTrust stores a user's trust score and level
+func SaveTrust(ctx context.Context, db *gorm.DB, userID uint, trust Trust, now time.Time) error {
+	return db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"trust_score":       trust.Score,
+		"trust_level":       trust.Level,
+		"trust_computed_at": now,
+	}).Error
+}
+
+func flag(set bool) float64 {
+	if set {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,13 @@
+package utils
+
+import "time"
+
+// CalculateAge returns the age in whole years at the given time
+func CalculateAge(dateOfBirth, now time.Time) int {
+	age := now.Year() - dateOfBirth.Year()
+	if now.Month() < dateOfBirth.Month() ||
+		(now.Month() == dateOfBirth.Month() && now.Day() < dateOfBirth.Day()) {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,32 @@
+package utils
+
+import "regexp"
+
+var contactPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"link", regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|et|io|me|app)(?:/\S*)?\b`)},
+	{"telegram", regexp.MustCompile(`(?i)\b(?:t|telegram)\.me/\w+|@[a-z]\w{4,31}\b`)},
+	{"phone", regexp.MustCompile(`\+?\d(?:[\s.-]?\d){8,13}`)},
+}
+
+// DetectContactInfo returns the kinds of off-platform contact details found in
+// content: phone, telegram, link
+func DetectContactInfo(content string) []string {
+	var kinds []string
+	for _, p := range contactPatterns {
+		if p.pattern.MatchString(content) {
+			kinds = append(kinds, p.kind)
+		}
+	}
+	return kinds
+}
+
+// RedactContactInfo masks any detected contact details in content
+func RedactContactInfo(content string) string {
+	for _, p := range contactPatterns {
+		content = p.pattern.ReplaceAllString(content, "•••")
+	}
+	return content
+}
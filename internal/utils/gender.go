@@ -0,0 +1,74 @@
+package utils
+
+import "strings"
+
+// Genders lists the accepted gender values. Binary genders keep their
+// original names so older clients keep working.
+var Genders = []string{"male", "female", "non_binary", "other"}
+
+// legacyGenders maps spellings stored before the gender list was fixed
+var legacyGenders = map[string]string{
+	"m":          "male",
+	"man":        "male",
+	"f":          "female",
+	"woman":      "female",
+	"nonbinary":  "non_binary",
+	"non-binary": "non_binary",
+	"enby":       "non_binary",
+}
+
+// NormalizeGender maps a stored or submitted gender onto Genders. Unknown
+// values become "other".
+func NormalizeGender(gender string) string {
+	gender = strings.ToLower(strings.TrimSpace(gender))
+	if mapped, ok := legacyGenders[gender]; ok {
+		return mapped
+	}
+	for _, g := range Genders {
+		if g == gender {
+			return g
+		}
+	}
+	return "other"
+}
+
+// SeekingGenders returns the genders a user with the given preference wants
+// to see, or nil when they are open to everyone
+func SeekingGenders(seeking string) []string {
+	switch seeking {
+	case "men":
+		return []string{"male"}
+	case "women":
+		return []string{"female"}
+	default:
+		return nil
+	}
+}
+
+// SeekersOf returns the preferences that include the given gender. Non-binary
+// and other users are only shown to people seeking everyone.
+func SeekersOf(gender string) []string {
+	switch gender {
+	case "male":
+		return []string{"men", "everyone"}
+	case "female":
+		return []string{"women", "everyone"}
+	default:
+		return []string{"everyone"}
+	}
+}
+
+// GendersCompatible reports whether two users fall within each other's
+// preferences
+func GendersCompatible(gender, seeking, otherGender, otherSeeking string) bool {
+	return seeks(seeking, otherGender) && seeks(otherSeeking, gender)
+}
+
+func seeks(seeking, gender string) bool {
+	for _, s := range SeekersOf(gender) {
+		if s == seeking {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	earthRadiusKm = 6371.0
+	kmPerMile     = 1.609344
+)
+
+// HaversineKm returns the great-circle distance between two coordinates in kilometers
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// RoundDistance coarsens a distance so exact locations can't be triangulated:
+// nearest unit under 10, nearest 5 under 50, nearest 10 beyond. Never below 1.
+func RoundDistance(distance float64) float64 {
+	var rounded float64
+	switch {
+	case distance < 10:
+		rounded = math.Round(distance)
+	case distance < 50:
+		rounded = math.Round(distance/5) * 5
+	default:
+		rounded = math.Round(distance/10) * 10
+	}
+
+	if rounded < 1 {
+		return 1
+	}
+	return rounded
+}
+
+// FormatDistance renders a distance in kilometers as a fuzzy label in the given unit (km or mi)
+func FormatDistance(distanceKm float64, unit string) string {
+	if unit == "mi" {
+		return fmt.Sprintf("≈%.0f mi away", RoundDistance(distanceKm/kmPerMile))
+	}
+	return fmt.Sprintf("≈%.0f km away", RoundDistance(distanceKm))
+}
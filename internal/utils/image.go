@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register decoders for image.DecodeConfig
+	_ "image/png"
+	"io"
+	"net/http"
+)
+
+// ImageInfo describes an image as read from its bytes rather than from what
+// the client claimed
+type ImageInfo struct {
+	ContentType string
+	Width       int
+	Height      int
+}
+
+var errNotAnImage = errors.New("file is not a supported image")
+
+// InspectImage sniffs the real content type from r's magic bytes and reads the
+// image dimensions from its header without decoding the pixels. r is left
+// positioned at the start.
+func InspectImage(r io.ReadSeeker) (ImageInfo, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return ImageInfo{}, errNotAnImage
+	}
+	head = head[:n]
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return ImageInfo{}, err
+	}
+
+	info := ImageInfo{ContentType: http.DetectContentType(head)}
+	switch info.ContentType {
+	case "image/jpeg", "image/png":
+		config, _, err := image.DecodeConfig(r)
+		if _, seekErr := r.Seek(0, io.SeekStart); seekErr != nil {
+			return ImageInfo{}, seekErr
+		}
+		if err != nil {
+			return ImageInfo{}, fmt.Errorf("corrupt image header: %w", err)
+		}
+		info.Width, info.Height = config.Width, config.Height
+	case "image/webp":
+		info.Width, info.Height, err = webpSize(head)
+		if err != nil {
+			return ImageInfo{}, err
+		}
+	default:
+		return ImageInfo{}, errNotAnImage
+	}
+
+	return info, nil
+}
+
+// webpSize reads the canvas size from a WebP header. The standard library has
+// no WebP decoder, so the three chunk layouts are parsed by hand.
+func webpSize(head []byte) (int, int, error) {
+	if len(head) < 30 {
+		return 0, 0, errors.New("corrupt image header")
+	}
+
+	switch string(head[12:16]) {
+	case "VP8 ": // lossy: start code then 14-bit dimensions
+		if head[23] != 0x9d || head[24] != 0x01 || head[25] != 0x2a {
+			return 0, 0, errors.New("corrupt image header")
+		}
+		return int(binary.LittleEndian.Uint16(head[26:28]) & 0x3fff), int(binary.LittleEndian.Uint16(head[28:30]) & 0x3fff), nil
+	case "VP8L": // lossless: signature byte then two packed 14-bit values
+		if head[20] != 0x2f {
+			return 0, 0, errors.New("corrupt image header")
+		}
+		bits := binary.LittleEndian.Uint32(head[21:25])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8X": // extended: 24-bit canvas size minus one
+		width := int(head[24]) | int(head[25])<<8 | int(head[26])<<16
+		height := int(head[27]) | int(head[28])<<8 | int(head[29])<<16
+		return width + 1, height + 1, nil
+	default:
+		return 0, 0, errors.New("corrupt image header")
+	}
+}
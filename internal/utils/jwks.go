@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is a public key in JSON Web Key form
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// PublicJWKS lists the public keys that verify tokens, for internal services
+// that check tokens themselves. HMAC secrets are never included.
+func PublicJWKS() []JWK {
+	keys := currentJWTKeys()
+	jwks := make([]JWK, 0, len(keys.byID))
+	for _, key := range keys.byID {
+		switch public := key.verify.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, JWK{
+				Kty: "RSA",
+				Kid: key.id,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(bigEndian(public.E)),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, JWK{
+				Kty: "OKP",
+				Kid: key.id,
+				Use: "sig",
+				Alg: "EdDSA",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return jwks
+}
+
+func bigEndian(n int) []byte {
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return b
+}
+
+// parseJWKS reads verify-only keys from a JWKS document, as served by
+// PublicJWKS, so keys retired from signing keep validating their tokens
+func parseJWKS(data []byte) ([]*jwtKey, error) {
+	var document struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid JWKS file: %w", err)
+	}
+
+	keys := make([]*jwtKey, 0, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.Kid == "" {
+			return nil, fmt.Errorf("JWKS key without kid")
+		}
+
+		switch jwk.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+			if err != nil {
+				return nil, fmt.Errorf("JWKS key %s: invalid modulus", jwk.Kid)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+			if err != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("JWKS key %s: invalid exponent", jwk.Kid)
+			}
+			keys = append(keys, &jwtKey{
+				id:     jwk.Kid,
+				method: jwt.SigningMethodRS256,
+				verify: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+			})
+		case "OKP":
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("JWKS key %s: invalid Ed25519 key", jwk.Kid)
+			}
+			keys = append(keys, &jwtKey{
+				id:     jwk.Kid,
+				method: jwt.SigningMethodEdDSA,
+				verify: ed25519.PublicKey(x),
+			})
+		default:
+			return nil, fmt.Errorf("JWKS key %s: unsupported key type %q", jwk.Kid, jwk.Kty)
+		}
+	}
+	return keys, nil
+}
@@ -1,24 +1,193 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// DefaultJWTSecret is the placeholder secret used when none is configured. It
+// is public, so release builds refuse to sign with it.
+const DefaultJWTSecret = "your-super-secret-jwt-key-here"
+
+var errInvalidToken = errors.New("invalid token")
+
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	Admin  bool   `json:"admin,omitempty"` // UserID is an admin's ID, not a user's
+	Scope  string `json:"scope,omitempty"` // set on restricted tokens, which only open the routes for their scope
 	jwt.RegisteredClaims
 }
 
-func GetJWTSecret() string {
+// JWTSettings selects how tokens are signed. Keys are rotated by moving the
+// old key into the verify-only set and configuring a new signing key; tokens
+// carry a kid header naming the key that signed them.
+type JWTSettings struct {
+	Algorithm       string   // HS256, RS256, or EdDSA
+	Secret          string   // HMAC secret: signs with HS256, otherwise verifies tokens issued before the switch
+	PreviousSecrets []string // retired HMAC secrets still accepted
+	PrivateKeyFile  string   // PEM private key for RS256 or EdDSA
+	KeyID           string   // kid of the private key; derived from the public key when empty
+	JWKSFile        string   // public keys of retired RS256/EdDSA keys still accepted
+}
+
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{} // nil for keys that only verify
+	verify interface{}
+}
+
+type jwtKeySet struct {
+	current *jwtKey
+	byID    map[string]*jwtKey
+	legacy  []jwt.VerificationKey // HMAC secrets for tokens issued before kid headers existed
+}
+
+var (
+	jwtKeysMu sync.RWMutex
+	jwtKeys   *jwtKeySet
+)
+
+// ConfigureJWT loads the signing key and the keys still accepted for
+// verification. Call it once at startup; until then tokens are signed with
+// HS256 and the JWT_SECRET environment variable.
+func ConfigureJWT(settings JWTSettings) error {
+	keys, err := loadJWTKeys(settings)
+	if err != nil {
+		return err
+	}
+
+	jwtKeysMu.Lock()
+	jwtKeys = keys
+	jwtKeysMu.Unlock()
+	return nil
+}
+
+func currentJWTKeys() *jwtKeySet {
+	jwtKeysMu.RLock()
+	keys := jwtKeys
+	jwtKeysMu.RUnlock()
+	if keys != nil {
+		return keys
+	}
+
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		return "your-super-secret-jwt-key-here"
+		secret = DefaultJWTSecret
+	}
+	keys, _ = loadJWTKeys(JWTSettings{Algorithm: "HS256", Secret: secret})
+	return keys
+}
+
+func loadJWTKeys(settings JWTSettings) (*jwtKeySet, error) {
+	keys := &jwtKeySet{byID: make(map[string]*jwtKey)}
+	add := func(key *jwtKey) {
+		if _, exists := keys.byID[key.id]; !exists {
+			keys.byID[key.id] = key
+		}
+	}
+
+	switch settings.Algorithm {
+	case "", "HS256":
+		if settings.Secret == "" {
+			return nil, errors.New("JWT_SECRET is required for HS256")
+		}
+		keys.current = hmacKey(settings.Secret)
+	case "RS256", "EdDSA":
+		key, err := loadPrivateKey(settings.Algorithm, settings.PrivateKeyFile, settings.KeyID)
+		if err != nil {
+			return nil, err
+		}
+		keys.current = key
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", settings.Algorithm)
+	}
+	add(keys.current)
+
+	// The HMAC secret keeps verifying after a switch to asymmetric signing,
+	// unless it is the public placeholder
+	if settings.Secret != "" && (keys.current.method == jwt.SigningMethodHS256 || settings.Secret != DefaultJWTSecret) {
+		add(hmacKey(settings.Secret))
+		keys.legacy = append(keys.legacy, []byte(settings.Secret))
+	}
+	for _, secret := range settings.PreviousSecrets {
+		add(hmacKey(secret))
+		keys.legacy = append(keys.legacy, []byte(secret))
+	}
+
+	if settings.JWKSFile != "" {
+		data, err := os.ReadFile(settings.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+		previous, err := parseJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range previous {
+			add(key)
+		}
+	}
+
+	return keys, nil
+}
+
+func hmacKey(secret string) *jwtKey {
+	sum := sha256.Sum256([]byte(secret))
+	return &jwtKey{
+		id:     "hs-" + hex.EncodeToString(sum[:6]),
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+func loadPrivateKey(algorithm, path, keyID string) (*jwtKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", algorithm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	key := &jwtKey{id: keyID}
+	switch algorithm {
+	case "RS256":
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RS256 private key: %w", err)
+		}
+		key.method, key.sign, key.verify = jwt.SigningMethodRS256, private, &private.PublicKey
+	case "EdDSA":
+		private, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EdDSA private key: %w", err)
+		}
+		signer := private.(crypto.Signer)
+		key.method, key.sign, key.verify = jwt.SigningMethodEdDSA, signer, signer.Public()
+	}
+
+	if key.id == "" {
+		der, err := x509.MarshalPKIXPublicKey(key.verify)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(der)
+		key.id = base64.RawURLEncoding.EncodeToString(sum[:12])
 	}
-	return secret
+	return key, nil
 }
 
 func GenerateToken(userID uint, email string) (string, error) {
@@ -32,8 +201,43 @@ func GenerateToken(userID uint, email string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(GetJWTSecret()))
+	return signToken(claims)
+}
+
+// GenerateAdminToken issues an access token for the admin API. Admins have
+// no refresh tokens and sign in again when it expires.
+func GenerateAdminToken(adminID uint, email string) (string, error) {
+	claims := &Claims{
+		UserID: adminID,
+		Email:  email,
+		Admin:  true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(12 * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// ScopeAppeal restricts a token to a suspended or banned user's appeal
+const ScopeAppeal = "appeal"
+
+// GenerateAppealToken issues a short-lived token with which a suspended or
+// banned user can see why and appeal, and nothing else
+func GenerateAppealToken(userID uint) (string, error) {
+	claims := &Claims{
+		UserID: userID,
+		Scope:  ScopeAppeal,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signToken(claims)
 }
 
 func GenerateRefreshToken(userID uint) (string, error) {
@@ -46,13 +250,36 @@ func GenerateRefreshToken(userID uint) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(GetJWTSecret()))
+	return signToken(claims)
+}
+
+func signToken(claims *Claims) (string, error) {
+	key := currentJWTKeys().current
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.sign)
 }
 
+// ValidateToken verifies a token against the key named by its kid header.
+// Tokens without one predate key rotation and are checked against the HMAC
+// secrets. The key's own algorithm is enforced, so a token can't pick a
+// weaker one.
 func ValidateToken(tokenString string) (*Claims, error) {
+	keys := currentJWTKeys()
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(GetJWTSecret()), nil
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			if token.Method.Alg() != jwt.SigningMethodHS256.Alg() || len(keys.legacy) == 0 {
+				return nil, errInvalidToken
+			}
+			return jwt.VerificationKeySet{Keys: keys.legacy}, nil
+		}
+
+		key := keys.byID[kid]
+		if key == nil || token.Method.Alg() != key.method.Alg() {
+			return nil, errInvalidToken
+		}
+		return key.verify, nil
 	})
 
 	if err != nil {
@@ -63,5 +290,5 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return claims, nil
 	}
 
-	return nil, jwt.ErrTokenInvalid
+	return nil, errInvalidToken
 }
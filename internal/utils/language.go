@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Detected content languages. Moderators are routed reports by these, and
+// each has its own word list.
+const (
+	LanguageAmharic = "am"
+	LanguageEnglish = "en"
+	LanguageOther   = "other"
+)
+
+// Languages lists the codes DetectLanguage returns
+var Languages = []string{LanguageAmharic, LanguageEnglish, LanguageOther}
+
+// minLanguageLetters is how much text it takes to call a language at all
+const minLanguageLetters = 3
+
+// englishStopwords are short words nearly every English sentence has
+var englishStopwords = map[string]bool{
+	"a": true, "about": true, "and": true, "are": true, "be": true, "but": true, "for": true,
+	"have": true, "i": true, "i'm": true, "im": true, "in": true, "is": true, "it": true,
+	"like": true, "love": true, "me": true, "my": true, "not": true, "of": true, "on": true,
+	"the": true, "this": true, "to": true, "want": true, "was": true, "what": true,
+	"with": true, "you": true, "your": true,
+}
+
+// romanizedAmharicWords are common Amharic words as people type them in
+// Latin letters when they have no Ethiopic keyboard
+var romanizedAmharicWords = map[string]bool{
+	"alehu": true, "algebagnm": true, "anchi": true, "ante": true,
+	"betam": true, "dehna": true, "endet": true, "eneye": true, "enem": true,
+	"ene": true, "gin": true, "hulu": true, "ishi": true, "lemin": true,
+	"min": true, "negn": true, "selam": true, "tenaystilign": true,
+	"wede": true, "yemiwod": true, "yelem": true, "yene": true, "yihe": true,
+	"yichalal": true, "yigermal": true,
+}
+
+// profanityByLanguage holds the words the content filter looks for. English
+// words are also checked in Amharic text, which often mixes the two.
+var profanityByLanguage = map[string][]string{
+	LanguageEnglish: profanityWords,
+	LanguageAmharic: {
+		"ሸርሙጣ", "ዲቃላ", "ቂጥ", "ብልት",
+		"sharmuta", "shermuta", "dikala", "diqala", "qit",
+	},
+}
+
+// suggestiveByLanguage holds words that aren't offensive but that users in
+// clean mode would rather not see, such as flirting about bodies or sex.
+// English words are also checked in Amharic text.
+var suggestiveByLanguage = map[string][]string{
+	LanguageEnglish: {
+		"sexy", "hookup", "hook up", "nsfw", "kinky", "naughty", "sensual", "lingerie",
+		"one night", "fwb", "netflix and chill", "body count", "nudes",
+	},
+	LanguageAmharic: {
+		"ወሲብ", "ሴክሲ", "ቆንጆ ሰውነት",
+		"wesib", "sexy", "konjo sewnet",
+	},
+}
+
+// DetectLanguage tells Amharic from English and other languages. Text in
+// Ethiopic script is Amharic; Latin text is judged by its common words, so
+// romanized Amharic counts too. It returns "" when there is too little text
+// to tell.
+func DetectLanguage(text string) string {
+	var ethiopic, letters int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Ethiopic, r) {
+			ethiopic++
+		}
+	}
+	if letters < minLanguageLetters {
+		return ""
+	}
+	if ethiopic*2 >= letters {
+		return LanguageAmharic
+	}
+
+	var english, amharic int
+	for _, word := range contentWords(text) {
+		if englishStopwords[word] {
+			english++
+		}
+		if romanizedAmharicWords[word] {
+			amharic++
+		}
+	}
+	switch {
+	case amharic > 0 && amharic >= english:
+		return LanguageAmharic
+	case english > 0:
+		return LanguageEnglish
+	}
+	return LanguageOther
+}
+
+// ContainsProfanity checks text against the word list for its language. A
+// word matches when it starts with a listed word, once digits are read as
+// the letters they imitate, so "fucking" and "sh1t" are both caught. Words
+// of three letters or fewer only match whole, so "qit" isn't "qitta".
+func ContainsProfanity(text, language string) bool {
+	return matchesWordLists(text, language, profanityByLanguage)
+}
+
+// IsSuggestive checks text against the suggestive word list for its
+// language, the same way ContainsProfanity does. Phrases match when their
+// words follow each other.
+func IsSuggestive(text, language string) bool {
+	return matchesWordLists(text, language, suggestiveByLanguage)
+}
+
+func matchesWordLists(text, language string, byLanguage map[string][]string) bool {
+	lists := [][]string{byLanguage[LanguageEnglish]}
+	if language == LanguageAmharic {
+		lists = append(lists, byLanguage[LanguageAmharic])
+	}
+
+	words := contentWords(lookalikeDigits.Replace(strings.ToLower(text)))
+	for i := range words {
+		for _, list := range lists {
+			for _, entry := range list {
+				phrase := strings.Fields(entry)
+				if i+len(phrase) > len(words) {
+					continue
+				}
+				if matchesPhrase(words[i:i+len(phrase)], phrase) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchesPhrase compares words with a listed phrase: every word but the last
+// must match whole, and the last may be the start of a longer word unless
+// it has three letters or fewer
+func matchesPhrase(words, phrase []string) bool {
+	last := len(phrase) - 1
+	for i := 0; i < last; i++ {
+		if words[i] != phrase[i] {
+			return false
+		}
+	}
+	bad := phrase[last]
+	return strings.HasPrefix(words[last], bad) && (len(words[last]) == len(bad) || utf8.RuneCountInString(bad) > 3)
+}
+
+// contentWords splits lowercased text into words, keeping apostrophes
+func contentWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r) && r != '\''
+	})
+}
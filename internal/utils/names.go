@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxDisplayNameLength = 50
+
+var ErrDisplayNameFormat = errors.New("names are 1-50 letters and may include spaces, hyphens, apostrophes or dots")
+
+// Name flags say why a display name needs a moderator's look
+const (
+	NameFlagProfanity     = "profanity"
+	NameFlagImpersonation = "impersonation"
+)
+
+// nameImpersonationWords suggest the account speaks for the app or its staff
+var nameImpersonationWords = []string{"admin", "moderator", "official", "support", "staff", "team"}
+
+// ValidateDisplayName checks a first or last name's shape: letters in any
+// script, with single spaces, hyphens, apostrophes or dots between them
+func ValidateDisplayName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxDisplayNameLength || name != strings.TrimSpace(name) {
+		return ErrDisplayNameFormat
+	}
+
+	previous := ' '
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.Is(unicode.Mn, r):
+		case r == ' ' || r == '-' || r == '\'' || r == '.':
+			if previous == ' ' || previous == '-' || previous == '\'' {
+				return ErrDisplayNameFormat
+			}
+		default:
+			return ErrDisplayNameFormat
+		}
+		previous = r
+	}
+	return nil
+}
+
+// DisplayNameFlag returns why a name should be reviewed before it is shown,
+// or "" when it can be used as is. Only whole words count, so a name such as
+// Stafford passes; the words run together are checked too, so spacing a
+// word out doesn't hide it.
+func DisplayNameFlag(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '\'' || r == '.'
+	})
+	for i := range words {
+		words[i] = lookalikeDigits.Replace(words[i])
+	}
+	joined := strings.Join(words, "")
+	switch {
+	case hasWord(words, profanityWords) || hasWord([]string{joined}, profanityWords):
+		return NameFlagProfanity
+	case hasWord(words, nameImpersonationWords):
+		return NameFlagImpersonation
+	}
+	return ""
+}
+
+// NameInitial is the initial a name is shown as while a flagged name waits
+// for review
+func NameInitial(name string) string {
+	for _, r := range name {
+		return string(unicode.ToUpper(r)) + "."
+	}
+	return ""
+}
+
+func hasWord(words, list []string) bool {
+	for _, word := range words {
+		for _, entry := range list {
+			if word == entry {
+				return true
+			}
+		}
+	}
+	return false
+}
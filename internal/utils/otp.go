@@ -2,9 +2,10 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math/big"
-	"time"
 )
 
 func GenerateOTP() (string, error) {
@@ -19,10 +20,6 @@ func GenerateOTP() (string, error) {
 	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
-func IsOTPExpired(createdAt time.Time, expiryDuration time.Duration) bool {
-	return time.Since(createdAt) > expiryDuration
-}
-
 func FormatPhoneNumber(phone string) string {
 	// Remove all non-digit characters
 	cleaned := ""
@@ -47,3 +44,16 @@ func FormatPhoneNumber(phone string) string {
 
 	return "+" + cleaned
 }
+
+// HashPhone returns the hex SHA-256 of the normalized phone number. Clients
+// hash address book entries the same way, so hashes can be matched without
+// either side revealing raw numbers.
+func HashPhone(phone string) string {
+	return HashString(FormatPhoneNumber(phone))
+}
+
+// HashString returns the hex SHA-256 of value
+func HashString(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
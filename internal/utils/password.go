@@ -8,8 +8,103 @@ import (
 	"strings"
 
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordScheme hashes passwords into a self-describing string that carries
+// the algorithm and its cost parameters, so hashes made under older settings
+// keep verifying after the settings change
+type PasswordScheme interface {
+	Name() string
+	Hash(password string) (string, error)
+	Verify(password, encodedHash string) (bool, error)
+	// Owns reports whether encodedHash was produced by this scheme
+	Owns(encodedHash string) bool
+	// Outdated reports whether encodedHash used weaker parameters than the
+	// scheme's current ones
+	Outdated(encodedHash string) bool
+}
+
+// PasswordPolicy selects the scheme new hashes use and the cost parameters
+type PasswordPolicy struct {
+	Scheme            string // argon2id or bcrypt
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+}
+
+var (
+	passwordSchemes = map[string]PasswordScheme{}
+	preferredScheme PasswordScheme
+)
+
+func init() {
+	ConfigurePasswords(PasswordPolicy{
+		Scheme:            "argon2id",
+		Argon2Memory:      64 * 1024,
+		Argon2Iterations:  3,
+		Argon2Parallelism: 2,
+		BcryptCost:        bcrypt.DefaultCost,
+	})
+}
+
+// RegisterPasswordScheme makes a scheme available for hashing and verifying,
+// replacing any registered under the same name
+func RegisterPasswordScheme(scheme PasswordScheme) {
+	passwordSchemes[scheme.Name()] = scheme
+}
+
+// ConfigurePasswords registers the built-in schemes with the policy's cost
+// parameters and selects the one new hashes use. Call it once at startup.
+func ConfigurePasswords(policy PasswordPolicy) error {
+	RegisterPasswordScheme(&argon2Scheme{params: params{
+		memory:      policy.Argon2Memory,
+		iterations:  policy.Argon2Iterations,
+		parallelism: policy.Argon2Parallelism,
+		saltLength:  16,
+		keyLength:   32,
+	}})
+	RegisterPasswordScheme(&bcryptScheme{cost: policy.BcryptCost})
+
+	scheme, ok := passwordSchemes[policy.Scheme]
+	if !ok {
+		return fmt.Errorf("unknown password scheme %q", policy.Scheme)
+	}
+	preferredScheme = scheme
+	return nil
+}
+
+// HashPassword hashes with the preferred scheme
+func HashPassword(password string) (string, error) {
+	return preferredScheme.Hash(password)
+}
+
+// VerifyPassword checks password against a hash made by any registered scheme
+func VerifyPassword(password, encodedHash string) (bool, error) {
+	scheme := schemeOf(encodedHash)
+	if scheme == nil {
+		return false, fmt.Errorf("unrecognized password hash")
+	}
+	return scheme.Verify(password, encodedHash)
+}
+
+// PasswordNeedsRehash reports whether a verified password should be hashed
+// again, because its hash uses another scheme or older parameters
+func PasswordNeedsRehash(encodedHash string) bool {
+	scheme := schemeOf(encodedHash)
+	return scheme != preferredScheme || scheme.Outdated(encodedHash)
+}
+
+func schemeOf(encodedHash string) PasswordScheme {
+	for _, scheme := range passwordSchemes {
+		if scheme.Owns(encodedHash) {
+			return scheme
+		}
+	}
+	return nil
+}
+
 type params struct {
 	memory      uint32
 	iterations  uint32
@@ -18,31 +113,36 @@ type params struct {
 	keyLength   uint32
 }
 
-var defaultParams = &params{
-	memory:      64 * 1024,
-	iterations:  3,
-	parallelism: 2,
-	saltLength:  16,
-	keyLength:   32,
+type argon2Scheme struct {
+	params params
 }
 
-func HashPassword(password string) (string, error) {
-	salt, err := generateRandomBytes(defaultParams.saltLength)
+func (s *argon2Scheme) Name() string {
+	return "argon2id"
+}
+
+func (s *argon2Scheme) Owns(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$argon2id$")
+}
+
+func (s *argon2Scheme) Hash(password string) (string, error) {
+	p := s.params
+	salt, err := generateRandomBytes(p.saltLength)
 	if err != nil {
 		return "", err
 	}
 
-	hash := argon2.IDKey([]byte(password), salt, defaultParams.iterations, defaultParams.memory, defaultParams.parallelism, defaultParams.keyLength)
+	hash := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
 
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 
-	encodedHash := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, defaultParams.memory, defaultParams.iterations, defaultParams.parallelism, b64Salt, b64Hash)
+	encodedHash := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.iterations, p.parallelism, b64Salt, b64Hash)
 
 	return encodedHash, nil
 }
 
-func VerifyPassword(password, encodedHash string) (bool, error) {
+func (s *argon2Scheme) Verify(password, encodedHash string) (bool, error) {
 	p, salt, hash, err := decodeHash(encodedHash)
 	if err != nil {
 		return false, err
@@ -56,6 +156,62 @@ func VerifyPassword(password, encodedHash string) (bool, error) {
 	return false, nil
 }
 
+func (s *argon2Scheme) Outdated(encodedHash string) bool {
+	p, _, _, err := decodeHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return p.memory < s.params.memory || p.iterations < s.params.iterations || p.parallelism < s.params.parallelism
+}
+
+type bcryptScheme struct {
+	cost int
+}
+
+func (s *bcryptScheme) Name() string {
+	return "bcrypt"
+}
+
+func (s *bcryptScheme) Owns(encodedHash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encodedHash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *bcryptScheme) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (s *bcryptScheme) Verify(password, encodedHash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *bcryptScheme) Outdated(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost < s.cost
+}
+
+// GenerateTemporaryPassword returns a random password to be replaced on
+// first login
+func GenerateTemporaryPassword() (string, error) {
+	b, err := generateRandomBytes(12)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 func generateRandomBytes(n uint32) ([]byte, error) {
 	b := make([]byte, n)
 	_, err := rand.Read(b)
@@ -0,0 +1,477 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// ErrQRTooLong is returned when content doesn't fit the largest supported
+// QR version
+var ErrQRTooLong = errors.New("content too long for a QR code")
+
+// qrVersion describes a QR symbol version at error correction level M, the
+// only level generated. Block sizes come from ISO/IEC 18004 table 9.
+type qrVersion struct {
+	blocks    []int // data codewords per block
+	ecPer     int   // error correction codewords per block
+	alignment []int // alignment pattern centers
+}
+
+var qrVersions = []qrVersion{
+	1:  {[]int{16}, 10, nil},
+	2:  {[]int{28}, 16, []int{6, 18}},
+	3:  {[]int{44}, 26, []int{6, 22}},
+	4:  {[]int{32, 32}, 18, []int{6, 26}},
+	5:  {[]int{43, 43}, 24, []int{6, 30}},
+	6:  {[]int{27, 27, 27, 27}, 16, []int{6, 34}},
+	7:  {[]int{31, 31, 31, 31}, 18, []int{6, 22, 38}},
+	8:  {[]int{38, 38, 39, 39}, 22, []int{6, 24, 42}},
+	9:  {[]int{36, 36, 36, 37, 37}, 22, []int{6, 26, 46}},
+	10: {[]int{43, 43, 43, 43, 44}, 26, []int{6, 28, 50}},
+}
+
+func (v qrVersion) dataCodewords() int {
+	total := 0
+	for _, n := range v.blocks {
+		total += n
+	}
+	return total
+}
+
+// QRCodePNG encodes content as a QR code (byte mode, level M) and renders it
+// as a PNG with scale pixels per module and the standard 4-module quiet zone
+func QRCodePNG(content string, scale int) ([]byte, error) {
+	modules, err := encodeQR([]byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	const quiet = 4
+	size := len(modules)
+	side := (size + 2*quiet) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if !modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+quiet)*scale+dx, (y+quiet)*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// qrSymbol is a symbol under construction; modules[y][x] is true for dark
+type qrSymbol struct {
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func encodeQR(data []byte) ([][]bool, error) {
+	// Smallest version that fits: mode indicator, count, and the bytes
+	number := 0
+	for v := 1; v < len(qrVersions); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrVersions[v].dataCodewords()*8 {
+			number = v
+			break
+		}
+	}
+	if number == 0 {
+		return nil, ErrQRTooLong
+	}
+	version := qrVersions[number]
+
+	codewords := interleaveQR(version, qrDataCodewords(data, number, version.dataCodewords()))
+
+	size := number*4 + 17
+	s := &qrSymbol{size: size, modules: make([][]bool, size), isFunction: make([][]bool, size)}
+	for i := range s.modules {
+		s.modules[i] = make([]bool, size)
+		s.isFunction[i] = make([]bool, size)
+	}
+	s.drawFunctionPatterns(number, version)
+	s.drawCodewords(codewords)
+
+	// Keep the mask with the lowest penalty
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		s.applyMask(mask)
+		s.drawFormatBits(mask)
+		if penalty := s.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		s.applyMask(mask) // XOR undoes it
+	}
+	s.applyMask(best)
+	s.drawFormatBits(best)
+
+	return s.modules, nil
+}
+
+// qrDataCodewords builds the byte mode bit stream and pads it to capacity
+func qrDataCodewords(data []byte, number, capacity int) []byte {
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 == 1)
+		}
+	}
+
+	appendBits(0x4, 4) // byte mode
+	if number >= 10 {
+		appendBits(len(data), 16)
+	} else {
+		appendBits(len(data), 8)
+	}
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	capacityBits := capacity * 8
+	terminator := capacityBits - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	appendBits(0, terminator)
+	appendBits(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xec); len(codewords) < capacity; pad ^= 0xec ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// interleaveQR splits data into blocks, adds Reed-Solomon error correction to
+// each, and interleaves the blocks codeword by codeword
+func interleaveQR(version qrVersion, data []byte) []byte {
+	divisor := reedSolomonDivisor(version.ecPer)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset, longest := 0, 0
+	for _, n := range version.blocks {
+		block := data[offset : offset+n]
+		offset += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+		if n > longest {
+			longest = n
+		}
+	}
+
+	result := make([]byte, 0, len(data)+len(version.blocks)*version.ecPer)
+	for i := 0; i < longest; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < version.ecPer; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+func (s *qrSymbol) set(x, y int, dark bool) {
+	s.modules[y][x] = dark
+	s.isFunction[y][x] = true
+}
+
+func (s *qrSymbol) drawFunctionPatterns(number int, version qrVersion) {
+	// Timing patterns
+	for i := 0; i < s.size; i++ {
+		s.set(6, i, i%2 == 0)
+		s.set(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with their separators
+	for _, center := range [][2]int{{3, 3}, {s.size - 4, 3}, {3, s.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := center[0]+dx, center[1]+dy
+				if x < 0 || x >= s.size || y < 0 || y >= s.size {
+					continue
+				}
+				distance := qrMax(qrAbs(dx), qrAbs(dy))
+				s.set(x, y, distance != 2 && distance != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, except where they would overlap a finder
+	last := len(version.alignment) - 1
+	for i, cx := range version.alignment {
+		for j, cy := range version.alignment {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					s.set(cx+dx, cy+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas; drawn for real once the mask is chosen
+	s.drawFormatBits(0)
+
+	// Version information, versions 7 and up
+	if number >= 7 {
+		remainder := number
+		for i := 0; i < 12; i++ {
+			remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1f25)
+		}
+		bits := number<<12 | remainder
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 == 1
+			a, b := s.size-11+i%3, i/3
+			s.set(a, b, dark)
+			s.set(b, a, dark)
+		}
+	}
+}
+
+// drawFormatBits writes the level M format information for mask, and the
+// dark module beside it
+func (s *qrSymbol) drawFormatBits(mask int) {
+	data := 0<<3 | mask // level M is 00
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		s.set(8, i, bit(i))
+	}
+	s.set(8, 7, bit(6))
+	s.set(8, 8, bit(7))
+	s.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		s.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		s.set(s.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		s.set(8, s.size-15+i, bit(i))
+	}
+	s.set(8, s.size-8, true)
+}
+
+// drawCodewords places the codewords in the two-column zigzag from the
+// bottom right, skipping function modules
+func (s *qrSymbol) drawCodewords(codewords []byte) {
+	i := 0
+	for right := s.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < s.size; vert++ {
+			y := vert
+			if upward {
+				y = s.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if s.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				s.modules[y][x] = (codewords[i/8]>>(7-i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+func (s *qrSymbol) applyMask(mask int) {
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !s.isFunction[y][x] {
+				s.modules[y][x] = !s.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol by the four rules of ISO/IEC 18004 section 7.8.3
+func (s *qrSymbol) penalty() int {
+	penalty := 0
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return s.modules[x][y]
+		}
+		return s.modules[y][x]
+	}
+
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < s.size; y++ {
+			// Runs of five or more modules of one color
+			run := 1
+			for x := 1; x < s.size; x++ {
+				if at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				penalty += run - 2
+			}
+
+			// Patterns that look like a finder
+			for x := 0; x+11 <= s.size; x++ {
+				for _, pattern := range finderLike {
+					match := true
+					for k, dark := range pattern {
+						if at(x+k, y, vertical) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						penalty += 40
+					}
+				}
+			}
+		}
+	}
+
+	// 2x2 blocks of one color
+	dark := 0
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			if s.modules[y][x] {
+				dark++
+			}
+			if x+1 < s.size && y+1 < s.size {
+				c := s.modules[y][x]
+				if c == s.modules[y][x+1] && c == s.modules[y+1][x] && c == s.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// Balance of dark and light
+	total := s.size * s.size
+	k := (qrAbs(dark*20-total*10)+total-1)/total - 1
+	penalty += k * 10
+
+	return penalty
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree,
+// highest coefficient first with the leading 1 omitted
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11d)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func qrAbs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
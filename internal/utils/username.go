@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 20
+)
+
+var (
+	ErrUsernameFormat        = errors.New("usernames are 3-20 letters, numbers, underscores or dots, starting with a letter")
+	ErrUsernameReserved      = errors.New("that username is reserved")
+	ErrUsernameInappropriate = errors.New("that username isn't allowed")
+)
+
+// Letters first, and dots only between other characters
+var usernamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(?:\.[a-z0-9_]+)*$`)
+
+// reservedUsernames would collide with app routes or pass for the team
+var reservedUsernames = map[string]bool{
+	"about": true, "account": true, "admin": true, "administrator": true, "api": true,
+	"app": true, "help": true, "login": true, "logout": true, "me": true,
+	"mod": true, "moderator": true, "null": true, "official": true, "privacy": true,
+	"register": true, "root": true, "security": true, "settings": true, "signup": true,
+	"staff": true, "support": true, "system": true, "team": true, "terms": true,
+	"undefined": true, "www": true,
+}
+
+// impersonationWords would let an account pass for the team
+var impersonationWords = []string{"admin", "moderator", "official", "support"}
+
+// profanityWords are not allowed in usernames or display names
+var profanityWords = []string{
+	"bitch", "cunt", "fuck", "nigga", "nigger", "porn", "pussy", "rape", "shit", "slut", "whore",
+}
+
+var lookalikeDigits = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "_", "", ".", "")
+
+// NormalizeUsername lowercases a handle and drops a leading @. Usernames are
+// stored normalized, so uniqueness is case-insensitive.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+// ValidateUsername checks a normalized username's format, reserved names,
+// and blocked words. Blocked words may not appear anywhere, once separators
+// are dropped and digits read as the letters they imitate.
+func ValidateUsername(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength || !usernamePattern.MatchString(username) {
+		return ErrUsernameFormat
+	}
+	if reservedUsernames[username] {
+		return ErrUsernameReserved
+	}
+
+	folded := lookalikeDigits.Replace(username)
+	if containsAny(folded, impersonationWords) || containsAny(folded, profanityWords) {
+		return ErrUsernameInappropriate
+	}
+	return nil
+}
+
+func containsAny(s string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares dotted numeric versions such as "1.4.2". It returns
+// -1, 0, or 1. Missing segments count as zero and any pre-release or build
+// suffix ("1.4.2-beta", "1.4.2+77") is ignored.
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for len(pa) < len(pb) {
+		pa = append(pa, 0)
+	}
+	for len(pb) < len(pa) {
+		pb = append(pb, 0)
+	}
+
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+
+	var parts []int
+	for _, segment := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(segment)
+		parts = append(parts, n)
+	}
+	return parts
+}
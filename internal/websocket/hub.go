@@ -1,14 +1,33 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	pendingEventTimeout = 5 * time.Second
+	joinCheckTimeout    = 5 * time.Second
+	abuseReportTimeout  = 5 * time.Second
+	closeWriteTimeout   = time.Second
+
+	// Sent when a connection is closed because its user was suspended or
+	// banned, so the app signs out instead of reconnecting
+	closeAccountBlocked = 4003
+)
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for development
@@ -16,26 +35,73 @@ var upgrader = websocket.Upgrader{
 }
 
 type Hub struct {
+	// mu guards clients, and each client's conversationID, which handlers
+	// read from their own goroutines while Run adds and removes connections
+	mu         sync.RWMutex
 	clients    map[*Client]bool
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan []byte
+	pending    *redis.Client // holds durable events for offline users; nil disables
+	// canJoin checks that a user belongs to a conversation they ask to join;
+	// nil lets every join through
+	canJoin func(ctx context.Context, userID, conversationID uint) bool
+	// mutedUntil looks up until when a connecting user is muted; nil
+	// treats everyone as unmuted until a mute event arrives
+	mutedUntil func(ctx context.Context, userID uint) time.Time
+	// reportAbuse tells the anti-abuse team about a user whose connection
+	// was closed for flooding; nil only logs it
+	reportAbuse func(ctx context.Context, userID uint, details string)
+	limits      *userRateLimits
+	moderate    chan services.ModerationEvent
 }
 
 type Client struct {
 	hub            *Hub
 	conn           *websocket.Conn
-	send           chan []byte
+	send           chan []byte       // messages, matches and other events that must arrive
+	low            *lowPriorityQueue // typing and other hints that may be coalesced or dropped
 	userID         uint
+	deviceID       string // empty for clients that don't sync
 	conversationID uint
+	resumeToken    string // lets the next connection pick up where this one left off
+	// Queued events sent on connect are acknowledged once the first
+	// ackAfter frames are written
+	pendingCount int
+	ackAfter     int
+	mutedUntil   atomic.Int64 // Unix milliseconds; what the user sends isn't relayed before then
+	closeFrame   []byte       // sent instead of a plain close when the hub drops the connection
+	protocol     int          // protocol version negotiated on connect
+	rejected     int          // client frames rejected so far, to key their error frames
+	limiter      *frameLimiter
 }
 
 type Message struct {
 	Type           string `json:"type"`
+	MessageID      uint   `json:"message_id,omitempty"`
 	ConversationID uint   `json:"conversation_id"`
+	RoomID         uint   `json:"room_id,omitempty"`
 	SenderID       uint   `json:"sender_id"`
-	Content        string `json:"content"`
+	Content        string `json:"content"` // empty for encrypted messages, which clients fetch by ID
 	MessageType    string `json:"message_type"`
+	SystemKind     string `json:"system_kind,omitempty"`
+	Encrypted      bool   `json:"encrypted,omitempty"`
+	Timestamp      string `json:"timestamp"`
+}
+
+// ModerationFrame tells a connection about a penalty that just took effect
+type ModerationFrame struct {
+	Type   string     `json:"type"` // moderation
+	Action string     `json:"action"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// MatchEvent tells a user they have a new match
+type MatchEvent struct {
+	Type           string `json:"type"` // match
+	MatchID        uint   `json:"match_id"`
+	ConversationID uint   `json:"conversation_id"`
+	UserID         uint   `json:"user_id"` // the other user
 	Timestamp      string `json:"timestamp"`
 }
 
@@ -46,74 +112,273 @@ type TypingMessage struct {
 	IsTyping       bool   `json:"is_typing"`
 }
 
-func NewHub() *Hub {
+// NewHub returns a hub that queues undelivered match and message events in
+// Redis, or drops them when pending is nil
+func NewHub(pending *redis.Client) *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan []byte),
+		pending:    pending,
+		limits:     newUserRateLimits(),
+		moderate:   make(chan services.ModerationEvent),
+	}
+}
+
+// AuthorizeJoins sets the check join_conversation requests must pass
+func (h *Hub) AuthorizeJoins(canJoin func(ctx context.Context, userID, conversationID uint) bool) {
+	h.canJoin = canJoin
+}
+
+// TrackMutes sets how the hub learns whether a connecting user is muted
+func (h *Hub) TrackMutes(mutedUntil func(ctx context.Context, userID uint) time.Time) {
+	h.mutedUntil = mutedUntil
+}
+
+// ReportAbuse sets how the hub reports users whose connections it closed for
+// sending too many frames
+func (h *Hub) ReportAbuse(reportAbuse func(ctx context.Context, userID uint, details string)) {
+	h.reportAbuse = reportAbuse
+}
+
+// EnforceModeration applies penalties published by any instance to the
+// connections open on this one until ctx is cancelled: muted users' typing
+// stops being relayed, and suspended or banned users are disconnected.
+func (h *Hub) EnforceModeration(ctx context.Context, rc *redis.Client) {
+	for event := range services.SubscribeModerationEvents(ctx, rc) {
+		h.moderate <- event
 	}
 }
 
 func (h *Hub) Run() {
 	for {
 		select {
+		case event := <-h.moderate:
+			h.applyModeration(event)
+
 		case client := <-h.register:
+			h.mu.Lock()
 			h.clients[client] = true
-			log.Printf("Client connected: User ID %d", client.userID)
+			h.mu.Unlock()
+			log.Printf("Client connected: User ID %d, device %q", client.userID, client.deviceID)
 
 		case client := <-h.unregister:
+			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+				h.drop(client)
 				log.Printf("Client disconnected: User ID %d", client.userID)
 			}
+			h.mu.Unlock()
 
 		case message := <-h.broadcast:
+			h.mu.Lock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+				h.offer(client, message)
 			}
+			h.mu.Unlock()
 		}
 	}
 }
 
-func (h *Hub) BroadcastToConversation(conversationID uint, message []byte) {
+// offer queues message on the client's connection, dropping the connection
+// when it's backed up. It reports whether the client took it. The caller
+// must hold mu for writing.
+func (h *Hub) offer(client *Client, message []byte) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
+		h.drop(client)
+		return false
+	}
+}
+
+// drop forgets a connection and closes its send channel, which ends its
+// write pump. The caller must hold mu for writing.
+func (h *Hub) drop(client *Client) {
+	close(client.send)
+	delete(h.clients, client)
+}
+
+func (h *Hub) applyModeration(event services.ModerationEvent) {
+	frame, err := json.Marshal(ModerationFrame{Type: "moderation", Action: event.Action, Until: event.Until})
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	for client := range h.clients {
-		if client.conversationID == conversationID {
+		if client.userID != event.UserID {
+			continue
+		}
+		switch event.Action {
+		case services.ModerationMute:
+			if event.Until != nil {
+				client.mutedUntil.Store(event.Until.UnixMilli())
+			}
 			select {
-			case client.send <- message:
+			case client.send <- frame:
 			default:
-				close(client.send)
-				delete(h.clients, client)
 			}
+		case services.ModerationDisconnect:
+			client.closeFrame = websocket.FormatCloseMessage(closeAccountBlocked, "account suspended")
+			h.drop(client)
+		}
+	}
+	if event.Action == services.ModerationDisconnect {
+		log.Printf("Disconnected user %d after a suspension", event.UserID)
+	}
+}
+
+func (h *Hub) BroadcastToConversation(conversationID uint, message []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.conversationID == conversationID {
+			h.offer(client, message)
 		}
 	}
 }
 
+// BroadcastSystemMessage sends a stored system message to the connections
+// that joined its conversation
+func (h *Hub) BroadcastSystemMessage(conversationID, messageID, actorID uint, content, kind string, createdAt time.Time) {
+	if messageBytes, err := systemMessageFrame(conversationID, messageID, actorID, content, kind, createdAt); err == nil {
+		h.BroadcastToConversation(conversationID, messageBytes)
+	}
+}
+
+func systemMessageFrame(conversationID, messageID, actorID uint, content, kind string, createdAt time.Time) ([]byte, error) {
+	return json.Marshal(Message{
+		Type:           "message",
+		MessageID:      messageID,
+		ConversationID: conversationID,
+		SenderID:       actorID,
+		Content:        content,
+		MessageType:    "system",
+		SystemKind:     kind,
+		Timestamp:      createdAt.Format(time.RFC3339),
+	})
+}
+
 func (h *Hub) BroadcastToUser(userID uint, message []byte) {
-	for client := range h.clients {
-		if client.userID == userID {
-			select {
-			case client.send <- message:
-			default:
-				close(client.send)
-				delete(h.clients, client)
+	h.sendToUser(userID, message)
+}
+
+// DeliverToUser sends an event that must not be lost, such as a new match, to
+// every connection of the user. When none takes it, because the user is
+// offline or their connections are backed up, it is queued and sent the next
+// time they connect.
+func (h *Hub) DeliverToUser(userID uint, message []byte) {
+	message = h.record(userID, message)
+	if h.sendToUser(userID, message) == 0 {
+		h.queueEvent(userID, message)
+	}
+}
+
+// DeliverToConversation sends a message event to the members' connections
+// that joined the conversation. Each member gets it stamped with their own
+// event_id, so a dropped connection can resume, and a member with no open
+// connection gets it queued.
+func (h *Hub) DeliverToConversation(conversationID uint, memberIDs []uint, message []byte) {
+	for _, userID := range memberIDs {
+		if userID == 0 {
+			continue
+		}
+		stamped := h.record(userID, message)
+
+		online := false
+		h.mu.Lock()
+		for client := range h.clients {
+			if client.userID != userID {
+				continue
 			}
+			online = true
+			if client.conversationID == conversationID {
+				h.offer(client, stamped)
+			}
+		}
+		h.mu.Unlock()
+		if !online {
+			h.queueEvent(userID, stamped)
+		}
+	}
+}
+
+// sendToUser sends message to each of the user's connections and returns how
+// many took it
+func (h *Hub) sendToUser(userID uint, message []byte) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sent := 0
+	for client := range h.clients {
+		if client.userID == userID && h.offer(client, message) {
+			sent++
+		}
+	}
+	return sent
+}
+
+func (h *Hub) queueEvent(userID uint, message []byte) {
+	if h.pending == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pendingEventTimeout)
+	defer cancel()
+	if err := h.pending.QueueEvent(ctx, userID, message); err != nil {
+		log.Printf("Failed to queue event for user %d: %v", userID, err)
+	}
+}
+
+// BroadcastLowPriority sends a typing indicator or similar hint to the
+// connections that joined the conversation. It replaces any waiting event
+// with the same key and is dropped for connections that are backed up.
+func (h *Hub) BroadcastLowPriority(conversationID uint, key string, message []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.conversationID == conversationID {
+			client.low.push(key, message)
+		}
+	}
+}
+
+// BroadcastToUsers sends message to every connection of the given users, e.g.
+// the members of a room
+func (h *Hub) BroadcastToUsers(userIDs []uint, message []byte) {
+	recipients := make(map[uint]bool, len(userIDs))
+	for _, id := range userIDs {
+		recipients[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if recipients[client.userID] {
+			h.offer(client, message)
 		}
 	}
 }
 
-func HandleWebSocket(hub *Hub, c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+// HandleWebSocket upgrades the request and registers the connection. The
+// first frame carries the token to resume it with. Initial frames, such as a
+// sync of what the device missed, follow; then, when resume names the
+// session a dropped connection had, the events logged since its last event
+// ID, or else the events queued while the user was offline.
+func HandleWebSocket(hub *Hub, c *gin.Context, deviceID string, resume ResumeRequest, initial ...[]byte) {
+	version, subprotocol := negotiateProtocol(c.Request)
+	var header http.Header
+	if subprotocol != "" {
+		header = http.Header{"Sec-WebSocket-Protocol": {subprotocol}}
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, header)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
+	conn.SetReadLimit(maxFrameSize)
 
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -122,12 +387,52 @@ func HandleWebSocket(hub *Hub, c *gin.Context) {
 	}
 
 	client := &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		userID: userID.(uint),
+		hub:      hub,
+		conn:     conn,
+		send:     make(chan []byte, 256),
+		low:      newLowPriorityQueue(),
+		userID:   userID.(uint),
+		deviceID: deviceID,
+		protocol: version,
+	}
+	client.queueFrame(ProtocolFrame{Type: "protocol", Version: version, Supported: []int{ProtocolV1, ProtocolV2}})
+	if hub.mutedUntil != nil {
+		client.mutedUntil.Store(hub.mutedUntil(c.Request.Context(), client.userID).UnixMilli())
+	}
+	if hub.pending != nil {
+		if token, err := newResumeToken(); err == nil {
+			client.resumeToken = token
+			client.queueFrame(SessionFrame{Type: "session", ResumeToken: token})
+		}
+	}
+	for _, frame := range initial {
+		client.send <- frame
+	}
+	if hub.pending != nil {
+		ctx := c.Request.Context()
+		resumed := resume.Token != "" && client.resume(ctx, resume)
+
+		events, err := hub.pending.PendingEvents(ctx, client.userID)
+		if err != nil {
+			log.Printf("Failed to load queued events for user %d: %v", client.userID, err)
+		}
+		if resumed {
+			// Queued events are in the log too, so the replay covered them
+			if err := hub.pending.AckEvents(ctx, client.userID, len(events)); err != nil {
+				log.Printf("Failed to acknowledge queued events for user %d: %v", client.userID, err)
+			}
+		} else {
+			for _, event := range events {
+				client.send <- []byte(event)
+			}
+			client.pendingCount = len(events)
+			client.ackAfter = len(client.send)
+		}
+		client.saveSession()
 	}
 
+	hub.limits.acquire(client.userID)
+	client.limiter = newFrameLimiter(hub.limits, client.userID)
 	hub.register <- client
 
 	go client.writePump()
@@ -136,6 +441,8 @@ func HandleWebSocket(hub *Hub, c *gin.Context) {
 
 func (c *Client) readPump() {
 	defer func() {
+		c.saveSession()
+		c.hub.limits.release(c.userID)
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
@@ -149,63 +456,169 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Parse message to determine type and conversation
-		var message map[string]interface{}
-		if err := json.Unmarshal(messageBytes, &message); err != nil {
-			log.Printf("Error parsing message: %v", err)
+		allowed, abusive := c.limiter.allow(time.Now())
+		if abusive {
+			c.closeForFlooding()
+			break
+		}
+		if !allowed {
+			c.reject(&ErrorFrame{Type: "error", Code: ErrorRateLimited, Message: "Too many frames, slow down"})
+			continue
+		}
+
+		frame, rejection := parseClientFrame(messageBytes, c.protocol)
+		if rejection != nil {
+			c.reject(rejection)
+			continue
+		}
+
+		// Muted users can still open conversations, but nothing they send
+		// reaches anyone else
+		if frame.Type != "join_conversation" && c.muted() {
 			continue
 		}
 
-		// Handle different message types
-		switch message["type"] {
+		switch frame.Type {
 		case "join_conversation":
-			if convID, ok := message["conversation_id"].(float64); ok {
-				c.conversationID = uint(convID)
+			if frame.ConversationID == c.conversationID {
+				continue
+			}
+			if !c.mayJoin(frame.ConversationID) {
+				c.reject(&ErrorFrame{Type: "error", Code: ErrorForbidden, Message: "You aren't part of this conversation", Ref: frame.ID, For: frame.Type})
+				continue
+			}
+			c.hub.mu.Lock()
+			c.conversationID = frame.ConversationID
+			c.hub.mu.Unlock()
+			c.low.dropTyping()
+			c.saveSession()
+		case "typing", "stop_typing":
+			// Only members of a conversation may type in it
+			if frame.ConversationID != c.conversationID && !c.mayJoin(frame.ConversationID) {
+				c.reject(&ErrorFrame{Type: "error", Code: ErrorForbidden, Message: "You aren't part of this conversation", Ref: frame.ID, For: frame.Type})
+				continue
 			}
-		case "typing":
 			// Broadcast typing indicator to conversation participants
-			if convID, ok := message["conversation_id"].(float64); ok {
-				typingMsg := TypingMessage{
-					Type:           "typing",
-					ConversationID: uint(convID),
-					UserID:         c.userID,
-					IsTyping:       true,
-				}
-				if msgBytes, err := json.Marshal(typingMsg); err == nil {
-					c.hub.BroadcastToConversation(uint(convID), msgBytes)
-				}
+			typingMsg := TypingMessage{
+				Type:           "typing",
+				ConversationID: frame.ConversationID,
+				UserID:         c.userID,
+				IsTyping:       frame.Type == "typing",
 			}
-		case "stop_typing":
-			if convID, ok := message["conversation_id"].(float64); ok {
-				typingMsg := TypingMessage{
-					Type:           "typing",
-					ConversationID: uint(convID),
-					UserID:         c.userID,
-					IsTyping:       false,
-				}
-				if msgBytes, err := json.Marshal(typingMsg); err == nil {
-					c.hub.BroadcastToConversation(uint(convID), msgBytes)
-				}
+			if msgBytes, err := json.Marshal(typingMsg); err == nil {
+				c.hub.BroadcastLowPriority(frame.ConversationID, typingKey(frame.ConversationID, c.userID), msgBytes)
 			}
 		}
 	}
 }
 
+// reject sends an error frame about a frame the client sent. Errors go with
+// the low priority events, so a client sending garbage can't crowd out its
+// own messages and the reader never blocks on a backed up connection.
+func (c *Client) reject(rejection *ErrorFrame) {
+	data, err := json.Marshal(rejection)
+	if err != nil {
+		return
+	}
+	key := "error:" + ErrorRateLimited // one is enough however many frames were dropped
+	if rejection.Code != ErrorRateLimited {
+		c.rejected++
+		key = fmt.Sprintf("error:%d", c.rejected)
+	}
+	c.low.push(key, data)
+}
+
+// closeForFlooding closes a connection that keeps going over its rate
+// limits and reports its user
+func (c *Client) closeForFlooding() {
+	log.Printf("Closing WebSocket of user %d for exceeding its rate limits", c.userID)
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(closeRateLimited, "rate limit exceeded"),
+		time.Now().Add(closeWriteTimeout))
+
+	if c.hub.reportAbuse == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), abuseReportTimeout)
+		defer cancel()
+		c.hub.reportAbuse(ctx, c.userID, fmt.Sprintf("WebSocket closed after %d rate limited frames within %s", abuseDropLimit, abuseWindow))
+	}()
+}
+
+func (c *Client) muted() bool {
+	return time.Now().UnixMilli() < c.mutedUntil.Load()
+}
+
+func (c *Client) mayJoin(conversationID uint) bool {
+	if c.hub.canJoin == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), joinCheckTimeout)
+	defer cancel()
+	return c.hub.canJoin(ctx, c.userID, conversationID)
+}
+
+func (c *Client) ackPending() {
+	ctx, cancel := context.WithTimeout(context.Background(), pendingEventTimeout)
+	defer cancel()
+	if err := c.hub.pending.AckEvents(ctx, c.userID, c.pendingCount); err != nil {
+		log.Printf("Failed to acknowledge queued events for user %d: %v", c.userID, err)
+	}
+	c.pendingCount = 0
+}
+
 func (c *Client) writePump() {
 	defer c.conn.Close()
 
+	written := 0
+	writeEvent := func(message []byte, ok bool) bool {
+		if !ok {
+			frame := c.closeFrame
+			if frame == nil {
+				frame = []byte{}
+			}
+			c.conn.WriteMessage(websocket.CloseMessage, frame)
+			return false
+		}
+
+		if err := c.conn.WriteMessage(websocket.TextMessage, encodeFrame(message, c.protocol)); err != nil {
+			log.Printf("WebSocket write error: %v", err)
+			return false
+		}
+
+		// Queued events stay queued until written, so a connection that
+		// drops mid-flush gets them again next time
+		written++
+		if c.pendingCount > 0 && written == c.ackAfter {
+			c.ackPending()
+		}
+		return true
+	}
+
 	for {
+		// Everything waiting in send goes out before any low priority event
 		select {
 		case message, ok := <-c.send:
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			if !writeEvent(message, ok) {
 				return
 			}
+			continue
+		default:
+		}
 
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("WebSocket write error: %v", err)
+		select {
+		case message, ok := <-c.send:
+			if !writeEvent(message, ok) {
 				return
 			}
+		case <-c.low.ready:
+			if frame, ok := c.low.pop(); ok {
+				if err := c.conn.WriteMessage(websocket.TextMessage, encodeFrame(frame, c.protocol)); err != nil {
+					log.Printf("WebSocket write error: %v", err)
+					return
+				}
+			}
 		}
 	}
 }
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+	"ethiopia-dating-app/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	opsWriteTimeout = 10 * time.Second
+	opsPingInterval = 30 * time.Second
+)
+
+// HandleOpsFeed streams the admin operations feed to a dashboard. Every
+// connection has its own Redis subscription, so events from all instances
+// reach every dashboard. Dashboards only listen; anything they send is
+// ignored.
+func HandleOpsFeed(rc *redis.Client, c *gin.Context) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := services.SubscribeOpsEvents(ctx, rc)
+
+	// Reading is only how a closed dashboard is noticed
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(opsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(opsWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(event)); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(opsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Protocol versions. Version 1 frames are flat JSON objects with a type;
+// version 2 frames are envelopes that carry the frame's fields as payload.
+// Clients pick one on connect through Sec-WebSocket-Protocol, and those that
+// offer none get version 1.
+const (
+	ProtocolV1 = 1
+	ProtocolV2 = 2
+
+	LatestProtocol = ProtocolV2
+)
+
+// subprotocols maps the Sec-WebSocket-Protocol names to versions
+var subprotocols = map[string]int{
+	"dating.v2": ProtocolV2,
+	"dating.v1": ProtocolV1,
+}
+
+// Error codes sent back in error frames
+const (
+	ErrorInvalidJSON        = "invalid_json"
+	ErrorUnsupportedVersion = "unsupported_version"
+	ErrorUnknownType        = "unknown_type"
+	ErrorInvalidPayload     = "invalid_payload"
+	ErrorForbidden          = "forbidden"
+)
+
+// maxFrameSize is the largest frame a client may send; none of the client
+// frames need more
+const maxFrameSize = 4096
+
+// Envelope is a version 2 frame. ID is the client's own reference for the
+// frame, echoed back in any error frame about it.
+type Envelope struct {
+	Type    string          `json:"type"`
+	Version int             `json:"version"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ProtocolFrame is the first frame on every connection, naming the version
+// it speaks
+type ProtocolFrame struct {
+	Type      string `json:"type"` // protocol
+	Version   int    `json:"version"`
+	Supported []int  `json:"supported"`
+}
+
+// ErrorFrame tells the client a frame it sent was rejected and why
+type ErrorFrame struct {
+	Type    string `json:"type"` // error
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Ref     string `json:"ref,omitempty"`      // the rejected frame's id
+	For     string `json:"for_type,omitempty"` // the rejected frame's type
+}
+
+// ConversationPayload is the payload of join_conversation, typing and
+// stop_typing
+type ConversationPayload struct {
+	ConversationID uint `json:"conversation_id"`
+}
+
+// clientFrame is a validated frame from a client
+type clientFrame struct {
+	Type           string
+	ID             string
+	ConversationID uint
+}
+
+// clientFrameTypes are the frames clients may send
+var clientFrameTypes = map[string]bool{
+	"join_conversation": true,
+	"typing":            true,
+	"stop_typing":       true,
+}
+
+// negotiateProtocol returns the version the client asked for in
+// Sec-WebSocket-Protocol, and the subprotocol to answer with
+func negotiateProtocol(r *http.Request) (int, string) {
+	for _, offered := range websocket.Subprotocols(r) {
+		if version, ok := subprotocols[offered]; ok {
+			return version, offered
+		}
+	}
+	return ProtocolV1, ""
+}
+
+// parseClientFrame decodes and validates a frame sent in the given version.
+// Version 2 payloads must not carry unknown fields.
+func parseClientFrame(data []byte, version int) (clientFrame, *ErrorFrame) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return clientFrame{}, frameError(ErrorInvalidJSON, "Frames must be JSON objects", envelope)
+	}
+	if version == ProtocolV1 {
+		// Flat frames are their own payload
+		envelope.Payload = data
+	} else if envelope.Version != ProtocolV2 {
+		return clientFrame{}, frameError(ErrorUnsupportedVersion, fmt.Sprintf("This connection speaks version %d", version), envelope)
+	}
+	if !clientFrameTypes[envelope.Type] {
+		return clientFrame{}, frameError(ErrorUnknownType, fmt.Sprintf("Unknown frame type %q", envelope.Type), envelope)
+	}
+
+	var payload ConversationPayload
+	decoder := json.NewDecoder(bytes.NewReader(envelope.Payload))
+	if version != ProtocolV1 {
+		decoder.DisallowUnknownFields()
+	}
+	if len(envelope.Payload) == 0 || decoder.Decode(&payload) != nil {
+		return clientFrame{}, frameError(ErrorInvalidPayload, "payload must be {\"conversation_id\": <number>}", envelope)
+	}
+	if payload.ConversationID == 0 {
+		return clientFrame{}, frameError(ErrorInvalidPayload, "conversation_id is required", envelope)
+	}
+
+	return clientFrame{Type: envelope.Type, ID: envelope.ID, ConversationID: payload.ConversationID}, nil
+}
+
+func frameError(code, message string, envelope Envelope) *ErrorFrame {
+	return &ErrorFrame{Type: "error", Code: code, Message: message, Ref: envelope.ID, For: envelope.Type}
+}
+
+// encodeFrame puts a server frame in the connection's version. Server
+// frames are built flat; version 2 moves their fields into an envelope.
+func encodeFrame(frame []byte, version int) []byte {
+	if version == ProtocolV1 {
+		return frame
+	}
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(frame, &head); err != nil {
+		return frame
+	}
+	wrapped, err := json.Marshal(Envelope{Type: head.Type, Version: version, Payload: frame})
+	if err != nil {
+		return frame
+	}
+	return wrapped
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lowPriorityLimit is how many distinct low priority events may wait on
+	// one connection; more are dropped
+	lowPriorityLimit = 32
+	// lowPriorityMaxAge is how long a low priority event stays worth sending.
+	// A typing indicator from ten seconds ago is wrong, not late.
+	lowPriorityMaxAge = 5 * time.Second
+
+	typingKeyPrefix = "typing:"
+)
+
+// typingKey coalesces typing events per user and conversation
+func typingKey(conversationID, userID uint) string {
+	return fmt.Sprintf("%s%d:%d", typingKeyPrefix, conversationID, userID)
+}
+
+type lowPriorityEvent struct {
+	frame    []byte
+	queuedAt time.Time
+}
+
+// lowPriorityQueue holds a connection's typing indicators and other hints
+// that only matter while fresh. An event replaces a waiting one with the
+// same key, so only the newest state goes out, and when the queue is full
+// new events are dropped rather than holding up or disconnecting the
+// client. The writer only reads from it when no message is waiting, so a
+// slow connection never loses a message to a pile of typing events.
+type lowPriorityQueue struct {
+	mu     sync.Mutex
+	keys   []string // oldest first
+	events map[string]lowPriorityEvent
+	ready  chan struct{}
+}
+
+func newLowPriorityQueue() *lowPriorityQueue {
+	return &lowPriorityQueue{
+		events: make(map[string]lowPriorityEvent),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// push queues frame under key and reports whether it was kept
+func (q *lowPriorityQueue) push(key string, frame []byte) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, waiting := q.events[key]; !waiting {
+		if len(q.keys) >= lowPriorityLimit {
+			return false
+		}
+		q.keys = append(q.keys, key)
+	}
+	q.events[key] = lowPriorityEvent{frame: frame, queuedAt: time.Now()}
+	q.signal()
+	return true
+}
+
+// pop returns the oldest event still fresh enough to send
+func (q *lowPriorityQueue) pop() ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.keys) > 0 {
+		key := q.keys[0]
+		q.keys = q.keys[1:]
+		event := q.events[key]
+		delete(q.events, key)
+		if time.Since(event.queuedAt) <= lowPriorityMaxAge {
+			if len(q.keys) > 0 {
+				q.signal()
+			}
+			return event.frame, true
+		}
+	}
+	return nil, false
+}
+
+// dropTyping discards waiting typing indicators, e.g. when the user opens a
+// different conversation and the old ones no longer apply
+func (q *lowPriorityQueue) dropTyping() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	kept := q.keys[:0]
+	for _, key := range q.keys {
+		if strings.HasPrefix(key, typingKeyPrefix) {
+			delete(q.events, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	q.keys = kept
+}
+
+func (q *lowPriorityQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
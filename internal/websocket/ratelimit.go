@@ -0,0 +1,142 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Each connection may send connectionFrameRate frames a second, in bursts
+	// of up to connectionFrameBurst
+	connectionFrameRate  = 5
+	connectionFrameBurst = 20
+	// All of a user's connections together may send userFrameRate frames a
+	// second, in bursts of up to userFrameBurst
+	userFrameRate  = 10
+	userFrameBurst = 40
+
+	// A connection that goes over its limits abuseDropLimit times within
+	// abuseWindow is closed with closeRateLimited and its user reported
+	abuseDropLimit = 50
+	abuseWindow    = 30 * time.Second
+
+	closeRateLimited = 4008
+
+	ErrorRateLimited = "rate_limited"
+)
+
+// tokenBucket allows rate events a second on average, and up to burst at once
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// allow takes a token if one is left
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+type userBucket struct {
+	bucket      *tokenBucket
+	connections int
+}
+
+// userRateLimits holds the buckets shared by each user's open connections.
+// A bucket lives as long as one of its user's connections does.
+type userRateLimits struct {
+	mu      sync.Mutex
+	buckets map[uint]*userBucket
+}
+
+func newUserRateLimits() *userRateLimits {
+	return &userRateLimits{buckets: make(map[uint]*userBucket)}
+}
+
+func (l *userRateLimits) acquire(userID uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.buckets[userID]
+	if !ok {
+		entry = &userBucket{bucket: newTokenBucket(userFrameRate, userFrameBurst)}
+		l.buckets[userID] = entry
+	}
+	entry.connections++
+}
+
+func (l *userRateLimits) release(userID uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.buckets[userID]
+	if !ok {
+		return
+	}
+	entry.connections--
+	if entry.connections <= 0 {
+		delete(l.buckets, userID)
+	}
+}
+
+func (l *userRateLimits) allow(userID uint, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.buckets[userID]
+	if !ok {
+		return true
+	}
+	return entry.bucket.allow(now)
+}
+
+// frameLimiter is a connection's view of its own and its user's limits. It
+// is only used from the connection's reader.
+type frameLimiter struct {
+	connection  *tokenBucket
+	users       *userRateLimits
+	userID      uint
+	drops       int
+	windowStart time.Time
+}
+
+func newFrameLimiter(users *userRateLimits, userID uint) *frameLimiter {
+	return &frameLimiter{
+		connection: newTokenBucket(connectionFrameRate, connectionFrameBurst),
+		users:      users,
+		userID:     userID,
+	}
+}
+
+// allow reports whether a frame may go through, and whether the connection
+// has gone over its limits so often that it should be closed
+func (l *frameLimiter) allow(now time.Time) (bool, bool) {
+	// Both buckets are charged, so a connection can't spend its user's
+	// allowance while its own is empty
+	connectionOK := l.connection.allow(now)
+	userOK := l.users.allow(l.userID, now)
+	if connectionOK && userOK {
+		return true, false
+	}
+
+	if now.Sub(l.windowStart) > abuseWindow {
+		l.windowStart = now
+		l.drops = 0
+	}
+	l.drops++
+	return false, l.drops >= abuseDropLimit
+}
@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"ethiopia-dating-app/internal/redis"
+)
+
+// relayChannel carries events made outside the API instances, such as by
+// background jobs in worker mode, to the hub of every instance
+const relayChannel = "websocket_relay"
+
+// relayedEvent is one member's copy of a conversation event, already stamped
+// with that member's event_id
+type relayedEvent struct {
+	ConversationID uint            `json:"conversation_id"`
+	UserID         uint            `json:"user_id"`
+	Message        json.RawMessage `json:"message"`
+}
+
+// Relay hands events to the connections open on the API instances through
+// Redis. Background jobs use it in place of a Hub, which has no connections
+// in worker mode.
+type Relay struct {
+	rc *redis.Client
+}
+
+func NewRelay(rc *redis.Client) *Relay {
+	return &Relay{rc: rc}
+}
+
+// DeliverSystemMessage sends a stored system message to the members'
+// connections that joined its conversation, on whichever instance they are.
+// Each copy is logged first, so a member whose connection dropped gets it
+// when they resume.
+func (r *Relay) DeliverSystemMessage(ctx context.Context, conversationID uint, memberIDs []uint, messageID, actorID uint, content, kind string, createdAt time.Time) {
+	frame, err := systemMessageFrame(conversationID, messageID, actorID, content, kind, createdAt)
+	if err != nil {
+		return
+	}
+
+	for _, userID := range memberIDs {
+		if userID == 0 {
+			continue
+		}
+		payload, err := json.Marshal(relayedEvent{
+			ConversationID: conversationID,
+			UserID:         userID,
+			Message:        recordEvent(r.rc, userID, frame),
+		})
+		if err != nil {
+			continue
+		}
+		if err := r.rc.Publish(ctx, relayChannel, payload); err != nil {
+			log.Printf("Failed to relay conversation %d event to user %d: %v", conversationID, userID, err)
+		}
+	}
+}
+
+// RelayEvents delivers events published through a Relay to the connections
+// open on this instance until ctx is cancelled
+func (h *Hub) RelayEvents(ctx context.Context, rc *redis.Client) {
+	pubsub := rc.Subscribe(ctx, relayChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			var event relayedEvent
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				continue
+			}
+
+			h.mu.Lock()
+			for client := range h.clients {
+				if client.userID == event.UserID && client.conversationID == event.ConversationID {
+					h.offer(client, event.Message)
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
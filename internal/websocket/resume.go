@@ -0,0 +1,139 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+
+	"ethiopia-dating-app/internal/redis"
+)
+
+// ResumeRequest is what a reconnecting client presents: the resume token
+// from its last session frame and the highest event_id it received
+type ResumeRequest struct {
+	Token       string
+	LastEventID int64
+}
+
+// SessionFrame gives the client the token to resume this connection with
+type SessionFrame struct {
+	Type        string `json:"type"` // session
+	ResumeToken string `json:"resume_token"`
+}
+
+// ResumeFrame says whether a resume worked. On resumed, the missed events
+// follow and the conversation is joined again; on resume_failed the client
+// refreshes over REST.
+type ResumeFrame struct {
+	Type           string `json:"type"` // resumed, resume_failed
+	Replayed       int    `json:"replayed,omitempty"`
+	ConversationID uint   `json:"conversation_id,omitempty"`
+}
+
+func newResumeToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// stampEventID adds event_id to a JSON object frame
+func stampEventID(frame []byte, id int64) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(frame, &fields); err != nil {
+		return frame
+	}
+	fields["event_id"], _ = json.Marshal(id)
+	stamped, err := json.Marshal(fields)
+	if err != nil {
+		return frame
+	}
+	return stamped
+}
+
+// record logs an event for the user so a resuming connection can replay it,
+// and returns the frame stamped with its event_id
+func (h *Hub) record(userID uint, message []byte) []byte {
+	return recordEvent(h.pending, userID, message)
+}
+
+func recordEvent(rc *redis.Client, userID uint, message []byte) []byte {
+	if rc == nil || userID == 0 {
+		return message
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pendingEventTimeout)
+	defer cancel()
+
+	id, err := rc.NextEventID(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to number event for user %d: %v", userID, err)
+		return message
+	}
+	stamped := stampEventID(message, id)
+	if err := rc.LogEvent(ctx, userID, id, stamped); err != nil {
+		log.Printf("Failed to log event for user %d: %v", userID, err)
+	}
+	return stamped
+}
+
+// resume replays what the client missed since its last session and rejoins
+// that session's conversation. It reports false when the session is gone or
+// the log no longer covers the gap.
+func (c *Client) resume(ctx context.Context, req ResumeRequest) bool {
+	session, events, ok := c.missedEvents(ctx, req)
+	if !ok {
+		c.queueFrame(ResumeFrame{Type: "resume_failed"})
+		return false
+	}
+
+	if session.ConversationID != 0 && c.mayJoin(session.ConversationID) {
+		c.conversationID = session.ConversationID
+	}
+	c.queueFrame(ResumeFrame{Type: "resumed", Replayed: len(events), ConversationID: c.conversationID})
+	for _, event := range events {
+		c.send <- []byte(event)
+	}
+	return true
+}
+
+func (c *Client) missedEvents(ctx context.Context, req ResumeRequest) (*redis.ResumeSession, []string, bool) {
+	session, err := c.hub.pending.TakeResumeSession(ctx, req.Token)
+	if err != nil {
+		log.Printf("Failed to load resume session for user %d: %v", c.userID, err)
+		return nil, nil, false
+	}
+	if session == nil || session.UserID != c.userID {
+		return nil, nil, false
+	}
+
+	events, complete, err := c.hub.pending.EventsSince(ctx, c.userID, req.LastEventID)
+	if err != nil {
+		log.Printf("Failed to load missed events for user %d: %v", c.userID, err)
+		return nil, nil, false
+	}
+	return session, events, complete
+}
+
+// queueFrame queues a control frame ahead of the connection's first write
+func (c *Client) queueFrame(frame interface{}) {
+	if data, err := json.Marshal(frame); err == nil {
+		c.send <- data
+	}
+}
+
+// saveSession lets the next connection resume this one. It is saved on
+// connect, on every join and on disconnect, and expires a while after that.
+func (c *Client) saveSession() {
+	if c.hub.pending == nil || c.resumeToken == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pendingEventTimeout)
+	defer cancel()
+	session := redis.ResumeSession{UserID: c.userID, ConversationID: c.conversationID}
+	if err := c.hub.pending.SaveResumeSession(ctx, c.resumeToken, session); err != nil {
+		log.Printf("Failed to save resume session for user %d: %v", c.userID, err)
+	}
+}